@@ -0,0 +1,104 @@
+package amp_test
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/amp"
+	"github.com/stretchr/testify/require"
+)
+
+type sharerTest struct {
+	name      string
+	numShares int
+}
+
+var sharerTests = []sharerTest{
+	{
+		name:      "root only",
+		numShares: 1,
+	},
+	{
+		name:      "two shares",
+		numShares: 2,
+	},
+	{
+		name:      "many shares",
+		numShares: 10,
+	},
+}
+
+// TestSharer executes the end-to-end derivation between sender and receiver,
+// asserting that shares are properly computed and, when reconstructed by the
+// receiver, produce identical child hashes and preimages as the sender.
+func TestSharer(t *testing.T) {
+	for _, test := range sharerTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			testSharer(t, test)
+		})
+	}
+}
+
+func testSharer(t *testing.T, test sharerTest) {
+	// Construct a new sharer with a random seed.
+	var (
+		sharer amp.Sharer
+		err    error
+	)
+	sharer, err = amp.NewSeedSharer()
+	require.NoError(t, err)
+
+	// Assert that we can instantiate an equivalent root sharer using the
+	// root share.
+	root := sharer.Root()
+	sharerFromRoot := amp.SeedSharerFromRoot(&root)
+	require.Equal(t, sharer, sharerFromRoot)
+
+	// Generate the child shares for the sender. This will create
+	// numShares-1 random shares, and a final share such that the XOR of
+	// all shares equals the root.
+	children := make([]*amp.Child, test.numShares)
+	for i := 0; i < test.numShares-1; i++ {
+		var left amp.Sharer
+		left, sharer, err = sharer.Split()
+		require.NoError(t, err)
+
+		children[i] = left.Child(0)
+	}
+	children[test.numShares-1] = sharer.Child(0)
+
+	// Compile the child descriptors that will be sent to the receiver.
+	descs := make([]amp.ChildDesc, test.numShares)
+	for i, child := range children {
+		descs[i] = child.ChildDesc
+	}
+
+	// Now, reconstruct the children on the receiver's end and assert that
+	// the derived hashes and preimages match those of the sender.
+	receiverChildren := amp.ReconstructChildren(descs...)
+	require.Equal(t, children, receiverChildren)
+
+	// Finally, assert that the hash of each preimage matches the child
+	// hash.
+	for _, child := range receiverChildren {
+		require.Equal(t, child.Hash, child.Preimage.Hash())
+	}
+}
+
+// TestSharerMerge asserts that merging a split sharer back into its sibling
+// yields the original share value.
+func TestSharerMerge(t *testing.T) {
+	sharer, err := amp.NewSeedSharer()
+	require.NoError(t, err)
+
+	left, right, err := sharer.Split()
+	require.NoError(t, err)
+
+	merged := left.Merge(right)
+	require.Equal(t, sharer.Child(0), merged.Child(0))
+
+	// Merging with a zero sharer should be a no-op.
+	require.Equal(t, sharer.Child(0), sharer.Merge(sharer.Zero()).Child(0))
+}
@@ -0,0 +1,79 @@
+package amp_test
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/decred/dcrlnd/amp"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/shards"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAMPShardTracker tests that we can derive and cancel shards at will using
+// the AMP shard tracker.
+func TestAMPShardTracker(t *testing.T) {
+	var root, setID, payAddr [32]byte
+	_, err := rand.Read(root[:])
+	require.NoError(t, err)
+
+	_, err = rand.Read(setID[:])
+	require.NoError(t, err)
+
+	_, err = rand.Read(payAddr[:])
+	require.NoError(t, err)
+
+	var totalAmt lnwire.MilliAtom = 1000
+
+	// Create an AMP shard tracker using the random data we just generated.
+	tracker := amp.NewShardTracker(root, setID, payAddr, totalAmt)
+
+	// Trying to retrieve a hash for id 0 should result in an error.
+	_, err = tracker.GetHash(0)
+	require.Error(t, err)
+
+	// We launch a new shard, and expect the information to be returned to
+	// be the information we just generated.
+	shard, err := tracker.NewShard(0, false)
+	require.NoError(t, err)
+
+	require.Equal(t, setID, shard.AMP().SetID())
+	require.Equal(t, totalAmt, shard.MPP().TotalMAtoms())
+	require.Equal(t, payAddr, shard.MPP().PaymentAddr())
+
+	// Retrieving the hash should now succeed.
+	hash, err := tracker.GetHash(0)
+	require.NoError(t, err)
+	require.Equal(t, shard.Hash(), hash)
+
+	// Now we cancel the shard, and launch some more shards, one of them
+	// being the last one.
+	require.NoError(t, tracker.CancelShard(0))
+
+	var launched []shards.PaymentShard
+	for i := uint64(1); i <= 3; i++ {
+		shard, err := tracker.NewShard(i, i == 3)
+		require.NoError(t, err)
+
+		launched = append(launched, shard)
+	}
+
+	// The canceled shard's hash should no longer be known.
+	_, err = tracker.GetHash(0)
+	require.Error(t, err)
+
+	// Finally, the receiver must be able to reconstruct the child
+	// preimages of the launched shards from the shares they carry.
+	descs := make([]amp.ChildDesc, len(launched))
+	for i, shard := range launched {
+		descs[i] = amp.ChildDesc{
+			Share: shard.AMP().RootShare(),
+			Index: shard.AMP().ChildIndex(),
+		}
+	}
+
+	children := amp.ReconstructChildren(descs...)
+	for i, child := range children {
+		require.Equal(t, launched[i].Hash(), child.Hash)
+	}
+}
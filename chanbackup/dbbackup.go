@@ -0,0 +1,269 @@
+package chanbackup
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrlnd/keychain"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// dbBackupVersion is the version of the encrypted database backup
+	// format.
+	dbBackupVersion = 0
+
+	// dbBackupChunkSize is the maximum size of the plaintext sealed within
+	// a single frame of an encrypted database backup.
+	dbBackupChunkSize = 64 * 1024
+
+	// dbBackupNoncePrefixSize is the size of the random nonce prefix of an
+	// encrypted database backup. The remainder of the nonce of each frame
+	// is its index within the backup.
+	dbBackupNoncePrefixSize = chacha20poly1305.NonceSizeX - 8
+
+	// dbBackupFrameHeaderSize is the size of the header of a frame, which
+	// holds the final frame flag and the length of its ciphertext.
+	dbBackupFrameHeaderSize = 5
+)
+
+var (
+	// dbBackupKeyTag is used to domain separate the key used to encrypt
+	// database backups from the key used to encrypt static channel
+	// backups, which are derived from the same base key.
+	dbBackupKeyTag = []byte("dcrlnd-db-backup")
+
+	// ErrDBBackupTruncated is returned when an encrypted database backup
+	// ends before its final frame.
+	ErrDBBackupTruncated = errors.New("database backup is truncated")
+)
+
+// genDBBackupEncryptionKey derives the key that we'll use to encrypt database
+// backups. Like the key of the static channel backups, it is derived from the
+// base key obtained from the keyring, along with a tag.
+func genDBBackupEncryptionKey(keyRing keychain.KeyRing) ([]byte, error) {
+	//  key = SHA256(tag || baseKey)
+	baseKey, err := keyRing.DeriveKey(baseEncryptionKeyLoc)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	h.Write(dbBackupKeyTag)
+	h.Write(baseKey.PubKey.SerializeCompressed())
+
+	return h.Sum(nil), nil
+}
+
+// dbBackupNonce returns the nonce of the frame with the given index.
+func dbBackupNonce(prefix []byte, index uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[dbBackupNoncePrefixSize:], index)
+
+	return nonce
+}
+
+// dbBackupWriter encrypts a database backup written to it. The backup is
+// split into frames, each sealed with the index of the frame within the nonce
+// and a flag marking the final frame as associated data, so frames can
+// neither be reordered nor dropped without being detected.
+type dbBackupWriter struct {
+	w           io.Writer
+	aead        cipher.AEAD
+	noncePrefix []byte
+	index       uint64
+	buf         []byte
+}
+
+// NewDBBackupWriter returns a writer which encrypts a database backup written
+// to it with a key derived from the passed keyRing, writing the ciphertext to
+// the passed writer. The writer must be closed to write the final frame.
+func NewDBBackupWriter(w io.Writer,
+	keyRing keychain.KeyRing) (io.WriteCloser, error) {
+
+	encryptionKey, err := genDBBackupEncryptionKey(keyRing)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header of the backup holds its version and the random nonce
+	// prefix.
+	header := make([]byte, 1+dbBackupNoncePrefixSize)
+	header[0] = dbBackupVersion
+	if _, err := rand.Read(header[1:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &dbBackupWriter{
+		w:           w,
+		aead:        aead,
+		noncePrefix: header[1:],
+		buf:         make([]byte, 0, dbBackupChunkSize),
+	}, nil
+}
+
+// Write buffers the passed plaintext and writes out every full frame. The
+// last chunk is kept buffered, as only Close knows whether it is the final
+// one.
+//
+// NOTE: This is part of the io.Writer interface.
+func (b *dbBackupWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(b.buf) == dbBackupChunkSize {
+			if err := b.writeFrame(false); err != nil {
+				return 0, err
+			}
+		}
+
+		chunk := dbBackupChunkSize - len(b.buf)
+		if chunk > len(p) {
+			chunk = len(p)
+		}
+		b.buf = append(b.buf, p[:chunk]...)
+		p = p[chunk:]
+	}
+
+	return n, nil
+}
+
+// Close writes the final frame holding the remaining buffered plaintext.
+//
+// NOTE: This is part of the io.Closer interface.
+func (b *dbBackupWriter) Close() error {
+	return b.writeFrame(true)
+}
+
+// writeFrame seals the buffered plaintext and writes it as a frame.
+func (b *dbBackupWriter) writeFrame(final bool) error {
+	var header [dbBackupFrameHeaderSize]byte
+	if final {
+		header[0] = 1
+	}
+	binary.BigEndian.PutUint32(
+		header[1:], uint32(len(b.buf)+b.aead.Overhead()),
+	)
+
+	nonce := dbBackupNonce(b.noncePrefix, b.index)
+	ciphertext := b.aead.Seal(nil, nonce, b.buf, header[:1])
+
+	if _, err := b.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := b.w.Write(ciphertext); err != nil {
+		return err
+	}
+
+	b.index++
+	b.buf = b.buf[:0]
+
+	return nil
+}
+
+// dbBackupReader decrypts a database backup written by a dbBackupWriter.
+type dbBackupReader struct {
+	r           io.Reader
+	aead        cipher.AEAD
+	noncePrefix []byte
+	index       uint64
+	plaintext   []byte
+	final       bool
+}
+
+// NewDBBackupReader returns a reader which decrypts the database backup read
+// from the passed reader with a key derived from the passed keyRing. Reading
+// fails if the backup was modified or truncated.
+func NewDBBackupReader(r io.Reader,
+	keyRing keychain.KeyRing) (io.Reader, error) {
+
+	encryptionKey, err := genDBBackupEncryptionKey(keyRing)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 1+dbBackupNoncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrDBBackupTruncated
+	}
+	if header[0] != dbBackupVersion {
+		return nil, fmt.Errorf("unknown database backup version %v",
+			header[0])
+	}
+
+	return &dbBackupReader{
+		r:           r,
+		aead:        aead,
+		noncePrefix: header[1:],
+	}, nil
+}
+
+// Read reads the decrypted backup, opening the next frame when needed.
+//
+// NOTE: This is part of the io.Reader interface.
+func (b *dbBackupReader) Read(p []byte) (int, error) {
+	for len(b.plaintext) == 0 {
+		if b.final {
+			return 0, io.EOF
+		}
+
+		if err := b.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, b.plaintext)
+	b.plaintext = b.plaintext[n:]
+
+	return n, nil
+}
+
+// readFrame reads and opens the next frame.
+func (b *dbBackupReader) readFrame() error {
+	var header [dbBackupFrameHeaderSize]byte
+	if _, err := io.ReadFull(b.r, header[:]); err != nil {
+		return ErrDBBackupTruncated
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	maxLength := uint32(dbBackupChunkSize + b.aead.Overhead())
+	if length > maxLength {
+		return fmt.Errorf("database backup frame too large: %v",
+			length)
+	}
+
+	ciphertext := make([]byte, length)
+	if _, err := io.ReadFull(b.r, ciphertext); err != nil {
+		return ErrDBBackupTruncated
+	}
+
+	nonce := dbBackupNonce(b.noncePrefix, b.index)
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, header[:1])
+	if err != nil {
+		return err
+	}
+
+	b.index++
+	b.plaintext = plaintext
+	b.final = header[0] == 1
+
+	return nil
+}
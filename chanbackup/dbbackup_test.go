@@ -0,0 +1,170 @@
+package chanbackup
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+// TestDBBackupEncryption tests that a database backup of various sizes can be
+// decrypted after being encrypted, and that a modified or truncated backup is
+// rejected.
+func TestDBBackupEncryption(t *testing.T) {
+	t.Parallel()
+
+	keyRing := &mockKeyRing{}
+
+	testCases := []struct {
+		name string
+
+		// size is the size of the plaintext backup.
+		size int
+
+		// mutator allows a test case to modify the encrypted backup
+		// before we attempt to decrypt it.
+		mutator func([]byte) []byte
+
+		// valid indicates if the backup should decrypt.
+		valid bool
+	}{
+		{
+			name:  "empty",
+			size:  0,
+			valid: true,
+		},
+		{
+			name:  "single frame",
+			size:  1000,
+			valid: true,
+		},
+		{
+			name:  "full frames",
+			size:  2 * dbBackupChunkSize,
+			valid: true,
+		},
+		{
+			name:  "partial frame",
+			size:  3*dbBackupChunkSize + 7,
+			valid: true,
+		},
+		{
+			name: "flipped byte",
+			size: 3*dbBackupChunkSize + 7,
+			mutator: func(b []byte) []byte {
+				b[len(b)/2] ^= 1
+				return b
+			},
+		},
+		{
+			name: "truncated final frame",
+			size: 3*dbBackupChunkSize + 7,
+			mutator: func(b []byte) []byte {
+				return b[:len(b)-1]
+			},
+		},
+		{
+			// Dropping the final frame leaves a backup consisting
+			// of valid frames only.
+			name: "dropped final frame",
+			size: 3*dbBackupChunkSize + 7,
+			mutator: func(b []byte) []byte {
+				// The final frame holds 7 bytes and the
+				// 16 byte authentication tag.
+				final := dbBackupFrameHeaderSize + 7 + 16
+				return b[:len(b)-final]
+			},
+		},
+		{
+			name: "unknown version",
+			size: 1000,
+			mutator: func(b []byte) []byte {
+				b[0] = dbBackupVersion + 1
+				return b
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			plaintext := make([]byte, testCase.size)
+			rand.Read(plaintext)
+
+			// Write the backup in uneven pieces to exercise the
+			// buffering of the writer.
+			var ciphertext bytes.Buffer
+			w, err := NewDBBackupWriter(&ciphertext, keyRing)
+			if err != nil {
+				t.Fatalf("unable to create writer: %v", err)
+			}
+			for p := plaintext; len(p) > 0; {
+				n := 1 + rand.Intn(dbBackupChunkSize)
+				if n > len(p) {
+					n = len(p)
+				}
+				if _, err := w.Write(p[:n]); err != nil {
+					t.Fatalf("unable to write: %v", err)
+				}
+				p = p[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unable to close writer: %v", err)
+			}
+
+			encrypted := ciphertext.Bytes()
+			if testCase.mutator != nil {
+				encrypted = testCase.mutator(encrypted)
+			}
+
+			var decrypted []byte
+			r, err := NewDBBackupReader(
+				bytes.NewReader(encrypted), keyRing,
+			)
+			if err == nil {
+				decrypted, err = ioutil.ReadAll(r)
+			}
+
+			switch {
+			case err != nil && testCase.valid:
+				t.Fatalf("unable to decrypt backup: %v", err)
+
+			case err == nil && !testCase.valid:
+				t.Fatalf("invalid backup was decrypted")
+
+			case testCase.valid &&
+				!bytes.Equal(plaintext, decrypted):
+
+				t.Fatalf("decrypted backup mismatch")
+			}
+		})
+	}
+}
+
+// TestDBBackupKeyDerivation tests that the key used to encrypt database
+// backups differs from the key of the static channel backups, and that key
+// derivation failures are reported.
+func TestDBBackupKeyDerivation(t *testing.T) {
+	t.Parallel()
+
+	scbKey, err := genEncryptionKey(&mockKeyRing{})
+	if err != nil {
+		t.Fatalf("unable to derive key: %v", err)
+	}
+	dbKey, err := genDBBackupEncryptionKey(&mockKeyRing{})
+	if err != nil {
+		t.Fatalf("unable to derive key: %v", err)
+	}
+	if bytes.Equal(scbKey, dbKey) {
+		t.Fatalf("database backup key matches static backup key")
+	}
+
+	var b bytes.Buffer
+	if _, err := NewDBBackupWriter(&b, &mockKeyRing{true}); err == nil {
+		t.Fatalf("expected error due to fail key gen")
+	}
+	if _, err := NewDBBackupReader(&b, &mockKeyRing{true}); err == nil {
+		t.Fatalf("expected error due to fail key gen")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/decred/dcrlnd/keychain"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/shachain"
+	"github.com/decred/dcrlnd/tlv"
 )
 
 const (
@@ -109,13 +110,6 @@ var (
 	// TODO(roasbeef): rename to commit chain?
 	commitDiffKey = []byte("commit-diff-key")
 
-	// revocationLogBucket is dedicated for storing the necessary delta
-	// state between channel updates required to re-construct a past state
-	// in order to punish a counterparty attempting a non-cooperative
-	// channel closure. This key should be accessed from within the
-	// sub-bucket of a target channel, identified by its channel point.
-	revocationLogBucket = []byte("revocation-log-key")
-
 	// frozenChanKey is the key where we store the information for any
 	// active "frozen" channels. This key is present only in the leaf
 	// bucket for a given channel.
@@ -1526,11 +1520,15 @@ func (c *OpenChannel) BalancesAtHeight(height uint64) (lnwire.MilliAtom,
 
 	// If we are not currently on the height requested, we need to look up
 	// the previous height to obtain our balances at the given height.
-	commit, err := c.FindPreviousState(height)
+	rl, commit, err := c.FindPreviousState(height)
 	if err != nil {
 		return 0, 0, err
 	}
 
+	if rl != nil {
+		return rl.OurBalance, rl.TheirBalance, nil
+	}
+
 	return commit.LocalBalance, commit.RemoteBalance, nil
 }
 
@@ -1610,6 +1608,80 @@ type HTLC struct {
 	// from the HtlcIndex as this will be incremented for each new log
 	// update added.
 	LogIndex uint64
+
+	// BlindingPoint is the blinding point that was received along with
+	// the HTLC, if it is part of a blinded route. It is required to
+	// process the onion blob of the HTLC.
+	BlindingPoint *secp256k1.PublicKey
+}
+
+// htlcBlindingPointType is the type of the blinding point within the TLV
+// extension of a stored HTLC.
+const htlcBlindingPointType tlv.Type = 0
+
+// encodeOnionAndExtension returns the onion blob of the HTLC followed by its
+// TLV extension. As the onion blob is of a fixed size, the extension can be
+// stored within the same field, which keeps the serialization of HTLCs
+// without an extension unchanged.
+func (h *HTLC) encodeOnionAndExtension() ([]byte, error) {
+	if h.BlindingPoint == nil {
+		return h.OnionBlob, nil
+	}
+
+	if len(h.OnionBlob) != lnwire.OnionPacketSize {
+		return nil, fmt.Errorf("invalid onion blob size %d for htlc "+
+			"with extension", len(h.OnionBlob))
+	}
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(
+			htlcBlindingPointType, &h.BlindingPoint,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Write(h.OnionBlob)
+	if err := tlvStream.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// decodeOnionAndExtension splits the given data, written with
+// encodeOnionAndExtension, into the onion blob of the HTLC and its TLV
+// extension.
+func (h *HTLC) decodeOnionAndExtension(data []byte) error {
+	if len(data) <= lnwire.OnionPacketSize {
+		h.OnionBlob = data
+		return nil
+	}
+
+	h.OnionBlob = data[:lnwire.OnionPacketSize]
+
+	var blindingPoint *secp256k1.PublicKey
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(htlcBlindingPointType, &blindingPoint),
+	)
+	if err != nil {
+		return err
+	}
+
+	parsedTypes, err := tlvStream.DecodeWithParsedTypes(
+		bytes.NewReader(data[lnwire.OnionPacketSize:]),
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := parsedTypes[htlcBlindingPointType]; ok {
+		h.BlindingPoint = blindingPoint
+	}
+
+	return nil
 }
 
 // SerializeHtlcs writes out the passed set of HTLC's into the passed writer
@@ -1624,9 +1696,14 @@ func SerializeHtlcs(b io.Writer, htlcs ...HTLC) error {
 	}
 
 	for _, htlc := range htlcs {
+		onionAndExtension, err := htlc.encodeOnionAndExtension()
+		if err != nil {
+			return err
+		}
+
 		if err := WriteElements(b,
 			htlc.Signature, htlc.RHash, htlc.Amt, htlc.RefundTimeout,
-			htlc.OutputIndex, htlc.Incoming, htlc.OnionBlob,
+			htlc.OutputIndex, htlc.Incoming, onionAndExtension,
 			htlc.HtlcIndex, htlc.LogIndex,
 		); err != nil {
 			return err
@@ -1655,14 +1732,20 @@ func DeserializeHtlcs(r io.Reader) ([]HTLC, error) {
 
 	htlcs = make([]HTLC, numHtlcs)
 	for i := uint16(0); i < numHtlcs; i++ {
+		var onionAndExtension []byte
 		if err := ReadElements(r,
 			&htlcs[i].Signature, &htlcs[i].RHash, &htlcs[i].Amt,
 			&htlcs[i].RefundTimeout, &htlcs[i].OutputIndex,
-			&htlcs[i].Incoming, &htlcs[i].OnionBlob,
+			&htlcs[i].Incoming, &onionAndExtension,
 			&htlcs[i].HtlcIndex, &htlcs[i].LogIndex,
 		); err != nil {
 			return htlcs, err
 		}
+
+		err := htlcs[i].decodeOnionAndExtension(onionAndExtension)
+		if err != nil {
+			return htlcs, err
+		}
 	}
 
 	return htlcs, nil
@@ -1675,6 +1758,7 @@ func (h *HTLC) Copy() HTLC {
 		Amt:           h.Amt,
 		RefundTimeout: h.RefundTimeout,
 		OutputIndex:   h.OutputIndex,
+		BlindingPoint: h.BlindingPoint,
 	}
 	copy(clone.Signature, h.Signature)
 	copy(clone.RHash[:], h.RHash[:])
@@ -2173,9 +2257,11 @@ func (c *OpenChannel) InsertNextRevocation(revKey *secp256k1.PublicKey) error {
 // remote party to the revocation log, and promote the current pending
 // commitment to the current remote commitment. The updates parameter is the
 // set of local updates that the peer still needs to send us a signature for.
-// We store this set of updates in case we go down.
+// We store this set of updates in case we go down. The output indexes are the
+// indexes of our and their output on the revoked remote commitment, or
+// OutputIndexEmpty if the respective output doesn't exist.
 func (c *OpenChannel) AdvanceCommitChainTail(fwdPkg *FwdPkg,
-	updates []LogUpdate) error {
+	updates []LogUpdate, ourOutputIndex, theirOutputIndex uint16) error {
 
 	c.Lock()
 	defer c.Unlock()
@@ -2215,12 +2301,8 @@ func (c *OpenChannel) AdvanceCommitChainTail(fwdPkg *FwdPkg,
 		}
 
 		// With the current preimage producer/store state updated,
-		// append a new log entry recording this the delta of this
-		// state transition.
-		//
-		// TODO(roasbeef): could make the deltas relative, would save
-		// space, but then tradeoff for more disk-seeks to recover the
-		// full state.
+		// append a new log entry recording the data needed to punish
+		// a broadcast of the state being revoked.
 		logKey := revocationLogBucket
 		logBucket, err := chanBucket.CreateBucketIfNotExists(logKey)
 		if err != nil {
@@ -2248,9 +2330,10 @@ func (c *OpenChannel) AdvanceCommitChainTail(fwdPkg *FwdPkg,
 
 		// With the commitment pointer swapped, we can now add the
 		// revoked (prior) state to the revocation log.
-		//
-		// TODO(roasbeef): store less
-		err = appendChannelLogEntry(logBucket, &c.RemoteCommitment)
+		err = putRevocationLog(
+			logBucket, &c.RemoteCommitment, ourOutputIndex,
+			theirOutputIndex,
+		)
 		if err != nil {
 			return err
 		}
@@ -2421,59 +2504,6 @@ func (c *OpenChannel) RemoveFwdPkg(height uint64) error {
 	})
 }
 
-// RevocationLogTail returns the "tail", or the end of the current revocation
-// log. This entry represents the last previous state for the remote node's
-// commitment chain. The ChannelDelta returned by this method will always lag
-// one state behind the most current (unrevoked) state of the remote node's
-// commitment chain.
-func (c *OpenChannel) RevocationLogTail() (*ChannelCommitment, error) {
-	c.RLock()
-	defer c.RUnlock()
-
-	// If we haven't created any state updates yet, then we'll exit early as
-	// there's nothing to be found on disk in the revocation bucket.
-	if c.RemoteCommitment.CommitHeight == 0 {
-		return nil, nil
-	}
-
-	var commit ChannelCommitment
-	if err := kvdb.View(c.Db, func(tx kvdb.RTx) error {
-		chanBucket, err := fetchChanBucket(
-			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
-		)
-		if err != nil {
-			return err
-		}
-
-		logBucket := chanBucket.NestedReadBucket(revocationLogBucket)
-		if logBucket == nil {
-			return ErrNoPastDeltas
-		}
-
-		// Once we have the bucket that stores the revocation log from
-		// this channel, we'll jump to the _last_ key in bucket. As we
-		// store the update number on disk in a big-endian format,
-		// this will retrieve the latest entry.
-		cursor := logBucket.ReadCursor()
-		_, tailLogEntry := cursor.Last()
-		logEntryReader := bytes.NewReader(tailLogEntry)
-
-		// Once we have the entry, we'll decode it into the channel
-		// delta pointer we created above.
-		var dbErr error
-		commit, dbErr = deserializeChanCommit(logEntryReader)
-		if dbErr != nil {
-			return dbErr
-		}
-
-		return nil
-	}); err != nil {
-		return nil, err
-	}
-
-	return &commit, nil
-}
-
 // CommitmentHeight returns the current commitment height. The commitment
 // height represents the number of updates to the commitment state to date.
 // This value is always monotonically increasing. This method is provided in
@@ -2513,13 +2543,22 @@ func (c *OpenChannel) CommitmentHeight() (uint64, error) {
 // the previous channel state indicated by the update number. This method is
 // intended to be used for obtaining the relevant data needed to claim all
 // funds rightfully spendable in the case of an on-chain broadcast of the
-// commitment transaction.
-func (c *OpenChannel) FindPreviousState(updateNum uint64) (*ChannelCommitment, error) {
+// commitment transaction. Depending on the format the state was stored in,
+// either a compact revocation log entry or, for states that weren't converted
+// from the former format, the full commitment is returned.
+func (c *OpenChannel) FindPreviousState(
+	updateNum uint64) (*RevocationLog, *ChannelCommitment, error) {
+
 	c.RLock()
 	defer c.RUnlock()
 
-	var commit ChannelCommitment
+	var (
+		rl     *RevocationLog
+		commit *ChannelCommitment
+	)
 	err := kvdb.View(c.Db, func(tx kvdb.RTx) error {
+		rl, commit = nil, nil
+
 		chanBucket, err := fetchChanBucket(
 			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
 		)
@@ -2528,23 +2567,41 @@ func (c *OpenChannel) FindPreviousState(updateNum uint64) (*ChannelCommitment, e
 		}
 
 		logBucket := chanBucket.NestedReadBucket(revocationLogBucket)
-		if logBucket == nil {
+		legacyBucket := chanBucket.NestedReadBucket(
+			revocationLogBucketDeprecated,
+		)
+		if logBucket == nil && legacyBucket == nil {
 			return ErrNoPastDeltas
 		}
 
-		c, err := fetchChannelLogEntry(logBucket, updateNum)
+		// The compact log is consulted first, as all states revoked
+		// since its introduction are stored there.
+		if logBucket != nil {
+			rl, err = fetchRevocationLog(logBucket, updateNum)
+			if err != errLogEntryNotFound {
+				return err
+			}
+		}
+
+		if legacyBucket == nil {
+			return errLogEntryNotFound
+		}
+
+		legacyCommit, err := fetchChannelLogEntry(
+			legacyBucket, updateNum,
+		)
 		if err != nil {
 			return err
 		}
 
-		commit = c
+		commit = &legacyCommit
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return &commit, nil
+	return rl, commit, nil
 }
 
 // ClosureType is an enum like structure that details exactly _how_ a channel
@@ -2734,10 +2791,16 @@ func (c *OpenChannel) CloseChannel(summary *ChannelCloseSummary,
 		}
 
 		// With the base channel data deleted, attempt to delete the
-		// information stored within the revocation log.
-		logBucket := chanBucket.NestedReadWriteBucket(revocationLogBucket)
-		if logBucket != nil {
-			err = chanBucket.DeleteNestedBucket(revocationLogBucket)
+		// information stored within the revocation logs.
+		for _, logKey := range [][]byte{
+			revocationLogBucket, revocationLogBucketDeprecated,
+		} {
+			logBucket := chanBucket.NestedReadWriteBucket(logKey)
+			if logBucket == nil {
+				continue
+			}
+
+			err = chanBucket.DeleteNestedBucket(logKey)
 			if err != nil {
 				return err
 			}
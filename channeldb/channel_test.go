@@ -767,7 +767,7 @@ func TestChannelStateTransition(t *testing.T) {
 	fwdPkg := NewFwdPkg(channel.ShortChanID(), oldRemoteCommit.CommitHeight,
 		diskCommitDiff.LogUpdates, nil)
 
-	err = channel.AdvanceCommitChainTail(fwdPkg, nil)
+	err = channel.AdvanceCommitChainTail(fwdPkg, nil, 0, 1)
 	if err != nil {
 		t.Fatalf("unable to append to revocation log: %v", err)
 	}
@@ -780,25 +780,22 @@ func TestChannelStateTransition(t *testing.T) {
 
 	// We should be able to fetch the channel delta created above by its
 	// update number with all the state properly reconstructed.
-	diskPrevCommit, err := channel.FindPreviousState(
+	diskPrevLog, diskPrevCommit, err := channel.FindPreviousState(
 		oldRemoteCommit.CommitHeight,
 	)
 	if err != nil {
 		t.Fatalf("unable to fetch past delta: %v", err)
 	}
 
-	// The two deltas (the original vs the on-disk version) should
-	// identical, and all HTLC data should properly be retained.
-	assertCommitmentEqual(t, &oldRemoteCommit, diskPrevCommit)
-
-	// The state number recovered from the tail of the revocation log
-	// should be identical to this current state.
-	logTail, err := channel.RevocationLogTail()
-	if err != nil {
-		t.Fatalf("unable to retrieve log: %v", err)
+	// The state should have been stored in the compact format, retaining
+	// the data of the original commitment needed to punish a breach.
+	if diskPrevCommit != nil {
+		t.Fatal("expected state to be stored in the compact format")
 	}
-	if logTail.CommitHeight != oldRemoteCommit.CommitHeight {
-		t.Fatal("update number doesn't match")
+	expectedLog := NewRevocationLog(&oldRemoteCommit, 0, 1)
+	if !reflect.DeepEqual(expectedLog, diskPrevLog) {
+		t.Fatalf("revocation logs don't match: expected %v, got %v",
+			spew.Sdump(expectedLog), spew.Sdump(diskPrevLog))
 	}
 
 	oldRemoteCommit = channel.RemoteCommitment
@@ -815,26 +812,24 @@ func TestChannelStateTransition(t *testing.T) {
 
 	fwdPkg = NewFwdPkg(channel.ShortChanID(), oldRemoteCommit.CommitHeight, nil, nil)
 
-	err = channel.AdvanceCommitChainTail(fwdPkg, nil)
+	err = channel.AdvanceCommitChainTail(
+		fwdPkg, nil, 1, OutputIndexEmpty,
+	)
 	if err != nil {
 		t.Fatalf("unable to append to revocation log: %v", err)
 	}
 
 	// Once again, fetch the state and ensure it has been properly updated.
-	prevCommit, err := channel.FindPreviousState(oldRemoteCommit.CommitHeight)
+	prevLog, _, err := channel.FindPreviousState(
+		oldRemoteCommit.CommitHeight,
+	)
 	if err != nil {
 		t.Fatalf("unable to fetch past delta: %v", err)
 	}
-	assertCommitmentEqual(t, &oldRemoteCommit, prevCommit)
-
-	// Once again, state number recovered from the tail of the revocation
-	// log should be identical to this current state.
-	logTail, err = channel.RevocationLogTail()
-	if err != nil {
-		t.Fatalf("unable to retrieve log: %v", err)
-	}
-	if logTail.CommitHeight != oldRemoteCommit.CommitHeight {
-		t.Fatal("update number doesn't match")
+	expectedLog = NewRevocationLog(&oldRemoteCommit, 1, OutputIndexEmpty)
+	if !reflect.DeepEqual(expectedLog, prevLog) {
+		t.Fatalf("revocation logs don't match: expected %v, got %v",
+			spew.Sdump(expectedLog), spew.Sdump(prevLog))
 	}
 
 	// The revocation state stored on-disk should now also be identical.
@@ -875,7 +870,9 @@ func TestChannelStateTransition(t *testing.T) {
 
 	// Attempting to find previous states on the channel should fail as the
 	// revocation log has been deleted.
-	_, err = updatedChannel[0].FindPreviousState(oldRemoteCommit.CommitHeight)
+	_, _, err = updatedChannel[0].FindPreviousState(
+		oldRemoteCommit.CommitHeight,
+	)
 	if err == nil {
 		t.Fatal("revocation log search should have failed")
 	}
@@ -1453,7 +1450,9 @@ func TestBalanceAtHeight(t *testing.T) {
 			commit.LocalBalance = local
 			commit.RemoteBalance = remote
 
-			return appendChannelLogEntry(logBucket, &commit)
+			return putRevocationLog(
+				logBucket, &commit, 0, OutputIndexEmpty,
+			)
 		})
 
 		return err
@@ -1620,3 +1619,117 @@ func TestHasChanStatus(t *testing.T) {
 		})
 	}
 }
+
+// TestLogUpdatesWithExtension asserts that log updates carrying messages with
+// a TLV extension can be serialized alongside regular messages.
+func TestLogUpdatesWithExtension(t *testing.T) {
+	t.Parallel()
+
+	_, blindingPoint := privKeyFromBytes(key[:])
+
+	updates := []LogUpdate{
+		{
+			LogIndex: 1,
+			UpdateMsg: &lnwire.UpdateAddHTLC{
+				ID:            1,
+				Amount:        1000,
+				BlindingPoint: blindingPoint,
+			},
+		},
+		{
+			LogIndex: 2,
+			UpdateMsg: &lnwire.UpdateAddHTLC{
+				ID:     2,
+				Amount: 2000,
+			},
+		},
+		{
+			LogIndex: 3,
+			UpdateMsg: &lnwire.UpdateFulfillHTLC{
+				ID: 3,
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := serializeLogUpdates(&b, updates); err != nil {
+		t.Fatalf("unable to serialize log updates: %v", err)
+	}
+
+	// Add some trailing data, to make sure that the extension doesn't
+	// consume data that follows the updates.
+	trailer := []byte{1, 2, 3}
+	b.Write(trailer)
+
+	r := bytes.NewReader(b.Bytes())
+	decoded, err := deserializeLogUpdates(r)
+	if err != nil {
+		t.Fatalf("unable to deserialize log updates: %v", err)
+	}
+
+	if !reflect.DeepEqual(updates, decoded) {
+		t.Fatalf("log updates mismatch: expected %v, got %v",
+			spew.Sdump(updates), spew.Sdump(decoded))
+	}
+
+	rest := make([]byte, len(trailer)+1)
+	n, _ := r.Read(rest)
+	if !bytes.Equal(trailer, rest[:n]) {
+		t.Fatalf("expected trailer %x, got %x", trailer, rest[:n])
+	}
+}
+
+// TestHtlcsWithExtension asserts that the TLV extension of an HTLC is
+// serialized alongside its onion blob, while HTLCs without an extension keep
+// their existing serialization.
+func TestHtlcsWithExtension(t *testing.T) {
+	t.Parallel()
+
+	_, blindingPoint := privKeyFromBytes(key[:])
+
+	onionBlob := bytes.Repeat([]byte{2}, lnwire.OnionPacketSize)
+	htlcs := []HTLC{
+		{
+			Signature:     []byte("sig"),
+			RHash:         [32]byte{1},
+			Amt:           1000,
+			RefundTimeout: 100,
+			Incoming:      true,
+			OnionBlob:     onionBlob,
+			HtlcIndex:     1,
+			LogIndex:      2,
+			BlindingPoint: blindingPoint,
+		},
+		{
+			Signature:     []byte("sig"),
+			RHash:         [32]byte{2},
+			Amt:           2000,
+			RefundTimeout: 200,
+			OnionBlob:     onionBlob,
+			HtlcIndex:     3,
+			LogIndex:      4,
+		},
+	}
+
+	var b bytes.Buffer
+	if err := SerializeHtlcs(&b, htlcs...); err != nil {
+		t.Fatalf("unable to serialize htlcs: %v", err)
+	}
+
+	decoded, err := DeserializeHtlcs(&b)
+	if err != nil {
+		t.Fatalf("unable to deserialize htlcs: %v", err)
+	}
+
+	if !reflect.DeepEqual(htlcs, decoded) {
+		t.Fatalf("htlcs mismatch: expected %v, got %v",
+			spew.Sdump(htlcs), spew.Sdump(decoded))
+	}
+
+	// An extension can't be added to an onion blob of unexpected size, as
+	// it couldn't be told apart from the onion blob when reading it back.
+	htlcs[0].OnionBlob = []byte("onionblob")
+	if err := SerializeHtlcs(&b, htlcs...); err == nil {
+		t.Fatalf("expected serialization to fail")
+	}
+}
@@ -1,6 +1,7 @@
 package channeldb
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -15,6 +16,85 @@ import (
 	"github.com/decred/dcrlnd/shachain"
 )
 
+// extendedMsgType is a message type that is never written to the wire. It
+// prefixes stored messages that carry a TLV extension. As the extension of a
+// message is decoded by reading all of the remaining data, such messages are
+// stored with a length prefix, so that they can be followed by other data.
+const extendedMsgType = 0xffff
+
+// updateAddSize is the size of an UpdateAddHTLC message without its TLV
+// extension: channel id, htlc id, amount, payment hash, expiry and onion blob.
+const updateAddSize = 32 + 8 + 8 + 32 + 4 + lnwire.OnionPacketSize
+
+// hasExtension returns true if the encoding of the message includes a TLV
+// extension.
+func hasExtension(msg lnwire.Message) bool {
+	add, ok := msg.(*lnwire.UpdateAddHTLC)
+	return ok && add.BlindingPoint != nil
+}
+
+// writeMessage writes a lightning message to the passed writer. Messages
+// without a TLV extension are written as is, to remain compatible with the
+// existing serialization.
+func writeMessage(w io.Writer, msg lnwire.Message) error {
+	if !hasExtension(msg) {
+		_, err := lnwire.WriteMessage(w, msg, 0)
+		return err
+	}
+
+	var b bytes.Buffer
+	if _, err := lnwire.WriteMessage(&b, msg, 0); err != nil {
+		return err
+	}
+
+	var msgType [2]byte
+	byteOrder.PutUint16(msgType[:], extendedMsgType)
+	if _, err := w.Write(msgType[:]); err != nil {
+		return err
+	}
+
+	return wire.WriteVarBytes(w, 0, b.Bytes())
+}
+
+// readMessage reads a lightning message that was written with writeMessage
+// from the passed reader.
+func readMessage(r io.Reader) (lnwire.Message, error) {
+	var msgType [2]byte
+	if _, err := io.ReadFull(r, msgType[:]); err != nil {
+		return nil, err
+	}
+
+	// If the message isn't length prefixed, we'll put back the message
+	// type that we just read and decode the message from the stream. As
+	// the decoder of UpdateAddHTLC consumes all remaining data looking for
+	// an extension, we limit the reader to the size of the message in that
+	// case.
+	switch lnwire.MessageType(byteOrder.Uint16(msgType[:])) {
+	case extendedMsgType:
+		// The message is length prefixed, which we'll handle below.
+
+	case lnwire.MsgUpdateAddHTLC:
+		return lnwire.ReadMessage(io.MultiReader(
+			bytes.NewReader(msgType[:]),
+			io.LimitReader(r, updateAddSize),
+		), 0)
+
+	default:
+		return lnwire.ReadMessage(
+			io.MultiReader(bytes.NewReader(msgType[:]), r), 0,
+		)
+	}
+
+	msgBytes, err := wire.ReadVarBytes(
+		r, 0, lnwire.MaxMessagePayload+2, "message",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return lnwire.ReadMessage(bytes.NewReader(msgBytes), 0)
+}
+
 // writeOutpoint writes an outpoint to the passed writer using the minimal
 // amount of bytes possible.
 func writeOutpoint(w io.Writer, o *wire.OutPoint) error {
@@ -184,7 +264,7 @@ func WriteElement(w io.Writer, element interface{}) error {
 		}
 
 	case lnwire.Message:
-		if _, err := lnwire.WriteMessage(w, e, 0); err != nil {
+		if err := writeMessage(w, e); err != nil {
 			return err
 		}
 
@@ -400,7 +480,7 @@ func ReadElement(r io.Reader, element interface{}) error {
 		*e = bytes
 
 	case *lnwire.Message:
-		msg, err := lnwire.ReadMessage(r, 0)
+		msg, err := readMessage(r)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,390 @@
+package channeldb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// ChanLifecycle describes how far a channel has progressed through its
+// lifecycle, as far as the database knows.
+type ChanLifecycle uint8
+
+const (
+	// ChanLifecycleUnknown is the lifecycle of a channel that isn't known
+	// to the database at all.
+	ChanLifecycleUnknown ChanLifecycle = iota
+
+	// ChanLifecycleOpen is the lifecycle of a channel that is still
+	// stored among the open channels. This includes pending channels and
+	// channels waiting for their closing transaction to confirm.
+	ChanLifecycleOpen
+
+	// ChanLifecyclePendingClose is the lifecycle of a channel whose
+	// closing transaction confirmed, but whose outputs are not fully
+	// resolved yet.
+	ChanLifecyclePendingClose
+
+	// ChanLifecycleClosed is the lifecycle of a fully closed channel.
+	ChanLifecycleClosed
+)
+
+// String returns a human readable representation of the lifecycle.
+func (l ChanLifecycle) String() string {
+	switch l {
+	case ChanLifecycleUnknown:
+		return "unknown"
+	case ChanLifecycleOpen:
+		return "open"
+	case ChanLifecyclePendingClose:
+		return "pending close"
+	case ChanLifecycleClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("ChanLifecycle(%d)", uint8(l))
+	}
+}
+
+// Resolved returns true if nothing refers to the records of a channel in this
+// lifecycle anymore, as the channel is either fully closed or unknown.
+func (l ChanLifecycle) Resolved() bool {
+	return l == ChanLifecycleUnknown || l == ChanLifecycleClosed
+}
+
+// Inconsistency is an orphaned or inconsistent record found by a consistency
+// check of the database.
+type Inconsistency struct {
+	// Description describes the record and what is wrong with it.
+	Description string
+
+	// Repairable is true if the record can safely be removed from the
+	// database.
+	Repairable bool
+
+	// Repaired is true if the record was removed from the database.
+	Repaired bool
+}
+
+// String returns a human readable representation of the inconsistency.
+func (i *Inconsistency) String() string {
+	switch {
+	case i.Repaired:
+		return i.Description + " (repaired)"
+	case i.Repairable:
+		return i.Description + " (repairable)"
+	default:
+		return i.Description
+	}
+}
+
+// FetchChanLifecycles returns the lifecycle of all channels known to the
+// database, indexed by their short channel ID. Channels which were never
+// assigned a short channel ID are omitted.
+func (d *DB) FetchChanLifecycles() (map[lnwire.ShortChannelID]ChanLifecycle,
+	error) {
+
+	lifecycles := make(map[lnwire.ShortChannelID]ChanLifecycle)
+
+	closedChannels, err := d.FetchClosedChannels(false)
+	if err != nil && err != ErrNoClosedChannels {
+		return nil, err
+	}
+	for _, summary := range closedChannels {
+		if summary.ShortChanID.ToUint64() == 0 {
+			continue
+		}
+
+		lifecycle := ChanLifecycleClosed
+		if summary.IsPending {
+			lifecycle = ChanLifecyclePendingClose
+		}
+		lifecycles[summary.ShortChanID] = lifecycle
+	}
+
+	// Open channels are added last, so they take precedence over any
+	// closed channel with the same short channel ID.
+	openChannels, err := d.FetchAllChannels()
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range openChannels {
+		chanID := channel.ShortChanID()
+		if chanID.ToUint64() == 0 {
+			continue
+		}
+
+		lifecycles[chanID] = ChanLifecycleOpen
+	}
+
+	return lifecycles, nil
+}
+
+// CheckConsistency walks the forwarding packages and the revocation logs of
+// the channels in the database and returns the orphaned or inconsistent
+// records it finds. If repair is true, the records that can safely be removed
+// are removed.
+//
+// NOTE: This must not be called while any of the channels are active.
+func (d *DB) CheckConsistency(repair bool) ([]*Inconsistency, error) {
+	lifecycles, err := d.FetchChanLifecycles()
+	if err != nil {
+		return nil, err
+	}
+
+	inconsistencies, err := d.checkFwdPkgs(lifecycles, repair)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check forwarding packages: "+
+			"%v", err)
+	}
+
+	logInconsistencies, err := d.checkRevocationLogs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to check revocation logs: %v",
+			err)
+	}
+
+	return append(inconsistencies, logInconsistencies...), nil
+}
+
+// checkFwdPkgs reports the forwarding packages whose source channel is fully
+// closed or unknown. Such packages are never loaded again by a link, so they
+// are removed if repair is true.
+func (d *DB) checkFwdPkgs(lifecycles map[lnwire.ShortChannelID]ChanLifecycle,
+	repair bool) ([]*Inconsistency, error) {
+
+	var (
+		inconsistencies []*Inconsistency
+		orphanedSources [][]byte
+	)
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		fwdPkgBkt := tx.ReadBucket(fwdPackagesKey)
+		if fwdPkgBkt == nil {
+			return nil
+		}
+
+		return fwdPkgBkt.ForEach(func(k, _ []byte) error {
+			if len(k) != 8 {
+				inconsistency := &Inconsistency{
+					Description: fmt.Sprintf("invalid "+
+						"forwarding package source %x",
+						k),
+				}
+				inconsistencies = append(
+					inconsistencies, inconsistency,
+				)
+				return nil
+			}
+
+			source := lnwire.NewShortChanIDFromInt(
+				byteOrder.Uint64(k),
+			)
+			lifecycle := lifecycles[source]
+			if !lifecycle.Resolved() {
+				return nil
+			}
+
+			sourceBkt := fwdPkgBkt.NestedReadBucket(k)
+			if sourceBkt == nil {
+				return nil
+			}
+
+			var numPkgs int
+			err := sourceBkt.ForEach(func(_, _ []byte) error {
+				numPkgs++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			inconsistency := &Inconsistency{
+				Description: fmt.Sprintf("%d forwarding "+
+					"package(s) of %v channel %v", numPkgs,
+					lifecycle, source),
+				Repairable: true,
+			}
+			inconsistencies = append(inconsistencies, inconsistency)
+			orphanedSources = append(
+				orphanedSources, append([]byte(nil), k...),
+			)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !repair || len(orphanedSources) == 0 {
+		return inconsistencies, nil
+	}
+
+	err = kvdb.Update(d, func(tx kvdb.RwTx) error {
+		fwdPkgBkt := tx.ReadWriteBucket(fwdPackagesKey)
+		if fwdPkgBkt == nil {
+			return ErrCorruptedFwdPkg
+		}
+
+		for _, source := range orphanedSources {
+			err := fwdPkgBkt.DeleteNestedBucket(source)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, inconsistency := range inconsistencies {
+		if inconsistency.Repairable {
+			inconsistency.Repaired = true
+		}
+	}
+
+	return inconsistencies, nil
+}
+
+// checkRevocationLogs reports the open channels whose revocation log doesn't
+// hold exactly one entry for each revoked state of the remote commitment
+// chain. Missing entries can't be restored, so nothing is repaired.
+func (d *DB) checkRevocationLogs() ([]*Inconsistency, error) {
+	channels, err := d.FetchAllChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	var inconsistencies []*Inconsistency
+	for _, channel := range channels {
+		// Restored channels don't have any state besides what's needed
+		// to recover the funds, so they don't have a revocation log.
+		if channel.HasChanStatus(ChanStatusRestored) {
+			continue
+		}
+
+		inconsistency, err := d.checkRevocationLog(channel)
+		if err != nil {
+			return nil, fmt.Errorf("channel %v: %v",
+				channel.FundingOutpoint, err)
+		}
+		if inconsistency != nil {
+			inconsistencies = append(inconsistencies, inconsistency)
+		}
+	}
+
+	return inconsistencies, nil
+}
+
+// checkRevocationLog checks that the revocation log of the channel has an
+// entry for every height below the height of the current remote commitment,
+// and none at or above it.
+func (d *DB) checkRevocationLog(channel *OpenChannel) (*Inconsistency, error) {
+	tailHeight := channel.RemoteCommitment.CommitHeight
+
+	var (
+		numMissing    uint64
+		firstMissing  uint64
+		numUnexpected uint64
+		nextHeight    uint64
+	)
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, channel.IdentityPub, &channel.FundingOutpoint,
+			channel.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		return forEachLogHeight(chanBucket, func(height uint64) {
+			if height >= tailHeight {
+				numUnexpected++
+				return
+			}
+
+			// The heights are passed in ascending order, so any
+			// gap before this height is missing. A height stored in
+			// both formats is counted once.
+			if height < nextHeight {
+				return
+			}
+			if height > nextHeight && numMissing == 0 {
+				firstMissing = nextHeight
+			}
+			numMissing += height - nextHeight
+			nextHeight = height + 1
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tailHeight > nextHeight && numMissing == 0 {
+		firstMissing = nextHeight
+	}
+	numMissing += tailHeight - nextHeight
+
+	switch {
+	case numMissing > 0:
+		desc := fmt.Sprintf("revocation log of channel %v is missing "+
+			"%d of %d revoked states, starting at height %d",
+			channel.FundingOutpoint, numMissing, tailHeight,
+			firstMissing)
+		return &Inconsistency{Description: desc}, nil
+
+	case numUnexpected > 0:
+		desc := fmt.Sprintf("revocation log of channel %v has %d "+
+			"entries at or above the remote commit height %d",
+			channel.FundingOutpoint, numUnexpected, tailHeight)
+		return &Inconsistency{Description: desc}, nil
+	}
+
+	return nil, nil
+}
+
+// forEachLogHeight calls f with the height of every entry of the revocation
+// log of the channel in ascending order, merging the entries stored in the
+// compact format with those still stored in the former format.
+func forEachLogHeight(chanBucket kvdb.RBucket, f func(uint64)) error {
+	var cursors []kvdb.RCursor
+	for _, key := range [][]byte{
+		revocationLogBucket, revocationLogBucketDeprecated,
+	} {
+		logBucket := chanBucket.NestedReadBucket(key)
+		if logBucket != nil {
+			cursors = append(cursors, logBucket.ReadCursor())
+		}
+	}
+
+	keys := make([][]byte, len(cursors))
+	for i, cursor := range cursors {
+		keys[i], _ = cursor.First()
+	}
+
+	for {
+		// Pick the cursor positioned at the lowest height.
+		next := -1
+		for i, k := range keys {
+			if k == nil {
+				continue
+			}
+			if len(k) != 8 {
+				return fmt.Errorf("invalid revocation log key "+
+					"%x", k)
+			}
+			if next == -1 || bytes.Compare(k, keys[next]) < 0 {
+				next = i
+			}
+		}
+		if next == -1 {
+			return nil
+		}
+
+		f(byteOrder.Uint64(keys[next]))
+		keys[next], _ = cursors[next].Next()
+	}
+}
@@ -0,0 +1,192 @@
+package channeldb
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// addTestFwdPkg adds an empty forwarding package at height 0 for the given
+// source channel.
+func addTestFwdPkg(t *testing.T, cdb *DB, source lnwire.ShortChannelID) {
+	err := kvdb.Update(cdb, func(tx kvdb.RwTx) error {
+		return NewChannelPackager(source).AddFwdPkg(
+			tx, NewFwdPkg(source, 0, nil, nil),
+		)
+	})
+	require.NoError(t, err)
+}
+
+// closeTestChannel closes the channel, leaving it pending if pending is true.
+func closeTestChannel(t *testing.T, channel *OpenChannel, pending bool) {
+	err := channel.CloseChannel(&ChannelCloseSummary{
+		ChanPoint:   channel.FundingOutpoint,
+		ShortChanID: channel.ShortChanID(),
+		RemotePub:   channel.IdentityPub,
+		IsPending:   pending,
+	})
+	require.NoError(t, err)
+}
+
+// TestCheckFwdPkgs asserts that the forwarding packages of fully closed and
+// unknown channels are reported and removed on repair, while those of open
+// and pending close channels are kept.
+func TestCheckFwdPkgs(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	openChan := createTestChannel(t, cdb, openChannelOption())
+	pendingChan := createTestChannel(t, cdb, openChannelOption())
+	closedChan := createTestChannel(t, cdb, openChannelOption())
+	unknownChanID := lnwire.NewShortChanIDFromInt(1234)
+
+	for _, chanID := range []lnwire.ShortChannelID{
+		openChan.ShortChanID(), pendingChan.ShortChanID(),
+		closedChan.ShortChanID(), unknownChanID,
+	} {
+		addTestFwdPkg(t, cdb, chanID)
+	}
+
+	closeTestChannel(t, pendingChan, true)
+	closeTestChannel(t, closedChan, false)
+
+	lifecycles, err := cdb.FetchChanLifecycles()
+	require.NoError(t, err)
+	require.Equal(t, map[lnwire.ShortChannelID]ChanLifecycle{
+		openChan.ShortChanID():    ChanLifecycleOpen,
+		pendingChan.ShortChanID(): ChanLifecyclePendingClose,
+		closedChan.ShortChanID():  ChanLifecycleClosed,
+	}, lifecycles)
+
+	// Without repair, the orphaned packages are only reported.
+	inconsistencies, err := cdb.CheckConsistency(false)
+	require.NoError(t, err)
+	require.Len(t, inconsistencies, 2)
+	for _, inconsistency := range inconsistencies {
+		require.True(t, inconsistency.Repairable)
+		require.False(t, inconsistency.Repaired)
+	}
+
+	inconsistencies, err = cdb.CheckConsistency(true)
+	require.NoError(t, err)
+	require.Len(t, inconsistencies, 2)
+	for _, inconsistency := range inconsistencies {
+		require.True(t, inconsistency.Repaired)
+	}
+
+	inconsistencies, err = cdb.CheckConsistency(false)
+	require.NoError(t, err)
+	require.Empty(t, inconsistencies)
+
+	// The packages of the open and pending close channels are untouched.
+	for _, chanID := range []lnwire.ShortChannelID{
+		openChan.ShortChanID(), pendingChan.ShortChanID(),
+	} {
+		err := kvdb.View(cdb, func(tx kvdb.RTx) error {
+			fwdPkgs, err := NewChannelPackager(chanID).LoadFwdPkgs(
+				tx,
+			)
+			require.Len(t, fwdPkgs, 1)
+			return err
+		})
+		require.NoError(t, err)
+	}
+}
+
+// TestCheckRevocationLog asserts that gaps in the revocation log of a channel
+// and entries beyond its remote commit height are reported, considering the
+// entries stored in both the compact and the former format.
+func TestCheckRevocationLog(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	channel := createTestChannel(
+		t, cdb, openChannelOption(),
+		channelCommitmentOption(3, 0, 0, false),
+	)
+
+	addLogEntries := func(legacy bool, heights ...uint64) {
+		err := kvdb.Update(cdb, func(tx kvdb.RwTx) error {
+			chanBucket, err := fetchChanBucketRw(
+				tx, channel.IdentityPub,
+				&channel.FundingOutpoint, channel.ChainHash,
+			)
+			if err != nil {
+				return err
+			}
+
+			logKey := revocationLogBucket
+			if legacy {
+				logKey = revocationLogBucketDeprecated
+			}
+			logBucket, err := chanBucket.CreateBucketIfNotExists(
+				logKey,
+			)
+			if err != nil {
+				return err
+			}
+
+			for _, height := range heights {
+				commit := channel.RemoteCommitment
+				commit.CommitHeight = height
+
+				if legacy {
+					err = appendChannelLogEntry(
+						logBucket, &commit,
+					)
+				} else {
+					err = putRevocationLog(
+						logBucket, &commit, 0, 1,
+					)
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	assertInconsistency := func(desc string) {
+		t.Helper()
+
+		inconsistencies, err := cdb.CheckConsistency(true)
+		require.NoError(t, err)
+
+		if desc == "" {
+			require.Empty(t, inconsistencies)
+			return
+		}
+
+		require.Len(t, inconsistencies, 1)
+		require.Contains(t, inconsistencies[0].Description, desc)
+		require.False(t, inconsistencies[0].Repairable)
+		require.False(t, inconsistencies[0].Repaired)
+	}
+
+	assertInconsistency("missing 3 of 3 revoked states, starting at " +
+		"height 0")
+
+	addLogEntries(true, 0)
+	addLogEntries(false, 2)
+	assertInconsistency("missing 1 of 3 revoked states, starting at " +
+		"height 1")
+
+	// A state stored in both formats is only counted once.
+	addLogEntries(false, 0, 1)
+	assertInconsistency("")
+
+	addLogEntries(true, 3)
+	assertInconsistency("has 1 entries at or above the remote commit " +
+		"height 3")
+}
@@ -16,6 +16,9 @@ import (
 	"github.com/decred/dcrlnd/channeldb/migration12"
 	"github.com/decred/dcrlnd/channeldb/migration13"
 	"github.com/decred/dcrlnd/channeldb/migration16"
+	"github.com/decred/dcrlnd/channeldb/migration18"
+	"github.com/decred/dcrlnd/channeldb/migration19"
+	"github.com/decred/dcrlnd/channeldb/migration20"
 	"github.com/decred/dcrlnd/channeldb/migration_01_to_11"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lnwire"
@@ -164,6 +167,17 @@ var (
 			number:    17,
 			migration: mig.CreateTLB(closeSummaryBucket),
 		},
+		{
+			// Create the indexes of invoices and payments by their
+			// creation and settle times.
+			number:    18,
+			migration: migration18.MigrateTimeIndexes,
+		},
+		{
+			// Create the index of the forwarding log by channel.
+			number:    19,
+			migration: migration19.MigrateForwardingLogChanIndex,
+		},
 	}
 
 	// Big endian is the preferred byte order, due to cursor scans over
@@ -262,6 +276,17 @@ func CreateWithBackend(backend kvdb.Backend, modifiers ...OptionModifier) (*DB,
 		return nil, err
 	}
 
+	// The conversion of the revocation logs is optional, as it may take a
+	// while for busy channels.
+	if opts.pruneRevocationLog && !opts.dryRun {
+		err := migration20.MigrateRevocationLog(backend)
+		if err != nil {
+			backend.Close()
+			return nil, fmt.Errorf("unable to prune revocation "+
+				"logs: %v", err)
+		}
+	}
+
 	return chanDB, nil
 }
 
@@ -274,10 +299,13 @@ var topLevelBuckets = [][]byte{
 	openChannelBucket,
 	closedChannelBucket,
 	forwardingLogBucket,
+	forwardingLogChanIndexBucket,
 	fwdPackagesKey,
 	invoiceBucket,
 	payAddrIndexBucket,
 	paymentsIndexBucket,
+	paymentsCreationTimeIndexBucket,
+	paymentsSettleTimeIndexBucket,
 	nodeInfoBucket,
 	nodeBucket,
 	edgeBucket,
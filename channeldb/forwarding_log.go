@@ -18,6 +18,14 @@ var (
 	// bucket is a timestamp (in nano seconds since the unix epoch), and
 	// the value a slice of a forwarding event for that timestamp.
 	forwardingLogBucket = []byte("circuit-fwd-log")
+
+	// forwardingLogChanIndexBucket is the bucket that indexes the
+	// forwarding log by channel. Each event has an entry for both its
+	// incoming and its outgoing channel, which allows querying the events
+	// of a channel without scanning the whole log.
+	//
+	// maps: chanID || timestamp => nil
+	forwardingLogChanIndexBucket = []byte("circuit-fwd-log-chan-index")
 )
 
 const (
@@ -121,10 +129,19 @@ func (f *ForwardingLog) AddForwardingEvents(events []ForwardingEvent) error {
 			return err
 		}
 
+		chanIndex, err := tx.CreateTopLevelBucket(
+			forwardingLogChanIndexBucket,
+		)
+		if err != nil {
+			return err
+		}
+
 		// With the bucket obtained, we can now begin to write out the
 		// series of events.
 		for _, event := range events {
-			err := storeEvent(logBucket, event, timestamp[:])
+			err := storeEvent(
+				logBucket, chanIndex, event, timestamp[:],
+			)
 			if err != nil {
 				return err
 			}
@@ -137,9 +154,9 @@ func (f *ForwardingLog) AddForwardingEvents(events []ForwardingEvent) error {
 // storeEvent tries to store a forwarding event into the given bucket by trying
 // to avoid collisions. If a key for the event timestamp already exists in the
 // database, the timestamp is incremented in nanosecond intervals until a "free"
-// slot is found.
-func storeEvent(bucket walletdb.ReadWriteBucket, event ForwardingEvent,
-	timestampScratchSpace []byte) error {
+// slot is found. The event is then added to the channel index.
+func storeEvent(bucket, chanIndex walletdb.ReadWriteBucket,
+	event ForwardingEvent, timestampScratchSpace []byte) error {
 
 	// First, we'll serialize this timestamp into our
 	// timestamp buffer.
@@ -180,7 +197,31 @@ func storeEvent(bucket walletdb.ReadWriteBucket, event ForwardingEvent,
 	if err != nil {
 		return err
 	}
-	return bucket.Put(timestampScratchSpace, eventBuf.Bytes())
+	err = bucket.Put(timestampScratchSpace, eventBuf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	for _, chanID := range []lnwire.ShortChannelID{
+		event.IncomingChanID, event.OutgoingChanID,
+	} {
+		key := chanIndexKey(chanID, timestampScratchSpace)
+		if err := chanIndex.Put(key, []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chanIndexKey returns the key of the channel index entry of an event with
+// the given serialized timestamp.
+func chanIndexKey(chanID lnwire.ShortChannelID, timestamp []byte) []byte {
+	var key [16]byte
+	byteOrder.PutUint64(key[:8], chanID.ToUint64())
+	copy(key[8:], timestamp)
+
+	return key[:]
 }
 
 // ForwardingEventQuery represents a query to the forwarding log payment
@@ -200,6 +241,57 @@ type ForwardingEventQuery struct {
 
 	// NumMaxEvents is the max number of events to return.
 	NumMaxEvents uint32
+
+	// IncomingChanIDs, if non-empty, restricts the query to the events
+	// whose incoming channel is one of the given channels.
+	IncomingChanIDs []lnwire.ShortChannelID
+
+	// OutgoingChanIDs, if non-empty, restricts the query to the events
+	// whose outgoing channel is one of the given channels.
+	OutgoingChanIDs []lnwire.ShortChannelID
+
+	// IncludeTotals, if set, sums up all events that match the query,
+	// irrespective of the index offset and max number of events.
+	IncludeTotals bool
+}
+
+// ForwardingTotals sums up a set of forwarding events.
+type ForwardingTotals struct {
+	// NumEvents is the number of events.
+	NumEvents uint64
+
+	// AmtIn is the total amount of the incoming HTLCs.
+	AmtIn lnwire.MilliAtom
+
+	// AmtOut is the total amount of the outgoing HTLCs.
+	AmtOut lnwire.MilliAtom
+}
+
+// Fees returns the total fees earned with the events.
+func (t *ForwardingTotals) Fees() lnwire.MilliAtom {
+	return t.AmtIn - t.AmtOut
+}
+
+// matches returns true if the event passes the channel filters of the query.
+func (q *ForwardingEventQuery) matches(event *ForwardingEvent) bool {
+	contains := func(chanIDs []lnwire.ShortChannelID,
+		chanID lnwire.ShortChannelID) bool {
+
+		if len(chanIDs) == 0 {
+			return true
+		}
+
+		for _, c := range chanIDs {
+			if c == chanID {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return contains(q.IncomingChanIDs, event.IncomingChanID) &&
+		contains(q.OutgoingChanIDs, event.OutgoingChanID)
 }
 
 // ForwardingLogTimeSlice is the response to a forwarding query. It includes
@@ -220,6 +312,10 @@ type ForwardingLogTimeSlice struct {
 	// their query in the event that the time slice has too many events to
 	// fit into a single response.
 	LastIndexOffset uint32
+
+	// Totals sums up all events that match the query. It is only set if
+	// the query requested it.
+	Totals *ForwardingTotals
 }
 
 // Query allows a caller to query the forwarding event time series for a
@@ -238,6 +334,41 @@ func (f *ForwardingLog) Query(q ForwardingEventQuery) (ForwardingLogTimeSlice, e
 	recordsToSkip := q.IndexOffset
 	recordOffset := q.IndexOffset
 
+	var totals ForwardingTotals
+
+	// addEvent adds a matching event to the response, and returns false
+	// once no more events are needed.
+	addEvent := func(event ForwardingEvent) bool {
+		if q.IncludeTotals {
+			totals.NumEvents++
+			totals.AmtIn += event.AmtIn
+			totals.AmtOut += event.AmtOut
+		}
+
+		// If our current return payload exceeds the max number of
+		// events, then we only continue if we're summing up the
+		// events.
+		if uint32(len(resp.ForwardingEvents)) >= q.NumMaxEvents {
+			return q.IncludeTotals
+		}
+
+		// If we're not yet past the user defined offset, then we'll
+		// continue to seek forward.
+		if recordsToSkip > 0 {
+			recordsToSkip--
+			return true
+		}
+
+		// At this point, we've skipped enough records to start to
+		// collate our query. For each record, we'll increment the
+		// final record offset so the querier can utilize pagination
+		// to seek further.
+		resp.ForwardingEvents = append(resp.ForwardingEvents, event)
+		recordOffset++
+
+		return true
+	}
+
 	err := kvdb.View(f.db, func(tx kvdb.RTx) error {
 		// If the bucket wasn't found, then there aren't any events to
 		// be returned.
@@ -253,46 +384,82 @@ func (f *ForwardingLog) Query(q ForwardingEventQuery) (ForwardingLogTimeSlice, e
 		byteOrder.PutUint64(startTime[:], uint64(q.StartTime.UnixNano()))
 		byteOrder.PutUint64(endTime[:], uint64(q.EndTime.UnixNano()))
 
-		// If we know that a set of log events exists, then we'll begin
-		// our seek through the log in order to satisfy the query.
-		// We'll continue until either we reach the end of the range,
-		// or reach our max number of events.
-		logCursor := logBucket.ReadCursor()
-		timestamp, events := logCursor.Seek(startTime[:])
-		for ; timestamp != nil && bytes.Compare(timestamp, endTime[:]) <= 0; timestamp, events = logCursor.Next() {
-			// If our current return payload exceeds the max number
-			// of events, then we'll exit now.
-			if uint32(len(resp.ForwardingEvents)) >= q.NumMaxEvents {
-				return nil
+		// If the query is restricted to a set of channels, we'll only
+		// visit the timestamps of their events.
+		var timestamps [][]byte
+		filtered := len(q.IncomingChanIDs) != 0 ||
+			len(q.OutgoingChanIDs) != 0
+		if filtered {
+			chanIndex := tx.ReadBucket(forwardingLogChanIndexBucket)
+			if chanIndex == nil {
+				return ErrNoForwardingEvents
 			}
 
-			// If we're not yet past the user defined offset, then
-			// we'll continue to seek forward.
-			if recordsToSkip > 0 {
-				recordsToSkip--
-				continue
+			chanIDs := q.IncomingChanIDs
+			if len(chanIDs) == 0 {
+				chanIDs = q.OutgoingChanIDs
 			}
 
+			timestamps = chanTimestamps(
+				chanIndex, chanIDs, startTime[:], endTime[:],
+			)
+		}
+
+		// visitEvents decodes the events stored under a timestamp and
+		// adds the matching ones to the response. It returns false
+		// once no more events are needed.
+		visitEvents := func(timestamp, events []byte) (bool, error) {
 			currentTime := time.Unix(
 				0, int64(byteOrder.Uint64(timestamp)),
 			)
 
-			// At this point, we've skipped enough records to start
-			// to collate our query. For each record, we'll
-			// increment the final record offset so the querier can
-			// utilize pagination to seek further.
 			readBuf := bytes.NewReader(events)
 			for readBuf.Len() != 0 {
 				var event ForwardingEvent
 				err := decodeForwardingEvent(readBuf, &event)
 				if err != nil {
-					return err
+					return false, err
+				}
+
+				if !q.matches(&event) {
+					continue
 				}
 
 				event.Timestamp = currentTime
-				resp.ForwardingEvents = append(resp.ForwardingEvents, event)
+				if !addEvent(event) {
+					return false, nil
+				}
+			}
+
+			return true, nil
+		}
 
-				recordOffset++
+		if filtered {
+			for _, timestamp := range timestamps {
+				events := logBucket.Get(timestamp)
+				if events == nil {
+					continue
+				}
+
+				more, err := visitEvents(timestamp, events)
+				if err != nil || !more {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		// If we know that a set of log events exists, then we'll begin
+		// our seek through the log in order to satisfy the query.
+		// We'll continue until either we reach the end of the range,
+		// or reach our max number of events.
+		logCursor := logBucket.ReadCursor()
+		timestamp, events := logCursor.Seek(startTime[:])
+		for ; timestamp != nil && bytes.Compare(timestamp, endTime[:]) <= 0; timestamp, events = logCursor.Next() {
+			more, err := visitEvents(timestamp, events)
+			if err != nil || !more {
+				return err
 			}
 		}
 
@@ -302,11 +469,57 @@ func (f *ForwardingLog) Query(q ForwardingEventQuery) (ForwardingLogTimeSlice, e
 		return ForwardingLogTimeSlice{}, err
 	}
 
+	if q.IncludeTotals {
+		resp.Totals = &totals
+	}
+
 	resp.LastIndexOffset = recordOffset
 
 	return resp, nil
 }
 
+// chanTimestamps returns the sorted and unique timestamps of the events of the
+// given channels that fall within the range of the serialized start and end
+// times.
+func chanTimestamps(chanIndex kvdb.RBucket, chanIDs []lnwire.ShortChannelID,
+	startTime, endTime []byte) [][]byte {
+
+	var timestamps [][]byte
+	cursor := chanIndex.ReadCursor()
+	for _, chanID := range chanIDs {
+		var prefix [8]byte
+		byteOrder.PutUint64(prefix[:], chanID.ToUint64())
+
+		k, _ := cursor.Seek(chanIndexKey(chanID, startTime))
+		for ; k != nil; k, _ = cursor.Next() {
+			timestamp := k[8:]
+			if !bytes.HasPrefix(k, prefix[:]) ||
+				bytes.Compare(timestamp, endTime) > 0 {
+
+				break
+			}
+
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool {
+		return bytes.Compare(timestamps[i], timestamps[j]) < 0
+	})
+
+	// The incoming and outgoing channel of an event may both be part of
+	// the set, so we remove duplicates.
+	unique := timestamps[:0]
+	for i, timestamp := range timestamps {
+		if i > 0 && bytes.Equal(timestamp, timestamps[i-1]) {
+			continue
+		}
+		unique = append(unique, timestamp)
+	}
+
+	return unique
+}
+
 // makeUniqueTimestamps takes a slice of forwarding events, sorts it by the
 // event timestamps and then makes sure there are no duplicates in the
 // timestamps. If duplicates are found, some of the timestamps are increased on
@@ -9,6 +9,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestForwardingLogBasicStorageAndQuery tests that we're able to store and
@@ -381,3 +382,151 @@ func TestForwardingLogStoreEvent(t *testing.T) {
 		}
 	}
 }
+
+// TestForwardingLogQueryChannels tests that the events can be filtered by
+// their incoming and outgoing channels, and that the totals of all matching
+// events are returned if requested.
+func TestForwardingLogQueryChannels(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	log := ForwardingLog{
+		db: db,
+	}
+
+	chan1 := lnwire.NewShortChanIDFromInt(1)
+	chan2 := lnwire.NewShortChanIDFromInt(2)
+	chan3 := lnwire.NewShortChanIDFromInt(3)
+
+	// We'll create 30 events which cycle through forwarding from the
+	// first to the second channel, from the second to the third channel
+	// and from the third to the first channel. Each event earns a fee of
+	// 10 milli-atoms.
+	routes := [][2]lnwire.ShortChannelID{
+		{chan1, chan2}, {chan2, chan3}, {chan3, chan1},
+	}
+	initialTime := time.Unix(1234, 0)
+	timestamp := initialTime
+	numEvents := 30
+	events := make([]ForwardingEvent, numEvents)
+	for i := 0; i < numEvents; i++ {
+		route := routes[i%len(routes)]
+		events[i] = ForwardingEvent{
+			Timestamp:      timestamp,
+			IncomingChanID: route[0],
+			OutgoingChanID: route[1],
+			AmtIn:          lnwire.MilliAtom(1000 + i),
+			AmtOut:         lnwire.MilliAtom(990 + i),
+		}
+
+		timestamp = timestamp.Add(time.Minute)
+	}
+	require.NoError(t, log.AddForwardingEvents(events))
+
+	// filter returns the events that match the given route, where an
+	// empty channel ID matches any channel.
+	filter := func(in, out lnwire.ShortChannelID) []ForwardingEvent {
+		var matches []ForwardingEvent
+		for _, event := range events {
+			if in.ToUint64() != 0 && event.IncomingChanID != in {
+				continue
+			}
+			if out.ToUint64() != 0 && event.OutgoingChanID != out {
+				continue
+			}
+			matches = append(matches, event)
+		}
+
+		return matches
+	}
+
+	// Querying the events of the second incoming channel should only
+	// return the events forwarded to the third channel.
+	timeSlice, err := log.Query(ForwardingEventQuery{
+		StartTime:       initialTime,
+		EndTime:         timestamp,
+		NumMaxEvents:    100,
+		IncomingChanIDs: []lnwire.ShortChannelID{chan2},
+	})
+	require.NoError(t, err)
+	require.Equal(t, filter(chan2, lnwire.ShortChannelID{}),
+		timeSlice.ForwardingEvents)
+	require.Equal(t, uint32(10), timeSlice.LastIndexOffset)
+	require.Nil(t, timeSlice.Totals)
+
+	// Events that match neither filter are excluded when both sides are
+	// restricted.
+	timeSlice, err = log.Query(ForwardingEventQuery{
+		StartTime:       initialTime,
+		EndTime:         timestamp,
+		NumMaxEvents:    100,
+		IncomingChanIDs: []lnwire.ShortChannelID{chan1, chan3},
+		OutgoingChanIDs: []lnwire.ShortChannelID{chan1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, filter(chan3, chan1), timeSlice.ForwardingEvents)
+
+	// Paginating through the events of the first incoming channel should
+	// return all of them, while the totals always sum up all matching
+	// events within the time range.
+	expected := filter(chan1, lnwire.ShortChannelID{})
+	var (
+		paginated []ForwardingEvent
+		offset    uint32
+	)
+	for {
+		timeSlice, err = log.Query(ForwardingEventQuery{
+			StartTime:       initialTime,
+			EndTime:         timestamp,
+			IndexOffset:     offset,
+			NumMaxEvents:    3,
+			IncomingChanIDs: []lnwire.ShortChannelID{chan1},
+			IncludeTotals:   true,
+		})
+		require.NoError(t, err)
+		// The indexes of the matching events are 0, 3, ..., 27,
+		// which sum up to 135.
+		require.Equal(t, &ForwardingTotals{
+			NumEvents: 10,
+			AmtIn:     10*1000 + 135,
+			AmtOut:    10*990 + 135,
+		}, timeSlice.Totals)
+		require.Equal(t, lnwire.MilliAtom(100), timeSlice.Totals.Fees())
+
+		if len(timeSlice.ForwardingEvents) == 0 {
+			break
+		}
+		paginated = append(paginated, timeSlice.ForwardingEvents...)
+		offset = timeSlice.LastIndexOffset
+	}
+	require.Equal(t, expected, paginated)
+
+	// The time range still applies to filtered queries.
+	timeSlice, err = log.Query(ForwardingEventQuery{
+		StartTime:       initialTime.Add(time.Minute),
+		EndTime:         initialTime.Add(5 * time.Minute),
+		NumMaxEvents:    100,
+		OutgoingChanIDs: []lnwire.ShortChannelID{chan2, chan3},
+		IncludeTotals:   true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ForwardingEvent{events[1], events[3], events[4]},
+		timeSlice.ForwardingEvents)
+	require.Equal(t, uint64(3), timeSlice.Totals.NumEvents)
+
+	// Unknown channels don't match any events.
+	timeSlice, err = log.Query(ForwardingEventQuery{
+		StartTime:       initialTime,
+		EndTime:         timestamp,
+		NumMaxEvents:    100,
+		IncomingChanIDs: []lnwire.ShortChannelID{chan1},
+		OutgoingChanIDs: []lnwire.ShortChannelID{chan3},
+		IncludeTotals:   true,
+	})
+	require.NoError(t, err)
+	require.Empty(t, timeSlice.ForwardingEvents)
+	require.Equal(t, &ForwardingTotals{}, timeSlice.Totals)
+}
@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/record"
@@ -1176,6 +1178,248 @@ func TestCustomRecords(t *testing.T) {
 	)
 }
 
+// TestAMPInvoiceSettle asserts that AMP htlcs are persisted along with their
+// AMP data, and that the htlc-level preimages are stored when settling an AMP
+// invoice referenced only by its payment address.
+func TestAMPInvoiceSettle(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	defer cleanUp()
+	require.NoError(t, err, "unable to make test db")
+
+	var (
+		setID   = SetID{0x01}
+		payAddr = [32]byte{0x02}
+	)
+
+	ampFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(
+			lnwire.TLVOnionPayloadOptional,
+			lnwire.PaymentAddrOptional,
+			lnwire.MPPOptional,
+			lnwire.AMPRequired,
+		), lnwire.Features,
+	)
+
+	// AMP invoices don't carry a preimage, and are indexed by their set
+	// id in place of a payment hash.
+	testInvoice := &Invoice{
+		Htlcs: map[CircuitKey]*InvoiceHTLC{},
+		Terms: ContractTerm{
+			Value:       lnwire.NewMAtomsFromAtoms(10000),
+			Features:    ampFeatures,
+			PaymentAddr: payAddr,
+		},
+	}
+	require.True(t, testInvoice.IsAMP())
+
+	_, err = db.AddInvoice(testInvoice, lntypes.Hash(setID))
+	require.NoError(t, err, "unable to add invoice")
+
+	// Accept two AMP htlcs, each carrying its own payment hash.
+	var (
+		key1      = CircuitKey{ChanID: lnwire.NewShortChanIDFromInt(1)}
+		key2      = CircuitKey{ChanID: lnwire.NewShortChanIDFromInt(2)}
+		preimage1 = lntypes.Preimage{0x11}
+		preimage2 = lntypes.Preimage{0x22}
+	)
+
+	ampData := func(preimage lntypes.Preimage,
+		idx uint16) *InvoiceHtlcAMPData {
+
+		return &InvoiceHtlcAMPData{
+			Record: *record.NewAMP([32]byte{0x33}, setID, idx),
+			Hash:   preimage.Hash(),
+		}
+	}
+
+	ref := InvoiceRefByAddr(payAddr)
+	_, err = db.UpdateInvoice(ref,
+		func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+			return &InvoiceUpdateDesc{
+				AddHtlcs: map[CircuitKey]*HtlcAcceptDesc{
+					key1: {
+						Amt:           5000000,
+						MppTotalAmt:   10000000,
+						CustomRecords: make(record.CustomSet),
+						AMP:           ampData(preimage1, 0),
+					},
+				},
+			}, nil
+		},
+	)
+	require.NoError(t, err, "unable to add invoice htlc")
+
+	// Settling with a preimage that doesn't match the htlc's hash must
+	// fail.
+	_, err = db.UpdateInvoice(ref,
+		func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+			return &InvoiceUpdateDesc{
+				State: &InvoiceStateUpdateDesc{
+					NewState: ContractSettled,
+					HTLCPreimages: map[CircuitKey]lntypes.Preimage{
+						key1: preimage2,
+					},
+					SetID: &setID,
+				},
+			}, nil
+		},
+	)
+	require.Equal(t, ErrHTLCPreimageMismatch, err)
+
+	// Add the second htlc and settle the set in the same update.
+	dbInvoice, err := db.UpdateInvoice(ref,
+		func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+			return &InvoiceUpdateDesc{
+				AddHtlcs: map[CircuitKey]*HtlcAcceptDesc{
+					key2: {
+						Amt:           5000000,
+						MppTotalAmt:   10000000,
+						CustomRecords: make(record.CustomSet),
+						AMP:           ampData(preimage2, 1),
+					},
+				},
+				State: &InvoiceStateUpdateDesc{
+					NewState: ContractSettled,
+					HTLCPreimages: map[CircuitKey]lntypes.Preimage{
+						key1: preimage1,
+						key2: preimage2,
+					},
+					SetID: &setID,
+				},
+			}, nil
+		},
+	)
+	require.NoError(t, err, "unable to settle amp set")
+
+	// AMP invoices remain open after a set is settled, the settlement is
+	// recorded as a sub-settlement of the set instead.
+	require.Equal(t, ContractOpen, dbInvoice.State)
+	require.Equal(t, lnwire.MilliAtom(10000000), dbInvoice.AmtPaid)
+	require.Len(t, dbInvoice.AMPState, 1)
+	require.Equal(t, HtlcStateSettled, dbInvoice.AMPState[setID].State)
+	require.Equal(
+		t, lnwire.MilliAtom(10000000), dbInvoice.AMPState[setID].AmtPaid,
+	)
+
+	// Settling the same set a second time must fail.
+	_, err = db.UpdateInvoice(ref,
+		func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+			return &InvoiceUpdateDesc{
+				State: &InvoiceStateUpdateDesc{
+					NewState: ContractSettled,
+					SetID:    &setID,
+				},
+			}, nil
+		},
+	)
+	require.Equal(t, ErrAMPSetAlreadySettled, err)
+
+	// Finally, retrieve the invoice from disk and assert that the AMP
+	// data of both htlcs was persisted, including their preimages.
+	dbInvoice2, err := db.LookupInvoice(ref)
+	require.NoError(t, err, "unable to lookup invoice")
+	require.Len(t, dbInvoice2.Htlcs, 2)
+
+	for key, preimage := range map[CircuitKey]lntypes.Preimage{
+		key1: preimage1,
+		key2: preimage2,
+	} {
+		htlc := dbInvoice2.Htlcs[key]
+		require.Equal(t, HtlcStateSettled, htlc.State)
+		require.NotNil(t, htlc.AMP)
+		require.Equal(t, setID, SetID(htlc.AMP.Record.SetID()))
+		require.Equal(t, preimage.Hash(), htlc.AMP.Hash)
+		require.Equal(t, &preimage, htlc.AMP.Preimage)
+	}
+	require.Len(t, dbInvoice2.AMPState, 1)
+	require.Equal(
+		t, dbInvoice.AMPState[setID].AmtPaid,
+		dbInvoice2.AMPState[setID].AmtPaid,
+	)
+	require.Equal(
+		t, dbInvoice.AMPState[setID].SettleDate.UnixNano(),
+		dbInvoice2.AMPState[setID].SettleDate.UnixNano(),
+	)
+
+	// The invoice can be paid again using a different set id, which is
+	// settled independently of the first one.
+	var (
+		setID2    = SetID{0x04}
+		key3      = CircuitKey{ChanID: lnwire.NewShortChanIDFromInt(3)}
+		preimage3 = lntypes.Preimage{0x44}
+	)
+	dbInvoice3, err := db.UpdateInvoice(ref,
+		func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+			return &InvoiceUpdateDesc{
+				AddHtlcs: map[CircuitKey]*HtlcAcceptDesc{
+					key3: {
+						Amt:           10000000,
+						MppTotalAmt:   10000000,
+						CustomRecords: make(record.CustomSet),
+						AMP: &InvoiceHtlcAMPData{
+							Record: *record.NewAMP(
+								[32]byte{0x33}, setID2, 0,
+							),
+							Hash: preimage3.Hash(),
+						},
+					},
+				},
+				State: &InvoiceStateUpdateDesc{
+					NewState: ContractSettled,
+					HTLCPreimages: map[CircuitKey]lntypes.Preimage{
+						key3: preimage3,
+					},
+					SetID: &setID2,
+				},
+			}, nil
+		},
+	)
+	require.NoError(t, err, "unable to settle second amp set")
+	require.Equal(t, ContractOpen, dbInvoice3.State)
+	require.Equal(t, lnwire.MilliAtom(20000000), dbInvoice3.AmtPaid)
+	require.Len(t, dbInvoice3.AMPState, 2)
+	require.Equal(
+		t, lnwire.MilliAtom(10000000), dbInvoice3.AMPState[setID2].AmtPaid,
+	)
+
+	// Each settled set has its own settle index, the invoice carries the
+	// one of the latest set.
+	require.Equal(t, uint64(1), dbInvoice3.AMPState[setID].SettleIndex)
+	require.Equal(t, uint64(2), dbInvoice3.AMPState[setID2].SettleIndex)
+	require.Equal(t, uint64(2), dbInvoice3.SettleIndex)
+
+	// Both sub-settlements are reported as settle events, each with the
+	// settle index of its set.
+	settled, err := db.InvoicesSettledSince(1)
+	require.NoError(t, err)
+	require.Len(t, settled, 1)
+	require.Equal(t, uint64(2), settled[0].SettleIndex)
+
+	settled, err = db.InvoicesSettledSince(0)
+	require.NoError(t, err)
+	require.Empty(t, settled)
+
+	// Once the invoice is canceled and deleted, the settle index entries
+	// of its sets are removed as well.
+	_, err = db.UpdateInvoice(ref,
+		func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+			return &InvoiceUpdateDesc{
+				State: &InvoiceStateUpdateDesc{
+					NewState: ContractCanceled,
+				},
+			}, nil
+		},
+	)
+	require.NoError(t, err, "unable to cancel invoice")
+	require.NoError(t, db.DeleteInvoice(ref))
+
+	settled, err = db.InvoicesSettledSince(1)
+	require.NoError(t, err)
+	require.Empty(t, settled)
+}
+
 // TestInvoiceRef asserts that the proper identifiers are returned from an
 // InvoiceRef depending on the constructor used.
 func TestInvoiceRef(t *testing.T) {
@@ -1185,12 +1429,253 @@ func TestInvoiceRef(t *testing.T) {
 	// An InvoiceRef by hash should return the provided hash and a nil
 	// payment addr.
 	refByHash := InvoiceRefByHash(payHash)
-	require.Equal(t, payHash, refByHash.PayHash())
+	require.Equal(t, &payHash, refByHash.PayHash())
 	require.Equal(t, (*[32]byte)(nil), refByHash.PayAddr())
 
 	// An InvoiceRef by hash and addr should return the payment hash and
 	// payment addr passed to the constructor.
 	refByHashAndAddr := InvoiceRefByHashAndAddr(payHash, payAddr)
-	require.Equal(t, payHash, refByHashAndAddr.PayHash())
+	require.Equal(t, &payHash, refByHashAndAddr.PayHash())
 	require.Equal(t, &payAddr, refByHashAndAddr.PayAddr())
+
+	// An InvoiceRef by addr should return the payment addr passed to the
+	// constructor and a nil payment hash.
+	refByAddr := InvoiceRefByAddr(payAddr)
+	require.Equal(t, (*lntypes.Hash)(nil), refByAddr.PayHash())
+	require.Equal(t, &payAddr, refByAddr.PayAddr())
+}
+
+// TestDeleteInvoice tests that settled and canceled invoices can be deleted,
+// and that resuming from the add and settle indexes of deleted invoices still
+// returns the invoices that were added or settled later.
+func TestDeleteInvoice(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	defer cleanUp()
+	require.NoError(t, err)
+
+	cancelInvoice := func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+		return &InvoiceUpdateDesc{
+			State: &InvoiceStateUpdateDesc{
+				NewState: ContractCanceled,
+			},
+		}, nil
+	}
+
+	// Add four invoices, of which the first one is settled, the second
+	// and third ones are canceled and the last one stays open.
+	amt := lnwire.NewMAtomsFromAtoms(1000)
+	var (
+		hashes   []lntypes.Hash
+		payAddrs [][32]byte
+	)
+	for i := 0; i < 4; i++ {
+		invoice, err := randInvoice(amt)
+		require.NoError(t, err)
+
+		// The third invoice is created much later than the others.
+		if i == 2 {
+			invoice.CreationDate = testNow.Add(time.Hour)
+		}
+
+		hash := invoice.Terms.PaymentPreimage.Hash()
+		_, err = db.AddInvoice(invoice, hash)
+		require.NoError(t, err)
+
+		hashes = append(hashes, hash)
+		payAddrs = append(payAddrs, invoice.Terms.PaymentAddr)
+	}
+
+	ref := InvoiceRefByHash(hashes[0])
+	_, err = db.UpdateInvoice(ref, getUpdateInvoice(amt))
+	require.NoError(t, err)
+
+	for _, hash := range hashes[1:3] {
+		ref := InvoiceRefByHash(hash)
+		_, err = db.UpdateInvoice(ref, cancelInvoice)
+		require.NoError(t, err)
+	}
+
+	// Open invoices can't be deleted.
+	err = db.DeleteInvoice(InvoiceRefByHash(hashes[3]))
+	require.Equal(t, ErrInvoicePending, err)
+
+	// Only the canceled invoice that was created before the cutoff is
+	// garbage collected.
+	numDeleted, err := db.DeleteCanceledInvoices(testNow.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, numDeleted)
+
+	_, err = db.LookupInvoice(InvoiceRefByHash(hashes[1]))
+	require.Equal(t, ErrInvoiceNotFound, err)
+	_, err = db.LookupInvoice(InvoiceRefByAddr(payAddrs[1]))
+	require.Equal(t, ErrInvoiceNotFound, err)
+
+	// Resuming from the add index of the deleted invoice returns the
+	// invoices added after it.
+	added, err := db.InvoicesAddedSince(2)
+	require.NoError(t, err)
+	require.Len(t, added, 2)
+	require.Equal(t, uint64(3), added[0].AddIndex)
+	require.Equal(t, uint64(4), added[1].AddIndex)
+
+	// Delete the settled invoice, after which there are no settled
+	// invoices left.
+	require.NoError(t, db.DeleteInvoice(InvoiceRefByHash(hashes[0])))
+
+	_, err = db.LookupInvoice(InvoiceRefByHash(hashes[0]))
+	require.Equal(t, ErrInvoiceNotFound, err)
+
+	err = db.DeleteInvoice(InvoiceRefByHash(hashes[0]))
+	require.Equal(t, ErrInvoiceNotFound, err)
+
+	// Settling a new invoice assigns it the next settle index, which is
+	// returned when resuming from the index of the deleted invoice.
+	ref = InvoiceRefByHash(hashes[3])
+	_, err = db.UpdateInvoice(ref, getUpdateInvoice(amt))
+	require.NoError(t, err)
+
+	settled, err := db.InvoicesSettledSince(1)
+	require.NoError(t, err)
+	require.Len(t, settled, 1)
+	require.Equal(t, uint64(2), settled[0].SettleIndex)
+
+	// New invoices don't reuse the add index of deleted invoices.
+	invoice, err := randInvoice(amt)
+	require.NoError(t, err)
+	addIndex, err := db.AddInvoice(
+		invoice, invoice.Terms.PaymentPreimage.Hash(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), addIndex)
+}
+
+// TestQueryInvoicesFilters tests that invoices can be queried by their creation
+// time, settle time and state, and that deleted invoices are removed from the
+// time indexes.
+func TestQueryInvoicesFilters(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(testNow)
+	db, cleanUp, err := MakeTestDB(OptionClock(testClock))
+	defer cleanUp()
+	require.NoError(t, err)
+
+	// Add three invoices that are created an hour apart. The first one is
+	// settled a day later, the second one is canceled and the third one
+	// stays open.
+	amt := lnwire.NewMAtomsFromAtoms(1000)
+	var hashes []lntypes.Hash
+	for i := 0; i < 3; i++ {
+		invoice, err := randInvoice(amt)
+		require.NoError(t, err)
+		invoice.CreationDate = testNow.Add(time.Duration(i) * time.Hour)
+
+		hash := invoice.Terms.PaymentPreimage.Hash()
+		_, err = db.AddInvoice(invoice, hash)
+		require.NoError(t, err)
+
+		hashes = append(hashes, hash)
+	}
+
+	settleTime := testNow.Add(24 * time.Hour)
+	testClock.SetTime(settleTime)
+	_, err = db.UpdateInvoice(
+		InvoiceRefByHash(hashes[0]), getUpdateInvoice(amt),
+	)
+	require.NoError(t, err)
+
+	_, err = db.UpdateInvoice(InvoiceRefByHash(hashes[1]),
+		func(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+			return &InvoiceUpdateDesc{
+				State: &InvoiceStateUpdateDesc{
+					NewState: ContractCanceled,
+				},
+			}, nil
+		},
+	)
+	require.NoError(t, err)
+
+	assertQuery := func(q InvoiceQuery, expected ...int) {
+		t.Helper()
+
+		q.NumMaxInvoices = math.MaxUint64
+		resp, err := db.QueryInvoices(q)
+		require.NoError(t, err)
+		require.Len(t, resp.Invoices, len(expected))
+
+		for i, invoice := range resp.Invoices {
+			require.Equal(
+				t, hashes[expected[i]],
+				invoice.Terms.PaymentPreimage.Hash(),
+			)
+		}
+	}
+
+	// Both ends of the creation time range are inclusive.
+	assertQuery(InvoiceQuery{
+		CreationDateStart: testNow.Add(time.Hour),
+		CreationDateEnd:   testNow.Add(2 * time.Hour),
+	}, 1, 2)
+	assertQuery(InvoiceQuery{
+		CreationDateEnd: testNow.Add(time.Hour),
+	}, 0, 1)
+
+	// Only the settled invoice has a settle time.
+	assertQuery(InvoiceQuery{
+		SettleDateStart: settleTime,
+		SettleDateEnd:   settleTime,
+	}, 0)
+	assertQuery(InvoiceQuery{
+		CreationDateStart: testNow.Add(time.Hour),
+		SettleDateEnd:     settleTime,
+	})
+
+	assertQuery(InvoiceQuery{
+		States: []ContractState{ContractCanceled, ContractOpen},
+	}, 1, 2)
+	assertQuery(InvoiceQuery{
+		CreationDateEnd: testNow.Add(time.Hour),
+		States:          []ContractState{ContractCanceled},
+	}, 1)
+
+	// Pagination applies to the filtered invoices.
+	assertQuery(InvoiceQuery{
+		IndexOffset:       2,
+		CreationDateStart: testNow.Add(time.Hour),
+	}, 2)
+	assertQuery(InvoiceQuery{
+		IndexOffset:       3,
+		Reversed:          true,
+		CreationDateStart: testNow.Add(time.Hour),
+	}, 1)
+
+	// Deleted invoices are removed from the time indexes.
+	require.NoError(t, db.DeleteInvoice(InvoiceRefByHash(hashes[0])))
+	require.NoError(t, db.DeleteInvoice(InvoiceRefByHash(hashes[1])))
+	assertQuery(InvoiceQuery{
+		SettleDateStart: testNow,
+	})
+	assertQuery(InvoiceQuery{
+		CreationDateStart: testNow,
+	}, 2)
+
+	countEntries := func(bucket []byte) int {
+		numEntries := 0
+		err := kvdb.View(db, func(tx kvdb.RTx) error {
+			index := tx.ReadBucket(invoiceBucket).NestedReadBucket(
+				bucket,
+			)
+			return index.ForEach(func(_, _ []byte) error {
+				numEntries++
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		return numEntries
+	}
+	require.Equal(t, 1, countEntries(creationTimeIndexBucket))
+	require.Zero(t, countEntries(settleTimeIndexBucket))
 }
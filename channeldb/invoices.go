@@ -3,9 +3,11 @@ package channeldb
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"time"
 
 	"github.com/decred/dcrlnd/channeldb/kvdb"
@@ -80,6 +82,19 @@ var (
 	//   settleIndexNo => invoiceKey
 	settleIndexBucket = []byte("invoice-settle-index")
 
+	// creationTimeIndexBucket is an index bucket that orders invoices by
+	// their creation time, which allows querying the invoices created in
+	// a time range without scanning all of them.
+	//
+	// maps: creationTime || addIndexNo => nil
+	creationTimeIndexBucket = []byte("invoice-creation-time-index")
+
+	// settleTimeIndexBucket is an index bucket that orders the settled
+	// invoices by their settle time.
+	//
+	// maps: settleTime || addIndexNo => nil
+	settleTimeIndexBucket = []byte("invoice-settle-time-index")
+
 	// ErrInvoiceAlreadySettled is returned when the invoice is already
 	// settled.
 	ErrInvoiceAlreadySettled = errors.New("invoice already settled")
@@ -95,6 +110,10 @@ var (
 	// ErrInvoiceStillOpen is returned when the invoice is still open.
 	ErrInvoiceStillOpen = errors.New("invoice still open")
 
+	// ErrInvoicePending is returned when an attempt is made to delete an
+	// invoice that is still open or accepted.
+	ErrInvoicePending = errors.New("invoice is still pending")
+
 	// ErrInvoiceCannotOpen is returned when an attempt is made to move an
 	// invoice to the open state.
 	ErrInvoiceCannotOpen = errors.New("cannot move invoice to open")
@@ -106,6 +125,14 @@ var (
 	// ErrInvoicePreimageMismatch is returned when the preimage doesn't
 	// match the invoice hash.
 	ErrInvoicePreimageMismatch = errors.New("preimage does not match")
+
+	// ErrHTLCPreimageMismatch is returned when the preimage of an AMP HTLC
+	// doesn't match the HTLC's payment hash.
+	ErrHTLCPreimageMismatch = errors.New("htlc preimage does not match")
+
+	// ErrAMPSetAlreadySettled is returned when attempting to settle an AMP
+	// set that has already been settled on the invoice.
+	ErrAMPSetAlreadySettled = errors.New("amp set already settled")
 )
 
 const (
@@ -135,6 +162,9 @@ const (
 	expiryHeightType tlv.Type = 13
 	htlcStateType    tlv.Type = 15
 	mppTotalAmtType  tlv.Type = 17
+	htlcAMPType      tlv.Type = 19
+	htlcHashType     tlv.Type = 21
+	htlcPreimageType tlv.Type = 23
 
 	// A set of tlv type definitions used to serialize invoice bodiees.
 	//
@@ -157,6 +187,12 @@ const (
 	invStateType    tlv.Type = 12
 	amtPaidType     tlv.Type = 13
 	hodlInvoiceType tlv.Type = 14
+	ampStateType    tlv.Type = 15
+
+	// ampStateEntrySize is the serialized size of a single entry of an
+	// invoice's AMP state: the set id, htlc state, settle index, settle
+	// time and amount paid.
+	ampStateEntrySize = 32 + 1 + 8 + 8 + 8
 )
 
 // InvoiceRef is a composite identifier for invoices. Invoices can be referenced
@@ -168,7 +204,10 @@ type InvoiceRef struct {
 	// payHash is the payment hash of the target invoice. All invoices are
 	// currently indexed by payment hash. This value will be used as a
 	// fallback when no payment address is known.
-	payHash lntypes.Hash
+	//
+	// NOTE: This value may be nil for invoices that are only referenced by
+	// their payment address, such as spontaneous AMP invoices.
+	payHash *lntypes.Hash
 
 	// payAddr is the payment addr of the target invoice. Newer invoices
 	// (0.11 and up) are indexed by payment address in addition to payment
@@ -182,7 +221,7 @@ type InvoiceRef struct {
 // its payment hash.
 func InvoiceRefByHash(payHash lntypes.Hash) InvoiceRef {
 	return InvoiceRef{
-		payHash: payHash,
+		payHash: &payHash,
 	}
 }
 
@@ -193,14 +232,29 @@ func InvoiceRefByHashAndAddr(payHash lntypes.Hash,
 	payAddr [32]byte) InvoiceRef {
 
 	return InvoiceRef{
-		payHash: payHash,
+		payHash: &payHash,
 		payAddr: &payAddr,
 	}
 }
 
-// PayHash returns the target invoice's payment hash.
-func (r InvoiceRef) PayHash() lntypes.Hash {
-	return r.payHash
+// InvoiceRefByAddr creates an InvoiceRef that queries the payment address
+// index for an invoice with the provided payment address. This is used for
+// AMP invoices, whose HTLCs each carry a distinct payment hash.
+func InvoiceRefByAddr(addr [32]byte) InvoiceRef {
+	return InvoiceRef{
+		payAddr: &addr,
+	}
+}
+
+// PayHash returns the optional payment hash of the target invoice.
+//
+// NOTE: This value may be nil.
+func (r InvoiceRef) PayHash() *lntypes.Hash {
+	if r.payHash != nil {
+		hash := *r.payHash
+		return &hash
+	}
+	return nil
 }
 
 // PayAddr returns the optional payment address of the target invoice.
@@ -216,10 +270,15 @@ func (r InvoiceRef) PayAddr() *[32]byte {
 
 // String returns a human-readable representation of an InvoiceRef.
 func (r InvoiceRef) String() string {
-	if r.payAddr != nil {
-		return fmt.Sprintf("(pay_hash=%v, pay_addr=%x)", r.payHash, *r.payAddr)
+	switch {
+	case r.payHash != nil && r.payAddr != nil:
+		return fmt.Sprintf("(pay_hash=%v, pay_addr=%x)", *r.payHash,
+			*r.payAddr)
+	case r.payHash != nil:
+		return fmt.Sprintf("(pay_hash=%v)", *r.payHash)
+	default:
+		return fmt.Sprintf("(pay_addr=%x)", *r.payAddr)
 	}
-	return fmt.Sprintf("(pay_hash=%v)", r.payHash)
 }
 
 // ContractState describes the state the invoice is in.
@@ -336,7 +395,9 @@ type Invoice struct {
 	// monotonically increasing sequence number for all settled invoices.
 	// Clients can then use this field as a "checkpoint" of sorts when
 	// implementing a streaming RPC to notify consumers of instances where
-	// an invoice has been settled before they re-connected.
+	// an invoice has been settled before they re-connected. For AMP
+	// invoices, this is the settle index of the most recently settled htlc
+	// set.
 	//
 	// NOTE: This index starts at 1.
 	SettleIndex uint64
@@ -357,6 +418,40 @@ type Invoice struct {
 	// HodlInvoice indicates whether the invoice should be held in the
 	// Accepted state or be settled right away.
 	HodlInvoice bool
+
+	// AMPState records the sub-settlements of an AMP invoice, keyed by the
+	// set id of each htlc set that paid to the invoice. AMP invoices can
+	// be paid multiple times, so they remain open while each of their sets
+	// is settled individually.
+	//
+	// NOTE: This value will only be set for AMP invoices.
+	AMPState map[SetID]InvoiceStateAMP
+}
+
+// SetID is the identifier shared by all htlcs of a single AMP payment.
+type SetID [32]byte
+
+// String returns the hex encoding of the set id.
+func (s SetID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// InvoiceStateAMP describes the state of a single htlc set that paid to an AMP
+// invoice.
+type InvoiceStateAMP struct {
+	// State is the state of the htlcs in the set.
+	State HtlcState
+
+	// SettleIndex is the settle index of the set. Each settled set of an
+	// AMP invoice gets its own entry in the settle index, so that clients
+	// are notified of every sub-settlement.
+	SettleIndex uint64
+
+	// SettleDate is the time at which the set was settled.
+	SettleDate time.Time
+
+	// AmtPaid is the total amount paid by the htlcs of the set.
+	AmtPaid lnwire.MilliAtom
 }
 
 // HtlcState defines the states an htlc paying to an invoice can be in.
@@ -407,6 +502,59 @@ type InvoiceHTLC struct {
 	// CustomRecords contains the custom key/value pairs that accompanied
 	// the htlc.
 	CustomRecords record.CustomSet
+
+	// AMP encapsulates additional data relevant to AMP HTLCs. This includes
+	// the AMP onion record, in addition to the HTLC's payment hash and
+	// preimage since these are unique to each AMP HTLC, and not the invoice
+	// as a whole.
+	//
+	// NOTE: This value will only be set for AMP HTLCs.
+	AMP *InvoiceHtlcAMPData
+}
+
+// InvoiceHtlcAMPData is a struct holding the additional metadata stored for
+// each received AMP HTLC. This includes the AMP onion record, in addition to
+// the HTLC's payment hash and preimage.
+type InvoiceHtlcAMPData struct {
+	// AMP is a copy of the AMP record presented in the onion payload
+	// containing the information necessary to correlate and settle a
+	// spontaneous HTLC set. Newer versions of senders set this field to
+	// indicate a spontaneous HTLC set.
+	Record record.AMP
+
+	// Hash is an HTLC-level payment hash that is stored only for AMP
+	// payments. This is done because an AMP HTLC will carry a different
+	// payment hash from the invoice it might be satisfying, so we track the
+	// payment hashes individually to able to compute whether or not the
+	// reconstructed preimage correctly matches the HTLC's hash.
+	Hash lntypes.Hash
+
+	// Preimage is an HTLC-level preimage that satisfies the AMP HTLC's
+	// Hash. The preimage is derived from secret share reconstruction of
+	// the shares in the AMP payloads of the set.
+	//
+	// NOTE: Preimage will only be present once the HTLC is in
+	// HtlcStateSettled.
+	Preimage *lntypes.Preimage
+}
+
+// Copy returns a deep copy of the InvoiceHtlcAMPData.
+func (d *InvoiceHtlcAMPData) Copy() *InvoiceHtlcAMPData {
+	if d == nil {
+		return nil
+	}
+
+	var preimage *lntypes.Preimage
+	if d.Preimage != nil {
+		pimg := *d.Preimage
+		preimage = &pimg
+	}
+
+	return &InvoiceHtlcAMPData{
+		Record:   d.Record,
+		Hash:     d.Hash,
+		Preimage: preimage,
+	}
 }
 
 // HtlcAcceptDesc describes the details of a newly accepted htlc.
@@ -427,6 +575,14 @@ type HtlcAcceptDesc struct {
 	// CustomRecords contains the custom key/value pairs that accompanied
 	// the htlc.
 	CustomRecords record.CustomSet
+
+	// AMP encapsulates additional data relevant to AMP HTLCs. This includes
+	// the AMP onion record, in addition to the HTLC's payment hash and
+	// preimage since these are unique to each AMP HTLC, and not the invoice
+	// as a whole.
+	//
+	// NOTE: This value will only be set for AMP HTLCs.
+	AMP *InvoiceHtlcAMPData
 }
 
 // InvoiceUpdateDesc describes the changes that should be applied to the
@@ -450,7 +606,20 @@ type InvoiceStateUpdateDesc struct {
 	NewState ContractState
 
 	// Preimage must be set to the preimage when NewState is settled.
+	//
+	// NOTE: This field is not used for AMP invoices, which instead set
+	// HTLCPreimages.
 	Preimage *lntypes.Preimage
+
+	// HTLCPreimages set the HTLC-level preimages stored for AMP HTLCs.
+	// These are only learned when settling an htlc set, as the preimages
+	// can only be derived once the full set of shares is received.
+	HTLCPreimages map[CircuitKey]lntypes.Preimage
+
+	// SetID identifies the htlc set to settle when settling an AMP
+	// invoice. AMP invoices themselves remain open, only the htlcs of the
+	// given set are settled.
+	SetID *SetID
 }
 
 // InvoiceUpdateCallback is a callback used in the db transaction to update the
@@ -476,17 +645,45 @@ func validateInvoice(i *Invoice, paymentHash lntypes.Hash) error {
 		return errors.New("invoice must have a feature vector")
 	}
 
-	if i.Terms.PaymentPreimage == nil && !i.HodlInvoice {
+	// AMP invoices don't have a single preimage, as each HTLC carries its
+	// own payment hash that is only derived once the full set arrives.
+	if i.Terms.PaymentPreimage == nil && !i.HodlInvoice && !i.IsAMP() {
 		return errors.New("non-hodl invoices must have a preimage")
 	}
 	return nil
 }
 
+// IsAMP returns true if the invoice is an AMP invoice, meaning it requires the
+// AMP feature bit.
+func (i *Invoice) IsAMP() bool {
+	if i.Terms.Features == nil {
+		return false
+	}
+
+	return i.Terms.Features.HasFeature(lnwire.AMPRequired)
+}
+
 // IsPending returns ture if the invoice is in ContractOpen state.
 func (i *Invoice) IsPending() bool {
 	return i.State == ContractOpen || i.State == ContractAccepted
 }
 
+// hasState returns true if the invoice is in one of the given states, or if
+// no states are given.
+func (i *Invoice) hasState(states []ContractState) bool {
+	if len(states) == 0 {
+		return true
+	}
+
+	for _, state := range states {
+		if i.State == state {
+			return true
+		}
+	}
+
+	return false
+}
+
 // AddInvoice inserts the targeted invoice into the database. If the invoice has
 // *any* payment hashes which already exists within the database, then the
 // insertion will be aborted and rejected due to the strict policy banning any
@@ -608,8 +805,12 @@ func (d *DB) InvoicesAddedSince(sinceAddIndex uint64) ([]Invoice, error) {
 
 		// We'll seek to the starting index, then manually advance the
 		// cursor in order to skip the entry with the since add index.
-		invoiceCursor.Seek(startIndex[:])
-		addSeqNo, invoiceKey := invoiceCursor.Next()
+		// If the invoice with that index was deleted, the cursor
+		// already points at the next entry.
+		addSeqNo, invoiceKey := invoiceCursor.Seek(startIndex[:])
+		if bytes.Equal(addSeqNo, startIndex[:]) {
+			addSeqNo, invoiceKey = invoiceCursor.Next()
+		}
 
 		for ; addSeqNo != nil && bytes.Compare(addSeqNo, startIndex[:]) > 0; addSeqNo, invoiceKey = invoiceCursor.Next() {
 
@@ -688,9 +889,12 @@ func fetchInvoiceNumByRef(invoiceIndex, payAddrIndex kvdb.RBucket,
 	payAddr := ref.PayAddr()
 
 	var (
-		invoiceNumByHash = invoiceIndex.Get(payHash[:])
+		invoiceNumByHash []byte
 		invoiceNumByAddr []byte
 	)
+	if payHash != nil {
+		invoiceNumByHash = invoiceIndex.Get(payHash[:])
+	}
 	if payAddr != nil {
 		// Only allow lookups for payment address if it is not a blank
 		// payment address, which is a special-cased value for legacy
@@ -717,6 +921,11 @@ func fetchInvoiceNumByRef(invoiceIndex, payAddrIndex kvdb.RBucket,
 	case invoiceNumByHash != nil:
 		return invoiceNumByHash, nil
 
+	// If the invoice was only referenced by its payment address, as is
+	// the case for AMP invoices, return the corresponding invoice number.
+	case payHash == nil && invoiceNumByAddr != nil:
+		return invoiceNumByAddr, nil
+
 	// Otherwise we don't know of the target invoice.
 	default:
 		return nil, ErrInvoiceNotFound
@@ -813,6 +1022,26 @@ type InvoiceQuery struct {
 	// add index.
 	PendingOnly bool
 
+	// CreationDateStart, if set, filters out the invoices created before
+	// this time.
+	CreationDateStart time.Time
+
+	// CreationDateEnd, if set, filters out the invoices created after this
+	// time.
+	CreationDateEnd time.Time
+
+	// SettleDateStart, if set, filters out the invoices that weren't
+	// settled at or after this time.
+	SettleDateStart time.Time
+
+	// SettleDateEnd, if set, filters out the invoices that weren't settled
+	// at or before this time.
+	SettleDateEnd time.Time
+
+	// States, if non-empty, filters out the invoices whose state isn't one
+	// of the given states.
+	States []ContractState
+
 	// Reversed, if set, indicates that the invoices returned should start
 	// from the IndexOffset and go backwards.
 	Reversed bool
@@ -865,11 +1094,29 @@ func (d *DB) QueryInvoices(q InvoiceQuery) (InvoiceSlice, error) {
 			return ErrNoInvoicesCreated
 		}
 
+		// If the query is restricted to a creation or settle time
+		// range, only the add index entries of the invoices within the
+		// ranges are read.
+		cursor := filterIndex(
+			invoiceAddIndex, timeRange{
+				timeIndex: invoices.NestedReadBucket(
+					creationTimeIndexBucket,
+				),
+				start: q.CreationDateStart,
+				end:   q.CreationDateEnd,
+			}, timeRange{
+				timeIndex: invoices.NestedReadBucket(
+					settleTimeIndexBucket,
+				),
+				start: q.SettleDateStart,
+				end:   q.SettleDateEnd,
+			},
+		)
+
 		// Create a paginator which reads from our add index bucket with
 		// the parameters provided by the invoice query.
 		paginator := newPaginator(
-			invoiceAddIndex.ReadCursor(), q.Reversed, q.IndexOffset,
-			q.NumMaxInvoices,
+			cursor, q.Reversed, q.IndexOffset, q.NumMaxInvoices,
 		)
 
 		// accumulateInvoices looks up an invoice based on the index we
@@ -888,6 +1135,12 @@ func (d *DB) QueryInvoices(q InvoiceQuery) (InvoiceSlice, error) {
 				return false, nil
 			}
 
+			// Skip the invoices that aren't in one of the
+			// requested states.
+			if !invoice.hasState(q.States) {
+				return false, nil
+			}
+
 			// At this point, we've exhausted the offset, so we'll
 			// begin collecting invoices found within the range.
 			resp.Invoices = append(resp.Invoices, invoice)
@@ -1016,8 +1269,12 @@ func (d *DB) InvoicesSettledSince(sinceSettleIndex uint64) ([]Invoice, error) {
 
 		// We'll seek to the starting index, then manually advance the
 		// cursor in order to skip the entry with the since add index.
-		invoiceCursor.Seek(startIndex[:])
-		seqNo, invoiceKey := invoiceCursor.Next()
+		// If the invoice with that index was deleted, the cursor
+		// already points at the next entry.
+		seqNo, invoiceKey := invoiceCursor.Seek(startIndex[:])
+		if bytes.Equal(seqNo, startIndex[:]) {
+			seqNo, invoiceKey = invoiceCursor.Next()
+		}
 
 		for ; seqNo != nil && bytes.Compare(seqNo, startIndex[:]) > 0; seqNo, invoiceKey = invoiceCursor.Next() {
 
@@ -1028,6 +1285,15 @@ func (d *DB) InvoicesSettledSince(sinceSettleIndex uint64) ([]Invoice, error) {
 				return err
 			}
 
+			// AMP invoices have an entry for each settled htlc
+			// set, so we report the settlement of the set that
+			// this entry belongs to.
+			if invoice.IsAMP() {
+				setAMPSettleEvent(
+					&invoice, byteOrder.Uint64(seqNo),
+				)
+			}
+
 			settledInvoices = append(settledInvoices, invoice)
 		}
 
@@ -1040,6 +1306,261 @@ func (d *DB) InvoicesSettledSince(sinceSettleIndex uint64) ([]Invoice, error) {
 	return settledInvoices, nil
 }
 
+// setAMPSettleEvent sets the settle index and date of the AMP invoice to those
+// of the htlc set with the given settle index.
+func setAMPSettleEvent(invoice *Invoice, settleIndex uint64) {
+	for _, ampState := range invoice.AMPState {
+		if ampState.SettleIndex != settleIndex {
+			continue
+		}
+
+		invoice.SettleIndex = ampState.SettleIndex
+		invoice.SettleDate = ampState.SettleDate
+
+		return
+	}
+}
+
+// DeleteInvoice deletes the settled or canceled invoice referenced by ref,
+// along with its entries in the invoice indexes. The sequences of the add and
+// settle indexes are left untouched, so the indexes of the remaining invoices
+// stay valid for clients resuming their invoice subscriptions.
+func (d *DB) DeleteInvoice(ref InvoiceRef) error {
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		invoices := tx.ReadWriteBucket(invoiceBucket)
+		if invoices == nil {
+			return ErrNoInvoicesCreated
+		}
+		invoiceIndex := invoices.NestedReadWriteBucket(
+			invoiceIndexBucket,
+		)
+		if invoiceIndex == nil {
+			return ErrNoInvoicesCreated
+		}
+		payAddrIndex := tx.ReadWriteBucket(payAddrIndexBucket)
+
+		invoiceNum, err := fetchInvoiceNumByRef(
+			invoiceIndex, payAddrIndex, ref,
+		)
+		if err != nil {
+			return err
+		}
+
+		invoice, err := fetchInvoice(invoiceNum, invoices)
+		if err != nil {
+			return err
+		}
+
+		// Pending invoices may have htlcs that are still held by the
+		// invoice registry, so they can't be deleted.
+		if invoice.IsPending() {
+			return ErrInvoicePending
+		}
+
+		return deleteInvoices(
+			invoices, invoiceIndex, payAddrIndex,
+			map[string]*Invoice{string(invoiceNum): &invoice},
+		)
+	})
+}
+
+// DeleteCanceledInvoices deletes all canceled invoices that were created
+// before the given time, and returns the number of deleted invoices. Like
+// DeleteInvoice, it keeps the add and settle indexes consistent.
+func (d *DB) DeleteCanceledInvoices(createdBefore time.Time) (int, error) {
+	var numDeleted int
+	err := kvdb.Update(d, func(tx kvdb.RwTx) error {
+		numDeleted = 0
+
+		invoices := tx.ReadWriteBucket(invoiceBucket)
+		if invoices == nil {
+			return nil
+		}
+		invoiceIndex := invoices.NestedReadWriteBucket(
+			invoiceIndexBucket,
+		)
+		if invoiceIndex == nil {
+			return nil
+		}
+		payAddrIndex := tx.ReadWriteBucket(payAddrIndexBucket)
+
+		toDelete := make(map[string]*Invoice)
+		err := invoices.ForEach(func(k, v []byte) error {
+			// Skip the nested index buckets.
+			if v == nil {
+				return nil
+			}
+
+			invoice, err := deserializeInvoice(bytes.NewReader(v))
+			if err != nil {
+				return err
+			}
+
+			if invoice.State != ContractCanceled ||
+				!invoice.CreationDate.Before(createdBefore) {
+
+				return nil
+			}
+
+			toDelete[string(k)] = &invoice
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		numDeleted = len(toDelete)
+
+		return deleteInvoices(
+			invoices, invoiceIndex, payAddrIndex, toDelete,
+		)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return numDeleted, nil
+}
+
+// deleteInvoices deletes the given invoices, keyed by their invoice number,
+// and removes them from the payment hash, payment address, add and settle
+// indexes.
+func deleteInvoices(invoices, invoiceIndex, payAddrIndex kvdb.RwBucket,
+	toDelete map[string]*Invoice) error {
+
+	if len(toDelete) == 0 {
+		return nil
+	}
+
+	// The payment hash index maps hashes to invoice numbers, so we look
+	// up the hashes of the deleted invoices by their number.
+	var hashes [][]byte
+	err := invoiceIndex.ForEach(func(k, v []byte) error {
+		if bytes.Equal(k, numInvoicesKey) {
+			return nil
+		}
+
+		if _, ok := toDelete[string(v)]; ok {
+			hashes = append(hashes, k)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if err := invoiceIndex.Delete(hash); err != nil {
+			return err
+		}
+	}
+
+	addIndex := invoices.NestedReadWriteBucket(addIndexBucket)
+	settleIndex := invoices.NestedReadWriteBucket(settleIndexBucket)
+	creationTimeIndex := invoices.NestedReadWriteBucket(
+		creationTimeIndexBucket,
+	)
+	settleTimeIndex := invoices.NestedReadWriteBucket(
+		settleTimeIndexBucket,
+	)
+
+	// deleteIndexEntry deletes the entry of an index if it still points
+	// to the given invoice.
+	deleteIndexEntry := func(index kvdb.RwBucket, key,
+		invoiceNum []byte) error {
+
+		if index == nil || !bytes.Equal(index.Get(key), invoiceNum) {
+			return nil
+		}
+
+		return index.Delete(key)
+	}
+
+	var seqNoBytes [8]byte
+	for invoiceNum, invoice := range toDelete {
+		invoiceKey := []byte(invoiceNum)
+
+		if invoice.Terms.PaymentAddr != BlankPayAddr {
+			err := deleteIndexEntry(
+				payAddrIndex, invoice.Terms.PaymentAddr[:],
+				invoiceKey,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		byteOrder.PutUint64(seqNoBytes[:], invoice.AddIndex)
+		err := deleteIndexEntry(addIndex, seqNoBytes[:], invoiceKey)
+		if err != nil {
+			return err
+		}
+
+		if creationTimeIndex != nil {
+			err := creationTimeIndex.Delete(timeIndexKey(
+				invoice.CreationDate, invoice.AddIndex,
+			))
+			if err != nil {
+				return err
+			}
+		}
+
+		if invoice.SettleIndex != 0 {
+			byteOrder.PutUint64(seqNoBytes[:], invoice.SettleIndex)
+			err := deleteIndexEntry(
+				settleIndex, seqNoBytes[:], invoiceKey,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if invoice.SettleIndex != 0 && settleTimeIndex != nil {
+			err := settleTimeIndex.Delete(timeIndexKey(
+				invoice.SettleDate, invoice.AddIndex,
+			))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Each settled htlc set of an AMP invoice has its own entries
+		// in the settle indexes.
+		for _, ampState := range invoice.AMPState {
+			if ampState.SettleIndex == 0 {
+				continue
+			}
+
+			byteOrder.PutUint64(seqNoBytes[:], ampState.SettleIndex)
+			err := deleteIndexEntry(
+				settleIndex, seqNoBytes[:], invoiceKey,
+			)
+			if err != nil {
+				return err
+			}
+
+			if settleTimeIndex == nil {
+				continue
+			}
+
+			err = settleTimeIndex.Delete(timeIndexKey(
+				ampState.SettleDate, invoice.AddIndex,
+			))
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := invoices.Delete(invoiceKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func putInvoice(invoices, invoiceIndex, payAddrIndex, addIndex kvdb.RwBucket,
 	i *Invoice, invoiceNum uint32, paymentHash lntypes.Hash) (
 	uint64, error) {
@@ -1095,6 +1616,21 @@ func putInvoice(invoices, invoiceIndex, payAddrIndex, addIndex kvdb.RwBucket,
 
 	i.AddIndex = nextAddSeqNo
 
+	// Add the invoice to the creation time index, so that it can be
+	// found by its creation time.
+	creationTimeIndex, err := invoices.CreateBucketIfNotExists(
+		creationTimeIndexBucket,
+	)
+	if err != nil {
+		return 0, err
+	}
+	err = creationTimeIndex.Put(
+		timeIndexKey(i.CreationDate, nextAddSeqNo), []byte{},
+	)
+	if err != nil {
+		return 0, err
+	}
+
 	// Finally, serialize the invoice itself to be written to the disk.
 	var buf bytes.Buffer
 	if err := serializeInvoice(&buf, i); err != nil {
@@ -1150,7 +1686,7 @@ func serializeInvoice(w io.Writer, i *Invoice) error {
 		hodlInvoice = 1
 	}
 
-	tlvStream, err := tlv.NewStream(
+	records := []tlv.Record{
 		// Memo and payreq.
 		tlv.MakePrimitiveRecord(memoType, &i.Memo),
 		tlv.MakePrimitiveRecord(payReqType, &i.PaymentRequest),
@@ -1174,7 +1710,19 @@ func serializeInvoice(w io.Writer, i *Invoice) error {
 		tlv.MakePrimitiveRecord(amtPaidType, &amtPaid),
 
 		tlv.MakePrimitiveRecord(hodlInvoiceType, &hodlInvoice),
-	)
+	}
+
+	// Only AMP invoices that have been paid at least once carry
+	// sub-settlement state.
+	if len(i.AMPState) > 0 {
+		ampState := ampStateRecords(i.AMPState)
+		records = append(records, tlv.MakeDynamicRecord(
+			ampStateType, &ampState, ampState.size,
+			ampStateEncoder, ampStateDecoder,
+		))
+	}
+
+	tlvStream, err := tlv.NewStream(records...)
 	if err != nil {
 		return err
 	}
@@ -1223,6 +1771,29 @@ func serializeHtlcs(w io.Writer, htlcs map[CircuitKey]*InvoiceHTLC) error {
 			tlv.MakePrimitiveRecord(mppTotalAmtType, &mppTotalAmt),
 		)
 
+		if htlc.AMP != nil {
+			setIDRecord := tlv.MakeDynamicRecord(
+				htlcAMPType, &htlc.AMP.Record,
+				htlc.AMP.Record.PayloadSize,
+				record.AMPEncoder, record.AMPDecoder,
+			)
+			records = append(records, setIDRecord)
+
+			hash32 := [32]byte(htlc.AMP.Hash)
+			hashRecord := tlv.MakePrimitiveRecord(
+				htlcHashType, &hash32,
+			)
+			records = append(records, hashRecord)
+
+			if htlc.AMP.Preimage != nil {
+				preimage32 := [32]byte(*htlc.AMP.Preimage)
+				preimageRecord := tlv.MakePrimitiveRecord(
+					htlcPreimageType, &preimage32,
+				)
+				records = append(records, preimageRecord)
+			}
+		}
+
 		// Convert the custom records to tlv.Record types that are ready
 		// for serialization.
 		customRecords := tlv.MapToRecords(htlc.CustomRecords)
@@ -1280,6 +1851,8 @@ func deserializeInvoice(r io.Reader) (Invoice, error) {
 		creationDateBytes []byte
 		settleDateBytes   []byte
 		featureBytes      []byte
+
+		ampState ampStateRecords
 	)
 
 	var i Invoice
@@ -1307,6 +1880,12 @@ func deserializeInvoice(r io.Reader) (Invoice, error) {
 		tlv.MakePrimitiveRecord(amtPaidType, &amtPaid),
 
 		tlv.MakePrimitiveRecord(hodlInvoiceType, &hodlInvoice),
+
+		// AMP sub-settlements.
+		tlv.MakeDynamicRecord(
+			ampStateType, &ampState, ampState.size,
+			ampStateEncoder, ampStateDecoder,
+		),
 	)
 	if err != nil {
 		return i, err
@@ -1338,6 +1917,10 @@ func deserializeInvoice(r io.Reader) (Invoice, error) {
 		i.HodlInvoice = true
 	}
 
+	if len(ampState) > 0 {
+		i.AMPState = ampState
+	}
+
 	err = i.CreationDate.UnmarshalBinary(creationDateBytes)
 	if err != nil {
 		return i, err
@@ -1392,6 +1975,9 @@ func deserializeHtlcs(r io.Reader) (map[CircuitKey]*InvoiceHTLC, error) {
 			state                   uint8
 			acceptTime, resolveTime uint64
 			amt, mppTotalAmt        uint64
+			amp                     = &record.AMP{}
+			hash32                  = &[32]byte{}
+			preimage32              = &[32]byte{}
 		)
 		tlvStream, err := tlv.NewStream(
 			tlv.MakePrimitiveRecord(chanIDType, &chanID),
@@ -1405,6 +1991,12 @@ func deserializeHtlcs(r io.Reader) (map[CircuitKey]*InvoiceHTLC, error) {
 			tlv.MakePrimitiveRecord(expiryHeightType, &htlc.Expiry),
 			tlv.MakePrimitiveRecord(htlcStateType, &state),
 			tlv.MakePrimitiveRecord(mppTotalAmtType, &mppTotalAmt),
+			tlv.MakeDynamicRecord(
+				htlcAMPType, amp, amp.PayloadSize,
+				record.AMPEncoder, record.AMPDecoder,
+			),
+			tlv.MakePrimitiveRecord(htlcHashType, hash32),
+			tlv.MakePrimitiveRecord(htlcPreimageType, preimage32),
 		)
 		if err != nil {
 			return nil, err
@@ -1422,6 +2014,19 @@ func deserializeHtlcs(r io.Reader) (map[CircuitKey]*InvoiceHTLC, error) {
 		htlc.Amt = lnwire.MilliAtom(amt)
 		htlc.MppTotalAmt = lnwire.MilliAtom(mppTotalAmt)
 
+		if _, ok := parsedTypes[htlcAMPType]; ok {
+			htlc.AMP = &InvoiceHtlcAMPData{
+				Record: *amp,
+			}
+			if _, ok := parsedTypes[htlcHashType]; ok {
+				htlc.AMP.Hash = *hash32
+			}
+			if _, ok := parsedTypes[htlcPreimageType]; ok {
+				preimage := lntypes.Preimage(*preimage32)
+				htlc.AMP.Preimage = &preimage
+			}
+		}
+
 		// Reconstruct the custom records fields from the parsed types
 		// map return from the tlv parser.
 		htlc.CustomRecords = hop.NewCustomRecords(parsedTypes)
@@ -1432,6 +2037,109 @@ func deserializeHtlcs(r io.Reader) (map[CircuitKey]*InvoiceHTLC, error) {
 	return htlcs, nil
 }
 
+// ampStateRecords is the serializable form of the AMP state of an invoice.
+type ampStateRecords map[SetID]InvoiceStateAMP
+
+// size returns the serialized size of the AMP state.
+func (a *ampStateRecords) size() uint64 {
+	return uint64(len(*a)) * ampStateEntrySize
+}
+
+// ampStateEncoder encodes the AMP state of an invoice. Entries are written in
+// order of their set id so that the encoding is deterministic.
+func ampStateEncoder(w io.Writer, val interface{}, buf *[8]byte) error {
+	v, ok := val.(*ampStateRecords)
+	if !ok {
+		return tlv.NewTypeForEncodingErr(val, "ampStateRecords")
+	}
+
+	setIDs := make([]SetID, 0, len(*v))
+	for setID := range *v {
+		setIDs = append(setIDs, setID)
+	}
+	sort.Slice(setIDs, func(i, j int) bool {
+		return bytes.Compare(setIDs[i][:], setIDs[j][:]) < 0
+	})
+
+	for _, setID := range setIDs {
+		state := (*v)[setID]
+
+		setID32 := [32]byte(setID)
+		if err := tlv.EBytes32(w, &setID32, buf); err != nil {
+			return err
+		}
+
+		htlcState := uint8(state.State)
+		if err := tlv.EUint8(w, &htlcState, buf); err != nil {
+			return err
+		}
+
+		settleIndex := state.SettleIndex
+		if err := tlv.EUint64(w, &settleIndex, buf); err != nil {
+			return err
+		}
+
+		settleDate := uint64(state.SettleDate.UnixNano())
+		if err := tlv.EUint64(w, &settleDate, buf); err != nil {
+			return err
+		}
+
+		amtPaid := uint64(state.AmtPaid)
+		if err := tlv.EUint64(w, &amtPaid, buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ampStateDecoder decodes the AMP state of an invoice.
+func ampStateDecoder(r io.Reader, val interface{}, buf *[8]byte,
+	l uint64) error {
+
+	v, ok := val.(*ampStateRecords)
+	if !ok || l%ampStateEntrySize != 0 {
+		return tlv.NewTypeForDecodingErr(
+			val, "ampStateRecords", l, ampStateEntrySize,
+		)
+	}
+
+	*v = make(ampStateRecords, l/ampStateEntrySize)
+	for n := uint64(0); n < l/ampStateEntrySize; n++ {
+		var (
+			setID32     [32]byte
+			htlcState   uint8
+			settleIndex uint64
+			settleDate  uint64
+			amtPaid     uint64
+		)
+		if err := tlv.DBytes32(r, &setID32, buf, 32); err != nil {
+			return err
+		}
+		if err := tlv.DUint8(r, &htlcState, buf, 1); err != nil {
+			return err
+		}
+		if err := tlv.DUint64(r, &settleIndex, buf, 8); err != nil {
+			return err
+		}
+		if err := tlv.DUint64(r, &settleDate, buf, 8); err != nil {
+			return err
+		}
+		if err := tlv.DUint64(r, &amtPaid, buf, 8); err != nil {
+			return err
+		}
+
+		(*v)[setID32] = InvoiceStateAMP{
+			State:       HtlcState(htlcState),
+			SettleIndex: settleIndex,
+			SettleDate:  time.Unix(0, int64(settleDate)),
+			AmtPaid:     lnwire.MilliAtom(amtPaid),
+		}
+	}
+
+	return nil
+}
+
 // copySlice allocates a new slice and copies the source into it.
 func copySlice(src []byte) []byte {
 	dest := make([]byte, len(src))
@@ -1449,6 +2157,8 @@ func copyInvoiceHTLC(src *InvoiceHTLC) *InvoiceHTLC {
 		result.CustomRecords[k] = v
 	}
 
+	result.AMP = src.AMP.Copy()
+
 	return &result
 }
 
@@ -1472,6 +2182,15 @@ func copyInvoice(src *Invoice) *Invoice {
 
 	dest.Terms.Features = src.Terms.Features.Clone()
 
+	if src.AMPState != nil {
+		dest.AMPState = make(
+			map[SetID]InvoiceStateAMP, len(src.AMPState),
+		)
+		for setID, state := range src.AMPState {
+			dest.AMPState[setID] = state
+		}
+	}
+
 	if src.Terms.PaymentPreimage != nil {
 		preimage := *src.Terms.PaymentPreimage
 		dest.Terms.PaymentPreimage = &preimage
@@ -1486,7 +2205,7 @@ func copyInvoice(src *Invoice) *Invoice {
 
 // updateInvoice fetches the invoice, obtains the update descriptor from the
 // callback and applies the updates in a single db transaction.
-func (d *DB) updateInvoice(hash lntypes.Hash, invoices, settleIndex kvdb.RwBucket,
+func (d *DB) updateInvoice(hash *lntypes.Hash, invoices, settleIndex kvdb.RwBucket,
 	invoiceNum []byte, callback InvoiceUpdateCallback) (*Invoice, error) {
 
 	invoice, err := fetchInvoice(invoiceNum, invoices)
@@ -1511,24 +2230,6 @@ func (d *DB) updateInvoice(hash lntypes.Hash, invoices, settleIndex kvdb.RwBucke
 
 	now := d.clock.Now()
 
-	// Update invoice state if the update descriptor indicates an invoice
-	// state change.
-	if update.State != nil {
-		err := updateInvoiceState(&invoice, hash, *update.State)
-		if err != nil {
-			return nil, err
-		}
-
-		if update.State.NewState == ContractSettled {
-			err := setSettleMetaFields(
-				settleIndex, invoiceNum, &invoice, now,
-			)
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
 	// Process add actions from update descriptor.
 	for key, htlcUpdate := range update.AddHtlcs {
 		if _, exists := invoice.Htlcs[key]; exists {
@@ -1549,11 +2250,59 @@ func (d *DB) updateInvoice(hash lntypes.Hash, invoices, settleIndex kvdb.RwBucke
 			AcceptTime:    now,
 			State:         HtlcStateAccepted,
 			CustomRecords: htlcUpdate.CustomRecords,
+			AMP:           htlcUpdate.AMP.Copy(),
 		}
 
 		invoice.Htlcs[key] = htlc
 	}
 
+	// Update invoice state if the update descriptor indicates an invoice
+	// state change. This happens after processing the added htlcs, since
+	// settling an AMP set requires an accurate view of the accepted htlcs.
+	switch {
+	// Settling an AMP invoice only settles the given htlc set, the invoice
+	// itself remains open so that it can be paid again.
+	case update.State != nil && invoice.IsAMP() &&
+		update.State.NewState == ContractSettled:
+
+		err := settleAMPSet(&invoice, *update.State, now)
+		if err != nil {
+			return nil, err
+		}
+
+		err = setAMPSettleMetaFields(
+			settleIndex, invoiceNum, &invoice, *update.State.SetID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = putInvoiceSettleTime(invoices, &invoice)
+		if err != nil {
+			return nil, err
+		}
+
+	case update.State != nil:
+		err := updateInvoiceState(&invoice, hash, *update.State)
+		if err != nil {
+			return nil, err
+		}
+
+		if update.State.NewState == ContractSettled {
+			err := setSettleMetaFields(
+				settleIndex, invoiceNum, &invoice, now,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			err = putInvoiceSettleTime(invoices, &invoice)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Align htlc states with invoice state and recalculate amount paid.
 	var (
 		amtPaid     lnwire.MilliAtom
@@ -1583,6 +2332,14 @@ func (d *DB) updateInvoice(hash lntypes.Hash, invoices, settleIndex kvdb.RwBucke
 			continue
 		}
 
+		// The htlcs of an AMP invoice are settled per set while the
+		// invoice itself remains open. Settled AMP htlcs are therefore
+		// final, and count towards the amount paid to the invoice.
+		if invoice.IsAMP() && htlc.State == HtlcStateSettled {
+			amtPaid += htlc.Amt
+			continue
+		}
+
 		// The invoice state may have changed and this could have
 		// implications for the states of the individual htlcs. Align
 		// the htlc state with the current invoice state.
@@ -1622,7 +2379,7 @@ func (d *DB) updateInvoice(hash lntypes.Hash, invoices, settleIndex kvdb.RwBucke
 }
 
 // updateInvoiceState validates and processes an invoice state update.
-func updateInvoiceState(invoice *Invoice, hash lntypes.Hash,
+func updateInvoiceState(invoice *Invoice, hash *lntypes.Hash,
 	update InvoiceStateUpdateDesc) error {
 
 	// Returning to open is never allowed from any state.
@@ -1651,7 +2408,7 @@ func updateInvoiceState(invoice *Invoice, hash lntypes.Hash,
 			// Validate preimage.
 			switch {
 			case update.Preimage != nil:
-				if update.Preimage.Hash() != hash {
+				if hash == nil || update.Preimage.Hash() != *hash {
 					return ErrInvoicePreimageMismatch
 				}
 				invoice.Terms.PaymentPreimage = update.Preimage
@@ -1678,6 +2435,70 @@ func updateInvoiceState(invoice *Invoice, hash lntypes.Hash,
 	return nil
 }
 
+// settleAMPSet validates and processes the settlement of a single htlc set of
+// an AMP invoice. Each accepted htlc of the set must be provided with a
+// preimage matching its payment hash. The htlcs of the set are settled and the
+// sub-settlement is recorded in the invoice's AMP state.
+func settleAMPSet(invoice *Invoice, update InvoiceStateUpdateDesc,
+	now time.Time) error {
+
+	switch {
+	case invoice.State == ContractCanceled:
+		return ErrInvoiceAlreadyCanceled
+
+	case invoice.State != ContractOpen:
+		return fmt.Errorf("cannot settle amp set of invoice in "+
+			"state %v", invoice.State)
+
+	case update.SetID == nil:
+		return errors.New("missing set id for amp invoice settle")
+	}
+
+	setID := *update.SetID
+	if _, ok := invoice.AMPState[setID]; ok {
+		return ErrAMPSetAlreadySettled
+	}
+
+	var amtPaid lnwire.MilliAtom
+	for key, htlc := range invoice.Htlcs {
+		if htlc.State != HtlcStateAccepted || htlc.AMP == nil ||
+			htlc.AMP.Record.SetID() != setID {
+
+			continue
+		}
+
+		preimage, ok := update.HTLCPreimages[key]
+		if !ok {
+			return fmt.Errorf("missing preimage for htlc %v", key)
+		}
+
+		if preimage.Hash() != htlc.AMP.Hash {
+			return ErrHTLCPreimageMismatch
+		}
+
+		htlc.AMP.Preimage = &preimage
+		htlc.State = HtlcStateSettled
+		htlc.ResolveTime = now
+
+		amtPaid += htlc.Amt
+	}
+
+	if amtPaid == 0 {
+		return fmt.Errorf("no accepted htlcs for amp set %v", setID)
+	}
+
+	if invoice.AMPState == nil {
+		invoice.AMPState = make(map[SetID]InvoiceStateAMP)
+	}
+	invoice.AMPState[setID] = InvoiceStateAMP{
+		State:      HtlcStateSettled,
+		SettleDate: now,
+		AmtPaid:    amtPaid,
+	}
+
+	return nil
+}
+
 // cancelSingleHtlc validates cancelation of a single htlc and update its state.
 func cancelSingleHtlc(resolveTime time.Time, htlc *InvoiceHTLC,
 	invState ContractState) error {
@@ -1737,6 +2558,21 @@ func updateHtlc(resolveTime time.Time, htlc *InvoiceHTLC,
 	return nil
 }
 
+// putInvoiceSettleTime adds the settled invoice to the settle time index, so
+// that it can be found by its settle time.
+func putInvoiceSettleTime(invoices kvdb.RwBucket, invoice *Invoice) error {
+	settleTimeIndex, err := invoices.CreateBucketIfNotExists(
+		settleTimeIndexBucket,
+	)
+	if err != nil {
+		return err
+	}
+
+	return settleTimeIndex.Put(
+		timeIndexKey(invoice.SettleDate, invoice.AddIndex), []byte{},
+	)
+}
+
 // setSettleMetaFields updates the metadata associated with settlement of an
 // invoice.
 func setSettleMetaFields(settleIndex kvdb.RwBucket, invoiceNum []byte,
@@ -1745,19 +2581,53 @@ func setSettleMetaFields(settleIndex kvdb.RwBucket, invoiceNum []byte,
 	// Now that we know the invoice hasn't already been settled, we'll
 	// update the settle index so we can place this settle event in the
 	// proper location within our time series.
-	nextSettleSeqNo, err := settleIndex.NextSequence()
+	nextSettleSeqNo, err := putSettleIndex(settleIndex, invoiceNum)
 	if err != nil {
 		return err
 	}
 
-	var seqNoBytes [8]byte
-	byteOrder.PutUint64(seqNoBytes[:], nextSettleSeqNo)
-	if err := settleIndex.Put(seqNoBytes[:], invoiceNum); err != nil {
+	invoice.SettleDate = now
+	invoice.SettleIndex = nextSettleSeqNo
+
+	return nil
+}
+
+// setAMPSettleMetaFields updates the metadata associated with the settlement
+// of a single htlc set of an AMP invoice. The set gets its own settle index,
+// which also becomes the settle index of the invoice.
+func setAMPSettleMetaFields(settleIndex kvdb.RwBucket, invoiceNum []byte,
+	invoice *Invoice, setID SetID) error {
+
+	nextSettleSeqNo, err := putSettleIndex(settleIndex, invoiceNum)
+	if err != nil {
 		return err
 	}
 
-	invoice.SettleDate = now
+	ampState := invoice.AMPState[setID]
+	ampState.SettleIndex = nextSettleSeqNo
+	invoice.AMPState[setID] = ampState
+
+	invoice.SettleDate = ampState.SettleDate
 	invoice.SettleIndex = nextSettleSeqNo
 
 	return nil
 }
+
+// putSettleIndex adds a new entry for the given invoice to the settle index
+// and returns its sequence number.
+func putSettleIndex(settleIndex kvdb.RwBucket, invoiceNum []byte) (uint64,
+	error) {
+
+	nextSettleSeqNo, err := settleIndex.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+
+	var seqNoBytes [8]byte
+	byteOrder.PutUint64(seqNoBytes[:], nextSettleSeqNo)
+	if err := settleIndex.Put(seqNoBytes[:], invoiceNum); err != nil {
+		return 0, err
+	}
+
+	return nextSettleSeqNo, nil
+}
@@ -47,23 +47,31 @@ func GetBoltBackend(path, name string, noFreeListSync bool) (Backend, error) {
 	return db, nil
 }
 
-// GetTestBackend opens (or creates if doesn't exist) a bbolt or etcd
-// backed database (for testing), and returns a kvdb.Backend and a cleanup
-// func. Whether to create/open bbolt or embedded etcd database is based
-// on the TestBackend constant which is conditionally compiled with build tag.
-// The passed path is used to hold all db files, while the name is only used
-// for bolt.
+// GetTestBackend opens (or creates if doesn't exist) a bbolt, etcd, postgres
+// or sqlite backed database (for testing), and returns a kvdb.Backend and a
+// cleanup func. Whether to create/open bbolt, embedded etcd, postgres or sqlite
+// database is based on the TestBackend constant which is conditionally
+// compiled with build tag. The passed path is used to hold all db files, while
+// the name is only used for bolt and sqlite.
 func GetTestBackend(path, name string) (Backend, func(), error) {
 	empty := func() {}
 
-	if TestBackend == BoltBackendName {
+	switch TestBackend {
+	case BoltBackendName:
 		db, err := GetBoltBackend(path, name, true)
 		if err != nil {
 			return nil, nil, err
 		}
 		return db, empty, nil
-	} else if TestBackend == EtcdBackendName {
+
+	case EtcdBackendName:
 		return GetEtcdTestBackend(path, name)
+
+	case PostgresBackendName:
+		return GetPostgresTestBackend(path, name)
+
+	case SqliteBackendName:
+		return GetSqliteTestBackend(path, name)
 	}
 
 	return nil, nil, fmt.Errorf("unknown backend")
@@ -0,0 +1,430 @@
+package kvdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	// DefaultBoltAutoCompactMinAge is the default minimum time that must
+	// have passed since a bolt database file was last compacted for the
+	// compaction to be considered again.
+	DefaultBoltAutoCompactMinAge = time.Hour * 24 * 7
+
+	// lastCompactionFileSuffix is the suffix of the file next to the
+	// database file that records the time and the resulting size of the
+	// last compaction.
+	lastCompactionFileSuffix = ".last-compacted"
+
+	// compactionRequestFileSuffix is the suffix of the file next to the
+	// database file whose existence requests the database to be compacted
+	// the next time it is opened.
+	compactionRequestFileSuffix = ".compact-requested"
+
+	// compactionTempFileSuffix is the suffix of the file the compacted
+	// database is written to before it replaces the original file.
+	compactionTempFileSuffix = ".compact-tmp"
+
+	// compactionTxMaxSize is the maximum number of bytes of keys and
+	// values written in a single transaction while compacting, which
+	// bounds the memory used by the compaction.
+	compactionTxMaxSize = 64 * 1024 * 1024
+
+	// compactionProgressStep is the percentage of progress after which
+	// the progress of a compaction is logged.
+	compactionProgressStep = 10
+
+	// dbFilePermission is the permission of the compacted database file.
+	dbFilePermission = 0600
+)
+
+// lastCompaction holds the time and resulting file size of the last
+// compaction of a database file.
+type lastCompaction struct {
+	time time.Time
+	size int64
+}
+
+// readLastCompaction returns the last compaction of the database file at the
+// given path, or nil if it was never compacted.
+func readLastCompaction(dbFilePath string) (*lastCompaction, error) {
+	data, err := ioutil.ReadFile(dbFilePath + lastCompactionFileSuffix)
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+
+	case err != nil:
+		return nil, err
+
+	case len(data) != 16:
+		return nil, fmt.Errorf("invalid last compaction file of %v",
+			dbFilePath)
+	}
+
+	return &lastCompaction{
+		time: time.Unix(0, int64(binary.BigEndian.Uint64(data[:8]))),
+		size: int64(binary.BigEndian.Uint64(data[8:])),
+	}, nil
+}
+
+// writeLastCompaction records the given compaction of the database file at
+// the given path.
+func writeLastCompaction(dbFilePath string, c *lastCompaction) error {
+	var data [16]byte
+	binary.BigEndian.PutUint64(data[:8], uint64(c.time.UnixNano()))
+	binary.BigEndian.PutUint64(data[8:], uint64(c.size))
+
+	return ioutil.WriteFile(
+		dbFilePath+lastCompactionFileSuffix, data[:], dbFilePermission,
+	)
+}
+
+// RequestBoltCompaction requests the bolt database file with the given name
+// in path to be compacted the next time it is opened through
+// CompactBoltBackend, regardless of the auto compaction settings. Passing
+// cancel withdraws a previous request.
+func RequestBoltCompaction(path, name string, cancel bool) error {
+	requestFilePath := filepath.Join(path, name) +
+		compactionRequestFileSuffix
+
+	if cancel {
+		err := os.Remove(requestFilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	if !fileExists(filepath.Join(path, name)) {
+		return fmt.Errorf("database file %v not found",
+			filepath.Join(path, name))
+	}
+
+	return ioutil.WriteFile(requestFilePath, nil, dbFilePermission)
+}
+
+// shouldCompact returns true if the database file at the given path needs to
+// be compacted according to the config, along with the reason.
+func shouldCompact(dbFilePath string, config *BoltConfig,
+	now time.Time) (bool, string, error) {
+
+	if fileExists(dbFilePath + compactionRequestFileSuffix) {
+		return true, "compaction was requested", nil
+	}
+
+	if !config.AutoCompact {
+		return false, "", nil
+	}
+
+	last, err := readLastCompaction(dbFilePath)
+	if err != nil {
+		return false, "", err
+	}
+	if last == nil {
+		return true, "database was never compacted", nil
+	}
+
+	age := now.Sub(last.time)
+	if age >= config.AutoCompactMinAge {
+		return true, fmt.Sprintf("last compaction was %v ago",
+			age.Truncate(time.Second)), nil
+	}
+
+	if config.AutoCompactMinGrowth == 0 {
+		return false, "", nil
+	}
+
+	info, err := os.Stat(dbFilePath)
+	if err != nil {
+		return false, "", err
+	}
+
+	growth := info.Size() - last.size
+	if growth >= int64(config.AutoCompactMinGrowth) {
+		return true, fmt.Sprintf("database grew by %d bytes since the "+
+			"last compaction", growth), nil
+	}
+
+	return false, "", nil
+}
+
+// CompactBoltBackend compacts the bolt database file with the given name in
+// path if it was requested through RequestBoltCompaction, or if auto
+// compaction is enabled and the last compaction is older than the minimum age
+// or the file grew by more than the minimum growth since. It must be called
+// before the database is opened.
+//
+// Bolt never returns the pages freed by deleted data to the file system, so
+// the compaction copies all buckets into a new file, which then replaces the
+// original file. This requires enough free disk space for a full copy of the
+// database.
+func CompactBoltBackend(path, name string, config *BoltConfig) error {
+	dbFilePath := filepath.Join(path, name)
+	if !fileExists(dbFilePath) {
+		return nil
+	}
+
+	now := time.Now()
+	compact, reason, err := shouldCompact(dbFilePath, config, now)
+	if err != nil {
+		return fmt.Errorf("unable to determine whether to compact %v: "+
+			"%v", dbFilePath, err)
+	}
+	if !compact {
+		return nil
+	}
+
+	log.Infof("Compacting database file %v, %v", dbFilePath, reason)
+
+	oldSize, newSize, err := compactBoltFile(dbFilePath)
+	if err != nil {
+		return fmt.Errorf("unable to compact %v: %v", dbFilePath, err)
+	}
+
+	log.Infof("Compacted database file %v from %d to %d bytes in %v",
+		dbFilePath, oldSize, newSize,
+		time.Since(now).Truncate(time.Millisecond))
+
+	err = writeLastCompaction(dbFilePath, &lastCompaction{
+		time: now,
+		size: newSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(dbFilePath + compactionRequestFileSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// compactBoltFile copies the database file at the given path into a new
+// file, which replaces the original file once the copy is complete. It
+// returns the size of the file before and after the compaction.
+func compactBoltFile(dbFilePath string) (int64, int64, error) {
+	info, err := os.Stat(dbFilePath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Remove the leftovers of an interrupted compaction.
+	tempFilePath := dbFilePath + compactionTempFileSuffix
+	err = os.Remove(tempFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, 0, err
+	}
+
+	src, err := bbolt.Open(dbFilePath, dbFilePermission, &bbolt.Options{
+		ReadOnly: true,
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	dst, err := bbolt.Open(tempFilePath, dbFilePermission, nil)
+	if err != nil {
+		src.Close()
+		return 0, 0, err
+	}
+
+	err = copyBoltDB(dst, src)
+
+	src.Close()
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFilePath)
+		return 0, 0, err
+	}
+
+	newInfo, err := os.Stat(tempFilePath)
+	if err != nil {
+		os.Remove(tempFilePath)
+		return 0, 0, err
+	}
+
+	if err := os.Rename(tempFilePath, dbFilePath); err != nil {
+		os.Remove(tempFilePath)
+		return 0, 0, err
+	}
+
+	return info.Size(), newInfo.Size(), nil
+}
+
+// copyBoltDB copies all buckets of src into the empty database dst. The
+// copy is split into several transactions to bound its memory usage, and its
+// progress is logged.
+func copyBoltDB(dst, src *bbolt.DB) error {
+	return src.View(func(srcTx *bbolt.Tx) error {
+		// Count the keys first, so we can report the progress.
+		var numKeys int
+		err := srcTx.ForEach(func(_ []byte, b *bbolt.Bucket) error {
+			numKeys += b.Stats().KeyN
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		c := &boltCopier{
+			dst:     dst,
+			numKeys: numKeys,
+		}
+		if err := c.begin(); err != nil {
+			return err
+		}
+		defer func() {
+			c.tx.Rollback()
+		}()
+
+		err = srcTx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			return c.copyBucket(nil, name, b)
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.tx.Commit()
+	})
+}
+
+// boltCopier copies buckets into a database, committing the transaction
+// whenever it grows too large.
+type boltCopier struct {
+	dst *bbolt.DB
+
+	// tx is the current write transaction of dst.
+	tx *bbolt.Tx
+
+	// txSize is the number of bytes of keys and values written in tx.
+	txSize int
+
+	// numKeys is the number of keys in the source database.
+	numKeys int
+
+	// copiedKeys is the number of keys copied so far.
+	copiedKeys int
+
+	// loggedPercent is the progress that was logged last.
+	loggedPercent int
+}
+
+// begin starts a new write transaction.
+func (c *boltCopier) begin() error {
+	tx, err := c.dst.Begin(true)
+	if err != nil {
+		return err
+	}
+
+	c.tx = tx
+	c.txSize = 0
+
+	return nil
+}
+
+// bucket returns the bucket at the given path in the current transaction.
+func (c *boltCopier) bucket(path [][]byte) *bbolt.Bucket {
+	b := c.tx.Bucket(path[0])
+	for _, key := range path[1:] {
+		b = b.Bucket(key)
+	}
+
+	return b
+}
+
+// put stores a key/value pair, or creates a nested bucket with the given
+// sequence if the value is nil, in the bucket at the given path.
+func (c *boltCopier) put(path [][]byte, k, v []byte, seq uint64) error {
+	// Commit the transaction before it grows too large.
+	size := len(k) + len(v)
+	if c.txSize+size > compactionTxMaxSize {
+		if err := c.tx.Commit(); err != nil {
+			return err
+		}
+		if err := c.begin(); err != nil {
+			return err
+		}
+	}
+	c.txSize += size
+
+	if len(path) == 0 {
+		b, err := c.tx.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+
+		return b.SetSequence(seq)
+	}
+
+	c.copiedKeys++
+	c.logProgress()
+
+	parent := c.bucket(path)
+
+	// Fill the entire page for best compaction.
+	parent.FillPercent = 1.0
+
+	if v != nil {
+		return parent.Put(k, v)
+	}
+
+	b, err := parent.CreateBucket(k)
+	if err != nil {
+		return err
+	}
+
+	return b.SetSequence(seq)
+}
+
+// copyBucket copies the bucket b with the given key, which is nested in the
+// bucket at the given path, along with all its contents.
+func (c *boltCopier) copyBucket(path [][]byte, key []byte,
+	b *bbolt.Bucket) error {
+
+	if err := c.put(path, key, nil, b.Sequence()); err != nil {
+		return err
+	}
+
+	// Copy the path, as appending may reuse the array of the parent.
+	path = append(append([][]byte(nil), path...), key)
+
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return c.copyBucket(path, k, b.Bucket(k))
+		}
+
+		return c.put(path, k, v, 0)
+	})
+}
+
+// logProgress logs the progress of the copy whenever it advanced by another
+// step.
+func (c *boltCopier) logProgress() {
+	if c.numKeys == 0 {
+		return
+	}
+
+	percent := c.copiedKeys * 100 / c.numKeys
+	if percent > 100 {
+		percent = 100
+	}
+	if percent < c.loggedPercent+compactionProgressStep {
+		return
+	}
+
+	c.loggedPercent = percent - percent%compactionProgressStep
+	log.Infof("Compaction progress: %d%% (%d of %d keys)", percent,
+		c.copiedKeys, c.numKeys)
+}
@@ -0,0 +1,200 @@
+package kvdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDBName  = "test.db"
+	numTestKeys = 2000
+)
+
+// fillTestDB creates the test database in path with a nested bucket holding
+// numTestKeys keys, and then deletes all but the first ten keys.
+func fillTestDB(t *testing.T, path string) {
+	db, err := GetBoltBackend(path, testDBName, true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	value := make([]byte, 512)
+	err = Update(db, func(tx RwTx) error {
+		top, err := tx.CreateTopLevelBucket([]byte("top"))
+		if err != nil {
+			return err
+		}
+		nested, err := top.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		if err := nested.SetSequence(42); err != nil {
+			return err
+		}
+
+		for i := 0; i < numTestKeys; i++ {
+			key := []byte{byte(i >> 8), byte(i)}
+			if err := nested.Put(key, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = Update(db, func(tx RwTx) error {
+		top := tx.ReadWriteBucket([]byte("top"))
+		nested := top.NestedReadWriteBucket([]byte("nested"))
+		for i := 10; i < numTestKeys; i++ {
+			key := []byte{byte(i >> 8), byte(i)}
+			if err := nested.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// assertTestDB asserts that the test database in path holds the data left by
+// fillTestDB.
+func assertTestDB(t *testing.T, path string) {
+	db, err := GetBoltBackend(path, testDBName, true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = View(db, func(tx RTx) error {
+		top := tx.ReadBucket([]byte("top"))
+		require.NotNil(t, top)
+
+		nested := top.NestedReadBucket([]byte("nested"))
+		require.NotNil(t, nested)
+		require.Equal(
+			t, uint64(42), nested.(RwBucket).Sequence(),
+		)
+
+		var numKeys int
+		err := nested.ForEach(func(k, v []byte) error {
+			require.Len(t, v, 512)
+			numKeys++
+			return nil
+		})
+		require.Equal(t, 10, numKeys)
+
+		return err
+	})
+	require.NoError(t, err)
+}
+
+// fileSize returns the size of the file at the given path.
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	return info.Size()
+}
+
+// TestCompactBoltBackend asserts that a requested compaction shrinks the
+// database file without altering its contents.
+func TestCompactBoltBackend(t *testing.T) {
+	t.Parallel()
+
+	path, err := ioutil.TempDir("", "compact")
+	require.NoError(t, err)
+	defer os.RemoveAll(path)
+
+	dbFilePath := filepath.Join(path, testDBName)
+	fillTestDB(t, path)
+	oldSize := fileSize(t, dbFilePath)
+
+	// Nothing happens unless the compaction is requested or auto
+	// compaction is enabled.
+	config := &BoltConfig{}
+	require.NoError(t, CompactBoltBackend(path, testDBName, config))
+	require.Equal(t, oldSize, fileSize(t, dbFilePath))
+
+	// A withdrawn request doesn't trigger the compaction either.
+	require.NoError(t, RequestBoltCompaction(path, testDBName, false))
+	require.NoError(t, RequestBoltCompaction(path, testDBName, true))
+	require.NoError(t, CompactBoltBackend(path, testDBName, config))
+	require.Equal(t, oldSize, fileSize(t, dbFilePath))
+
+	require.NoError(t, RequestBoltCompaction(path, testDBName, false))
+	require.NoError(t, CompactBoltBackend(path, testDBName, config))
+
+	newSize := fileSize(t, dbFilePath)
+	require.True(t, newSize < oldSize/10)
+	require.False(t, fileExists(dbFilePath+compactionRequestFileSuffix))
+	require.False(t, fileExists(dbFilePath+compactionTempFileSuffix))
+
+	last, err := readLastCompaction(dbFilePath)
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	require.Equal(t, newSize, last.size)
+
+	assertTestDB(t, path)
+}
+
+// TestShouldCompact asserts that auto compaction is triggered by the age of
+// the last compaction and the growth of the database file since.
+func TestShouldCompact(t *testing.T) {
+	t.Parallel()
+
+	path, err := ioutil.TempDir("", "compact")
+	require.NoError(t, err)
+	defer os.RemoveAll(path)
+
+	dbFilePath := filepath.Join(path, testDBName)
+	err = ioutil.WriteFile(dbFilePath, make([]byte, 1000), 0600)
+	require.NoError(t, err)
+
+	now := time.Now()
+	config := &BoltConfig{
+		AutoCompact:       true,
+		AutoCompactMinAge: time.Hour,
+	}
+
+	// A database that was never compacted is compacted.
+	compact, _, err := shouldCompact(dbFilePath, config, now)
+	require.NoError(t, err)
+	require.True(t, compact)
+
+	err = writeLastCompaction(dbFilePath, &lastCompaction{
+		time: now.Add(-time.Minute),
+		size: 500,
+	})
+	require.NoError(t, err)
+
+	// The last compaction is too recent.
+	compact, _, err = shouldCompact(dbFilePath, config, now)
+	require.NoError(t, err)
+	require.False(t, compact)
+
+	// Unless the file grew enough since.
+	config.AutoCompactMinGrowth = 500
+	compact, _, err = shouldCompact(dbFilePath, config, now)
+	require.NoError(t, err)
+	require.True(t, compact)
+
+	config.AutoCompactMinGrowth = 501
+	compact, _, err = shouldCompact(dbFilePath, config, now)
+	require.NoError(t, err)
+	require.False(t, compact)
+
+	// Or the last compaction is old enough.
+	compact, _, err = shouldCompact(dbFilePath, config, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, compact)
+
+	// Nothing is compacted automatically if auto compaction is disabled.
+	config.AutoCompact = false
+	compact, _, err = shouldCompact(dbFilePath, config, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, compact)
+}
@@ -155,9 +155,10 @@ type BackendConfig struct {
 	CollectCommitStats bool
 }
 
-// newEtcdBackend returns a db object initialized with the passed backend
-// config. If etcd connection cannot be estabished, then returns error.
-func newEtcdBackend(config BackendConfig) (*db, error) {
+// NewClient returns an etcd client connected to the etcd instance of the
+// passed backend config. If etcd connection cannot be estabished, then returns
+// error.
+func NewClient(config BackendConfig) (*clientv3.Client, error) {
 	if config.Ctx == nil {
 		config.Ctx = context.Background()
 	}
@@ -173,7 +174,7 @@ func newEtcdBackend(config BackendConfig) (*db, error) {
 		return nil, err
 	}
 
-	cli, err := clientv3.New(clientv3.Config{
+	return clientv3.New(clientv3.Config{
 		Context:     config.Ctx,
 		Endpoints:   []string{config.Host},
 		DialTimeout: etcdConnectionTimeout,
@@ -181,7 +182,16 @@ func newEtcdBackend(config BackendConfig) (*db, error) {
 		Password:    config.Pass,
 		TLS:         tlsConfig,
 	})
+}
+
+// newEtcdBackend returns a db object initialized with the passed backend
+// config. If etcd connection cannot be estabished, then returns error.
+func newEtcdBackend(config BackendConfig) (*db, error) {
+	if config.Ctx == nil {
+		config.Ctx = context.Background()
+	}
 
+	cli, err := NewClient(config)
 	if err != nil {
 		return nil, err
 	}
@@ -8,10 +8,6 @@ import (
 	"github.com/decred/dcrlnd/channeldb/kvdb/etcd"
 )
 
-// TestBackend is conditionally set to etcd when the kvdb_etcd build tag is
-// defined, allowing testing our database code with etcd backend.
-const TestBackend = EtcdBackendName
-
 // GetEtcdBackend returns an etcd backend configured according to the
 // passed etcdConfig.
 func GetEtcdBackend(ctx context.Context, prefix string,
@@ -0,0 +1,24 @@
+// +build !kvdb_postgres
+
+package kvdb
+
+import (
+	"context"
+	"fmt"
+)
+
+var errPostgresNotAvailable = fmt.Errorf("postgres backend not available")
+
+// GetPostgresBackend is a stub returning nil and errPostgresNotAvailable
+// error.
+func GetPostgresBackend(ctx context.Context, prefix string,
+	postgresConfig *PostgresConfig) (Backend, error) {
+
+	return nil, errPostgresNotAvailable
+}
+
+// GetPostgresTestBackend is a stub returning nil, an empty closure and an
+// errPostgresNotAvailable error.
+func GetPostgresTestBackend(path, name string) (Backend, func(), error) {
+	return nil, func() {}, errPostgresNotAvailable
+}
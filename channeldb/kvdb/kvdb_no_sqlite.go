@@ -0,0 +1,23 @@
+// +build !kvdb_sqlite
+
+package kvdb
+
+import (
+	"context"
+	"fmt"
+)
+
+var errSqliteNotAvailable = fmt.Errorf("sqlite backend not available")
+
+// GetSqliteBackend is a stub returning nil and errSqliteNotAvailable error.
+func GetSqliteBackend(ctx context.Context, dbPath, dbFileName string,
+	sqliteConfig *SqliteConfig) (Backend, error) {
+
+	return nil, errSqliteNotAvailable
+}
+
+// GetSqliteTestBackend is a stub returning nil, an empty closure and an
+// errSqliteNotAvailable error.
+func GetSqliteTestBackend(path, name string) (Backend, func(), error) {
+	return nil, func() {}, errSqliteNotAvailable
+}
@@ -0,0 +1,51 @@
+// +build kvdb_postgres
+
+package kvdb
+
+import (
+	"context"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb/postgres"
+)
+
+// GetPostgresBackend returns a postgres backend configured according to the
+// passed postgresConfig.
+func GetPostgresBackend(ctx context.Context, prefix string,
+	postgresConfig *PostgresConfig) (Backend, error) {
+
+	// Config translation is needed here in order to keep the
+	// postgres package fully independent from the rest of the source
+	// tree.
+	backendConfig := postgres.BackendConfig{
+		Ctx:            ctx,
+		Dsn:            postgresConfig.Dsn,
+		Prefix:         prefix,
+		Timeout:        postgresConfig.Timeout,
+		MaxConnections: postgresConfig.MaxConnections,
+	}
+
+	return Open(PostgresBackendName, backendConfig)
+}
+
+// GetPostgresTestBackend creates a postgres backend for testing, which is
+// stored in a fresh table of the database given by the
+// DCRLND_POSTGRES_TEST_DSN environment variable.
+func GetPostgresTestBackend(path, name string) (Backend, func(), error) {
+	empty := func() {}
+
+	config, cleanup, err := postgres.NewTestInstance()
+	if err != nil {
+		return nil, empty, err
+	}
+
+	backend, err := Open(PostgresBackendName, *config)
+	if err != nil {
+		cleanup()
+		return nil, empty, err
+	}
+
+	return backend, func() {
+		backend.Close()
+		cleanup()
+	}, nil
+}
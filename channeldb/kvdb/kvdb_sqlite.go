@@ -0,0 +1,47 @@
+// +build kvdb_sqlite
+
+package kvdb
+
+import (
+	"context"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb/sqlite"
+)
+
+// GetSqliteBackend returns a sqlite backend configured according to the
+// passed sqliteConfig, which is stored in the file with the given name in
+// dbPath.
+func GetSqliteBackend(ctx context.Context, dbPath, dbFileName string,
+	sqliteConfig *SqliteConfig) (Backend, error) {
+
+	// Config translation is needed here in order to keep the
+	// sqlite package fully independent from the rest of the source
+	// tree.
+	backendConfig := sqlite.BackendConfig{
+		Ctx:         ctx,
+		DBPath:      dbPath,
+		DBFileName:  dbFileName,
+		BusyTimeout: sqliteConfig.BusyTimeout,
+		Timeout:     sqliteConfig.Timeout,
+		NoVacuum:    sqliteConfig.NoVacuum,
+	}
+
+	return Open(SqliteBackendName, backendConfig)
+}
+
+// GetSqliteTestBackend creates a sqlite backend for testing, which is stored
+// in the file with the given name in path.
+func GetSqliteTestBackend(path, name string) (Backend, func(), error) {
+	empty := func() {}
+
+	backend, err := GetSqliteBackend(
+		context.Background(), path, name, &SqliteConfig{},
+	)
+	if err != nil {
+		return nil, empty, err
+	}
+
+	return backend, func() {
+		backend.Close()
+	}, nil
+}
@@ -0,0 +1,183 @@
+// +build kvdb_postgres
+
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/decred/dcrlnd/channeldb/kvdb/sqlbase"
+	"github.com/lib/pq"
+)
+
+const (
+	// driverName is the name of the database/sql driver of PostgreSQL.
+	driverName = "postgres"
+
+	// serializationFailure is the error code of transactions that failed
+	// because of a concurrent transaction.
+	serializationFailure = "40001"
+
+	// deadlockDetected is the error code of transactions that were
+	// aborted to resolve a deadlock.
+	deadlockDetected = "40P01"
+)
+
+// invalidTableChars matches the characters of a prefix that aren't valid in
+// table names.
+var invalidTableChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// BackendConfig holds the configuration and connection parameters of a
+// PostgreSQL backend.
+type BackendConfig struct {
+	// Ctx is the context we use to cancel operations upon exit.
+	Ctx context.Context
+
+	// Dsn is the connection string of the database.
+	Dsn string
+
+	// Prefix is used to name the table of the database, which enables
+	// several databases to share the same PostgreSQL database.
+	Prefix string
+
+	// Timeout is the maximum duration of a transaction. It is unlimited
+	// if zero.
+	Timeout time.Duration
+
+	// MaxConnections is the maximum number of open connections to the
+	// database. It is unlimited if zero.
+	MaxConnections int
+}
+
+// tableName returns the name of the table of the database with the given
+// prefix.
+func tableName(prefix string) string {
+	return "kv_" + invalidTableChars.ReplaceAllString(prefix, "_")
+}
+
+// isConflictError returns true if the error signals that a transaction failed
+// because of a concurrent transaction and should be retried.
+func isConflictError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	return pqErr.Code == serializationFailure ||
+		pqErr.Code == deadlockDetected
+}
+
+// copyTable writes the rows of the table holding the bucket tree to w in the
+// text format of the COPY command, one row per line with the columns id,
+// parent_id, key, value and sequence. The rows are read within a single
+// repeatable read transaction, so they form a consistent snapshot, and are
+// ordered by id, so every bucket precedes its children. The copy can be loaded
+// into an empty table created with the same schema, e.g. with psql:
+//
+//	\copy <table> (id, parent_id, key, value, sequence) FROM '<file>'
+//
+// after which the sequence of the id column has to be set past the largest
+// restored id.
+func copyTable(ctx context.Context, table string, sqlDB *sql.DB,
+	w io.Writer) error {
+
+	tx, err := sqlDB.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, parent_id, key, "+
+		"value, value IS NULL, sequence FROM "+table+" ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("unable to copy table %v: %v", table, err)
+	}
+	defer rows.Close()
+
+	bufWriter := bufio.NewWriter(w)
+	for rows.Next() {
+		var (
+			id, sequence int64
+			parentID     sql.NullInt64
+			key, value   []byte
+			isBucket     bool
+		)
+		err := rows.Scan(
+			&id, &parentID, &key, &value, &isBucket, &sequence,
+		)
+		if err != nil {
+			return err
+		}
+
+		// NULL is written as \N, and bytea values in the hex format,
+		// whose leading backslash has to be escaped.
+		parent := `\N`
+		if parentID.Valid {
+			parent = strconv.FormatInt(parentID.Int64, 10)
+		}
+		val := `\N`
+		if !isBucket {
+			val = `\\x` + hex.EncodeToString(value)
+		}
+
+		_, err = fmt.Fprintf(
+			bufWriter, "%d\t%s\t%s\t%s\t%d\n", id, parent,
+			`\\x`+hex.EncodeToString(key), val, sequence,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return bufWriter.Flush()
+}
+
+// newPostgresBackend returns a db object initialized with the passed backend
+// config. The bucket tree is stored in a single table, which is created if it
+// doesn't exist yet.
+//
+// Write transactions run with serializable isolation, so they behave as if
+// they were run one after another even if several processes share the
+// database. Transactions that fail because of a conflict are retried. Read
+// transactions run with repeatable read isolation, so they see a consistent
+// snapshot of the database.
+func newPostgresBackend(config BackendConfig) (walletdb.DB, error) {
+	table := tableName(config.Prefix)
+
+	return sqlbase.NewDB(&sqlbase.Config{
+		Ctx:            config.Ctx,
+		DriverName:     driverName,
+		Dsn:            config.Dsn,
+		Table:          table,
+		Timeout:        config.Timeout,
+		MaxConnections: config.MaxConnections,
+		ReadTxOptions: &sql.TxOptions{
+			Isolation: sql.LevelRepeatableRead,
+			ReadOnly:  true,
+		},
+		WriteTxOptions: &sql.TxOptions{
+			Isolation: sql.LevelSerializable,
+		},
+		IsConflictError: isConflictError,
+		Copy: func(ctx context.Context, sqlDB *sql.DB,
+			w io.Writer) error {
+
+			return copyTable(ctx, table, sqlDB, w)
+		},
+	})
+}
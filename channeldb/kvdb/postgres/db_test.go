@@ -0,0 +1,179 @@
+// +build kvdb_postgres
+
+package postgres
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPrefixIsolation asserts that databases with different prefixes don't
+// see each other's buckets, and that the data of a database persists once it
+// is reopened.
+func TestPrefixIsolation(t *testing.T) {
+	config1, cleanup1, err := NewTestInstance()
+	require.NoError(t, err)
+	defer cleanup1()
+
+	config2, cleanup2, err := NewTestInstance()
+	require.NoError(t, err)
+	defer cleanup2()
+
+	db1, err := newPostgresBackend(*config1)
+	require.NoError(t, err)
+
+	db2, err := newPostgresBackend(*config2)
+	require.NoError(t, err)
+	defer db2.Close()
+
+	err = walletdb.Update(db1, func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte("key"), []byte("value"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db1.Close())
+
+	err = walletdb.View(db2, func(tx walletdb.ReadTx) error {
+		require.Nil(t, tx.ReadBucket([]byte("bucket")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	db1, err = newPostgresBackend(*config1)
+	require.NoError(t, err)
+	defer db1.Close()
+
+	err = walletdb.View(db1, func(tx walletdb.ReadTx) error {
+		bucket := tx.ReadBucket([]byte("bucket"))
+		require.NotNil(t, bucket)
+		require.Equal(t, []byte("value"), bucket.Get([]byte("key")))
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestConcurrentWriters asserts that the write transactions of processes
+// sharing the database are serialized, by incrementing a counter from two
+// backends concurrently.
+func TestConcurrentWriters(t *testing.T) {
+	config, cleanup, err := NewTestInstance()
+	require.NoError(t, err)
+	defer cleanup()
+
+	const numIncrements = 50
+
+	var wg sync.WaitGroup
+	errChan := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		db, err := newPostgresBackend(*config)
+		require.NoError(t, err)
+		defer db.Close()
+
+		extendedDB := db.(interface {
+			Update(func(walletdb.ReadWriteTx) error) error
+		})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < numIncrements; j++ {
+				err := extendedDB.Update(incrementCounter)
+				if err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(errChan)
+	for err := range errChan {
+		require.NoError(t, err)
+	}
+
+	db, err := newPostgresBackend(*config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bucket := tx.ReadBucket([]byte("counter"))
+		require.Equal(
+			t, uint64(2*numIncrements),
+			bucket.(walletdb.ReadWriteBucket).Sequence(),
+		)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// incrementCounter increments the sequence of the counter bucket.
+func incrementCounter(tx walletdb.ReadWriteTx) error {
+	bucket, err := tx.CreateTopLevelBucket([]byte("counter"))
+	if err != nil {
+		return err
+	}
+
+	_, err = bucket.NextSequence()
+	return err
+}
+
+// TestCopy asserts that the copy of a database holds the rows of its bucket
+// tree in the text format of the COPY command.
+func TestCopy(t *testing.T) {
+	config, cleanup, err := NewTestInstance()
+	require.NoError(t, err)
+	defer cleanup()
+
+	db, err := newPostgresBackend(*config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+		if err := bucket.SetSequence(42); err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte("key"), []byte("value"))
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, db.Copy(&buf))
+
+	// The rows are the pseudo root bucket, the bucket and its key/value
+	// pair, each of them a child of the previous one.
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+
+	var rows [][]string
+	for _, line := range lines {
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+
+	require.Equal(t, []string{`\N`, `\\x`, `\N`, "0"}, rows[0][1:])
+	require.Equal(
+		t, []string{rows[0][0], `\\x6275636b6574`, `\N`, "42"},
+		rows[1][1:],
+	)
+	require.Equal(
+		t, []string{rows[1][0], `\\x6b6579`, `\\x76616c7565`, "0"},
+		rows[2][1:],
+	)
+}
@@ -0,0 +1,21 @@
+// +build kvdb_postgres
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb/walletdbtest"
+)
+
+// TestWalletDBInterface performs the WalletDB interface test suite for the
+// postgres database driver.
+func TestWalletDBInterface(t *testing.T) {
+	config, cleanup, err := NewTestInstance()
+	if err != nil {
+		t.Fatalf("unable to create test instance: %v", err)
+	}
+	defer cleanup()
+
+	walletdbtest.TestInterface(t, dbType, *config)
+}
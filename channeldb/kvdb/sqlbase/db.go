@@ -0,0 +1,334 @@
+package sqlbase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+const (
+	// maxTxRetries is the number of times a transaction is retried after
+	// it failed because of a conflict with a concurrent transaction.
+	maxTxRetries = 10
+
+	// txRetryDelay is the delay before retrying a transaction, which is
+	// doubled with every retry.
+	txRetryDelay = 10 * time.Millisecond
+)
+
+// ErrNotSupported is returned by the operations that aren't supported by the
+// sql backends.
+var ErrNotSupported = errors.New("operation not supported by sql backend")
+
+// validTableName matches the table names that are accepted by NewDB.
+var validTableName = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// schema is the list of statements that create the table holding the bucket
+// tree. Each row is either a key/value pair or a bucket, in which case its
+// value is NULL. The single row without a parent is the pseudo root bucket,
+// whose children are the top level buckets. Deleting a bucket deletes its
+// whole subtree through the foreign key constraint.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS {table} (
+		id BIGSERIAL PRIMARY KEY,
+		parent_id BIGINT REFERENCES {table} (id) ON DELETE CASCADE,
+		key BYTEA NOT NULL,
+		value BYTEA,
+		sequence BIGINT NOT NULL DEFAULT 0
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS {table}_parent_key
+		ON {table} (parent_id, key)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS {table}_root
+		ON {table} (key) WHERE parent_id IS NULL`,
+}
+
+// Config holds the configuration of a sql backend.
+type Config struct {
+	// Ctx is the context we use to cancel operations upon exit.
+	Ctx context.Context
+
+	// DriverName is the name of the database/sql driver to use.
+	DriverName string
+
+	// Dsn is the data source name passed to the driver.
+	Dsn string
+
+	// Table is the name of the table that holds the bucket tree, which
+	// allows several databases to share the same sql database.
+	Table string
+
+	// Timeout is the maximum duration of a transaction. It is unlimited
+	// if zero.
+	Timeout time.Duration
+
+	// MaxConnections is the maximum number of open connections to the
+	// database. It is unlimited if zero.
+	MaxConnections int
+
+	// SchemaReplacements maps the types used in the schema and queries,
+	// which are those of PostgreSQL, to the types of the database in use.
+	SchemaReplacements map[string]string
+
+	// ReadTxOptions are the options of read-only transactions.
+	ReadTxOptions *sql.TxOptions
+
+	// WriteTxOptions are the options of read-write transactions.
+	WriteTxOptions *sql.TxOptions
+
+	// IsConflictError returns true if the error signals that a transaction
+	// failed because of a conflict with a concurrent transaction, in which
+	// case it is retried.
+	IsConflictError func(error) bool
+
+	// InitStatements are run once the table of the bucket tree exists,
+	// which allows backends to maintain the database when it is opened.
+	InitStatements []string
+
+	// Copy writes a consistent copy of the database to the writer. Copies
+	// aren't supported by the backend if nil.
+	Copy func(ctx context.Context, db *sql.DB, w io.Writer) error
+}
+
+// db is a walletdb.DB that stores the bucket tree in a sql table.
+type db struct {
+	cfg *Config
+
+	db *sql.DB
+
+	// rootID is the id of the pseudo root bucket.
+	rootID int64
+
+	// queries holds the queries on the table of the database.
+	queries *queries
+
+	// writeMtx serializes the write transactions of this process, as
+	// bolt does. Write transactions of other processes sharing the
+	// database are serialized by the database itself.
+	writeMtx sync.Mutex
+}
+
+// Enforce db implements the walletdb.DB interface.
+var _ walletdb.DB = (*db)(nil)
+
+// NewDB opens the database described by the config, creating the table of
+// the bucket tree if it doesn't exist yet.
+func NewDB(cfg *Config) (walletdb.DB, error) {
+	if !validTableName.MatchString(cfg.Table) {
+		return nil, fmt.Errorf("invalid table name %q", cfg.Table)
+	}
+
+	if cfg.Ctx == nil {
+		cfg.Ctx = context.Background()
+	}
+	if cfg.IsConflictError == nil {
+		cfg.IsConflictError = func(error) bool { return false }
+	}
+
+	sqlDB, err := sql.Open(cfg.DriverName, cfg.Dsn)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxConnections)
+
+	backend := &db{
+		cfg:     cfg,
+		db:      sqlDB,
+		queries: newQueries(cfg.Table, cfg.SchemaReplacements),
+	}
+	if err := backend.init(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+// init creates the table of the bucket tree and its pseudo root bucket if
+// they don't exist yet, and runs the init statements of the config.
+func (db *db) init() error {
+	replacer := strings.NewReplacer("{table}", db.cfg.Table)
+	for _, stmt := range schema {
+		stmt = replacer.Replace(stmt)
+		for from, to := range db.cfg.SchemaReplacements {
+			stmt = strings.ReplaceAll(stmt, from, to)
+		}
+
+		if _, err := db.db.ExecContext(db.cfg.Ctx, stmt); err != nil {
+			return fmt.Errorf("unable to create table %v: %v",
+				db.cfg.Table, err)
+		}
+	}
+
+	_, err := db.db.ExecContext(db.cfg.Ctx, db.queries.createRoot)
+	if err != nil {
+		return fmt.Errorf("unable to create root bucket: %v", err)
+	}
+
+	row := db.db.QueryRowContext(db.cfg.Ctx, db.queries.root)
+	if err := row.Scan(&db.rootID); err != nil {
+		return fmt.Errorf("unable to fetch root bucket: %v", err)
+	}
+
+	for _, stmt := range db.cfg.InitStatements {
+		if err := db.runStatement(stmt); err != nil {
+			return fmt.Errorf("unable to initialize database: %v",
+				err)
+		}
+	}
+
+	return nil
+}
+
+// runStatement runs the statement to completion. It is run as a query whose
+// rows are drained, as some drivers only run the first step of statements
+// that return rows when they are executed.
+func (db *db) runStatement(stmt string) error {
+	rows, err := db.db.QueryContext(db.cfg.Ctx, stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+
+	return rows.Err()
+}
+
+// executeTransaction runs f in a new transaction, which is committed if f
+// succeeds and the transaction isn't read-only. Transactions that fail
+// because of a conflict with a concurrent transaction are retried.
+func (db *db) executeTransaction(f func(tx *readWriteTx) error,
+	readOnly bool) error {
+
+	delay := txRetryDelay
+	for i := 0; ; i++ {
+		err := db.tryTransaction(f, readOnly)
+		if err == nil || i == maxTxRetries ||
+			!db.cfg.IsConflictError(err) {
+
+			return err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-db.cfg.Ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// tryTransaction runs f in a new transaction once.
+func (db *db) tryTransaction(f func(tx *readWriteTx) error,
+	readOnly bool) error {
+
+	tx, err := newReadWriteTx(db, readOnly)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the transaction is rolled back and its write lock released
+	// if f panics, as bolt does.
+	defer func() {
+		if tx.active {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = f(tx)
+
+	// Errors of the database take precedence, as they may have caused
+	// the error of f.
+	if tx.err != nil {
+		err = tx.err
+	}
+
+	if err != nil || readOnly {
+		rollbackErr := tx.Rollback()
+		if err != nil {
+			return err
+		}
+
+		return rollbackErr
+	}
+
+	return tx.Commit()
+}
+
+// View opens a database read transaction and executes the function f with the
+// transaction passed as a parameter.  After f exits, the transaction is rolled
+// back.  If f errors, its error is returned, not a rollback error (if any
+// occur).
+func (db *db) View(f func(tx walletdb.ReadTx) error) error {
+	return db.executeTransaction(func(tx *readWriteTx) error {
+		return f(tx)
+	}, true)
+}
+
+// Update opens a database read/write transaction and executes the function f
+// with the transaction passed as a parameter.  After f exits, if f did not
+// error, the transaction is committed.  Otherwise, if f did error, the
+// transaction is rolled back.  If the rollback fails, the original error
+// returned by f is still returned.  If the commit fails, the commit error is
+// returned.
+func (db *db) Update(f func(tx walletdb.ReadWriteTx) error) error {
+	return db.executeTransaction(func(tx *readWriteTx) error {
+		return f(tx)
+	}, false)
+}
+
+// Batch opens a database read/write transaction and executes the function f
+// with the transaction passed as a parameter.  After f exits, if f did not
+// error, the transaction is committed.  Otherwise, if f did error, the
+// transaction is rolled back.  If the rollback fails, the original error
+// returned by f is still returned.  If the commit fails, the commit error is
+// returned.
+//
+// Batch is only useful when there are multiple goroutines calling it.
+func (db *db) Batch(f func(tx walletdb.ReadWriteTx) error) error {
+	return db.Update(f)
+}
+
+// PrintStats returns all collected stats pretty printed into a string.
+func (db *db) PrintStats() string {
+	return ""
+}
+
+// BeginReadTx opens a database read transaction.
+func (db *db) BeginReadTx() (walletdb.ReadTx, error) {
+	return newReadWriteTx(db, true)
+}
+
+// BeginReadWriteTx opens a database read+write transaction.
+func (db *db) BeginReadWriteTx() (walletdb.ReadWriteTx, error) {
+	return newReadWriteTx(db, false)
+}
+
+// Copy writes a copy of the database to the provided writer, in the format of
+// the backend. ErrNotSupported is returned if the backend can't copy its
+// database, which should then be backed up with the tools of the database
+// server.
+//
+// This function is part of the walletdb.Db interface implementation.
+func (db *db) Copy(w io.Writer) error {
+	if db.cfg.Copy == nil {
+		return ErrNotSupported
+	}
+
+	return db.cfg.Copy(db.cfg.Ctx, db.db, w)
+}
+
+// Close cleanly shuts down the database and syncs all data.
+// This function is part of the walletdb.Db interface implementation.
+func (db *db) Close() error {
+	return db.db.Close()
+}
@@ -0,0 +1,86 @@
+package sqlbase
+
+import (
+	"fmt"
+	"strings"
+)
+
+// forEachBatchSize is the number of rows that are fetched at once when
+// iterating over a bucket.
+const forEachBatchSize = 1000
+
+// queries holds the queries on the table of the bucket tree. Rows with a NULL
+// value are buckets, so queries returning key/value pairs also return whether
+// the value is NULL to tell buckets from empty values.
+type queries struct {
+	createRoot   string
+	root         string
+	lookup       string
+	get          string
+	createBucket string
+	deleteBucket string
+	put          string
+	delete       string
+	first        string
+	last         string
+	next         string
+	prev         string
+	seek         string
+	firstBatch   string
+	nextBatch    string
+	sequence     string
+	nextSequence string
+	setSequence  string
+}
+
+// newQueries returns the queries on the given table, replacing the types used
+// in the queries according to the given replacements.
+func newQueries(table string, replacements map[string]string) *queries {
+	replacer := strings.NewReplacer("{table}", table)
+	q := func(query string) string {
+		query = replacer.Replace(query)
+		for from, to := range replacements {
+			query = strings.ReplaceAll(query, from, to)
+		}
+
+		return query
+	}
+
+	const kv = "SELECT key, value, value IS NULL FROM {table} "
+	return &queries{
+		createRoot: q("INSERT INTO {table} (key) VALUES ('') " +
+			"ON CONFLICT DO NOTHING"),
+		root: q("SELECT id FROM {table} WHERE parent_id IS NULL"),
+		lookup: q("SELECT id, value IS NULL FROM {table} " +
+			"WHERE parent_id = $1 AND key = $2"),
+		get: q("SELECT value, value IS NULL FROM {table} " +
+			"WHERE parent_id = $1 AND key = $2"),
+		createBucket: q("INSERT INTO {table} (parent_id, key) " +
+			"VALUES ($1, $2) RETURNING id"),
+		deleteBucket: q("DELETE FROM {table} WHERE id = $1"),
+		put: q("INSERT INTO {table} (parent_id, key, value) " +
+			"VALUES ($1, $2, COALESCE($3, CAST('' AS BYTEA))) " +
+			"ON CONFLICT (parent_id, key) " +
+			"DO UPDATE SET value = excluded.value " +
+			"WHERE {table}.value IS NOT NULL"),
+		delete: q("DELETE FROM {table} WHERE parent_id = $1 " +
+			"AND key = $2 AND value IS NOT NULL"),
+		first: q(kv + "WHERE parent_id = $1 ORDER BY key LIMIT 1"),
+		last:  q(kv + "WHERE parent_id = $1 ORDER BY key DESC LIMIT 1"),
+		next: q(kv + "WHERE parent_id = $1 AND key > $2 " +
+			"ORDER BY key LIMIT 1"),
+		prev: q(kv + "WHERE parent_id = $1 AND key < $2 " +
+			"ORDER BY key DESC LIMIT 1"),
+		seek: q(kv + "WHERE parent_id = $1 AND key >= $2 " +
+			"ORDER BY key LIMIT 1"),
+		firstBatch: q(fmt.Sprintf(kv+"WHERE parent_id = $1 "+
+			"ORDER BY key LIMIT %d", forEachBatchSize)),
+		nextBatch: q(fmt.Sprintf(kv+"WHERE parent_id = $1 "+
+			"AND key > $2 ORDER BY key LIMIT %d", forEachBatchSize)),
+		sequence: q("SELECT sequence FROM {table} WHERE id = $1"),
+		nextSequence: q("UPDATE {table} SET sequence = sequence + 1 " +
+			"WHERE id = $1 RETURNING sequence"),
+		setSequence: q("UPDATE {table} SET sequence = $2 " +
+			"WHERE id = $1"),
+	}
+}
@@ -0,0 +1,408 @@
+package sqlbase
+
+import (
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+const (
+	// maxKeySize is the maximum size of a key, which is the same as in
+	// bolt.
+	maxKeySize = 32768
+
+	// maxValueSize is the maximum size of a value, which is the same as in
+	// bolt.
+	maxValueSize = (1 << 31) - 2
+)
+
+// readWriteBucket stores the bucket id and the buckets transaction.
+type readWriteBucket struct {
+	// id is the id of the row of the bucket, which is the parent of the
+	// rows of its key/value pairs and nested buckets.
+	id int64
+
+	// tx holds the parent transaction.
+	tx *readWriteTx
+}
+
+// Enforce readWriteBucket implements the walletdb.ReadWriteBucket interface.
+var _ walletdb.ReadWriteBucket = (*readWriteBucket)(nil)
+
+// newReadWriteBucket creates a new rw bucket with the passed transaction
+// and bucket id.
+func newReadWriteBucket(tx *readWriteTx, id int64) *readWriteBucket {
+	return &readWriteBucket{
+		id: id,
+		tx: tx,
+	}
+}
+
+// lookup returns the id of the row with the given key and whether it is a
+// bucket. It returns false if the key doesn't exist.
+func (b *readWriteBucket) lookup(key []byte) (int64, bool, bool, error) {
+	var (
+		id       int64
+		isBucket bool
+	)
+	found, err := b.tx.queryRow(
+		b.tx.queries().lookup, []interface{}{b.id, key}, &id, &isBucket,
+	)
+
+	return id, isBucket, found, err
+}
+
+// NestedReadBucket retrieves a nested read bucket with the given key.
+// Returns nil if the bucket does not exist.
+func (b *readWriteBucket) NestedReadBucket(key []byte) walletdb.ReadBucket {
+	return b.NestedReadWriteBucket(key)
+}
+
+// ForEach invokes the passed function with every key/value pair in
+// the bucket. This includes nested buckets, in which case the value
+// is nil, but it does not include the key/value pairs within those
+// nested buckets.
+func (b *readWriteBucket) ForEach(cb func(k, v []byte) error) error {
+	// The rows are fetched in batches, as the callback may run queries
+	// of its own, which isn't possible while the rows of a query are
+	// being read.
+	var lastKey []byte
+	for {
+		query := b.tx.queries().firstBatch
+		args := []interface{}{b.id}
+		if lastKey != nil {
+			query = b.tx.queries().nextBatch
+			args = append(args, lastKey)
+		}
+
+		kvs, err := b.queryKeyValues(query, args...)
+		if err != nil {
+			return err
+		}
+
+		for _, kv := range kvs {
+			if err := cb(kv.key, kv.value); err != nil {
+				return err
+			}
+		}
+
+		if len(kvs) < forEachBatchSize {
+			return nil
+		}
+		lastKey = kvs[len(kvs)-1].key
+	}
+}
+
+// keyValue is a key/value pair of a bucket. The value is nil for nested
+// buckets.
+type keyValue struct {
+	key   []byte
+	value []byte
+}
+
+// queryKeyValues runs a query that returns key/value pairs.
+func (b *readWriteBucket) queryKeyValues(query string,
+	args ...interface{}) ([]keyValue, error) {
+
+	if b.tx.err != nil {
+		return nil, b.tx.err
+	}
+	if !b.tx.active {
+		return nil, walletdb.ErrTxClosed
+	}
+
+	rows, err := b.tx.tx.Query(query, args...)
+	if err != nil {
+		b.tx.setErr(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var kvs []keyValue
+	for rows.Next() {
+		var (
+			kv       keyValue
+			isBucket bool
+		)
+		if err := rows.Scan(&kv.key, &kv.value, &isBucket); err != nil {
+			b.tx.setErr(err)
+			return nil, err
+		}
+
+		kv.value = normalizeValue(kv.value, isBucket)
+		kvs = append(kvs, kv)
+	}
+	if err := rows.Err(); err != nil {
+		b.tx.setErr(err)
+		return nil, err
+	}
+
+	return kvs, nil
+}
+
+// normalizeValue returns nil for the value of a nested bucket, and a non-nil
+// slice for values, as empty values are distinct from nested buckets.
+func normalizeValue(value []byte, isBucket bool) []byte {
+	switch {
+	case isBucket:
+		return nil
+
+	case value == nil:
+		return []byte{}
+	}
+
+	return value
+}
+
+// Get returns the value for the given key. Returns nil if the key does
+// not exist in this bucket.
+func (b *readWriteBucket) Get(key []byte) []byte {
+	// Return nil if the key is empty.
+	if len(key) == 0 {
+		return nil
+	}
+
+	var (
+		value    []byte
+		isBucket bool
+	)
+	found, err := b.tx.queryRow(
+		b.tx.queries().get, []interface{}{b.id, key}, &value, &isBucket,
+	)
+	if err != nil || !found {
+		return nil
+	}
+
+	return normalizeValue(value, isBucket)
+}
+
+// ReadCursor returns a new read-only cursor for this bucket.
+func (b *readWriteBucket) ReadCursor() walletdb.ReadCursor {
+	return newReadWriteCursor(b)
+}
+
+// NestedReadWriteBucket retrieves a nested bucket with the given key.
+// Returns nil if the bucket does not exist.
+func (b *readWriteBucket) NestedReadWriteBucket(
+	key []byte) walletdb.ReadWriteBucket {
+
+	if len(key) == 0 {
+		return nil
+	}
+
+	id, isBucket, found, err := b.lookup(key)
+	if err != nil || !found || !isBucket {
+		return nil
+	}
+
+	return newReadWriteBucket(b.tx, id)
+}
+
+// CreateBucket creates and returns a new nested bucket with the given
+// key. Returns ErrBucketExists if the bucket already exists,
+// ErrBucketNameRequired if the key is empty, or ErrIncompatibleValue
+// if the key value is otherwise invalid for the particular database
+// implementation.  Other errors are possible depending on the
+// implementation.
+func (b *readWriteBucket) CreateBucket(key []byte) (
+	walletdb.ReadWriteBucket, error) {
+
+	if len(key) == 0 {
+		return nil, walletdb.ErrBucketNameRequired
+	}
+	if len(key) > maxKeySize {
+		return nil, walletdb.ErrKeyTooLarge
+	}
+
+	_, isBucket, found, err := b.lookup(key)
+	switch {
+	case err != nil:
+		return nil, err
+
+	case found && isBucket:
+		return nil, walletdb.ErrBucketExists
+
+	case found:
+		return nil, walletdb.ErrIncompatibleValue
+	}
+
+	return b.createBucket(key)
+}
+
+// createBucket inserts the row of a new nested bucket.
+func (b *readWriteBucket) createBucket(key []byte) (*readWriteBucket, error) {
+	if b.tx.readOnly {
+		return nil, walletdb.ErrTxNotWritable
+	}
+
+	var id int64
+	_, err := b.tx.queryRow(
+		b.tx.queries().createBucket, []interface{}{b.id, key}, &id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReadWriteBucket(b.tx, id), nil
+}
+
+// CreateBucketIfNotExists creates and returns a new nested bucket with
+// the given key if it does not already exist.  Returns
+// ErrBucketNameRequired if the key is empty or ErrIncompatibleValue
+// if the key value is otherwise invalid for the particular database
+// backend.  Other errors are possible depending on the implementation.
+func (b *readWriteBucket) CreateBucketIfNotExists(key []byte) (
+	walletdb.ReadWriteBucket, error) {
+
+	if len(key) == 0 {
+		return nil, walletdb.ErrBucketNameRequired
+	}
+	if len(key) > maxKeySize {
+		return nil, walletdb.ErrKeyTooLarge
+	}
+
+	id, isBucket, found, err := b.lookup(key)
+	switch {
+	case err != nil:
+		return nil, err
+
+	case found && isBucket:
+		return newReadWriteBucket(b.tx, id), nil
+
+	case found:
+		return nil, walletdb.ErrIncompatibleValue
+	}
+
+	return b.createBucket(key)
+}
+
+// DeleteNestedBucket deletes the nested bucket and its sub-buckets
+// pointed to by the passed key. All values in the bucket and sub-buckets
+// will be deleted as well.
+func (b *readWriteBucket) DeleteNestedBucket(key []byte) error {
+	// Bolt reports empty keys as not being buckets.
+	if len(key) == 0 {
+		return walletdb.ErrIncompatibleValue
+	}
+
+	id, isBucket, found, err := b.lookup(key)
+	switch {
+	case err != nil:
+		return err
+
+	case !found:
+		return walletdb.ErrBucketNotFound
+
+	case !isBucket:
+		return walletdb.ErrIncompatibleValue
+	}
+
+	_, err = b.tx.exec(b.tx.queries().deleteBucket, id)
+	return err
+}
+
+// Put updates the value for the passed key.
+// Returns ErrKeyRequired if the passed key is empty.
+func (b *readWriteBucket) Put(key, value []byte) error {
+	switch {
+	case len(key) == 0:
+		return walletdb.ErrKeyRequired
+
+	case len(key) > maxKeySize:
+		return walletdb.ErrKeyTooLarge
+
+	case len(value) > maxValueSize:
+		return walletdb.ErrValueTooLarge
+	}
+
+	// Empty values must not be stored as NULL, which marks buckets. As
+	// some drivers bind empty values as NULL, the put query replaces NULL
+	// with an empty value as well.
+	if value == nil {
+		value = []byte{}
+	}
+
+	// The value is inserted, or updated unless the key is a nested
+	// bucket.
+	n, err := b.tx.exec(b.tx.queries().put, b.id, key, value)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return walletdb.ErrIncompatibleValue
+	}
+
+	return nil
+}
+
+// Delete deletes the key/value pointed to by the passed key.
+// Returns ErrKeyRequired if the passed key is empty.
+func (b *readWriteBucket) Delete(key []byte) error {
+	if len(key) == 0 {
+		return walletdb.ErrKeyRequired
+	}
+
+	n, err := b.tx.exec(b.tx.queries().delete, b.id, key)
+	if err != nil || n != 0 {
+		return err
+	}
+
+	// Nothing was deleted, which is fine unless the key is a nested
+	// bucket.
+	_, isBucket, _, err := b.lookup(key)
+	if err != nil {
+		return err
+	}
+	if isBucket {
+		return walletdb.ErrIncompatibleValue
+	}
+
+	return nil
+}
+
+// ReadWriteCursor returns a new read-write cursor for this bucket.
+func (b *readWriteBucket) ReadWriteCursor() walletdb.ReadWriteCursor {
+	return newReadWriteCursor(b)
+}
+
+// Tx returns the buckets transaction.
+func (b *readWriteBucket) Tx() walletdb.ReadWriteTx {
+	return b.tx
+}
+
+// NextSequence returns an autoincrementing sequence number for this bucket.
+// Note that this is not a thread safe function and as such it must not be used
+// for synchronization.
+func (b *readWriteBucket) NextSequence() (uint64, error) {
+	if b.tx.readOnly {
+		return 0, walletdb.ErrTxNotWritable
+	}
+
+	var seq int64
+	_, err := b.tx.queryRow(
+		b.tx.queries().nextSequence, []interface{}{b.id}, &seq,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(seq), nil
+}
+
+// SetSequence updates the sequence number for the bucket.
+func (b *readWriteBucket) SetSequence(v uint64) error {
+	_, err := b.tx.exec(b.tx.queries().setSequence, b.id, int64(v))
+	return err
+}
+
+// Sequence returns the current sequence number for this bucket without
+// incrementing it.
+func (b *readWriteBucket) Sequence() uint64 {
+	var seq int64
+	_, err := b.tx.queryRow(
+		b.tx.queries().sequence, []interface{}{b.id}, &seq,
+	)
+	if err != nil {
+		return 0
+	}
+
+	return uint64(seq)
+}
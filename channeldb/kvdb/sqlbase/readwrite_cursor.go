@@ -0,0 +1,101 @@
+package sqlbase
+
+import (
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// readWriteCursor holds a reference to the cursors bucket and the key the
+// cursor is currently positioned at.
+type readWriteCursor struct {
+	bucket *readWriteBucket
+
+	// currKey holds the current key the cursor is positioned at, or nil if
+	// it isn't positioned at a key.
+	currKey []byte
+}
+
+// Enforce readWriteCursor implements the walletdb.ReadWriteCursor interface.
+var _ walletdb.ReadWriteCursor = (*readWriteCursor)(nil)
+
+// newReadWriteCursor creates a new cursor for the passed bucket.
+func newReadWriteCursor(bucket *readWriteBucket) *readWriteCursor {
+	return &readWriteCursor{
+		bucket: bucket,
+	}
+}
+
+// move runs a query that returns at most one key/value pair, and positions
+// the cursor at its key.
+func (c *readWriteCursor) move(query string, args ...interface{}) ([]byte,
+	[]byte) {
+
+	var (
+		key, value []byte
+		isBucket   bool
+	)
+	args = append([]interface{}{c.bucket.id}, args...)
+	found, err := c.bucket.tx.queryRow(
+		query, args, &key, &value, &isBucket,
+	)
+	if err != nil || !found {
+		c.currKey = nil
+		return nil, nil
+	}
+
+	c.currKey = key
+	return key, normalizeValue(value, isBucket)
+}
+
+// First positions the cursor at the first key/value pair and returns
+// the pair.
+func (c *readWriteCursor) First() (key, value []byte) {
+	return c.move(c.bucket.tx.queries().first)
+}
+
+// Last positions the cursor at the last key/value pair and returns the
+// pair.
+func (c *readWriteCursor) Last() (key, value []byte) {
+	return c.move(c.bucket.tx.queries().last)
+}
+
+// Next moves the cursor one key/value pair forward and returns the new
+// pair.
+func (c *readWriteCursor) Next() (key, value []byte) {
+	if c.currKey == nil {
+		return nil, nil
+	}
+
+	return c.move(c.bucket.tx.queries().next, c.currKey)
+}
+
+// Prev moves the cursor one key/value pair backward and returns the new
+// pair.
+func (c *readWriteCursor) Prev() (key, value []byte) {
+	if c.currKey == nil {
+		return nil, nil
+	}
+
+	return c.move(c.bucket.tx.queries().prev, c.currKey)
+}
+
+// Seek positions the cursor at the passed seek key.  If the key does
+// not exist, the cursor is moved to the next key after seek.  Returns
+// the new pair.
+func (c *readWriteCursor) Seek(seek []byte) (key, value []byte) {
+	if len(seek) == 0 {
+		return c.First()
+	}
+
+	return c.move(c.bucket.tx.queries().seek, seek)
+}
+
+// Delete removes the current key/value pair the cursor is at without
+// invalidating the cursor.  Returns ErrIncompatibleValue if attempted
+// when the cursor points to a nested bucket.
+func (c *readWriteCursor) Delete() error {
+	if c.currKey == nil {
+		return nil
+	}
+
+	return c.bucket.Delete(c.currKey)
+}
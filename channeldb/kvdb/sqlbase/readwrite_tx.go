@@ -0,0 +1,234 @@
+package sqlbase
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// readWriteTx holds a reference to the sql transaction.
+type readWriteTx struct {
+	db *db
+
+	// tx is the underlying sql transaction.
+	tx *sql.Tx
+
+	// cancel cancels the context of the transaction.
+	cancel func()
+
+	// readOnly is true if the transaction may not modify the database.
+	readOnly bool
+
+	// active is true if the transaction hasn't been committed or rolled
+	// back yet.
+	active bool
+
+	// err is the first error returned by the database. It is recorded for
+	// the operations that can't return errors, and fails all subsequent
+	// operations as well as the commit of the transaction.
+	err error
+
+	// onCommit are the callbacks to run once the transaction is committed.
+	onCommit []func()
+}
+
+// newReadWriteTx begins a new sql transaction. Write transactions hold the
+// write mutex of the database until they are closed.
+func newReadWriteTx(db *db, readOnly bool) (*readWriteTx, error) {
+	opts := db.cfg.WriteTxOptions
+	if readOnly {
+		opts = db.cfg.ReadTxOptions
+	} else {
+		db.writeMtx.Lock()
+	}
+
+	var (
+		ctx    context.Context
+		cancel func()
+	)
+	if db.cfg.Timeout != 0 {
+		ctx, cancel = context.WithTimeout(db.cfg.Ctx, db.cfg.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(db.cfg.Ctx)
+	}
+
+	tx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		cancel()
+		if !readOnly {
+			db.writeMtx.Unlock()
+		}
+
+		return nil, err
+	}
+
+	return &readWriteTx{
+		db:       db,
+		tx:       tx,
+		cancel:   cancel,
+		readOnly: readOnly,
+		active:   true,
+	}, nil
+}
+
+// rootBucket returns the pseudo root bucket, whose children are the top level
+// buckets.
+func (tx *readWriteTx) rootBucket() *readWriteBucket {
+	return newReadWriteBucket(tx, tx.db.rootID)
+}
+
+// queries returns the queries on the table of the database.
+func (tx *readWriteTx) queries() *queries {
+	return tx.db.queries
+}
+
+// setErr records the first error returned by the database.
+func (tx *readWriteTx) setErr(err error) {
+	if tx.err == nil {
+		tx.err = err
+	}
+}
+
+// queryRow runs a query that returns at most one row and scans it into dest.
+// It returns false if the query didn't return any row.
+func (tx *readWriteTx) queryRow(query string, args []interface{},
+	dest ...interface{}) (bool, error) {
+
+	if tx.err != nil {
+		return false, tx.err
+	}
+	if !tx.active {
+		return false, walletdb.ErrTxClosed
+	}
+
+	err := tx.tx.QueryRow(query, args...).Scan(dest...)
+	switch {
+	case err == sql.ErrNoRows:
+		return false, nil
+
+	case err != nil:
+		tx.setErr(err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// exec runs a statement that modifies the database and returns the number of
+// affected rows.
+func (tx *readWriteTx) exec(query string, args ...interface{}) (int64,
+	error) {
+
+	if tx.err != nil {
+		return 0, tx.err
+	}
+	if !tx.active {
+		return 0, walletdb.ErrTxClosed
+	}
+	if tx.readOnly {
+		return 0, walletdb.ErrTxNotWritable
+	}
+
+	result, err := tx.tx.Exec(query, args...)
+	if err != nil {
+		tx.setErr(err)
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		tx.setErr(err)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// close marks the transaction as closed and releases its resources.
+func (tx *readWriteTx) close() {
+	tx.active = false
+	tx.cancel()
+
+	if !tx.readOnly {
+		tx.db.writeMtx.Unlock()
+	}
+}
+
+// ReadBucket opens the root bucket for read only access.  If the bucket
+// described by the key does not exist, nil is returned.
+func (tx *readWriteTx) ReadBucket(key []byte) walletdb.ReadBucket {
+	return tx.rootBucket().NestedReadWriteBucket(key)
+}
+
+// Rollback closes the transaction, discarding changes (if any) if the
+// database was modified by a write transaction.
+func (tx *readWriteTx) Rollback() error {
+	// If the transaction has been closed rollback will fail.
+	if !tx.active {
+		return walletdb.ErrTxClosed
+	}
+
+	err := tx.tx.Rollback()
+	tx.close()
+
+	return err
+}
+
+// ReadWriteBucket opens the root bucket for read/write access.  If the
+// bucket described by the key does not exist, nil is returned.
+func (tx *readWriteTx) ReadWriteBucket(key []byte) walletdb.ReadWriteBucket {
+	return tx.rootBucket().NestedReadWriteBucket(key)
+}
+
+// CreateTopLevelBucket creates the top level bucket for a key if it
+// does not exist.  The newly-created bucket it returned.
+func (tx *readWriteTx) CreateTopLevelBucket(key []byte) (
+	walletdb.ReadWriteBucket, error) {
+
+	return tx.rootBucket().CreateBucketIfNotExists(key)
+}
+
+// DeleteTopLevelBucket deletes the top level bucket for a key.  This
+// errors if the bucket can not be found or the key keys a single value
+// instead of a bucket.
+func (tx *readWriteTx) DeleteTopLevelBucket(key []byte) error {
+	return tx.rootBucket().DeleteNestedBucket(key)
+}
+
+// Commit commits the transaction if not already committed. Transactions that
+// ran into a database error are rolled back instead, and the error is
+// returned.
+func (tx *readWriteTx) Commit() error {
+	// Commit will fail if the transaction is already closed.
+	if !tx.active {
+		return walletdb.ErrTxClosed
+	}
+	if tx.readOnly {
+		return walletdb.ErrTxNotWritable
+	}
+
+	if tx.err != nil {
+		_ = tx.tx.Rollback()
+		tx.close()
+
+		return tx.err
+	}
+
+	err := tx.tx.Commit()
+	tx.close()
+	if err != nil {
+		return err
+	}
+
+	for _, cb := range tx.onCommit {
+		cb()
+	}
+
+	return nil
+}
+
+// OnCommit adds a callback that is run once the transaction is committed.
+func (tx *readWriteTx) OnCommit(cb func()) {
+	tx.onCommit = append(tx.onCommit, cb)
+}
@@ -0,0 +1,179 @@
+// +build kvdb_sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/decred/dcrlnd/channeldb/kvdb/sqlbase"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	// driverName is the name of the database/sql driver of SQLite.
+	driverName = "sqlite"
+
+	// tableName is the name of the table that holds the bucket tree. As
+	// every database is stored in its own file, there's no need to share
+	// the file between several tables.
+	tableName = "kv"
+
+	// defaultBusyTimeout is the time a connection waits for a lock held by
+	// another connection if no busy timeout is configured.
+	defaultBusyTimeout = 5 * time.Second
+
+	// dirPermissions is the permission of the directory of the database if
+	// it doesn't exist yet.
+	dirPermissions = 0700
+)
+
+// BackendConfig holds the configuration of a SQLite backend.
+type BackendConfig struct {
+	// Ctx is the context we use to cancel operations upon exit.
+	Ctx context.Context
+
+	// DBPath is the directory of the database file.
+	DBPath string
+
+	// DBFileName is the name of the database file.
+	DBFileName string
+
+	// BusyTimeout is the time a connection waits for a lock held by another
+	// connection before failing. The default busy timeout is used if zero.
+	BusyTimeout time.Duration
+
+	// Timeout is the maximum duration of a transaction. It is unlimited
+	// if zero.
+	Timeout time.Duration
+
+	// NoVacuum disables reclaiming the free pages of the database file when
+	// it is opened.
+	NoVacuum bool
+}
+
+// dsn returns the data source name of the database, which sets the pragmas of
+// every connection.
+func (c *BackendConfig) dsn() string {
+	busyTimeout := c.BusyTimeout
+	if busyTimeout == 0 {
+		busyTimeout = defaultBusyTimeout
+	}
+
+	// Incremental vacuum has to be enabled before the database is written
+	// to for the first time, so it comes first. It is a no-op for
+	// existing databases.
+	pragmas := []string{
+		"auto_vacuum(incremental)",
+		"journal_mode(wal)",
+		"synchronous(full)",
+		"foreign_keys(on)",
+		fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()),
+	}
+
+	query := url.Values{"_pragma": pragmas}
+	path := filepath.Join(c.DBPath, c.DBFileName)
+
+	return path + "?" + query.Encode()
+}
+
+// isConflictError returns true if the error signals that a transaction failed
+// because the database was locked by another connection, in which case it
+// should be retried.
+func isConflictError(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	// The extended result codes hold the primary result code in their
+	// least significant byte.
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+
+	return false
+}
+
+// copyDB writes a copy of the database file to w. The copy is made with
+// VACUUM INTO, which reads a consistent snapshot of the database, into a
+// temporary file next to the database that is removed once it was written to
+// w. The copy is a regular SQLite database that can be opened in place of the
+// original file.
+func copyDB(ctx context.Context, dbPath string, sqlDB *sql.DB,
+	w io.Writer) error {
+
+	tempDir, err := ioutil.TempDir(dbPath, "copy")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	copyPath := filepath.Join(tempDir, "copy.sqlite")
+	_, err = sqlDB.ExecContext(ctx, "VACUUM INTO ?", copyPath)
+	if err != nil {
+		return fmt.Errorf("unable to copy database: %v", err)
+	}
+
+	copyFile, err := os.Open(copyPath)
+	if err != nil {
+		return err
+	}
+	defer copyFile.Close()
+
+	_, err = io.Copy(w, copyFile)
+	return err
+}
+
+// newSqliteBackend returns a db object initialized with the passed backend
+// config. The bucket tree is stored in a single table of the database file,
+// which is created if it doesn't exist yet.
+//
+// The database runs in WAL mode, so read transactions see a consistent
+// snapshot of the database and don't block the write transaction, which is
+// serialized with the other write transactions of this process. Unlike bolt,
+// the database file shrinks as the pages freed by deleted data are returned
+// to the file system when the database is opened, unless NoVacuum is set.
+func newSqliteBackend(config BackendConfig) (walletdb.DB, error) {
+	if err := os.MkdirAll(config.DBPath, dirPermissions); err != nil {
+		return nil, err
+	}
+
+	var initStatements []string
+	if !config.NoVacuum {
+		initStatements = append(
+			initStatements, "PRAGMA incremental_vacuum",
+		)
+	}
+
+	return sqlbase.NewDB(&sqlbase.Config{
+		Ctx:        config.Ctx,
+		DriverName: driverName,
+		Dsn:        config.dsn(),
+		Table:      tableName,
+		Timeout:    config.Timeout,
+		SchemaReplacements: map[string]string{
+			"BIGSERIAL PRIMARY KEY": "INTEGER PRIMARY KEY",
+			"BYTEA":                 "BLOB",
+			"BIGINT":                "INTEGER",
+		},
+		IsConflictError: isConflictError,
+		InitStatements:  initStatements,
+		Copy: func(ctx context.Context, sqlDB *sql.DB,
+			w io.Writer) error {
+
+			return copyDB(ctx, config.DBPath, sqlDB, w)
+		},
+	})
+}
@@ -0,0 +1,197 @@
+// +build kvdb_sqlite
+
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestConfig returns the config of a database in a fresh temporary
+// directory, which is removed by the returned cleanup func.
+func newTestConfig(t *testing.T) (BackendConfig, func()) {
+	tempDir, err := ioutil.TempDir("", "sqlite")
+	require.NoError(t, err)
+
+	config := BackendConfig{
+		DBPath:     filepath.Join(tempDir, "nested"),
+		DBFileName: "test.db",
+	}
+
+	return config, func() {
+		os.RemoveAll(tempDir)
+	}
+}
+
+// fileSize returns the size of the database file of the config.
+func fileSize(t *testing.T, config BackendConfig) int64 {
+	info, err := os.Stat(filepath.Join(config.DBPath, config.DBFileName))
+	require.NoError(t, err)
+
+	return info.Size()
+}
+
+// TestEmptyValues asserts that empty values are stored as such and aren't
+// mistaken for nested buckets.
+func TestEmptyValues(t *testing.T) {
+	config, cleanup := newTestConfig(t)
+	defer cleanup()
+
+	db, err := newSqliteBackend(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put([]byte("nil"), nil); err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte("empty"), []byte{})
+	})
+	require.NoError(t, err)
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bucket := tx.ReadBucket([]byte("bucket"))
+		require.NotNil(t, bucket)
+
+		for _, key := range []string{"nil", "empty"} {
+			value := bucket.Get([]byte(key))
+			require.NotNil(t, value)
+			require.Empty(t, value)
+			require.Nil(t, bucket.NestedReadBucket([]byte(key)))
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestIncrementalVacuum asserts that the database file shrinks once data is
+// deleted and the database is reopened, unless vacuuming is disabled.
+func TestIncrementalVacuum(t *testing.T) {
+	config, cleanup := newTestConfig(t)
+	defer cleanup()
+
+	db, err := newSqliteBackend(config)
+	require.NoError(t, err)
+
+	value := make([]byte, 1024)
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 1000; i++ {
+			key := []byte{byte(i >> 8), byte(i)}
+			if err := bucket.Put(key, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	fullSize := fileSize(t, config)
+
+	db, err = newSqliteBackend(config)
+	require.NoError(t, err)
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket([]byte("bucket"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// The freed pages are kept in the file if vacuuming is disabled.
+	noVacuumConfig := config
+	noVacuumConfig.NoVacuum = true
+	db, err = newSqliteBackend(noVacuumConfig)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.Equal(t, fullSize, fileSize(t, config))
+
+	// Otherwise they are returned to the file system.
+	db, err = newSqliteBackend(config)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.Less(t, fileSize(t, config), fullSize/10)
+}
+
+// TestCopy asserts that the copy of a database is a database file holding the
+// same bucket tree, and that no temporary file is left next to the database.
+func TestCopy(t *testing.T) {
+	config, cleanup := newTestConfig(t)
+	defer cleanup()
+
+	db, err := newSqliteBackend(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+		if err := bucket.SetSequence(42); err != nil {
+			return err
+		}
+
+		nested, err := bucket.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+
+		return nested.Put([]byte("key"), []byte("value"))
+	})
+	require.NoError(t, err)
+
+	copyConfig, copyCleanup := newTestConfig(t)
+	defer copyCleanup()
+	require.NoError(t, os.MkdirAll(copyConfig.DBPath, dirPermissions))
+
+	copyPath := filepath.Join(copyConfig.DBPath, copyConfig.DBFileName)
+	copyFile, err := os.Create(copyPath)
+	require.NoError(t, err)
+	require.NoError(t, db.Copy(copyFile))
+	require.NoError(t, copyFile.Close())
+
+	files, err := ioutil.ReadDir(config.DBPath)
+	require.NoError(t, err)
+	for _, file := range files {
+		require.False(t, file.IsDir(), "copy directory not removed")
+	}
+
+	copyDB, err := newSqliteBackend(copyConfig)
+	require.NoError(t, err)
+	defer copyDB.Close()
+
+	err = walletdb.View(copyDB, func(tx walletdb.ReadTx) error {
+		bucket := tx.ReadBucket([]byte("bucket"))
+		require.NotNil(t, bucket)
+		require.EqualValues(
+			t, 42, bucket.(walletdb.ReadWriteBucket).Sequence(),
+		)
+
+		nested := bucket.NestedReadBucket([]byte("nested"))
+		require.NotNil(t, nested)
+		require.Equal(t, []byte("value"), nested.Get([]byte("key")))
+
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,27 @@
+// +build kvdb_sqlite
+
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb/walletdbtest"
+)
+
+// TestWalletDBInterface performs the WalletDB interface test suite for the
+// sqlite database driver.
+func TestWalletDBInterface(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := BackendConfig{
+		DBPath:     tempDir,
+		DBFileName: "test.db",
+	}
+	walletdbtest.TestInterface(t, dbType, config)
+}
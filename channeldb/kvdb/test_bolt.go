@@ -0,0 +1,8 @@
+// +build !kvdb_etcd,!kvdb_postgres,!kvdb_sqlite
+
+package kvdb
+
+// TestBackend is conditionally set to bdb when none of the kvdb_etcd,
+// kvdb_postgres and kvdb_sqlite build tags are defined, allowing testing our
+// database code with bolt backend.
+const TestBackend = BoltBackendName
@@ -0,0 +1,7 @@
+// +build kvdb_etcd
+
+package kvdb
+
+// TestBackend is conditionally set to etcd when the kvdb_etcd build tag is
+// defined, allowing testing our database code with etcd backend.
+const TestBackend = EtcdBackendName
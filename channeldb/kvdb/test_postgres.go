@@ -0,0 +1,8 @@
+// +build kvdb_postgres,!kvdb_etcd
+
+package kvdb
+
+// TestBackend is conditionally set to postgres when the kvdb_postgres build
+// tag is defined, allowing testing our database code with postgres backend.
+// The etcd backend takes precedence if both tags are defined.
+const TestBackend = PostgresBackendName
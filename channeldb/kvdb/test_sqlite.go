@@ -0,0 +1,8 @@
+// +build kvdb_sqlite,!kvdb_etcd,!kvdb_postgres
+
+package kvdb
+
+// TestBackend is conditionally set to sqlite when the kvdb_sqlite build tag is
+// defined, allowing testing our database code with sqlite backend. The etcd
+// and postgres backends take precedence if their tags are defined as well.
+const TestBackend = SqliteBackendName
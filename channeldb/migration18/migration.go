@@ -0,0 +1,314 @@
+package migration18
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+var (
+	invoiceBucket = []byte("invoices")
+
+	addIndexBucket = []byte("invoice-add-index")
+
+	creationTimeIndexBucket = []byte("invoice-creation-time-index")
+
+	settleTimeIndexBucket = []byte("invoice-settle-time-index")
+
+	paymentsRootBucket = []byte("payments-root-bucket")
+
+	paymentSequenceKey = []byte("payment-sequence-key")
+
+	paymentCreationInfoKey = []byte("payment-creation-info")
+
+	paymentHtlcsBucket = []byte("payment-htlcs-bucket")
+
+	htlcSettleInfoKey = []byte("htlc-settle-info")
+
+	paymentsCreationTimeIndexBucket = []byte("payments-creation-time-index")
+
+	paymentsSettleTimeIndexBucket = []byte("payments-settle-time-index")
+
+	byteOrder = binary.BigEndian
+)
+
+const (
+	createTimeType tlv.Type = 2
+	settleTimeType tlv.Type = 3
+)
+
+// timeIndexEntry is an entry of a time index, which maps the time of an event
+// and the index of the item it happened to to an empty value.
+type timeIndexEntry struct {
+	// unixNano is the time of the event in unix nano seconds.
+	unixNano uint64
+
+	// index is the add index of the invoice or the sequence number of the
+	// payment the event happened to.
+	index uint64
+}
+
+// key returns the key of the entry in the time index.
+func (e timeIndexEntry) key() []byte {
+	var key [16]byte
+	byteOrder.PutUint64(key[:8], e.unixNano)
+	byteOrder.PutUint64(key[8:], e.index)
+
+	return key[:]
+}
+
+// MigrateTimeIndexes migrates the invoices and payments db to contain new
+// buckets which index invoices and payments by their creation and settle
+// times. This allows querying them by time range without scanning the whole
+// history.
+func MigrateTimeIndexes(tx kvdb.RwTx) error {
+	log.Infof("Migrating invoices and payments to add time indexes")
+
+	if err := migrateInvoiceTimeIndexes(tx); err != nil {
+		return err
+	}
+
+	return migratePaymentTimeIndexes(tx)
+}
+
+// migrateInvoiceTimeIndexes creates the creation and settle time indexes of
+// the invoices.
+func migrateInvoiceTimeIndexes(tx kvdb.RwTx) error {
+	invoices := tx.ReadWriteBucket(invoiceBucket)
+	if invoices == nil {
+		return nil
+	}
+
+	addIndex := invoices.NestedReadBucket(addIndexBucket)
+	if addIndex == nil {
+		return nil
+	}
+
+	// Collect the entries first, as buckets must not be modified while
+	// they are iterated.
+	var creationEntries, settleEntries []timeIndexEntry
+	err := addIndex.ForEach(func(k, invoiceKey []byte) error {
+		invoiceBytes := invoices.Get(invoiceKey)
+		if invoiceBytes == nil {
+			return fmt.Errorf("invoice %x not found", invoiceKey)
+		}
+
+		creationDate, settleDate, err := deserializeInvoiceDates(
+			bytes.NewReader(invoiceBytes),
+		)
+		if err != nil {
+			return err
+		}
+
+		addIndexNo := byteOrder.Uint64(k)
+		creationEntries = append(creationEntries, timeIndexEntry{
+			unixNano: unixNano(creationDate),
+			index:    addIndexNo,
+		})
+
+		if !settleDate.IsZero() {
+			settleEntries = append(settleEntries, timeIndexEntry{
+				unixNano: unixNano(settleDate),
+				index:    addIndexNo,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	creationTimeIndex, err := invoices.CreateBucketIfNotExists(
+		creationTimeIndexBucket,
+	)
+	if err != nil {
+		return err
+	}
+	if err := putEntries(creationTimeIndex, creationEntries); err != nil {
+		return err
+	}
+
+	settleTimeIndex, err := invoices.CreateBucketIfNotExists(
+		settleTimeIndexBucket,
+	)
+	if err != nil {
+		return err
+	}
+
+	return putEntries(settleTimeIndex, settleEntries)
+}
+
+// migratePaymentTimeIndexes creates the creation and settle time indexes of
+// the payments. Legacy duplicate payments aren't indexed.
+func migratePaymentTimeIndexes(tx kvdb.RwTx) error {
+	creationTimeIndex, err := tx.CreateTopLevelBucket(
+		paymentsCreationTimeIndexBucket,
+	)
+	if err != nil {
+		return err
+	}
+
+	settleTimeIndex, err := tx.CreateTopLevelBucket(
+		paymentsSettleTimeIndexBucket,
+	)
+	if err != nil {
+		return err
+	}
+
+	payments := tx.ReadBucket(paymentsRootBucket)
+	if payments == nil {
+		return nil
+	}
+
+	var creationEntries, settleEntries []timeIndexEntry
+	err = payments.ForEach(func(k, _ []byte) error {
+		bucket := payments.NestedReadBucket(k)
+		if bucket == nil {
+			return errors.New("non bucket element in payments " +
+				"bucket")
+		}
+
+		seqBytes := bucket.Get(paymentSequenceKey)
+		if seqBytes == nil {
+			return errors.New("expected sequence number")
+		}
+		seqNum := byteOrder.Uint64(seqBytes)
+
+		// The creation time follows the payment hash and value in the
+		// creation info.
+		info := bucket.Get(paymentCreationInfoKey)
+		if info == nil {
+			return nil
+		}
+		if len(info) < 48 {
+			return fmt.Errorf("invalid creation info of payment %x",
+				k)
+		}
+		creationEntries = append(creationEntries, timeIndexEntry{
+			unixNano: byteOrder.Uint64(info[40:48]),
+			index:    seqNum,
+		})
+
+		settleTime, err := fetchSettleTime(bucket)
+		if err != nil {
+			return err
+		}
+		if settleTime != 0 {
+			settleEntries = append(settleEntries, timeIndexEntry{
+				unixNano: settleTime,
+				index:    seqNum,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := putEntries(creationTimeIndex, creationEntries); err != nil {
+		return err
+	}
+
+	return putEntries(settleTimeIndex, settleEntries)
+}
+
+// fetchSettleTime returns the time the first htlc of the payment stored in the
+// given bucket settled in unix nano seconds, or zero if none of them did.
+func fetchSettleTime(bucket kvdb.RBucket) (uint64, error) {
+	htlcs := bucket.NestedReadBucket(paymentHtlcsBucket)
+	if htlcs == nil {
+		return 0, nil
+	}
+
+	var settleTime uint64
+	err := htlcs.ForEach(func(k, _ []byte) error {
+		htlc := htlcs.NestedReadBucket(k)
+		if htlc == nil {
+			return errors.New("non bucket element in htlcs bucket")
+		}
+
+		// The settle time follows the preimage in the settle info.
+		settleInfo := htlc.Get(htlcSettleInfoKey)
+		if settleInfo == nil {
+			return nil
+		}
+		if len(settleInfo) < 40 {
+			return fmt.Errorf("invalid settle info of htlc %x", k)
+		}
+
+		t := byteOrder.Uint64(settleInfo[32:40])
+		if t != 0 && (settleTime == 0 || t < settleTime) {
+			settleTime = t
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return settleTime, nil
+}
+
+// deserializeInvoiceDates decodes the creation and settle dates of a
+// serialized invoice.
+func deserializeInvoiceDates(r io.Reader) (time.Time, time.Time, error) {
+	var (
+		creationDate, settleDate           time.Time
+		creationDateBytes, settleDateBytes []byte
+	)
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(createTimeType, &creationDateBytes),
+		tlv.MakePrimitiveRecord(settleTimeType, &settleDateBytes),
+	)
+	if err != nil {
+		return creationDate, settleDate, err
+	}
+
+	var bodyLen int64
+	err = binary.Read(r, byteOrder, &bodyLen)
+	if err != nil {
+		return creationDate, settleDate, err
+	}
+
+	lr := io.LimitReader(r, bodyLen)
+	if err = tlvStream.Decode(lr); err != nil {
+		return creationDate, settleDate, err
+	}
+
+	if err := creationDate.UnmarshalBinary(creationDateBytes); err != nil {
+		return creationDate, settleDate, err
+	}
+
+	err = settleDate.UnmarshalBinary(settleDateBytes)
+	return creationDate, settleDate, err
+}
+
+// unixNano returns the time in unix nano seconds, or zero for the zero time.
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return uint64(t.UnixNano())
+}
+
+// putEntries writes the given entries to a time index.
+func putEntries(timeIndex kvdb.RwBucket, entries []timeIndexEntry) error {
+	for _, entry := range entries {
+		if err := timeIndex.Put(entry.key(), []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package migration18
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/channeldb/migtest"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+var (
+	hexStr = migtest.Hex
+
+	hash1 = hexStr("02acee76ebd53d00824410cf6adecad4f50334dac702bd5a2d3ba01b91709f0e")
+	hash2 = hexStr("62eb3f0a48f954e495d0c14ac63df04a67cefa59dafdbcd3d5046d1f5647840c")
+
+	seqNum1 = hexStr("0000000000000001")
+	seqNum2 = hexStr("0000000000000002")
+
+	htlcID1 = hexStr("0000000000000001")
+	htlcID2 = hexStr("0000000000000002")
+
+	invoiceNum1 = hexStr("00000000")
+	invoiceNum2 = hexStr("00000001")
+
+	creationTime1 = time.Unix(1000, 0)
+	creationTime2 = time.Unix(2000, 0)
+	settleTime1   = time.Unix(3000, 0)
+	settleTime2   = time.Unix(4000, 0)
+
+	// prePayments is the data in the payments root bucket before the
+	// migration. The second payment has two settled htlcs, the earliest
+	// of which determines its settle time.
+	prePayments = map[string]interface{}{
+		hash1: map[string]interface{}{
+			"payment-sequence-key":  seqNum1,
+			"payment-creation-info": creationInfo(creationTime1),
+		},
+		hash2: map[string]interface{}{
+			"payment-sequence-key":  seqNum2,
+			"payment-creation-info": creationInfo(creationTime2),
+			"payment-htlcs-bucket": map[string]interface{}{
+				htlcID1: map[string]interface{}{
+					"htlc-settle-info": settleInfo(
+						settleTime2,
+					),
+				},
+				htlcID2: map[string]interface{}{
+					"htlc-settle-info": settleInfo(
+						settleTime1,
+					),
+				},
+			},
+		},
+	}
+
+	// postPaymentsCreation is the expected creation time index of the
+	// payments.
+	postPaymentsCreation = map[string]interface{}{
+		timeKey(creationTime1, 1): "",
+		timeKey(creationTime2, 2): "",
+	}
+
+	// postPaymentsSettle is the expected settle time index of the
+	// payments.
+	postPaymentsSettle = map[string]interface{}{
+		timeKey(settleTime1, 2): "",
+	}
+
+	// preInvoices is the data in the invoices bucket before the migration.
+	preInvoices = map[string]interface{}{
+		invoiceNum1: invoice(creationTime1, time.Time{}),
+		invoiceNum2: invoice(creationTime2, settleTime1),
+		"invoice-add-index": map[string]interface{}{
+			seqNum1: invoiceNum1,
+			seqNum2: invoiceNum2,
+		},
+	}
+
+	// postInvoices is the expected data in the invoices bucket after the
+	// migration.
+	postInvoices = map[string]interface{}{
+		invoiceNum1: invoice(creationTime1, time.Time{}),
+		invoiceNum2: invoice(creationTime2, settleTime1),
+		"invoice-add-index": map[string]interface{}{
+			seqNum1: invoiceNum1,
+			seqNum2: invoiceNum2,
+		},
+		"invoice-creation-time-index": postInvoicesCreation,
+		"invoice-settle-time-index":   postInvoicesSettle,
+	}
+
+	// postInvoicesCreation is the expected creation time index of the
+	// invoices.
+	postInvoicesCreation = map[string]interface{}{
+		timeKey(creationTime1, 1): "",
+		timeKey(creationTime2, 2): "",
+	}
+
+	// postInvoicesSettle is the expected settle time index of the invoices.
+	postInvoicesSettle = map[string]interface{}{
+		timeKey(settleTime1, 2): "",
+	}
+)
+
+// timeKey returns the time index key for the given time and index.
+func timeKey(t time.Time, index uint64) string {
+	return string(timeIndexEntry{
+		unixNano: uint64(t.UnixNano()),
+		index:    index,
+	}.key())
+}
+
+// creationInfo returns serialized payment creation info with the given
+// creation time.
+func creationInfo(t time.Time) string {
+	var b [52]byte
+	binary.BigEndian.PutUint64(b[40:48], uint64(t.UnixNano()))
+	return string(b[:])
+}
+
+// settleInfo returns serialized htlc settle info with the given settle time.
+func settleInfo(t time.Time) string {
+	var b [40]byte
+	binary.BigEndian.PutUint64(b[32:40], uint64(t.UnixNano()))
+	return string(b[:])
+}
+
+// invoice returns a serialized invoice with the given creation and settle
+// dates, along with a memo that isn't known to the migration.
+func invoice(creationDate, settleDate time.Time) string {
+	memo := []byte("memo")
+	creationDateBytes, err := creationDate.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	settleDateBytes, err := settleDate.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(0, &memo),
+		tlv.MakePrimitiveRecord(createTimeType, &creationDateBytes),
+		tlv.MakePrimitiveRecord(settleTimeType, &settleDateBytes),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	var body bytes.Buffer
+	if err := tlvStream.Encode(&body); err != nil {
+		panic(err)
+	}
+
+	var b bytes.Buffer
+	err = binary.Write(&b, binary.BigEndian, uint64(body.Len()))
+	if err != nil {
+		panic(err)
+	}
+	b.Write(body.Bytes())
+
+	return b.String()
+}
+
+// hasKeys asserts that the bucket contains all of the given keys. This
+// complements migtest.VerifyDB, which doesn't tell missing keys from keys
+// with empty values.
+func hasKeys(bucket kvdb.RBucket, keys map[string]interface{}) error {
+	if bucket == nil {
+		return errors.New("bucket not found")
+	}
+
+	for key := range keys {
+		if bucket.Get([]byte(key)) == nil {
+			return fmt.Errorf("key %x not found", key)
+		}
+	}
+
+	return nil
+}
+
+// TestMigrateTimeIndexes asserts that the database is properly migrated to
+// contain the time indexes of invoices and payments.
+func TestMigrateTimeIndexes(t *testing.T) {
+	before := func(tx kvdb.RwTx) error {
+		err := migtest.RestoreDB(tx, paymentsRootBucket, prePayments)
+		if err != nil {
+			return err
+		}
+
+		return migtest.RestoreDB(tx, invoiceBucket, preInvoices)
+	}
+
+	after := func(tx kvdb.RwTx) error {
+		err := migtest.VerifyDB(tx, paymentsRootBucket, prePayments)
+		if err != nil {
+			return err
+		}
+
+		err = migtest.VerifyDB(
+			tx, paymentsCreationTimeIndexBucket,
+			postPaymentsCreation,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = migtest.VerifyDB(
+			tx, paymentsSettleTimeIndexBucket, postPaymentsSettle,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = migtest.VerifyDB(tx, invoiceBucket, postInvoices)
+		if err != nil {
+			return err
+		}
+
+		err = hasKeys(
+			tx.ReadBucket(paymentsCreationTimeIndexBucket),
+			postPaymentsCreation,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = hasKeys(
+			tx.ReadBucket(paymentsSettleTimeIndexBucket),
+			postPaymentsSettle,
+		)
+		if err != nil {
+			return err
+		}
+
+		invoices := tx.ReadBucket(invoiceBucket)
+		err = hasKeys(
+			invoices.NestedReadBucket(creationTimeIndexBucket),
+			postInvoicesCreation,
+		)
+		if err != nil {
+			return err
+		}
+
+		return hasKeys(
+			invoices.NestedReadBucket(settleTimeIndexBucket),
+			postInvoicesSettle,
+		)
+	}
+
+	migtest.ApplyMigration(t, before, after, MigrateTimeIndexes, false)
+}
@@ -0,0 +1,72 @@
+package migration19
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+)
+
+var (
+	forwardingLogBucket = []byte("circuit-fwd-log")
+
+	forwardingLogChanIndexBucket = []byte("circuit-fwd-log-chan-index")
+)
+
+// forwardingEventSize is the size of a serialized forwarding event, which
+// starts with the incoming and outgoing channel IDs.
+const forwardingEventSize = 32
+
+// MigrateForwardingLogChanIndex creates the index of the forwarding log by
+// channel, which maps the ID of the incoming and the outgoing channel of each
+// forwarding event and its timestamp to an empty value. This allows querying
+// the forwarding history of a channel without scanning the whole log.
+func MigrateForwardingLogChanIndex(tx kvdb.RwTx) error {
+	log.Infof("Migrating forwarding log to add channel index")
+
+	chanIndex, err := tx.CreateTopLevelBucket(forwardingLogChanIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	logBucket := tx.ReadWriteBucket(forwardingLogBucket)
+	if logBucket == nil {
+		return nil
+	}
+
+	// Collect the index keys first, as the bucket must not be modified
+	// while iterating over it.
+	var keys [][]byte
+	err = logBucket.ForEach(func(timestamp, events []byte) error {
+		if len(timestamp) != 8 || len(events)%forwardingEventSize != 0 {
+			return fmt.Errorf("invalid forwarding event at %x",
+				timestamp)
+		}
+
+		for len(events) != 0 {
+			chanIDs := [][]byte{events[:8], events[8:16]}
+			for _, chanID := range chanIDs {
+				key := make([]byte, 16)
+				copy(key[:8], chanID)
+				copy(key[8:], timestamp)
+				keys = append(keys, key)
+			}
+
+			events = events[forwardingEventSize:]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := chanIndex.Put(key, []byte{}); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Indexed %d forwarding events", len(keys)/2)
+
+	return nil
+}
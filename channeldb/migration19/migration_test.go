@@ -0,0 +1,108 @@
+package migration19
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/channeldb/migtest"
+)
+
+var (
+	hexStr = migtest.Hex
+
+	timestamp1 = hexStr("0000000000000001")
+	timestamp2 = hexStr("0000000000000002")
+
+	chanID1 = hexStr("0000000000000010")
+	chanID2 = hexStr("0000000000000020")
+	chanID3 = hexStr("0000000000000030")
+
+	amts = hexStr("00000000000003e800000000000003e0")
+
+	// preLog is the forwarding log before the migration. The first event
+	// is forwarded from the first to the second channel, the second one
+	// from the second to the third channel.
+	preLog = map[string]interface{}{
+		timestamp1: chanID1 + chanID2 + amts,
+		timestamp2: chanID2 + chanID3 + amts,
+	}
+
+	// postChanIndex is the expected channel index after the migration.
+	postChanIndex = map[string]interface{}{
+		chanID1 + timestamp1: "",
+		chanID2 + timestamp1: "",
+		chanID2 + timestamp2: "",
+		chanID3 + timestamp2: "",
+	}
+)
+
+// hasKeys asserts that the bucket contains exactly the given keys. This
+// complements migtest.VerifyDB, which doesn't tell missing keys from keys
+// with empty values.
+func hasKeys(bucket kvdb.RBucket, keys map[string]interface{}) error {
+	if bucket == nil {
+		return errors.New("bucket not found")
+	}
+
+	numKeys := 0
+	err := bucket.ForEach(func(k, _ []byte) error {
+		if _, ok := keys[string(k)]; !ok {
+			return fmt.Errorf("unexpected key %x", k)
+		}
+		numKeys++
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if numKeys != len(keys) {
+		return fmt.Errorf("expected %d keys, found %d", len(keys),
+			numKeys)
+	}
+
+	return nil
+}
+
+// TestMigrateForwardingLogChanIndex asserts that the channel index of the
+// forwarding log is created and populated with both channels of every event.
+func TestMigrateForwardingLogChanIndex(t *testing.T) {
+	before := func(tx kvdb.RwTx) error {
+		return migtest.RestoreDB(tx, forwardingLogBucket, preLog)
+	}
+
+	after := func(tx kvdb.RwTx) error {
+		err := migtest.VerifyDB(tx, forwardingLogBucket, preLog)
+		if err != nil {
+			return err
+		}
+
+		return hasKeys(
+			tx.ReadBucket(forwardingLogChanIndexBucket),
+			postChanIndex,
+		)
+	}
+
+	migtest.ApplyMigration(
+		t, before, after, MigrateForwardingLogChanIndex, false,
+	)
+}
+
+// TestMigrateForwardingLogChanIndexEmpty asserts that the channel index is
+// created for nodes that never forwarded a payment.
+func TestMigrateForwardingLogChanIndexEmpty(t *testing.T) {
+	after := func(tx kvdb.RwTx) error {
+		return hasKeys(
+			tx.ReadBucket(forwardingLogChanIndexBucket),
+			map[string]interface{}{},
+		)
+	}
+
+	migtest.ApplyMigration(
+		t, func(kvdb.RwTx) error { return nil }, after,
+		MigrateForwardingLogChanIndex, false,
+	)
+}
@@ -0,0 +1,199 @@
+package migration20
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+// maxVarBytes is the maximum length of the variable length byte slices of a
+// commitment, as enforced by the codec of the channel database.
+const maxVarBytes = 66000
+
+// htlc is an HTLC of a commitment stored in the former revocation log
+// format, limited to the fields needed by the compact format.
+type htlc struct {
+	rHash         [32]byte
+	amt           uint64
+	refundTimeout uint32
+	outputIndex   int32
+	incoming      bool
+}
+
+// commitment is a commitment stored in the former revocation log format,
+// limited to the fields needed by the compact format.
+type commitment struct {
+	commitHeight  uint64
+	localBalance  uint64
+	remoteBalance uint64
+	commitTx      *wire.MsgTx
+	htlcs         []htlc
+}
+
+// readElements reads the given fixed size values in big endian order.
+func readElements(r io.Reader, elements ...interface{}) error {
+	for _, element := range elements {
+		if err := binary.Read(r, byteOrder, element); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// skipVarBytes reads and discards a variable length byte slice.
+func skipVarBytes(r io.Reader) error {
+	_, err := wire.ReadVarBytes(r, 0, maxVarBytes, "[]byte")
+	return err
+}
+
+// deserializeCommitment deserializes a commitment stored in the former
+// revocation log format.
+func deserializeCommitment(r io.Reader) (*commitment, error) {
+	var (
+		c       commitment
+		indexes [4]uint64
+		fees    [2]uint64
+	)
+
+	// The log and HTLC indexes of both parties as well as the commitment
+	// fee and fee rate are skipped.
+	err := readElements(
+		r, &c.commitHeight, &indexes, &c.localBalance, &c.remoteBalance,
+		&fees,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.commitTx = wire.NewMsgTx()
+	if err := c.commitTx.Deserialize(r); err != nil {
+		return nil, err
+	}
+
+	// Skip the commitment signature.
+	if err := skipVarBytes(r); err != nil {
+		return nil, err
+	}
+
+	var numHtlcs uint16
+	if err := readElements(r, &numHtlcs); err != nil {
+		return nil, err
+	}
+
+	c.htlcs = make([]htlc, numHtlcs)
+	for i := range c.htlcs {
+		h := &c.htlcs[i]
+
+		// Skip the HTLC signature.
+		if err := skipVarBytes(r); err != nil {
+			return nil, err
+		}
+
+		err := readElements(
+			r, &h.rHash, &h.amt, &h.refundTimeout, &h.outputIndex,
+			&h.incoming,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip the onion blob, then the HTLC and log indexes.
+		if err := skipVarBytes(r); err != nil {
+			return nil, err
+		}
+		var htlcIndexes [2]uint64
+		if err := readElements(r, &htlcIndexes); err != nil {
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
+
+// serializeRevocationLog serializes the compact revocation log entry of the
+// given commitment.
+func serializeRevocationLog(w io.Writer, c *commitment, ourOutputIndex,
+	theirOutputIndex uint16) error {
+
+	commitTxHash := [32]byte(c.commitTx.TxHash())
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(
+			revLogOurOutputIndexType, &ourOutputIndex,
+		),
+		tlv.MakePrimitiveRecord(
+			revLogTheirOutputIndexType, &theirOutputIndex,
+		),
+		tlv.MakePrimitiveRecord(revLogCommitTxHashType, &commitTxHash),
+		tlv.MakePrimitiveRecord(revLogOurBalanceType, &c.localBalance),
+		tlv.MakePrimitiveRecord(
+			revLogTheirBalanceType, &c.remoteBalance,
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := writeTlvStream(w, tlvStream); err != nil {
+		return err
+	}
+
+	for _, h := range c.htlcs {
+		// Dust HTLCs don't have an output that could be swept.
+		if h.outputIndex < 0 {
+			continue
+		}
+
+		var incoming uint8
+		if h.incoming {
+			incoming = 1
+		}
+		outputIndex := uint16(h.outputIndex)
+
+		// The amount of the HTLC output is stored in atoms.
+		amt := h.amt / 1000
+
+		tlvStream, err := tlv.NewStream(
+			tlv.MakePrimitiveRecord(htlcEntryRHashType, &h.rHash),
+			tlv.MakePrimitiveRecord(
+				htlcEntryRefundTimeoutType, &h.refundTimeout,
+			),
+			tlv.MakePrimitiveRecord(
+				htlcEntryOutputIndexType, &outputIndex,
+			),
+			tlv.MakePrimitiveRecord(
+				htlcEntryIncomingType, &incoming,
+			),
+			tlv.MakePrimitiveRecord(htlcEntryAmtType, &amt),
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := writeTlvStream(w, tlvStream); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTlvStream writes the TLV stream prefixed with its length.
+func writeTlvStream(w io.Writer, tlvStream *tlv.Stream) error {
+	var b bytes.Buffer
+	if err := tlvStream.Encode(&b); err != nil {
+		return err
+	}
+
+	var scratch [8]byte
+	if err := tlv.WriteVarInt(w, uint64(b.Len()), &scratch); err != nil {
+		return err
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,352 @@
+package migration20
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+var (
+	openChannelBucket = []byte("open-chan-bucket")
+
+	revocationLogBucket = []byte("revocation-log")
+
+	revocationLogBucketDeprecated = []byte("revocation-log-key")
+
+	byteOrder = binary.BigEndian
+)
+
+// outputIndexEmpty is the output index of a commitment output that doesn't
+// exist.
+const outputIndexEmpty = math.MaxUint16
+
+// migrationBatchSize is the number of log entries converted within a single
+// database transaction, which keeps the transactions small for channels with
+// a huge revocation log.
+var migrationBatchSize = 10000
+
+const (
+	revLogOurOutputIndexType   tlv.Type = 0
+	revLogTheirOutputIndexType tlv.Type = 1
+	revLogCommitTxHashType     tlv.Type = 2
+	revLogOurBalanceType       tlv.Type = 3
+	revLogTheirBalanceType     tlv.Type = 4
+
+	htlcEntryRHashType         tlv.Type = 0
+	htlcEntryRefundTimeoutType tlv.Type = 1
+	htlcEntryOutputIndexType   tlv.Type = 2
+	htlcEntryIncomingType      tlv.Type = 3
+	htlcEntryAmtType           tlv.Type = 4
+)
+
+// chanBucketPath is the path of the bucket of an open channel, below the open
+// channel bucket.
+type chanBucketPath struct {
+	nodePub   []byte
+	chainHash []byte
+	chanPoint []byte
+}
+
+// MigrateRevocationLog converts the revocation log entries of all open
+// channels from the former format, which stored the full commitment of each
+// revoked state, to the compact format, which only stores the data needed to
+// punish a breach. The former log of a channel is removed once all its
+// entries are converted.
+//
+// The compact format records the indexes of our and their output on the
+// revoked commitment. As the keys to derive the output scripts aren't known
+// here, the outputs are located by their amount. An entry whose outputs can't
+// be told apart this way is kept in the former format, which is still read.
+//
+// NOTE: Unlike regular migrations, this one is optional and converts the log
+// in multiple transactions, so it may be interrupted and resumed safely.
+func MigrateRevocationLog(db kvdb.Backend) error {
+	log.Infof("Migrating revocation logs to the compact format")
+
+	var chanPaths []chanBucketPath
+	err := kvdb.View(db, func(tx kvdb.RTx) error {
+		chanPaths = nil
+
+		var err error
+		chanPaths, err = findLegacyLogs(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var totalConverted, totalKept uint64
+	for _, chanPath := range chanPaths {
+		converted, kept, err := migrateChannel(db, chanPath)
+		if err != nil {
+			return fmt.Errorf("unable to migrate revocation log "+
+				"of channel %x: %v", chanPath.chanPoint, err)
+		}
+
+		totalConverted += converted
+		totalKept += kept
+	}
+
+	log.Infof("Converted %d revocation log entries of %d channels, %d "+
+		"entries were kept in the former format", totalConverted,
+		len(chanPaths), totalKept)
+
+	return nil
+}
+
+// findLegacyLogs returns the paths of the open channels that have a
+// revocation log in the former format.
+func findLegacyLogs(tx kvdb.RTx) ([]chanBucketPath, error) {
+	openChanBucket := tx.ReadBucket(openChannelBucket)
+	if openChanBucket == nil {
+		return nil, nil
+	}
+
+	var chanPaths []chanBucketPath
+	err := forEachNestedBucket(openChanBucket, func(nodePub []byte,
+		nodeBucket kvdb.RBucket) error {
+
+		return forEachNestedBucket(nodeBucket, func(chainHash []byte,
+			chainBucket kvdb.RBucket) error {
+
+			return forEachNestedBucket(chainBucket, func(cp []byte,
+				chanBucket kvdb.RBucket) error {
+
+				legacyBucket := chanBucket.NestedReadBucket(
+					revocationLogBucketDeprecated,
+				)
+				if legacyBucket == nil {
+					return nil
+				}
+
+				chanPaths = append(chanPaths, chanBucketPath{
+					nodePub:   copyBytes(nodePub),
+					chainHash: copyBytes(chainHash),
+					chanPoint: copyBytes(cp),
+				})
+
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chanPaths, nil
+}
+
+// forEachNestedBucket calls f with each nested bucket of the given bucket.
+func forEachNestedBucket(bucket kvdb.RBucket,
+	f func(k []byte, nested kvdb.RBucket) error) error {
+
+	return bucket.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+
+		nested := bucket.NestedReadBucket(k)
+		if nested == nil {
+			return nil
+		}
+
+		return f(k, nested)
+	})
+}
+
+// migrateChannel converts the former revocation log of a channel in batches,
+// returning the number of converted entries and the number of entries kept
+// in the former format.
+func migrateChannel(db kvdb.Backend, chanPath chanBucketPath) (uint64,
+	uint64, error) {
+
+	var (
+		converted, kept uint64
+		nextKey         []byte
+	)
+	for done := false; !done; {
+		var batchConverted, batchKept uint64
+		var batchNextKey []byte
+		err := kvdb.Update(db, func(tx kvdb.RwTx) error {
+			batchConverted, batchKept = 0, 0
+			batchNextKey = nil
+
+			chanBucket, err := fetchChanBucket(tx, chanPath)
+			if err != nil {
+				return err
+			}
+
+			legacyBucket := chanBucket.NestedReadWriteBucket(
+				revocationLogBucketDeprecated,
+			)
+			if legacyBucket == nil {
+				return nil
+			}
+
+			logBucket, err := chanBucket.CreateBucketIfNotExists(
+				revocationLogBucket,
+			)
+			if err != nil {
+				return err
+			}
+
+			// Collect the batch first, as the bucket must not be
+			// modified while iterating over it.
+			var keys, values [][]byte
+			cursor := legacyBucket.ReadCursor()
+			k, v := cursor.First()
+			if nextKey != nil {
+				k, v = cursor.Seek(nextKey)
+			}
+			for ; k != nil; k, v = cursor.Next() {
+				if len(keys) == migrationBatchSize {
+					batchNextKey = copyBytes(k)
+					break
+				}
+
+				keys = append(keys, copyBytes(k))
+				values = append(values, copyBytes(v))
+			}
+
+			for i, key := range keys {
+				ok, err := convertEntry(
+					logBucket, key, values[i],
+				)
+				if err != nil {
+					return fmt.Errorf("entry %x: %v", key,
+						err)
+				}
+				if !ok {
+					batchKept++
+					continue
+				}
+
+				if err := legacyBucket.Delete(key); err != nil {
+					return err
+				}
+				batchConverted++
+			}
+
+			// Drop the former log once all its entries are
+			// converted.
+			if batchNextKey != nil || kept+batchKept > 0 {
+				return nil
+			}
+			return chanBucket.DeleteNestedBucket(
+				revocationLogBucketDeprecated,
+			)
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+
+		converted += batchConverted
+		kept += batchKept
+		nextKey = batchNextKey
+		done = nextKey == nil
+
+		log.Infof("Converted %d revocation log entries of channel %x",
+			converted, chanPath.chanPoint)
+	}
+
+	return converted, kept, nil
+}
+
+// convertEntry writes the compact revocation log entry of the given entry in
+// the former format to the log bucket. False is returned if the entry can't
+// be converted.
+func convertEntry(logBucket kvdb.RwBucket, key, value []byte) (bool, error) {
+	c, err := deserializeCommitment(bytes.NewReader(value))
+	if err != nil {
+		return false, err
+	}
+
+	ourOutputIndex, theirOutputIndex, ok := findOutputIndexes(c)
+	if !ok {
+		return false, nil
+	}
+
+	var b bytes.Buffer
+	err = serializeRevocationLog(
+		&b, c, ourOutputIndex, theirOutputIndex,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, logBucket.Put(key, b.Bytes())
+}
+
+// findOutputIndexes locates our and their output on the commitment by their
+// amount among the outputs that don't belong to an HTLC. False is returned if
+// either output can't be told apart from another output.
+func findOutputIndexes(c *commitment) (uint16, uint16, bool) {
+	htlcOutputs := make(map[int32]struct{}, len(c.htlcs))
+	for _, h := range c.htlcs {
+		htlcOutputs[h.outputIndex] = struct{}{}
+	}
+
+	ourAmt := int64(c.localBalance / 1000)
+	theirAmt := int64(c.remoteBalance / 1000)
+
+	// If both balances are equal, their outputs can't be told apart.
+	if ourAmt == theirAmt {
+		return 0, 0, false
+	}
+
+	var (
+		ourOutputIndex   uint16 = outputIndexEmpty
+		theirOutputIndex uint16 = outputIndexEmpty
+	)
+	for i, txOut := range c.commitTx.TxOut {
+		if _, ok := htlcOutputs[int32(i)]; ok {
+			continue
+		}
+
+		switch txOut.Value {
+		case ourAmt:
+			if ourOutputIndex != outputIndexEmpty {
+				return 0, 0, false
+			}
+			ourOutputIndex = uint16(i)
+
+		case theirAmt:
+			if theirOutputIndex != outputIndexEmpty {
+				return 0, 0, false
+			}
+			theirOutputIndex = uint16(i)
+		}
+	}
+
+	return ourOutputIndex, theirOutputIndex, true
+}
+
+// fetchChanBucket returns the bucket of the channel at the given path.
+func fetchChanBucket(tx kvdb.RwTx,
+	chanPath chanBucketPath) (kvdb.RwBucket, error) {
+
+	bucket := tx.ReadWriteBucket(openChannelBucket)
+	for _, key := range [][]byte{
+		chanPath.nodePub, chanPath.chainHash, chanPath.chanPoint,
+	} {
+		if bucket == nil {
+			break
+		}
+		bucket = bucket.NestedReadWriteBucket(key)
+	}
+	if bucket == nil {
+		return nil, fmt.Errorf("channel bucket not found")
+	}
+
+	return bucket, nil
+}
+
+// copyBytes returns a copy of the given byte slice, which is only valid
+// within the transaction it was read in.
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
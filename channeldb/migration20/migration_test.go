@@ -0,0 +1,250 @@
+package migration20
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/channeldb/migtest"
+)
+
+var (
+	hexStr = migtest.Hex
+
+	nodePub   = hexStr("02aa")
+	chainHash = hexStr("bb")
+	chanPoint = hexStr("cc01")
+
+	height0 = hexStr("0000000000000000")
+	height1 = hexStr("0000000000000001")
+	height2 = hexStr("0000000000000002")
+
+	// convertible has distinct balances and an HTLC output in between our
+	// and their output, along with a dust HTLC.
+	convertible = &commitment{
+		localBalance:  5000000,
+		remoteBalance: 7000000,
+		commitTx:      commitTx(5000, 3000, 7000),
+		htlcs: []htlc{
+			{
+				rHash:         [32]byte{1},
+				amt:           3000000,
+				refundTimeout: 100,
+				outputIndex:   1,
+				incoming:      true,
+			},
+			{
+				rHash:         [32]byte{2},
+				amt:           1000,
+				refundTimeout: 101,
+				outputIndex:   -1,
+			},
+		},
+	}
+
+	// ambiguous has equal balances, so its outputs can't be told apart.
+	ambiguous = &commitment{
+		commitHeight:  1,
+		localBalance:  6000000,
+		remoteBalance: 6000000,
+		commitTx:      commitTx(6000, 6000),
+	}
+
+	// dustOutput has our balance below the dust limit, so only their
+	// output exists.
+	dustOutput = &commitment{
+		commitHeight:  2,
+		localBalance:  100000,
+		remoteBalance: 11000000,
+		commitTx:      commitTx(11000),
+	}
+)
+
+// commitTx returns a commitment transaction with outputs of the given values.
+func commitTx(values ...int64) *wire.MsgTx {
+	tx := wire.NewMsgTx()
+	tx.Version = 2
+	for _, value := range values {
+		tx.AddTxOut(wire.NewTxOut(value, []byte{0x01}))
+	}
+
+	return tx
+}
+
+// legacyEntry serializes the commitment in the former revocation log format.
+func legacyEntry(c *commitment) string {
+	var b bytes.Buffer
+	write := func(elements ...interface{}) {
+		for _, element := range elements {
+			err := binary.Write(&b, byteOrder, element)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	writeVarBytes := func(v []byte) {
+		if err := wire.WriteVarBytes(&b, 0, v); err != nil {
+			panic(err)
+		}
+	}
+
+	write(
+		c.commitHeight, [4]uint64{1, 2, 3, 4}, c.localBalance,
+		c.remoteBalance, [2]uint64{5, 6},
+	)
+	if err := c.commitTx.Serialize(&b); err != nil {
+		panic(err)
+	}
+	writeVarBytes([]byte("commit sig"))
+
+	write(uint16(len(c.htlcs)))
+	for _, h := range c.htlcs {
+		writeVarBytes([]byte("htlc sig"))
+		write(
+			h.rHash, h.amt, h.refundTimeout, h.outputIndex,
+			h.incoming,
+		)
+		writeVarBytes([]byte("onion blob"))
+		write([2]uint64{7, 8})
+	}
+
+	return b.String()
+}
+
+// compactEntry serializes the commitment in the compact revocation log
+// format.
+func compactEntry(c *commitment, ourOutputIndex,
+	theirOutputIndex uint16) string {
+
+	var b bytes.Buffer
+	err := serializeRevocationLog(&b, c, ourOutputIndex, theirOutputIndex)
+	if err != nil {
+		panic(err)
+	}
+
+	return b.String()
+}
+
+// TestDeserializeCommitment asserts that the fields needed by the compact
+// format are read from a commitment in the former format.
+func TestDeserializeCommitment(t *testing.T) {
+	t.Parallel()
+
+	c, err := deserializeCommitment(
+		bytes.NewReader([]byte(legacyEntry(convertible))),
+	)
+	if err != nil {
+		t.Fatalf("unable to deserialize commitment: %v", err)
+	}
+
+	if c.localBalance != convertible.localBalance ||
+		c.remoteBalance != convertible.remoteBalance {
+
+		t.Fatalf("balance mismatch: got %v/%v", c.localBalance,
+			c.remoteBalance)
+	}
+	if c.commitTx.TxHash() != convertible.commitTx.TxHash() {
+		t.Fatalf("commitment transaction mismatch")
+	}
+	if len(c.htlcs) != 2 || c.htlcs[0] != convertible.htlcs[0] ||
+		c.htlcs[1] != convertible.htlcs[1] {
+
+		t.Fatalf("htlc mismatch: got %v", c.htlcs)
+	}
+}
+
+// openChanBucket returns the content of the open channel bucket holding a
+// single channel with the given content.
+func openChanBucket(chanBucket map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		nodePub: map[string]interface{}{
+			chainHash: map[string]interface{}{
+				chanPoint: chanBucket,
+			},
+		},
+	}
+}
+
+// TestMigrateRevocationLog asserts that the entries of the former revocation
+// log are converted to the compact format, except those whose outputs can't
+// be located, in which case the former log is kept.
+func TestMigrateRevocationLog(t *testing.T) {
+	pre := openChanBucket(map[string]interface{}{
+		"revocation-log-key": map[string]interface{}{
+			height0: legacyEntry(convertible),
+			height1: legacyEntry(ambiguous),
+			height2: legacyEntry(dustOutput),
+		},
+	})
+
+	post := openChanBucket(map[string]interface{}{
+		"revocation-log-key": map[string]interface{}{
+			height1: legacyEntry(ambiguous),
+		},
+		"revocation-log": map[string]interface{}{
+			height0: compactEntry(convertible, 0, 2),
+			height2: compactEntry(dustOutput, outputIndexEmpty, 0),
+		},
+	})
+
+	testMigrateRevocationLog(t, pre, post)
+}
+
+// TestMigrateRevocationLogDrop asserts that the former revocation log is
+// removed once all its entries are converted, even if it takes multiple
+// batches.
+func TestMigrateRevocationLogDrop(t *testing.T) {
+	defer func(batchSize int) {
+		migrationBatchSize = batchSize
+	}(migrationBatchSize)
+	migrationBatchSize = 1
+
+	pre := openChanBucket(map[string]interface{}{
+		"revocation-log-key": map[string]interface{}{
+			height0: legacyEntry(convertible),
+			height2: legacyEntry(dustOutput),
+		},
+	})
+
+	post := openChanBucket(map[string]interface{}{
+		"revocation-log": map[string]interface{}{
+			height0: compactEntry(convertible, 0, 2),
+			height2: compactEntry(dustOutput, outputIndexEmpty, 0),
+		},
+	})
+
+	testMigrateRevocationLog(t, pre, post)
+}
+
+// testMigrateRevocationLog runs the migration on the given open channel
+// bucket and asserts the result. As the verification fails on unexpected keys,
+// this also asserts that the former log is only kept if expected.
+func testMigrateRevocationLog(t *testing.T, pre,
+	post map[string]interface{}) {
+
+	db, cleanUp, err := migtest.MakeDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+
+	err = kvdb.Update(db, func(tx kvdb.RwTx) error {
+		return migtest.RestoreDB(tx, openChannelBucket, pre)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateRevocationLog(db); err != nil {
+		t.Fatalf("unable to migrate: %v", err)
+	}
+
+	err = kvdb.View(db, func(tx kvdb.RTx) error {
+		return migtest.VerifyDB(tx, openChannelBucket, post)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -29,6 +29,69 @@ type HTLCAttemptInfo struct {
 
 	// AttemptTime is the time at which this HTLC was attempted.
 	AttemptTime time.Time
+
+	// Hash is the hash used for this single HTLC attempt. For AMP payments
+	// this will differ across attempts, for non-AMP payments each attempt
+	// will use the same hash. This can be nil for older payment attempts,
+	// in which the payment's PaymentHash in the PaymentCreationInfo should
+	// be used.
+	Hash *lntypes.Hash
+
+	// ShardAllocation describes how the amount of this attempt was
+	// chosen when the payment was split. It is nil for attempts that
+	// weren't created by splitting the remaining amount.
+	ShardAllocation *ShardAllocation
+}
+
+// SplitStrategy identifies the strategy used to split a payment into shards.
+type SplitStrategy uint8
+
+const (
+	// SplitStrategyHalving halves the shard amount until a route is
+	// found.
+	SplitStrategyHalving SplitStrategy = 0
+
+	// SplitStrategyFeeAware allocates the shard amount proportionally to
+	// the fee, success probability and capacity of candidate routes.
+	SplitStrategyFeeAware SplitStrategy = 1
+)
+
+// String returns a human readable name of the split strategy.
+func (s SplitStrategy) String() string {
+	switch s {
+	case SplitStrategyHalving:
+		return "halving"
+
+	case SplitStrategyFeeAware:
+		return "fee-aware"
+
+	default:
+		return "unknown"
+	}
+}
+
+// ShardAllocation records the decision of a split strategy that resulted in
+// the amount of a single HTLC attempt.
+type ShardAllocation struct {
+	// Strategy is the split strategy that allocated the shard.
+	Strategy SplitStrategy
+
+	// SplitAmt is the amount that couldn't be sent in one piece and was
+	// split.
+	SplitAmt lnwire.MilliAtom
+
+	// NumCandidates is the number of candidate routes that were
+	// considered.
+	NumCandidates uint32
+
+	// SharePPM is the share of the split amount that was allocated to
+	// this shard, in parts per million.
+	SharePPM uint32
+
+	// ProbabilityPPM is the estimated success probability of the route
+	// of the shard, in parts per million. It is zero if the strategy
+	// doesn't estimate probabilities.
+	ProbabilityPPM uint32
 }
 
 // HTLCAttempt contains information about a specific HTLC attempt for a given
@@ -145,6 +208,40 @@ func (m *MPPayment) TerminalInfo() (*HTLCSettleInfo, *FailureReason) {
 	return nil, m.FailureReason
 }
 
+// settleTime returns the time the first HTLC of the payment settled, or the
+// zero time if none of them did.
+func (m *MPPayment) settleTime() time.Time {
+	var settleTime time.Time
+	for _, h := range m.HTLCs {
+		if h.Settle == nil {
+			continue
+		}
+
+		t := h.Settle.SettleTime
+		if settleTime.IsZero() || t.Before(settleTime) {
+			settleTime = t
+		}
+	}
+
+	return settleTime
+}
+
+// hasStatus returns true if the payment has one of the given statuses, or if
+// no statuses are given.
+func (m *MPPayment) hasStatus(statuses []PaymentStatus) bool {
+	if len(statuses) == 0 {
+		return true
+	}
+
+	for _, status := range statuses {
+		if m.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SentAmt returns the sum of sent amount and fees for HTLCs that are either
 // settled or still in flight.
 func (m *MPPayment) SentAmt() (lnwire.MilliAtom, lnwire.MilliAtom) {
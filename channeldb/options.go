@@ -35,6 +35,11 @@ type Options struct {
 	// dryRun will fail to commit a successful migration when opening the
 	// database if set to true.
 	dryRun bool
+
+	// pruneRevocationLog will convert the revocation logs stored in the
+	// former format to the compact format when opening the database if set
+	// to true.
+	pruneRevocationLog bool
 }
 
 // DefaultOptions returns an Options populated with default values.
@@ -85,3 +90,12 @@ func OptionDryRunMigration(dryRun bool) OptionModifier {
 		o.dryRun = dryRun
 	}
 }
+
+// OptionPruneRevocationLog controls whether or not to convert the revocation
+// logs stored in the former format to the compact format, dropping the full
+// commitments they hold, when opening the database.
+func OptionPruneRevocationLog(prune bool) OptionModifier {
+	return func(o *Options) {
+		o.pruneRevocationLog = prune
+	}
+}
@@ -1,6 +1,12 @@
 package channeldb
 
-import "github.com/decred/dcrlnd/channeldb/kvdb"
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+)
 
 type paginator struct {
 	// cursor is the cursor which we are using to iterate through a bucket.
@@ -138,3 +144,202 @@ func (p paginator) query(fetchAndAppend func(k, v []byte) (bool, error)) error {
 
 	return nil
 }
+
+// timeIndexKey returns the key of an entry of a time index, which maps the
+// time of an event and the index of the item it happened to to an empty value.
+// The keys are ordered by time, and by index for events that happened at the
+// same time.
+func timeIndexKey(t time.Time, index uint64) []byte {
+	// Convert to unix nano seconds, but only if time is non-zero. Calling
+	// UnixNano() on a zero time yields an undefined result.
+	var unixNano int64
+	if !t.IsZero() {
+		unixNano = t.UnixNano()
+	}
+
+	var key [16]byte
+	byteOrder.PutUint64(key[:8], uint64(unixNano))
+	byteOrder.PutUint64(key[8:], index)
+
+	return key[:]
+}
+
+// timeRange selects the entries of a time index that fall within a time range.
+// A zero start or end time leaves the range open on that side, both ends of
+// the range are inclusive.
+type timeRange struct {
+	// timeIndex is the time index bucket to read the entries from.
+	timeIndex kvdb.RBucket
+
+	// start is the earliest time of the selected entries.
+	start time.Time
+
+	// end is the latest time of the selected entries.
+	end time.Time
+}
+
+// isOpen returns true if the range is open on both sides, in which case it
+// doesn't filter anything.
+func (r timeRange) isOpen() bool {
+	return r.start.IsZero() && r.end.IsZero()
+}
+
+// indexes returns the set of indexes of the entries within the time range.
+func (r timeRange) indexes() map[uint64]struct{} {
+	indexes := make(map[uint64]struct{})
+	if r.timeIndex == nil {
+		return indexes
+	}
+
+	var endNano int64
+	if !r.end.IsZero() {
+		endNano = r.end.UnixNano()
+	}
+
+	cursor := r.timeIndex.ReadCursor()
+	k, _ := cursor.Seek(timeIndexKey(r.start, 0))
+	for ; k != nil; k, _ = cursor.Next() {
+		if len(k) != 16 {
+			continue
+		}
+
+		if endNano != 0 && int64(byteOrder.Uint64(k[:8])) > endNano {
+			break
+		}
+
+		indexes[byteOrder.Uint64(k[8:])] = struct{}{}
+	}
+
+	return indexes
+}
+
+// filterIndex returns a cursor over the entries of the index bucket whose
+// keys, which must be 8-byte indexes, are selected by all of the given time
+// ranges. Ranges that are open on both sides are ignored, so if all of them
+// are, a cursor over the whole index bucket is returned.
+func filterIndex(index kvdb.RBucket, ranges ...timeRange) kvdb.RCursor {
+	var selected map[uint64]struct{}
+	for _, r := range ranges {
+		if r.isOpen() {
+			continue
+		}
+
+		indexes := r.indexes()
+		if selected == nil {
+			selected = indexes
+			continue
+		}
+
+		for i := range selected {
+			if _, ok := indexes[i]; !ok {
+				delete(selected, i)
+			}
+		}
+	}
+
+	if selected == nil {
+		return index.ReadCursor()
+	}
+
+	cursor := &sliceCursor{pos: -1}
+	for i := range selected {
+		var key [8]byte
+		byteOrder.PutUint64(key[:], i)
+
+		// Skip stale entries that don't point to an existing item.
+		value := index.Get(key[:])
+		if value == nil {
+			continue
+		}
+
+		cursor.keys = append(cursor.keys, key[:])
+		cursor.values = append(cursor.values, value)
+	}
+	sort.Sort(cursor)
+
+	return cursor
+}
+
+// sliceCursor is a read cursor over a sorted set of key/value pairs held in
+// memory. It allows the paginator to iterate over a subset of the entries of
+// an index bucket.
+type sliceCursor struct {
+	keys   [][]byte
+	values [][]byte
+	pos    int
+}
+
+// A compile time check to ensure sliceCursor implements the kvdb.RCursor
+// interface.
+var _ kvdb.RCursor = (*sliceCursor)(nil)
+
+// Len returns the number of key/value pairs of the cursor.
+//
+// NOTE: Part of the sort.Interface interface.
+func (c *sliceCursor) Len() int {
+	return len(c.keys)
+}
+
+// Less returns whether the key at index i sorts before the key at index j.
+//
+// NOTE: Part of the sort.Interface interface.
+func (c *sliceCursor) Less(i, j int) bool {
+	return bytes.Compare(c.keys[i], c.keys[j]) < 0
+}
+
+// Swap swaps the key/value pairs at index i and j.
+//
+// NOTE: Part of the sort.Interface interface.
+func (c *sliceCursor) Swap(i, j int) {
+	c.keys[i], c.keys[j] = c.keys[j], c.keys[i]
+	c.values[i], c.values[j] = c.values[j], c.values[i]
+}
+
+// current returns the key/value pair the cursor is positioned at, or nil if
+// it is out of range.
+func (c *sliceCursor) current() ([]byte, []byte) {
+	if c.pos < 0 || c.pos >= len(c.keys) {
+		return nil, nil
+	}
+
+	return c.keys[c.pos], c.values[c.pos]
+}
+
+// First positions the cursor at the first key/value pair and returns the
+// pair.
+func (c *sliceCursor) First() ([]byte, []byte) {
+	c.pos = 0
+	return c.current()
+}
+
+// Last positions the cursor at the last key/value pair and returns the pair.
+func (c *sliceCursor) Last() ([]byte, []byte) {
+	c.pos = len(c.keys) - 1
+	return c.current()
+}
+
+// Next moves the cursor one key/value pair forward and returns the new pair.
+func (c *sliceCursor) Next() ([]byte, []byte) {
+	if c.pos < len(c.keys) {
+		c.pos++
+	}
+	return c.current()
+}
+
+// Prev moves the cursor one key/value pair backward and returns the new pair.
+func (c *sliceCursor) Prev() ([]byte, []byte) {
+	if c.pos >= 0 {
+		c.pos--
+	}
+	return c.current()
+}
+
+// Seek positions the cursor at the passed seek key. If the key does not
+// exist, the cursor is moved to the next key after seek. Returns the new
+// pair.
+func (c *sliceCursor) Seek(seek []byte) ([]byte, []byte) {
+	c.pos = sort.Search(len(c.keys), func(i int) bool {
+		return bytes.Compare(c.keys[i], seek) >= 0
+	})
+	return c.current()
+}
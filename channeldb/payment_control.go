@@ -72,6 +72,18 @@ var (
 	// shard where the total amount doesn't match existing shards.
 	ErrMPPTotalAmountMismatch = errors.New("mp payment total amount mismatch")
 
+	// ErrAMPayment is returned if we try to register a non-AMP attempt for
+	// a payment that already has an AMP attempt registered.
+	ErrAMPayment = errors.New("payment has AMP attempts")
+
+	// ErrNonAMPayment is returned if we try to register an AMP attempt for
+	// a payment that already has a non-AMP attempt registered.
+	ErrNonAMPayment = errors.New("payment has non-AMP attempts")
+
+	// ErrAMPSetIDMismatch is returned if we try to register an AMP shard
+	// where the set ID doesn't match existing shards.
+	ErrAMPSetIDMismatch = errors.New("amp set id mismatch")
+
 	// errNoAttemptInfo is returned when no attempt info is stored yet.
 	errNoAttemptInfo = errors.New("unable to find attempt info for " +
 		"inflight payment")
@@ -169,6 +181,11 @@ func (p *PaymentControl) InitPayment(paymentHash lntypes.Hash,
 			if err := indexBucket.Delete(seqBytes); err != nil {
 				return err
 			}
+
+			err := deletePaymentTimeIndexes(tx, bucket)
+			if err != nil {
+				return err
+			}
 		}
 
 		// Once we have obtained a sequence number, we add an entry
@@ -184,6 +201,18 @@ func (p *PaymentControl) InitPayment(paymentHash lntypes.Hash,
 			return err
 		}
 
+		// Add the payment to the creation time index, so that it can
+		// be found by its creation time.
+		creationTimeIndex := tx.ReadWriteBucket(
+			paymentsCreationTimeIndexBucket,
+		)
+		err = creationTimeIndex.Put(timeIndexKey(
+			info.CreationTime, byteOrder.Uint64(sequenceNum),
+		), []byte{})
+		if err != nil {
+			return err
+		}
+
 		// Add the payment info to the bucket, which contains the
 		// static information for this payment
 		err = bucket.Put(paymentCreationInfoKey, infoBytes)
@@ -303,10 +332,29 @@ func (p *PaymentControl) RegisterAttempt(paymentHash lntypes.Hash,
 		}
 
 		// Make sure any existing shards match the new one with regards
-		// to MPP options.
+		// to MPP and AMP options.
 		mpp := attempt.Route.FinalHop().MPP
+		amp := attempt.Route.FinalHop().AMP
 		for _, h := range p.InFlightHTLCs() {
 			hMpp := h.Route.FinalHop().MPP
+			hAmp := h.Route.FinalHop().AMP
+
+			switch {
+
+			// We tried to register a non-AMP attempt for an AMP
+			// payment.
+			case amp == nil && hAmp != nil:
+				return ErrAMPayment
+
+			// We tried to register an AMP shard for a non-AMP
+			// payment.
+			case amp != nil && hAmp == nil:
+				return ErrNonAMPayment
+
+			// All AMP shards must belong to the same set.
+			case amp != nil && amp.SetID() != hAmp.SetID():
+				return ErrAMPSetIDMismatch
+			}
 
 			switch {
 
@@ -464,6 +512,29 @@ func (p *PaymentControl) updateHtlcKey(paymentHash lntypes.Hash,
 			return err
 		}
 
+		// The first settled htlc determines the settle time of the
+		// payment, which is recorded in the settle time index.
+		if bytes.Equal(key, htlcSettleInfoKey) &&
+			p.settleTime().IsZero() {
+
+			settleInfo, err := deserializeHTLCSettleInfo(
+				bytes.NewReader(value),
+			)
+			if err != nil {
+				return err
+			}
+
+			settleTimeIndex := tx.ReadWriteBucket(
+				paymentsSettleTimeIndexBucket,
+			)
+			err = settleTimeIndex.Put(timeIndexKey(
+				settleInfo.SettleTime, p.SequenceNum,
+			), []byte{})
+			if err != nil {
+				return err
+			}
+		}
+
 		// Retrieve attempt info for the notification.
 		payment, err = fetchPayment(bucket)
 		return err
@@ -558,6 +629,15 @@ func (p *PaymentControl) FetchPayment(paymentHash lntypes.Hash) (
 	return payment, nil
 }
 
+// DeletePayment deletes the payment with the given hash, or only its failed
+// htlc attempts if failedHtlcsOnly is set. In-flight payments can't be
+// deleted.
+func (p *PaymentControl) DeletePayment(paymentHash lntypes.Hash,
+	failedHtlcsOnly bool) error {
+
+	return p.db.DeletePayment(paymentHash, failedHtlcsOnly)
+}
+
 // createPaymentBucket creates or fetches the sub-bucket assigned to this
 // payment hash.
 func createPaymentBucket(tx kvdb.RwTx, paymentHash lntypes.Hash) (
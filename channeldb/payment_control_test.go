@@ -472,7 +472,7 @@ func TestPaymentControlDeleteNonInFligt(t *testing.T) {
 	}
 
 	// Delete payments.
-	if err := db.DeletePayments(); err != nil {
+	if err := db.DeletePayments(false, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -1017,3 +1017,147 @@ func assertNoIndex(t *testing.T, p *PaymentControl, seqNr uint64) {
 	_, err := fetchPaymentIndexEntry(t, p, seqNr)
 	require.Equal(t, errNoSequenceNrIndex, err)
 }
+
+// TestPaymentControlDeletePayment checks that single payments, or only their
+// failed htlcs, can be deleted, unless they are in flight.
+func TestPaymentControlDeletePayment(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := MakeTestDB()
+	defer cleanup()
+	require.NoError(t, err)
+
+	pControl := NewPaymentControl(db)
+
+	// initPayment initiates a new payment with a first htlc attempt that
+	// is failed.
+	initPayment := func() (*PaymentCreationInfo, *HTLCAttemptInfo,
+		lntypes.Preimage) {
+
+		info, attempt, preimg, err := genInfo()
+		require.NoError(t, err)
+
+		require.NoError(t, pControl.InitPayment(info.PaymentHash, info))
+		_, err = pControl.RegisterAttempt(info.PaymentHash, attempt)
+		require.NoError(t, err)
+		_, err = pControl.FailAttempt(
+			info.PaymentHash, attempt.AttemptID,
+			&HTLCFailInfo{Reason: HTLCFailUnreadable},
+		)
+		require.NoError(t, err)
+
+		return info, attempt, preimg
+	}
+
+	// The first payment succeeds with its second attempt.
+	succeeded, attempt, preimg := initPayment()
+	attempt.AttemptID = 1
+	_, err = pControl.RegisterAttempt(succeeded.PaymentHash, attempt)
+	require.NoError(t, err)
+	_, err = pControl.SettleAttempt(
+		succeeded.PaymentHash, attempt.AttemptID,
+		&HTLCSettleInfo{Preimage: preimg},
+	)
+	require.NoError(t, err)
+
+	// The second payment fails.
+	failed, _, _ := initPayment()
+	_, err = pControl.Fail(failed.PaymentHash, FailureReasonNoRoute)
+	require.NoError(t, err)
+
+	// The third payment is still in flight with its second attempt.
+	inFlight, attempt, _ := initPayment()
+	attempt.AttemptID = 1
+	_, err = pControl.RegisterAttempt(inFlight.PaymentHash, attempt)
+	require.NoError(t, err)
+
+	// In-flight payments can't be deleted, not even their failed htlcs.
+	err = pControl.DeletePayment(inFlight.PaymentHash, false)
+	require.Equal(t, ErrPaymentInFlight, err)
+	err = pControl.DeletePayment(inFlight.PaymentHash, true)
+	require.Equal(t, ErrPaymentInFlight, err)
+
+	// Deleting the failed htlcs of the successful payment keeps the
+	// settled one.
+	require.NoError(t, pControl.DeletePayment(succeeded.PaymentHash, true))
+	payment, err := pControl.FetchPayment(succeeded.PaymentHash)
+	require.NoError(t, err)
+	require.Equal(t, StatusSucceeded, payment.Status)
+	require.Len(t, payment.HTLCs, 1)
+	require.NotNil(t, payment.HTLCs[0].Settle)
+
+	// The failed payment is deleted entirely.
+	require.NoError(t, pControl.DeletePayment(failed.PaymentHash, false))
+	_, err = pControl.FetchPayment(failed.PaymentHash)
+	require.Equal(t, ErrPaymentNotInitiated, err)
+
+	err = pControl.DeletePayment(failed.PaymentHash, false)
+	require.Equal(t, ErrPaymentNotInitiated, err)
+
+	payments, err := db.FetchPayments()
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+}
+
+// TestDeletePaymentsInRange checks that only the payments created in the
+// requested time range are deleted.
+func TestDeletePaymentsInRange(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := MakeTestDB()
+	defer cleanup()
+	require.NoError(t, err)
+
+	pControl := NewPaymentControl(db)
+
+	var hashes []lntypes.Hash
+	for _, created := range []int64{100, 200, 300} {
+		info, attempt, preimg, err := genInfo()
+		require.NoError(t, err)
+		info.CreationTime = time.Unix(created, 0)
+
+		require.NoError(t, pControl.InitPayment(info.PaymentHash, info))
+		_, err = pControl.RegisterAttempt(info.PaymentHash, attempt)
+		require.NoError(t, err)
+		_, err = pControl.SettleAttempt(
+			info.PaymentHash, attempt.AttemptID,
+			&HTLCSettleInfo{Preimage: preimg},
+		)
+		require.NoError(t, err)
+
+		hashes = append(hashes, info.PaymentHash)
+	}
+
+	assertPayments := func(expected ...lntypes.Hash) {
+		t.Helper()
+
+		payments, err := db.FetchPayments()
+		require.NoError(t, err)
+		require.Len(t, payments, len(expected))
+
+		for i, payment := range payments {
+			require.Equal(t, expected[i], payment.Info.PaymentHash)
+		}
+	}
+
+	// None of the payments failed, so nothing is deleted if only failed
+	// payments are requested.
+	err = db.DeletePaymentsInRange(
+		time.Unix(150, 0), time.Unix(250, 0), true, false,
+	)
+	require.NoError(t, err)
+	assertPayments(hashes...)
+
+	err = db.DeletePaymentsInRange(
+		time.Unix(150, 0), time.Unix(250, 0), false, false,
+	)
+	require.NoError(t, err)
+	assertPayments(hashes[0], hashes[2])
+
+	// A zero end time leaves the range open.
+	err = db.DeletePaymentsInRange(
+		time.Unix(300, 0), time.Time{}, false, false,
+	)
+	require.NoError(t, err)
+	assertPayments(hashes[0])
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/lntypes"
@@ -101,6 +102,22 @@ var (
 	// 	|--...
 	// 	|--<sequence-number>: <payment hash>
 	paymentsIndexBucket = []byte("payments-index-bucket")
+
+	// paymentsCreationTimeIndexBucket is the name of the top-level bucket
+	// within the database that orders payments by their creation time.
+	// Legacy duplicate payments aren't included in this index.
+	// payments-creation-time-index
+	// 	|--<creation-time><sequence-number>: nil
+	// 	|--...
+	paymentsCreationTimeIndexBucket = []byte("payments-creation-time-index")
+
+	// paymentsSettleTimeIndexBucket is the name of the top-level bucket
+	// within the database that orders the succeeded payments by the time
+	// their first HTLC settled.
+	// payments-settle-time-index
+	// 	|--<settle-time><sequence-number>: nil
+	// 	|--...
+	paymentsSettleTimeIndexBucket = []byte("payments-settle-time-index")
 )
 
 var (
@@ -479,6 +496,27 @@ type PaymentsQuery struct {
 	// fully completed. This means that pending payments, as well as failed
 	// payments will show up if this field is set to true.
 	IncludeIncomplete bool
+
+	// CreationDateStart, if set, filters out the payments created before
+	// this time.
+	CreationDateStart time.Time
+
+	// CreationDateEnd, if set, filters out the payments created after this
+	// time.
+	CreationDateEnd time.Time
+
+	// SettleDateStart, if set, filters out the payments whose first HTLC
+	// didn't settle at or after this time.
+	SettleDateStart time.Time
+
+	// SettleDateEnd, if set, filters out the payments whose first HTLC
+	// didn't settle at or before this time.
+	SettleDateEnd time.Time
+
+	// Statuses, if non-empty, filters out the payments whose status isn't
+	// one of the given statuses. In that case IncludeIncomplete is
+	// ignored.
+	Statuses []PaymentStatus
 }
 
 // PaymentsResponse contains the result of a query to the payments database.
@@ -547,22 +585,48 @@ func (db *DB) QueryPayments(query PaymentsQuery) (PaymentsResponse, error) {
 
 			// To keep compatibility with the old API, we only
 			// return non-succeeded payments if requested.
-			if payment.Status != StatusSucceeded &&
+			if len(query.Statuses) == 0 &&
+				payment.Status != StatusSucceeded &&
 				!query.IncludeIncomplete {
 
 				return false, err
 			}
 
+			// Skip the payments that don't have one of the
+			// requested statuses.
+			if !payment.hasStatus(query.Statuses) {
+				return false, nil
+			}
+
 			// At this point, we've exhausted the offset, so we'll
 			// begin collecting invoices found within the range.
 			resp.Payments = append(resp.Payments, payment)
 			return true, nil
 		}
 
+		// If the query is restricted to a creation or settle time
+		// range, only the index entries of the payments within the
+		// ranges are read.
+		cursor := filterIndex(
+			indexes, timeRange{
+				timeIndex: tx.ReadBucket(
+					paymentsCreationTimeIndexBucket,
+				),
+				start: query.CreationDateStart,
+				end:   query.CreationDateEnd,
+			}, timeRange{
+				timeIndex: tx.ReadBucket(
+					paymentsSettleTimeIndexBucket,
+				),
+				start: query.SettleDateStart,
+				end:   query.SettleDateEnd,
+			},
+		)
+
 		// Create a paginator which reads from our sequence index bucket
 		// with the parameters provided by the payments query.
 		paginator := newPaginator(
-			indexes.ReadCursor(), query.Reversed, query.IndexOffset,
+			cursor, query.Reversed, query.IndexOffset,
 			query.MaxPayments,
 		)
 
@@ -672,8 +736,78 @@ func fetchPaymentWithSequenceNumber(tx kvdb.RTx, paymentHash lntypes.Hash,
 	return duplicatePayment, nil
 }
 
-// DeletePayments deletes all completed and failed payments from the DB.
-func (db *DB) DeletePayments() error {
+// DeletePayment deletes a payment from the DB given its payment hash. If
+// failedHtlcsOnly is set, only the failed HTLC attempts of the payment are
+// deleted. In-flight payments can't be deleted, as they are still tracked by
+// the router.
+func (db *DB) DeletePayment(paymentHash lntypes.Hash,
+	failedHtlcsOnly bool) error {
+
+	return kvdb.Update(db, func(tx kvdb.RwTx) error {
+		payments := tx.ReadWriteBucket(paymentsRootBucket)
+		if payments == nil {
+			return ErrPaymentNotInitiated
+		}
+
+		bucket := payments.NestedReadWriteBucket(paymentHash[:])
+		if bucket == nil {
+			return ErrPaymentNotInitiated
+		}
+
+		paymentStatus, err := fetchPaymentStatus(bucket)
+		if err != nil {
+			return err
+		}
+
+		if paymentStatus == StatusInFlight {
+			return ErrPaymentInFlight
+		}
+
+		if failedHtlcsOnly {
+			return deleteFailedHtlcs(bucket)
+		}
+
+		seqNrs, err := fetchSequenceNumbers(bucket)
+		if err != nil {
+			return err
+		}
+
+		if err := deletePaymentTimeIndexes(tx, bucket); err != nil {
+			return err
+		}
+
+		err = payments.DeleteNestedBucket(paymentHash[:])
+		if err != nil {
+			return err
+		}
+
+		indexBucket := tx.ReadWriteBucket(paymentsIndexBucket)
+		for _, k := range seqNrs {
+			if err := indexBucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeletePayments deletes all completed and failed payments from the DB. If
+// failedOnly is set, only failed payments are deleted. If failedHtlcsOnly is
+// set, the payments are kept and only their failed HTLC attempts are deleted.
+func (db *DB) DeletePayments(failedOnly, failedHtlcsOnly bool) error {
+	return db.DeletePaymentsInRange(
+		time.Time{}, time.Time{}, failedOnly, failedHtlcsOnly,
+	)
+}
+
+// DeletePaymentsInRange deletes the completed and failed payments that were
+// created in the given time range from the DB. A zero start or end time leaves
+// the range open on that side. The failedOnly and failedHtlcsOnly flags have
+// the same meaning as for DeletePayments.
+func (db *DB) DeletePaymentsInRange(startTime, endTime time.Time, failedOnly,
+	failedHtlcsOnly bool) error {
+
 	return kvdb.Update(db, func(tx kvdb.RwTx) error {
 		payments := tx.ReadWriteBucket(paymentsRootBucket)
 		if payments == nil {
@@ -688,6 +822,10 @@ func (db *DB) DeletePayments() error {
 			// deleteIndexes is the set of indexes pointing to these
 			// payments that need to be deleted.
 			deleteIndexes [][]byte
+
+			// deleteHtlcs is the set of payment buckets of which
+			// the failed htlcs need to be deleted.
+			deleteHtlcs [][]byte
 		)
 		err := payments.ForEach(func(k, _ []byte) error {
 			bucket := payments.NestedReadWriteBucket(k)
@@ -711,6 +849,35 @@ func (db *DB) DeletePayments() error {
 				return nil
 			}
 
+			// If we requested to only delete failed payments, we
+			// can return if this one is not.
+			if failedOnly && paymentStatus != StatusFailed {
+				return nil
+			}
+
+			// Skip the payments that weren't created in the
+			// requested time range.
+			if !startTime.IsZero() || !endTime.IsZero() {
+				creationInfo, err := fetchCreationInfo(bucket)
+				if err != nil {
+					return err
+				}
+
+				created := creationInfo.CreationTime
+				afterEnd := !endTime.IsZero() &&
+					created.After(endTime)
+				if created.Before(startTime) || afterEnd {
+					return nil
+				}
+			}
+
+			// If we are only deleting failed htlcs, the payment
+			// itself is kept.
+			if failedHtlcsOnly {
+				deleteHtlcs = append(deleteHtlcs, k)
+				return nil
+			}
+
 			// Add the bucket to the set of buckets we can delete.
 			deleteBuckets = append(deleteBuckets, k)
 
@@ -729,7 +896,20 @@ func (db *DB) DeletePayments() error {
 			return err
 		}
 
+		for _, k := range deleteHtlcs {
+			bucket := payments.NestedReadWriteBucket(k)
+			if err := deleteFailedHtlcs(bucket); err != nil {
+				return err
+			}
+		}
+
 		for _, k := range deleteBuckets {
+			bucket := payments.NestedReadBucket(k)
+			err := deletePaymentTimeIndexes(tx, bucket)
+			if err != nil {
+				return err
+			}
+
 			if err := payments.DeleteNestedBucket(k); err != nil {
 				return err
 			}
@@ -748,6 +928,70 @@ func (db *DB) DeletePayments() error {
 	})
 }
 
+// deletePaymentTimeIndexes deletes the entries of the payment stored in the
+// given bucket from the creation and settle time indexes.
+func deletePaymentTimeIndexes(tx kvdb.RwTx, bucket kvdb.RBucket) error {
+	payment, err := fetchPayment(bucket)
+	if err != nil {
+		return err
+	}
+
+	creationTimeIndex := tx.ReadWriteBucket(paymentsCreationTimeIndexBucket)
+	if creationTimeIndex != nil {
+		err := creationTimeIndex.Delete(timeIndexKey(
+			payment.Info.CreationTime, payment.SequenceNum,
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	settleTime := payment.settleTime()
+	settleTimeIndex := tx.ReadWriteBucket(paymentsSettleTimeIndexBucket)
+	if settleTime.IsZero() || settleTimeIndex == nil {
+		return nil
+	}
+
+	return settleTimeIndex.Delete(
+		timeIndexKey(settleTime, payment.SequenceNum),
+	)
+}
+
+// deleteFailedHtlcs deletes the failed htlc attempts of the payment stored in
+// the given bucket.
+func deleteFailedHtlcs(bucket kvdb.RwBucket) error {
+	htlcsBucket := bucket.NestedReadWriteBucket(paymentHtlcsBucket)
+	if htlcsBucket == nil {
+		return nil
+	}
+
+	var failedHtlcs [][]byte
+	err := htlcsBucket.ForEach(func(k, _ []byte) error {
+		htlcBucket := htlcsBucket.NestedReadBucket(k)
+		if htlcBucket == nil {
+			return fmt.Errorf("non bucket element in htlcs " +
+				"bucket")
+		}
+
+		if htlcBucket.Get(htlcFailInfoKey) != nil {
+			failedHtlcs = append(failedHtlcs, k)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range failedHtlcs {
+		if err := htlcsBucket.DeleteNestedBucket(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // fetchSequenceNumbers fetches all the sequence numbers associated with a
 // payment, including those belonging to any duplicate payments.
 func fetchSequenceNumbers(paymentBucket kvdb.RBucket) ([][]byte, error) {
@@ -852,7 +1096,49 @@ func serializeHTLCAttemptInfo(w io.Writer, a *HTLCAttemptInfo) error {
 		return err
 	}
 
-	return serializeTime(w, a.AttemptTime)
+	if err := serializeTime(w, a.AttemptTime); err != nil {
+		return err
+	}
+
+	// If the hash is nil we can just return.
+	if a.Hash == nil {
+		return nil
+	}
+
+	if _, err := w.Write(a.Hash[:]); err != nil {
+		return err
+	}
+
+	// The shard allocation is optional and follows the hash.
+	if a.ShardAllocation == nil {
+		return nil
+	}
+
+	return serializeShardAllocation(w, a.ShardAllocation)
+}
+
+func serializeShardAllocation(w io.Writer, s *ShardAllocation) error {
+	return WriteElements(w,
+		uint8(s.Strategy), s.SplitAmt, s.NumCandidates, s.SharePPM,
+		s.ProbabilityPPM,
+	)
+}
+
+func deserializeShardAllocation(r io.Reader) (*ShardAllocation, error) {
+	var (
+		s        ShardAllocation
+		strategy uint8
+	)
+	err := ReadElements(r,
+		&strategy, &s.SplitAmt, &s.NumCandidates, &s.SharePPM,
+		&s.ProbabilityPPM,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.Strategy = SplitStrategy(strategy)
+
+	return &s, nil
 }
 
 func deserializeHTLCAttemptInfo(r io.Reader) (*HTLCAttemptInfo, error) {
@@ -871,6 +1157,35 @@ func deserializeHTLCAttemptInfo(r io.Reader) (*HTLCAttemptInfo, error) {
 		return nil, err
 	}
 
+	hash := lntypes.Hash{}
+	_, err = io.ReadFull(r, hash[:])
+
+	switch {
+
+	// Older payment attempts wouldn't have the hash set, in which case we
+	// can just return.
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+		return a, nil
+
+	case err != nil:
+		return nil, err
+
+	default:
+	}
+
+	a.Hash = &hash
+
+	// Attempts that weren't created by splitting the payment don't have a
+	// shard allocation.
+	a.ShardAllocation, err = deserializeShardAllocation(r)
+	switch {
+	case err == io.EOF:
+		return a, nil
+
+	case err != nil:
+		return nil, err
+	}
+
 	return a, nil
 }
 
@@ -907,6 +1222,35 @@ func serializeHop(w io.Writer, h *route.Hop) error {
 		records = append(records, h.MPP.Record())
 	}
 
+	if h.AMP != nil {
+		records = append(records, h.AMP.Record())
+	}
+
+	if h.EncryptedData != nil {
+		records = append(records,
+			record.NewEncryptedDataRecord(&h.EncryptedData),
+		)
+	}
+
+	if h.BlindingPoint != nil {
+		records = append(records,
+			record.NewBlindingPointRecord(&h.BlindingPoint),
+		)
+	}
+
+	totalAmt := uint64(h.TotalAmtMAtoms)
+	if totalAmt != 0 {
+		records = append(records,
+			record.NewTotalAmtMAtomsBlindedRecord(&totalAmt),
+		)
+	}
+
+	if h.TrampolineOnion != nil {
+		records = append(records,
+			record.NewTrampolineOnionRecord(&h.TrampolineOnion),
+		)
+	}
+
 	// Final sanity check to absolutely rule out custom records that are not
 	// custom and write into the standard range.
 	if err := h.CustomRecords.Validate(); err != nil {
@@ -1021,6 +1365,74 @@ func deserializeHop(r io.Reader) (*route.Hop, error) {
 		h.MPP = mpp
 	}
 
+	// If the AMP type is present, remove it from the generic TLV map and
+	// parse it back into a proper AMP struct.
+	ampType := uint64(record.AMPOnionType)
+	if ampBytes, ok := tlvMap[ampType]; ok {
+		delete(tlvMap, ampType)
+
+		var (
+			amp    = &record.AMP{}
+			ampRec = amp.Record()
+			r      = bytes.NewReader(ampBytes)
+		)
+		err := ampRec.Decode(r, uint64(len(ampBytes)))
+		if err != nil {
+			return nil, err
+		}
+		h.AMP = amp
+	}
+
+	// If any of the blinded route types are present, remove them from the
+	// generic TLV map and parse them back into the hop.
+	encryptedDataType := uint64(record.EncryptedDataOnionType)
+	if dataBytes, ok := tlvMap[encryptedDataType]; ok {
+		delete(tlvMap, encryptedDataType)
+
+		h.EncryptedData = dataBytes
+	}
+
+	blindingPointType := uint64(record.BlindingPointOnionType)
+	if pointBytes, ok := tlvMap[blindingPointType]; ok {
+		delete(tlvMap, blindingPointType)
+
+		var (
+			point    *secp256k1.PublicKey
+			pointRec = record.NewBlindingPointRecord(&point)
+			r        = bytes.NewReader(pointBytes)
+		)
+		err := pointRec.Decode(r, uint64(len(pointBytes)))
+		if err != nil {
+			return nil, err
+		}
+		h.BlindingPoint = point
+	}
+
+	totalAmtType := uint64(record.TotalAmtMAtomsBlindedType)
+	if amtBytes, ok := tlvMap[totalAmtType]; ok {
+		delete(tlvMap, totalAmtType)
+
+		var (
+			totalAmt    uint64
+			totalAmtRec = record.NewTotalAmtMAtomsBlindedRecord(
+				&totalAmt,
+			)
+			r = bytes.NewReader(amtBytes)
+		)
+		err := totalAmtRec.Decode(r, uint64(len(amtBytes)))
+		if err != nil {
+			return nil, err
+		}
+		h.TotalAmtMAtoms = lnwire.MilliAtom(totalAmt)
+	}
+
+	trampolineType := uint64(record.TrampolineOnionType)
+	if onionBytes, ok := tlvMap[trampolineType]; ok {
+		delete(tlvMap, trampolineType)
+
+		h.TrampolineOnion = onionBytes
+	}
+
 	h.CustomRecords = tlvMap
 
 	return h, nil
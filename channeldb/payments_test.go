@@ -42,6 +42,19 @@ var (
 		LegacyPayload:    true,
 	}
 
+	testHop3 = &route.Hop{
+		PubKeyBytes:      route.NewVertex(pub),
+		ChannelID:        12345,
+		OutgoingTimeLock: 111,
+		AmtToForward:     555,
+		CustomRecords: record.CustomSet{
+			65536: []byte{},
+			80001: []byte{},
+		},
+		MPP: record.NewMPP(32, [32]byte{0x42}),
+		AMP: record.NewAMP([32]byte{0x69}, [32]byte{0x42}, 1),
+	}
+
 	testRoute = route.Route{
 		TotalTimeLock: 123,
 		TotalAmount:   1234567,
@@ -57,8 +70,10 @@ func makeFakeInfo() (*PaymentCreationInfo, *HTLCAttemptInfo) {
 	var preimg lntypes.Preimage
 	copy(preimg[:], rev[:])
 
+	hash := preimg.Hash()
+
 	c := &PaymentCreationInfo{
-		PaymentHash: preimg.Hash(),
+		PaymentHash: hash,
 		Value:       1000,
 		// Use single second precision to avoid false positive test
 		// failures due to the monotonic time component.
@@ -71,6 +86,7 @@ func makeFakeInfo() (*PaymentCreationInfo, *HTLCAttemptInfo) {
 		SessionKey:  priv,
 		Route:       testRoute,
 		AttemptTime: time.Unix(100, 0),
+		Hash:        &hash,
 	}
 	return c, a
 }
@@ -162,6 +178,144 @@ func TestRouteSerialization(t *testing.T) {
 	}
 }
 
+// TestAMPRouteSerialization asserts that a route whose final hop carries an
+// AMP record is properly serialized and deserialized.
+func TestAMPRouteSerialization(t *testing.T) {
+	t.Parallel()
+
+	ampRoute := route.Route{
+		TotalTimeLock: 123,
+		TotalAmount:   1234567,
+		SourcePubKey:  route.NewVertex(pub),
+		Hops: []*route.Hop{
+			testHop2,
+			testHop3,
+		},
+	}
+
+	var b bytes.Buffer
+	err := SerializeRoute(&b, ampRoute)
+	require.NoError(t, err)
+
+	r := bytes.NewReader(b.Bytes())
+	route2, err := DeserializeRoute(r)
+	require.NoError(t, err)
+
+	require.Equal(t, ampRoute, route2)
+}
+
+// TestBlindedRouteSerialization asserts that a route ending in a blinded route
+// is properly serialized and deserialized.
+func TestBlindedRouteSerialization(t *testing.T) {
+	t.Parallel()
+
+	blindingPoint, err := secp256k1.ParsePubKey(pub.SerializeCompressed())
+	require.NoError(t, err)
+
+	blindedRoute := route.Route{
+		TotalTimeLock: 123,
+		TotalAmount:   1234567,
+		SourcePubKey:  route.NewVertex(pub),
+		Hops: []*route.Hop{
+			testHop2,
+			{
+				PubKeyBytes:   route.NewVertex(pub),
+				ChannelID:     12345,
+				AmtToForward:  555,
+				EncryptedData: []byte{1, 2, 3},
+				BlindingPoint: blindingPoint,
+				CustomRecords: record.CustomSet{},
+			},
+			{
+				PubKeyBytes:      route.NewVertex(pub),
+				ChannelID:        1,
+				OutgoingTimeLock: 111,
+				AmtToForward:     555,
+				EncryptedData:    []byte{4, 5, 6},
+				TotalAmtMAtoms:   555,
+				CustomRecords: record.CustomSet{
+					65536: []byte{},
+				},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	err = SerializeRoute(&b, blindedRoute)
+	require.NoError(t, err)
+
+	r := bytes.NewReader(b.Bytes())
+	route2, err := DeserializeRoute(r)
+	require.NoError(t, err)
+
+	require.Equal(t, blindedRoute, route2)
+}
+
+// TestTrampolineRouteSerialization asserts that a route to a trampoline node
+// is properly serialized and deserialized.
+func TestTrampolineRouteSerialization(t *testing.T) {
+	t.Parallel()
+
+	trampolineRoute := route.Route{
+		TotalTimeLock: 123,
+		TotalAmount:   1234567,
+		SourcePubKey:  route.NewVertex(pub),
+		Hops: []*route.Hop{
+			testHop2,
+			{
+				PubKeyBytes:      route.NewVertex(pub),
+				ChannelID:        12345,
+				OutgoingTimeLock: 111,
+				AmtToForward:     555,
+				TrampolineOnion:  []byte{1, 2, 3},
+				CustomRecords:    record.CustomSet{},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	err := SerializeRoute(&b, trampolineRoute)
+	require.NoError(t, err)
+
+	r := bytes.NewReader(b.Bytes())
+	route2, err := DeserializeRoute(r)
+	require.NoError(t, err)
+
+	require.Equal(t, trampolineRoute, route2)
+}
+
+// TestShardAllocationSerialization asserts that the shard allocation of an
+// htlc attempt survives serialization, and that attempts without one are still
+// deserialized correctly.
+func TestShardAllocationSerialization(t *testing.T) {
+	t.Parallel()
+
+	_, attempt := makeFakeInfo()
+	attempt.Route = route.Route{}
+	attempt.ShardAllocation = &ShardAllocation{
+		Strategy:       SplitStrategyFeeAware,
+		SplitAmt:       500000,
+		NumCandidates:  3,
+		SharePPM:       600000,
+		ProbabilityPPM: 750000,
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, serializeHTLCAttemptInfo(&b, attempt))
+
+	attempt2, err := deserializeHTLCAttemptInfo(&b)
+	require.NoError(t, err)
+	require.Equal(t, attempt.ShardAllocation, attempt2.ShardAllocation)
+
+	attempt.ShardAllocation = nil
+	b.Reset()
+	require.NoError(t, serializeHTLCAttemptInfo(&b, attempt))
+
+	attempt2, err = deserializeHTLCAttemptInfo(&b)
+	require.NoError(t, err)
+	require.Nil(t, attempt2.ShardAllocation)
+}
+
 // deletePayment removes a payment with paymentHash from the payments database.
 func deletePayment(t *testing.T, db *DB, paymentHash lntypes.Hash, seqNr uint64) {
 	t.Helper()
@@ -504,6 +658,137 @@ func TestQueryPayments(t *testing.T) {
 	}
 }
 
+// TestQueryPaymentsFilters tests that payments can be queried by their
+// creation time, settle time and status, and that the time indexes are kept
+// up to date when payments are retried or deleted.
+func TestQueryPaymentsFilters(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := MakeTestDB()
+	defer cleanup()
+	require.NoError(t, err)
+
+	pControl := NewPaymentControl(db)
+
+	// Create three payments, of which the first and last succeed and the
+	// second one fails.
+	var infos []*PaymentCreationInfo
+	for i, created := range []int64{100, 200, 300} {
+		info, attempt, preimg, err := genInfo()
+		require.NoError(t, err)
+		info.CreationTime = time.Unix(created, 0)
+
+		require.NoError(t, pControl.InitPayment(info.PaymentHash, info))
+		infos = append(infos, info)
+
+		if i == 1 {
+			_, err := pControl.Fail(
+				info.PaymentHash, FailureReasonNoRoute,
+			)
+			require.NoError(t, err)
+			continue
+		}
+
+		_, err = pControl.RegisterAttempt(info.PaymentHash, attempt)
+		require.NoError(t, err)
+		_, err = pControl.SettleAttempt(
+			info.PaymentHash, attempt.AttemptID,
+			&HTLCSettleInfo{
+				Preimage:   preimg,
+				SettleTime: time.Unix(created*10, 0),
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	assertQuery := func(query PaymentsQuery, expected ...int) {
+		t.Helper()
+
+		query.MaxPayments = math.MaxUint64
+		query.IncludeIncomplete = true
+		resp, err := db.QueryPayments(query)
+		require.NoError(t, err)
+		require.Len(t, resp.Payments, len(expected))
+
+		for i, payment := range resp.Payments {
+			require.Equal(
+				t, infos[expected[i]].PaymentHash,
+				payment.Info.PaymentHash,
+			)
+		}
+	}
+
+	// Both ends of the creation time range are inclusive.
+	assertQuery(PaymentsQuery{
+		CreationDateStart: time.Unix(150, 0),
+		CreationDateEnd:   time.Unix(300, 0),
+	}, 1, 2)
+	assertQuery(PaymentsQuery{
+		CreationDateEnd: time.Unix(200, 0),
+	}, 0, 1)
+
+	// The failed payment has no settle time.
+	assertQuery(PaymentsQuery{
+		SettleDateStart: time.Unix(1000, 0),
+	}, 0, 2)
+	assertQuery(PaymentsQuery{
+		CreationDateStart: time.Unix(200, 0),
+		SettleDateStart:   time.Unix(1000, 0),
+	}, 2)
+
+	assertQuery(PaymentsQuery{
+		Statuses: []PaymentStatus{StatusFailed},
+	}, 1)
+	assertQuery(PaymentsQuery{
+		CreationDateEnd: time.Unix(200, 0),
+		Statuses:        []PaymentStatus{StatusSucceeded},
+	}, 0)
+
+	// Pagination applies to the filtered payments.
+	assertQuery(PaymentsQuery{
+		IndexOffset:     1,
+		SettleDateStart: time.Unix(1000, 0),
+	}, 2)
+	assertQuery(PaymentsQuery{
+		IndexOffset:     3,
+		Reversed:        true,
+		SettleDateStart: time.Unix(1000, 0),
+	}, 0)
+
+	// Retrying the failed payment moves it in the creation time index.
+	infos[1].CreationTime = time.Unix(400, 0)
+	require.NoError(t, pControl.InitPayment(infos[1].PaymentHash, infos[1]))
+	assertQuery(PaymentsQuery{
+		CreationDateStart: time.Unix(150, 0),
+		CreationDateEnd:   time.Unix(300, 0),
+	}, 2)
+	assertQuery(PaymentsQuery{
+		CreationDateStart: time.Unix(350, 0),
+	}, 1)
+
+	// Deleted payments are removed from the time indexes.
+	require.NoError(t, db.DeletePayment(infos[2].PaymentHash, false))
+	assertQuery(PaymentsQuery{
+		SettleDateStart: time.Unix(1000, 0),
+	}, 0)
+
+	_, err = pControl.Fail(infos[1].PaymentHash, FailureReasonNoRoute)
+	require.NoError(t, err)
+	require.NoError(t, db.DeletePayments(false, false))
+	err = kvdb.View(db, func(tx kvdb.RTx) error {
+		for _, bucket := range [][]byte{
+			paymentsCreationTimeIndexBucket,
+			paymentsSettleTimeIndexBucket,
+		} {
+			k, _ := tx.ReadBucket(bucket).ReadCursor().First()
+			require.Nil(t, k)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 // TestFetchPaymentWithSequenceNumber tests lookup of payments with their
 // sequence number. It sets up one payment with no duplicates, and another with
 // two duplicates in its duplicates bucket then uses these payments to test the
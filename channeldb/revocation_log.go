@@ -0,0 +1,313 @@
+package channeldb
+
+import (
+	"bytes"
+	"io"
+	"math"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+// OutputIndexEmpty is used as the output index of a commitment output that
+// doesn't exist, because its amount is below the dust limit.
+const OutputIndexEmpty = math.MaxUint16
+
+var (
+	// revocationLogBucket is dedicated for storing the compact revocation
+	// log of a channel, which holds for each revoked state of the remote
+	// commitment chain the data needed to punish a counterparty attempting
+	// to broadcast that state. This key should be accessed from within
+	// the sub-bucket of a target channel, identified by its channel point.
+	revocationLogBucket = []byte("revocation-log")
+
+	// revocationLogBucketDeprecated is the bucket of the former revocation
+	// log format, which stored the full commitment of each revoked state.
+	// It is still read for the states that weren't converted to the
+	// compact format by the revocation log migration.
+	revocationLogBucketDeprecated = []byte("revocation-log-key")
+)
+
+const (
+	// Types of the records of a revocation log entry.
+	revLogOurOutputIndexType   tlv.Type = 0
+	revLogTheirOutputIndexType tlv.Type = 1
+	revLogCommitTxHashType     tlv.Type = 2
+	revLogOurBalanceType       tlv.Type = 3
+	revLogTheirBalanceType     tlv.Type = 4
+
+	// Types of the records of an HTLC entry.
+	htlcEntryRHashType         tlv.Type = 0
+	htlcEntryRefundTimeoutType tlv.Type = 1
+	htlcEntryOutputIndexType   tlv.Type = 2
+	htlcEntryIncomingType      tlv.Type = 3
+	htlcEntryAmtType           tlv.Type = 4
+)
+
+// HTLCEntry specifies the minimal info needed to be stored on disk for an
+// HTLC output of a revoked commitment, which is enough to reconstruct its
+// script and sweep it if the commitment is broadcast.
+type HTLCEntry struct {
+	// RHash is the payment hash of the HTLC.
+	RHash [32]byte
+
+	// RefundTimeout is the absolute timeout on the HTLC that the sender
+	// must wait before reclaiming the funds in limbo.
+	RefundTimeout uint32
+
+	// OutputIndex is the output index of the HTLC on the revoked
+	// commitment transaction.
+	OutputIndex uint16
+
+	// Incoming denotes whether we're the receiver or the sender of this
+	// HTLC.
+	Incoming bool
+
+	// Amt is the amount of the HTLC output.
+	Amt dcrutil.Amount
+}
+
+// RevocationLog stores the info needed to punish a counterparty that
+// broadcasts a revoked commitment. Unlike the full ChannelCommitment stored
+// by the former revocation log format, it doesn't hold the commitment
+// transaction, the signatures and the onion blobs of the HTLCs, which take up
+// most of the space of a busy channel's revocation log.
+type RevocationLog struct {
+	// OurOutputIndex specifies our output index in the revoked commitment,
+	// or OutputIndexEmpty if there is no such output.
+	OurOutputIndex uint16
+
+	// TheirOutputIndex specifies their output index in the revoked
+	// commitment, or OutputIndexEmpty if there is no such output.
+	TheirOutputIndex uint16
+
+	// CommitTxHash is the hash of the revoked commitment transaction.
+	CommitTxHash chainhash.Hash
+
+	// OurBalance is our balance on the revoked commitment.
+	OurBalance lnwire.MilliAtom
+
+	// TheirBalance is their balance on the revoked commitment.
+	TheirBalance lnwire.MilliAtom
+
+	// HTLCEntries is the set of HTLC outputs of the revoked commitment.
+	// HTLCs below the dust limit don't have an output and are omitted.
+	HTLCEntries []*HTLCEntry
+}
+
+// NewRevocationLog creates the revocation log entry of the given revoked
+// commitment, along with the indexes of our and their output on it.
+func NewRevocationLog(commit *ChannelCommitment, ourOutputIndex,
+	theirOutputIndex uint16) *RevocationLog {
+
+	rl := &RevocationLog{
+		OurOutputIndex:   ourOutputIndex,
+		TheirOutputIndex: theirOutputIndex,
+		CommitTxHash:     commit.CommitTx.TxHash(),
+		OurBalance:       commit.LocalBalance,
+		TheirBalance:     commit.RemoteBalance,
+	}
+
+	for _, htlc := range commit.Htlcs {
+		// Dust HTLCs don't have an output that could be swept.
+		if htlc.OutputIndex < 0 {
+			continue
+		}
+
+		rl.HTLCEntries = append(rl.HTLCEntries, &HTLCEntry{
+			RHash:         htlc.RHash,
+			RefundTimeout: htlc.RefundTimeout,
+			OutputIndex:   uint16(htlc.OutputIndex),
+			Incoming:      htlc.Incoming,
+			Amt:           htlc.Amt.ToAtoms(),
+		})
+	}
+
+	return rl
+}
+
+// putRevocationLog adds the revocation log entry of the given revoked
+// commitment to the log bucket.
+func putRevocationLog(log kvdb.RwBucket, commit *ChannelCommitment,
+	ourOutputIndex, theirOutputIndex uint16) error {
+
+	rl := NewRevocationLog(commit, ourOutputIndex, theirOutputIndex)
+
+	var b bytes.Buffer
+	if err := serializeRevocationLog(&b, rl); err != nil {
+		return err
+	}
+
+	logEntrykey := makeLogKey(commit.CommitHeight)
+	return log.Put(logEntrykey[:], b.Bytes())
+}
+
+// fetchRevocationLog queries the revocation log bucket to find the log entry
+// of the given commit height.
+func fetchRevocationLog(log kvdb.RBucket,
+	updateNum uint64) (*RevocationLog, error) {
+
+	logEntrykey := makeLogKey(updateNum)
+	commitBytes := log.Get(logEntrykey[:])
+	if commitBytes == nil {
+		return nil, errLogEntryNotFound
+	}
+
+	return deserializeRevocationLog(bytes.NewReader(commitBytes))
+}
+
+// serializeRevocationLog serializes a revocation log entry as a TLV stream,
+// followed by the TLV streams of its HTLC entries. Each stream is prefixed
+// with its length.
+func serializeRevocationLog(w io.Writer, rl *RevocationLog) error {
+	ourBalance := uint64(rl.OurBalance)
+	theirBalance := uint64(rl.TheirBalance)
+	commitTxHash := [32]byte(rl.CommitTxHash)
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(
+			revLogOurOutputIndexType, &rl.OurOutputIndex,
+		),
+		tlv.MakePrimitiveRecord(
+			revLogTheirOutputIndexType, &rl.TheirOutputIndex,
+		),
+		tlv.MakePrimitiveRecord(revLogCommitTxHashType, &commitTxHash),
+		tlv.MakePrimitiveRecord(revLogOurBalanceType, &ourBalance),
+		tlv.MakePrimitiveRecord(revLogTheirBalanceType, &theirBalance),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := writeTlvStream(w, tlvStream); err != nil {
+		return err
+	}
+
+	for _, htlc := range rl.HTLCEntries {
+		var incoming uint8
+		if htlc.Incoming {
+			incoming = 1
+		}
+		amt := uint64(htlc.Amt)
+
+		tlvStream, err := tlv.NewStream(
+			htlcEntryRecords(htlc, &incoming, &amt)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := writeTlvStream(w, tlvStream); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTlvStream writes the TLV stream prefixed with its length, so it can be
+// followed by other data.
+func writeTlvStream(w io.Writer, tlvStream *tlv.Stream) error {
+	var b bytes.Buffer
+	if err := tlvStream.Encode(&b); err != nil {
+		return err
+	}
+
+	var scratch [8]byte
+	if err := tlv.WriteVarInt(w, uint64(b.Len()), &scratch); err != nil {
+		return err
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// readTlvStream reads a TLV stream written by writeTlvStream.
+func readTlvStream(r io.Reader, tlvStream *tlv.Stream) error {
+	var scratch [8]byte
+	length, err := tlv.ReadVarInt(r, &scratch)
+	if err != nil {
+		return err
+	}
+
+	return tlvStream.Decode(io.LimitReader(r, int64(length)))
+}
+
+// deserializeRevocationLog deserializes a revocation log entry serialized by
+// serializeRevocationLog.
+func deserializeRevocationLog(r *bytes.Reader) (*RevocationLog, error) {
+	var (
+		rl                       RevocationLog
+		ourBalance, theirBalance uint64
+		commitTxHash             [32]byte
+	)
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(
+			revLogOurOutputIndexType, &rl.OurOutputIndex,
+		),
+		tlv.MakePrimitiveRecord(
+			revLogTheirOutputIndexType, &rl.TheirOutputIndex,
+		),
+		tlv.MakePrimitiveRecord(revLogCommitTxHashType, &commitTxHash),
+		tlv.MakePrimitiveRecord(revLogOurBalanceType, &ourBalance),
+		tlv.MakePrimitiveRecord(revLogTheirBalanceType, &theirBalance),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readTlvStream(r, tlvStream); err != nil {
+		return nil, err
+	}
+
+	rl.CommitTxHash = commitTxHash
+	rl.OurBalance = lnwire.MilliAtom(ourBalance)
+	rl.TheirBalance = lnwire.MilliAtom(theirBalance)
+
+	for r.Len() > 0 {
+		var (
+			htlc     HTLCEntry
+			incoming uint8
+			amt      uint64
+		)
+
+		tlvStream, err := tlv.NewStream(
+			htlcEntryRecords(&htlc, &incoming, &amt)...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := readTlvStream(r, tlvStream); err != nil {
+			return nil, err
+		}
+
+		htlc.Incoming = incoming == 1
+		htlc.Amt = dcrutil.Amount(amt)
+		rl.HTLCEntries = append(rl.HTLCEntries, &htlc)
+	}
+
+	return &rl, nil
+}
+
+// htlcEntryRecords returns the TLV records of an HTLC entry, using the given
+// values as the storage of the fields that aren't of a primitive TLV type.
+func htlcEntryRecords(htlc *HTLCEntry, incoming *uint8,
+	amt *uint64) []tlv.Record {
+
+	return []tlv.Record{
+		tlv.MakePrimitiveRecord(htlcEntryRHashType, &htlc.RHash),
+		tlv.MakePrimitiveRecord(
+			htlcEntryRefundTimeoutType, &htlc.RefundTimeout,
+		),
+		tlv.MakePrimitiveRecord(
+			htlcEntryOutputIndexType, &htlc.OutputIndex,
+		),
+		tlv.MakePrimitiveRecord(htlcEntryIncomingType, incoming),
+		tlv.MakePrimitiveRecord(htlcEntryAmtType, amt),
+	}
+}
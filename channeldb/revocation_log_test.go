@@ -0,0 +1,230 @@
+package channeldb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRevocationLogSerialization asserts that a revocation log entry survives
+// a serialization round trip, and that dust HTLCs are omitted from it.
+func TestRevocationLogSerialization(t *testing.T) {
+	t.Parallel()
+
+	commit := &ChannelCommitment{
+		CommitHeight:  42,
+		LocalBalance:  lnwire.MilliAtom(1000000),
+		RemoteBalance: lnwire.MilliAtom(2000000),
+		CommitTx:      wire.NewMsgTx(),
+		Htlcs: []HTLC{
+			{
+				RHash:         [32]byte{1},
+				Amt:           lnwire.MilliAtom(50000000),
+				RefundTimeout: 100,
+				OutputIndex:   2,
+				Incoming:      true,
+			},
+			{
+				RHash:         [32]byte{2},
+				Amt:           lnwire.MilliAtom(1000),
+				RefundTimeout: 101,
+				OutputIndex:   -1,
+			},
+			{
+				RHash:         [32]byte{3},
+				Amt:           lnwire.MilliAtom(60000000),
+				RefundTimeout: 102,
+				OutputIndex:   3,
+			},
+		},
+	}
+
+	rl := NewRevocationLog(commit, OutputIndexEmpty, 1)
+	require.Equal(t, commit.CommitTx.TxHash(), rl.CommitTxHash)
+	require.Len(t, rl.HTLCEntries, 2)
+	require.Equal(t, &HTLCEntry{
+		RHash:         [32]byte{1},
+		RefundTimeout: 100,
+		OutputIndex:   2,
+		Incoming:      true,
+		Amt:           50000,
+	}, rl.HTLCEntries[0])
+
+	var b bytes.Buffer
+	require.NoError(t, serializeRevocationLog(&b, rl))
+
+	decoded, err := deserializeRevocationLog(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, rl, decoded)
+
+	// An entry without any HTLCs must round trip as well.
+	rl.HTLCEntries = nil
+	b.Reset()
+	require.NoError(t, serializeRevocationLog(&b, rl))
+
+	decoded, err = deserializeRevocationLog(bytes.NewReader(b.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, rl, decoded)
+}
+
+// TestFindPreviousStateLegacy asserts that states stored in the former
+// revocation log format are still found, and that the compact format takes
+// precedence over it.
+func TestFindPreviousStateLegacy(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	channel := createTestChannel(
+		t, cdb, openChannelOption(),
+		channelCommitmentOption(3, 0, 0, false),
+		channelCommitmentOption(3, 0, 0, true),
+	)
+
+	commits := make([]ChannelCommitment, 3)
+	for i := range commits {
+		commits[i] = channel.RemoteCommitment
+		commits[i].CommitHeight = uint64(i)
+		commits[i].LocalBalance = lnwire.MilliAtom(i * 1000)
+	}
+
+	err = kvdb.Update(cdb, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, channel.IdentityPub, &channel.FundingOutpoint,
+			channel.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		legacyBucket, err := chanBucket.CreateBucket(
+			revocationLogBucketDeprecated,
+		)
+		if err != nil {
+			return err
+		}
+		logBucket, err := chanBucket.CreateBucket(revocationLogBucket)
+		if err != nil {
+			return err
+		}
+
+		// Heights 0 and 1 are stored in the former format, while 1
+		// and 2 are stored in the compact format.
+		for _, commit := range commits[:2] {
+			commit := commit
+			err := appendChannelLogEntry(legacyBucket, &commit)
+			if err != nil {
+				return err
+			}
+		}
+		for _, commit := range commits[1:] {
+			commit := commit
+			err := putRevocationLog(logBucket, &commit, 0, 1)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	rl, commit, err := channel.FindPreviousState(0)
+	require.NoError(t, err)
+	require.Nil(t, rl)
+	assertCommitmentEqual(t, &commits[0], commit)
+
+	for _, height := range []uint64{1, 2} {
+		rl, commit, err := channel.FindPreviousState(height)
+		require.NoError(t, err)
+		require.Nil(t, commit)
+		require.Equal(t, NewRevocationLog(&commits[height], 0, 1), rl)
+	}
+
+	_, _, err = channel.FindPreviousState(3)
+	require.Equal(t, errLogEntryNotFound, err)
+
+	// Both formats are consulted when looking up past balances.
+	local, _, err := channel.BalancesAtHeight(0)
+	require.NoError(t, err)
+	require.Equal(t, commits[0].LocalBalance, local)
+
+	local, _, err = channel.BalancesAtHeight(2)
+	require.NoError(t, err)
+	require.Equal(t, commits[2].LocalBalance, local)
+}
+
+// TestPruneRevocationLog asserts that the entries of the former revocation log
+// are converted to the compact format when opening the database with the
+// option to prune the revocation log.
+func TestPruneRevocationLog(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	channel := createTestChannel(
+		t, cdb, openChannelOption(),
+		channelCommitmentOption(1, 0, 0, false),
+	)
+
+	// The outputs of the revoked commitment are located by their amount,
+	// skipping the output of the HTLC.
+	commit := channel.RemoteCommitment
+	commit.CommitHeight = 0
+	commit.LocalBalance = lnwire.MilliAtom(5000000)
+	commit.RemoteBalance = lnwire.MilliAtom(7000000)
+	commit.CommitTx = wire.NewMsgTx()
+	for _, value := range []int64{7000, 5000, 3000} {
+		commit.CommitTx.AddTxOut(wire.NewTxOut(value, []byte{0x01}))
+	}
+	commit.Htlcs = []HTLC{{
+		Signature:     []byte{0x02},
+		RHash:         [32]byte{1},
+		Amt:           lnwire.MilliAtom(3000000),
+		RefundTimeout: 100,
+		OutputIndex:   2,
+		OnionBlob:     []byte{0x03},
+	}}
+
+	err = kvdb.Update(cdb, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, channel.IdentityPub, &channel.FundingOutpoint,
+			channel.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		legacyBucket, err := chanBucket.CreateBucket(
+			revocationLogBucketDeprecated,
+		)
+		if err != nil {
+			return err
+		}
+
+		return appendChannelLogEntry(legacyBucket, &commit)
+	})
+	require.NoError(t, err)
+
+	prunedDB, err := CreateWithBackend(
+		cdb.Backend, OptionPruneRevocationLog(true),
+	)
+	require.NoError(t, err)
+
+	channels, err := prunedDB.FetchAllChannels()
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+
+	rl, legacyCommit, err := channels[0].FindPreviousState(0)
+	require.NoError(t, err)
+	require.Nil(t, legacyCommit)
+	require.Equal(t, NewRevocationLog(&commit, 1, 0), rl)
+}
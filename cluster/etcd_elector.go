@@ -0,0 +1,113 @@
+// +build kvdb_etcd
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb/etcd"
+	"github.com/matheusd/etcd/clientv3"
+	"github.com/matheusd/etcd/clientv3/concurrency"
+)
+
+const (
+	// etcdResignTimeout is the maximum time resigning from the leader
+	// role may take.
+	etcdResignTimeout = 10 * time.Second
+)
+
+// etcdLeaderElector is an implementation of LeaderElector using etcd as the
+// election governor. The leadership is bound to a lease, which is kept alive
+// as long as the elector is connected to etcd, so other members of the
+// election take over once the leader fails.
+type etcdLeaderElector struct {
+	id       string
+	cli      *clientv3.Client
+	session  *concurrency.Session
+	election *concurrency.Election
+}
+
+// A compile time check to ensure etcdLeaderElector implements the
+// LeaderElector interface.
+var _ LeaderElector = (*etcdLeaderElector)(nil)
+
+// newEtcdLeaderElector constructs a new etcdLeaderElector, which campaigns
+// under the passed id for the election with the passed prefix. The session
+// TTL is the number of seconds after which the leadership is lost if the
+// leader can't reach etcd.
+func newEtcdLeaderElector(ctx context.Context, id, electionPrefix string,
+	sessionTTL int, cfg etcd.BackendConfig) (*etcdLeaderElector, error) {
+
+	cfg.Ctx = ctx
+	cli, err := etcd.NewClient(cfg)
+	if err != nil {
+		log.Errorf("Unable to connect to etcd: %v", err)
+		return nil, err
+	}
+
+	// Create a session to keep the lease of the leadership alive.
+	session, err := concurrency.NewSession(
+		cli, concurrency.WithTTL(sessionTTL),
+	)
+	if err != nil {
+		log.Errorf("Unable to start new leader election session: %v",
+			err)
+		cli.Close()
+		return nil, err
+	}
+
+	return &etcdLeaderElector{
+		id:       id,
+		cli:      cli,
+		session:  session,
+		election: concurrency.NewElection(session, electionPrefix),
+	}, nil
+}
+
+// Leader returns the leader value for the current election, or an empty
+// string if there's no leader.
+func (e *etcdLeaderElector) Leader(ctx context.Context) (string, error) {
+	resp, err := e.election.Leader(ctx)
+	switch {
+	case err == concurrency.ErrElectionNoLeader:
+		return "", nil
+
+	case err != nil:
+		return "", err
+	}
+
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Campaign will start a new leadership campaign. Campaign will block until
+// the member is elected or the context is canceled.
+func (e *etcdLeaderElector) Campaign(ctx context.Context) error {
+	return e.election.Campaign(ctx, e.id)
+}
+
+// Resign resigns the leader role allowing other election members to take on
+// leadership. It also revokes the lease of the session and closes the
+// connection to etcd, so the elector can't be used anymore.
+func (e *etcdLeaderElector) Resign() error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), etcdResignTimeout,
+	)
+	defer cancel()
+
+	err := e.election.Resign(ctx)
+	if closeErr := e.session.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := e.cli.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Done returns a channel that is closed once the lease of the session expired
+// or can't be kept alive anymore, after which the leadership is lost.
+func (e *etcdLeaderElector) Done() <-chan struct{} {
+	return e.session.Done()
+}
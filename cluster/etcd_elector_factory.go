@@ -0,0 +1,67 @@
+// +build kvdb_etcd
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/channeldb/kvdb/etcd"
+)
+
+// makeEtcdElector will construct a new etcdLeaderElector. It expects the id,
+// the election prefix, the session TTL and the etcd config of the database to
+// be passed in that order.
+func makeEtcdElector(ctx context.Context, args ...interface{}) (LeaderElector,
+	error) {
+
+	if len(args) != 4 {
+		return nil, fmt.Errorf("invalid number of arguments to "+
+			"cluster.makeEtcdElector(): expected 4, got %v",
+			len(args))
+	}
+
+	id, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument (0) to " +
+			"cluster.makeEtcdElector(), expected: string")
+	}
+
+	electionPrefix, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument (1) to " +
+			"cluster.makeEtcdElector(), expected: string")
+	}
+
+	sessionTTL, ok := args[2].(int)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument (2) to " +
+			"cluster.makeEtcdElector(), expected: int")
+	}
+
+	etcdCfg, ok := args[3].(*kvdb.EtcdConfig)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument (3) to " +
+			"cluster.makeEtcdElector(), expected: *kvdb.EtcdConfig")
+	}
+
+	// Config translation is needed here in order to keep the etcd
+	// package fully independent from the rest of the source tree.
+	backendCfg := etcd.BackendConfig{
+		Host:               etcdCfg.Host,
+		User:               etcdCfg.User,
+		Pass:               etcdCfg.Pass,
+		CertFile:           etcdCfg.CertFile,
+		KeyFile:            etcdCfg.KeyFile,
+		InsecureSkipVerify: etcdCfg.InsecureSkipVerify,
+	}
+
+	return newEtcdLeaderElector(
+		ctx, id, electionPrefix, sessionTTL, backendCfg,
+	)
+}
+
+func init() {
+	RegisterLeaderElectorFactory(EtcdLeaderElector, makeEtcdElector)
+}
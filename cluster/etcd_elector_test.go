@@ -0,0 +1,204 @@
+// +build kvdb_etcd
+
+package cluster
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb/etcd"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testElectionPrefix = "/election/"
+	testSessionTTL     = 2
+	testTimeout        = 10 * time.Second
+)
+
+// newTestEtcd starts an embedded etcd instance for testing, returning its
+// config and a cleanup func.
+func newTestEtcd(t *testing.T) (*etcd.BackendConfig, func()) {
+	tmpDir, err := ioutil.TempDir("", "etcd")
+	require.NoError(t, err)
+
+	config, cleanup, err := etcd.NewEmbeddedEtcdInstance(tmpDir)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatalf("unable to start etcd: %v", err)
+	}
+
+	return config, func() {
+		cleanup()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+// campaign starts a campaign of the elector in a goroutine, and returns a
+// channel which receives its result.
+func campaign(ctx context.Context, e LeaderElector) <-chan error {
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- e.Campaign(ctx)
+	}()
+
+	return errChan
+}
+
+// assertElected asserts that the campaign with the given result channel
+// succeeds.
+func assertElected(t *testing.T, errChan <-chan error) {
+	select {
+	case err := <-errChan:
+		require.NoError(t, err)
+
+	case <-time.After(testTimeout):
+		t.Fatalf("campaign didn't succeed")
+	}
+}
+
+// assertNotElected asserts that the campaign with the given result channel is
+// still running.
+func assertNotElected(t *testing.T, errChan <-chan error) {
+	select {
+	case err := <-errChan:
+		t.Fatalf("campaign unexpectedly returned: %v", err)
+
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// assertLeader asserts that the current leader of the election has the given
+// id.
+func assertLeader(t *testing.T, e LeaderElector, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	leader, err := e.Leader(ctx)
+	require.NoError(t, err)
+	require.Equal(t, id, leader)
+}
+
+// TestEtcdElector tests that the etcd leader elector elects a single leader
+// at a time, and that a standby takes over once the leader resigns.
+func TestEtcdElector(t *testing.T) {
+	config, cleanup := newTestEtcd(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const (
+		id1 = "e1"
+		id2 = "e2"
+	)
+
+	e1, err := newEtcdLeaderElector(
+		ctx, id1, testElectionPrefix, testSessionTTL, *config,
+	)
+	require.NoError(t, err)
+
+	e2, err := newEtcdLeaderElector(
+		ctx, id2, testElectionPrefix, testSessionTTL, *config,
+	)
+	require.NoError(t, err)
+	defer e2.Resign()
+
+	leader, err := e2.Leader(ctx)
+	require.NoError(t, err)
+	require.Empty(t, leader)
+
+	assertElected(t, campaign(ctx, e1))
+	assertLeader(t, e2, id1)
+
+	// The second elector stands by as long as the first one leads.
+	errChan := campaign(ctx, e2)
+	assertNotElected(t, errChan)
+
+	require.NoError(t, e1.Resign())
+	assertElected(t, errChan)
+	assertLeader(t, e2, id2)
+}
+
+// TestEtcdElectorLeaseLoss tests that the leader learns when the lease of its
+// leadership is lost, and that a standby takes over.
+func TestEtcdElectorLeaseLoss(t *testing.T) {
+	config, cleanup := newTestEtcd(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e1, err := newEtcdLeaderElector(
+		ctx, "e1", testElectionPrefix, testSessionTTL, *config,
+	)
+	require.NoError(t, err)
+	defer e1.Resign()
+
+	e2, err := newEtcdLeaderElector(
+		ctx, "e2", testElectionPrefix, testSessionTTL, *config,
+	)
+	require.NoError(t, err)
+	defer e2.Resign()
+
+	assertElected(t, campaign(ctx, e1))
+
+	errChan := campaign(ctx, e2)
+	assertNotElected(t, errChan)
+
+	// Revoke the lease of the leader, as if it expired because the
+	// leader couldn't reach etcd anymore.
+	_, err = e2.cli.Revoke(ctx, e1.session.Lease())
+	require.NoError(t, err)
+
+	select {
+	case <-e1.Done():
+	case <-time.After(testTimeout):
+		t.Fatalf("leader wasn't notified about the lost lease")
+	}
+
+	assertElected(t, errChan)
+	assertLeader(t, e2, "e2")
+}
+
+// TestCampaignCancel tests that a campaign returns once its context is
+// canceled.
+func TestCampaignCancel(t *testing.T) {
+	config, cleanup := newTestEtcd(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e1, err := newEtcdLeaderElector(
+		ctx, "e1", testElectionPrefix, testSessionTTL, *config,
+	)
+	require.NoError(t, err)
+	defer e1.Resign()
+
+	e2, err := newEtcdLeaderElector(
+		ctx, "e2", testElectionPrefix, testSessionTTL, *config,
+	)
+	require.NoError(t, err)
+	defer e2.Resign()
+
+	assertElected(t, campaign(ctx, e1))
+
+	campaignCtx, cancelCampaign := context.WithCancel(ctx)
+	errChan := campaign(campaignCtx, e2)
+	assertNotElected(t, errChan)
+
+	cancelCampaign()
+	select {
+	case err := <-errChan:
+		require.Error(t, err)
+
+	case <-time.After(testTimeout):
+		t.Fatalf("campaign didn't return")
+	}
+
+	assertLeader(t, e1, "e1")
+}
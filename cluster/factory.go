@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+)
+
+// leaderElectorFactoryFunc is a function type used for registering leader
+// elector factories.
+type leaderElectorFactoryFunc func(context.Context, ...interface{}) (
+	LeaderElector, error)
+
+// leaderElectorFactories holds the factories of the leader electors that are
+// compiled into the binary, keyed by their id.
+var leaderElectorFactories map[string]leaderElectorFactoryFunc
+
+// RegisterLeaderElectorFactory will register a new LeaderElector factory.
+// This is useful for registering leader electors that are only available with
+// some build tags.
+func RegisterLeaderElectorFactory(id string,
+	factory leaderElectorFactoryFunc) {
+
+	if leaderElectorFactories == nil {
+		leaderElectorFactories = make(
+			map[string]leaderElectorFactoryFunc,
+		)
+	}
+
+	leaderElectorFactories[id] = factory
+}
+
+// MakeLeaderElector will construct a LeaderElector identified by id with the
+// passed arguments.
+func MakeLeaderElector(ctx context.Context, id string,
+	args ...interface{}) (LeaderElector, error) {
+
+	if _, ok := leaderElectorFactories[id]; !ok {
+		return nil, fmt.Errorf("leader elector factory for '%v' "+
+			"not found", id)
+	}
+
+	return leaderElectorFactories[id](ctx, args...)
+}
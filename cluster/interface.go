@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"context"
+)
+
+const (
+	// EtcdLeaderElector is the id used when constructing an etcd based
+	// leader elector.
+	EtcdLeaderElector = "etcd"
+)
+
+// LeaderElector is a general interface implementing basic leader election
+// primitives, allowing several nodes of a cluster to elect the single node
+// that is active while the others stand by.
+type LeaderElector interface {
+	// Campaign starts a run for leadership. Campaign will block until the
+	// caller is elected as the leader or the passed context is canceled.
+	Campaign(ctx context.Context) error
+
+	// Resign resigns from the leader role, allowing other election members
+	// to take on leadership.
+	Resign() error
+
+	// Leader returns the leader value for the current election.
+	Leader(ctx context.Context) (string, error)
+
+	// Done returns a channel that is closed once the leadership can no
+	// longer be held, as the lease backing it expired or was lost. As
+	// another node may be elected from then on, the leader must stop all
+	// its activity.
+	Done() <-chan struct{}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var sendCustomCommand = cli.Command{
+	Name:     "sendcustom",
+	Category: "Peers",
+	Usage:    "Send a custom message to a connected peer.",
+	Description: `
+	Send a message in the custom type range, which starts at 32768, to a
+	connected peer. The payload of the message is opaque to the Lightning
+	protocol.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "peer",
+			Usage: "the hex encoded pubkey of the peer",
+		},
+		cli.Uint64Flag{
+			Name:  "type",
+			Usage: "the message type",
+		},
+		cli.StringFlag{
+			Name:  "data",
+			Usage: "the hex encoded payload of the message",
+		},
+	},
+	Action: actionDecorator(sendCustom),
+}
+
+func sendCustom(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	peer, err := hex.DecodeString(ctx.String("peer"))
+	if err != nil {
+		return fmt.Errorf("unable to decode peer pubkey: %v", err)
+	}
+
+	data, err := hex.DecodeString(ctx.String("data"))
+	if err != nil {
+		return fmt.Errorf("unable to decode data: %v", err)
+	}
+
+	_, err = client.SendCustomMessage(
+		ctxb, &lnrpc.SendCustomMessageRequest{
+			Peer: peer,
+			Type: uint32(ctx.Uint64("type")),
+			Data: data,
+		},
+	)
+
+	return err
+}
+
+var subscribeCustomCommand = cli.Command{
+	Name:     "subscribecustom",
+	Category: "Peers",
+	Usage:    "Subscribe to the custom messages received from peers.",
+	Description: `
+	Print the messages in the custom type range received from any of the
+	connected peers until the command is interrupted.`,
+	Action: actionDecorator(subscribeCustom),
+}
+
+func subscribeCustom(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	stream, err := client.SubscribeCustomMessages(
+		ctxb, &lnrpc.SubscribeCustomMessagesRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		fmt.Printf("Received from peer %x: type=%d, data=%x\n",
+			msg.Peer, msg.Type, msg.Data)
+	}
+}
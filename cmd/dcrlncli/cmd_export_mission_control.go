@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/urfave/cli"
+)
+
+var exportMissionControlCommand = cli.Command{
+	Name:     "exportmc",
+	Category: "Payments",
+	Usage: "Export the internal mission control history in a format " +
+		"that can be imported with importmc.",
+	Description: `
+	Export the node pair-level mission control history of this node. The
+	output can be stored in a file and be imported into another node (or
+	into this node after a migration) with the importmc command.
+
+	Example:
+	    dcrlncli exportmc > mc.json
+	`,
+	Action: actionDecorator(exportMissionControl),
+}
+
+func exportMissionControl(ctx *cli.Context) error {
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	req := &routerrpc.XExportMissionControlRequest{}
+	rpcCtx := context.Background()
+	resp, err := client.XExportMissionControl(rpcCtx, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
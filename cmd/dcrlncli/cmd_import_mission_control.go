@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/matheusd/protobuf-hex-display/jsonpb"
+	"github.com/urfave/cli"
+)
+
+var importMissionControlCommand = cli.Command{
+	Name:     "importmc",
+	Category: "Payments",
+	Usage: "Import mission control history that was exported with " +
+		"exportmc.",
+	ArgsUsage: "file",
+	Description: `
+	Import node pair-level mission control history into the internal
+	mission control state. The file must contain the output of the
+	exportmc command. Use "-" as the file name to read the history from
+	stdin.
+
+	By default, only results that are more recent than the existing
+	results for a node pair are imported. The --force flag overwrites the
+	existing results with the imported ones.
+
+	Note that the imported history is only kept in memory and does not
+	survive a restart.
+
+	Example:
+	    dcrlncli importmc mc.json
+	`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name: "force",
+			Usage: "whether to overwrite existing results with " +
+				"older imported results",
+		},
+	},
+	Action: actionDecorator(importMissionControl),
+}
+
+func importMissionControl(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return cli.ShowCommandHelp(ctx, "importmc")
+	}
+
+	var (
+		jsonHistory []byte
+		err         error
+	)
+	if args.First() == "-" {
+		jsonHistory, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		jsonHistory, err = ioutil.ReadFile(
+			cleanAndExpandPath(args.First()),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read mission control history: %v",
+			err)
+	}
+
+	history := &routerrpc.XExportMissionControlResponse{}
+	err = jsonpb.UnmarshalString(string(jsonHistory), history)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal mission control "+
+			"history: %v", err)
+	}
+
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	req := &routerrpc.XImportMissionControlRequest{
+		Pairs: history.Pairs,
+		Force: ctx.Bool("force"),
+	}
+	rpcCtx := context.Background()
+	resp, err := client.XImportMissionControl(rpcCtx, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/decred/dcrlnd/lnrpc"
 	"github.com/urfave/cli"
@@ -69,6 +70,19 @@ var addInvoiceCommand = cli.Command{
 				"in directly connected channels and create the " +
 				"invoice anyway.",
 		},
+		cli.BoolFlag{
+			Name: "amp",
+			Usage: "Creates an AMP invoice. If true, preimage " +
+				"should not be set. AMP invoices can be paid " +
+				"multiple times.",
+		},
+		cli.BoolFlag{
+			Name: "blind",
+			Usage: "Hides the identity of the node behind a " +
+				"blinded path whose introduction node is one " +
+				"of its channel peers. Cannot be used along " +
+				"with --private.",
+		},
 	},
 	Action: actionDecorator(addInvoice),
 }
@@ -122,6 +136,8 @@ func addInvoice(ctx *cli.Context) error {
 		Expiry:              ctx.Int64("expiry"),
 		Private:             ctx.Bool("private"),
 		IgnoreMaxInboundAmt: ctx.Bool("ignore_max_inbound_amt"),
+		IsAmp:               ctx.Bool("amp"),
+		IsBlinded:           ctx.Bool("blind"),
 	}
 
 	resp, err := client.AddInvoice(context.Background(), invoice)
@@ -227,6 +243,32 @@ var listInvoicesCommand = cli.Command{
 			Usage: "If set, invoices succeeding the " +
 				"'index_offset' will be returned",
 		},
+		cli.Int64Flag{
+			Name: "creation_date_start",
+			Usage: "Only return invoices created at or after " +
+				"this unix timestamp",
+		},
+		cli.Int64Flag{
+			Name: "creation_date_end",
+			Usage: "Only return invoices created at or before " +
+				"this unix timestamp",
+		},
+		cli.Int64Flag{
+			Name: "settle_date_start",
+			Usage: "Only return invoices settled at or after " +
+				"this unix timestamp",
+		},
+		cli.Int64Flag{
+			Name: "settle_date_end",
+			Usage: "Only return invoices settled at or before " +
+				"this unix timestamp",
+		},
+		cli.StringSliceFlag{
+			Name: "state",
+			Usage: "Only return invoices in the given state " +
+				"(open|settled|canceled|accepted), can be " +
+				"specified multiple times",
+		},
 	},
 	Action: actionDecorator(listInvoices),
 }
@@ -236,10 +278,27 @@ func listInvoices(ctx *cli.Context) error {
 	defer cleanUp()
 
 	req := &lnrpc.ListInvoiceRequest{
-		PendingOnly:    ctx.Bool("pending_only"),
-		IndexOffset:    ctx.Uint64("index_offset"),
-		NumMaxInvoices: ctx.Uint64("max_invoices"),
-		Reversed:       !ctx.Bool("paginate-forwards"),
+		PendingOnly:       ctx.Bool("pending_only"),
+		IndexOffset:       ctx.Uint64("index_offset"),
+		NumMaxInvoices:    ctx.Uint64("max_invoices"),
+		Reversed:          !ctx.Bool("paginate-forwards"),
+		CreationDateStart: ctx.Int64("creation_date_start"),
+		CreationDateEnd:   ctx.Int64("creation_date_end"),
+		SettleDateStart:   ctx.Int64("settle_date_start"),
+		SettleDateEnd:     ctx.Int64("settle_date_end"),
+	}
+
+	for _, state := range ctx.StringSlice("state") {
+		value, ok := lnrpc.Invoice_InvoiceState_value[strings.ToUpper(
+			state,
+		)]
+		if !ok {
+			return fmt.Errorf("unknown invoice state %v", state)
+		}
+
+		req.States = append(
+			req.States, lnrpc.Invoice_InvoiceState(value),
+		)
 	}
 
 	invoices, err := client.ListInvoices(context.Background(), req)
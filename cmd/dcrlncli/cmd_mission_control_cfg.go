@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/urfave/cli"
+)
+
+var getCfgCommand = cli.Command{
+	Name:     "getmccfg",
+	Category: "Payments",
+	Usage:    "Display mission control's config.",
+	Description: `
+	Returns the config currently being used by mission control.
+	`,
+	Action: actionDecorator(getCfg),
+}
+
+func getCfg(ctx *cli.Context) error {
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	resp, err := client.GetMissionControlConfig(
+		context.Background(), &routerrpc.GetMissionControlConfigRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var setCfgCommand = cli.Command{
+	Name:     "setmccfg",
+	Category: "Payments",
+	Usage:    "Set mission control's config.",
+	Description: `
+	Update the config values being used by mission control to calculate
+	the probability that payment routes will succeed. Values that are not
+	set are kept at their current value. When switching the estimator, the
+	parameters of the new estimator that are not set are taken from its
+	defaults.
+
+	The new config is applied immediately and persisted, so that it takes
+	precedence over the startup configuration after a restart.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "estimator",
+			Usage: "the probability estimator to use, either " +
+				"\"apriori\" or \"bimodal\"",
+		},
+		cli.DurationFlag{
+			Name: "halflife",
+			Usage: "the amount of time taken to restore a node " +
+				"or channel to 50% probability of success " +
+				"(apriori estimator).",
+		},
+		cli.Float64Flag{
+			Name: "hopprob",
+			Usage: "the probability of success assigned " +
+				"to hops that we have no information about " +
+				"(apriori estimator).",
+		},
+		cli.Float64Flag{
+			Name: "weight",
+			Usage: "the degree to which mission control should " +
+				"rely on historical results, expressed as " +
+				"value in [0;1] (apriori estimator).",
+		},
+		cli.Float64Flag{
+			Name: "nodeweight",
+			Usage: "the degree to which results of other " +
+				"channels of a node should be taken into " +
+				"account, expressed as value in [0;1] " +
+				"(bimodal estimator).",
+		},
+		cli.Uint64Flag{
+			Name: "scalematoms",
+			Usage: "the scale in milliatoms over which channels " +
+				"are assumed to have some liquidity left " +
+				"(bimodal estimator).",
+		},
+		cli.DurationFlag{
+			Name: "decaytime",
+			Usage: "the time over which previous results are " +
+				"forgotten (bimodal estimator).",
+		},
+		cli.UintFlag{
+			Name: "pmtnr",
+			Usage: "the number of payments mission control " +
+				"should store",
+		},
+		cli.DurationFlag{
+			Name: "failrelax",
+			Usage: "the amount of time to wait after a failure " +
+				"before raising failure amount",
+		},
+		cli.Uint64Flag{
+			Name: "attemptcost",
+			Usage: "the (virtual) cost in atoms of a failed " +
+				"payment attempt, used in path finding",
+		},
+	},
+	Action: actionDecorator(setCfg),
+}
+
+func setCfg(ctx *cli.Context) error {
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	// Fetch the current config, so that we only change the values that
+	// were set.
+	resp, err := client.GetMissionControlConfig(
+		context.Background(), &routerrpc.GetMissionControlConfigRequest{},
+	)
+	if err != nil {
+		return err
+	}
+	mcCfg := resp.Config
+
+	var haveValue bool
+
+	if ctx.IsSet("estimator") {
+		haveValue = true
+
+		switch ctx.String("estimator") {
+		case routing.AprioriEstimatorName:
+			if mcCfg.GetApriori() == nil {
+				mcCfg.EstimatorConfig = defaultAprioriParams()
+			}
+			mcCfg.Model = routerrpc.MissionControlConfig_APRIORI
+
+		case routing.BimodalEstimatorName:
+			if mcCfg.GetBimodal() == nil {
+				mcCfg.EstimatorConfig = defaultBimodalParams()
+			}
+			mcCfg.Model = routerrpc.MissionControlConfig_BIMODAL
+
+		default:
+			return fmt.Errorf("unknown estimator %v",
+				ctx.String("estimator"))
+		}
+	}
+
+	// Apply the estimator parameters, making sure that they match the
+	// selected estimator.
+	apriori := mcCfg.GetApriori()
+	bimodal := mcCfg.GetBimodal()
+	for _, flag := range []string{"halflife", "hopprob", "weight"} {
+		if ctx.IsSet(flag) && apriori == nil {
+			return fmt.Errorf("--%v requires the apriori estimator",
+				flag)
+		}
+	}
+	bimodalFlags := []string{"nodeweight", "scalematoms", "decaytime"}
+	for _, flag := range bimodalFlags {
+		if ctx.IsSet(flag) && bimodal == nil {
+			return fmt.Errorf("--%v requires the bimodal estimator",
+				flag)
+		}
+	}
+
+	if ctx.IsSet("halflife") {
+		haveValue = true
+		apriori.HalfLifeSeconds = uint64(
+			ctx.Duration("halflife").Seconds(),
+		)
+	}
+
+	if ctx.IsSet("hopprob") {
+		haveValue = true
+		apriori.HopProbability = ctx.Float64("hopprob")
+	}
+
+	if ctx.IsSet("weight") {
+		haveValue = true
+		apriori.Weight = ctx.Float64("weight")
+	}
+
+	if ctx.IsSet("nodeweight") {
+		haveValue = true
+		bimodal.NodeWeight = ctx.Float64("nodeweight")
+	}
+
+	if ctx.IsSet("scalematoms") {
+		haveValue = true
+		bimodal.ScaleMAtoms = ctx.Uint64("scalematoms")
+	}
+
+	if ctx.IsSet("decaytime") {
+		haveValue = true
+		bimodal.DecayTime = uint64(ctx.Duration("decaytime").Seconds())
+	}
+
+	if ctx.IsSet("pmtnr") {
+		haveValue = true
+		mcCfg.MaximumPaymentResults = uint32(ctx.Int("pmtnr"))
+	}
+
+	if ctx.IsSet("failrelax") {
+		haveValue = true
+		mcCfg.MinimumFailureRelaxInterval = uint64(ctx.Duration(
+			"failrelax",
+		).Seconds())
+	}
+
+	if ctx.IsSet("attemptcost") {
+		haveValue = true
+		mcCfg.AttemptCost = ctx.Uint64("attemptcost")
+	}
+
+	if !haveValue {
+		return cli.ShowCommandHelp(ctx, "setmccfg")
+	}
+
+	_, err = client.SetMissionControlConfig(
+		context.Background(), &routerrpc.SetMissionControlConfigRequest{
+			Config: mcCfg,
+		},
+	)
+	return err
+}
+
+// defaultAprioriParams returns the default parameters of the apriori
+// estimator.
+func defaultAprioriParams() *routerrpc.MissionControlConfig_Apriori {
+	defaults := routing.DefaultAprioriConfig()
+
+	return &routerrpc.MissionControlConfig_Apriori{
+		Apriori: &routerrpc.AprioriParameters{
+			HalfLifeSeconds: uint64(
+				defaults.PenaltyHalfLife / time.Second,
+			),
+			HopProbability: defaults.AprioriHopProbability,
+			Weight:         defaults.AprioriWeight,
+		},
+	}
+}
+
+// defaultBimodalParams returns the default parameters of the bimodal
+// estimator.
+func defaultBimodalParams() *routerrpc.MissionControlConfig_Bimodal {
+	defaults := routing.DefaultBimodalConfig()
+
+	return &routerrpc.MissionControlConfig_Bimodal{
+		Bimodal: &routerrpc.BimodalParameters{
+			NodeWeight:  defaults.BimodalNodeWeight,
+			ScaleMAtoms: uint64(defaults.BimodalScaleMAtoms),
+			DecayTime:   uint64(defaults.BimodalDecayTime / time.Second),
+		},
+	}
+}
@@ -65,6 +65,14 @@ var (
 		Value: 1,
 	}
 
+	splitStrategyFlag = cli.StringFlag{
+		Name: "split_strategy",
+		Usage: "the strategy used to split the payment if no route " +
+			"is found for the full amount, either 'halving' or " +
+			"'fee_aware'",
+		Value: "halving",
+	}
+
 	jsonFlag = cli.BoolFlag{
 		Name: "json",
 		Usage: "if set, payment updates are printed as json " +
@@ -107,7 +115,26 @@ func paymentFlags() []cli.Flag {
 			Name:  "allow_self_payment",
 			Usage: "Allow sending a circular payment to self",
 		},
-		dataFlag, inflightUpdatesFlag, maxPartsFlag, jsonFlag,
+		cli.StringFlag{
+			Name: "trampoline_node",
+			Usage: "The compressed identity pubkey of a trampoline " +
+				"node that finds a route to the destination " +
+				"on our behalf, which must be a direct peer",
+		},
+		cli.Int64Flag{
+			Name: "trampoline_fee",
+			Usage: "The fee in atoms paid to the trampoline node, " +
+				"which must cover the routing fees of the " +
+				"forwarded payment",
+		},
+		cli.Int64Flag{
+			Name: "trampoline_cltv_delta",
+			Usage: "The number of blocks handed to the trampoline " +
+				"node to cover the time locks of the route to " +
+				"the destination",
+		},
+		dataFlag, inflightUpdatesFlag, maxPartsFlag,
+		splitStrategyFlag, jsonFlag,
 	}
 }
 
@@ -153,6 +180,12 @@ var sendPaymentCommand = cli.Command{
 			Name:  "keysend",
 			Usage: "Will generate a pre-image and encode it in the sphinx packet, a dest must be set [experimental]",
 		},
+		cli.BoolFlag{
+			Name: "amp",
+			Usage: "Will generate a random root seed from which the " +
+				"payment hash and preimage of each shard is " +
+				"derived, a dest must be set [experimental]",
+		},
 	),
 	Action: sendPayment,
 }
@@ -262,6 +295,28 @@ func sendPayment(ctx *cli.Context) error {
 		DestCustomRecords: make(map[uint64][]byte),
 	}
 
+	// AMP payments derive the payment hash of each shard from a random
+	// root seed generated by the sender, so no payment hash is needed.
+	if ctx.Bool("amp") {
+		switch {
+		case ctx.Bool("keysend"):
+			return errors.New("cannot use keysend and amp " +
+				"together")
+
+		case ctx.IsSet("payment_hash"):
+			return errors.New("cannot set payment hash when using " +
+				"amp")
+		}
+
+		req.Amp = true
+
+		if ctx.IsSet("final_cltv_delta") {
+			req.FinalCltvDelta = int32(ctx.Int64("final_cltv_delta"))
+		}
+
+		return sendPaymentRequest(ctx, req)
+	}
+
 	var rHash []byte
 
 	if ctx.Bool("keysend") {
@@ -338,6 +393,19 @@ func sendPaymentRequest(ctx *cli.Context,
 		}
 		req.LastHopPubkey = lastHop[:]
 	}
+	if ctx.IsSet("trampoline_node") {
+		trampolineNode, err := route.NewVertexFromStr(
+			ctx.String("trampoline_node"),
+		)
+		if err != nil {
+			return err
+		}
+		req.TrampolineNode = trampolineNode[:]
+		req.TrampolineFeeMAtoms = ctx.Int64("trampoline_fee") * 1000
+		req.TrampolineCltvDelta = int32(
+			ctx.Int64("trampoline_cltv_delta"),
+		)
+	}
 
 	req.CltvLimit = int32(ctx.Int(cltvLimitFlag.Name))
 	req.TimeoutSeconds = paymentTimeoutSeconds
@@ -346,6 +414,18 @@ func sendPaymentRequest(ctx *cli.Context,
 
 	req.MaxParts = uint32(ctx.Uint(maxPartsFlag.Name))
 
+	switch ctx.String(splitStrategyFlag.Name) {
+	case "halving":
+		req.SplitStrategy = lnrpc.SplitStrategy_HALVING
+
+	case "fee_aware":
+		req.SplitStrategy = lnrpc.SplitStrategy_FEE_AWARE
+
+	default:
+		return fmt.Errorf("unknown split strategy %v",
+			ctx.String(splitStrategyFlag.Name))
+	}
+
 	// Parse custom data records.
 	data := ctx.String(dataFlag.Name)
 	if data != "" {
@@ -686,6 +766,12 @@ var payInvoiceCommand = cli.Command{
 			Usage: "Number of atoms to fulfill the " +
 				"invoice (optional)",
 		},
+		cli.BoolFlag{
+			Name: "amp",
+			Usage: "Pay the invoice using AMP, the invoice must " +
+				"signal support for AMP. Invoices that require " +
+				"AMP are always paid using AMP [experimental]",
+		},
 	),
 	Action: actionDecorator(payInvoice),
 }
@@ -707,6 +793,7 @@ func payInvoice(ctx *cli.Context) error {
 		PaymentRequest:    payReq,
 		Amt:               ctx.Int64("amt"),
 		DestCustomRecords: make(map[uint64][]byte),
+		Amp:               ctx.Bool("amp"),
 	}
 
 	return sendPaymentRequest(ctx, req)
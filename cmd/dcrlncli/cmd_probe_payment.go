@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/urfave/cli"
+)
+
+var probePaymentCommand = cli.Command{
+	Name:     "probepayment",
+	Category: "Payments",
+	Usage:    "Probe the destination of a payment without risking funds.",
+	Description: `
+	Probe whether a payment is able to reach its destination. The probes
+	are sent as payments with a random payment hash, so that they are
+	failed by the destination and no funds are transferred. If the full
+	amount doesn't reach the destination, smaller amounts are probed to
+	find the largest amount that does.
+
+	Either a payment request or both the destination and the amount must
+	be specified.`,
+	ArgsUsage: "[--pay_req=R] [--dest=D --amt=A]",
+	Action:    actionDecorator(probePayment),
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "pay_req",
+			Usage: "a zpay32 encoded payment request to probe",
+		},
+		cli.StringFlag{
+			Name:  "dest, d",
+			Usage: "the compressed identity pubkey of the destination",
+		},
+		cli.Int64Flag{
+			Name: "amt, a",
+			Usage: "the largest amount to probe for in atoms, " +
+				"required unless the payment request specifies " +
+				"an amount",
+		},
+		cli.Int64Flag{
+			Name: "final_cltv_delta",
+			Usage: "the number of blocks the last hop has to " +
+				"reveal the preimage",
+		},
+		cli.Int64Flag{
+			Name: "fee_limit",
+			Usage: "maximum fee allowed in atoms for the probed " +
+				"routes",
+		},
+		cltvLimitFlag,
+		lastHopFlag,
+		cli.Uint64Flag{
+			Name: "outgoing_chan_id",
+			Usage: "short channel id of the outgoing channel to " +
+				"use for the first hop of the probes",
+		},
+		cli.UintFlag{
+			Name: "max_probes",
+			Usage: "the maximum number of probes to send out, " +
+				"defaults to 10",
+		},
+		cli.IntFlag{
+			Name:  "timeout",
+			Usage: "the maximum number of seconds spent on each probe",
+			Value: paymentTimeoutSeconds,
+		},
+	},
+}
+
+func probePayment(ctx *cli.Context) error {
+	req := &routerrpc.ProbePaymentRequest{
+		PaymentRequest: ctx.String("pay_req"),
+		Amt:            ctx.Int64("amt"),
+		FinalCltvDelta: int32(ctx.Int64("final_cltv_delta")),
+		FeeLimitAtoms:  ctx.Int64("fee_limit"),
+		CltvLimit:      int32(ctx.Uint(cltvLimitFlag.Name)),
+		MaxProbes:      uint32(ctx.Uint("max_probes")),
+		TimeoutSeconds: int32(ctx.Int("timeout")),
+	}
+
+	if ctx.IsSet("dest") {
+		dest, err := hex.DecodeString(ctx.String("dest"))
+		if err != nil {
+			return err
+		}
+		req.Dest = dest
+	}
+
+	if req.PaymentRequest == "" && req.Dest == nil {
+		return errors.New("either pay_req or dest must be specified")
+	}
+
+	if outChan := ctx.Uint64("outgoing_chan_id"); outChan != 0 {
+		req.OutgoingChanIds = []uint64{outChan}
+	}
+
+	if ctx.IsSet(lastHopFlag.Name) {
+		lastHop, err := route.NewVertexFromStr(
+			ctx.String(lastHopFlag.Name),
+		)
+		if err != nil {
+			return err
+		}
+		req.LastHopPubkey = lastHop[:]
+	}
+
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	resp, err := client.ProbePayment(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -1992,6 +1992,33 @@ var listPaymentsCommand = cli.Command{
 				"index_offset will be returned, allowing " +
 				"forwards pagination",
 		},
+		cli.Int64Flag{
+			Name: "creation_date_start",
+			Usage: "only return payments created at or after " +
+				"this unix timestamp",
+		},
+		cli.Int64Flag{
+			Name: "creation_date_end",
+			Usage: "only return payments created at or before " +
+				"this unix timestamp",
+		},
+		cli.Int64Flag{
+			Name: "settle_date_start",
+			Usage: "only return payments that settled at or " +
+				"after this unix timestamp",
+		},
+		cli.Int64Flag{
+			Name: "settle_date_end",
+			Usage: "only return payments that settled at or " +
+				"before this unix timestamp",
+		},
+		cli.StringSliceFlag{
+			Name: "status",
+			Usage: "only return payments with the given status " +
+				"(in_flight|succeeded|failed), can be " +
+				"specified multiple times, overrides " +
+				"include_incomplete",
+		},
 	},
 	Action: actionDecorator(listPayments),
 }
@@ -2005,6 +2032,23 @@ func listPayments(ctx *cli.Context) error {
 		IndexOffset:       uint64(ctx.Uint("index_offset")),
 		MaxPayments:       uint64(ctx.Uint("max_payments")),
 		Reversed:          !ctx.Bool("paginate_forwards"),
+		CreationDateStart: ctx.Int64("creation_date_start"),
+		CreationDateEnd:   ctx.Int64("creation_date_end"),
+		SettleDateStart:   ctx.Int64("settle_date_start"),
+		SettleDateEnd:     ctx.Int64("settle_date_end"),
+	}
+
+	for _, status := range ctx.StringSlice("status") {
+		value, ok := lnrpc.Payment_PaymentStatus_value[strings.ToUpper(
+			status,
+		)]
+		if !ok {
+			return fmt.Errorf("unknown payment status %v", status)
+		}
+
+		req.Statuses = append(
+			req.Statuses, lnrpc.Payment_PaymentStatus(value),
+		)
 	}
 
 	payments, err := client.ListPayments(context.Background(), req)
@@ -2016,6 +2060,111 @@ func listPayments(ctx *cli.Context) error {
 	return nil
 }
 
+var deletePaymentsCommand = cli.Command{
+	Name:     "deletepayments",
+	Category: "Payments",
+	Usage:    "Delete a single or multiple payments from the database.",
+	ArgsUsage: "--all [--failed_only] [--failed_htlcs_only] " +
+		"[--start_time=N] [--end_time=N] | --payment_hash=hash " +
+		"[--failed_htlcs_only]",
+	Description: "This command either deletes all failed payments or " +
+		"a single payment from the database. In-flight payments " +
+		"are never deleted. If --all is set, the payments can be " +
+		"restricted to failed payments and to payments created in " +
+		"a time range. With --failed_htlcs_only, only the failed " +
+		"HTLC attempts of the payments are deleted.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "payment_hash",
+			Usage: "the hex encoded payment hash of the payment " +
+				"to delete",
+		},
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "delete all payments in the database",
+		},
+		cli.BoolFlag{
+			Name:  "failed_only",
+			Usage: "only delete failed payments, requires --all",
+		},
+		cli.BoolFlag{
+			Name: "failed_htlcs_only",
+			Usage: "only delete failed HTLC attempts, keeping the " +
+				"payments themselves",
+		},
+		cli.Int64Flag{
+			Name: "start_time",
+			Usage: "only delete payments created at or after this " +
+				"unix timestamp, requires --all",
+		},
+		cli.Int64Flag{
+			Name: "end_time",
+			Usage: "only delete payments created at or before this " +
+				"unix timestamp, requires --all",
+		},
+	},
+	Action: actionDecorator(deletePayments),
+}
+
+func deletePayments(ctx *cli.Context) error {
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var (
+		paymentHash     = ctx.String("payment_hash")
+		all             = ctx.Bool("all")
+		failedOnly      = ctx.Bool("failed_only")
+		failedHtlcsOnly = ctx.Bool("failed_htlcs_only")
+		startTime       = ctx.Int64("start_time")
+		endTime         = ctx.Int64("end_time")
+	)
+	switch {
+	case paymentHash != "" && all:
+		return fmt.Errorf("either --payment_hash or --all must be " +
+			"set, not both")
+
+	case paymentHash != "":
+		if failedOnly || startTime != 0 || endTime != 0 {
+			return fmt.Errorf("--failed_only, --start_time and " +
+				"--end_time require --all")
+		}
+
+		hash, err := hex.DecodeString(paymentHash)
+		if err != nil {
+			return fmt.Errorf("error decoding payment hash: %v",
+				err)
+		}
+
+		_, err = client.DeletePayment(
+			context.Background(), &lnrpc.DeletePaymentRequest{
+				PaymentHash:     hash,
+				FailedHtlcsOnly: failedHtlcsOnly,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+	case all:
+		_, err := client.DeleteAllPayments(
+			context.Background(), &lnrpc.DeleteAllPaymentsRequest{
+				FailedPaymentsOnly: failedOnly,
+				FailedHtlcsOnly:    failedHtlcsOnly,
+				CreationDateStart:  startTime,
+				CreationDateEnd:    endTime,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("either --payment_hash or --all must be set")
+	}
+
+	return nil
+}
+
 var getChanInfoCommand = cli.Command{
 	Name:     "getchaninfo",
 	Category: "Graph",
@@ -2392,6 +2541,135 @@ func stopDaemon(ctx *cli.Context) error {
 	return nil
 }
 
+var compactDBCommand = cli.Command{
+	Name:  "compactdb",
+	Usage: "Compact the channel database on the next restart.",
+	Description: `
+	Request the channel database to be compacted the next time the daemon
+	starts, which returns the space freed by deleted data to the file
+	system. The progress of the compaction is logged during the startup.
+
+	The compaction temporarily requires enough free disk space for a full
+	copy of the database. Use --cancel to withdraw a previous request.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "cancel",
+			Usage: "withdraw a previous compaction request",
+		},
+	},
+	Action: actionDecorator(compactDB),
+}
+
+func compactDB(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.CompactDatabaseRequest{
+		Cancel: ctx.Bool("cancel"),
+	}
+	resp, err := client.CompactDatabase(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var backupDBCommand = cli.Command{
+	Name:      "backupdb",
+	Usage:     "Stream a snapshot of the channel database to a file.",
+	ArgsUsage: "--output_file [--no_encrypt]",
+	Description: `
+	Stream a consistent snapshot of the channel database to the target
+	file without stopping the daemon. The snapshot is taken within a single
+	read transaction, so it reflects the state of the database when the
+	command was issued.
+
+	The snapshot is encrypted with a key derived from the seed of the node,
+	so it can be stored safely on untrusted storage. If --no_encrypt is set,
+	the snapshot holds the plaintext data of the node, including the data
+	needed to spend its channel funds, and must be protected accordingly.
+
+	The snapshot is first written to a temporary file, which is renamed to
+	the target file once the snapshot is complete.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output_file",
+			Usage: "the file to write the snapshot to",
+		},
+		cli.BoolFlag{
+			Name: "no_encrypt",
+			Usage: "don't encrypt the snapshot with a key " +
+				"derived from the seed of the node",
+		},
+	},
+	Action: actionDecorator(backupDB),
+}
+
+func backupDB(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	if !ctx.IsSet("output_file") {
+		return fmt.Errorf("output_file must be specified")
+	}
+	outputFile := cleanAndExpandPath(ctx.String("output_file"))
+	tempFile := outputFile + ".tmp"
+
+	req := &lnrpc.BackupDatabaseRequest{
+		NoEncrypt: ctx.Bool("no_encrypt"),
+	}
+	stream, err := client.BackupDatabase(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	// The snapshot is only readable by the user, as it may not be
+	// encrypted.
+	f, err := os.OpenFile(
+		tempFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600,
+	)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile)
+
+	var size int
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		if _, err := f.Write(chunk.Data); err != nil {
+			f.Close()
+			return err
+		}
+		size += len(chunk.Data)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile, outputFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d bytes to %s\n", size, outputFile)
+	return nil
+}
+
 var signMessageCommand = cli.Command{
 	Name:      "signmessage",
 	Category:  "Wallet",
@@ -2731,6 +3009,12 @@ var forwardingHistoryCommand = cli.Command{
 	Finally, callers can skip a series of events using the '--index_offset'
 	parameter. Each response will contain the offset index of the last
 	entry. Using this callers can manually paginate within a time slice.
+
+	The events can be restricted to a set of incoming or outgoing channels
+	with the '--incoming_chan_id' and '--outgoing_chan_id' params, which
+	can be repeated, or to the current and past channels with a peer with
+	the '--incoming_peer' and '--outgoing_peer' params. The totals of all
+	matching events can be requested with '--include_totals'.
 	`,
 	Flags: []cli.Flag{
 		cli.StringFlag{
@@ -2751,6 +3035,31 @@ var forwardingHistoryCommand = cli.Command{
 			Name:  "max_events",
 			Usage: "The max number of events to return",
 		},
+		cli.StringSliceFlag{
+			Name: "incoming_chan_id",
+			Usage: "Only return events received through this " +
+				"channel, can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name: "outgoing_chan_id",
+			Usage: "Only return events forwarded through this " +
+				"channel, can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name: "incoming_peer",
+			Usage: "Only return events received through a " +
+				"channel with this peer",
+		},
+		cli.StringFlag{
+			Name: "outgoing_peer",
+			Usage: "Only return events forwarded through a " +
+				"channel with this peer",
+		},
+		cli.BoolFlag{
+			Name: "include_totals",
+			Usage: "Include the totals of all events that match " +
+				"the query",
+		},
 	},
 	Action: actionDecorator(forwardingHistory),
 }
@@ -2820,11 +3129,45 @@ func forwardingHistory(ctx *cli.Context) error {
 	}
 
 	req := &lnrpc.ForwardingHistoryRequest{
-		StartTime:    startTime,
-		EndTime:      endTime,
-		IndexOffset:  indexOffset,
-		NumMaxEvents: maxEvents,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		IndexOffset:   indexOffset,
+		NumMaxEvents:  maxEvents,
+		IncludeTotals: ctx.Bool("include_totals"),
+	}
+
+	req.IncomingChanIds, err = parseChanIDs(
+		ctx.StringSlice("incoming_chan_id"),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to decode incoming_chan_id: %v", err)
 	}
+	req.OutgoingChanIds, err = parseChanIDs(
+		ctx.StringSlice("outgoing_chan_id"),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to decode outgoing_chan_id: %v", err)
+	}
+
+	if ctx.IsSet("incoming_peer") {
+		req.IncomingPeer, err = hex.DecodeString(
+			ctx.String("incoming_peer"),
+		)
+		if err != nil {
+			return fmt.Errorf("unable to decode incoming_peer: %v",
+				err)
+		}
+	}
+	if ctx.IsSet("outgoing_peer") {
+		req.OutgoingPeer, err = hex.DecodeString(
+			ctx.String("outgoing_peer"),
+		)
+		if err != nil {
+			return fmt.Errorf("unable to decode outgoing_peer: %v",
+				err)
+		}
+	}
+
 	resp, err := client.ForwardingHistory(ctxb, req)
 	if err != nil {
 		return err
@@ -2834,6 +3177,20 @@ func forwardingHistory(ctx *cli.Context) error {
 	return nil
 }
 
+// parseChanIDs parses a list of short channel IDs.
+func parseChanIDs(chanIDs []string) ([]uint64, error) {
+	var ids []uint64
+	for _, chanID := range chanIDs {
+		id, err := strconv.ParseUint(chanID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 var exportChanBackupCommand = cli.Command{
 	Name:     "exportchanbackup",
 	Category: "Channels",
@@ -19,6 +19,7 @@ func invoicesCommands() []cli.Command {
 		cancelInvoiceCommand,
 		addHoldInvoiceCommand,
 		settleInvoiceCommand,
+		deleteInvoiceCommand,
 	}
 }
 
@@ -258,3 +259,59 @@ func addHoldInvoice(ctx *cli.Context) error {
 
 	return nil
 }
+
+var deleteInvoiceCommand = cli.Command{
+	Name:     "deleteinvoice",
+	Category: "Invoices",
+	Usage:    "Deletes a settled or canceled invoice",
+	Description: `
+	Deletes a settled or canceled invoice from the database. Open and
+	accepted invoices can't be deleted.`,
+	ArgsUsage: "paymenthash",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "paymenthash",
+			Usage: "The hex-encoded payment hash (32 byte) for which the " +
+				"corresponding invoice will be deleted.",
+		},
+	},
+	Action: actionDecorator(deleteInvoice),
+}
+
+func deleteInvoice(ctx *cli.Context) error {
+	var (
+		paymentHash []byte
+		err         error
+	)
+
+	client, cleanUp := getInvoicesClient(ctx)
+	defer cleanUp()
+
+	args := ctx.Args()
+
+	switch {
+	case ctx.IsSet("paymenthash"):
+		paymentHash, err = hex.DecodeString(ctx.String("paymenthash"))
+	case args.Present():
+		paymentHash, err = hex.DecodeString(args.First())
+	default:
+		return fmt.Errorf("payment hash argument missing")
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to parse payment hash: %v", err)
+	}
+
+	resp, err := client.DeleteInvoice(
+		context.Background(), &invoicesrpc.DeleteInvoiceMsg{
+			PaymentHash: paymentHash,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -302,6 +302,7 @@ func main() {
 		listChannelsCommand,
 		closedChannelsCommand,
 		listPaymentsCommand,
+		deletePaymentsCommand,
 		describeGraphCommand,
 		getNodeMetricsCommand,
 		getChanInfoCommand,
@@ -312,6 +313,10 @@ func main() {
 		decodePayReqCommand,
 		listChainTxnsCommand,
 		stopCommand,
+		compactDBCommand,
+		backupDBCommand,
+		sendCustomCommand,
+		subscribeCustomCommand,
 		signMessageCommand,
 		verifyMessageCommand,
 		feeReportCommand,
@@ -6,8 +6,13 @@ import "github.com/urfave/cli"
 func routerCommands() []cli.Command {
 	return []cli.Command{
 		queryMissionControlCommand,
+		exportMissionControlCommand,
+		importMissionControlCommand,
 		queryProbCommand,
+		getCfgCommand,
+		setCfgCommand,
 		resetMissionControlCommand,
 		buildRouteCommand,
+		probePaymentCommand,
 	}
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/decred/dcrlnd/build"
 	"github.com/decred/dcrlnd/chanbackup"
 	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/cluster"
 	"github.com/decred/dcrlnd/discovery"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/htlcswitch/hodl"
@@ -256,14 +257,23 @@ type Config struct {
 
 	RejectHTLC bool `long:"rejecthtlc" description:"If true, lnd will not forward any HTLCs that are meant as onward payments. This option will still allow lnd to send HTLCs and receive HTLCs but lnd won't be used as a hop."`
 
+	InterceptorDisconnect string `long:"interceptordisconnect" description:"The action taken on the forwards held by the htlc interceptor once it disconnects. With hold, forwards are held while no interceptor is connected until the next interceptor connects or they're about to expire." choice:"resume" choice:"fail" choice:"hold"`
+
+	InterceptorCltvDelta uint32 `long:"interceptorcltvdelta" description:"The number of blocks before the expiry of an incoming htlc at which a forward held by the htlc interceptor is failed back automatically."`
+
 	StaggerInitialReconnect bool `long:"stagger-initial-reconnect" description:"If true, will apply a randomized staggering between 0s and 30s when reconnecting to persistent peers on startup. The first 10 reconnections will be attempted instantly, regardless of the flag's value"`
 
 	MaxOutgoingCltvExpiry uint32 `long:"max-cltv-expiry" description:"The maximum number of blocks funds could be locked up for when forwarding payments."`
 
 	MaxChannelFeeAllocation float64 `long:"max-channel-fee-allocation" description:"The maximum percentage of total funds that can be allocated to a channel's commitment fee. This only applies for the initiator of the channel. Valid values are within [0.1, 1]."`
 
+	MaxDustExposure dcrutil.Amount `long:"max-dust-exposure" description:"The maximum total value of dust HTLCs, in atoms, allowed on each commitment of a channel. As dust HTLCs are trimmed from the commitment, their value is lost to fees if the channel is force closed. HTLCs that would exceed this limit are failed. A value of 0 disables the limit."`
+
 	DryRunMigration bool `long:"dry-run-migration" description:"If true, lnd will abort committing a migration if it would otherwise have been successful. This leaves the database unmodified, and still compatible with the previously active version of lnd."`
 
+	CheckDB  bool `long:"checkdb" description:"If true, dcrlnd checks the channel database for orphaned or inconsistent records, such as forwarding packages and circuits of closed channels or gaps in revocation logs, reports them and exits without starting."`
+	RepairDB bool `long:"repairdb" description:"If true, the database check requested with --checkdb also removes the records it found that can safely be removed."`
+
 	net tor.Net
 
 	EnableUpfrontShutdown bool `long:"enable-upfront-shutdown" description:"If true, option upfront shutdown script will be enabled. If peers that we open channels with support this feature, we will automatically set the script to which cooperative closes should be paid out to on channel open. This offers the partial protection of a channel peer disconnecting from us if cooperative close is attempted with a different script."`
@@ -272,6 +282,10 @@ type Config struct {
 
 	KeysendHoldTime time.Duration `long:"keysend-hold-time" description:"If non-zero, keysend payments are accepted but not immediately settled. If the payment isn't settled manually after the specified time, it is canceled automatically. [experimental]"`
 
+	AcceptAMP bool `long:"accept-amp" description:"If true, spontaneous payments via AMP will be accepted. [experimental]"`
+
+	GcCanceledInvoicesAfter time.Duration `long:"gc-canceled-invoices-after" description:"If non-zero, canceled invoices that were created longer than the specified duration ago are periodically deleted from the database. Valid time units are {s, m, h}."`
+
 	Routing *routing.Conf `group:"routing" namespace:"routing"`
 
 	Workers *lncfg.Workers `group:"workers" namespace:"workers"`
@@ -292,6 +306,12 @@ type Config struct {
 
 	DB *lncfg.DB `group:"db" namespace:"db"`
 
+	Cluster *lncfg.Cluster `group:"cluster" namespace:"cluster"`
+
+	Trampoline *lncfg.Trampoline `group:"trampoline" namespace:"trampoline"`
+
+	Reputation *lncfg.Reputation `group:"reputation" namespace:"reputation"`
+
 	// LogWriter is the root logger that all of the daemon's subloggers are
 	// hooked up to.
 	LogWriter *build.RotatingLogWriter
@@ -402,9 +422,19 @@ func DefaultConfig() Config {
 		},
 		MaxOutgoingCltvExpiry:   htlcswitch.DefaultMaxOutgoingCltvExpiry,
 		MaxChannelFeeAllocation: htlcswitch.DefaultMaxLinkFeeAllocation,
+		MaxDustExposure:         htlcswitch.DefaultMaxDustExposure,
+		InterceptorDisconnect:   "resume",
+		InterceptorCltvDelta:    lncfg.DefaultCltvInterceptDelta,
 		LogWriter:               build.NewRotatingLogWriter(),
 		DB:                      lncfg.DefaultDB(),
-		registeredChains:        newChainRegistry(),
+		Cluster:                 lncfg.DefaultCluster(),
+		Trampoline: &lncfg.Trampoline{
+			BaseFee:       lncfg.DefaultTrampolineBaseFee,
+			FeeRate:       lncfg.DefaultTrampolineFeeRate,
+			TimeLockDelta: lncfg.DefaultTrampolineTimeLockDelta,
+		},
+		Reputation:       lncfg.DefaultReputation(),
+		registeredChains: newChainRegistry(),
 	}
 }
 
@@ -1079,18 +1109,45 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 			maxRemoteHtlcs)
 	}
 
+	if cfg.RepairDB && !cfg.CheckDB {
+		return nil, fmt.Errorf("repairdb requires checkdb to be set")
+	}
+
+	if cfg.InterceptorCltvDelta < lncfg.DefaultFinalCltvRejectDelta {
+		return nil, fmt.Errorf("interceptorcltvdelta must be at "+
+			"least %d", lncfg.DefaultFinalCltvRejectDelta)
+	}
+
+	if cfg.GcCanceledInvoicesAfter < 0 {
+		return nil, fmt.Errorf("gc-canceled-invoices-after must not " +
+			"be negative")
+	}
+
 	// Validate the subconfigs for workers, caches, and the tower client.
 	err = lncfg.Validate(
 		cfg.Workers,
 		cfg.Caches,
 		cfg.WtClient,
 		cfg.DB,
+		cfg.Cluster,
 		cfg.HealthChecks,
+		cfg.Trampoline,
+		cfg.Reputation,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// The etcd leader elector connects to the etcd instance configured
+	// for the database.
+	if cfg.Cluster.EnableLeaderElection &&
+		cfg.Cluster.LeaderElector == cluster.EtcdLeaderElector &&
+		(cfg.DB.Etcd == nil || cfg.DB.Etcd.Host == "") {
+
+		return nil, fmt.Errorf("etcd leader election requires the " +
+			"etcd host of the database to be set")
+	}
+
 	// Finally, ensure that the user's color is correctly formatted,
 	// otherwise the server will not be able to start after the unlocking
 	// the wallet.
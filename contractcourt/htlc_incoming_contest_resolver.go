@@ -228,6 +228,10 @@ func (h *htlcIncomingContestResolver) Resolve() (ContractResolver, error) {
 	var (
 		hodlChan       chan interface{}
 		witnessUpdates <-chan lntypes.Preimage
+
+		// watchPreimage indicates whether we may learn the preimage
+		// of the htlc through the preimage database.
+		watchPreimage = payload.FwdInfo.NextHop != hop.Exit
 	)
 	if payload.FwdInfo.NextHop == hop.Exit {
 		// Create a buffered hodl chan to prevent deadlock.
@@ -265,6 +269,11 @@ func (h *htlcIncomingContestResolver) Resolve() (ContractResolver, error) {
 				return processHtlcResolution(resolution)
 			}
 
+			// Without an invoice, we may still learn the preimage
+			// from a payment that we made for the htlc, which is
+			// the case if we forwarded it as a trampoline node.
+			watchPreimage = true
+
 		// If we settled the htlc, we can resolve it.
 		case *invoices.HtlcSettleResolution:
 			return processHtlcResolution(resolution)
@@ -277,7 +286,9 @@ func (h *htlcIncomingContestResolver) Resolve() (ContractResolver, error) {
 			return nil, fmt.Errorf("unknown htlc resolution type: %T",
 				resolution)
 		}
-	} else {
+	}
+
+	if watchPreimage {
 		// If the HTLC hasn't expired yet, then we may still be able to
 		// claim it if we learn of the pre-image, so we'll subscribe to
 		// the preimage database to see if it turns up, or the HTLC
@@ -445,8 +456,13 @@ func (h *htlcIncomingContestResolver) Supplement(htlc channeldb.HTLC) {
 func (h *htlcIncomingContestResolver) decodePayload() (*hop.Payload, error) {
 
 	onionReader := bytes.NewReader(h.htlc.OnionBlob)
+	blindingInfo := hop.ReconstructBlindingInfo{
+		IncomingAmt:    h.htlc.Amt,
+		IncomingExpiry: h.htlc.RefundTimeout,
+		BlindingPoint:  h.htlc.BlindingPoint,
+	}
 	iterator, err := h.OnionProcessor.ReconstructHopIterator(
-		onionReader, h.htlc.RHash[:],
+		onionReader, h.htlc.RHash[:], blindingInfo,
 	)
 	if err != nil {
 		return nil, err
@@ -6,16 +6,23 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/htlcswitch/hop"
 	"github.com/decred/dcrlnd/input"
 	"github.com/decred/dcrlnd/invoices"
+	"github.com/decred/dcrlnd/keychain"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwallet"
 	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/blindedpath"
+	"github.com/decred/dcrlnd/routing/route"
 	sphinx "github.com/decred/lightning-onion/v3"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -144,6 +151,165 @@ func TestHtlcIncomingResolverExitSettle(t *testing.T) {
 	}
 }
 
+// TestHtlcIncomingResolverExitBlinded tests that the payload of an exit hop
+// htlc that is part of a blinded route, of which we aren't the introduction
+// node, can be decoded after the htlc was stored on a closed channel.
+func TestHtlcIncomingResolverExitBlinded(t *testing.T) {
+	t.Parallel()
+	defer timeout(t)()
+
+	relayKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	nodeKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	pathKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	// Create a blinded route to our node through the relay, which derives
+	// the blinding point that is handed to us along with the htlc.
+	pathID := [32]byte{1, 2, 3}
+	recipientData, err := record.EncodeBlindedRouteData(
+		&record.BlindedRouteData{PathID: pathID[:]},
+	)
+	require.NoError(t, err)
+
+	path, err := blindedpath.BuildBlindedPath(
+		pathKey, []*blindedpath.HopInfo{
+			{NodePub: relayKey.PubKey()},
+			{NodePub: nodeKey.PubKey(), PlainText: recipientData},
+		},
+	)
+	require.NoError(t, err)
+
+	blindingPoint, err := blindedpath.NextBlindingPoint(
+		&keychain.PrivKeyECDH{PrivKey: relayKey}, path.BlindingPoint,
+	)
+	require.NoError(t, err)
+
+	// Create the onion that the relay forwards to our blinded node ID.
+	amt := lnwire.MilliAtom(testHtlcAmount)
+	rt := &route.Route{
+		TotalTimeLock: testHtlcExpiry,
+		TotalAmount:   amt,
+		Hops: []*route.Hop{{
+			PubKeyBytes: route.NewVertex(
+				path.BlindedHops[1].BlindedNodePub,
+			),
+			AmtToForward:     amt,
+			OutgoingTimeLock: testHtlcExpiry,
+			EncryptedData:    path.BlindedHops[1].CipherText,
+			TotalAmtMAtoms:   amt,
+		}},
+	}
+	sphinxPath, err := rt.ToSphinxPath()
+	require.NoError(t, err)
+
+	onion, err := sphinx.NewOnionPacket(
+		sphinxPath, sessionKey, testResHash[:],
+		sphinx.DeterministicPacketFiller,
+	)
+	require.NoError(t, err)
+
+	var onionBlob bytes.Buffer
+	require.NoError(t, onion.Encode(&onionBlob))
+
+	// Store the htlc the way it is stored on the commitment of a closed
+	// channel, so that the resolver is handed the htlc read back from
+	// disk.
+	var b bytes.Buffer
+	err = channeldb.SerializeHtlcs(&b, channeldb.HTLC{
+		RHash:         testResHash,
+		Amt:           amt,
+		RefundTimeout: testHtlcExpiry,
+		Incoming:      true,
+		OnionBlob:     onionBlob.Bytes(),
+		BlindingPoint: blindingPoint,
+	})
+	require.NoError(t, err)
+
+	htlcs, err := channeldb.DeserializeHtlcs(&b)
+	require.NoError(t, err)
+	require.Len(t, htlcs, 1)
+
+	nodeKeyECDH := &keychain.PrivKeyECDH{PrivKey: nodeKey}
+	sphinxRouter := sphinx.NewRouter(
+		nodeKeyECDH, chaincfg.SimNetParams(),
+		sphinx.NewMemoryReplayLog(),
+	)
+	require.NoError(t, sphinxRouter.Start())
+	defer sphinxRouter.Stop()
+
+	ctx := newIncomingResolverTestContext(t, true)
+	ctx.resolver.OnionProcessor = hop.NewOnionProcessor(
+		sphinxRouter, nodeKeyECDH,
+	)
+	ctx.resolver.Supplement(htlcs[0])
+	ctx.registry.notifyResolution = invoices.NewSettleResolution(
+		testResPreimage, testResCircuitKey, testAcceptHeight,
+		invoices.ResultSettled,
+	)
+
+	ctx.resolve()
+
+	// The path id of the blinded route must be exposed to the registry as
+	// the payment address, so that the htlc can be matched against the
+	// invoice it pays to.
+	data := <-ctx.registry.notifyChan
+	mpp := data.payload.MultiPath()
+	require.NotNil(t, mpp)
+	require.Equal(t, pathID, mpp.PaymentAddr())
+	require.Equal(t, amt, mpp.TotalMAtoms())
+
+	ctx.waitForResult(true)
+}
+
+// TestHtlcIncomingResolverExitNoInvoicePreimageKnown tests resolution of an
+// exit hop htlc without an invoice, for which the preimage is already known
+// initially. This is the case for htlcs that we forwarded as a trampoline node
+// if the outgoing payment settled before the resolver started.
+func TestHtlcIncomingResolverExitNoInvoicePreimageKnown(t *testing.T) {
+	t.Parallel()
+	defer timeout(t)()
+
+	ctx := newIncomingResolverTestContext(t, true)
+	ctx.registry.notifyResolution = invoices.NewFailResolution(
+		testResCircuitKey, testAcceptHeight,
+		invoices.ResultInvoiceNotFound,
+	)
+	ctx.witnessBeacon.lookupPreimage[testResHash] = testResPreimage
+
+	ctx.resolve()
+	<-ctx.registry.notifyChan
+	ctx.waitForResult(true)
+}
+
+// TestHtlcIncomingResolverExitNoInvoiceContestedSuccess tests resolution of
+// an exit hop htlc without an invoice, for which the preimage becomes known
+// after the resolver has been started. This is the case for htlcs that we
+// forwarded as a trampoline node if the outgoing payment is still in flight
+// when the resolver starts.
+func TestHtlcIncomingResolverExitNoInvoiceContestedSuccess(t *testing.T) {
+	t.Parallel()
+	defer timeout(t)()
+
+	ctx := newIncomingResolverTestContext(t, true)
+	ctx.registry.notifyResolution = invoices.NewFailResolution(
+		testResCircuitKey, testAcceptHeight,
+		invoices.ResultInvoiceNotFound,
+	)
+
+	ctx.resolve()
+	<-ctx.registry.notifyChan
+
+	// Simulate a new block coming in. HTLC is not yet expired.
+	ctx.notifyEpoch(testInitialBlockHeight + 1)
+
+	ctx.witnessBeacon.preImageUpdates <- testResPreimage
+	ctx.waitForResult(true)
+}
+
 // TestHtlcIncomingResolverExitCancel tests resolution of an exit hop htlc for
 // an invoice that is already canceled when the resolver starts.
 func TestHtlcIncomingResolverExitCancel(t *testing.T) {
@@ -280,8 +446,8 @@ type mockOnionProcessor struct {
 	offeredOnionBlob []byte
 }
 
-func (o *mockOnionProcessor) ReconstructHopIterator(r io.Reader, rHash []byte) (
-	hop.Iterator, error) {
+func (o *mockOnionProcessor) ReconstructHopIterator(r io.Reader, rHash []byte,
+	_ hop.ReconstructBlindingInfo) (hop.Iterator, error) {
 
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
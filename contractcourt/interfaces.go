@@ -37,8 +37,10 @@ type Registry interface {
 // OnionProcessor is an interface used to decode onion blobs.
 type OnionProcessor interface {
 	// ReconstructHopIterator attempts to decode a valid sphinx packet from
-	// the passed io.Reader instance.
-	ReconstructHopIterator(r io.Reader, rHash []byte) (hop.Iterator, error)
+	// the passed io.Reader instance, using the blinding info to process
+	// htlcs that are part of a blinded route.
+	ReconstructHopIterator(r io.Reader, rHash []byte,
+		blindingInfo hop.ReconstructBlindingInfo) (hop.Iterator, error)
 }
 
 // UtxoSweeper defines the sweep functions that contract court requires.
@@ -13,6 +13,7 @@ type notifyExitHopData struct {
 	hodlChan      chan<- interface{}
 	expiry        uint32
 	currentHeight int32
+	payload       invoices.Payload
 }
 
 type mockRegistry struct {
@@ -32,6 +33,7 @@ func (r *mockRegistry) NotifyExitHopHtlc(payHash lntypes.Hash,
 		paidAmount:    paidAmount,
 		expiry:        expiry,
 		currentHeight: currentHeight,
+		payload:       payload,
 	}
 
 	return r.notifyResolution, r.notifyErr
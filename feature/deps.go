@@ -58,6 +58,12 @@ var deps = depDesc{
 	lnwire.AnchorsOptional: {
 		lnwire.StaticRemoteKeyOptional: {},
 	},
+	lnwire.AMPOptional: {
+		lnwire.PaymentAddrOptional: {},
+	},
+	lnwire.TrampolineRoutingOptional: {
+		lnwire.PaymentAddrOptional: {},
+	},
 }
 
 // ValidateDeps asserts that a feature vector sets all features and their
@@ -23,6 +23,10 @@ type Config struct {
 
 	// NoWumbo unsets any bits signalling support for wumbo channels.
 	NoWumbo bool
+
+	// NoTrampoline unsets any bits signalling support for acting as a
+	// trampoline node.
+	NoTrampoline bool
 }
 
 // Manager is responsible for generating feature vectors for different requested
@@ -77,6 +81,8 @@ func newManager(cfg Config, desc setDesc) (*Manager, error) {
 			raw.Unset(lnwire.PaymentAddrRequired)
 			raw.Unset(lnwire.MPPOptional)
 			raw.Unset(lnwire.MPPRequired)
+			raw.Unset(lnwire.TrampolineRoutingOptional)
+			raw.Unset(lnwire.TrampolineRoutingRequired)
 		}
 		if cfg.NoStaticRemoteKey {
 			raw.Unset(lnwire.StaticRemoteKeyOptional)
@@ -90,6 +96,10 @@ func newManager(cfg Config, desc setDesc) (*Manager, error) {
 			raw.Unset(lnwire.WumboChannelsOptional)
 			raw.Unset(lnwire.WumboChannelsRequired)
 		}
+		if cfg.NoTrampoline {
+			raw.Unset(lnwire.TrampolineRoutingOptional)
+			raw.Unset(lnwire.TrampolineRoutingRequired)
+		}
 
 		// Ensure that all of our feature sets properly set any
 		// dependent features.
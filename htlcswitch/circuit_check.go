@@ -0,0 +1,174 @@
+package htlcswitch
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/htlcswitch/hop"
+)
+
+// CheckCircuitMap walks the circuits and keystones persisted by the circuit
+// map in the given database and returns the orphaned records it finds. A
+// circuit is orphaned if its incoming channel and its outgoing channel, if it
+// has any, are both fully closed or unknown to the database, as no link will
+// ever resolve it. If repair is true, orphaned circuits along with their
+// keystones are removed, as well as stray keystones of locally initiated
+// payments.
+//
+// NOTE: This must not be called while the switch is running.
+func CheckCircuitMap(db *channeldb.DB,
+	repair bool) ([]*channeldb.Inconsistency, error) {
+
+	lifecycles, err := db.FetchChanLifecycles()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		inconsistencies []*channeldb.Inconsistency
+		staleKeys       [][]byte
+		staleKeystones  [][]byte
+	)
+	err = kvdb.View(db, func(tx kvdb.RTx) error {
+		circuitBkt := tx.ReadBucket(circuitAddKey)
+		keystoneBkt := tx.ReadBucket(circuitKeystoneKey)
+		if circuitBkt == nil || keystoneBkt == nil {
+			return nil
+		}
+
+		// Index the outgoing keys of all keystones by their incoming
+		// key, so they can be matched with their circuits.
+		keystones := make(map[CircuitKey]CircuitKey)
+		err := keystoneBkt.ForEach(func(k, v []byte) error {
+			var inKey, outKey CircuitKey
+			if err := inKey.SetBytes(v); err != nil {
+				return err
+			}
+			if err := outKey.SetBytes(k); err != nil {
+				return err
+			}
+
+			keystones[inKey] = outKey
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		circuits := make(map[CircuitKey]struct{})
+		err = circuitBkt.ForEach(func(k, _ []byte) error {
+			var inKey CircuitKey
+			if err := inKey.SetBytes(k); err != nil {
+				return err
+			}
+			circuits[inKey] = struct{}{}
+
+			// Circuits of locally initiated payments are kept, as
+			// their result may still be awaited.
+			if inKey.ChanID == hop.Source {
+				return nil
+			}
+
+			inLifecycle := lifecycles[inKey.ChanID]
+			if !inLifecycle.Resolved() {
+				return nil
+			}
+
+			desc := fmt.Sprintf("circuit %v of %v channel", inKey,
+				inLifecycle)
+
+			outKey, ok := keystones[inKey]
+			if ok {
+				outLifecycle := lifecycles[outKey.ChanID]
+				if !outLifecycle.Resolved() {
+					return nil
+				}
+
+				desc += fmt.Sprintf(" forwarded to %v of %v "+
+					"channel", outKey, outLifecycle)
+				staleKeystones = append(
+					staleKeystones, outKey.Bytes(),
+				)
+			}
+
+			inconsistencies = append(
+				inconsistencies, &channeldb.Inconsistency{
+					Description: desc,
+					Repairable:  true,
+				},
+			)
+			staleKeys = append(staleKeys, inKey.Bytes())
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Finally, report the keystones without a circuit. Only those
+		// of locally initiated payments are removed, as is done when
+		// the circuit map is restored.
+		for inKey, outKey := range keystones {
+			if _, ok := circuits[inKey]; ok {
+				continue
+			}
+
+			repairable := outKey.ChanID == hop.Source
+			inconsistencies = append(
+				inconsistencies, &channeldb.Inconsistency{
+					Description: fmt.Sprintf("stray "+
+						"keystone %v -> %v", inKey,
+						outKey),
+					Repairable: repairable,
+				},
+			)
+			if repairable {
+				staleKeystones = append(
+					staleKeystones, outKey.Bytes(),
+				)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !repair || len(staleKeys)+len(staleKeystones) == 0 {
+		return inconsistencies, nil
+	}
+
+	err = kvdb.Update(db, func(tx kvdb.RwTx) error {
+		circuitBkt := tx.ReadWriteBucket(circuitAddKey)
+		keystoneBkt := tx.ReadWriteBucket(circuitKeystoneKey)
+		if circuitBkt == nil || keystoneBkt == nil {
+			return ErrCorruptedCircuitMap
+		}
+
+		for _, k := range staleKeystones {
+			if err := keystoneBkt.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, k := range staleKeys {
+			if err := circuitBkt.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, inconsistency := range inconsistencies {
+		if inconsistency.Repairable {
+			inconsistency.Repaired = true
+		}
+	}
+
+	return inconsistencies, nil
+}
@@ -0,0 +1,95 @@
+package htlcswitch_test
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/htlcswitch/hop"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckCircuitMap asserts that circuits between channels unknown to the
+// database are reported and removed on repair, while circuits of locally
+// initiated payments are kept.
+func TestCheckCircuitMap(t *testing.T) {
+	t.Parallel()
+
+	var (
+		chan1 = lnwire.NewShortChanIDFromInt(1)
+		chan2 = lnwire.NewShortChanIDFromInt(2)
+	)
+
+	cfg, circuitMap := newCircuitMap(t)
+
+	halfCircuit := &htlcswitch.PaymentCircuit{
+		Incoming: htlcswitch.CircuitKey{
+			ChanID: chan1,
+			HtlcID: 1,
+		},
+		ErrorEncrypter: testExtracter,
+	}
+	fullCircuit := &htlcswitch.PaymentCircuit{
+		Incoming: htlcswitch.CircuitKey{
+			ChanID: chan1,
+			HtlcID: 2,
+		},
+		ErrorEncrypter: testExtracter,
+	}
+	localCircuit := &htlcswitch.PaymentCircuit{
+		Incoming: htlcswitch.CircuitKey{
+			ChanID: hop.Source,
+			HtlcID: 3,
+		},
+	}
+
+	_, err := circuitMap.CommitCircuits(
+		halfCircuit, fullCircuit, localCircuit,
+	)
+	require.NoError(t, err)
+
+	err = circuitMap.OpenCircuits(
+		htlcswitch.Keystone{
+			InKey: fullCircuit.Incoming,
+			OutKey: htlcswitch.CircuitKey{
+				ChanID: chan2,
+				HtlcID: 1,
+			},
+		},
+		htlcswitch.Keystone{
+			InKey: localCircuit.Incoming,
+			OutKey: htlcswitch.CircuitKey{
+				ChanID: chan2,
+				HtlcID: 2,
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	// Without repair, the orphaned circuits are only reported.
+	inconsistencies, err := htlcswitch.CheckCircuitMap(cfg.DB, false)
+	require.NoError(t, err)
+	require.Len(t, inconsistencies, 2)
+	for _, inconsistency := range inconsistencies {
+		require.True(t, inconsistency.Repairable)
+		require.False(t, inconsistency.Repaired)
+	}
+
+	inconsistencies, err = htlcswitch.CheckCircuitMap(cfg.DB, true)
+	require.NoError(t, err)
+	require.Len(t, inconsistencies, 2)
+	for _, inconsistency := range inconsistencies {
+		require.True(t, inconsistency.Repaired)
+	}
+
+	inconsistencies, err = htlcswitch.CheckCircuitMap(cfg.DB, false)
+	require.NoError(t, err)
+	require.Empty(t, inconsistencies)
+
+	// Only the circuit of the local payment survives a restart.
+	_, circuitMap = restartCircuitMap(t, cfg)
+	require.Nil(t, circuitMap.LookupCircuit(halfCircuit.Incoming))
+	require.Nil(t, circuitMap.LookupCircuit(fullCircuit.Incoming))
+	require.NotNil(t, circuitMap.LookupCircuit(localCircuit.Incoming))
+	require.Equal(t, 1, circuitMap.NumOpen())
+}
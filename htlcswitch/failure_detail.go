@@ -54,6 +54,22 @@ const (
 	// OutgoingFailureForwardsDisabled is returned when the switch is
 	// configured to disallow forwards.
 	OutgoingFailureForwardsDisabled
+
+	// OutgoingFailureDustExposure is returned when a htlc is failed
+	// because it would take the total value of the dust htlcs on a
+	// commitment of the channel above our configured maximum.
+	OutgoingFailureDustExposure
+
+	// OutgoingFailureRateLimited is returned when a htlc is failed because
+	// its incoming channel exceeded the rate at which we forward htlcs
+	// for channels without a good reputation.
+	OutgoingFailureRateLimited
+
+	// OutgoingFailureInsufficientReputation is returned when a htlc is
+	// failed because its incoming channel lacks the reputation to use the
+	// slots and liquidity of the outgoing channel that are reserved for
+	// channels with a good reputation.
+	OutgoingFailureInsufficientReputation
 )
 
 // FailureString returns the string representation of a failure detail.
@@ -91,6 +107,15 @@ func (fd OutgoingFailure) FailureString() string {
 	case OutgoingFailureForwardsDisabled:
 		return "node configured to disallow forwards"
 
+	case OutgoingFailureDustExposure:
+		return "dust exposure exceeds maximum"
+
+	case OutgoingFailureRateLimited:
+		return "incoming channel exceeds forwarding rate limit"
+
+	case OutgoingFailureInsufficientReputation:
+		return "incoming channel has insufficient reputation"
+
 	default:
 		return "unknown failure detail"
 	}
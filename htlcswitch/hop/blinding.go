@@ -0,0 +1,182 @@
+package hop
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/blindedpath"
+	sphinx "github.com/decred/lightning-onion/v3"
+)
+
+// feeRateParts is the total number of parts that a proportional fee rate is
+// expressed in.
+const feeRateParts = 1e6
+
+// BlindingKit holds the information required to process the payload of a hop
+// within a blinded route.
+type BlindingKit struct {
+	// NodeKey is the key of our node, used to decrypt the data that the
+	// creator of the blinded route encrypted for us.
+	NodeKey sphinx.SingleKeyECDH
+
+	// UpdateAddBlinding is the blinding point that was received in the
+	// update_add_htlc message. It is nil if we are the introduction node of
+	// the blinded route, as the sender then includes the blinding point in
+	// our onion payload.
+	UpdateAddBlinding *secp256k1.PublicKey
+
+	// IncomingCltv is the expiry of the incoming HTLC.
+	IncomingCltv uint32
+
+	// IncomingAmount is the amount of the incoming HTLC.
+	IncomingAmount lnwire.MilliAtom
+}
+
+// DecryptAndValidateFwdInfo decrypts the data of a hop within a blinded route
+// and populates the forwarding information of the payload with it. Final hops
+// have the path id set by the creator of the route exposed as the payment
+// address of an MPP record, so that the payment can be matched against the
+// invoice it was created for.
+func (b *BlindingKit) DecryptAndValidateFwdInfo(payload *Payload,
+	isFinalHop bool) error {
+
+	invalidErr := func(violation PayloadViolation) error {
+		return ErrInvalidPayload{
+			Type:      record.EncryptedDataOnionType,
+			Violation: violation,
+			FinalHop:  isFinalHop,
+		}
+	}
+
+	// The blinding point is either handed to us by the previous hop, or
+	// included in our payload if we're the introduction node. Exactly one
+	// of them must be present.
+	blindingPoint := b.UpdateAddBlinding
+	switch {
+	case blindingPoint != nil && payload.BlindingPoint != nil:
+		return ErrInvalidPayload{
+			Type:      record.BlindingPointOnionType,
+			Violation: IncludedViolation,
+			FinalHop:  isFinalHop,
+		}
+
+	case blindingPoint == nil && payload.BlindingPoint == nil:
+		return ErrInvalidPayload{
+			Type:      record.BlindingPointOnionType,
+			Violation: OmittedViolation,
+			FinalHop:  isFinalHop,
+		}
+
+	case blindingPoint == nil:
+		blindingPoint = payload.BlindingPoint
+	}
+
+	if len(payload.EncryptedData) == 0 {
+		return invalidErr(OmittedViolation)
+	}
+
+	plainText, err := blindedpath.DecryptBlindedHopData(
+		b.NodeKey, blindingPoint, payload.EncryptedData,
+	)
+	if err != nil {
+		log.Debugf("Unable to decrypt blinded hop data: %v", err)
+		return invalidErr(InvalidViolation)
+	}
+
+	data, err := record.DecodeBlindedRouteData(bytes.NewReader(plainText))
+	if err != nil {
+		log.Debugf("Unable to decode blinded hop data: %v", err)
+		return invalidErr(InvalidViolation)
+	}
+
+	// Enforce the constraints the creator of the route placed on the
+	// HTLCs that we receive.
+	if data.Constraints != nil {
+		if b.IncomingCltv > data.Constraints.MaxCltvExpiry {
+			return invalidErr(InvalidViolation)
+		}
+
+		if b.IncomingAmount < data.Constraints.HtlcMinimumMAtoms {
+			return invalidErr(InvalidViolation)
+		}
+	}
+
+	if isFinalHop {
+		return validateFinalHop(payload, data)
+	}
+
+	// As an intermediate hop, the next channel and our relay policy must
+	// be part of the encrypted data.
+	if data.ShortChannelID == nil || data.RelayInfo == nil {
+		return invalidErr(OmittedViolation)
+	}
+
+	relay := data.RelayInfo
+	if b.IncomingAmount < relay.BaseFee ||
+		b.IncomingCltv < uint32(relay.CltvExpiryDelta) {
+
+		return invalidErr(InvalidViolation)
+	}
+
+	nextBlinding := data.NextBlindingOverride
+	if nextBlinding == nil {
+		nextBlinding, err = blindedpath.NextBlindingPoint(
+			b.NodeKey, blindingPoint,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	payload.FwdInfo.NextHop = *data.ShortChannelID
+	payload.FwdInfo.AmountToForward = forwardingAmount(
+		b.IncomingAmount, relay,
+	)
+	payload.FwdInfo.OutgoingCTLV = b.IncomingCltv -
+		uint32(relay.CltvExpiryDelta)
+	payload.FwdInfo.NextBlinding = nextBlinding
+
+	return nil
+}
+
+// validateFinalHop checks the decrypted data of the final hop of a blinded
+// route against the payload given to us by the sender.
+func validateFinalHop(payload *Payload,
+	data *record.BlindedRouteData) error {
+
+	var paymentAddr [32]byte
+	if data.ShortChannelID != nil || len(data.PathID) != len(paymentAddr) {
+		return ErrInvalidPayload{
+			Type:      record.EncryptedDataOnionType,
+			Violation: InvalidViolation,
+			FinalHop:  true,
+		}
+	}
+	copy(paymentAddr[:], data.PathID)
+
+	payload.MPP = record.NewMPP(payload.TotalAmtMAtoms, paymentAddr)
+
+	return nil
+}
+
+// forwardingAmount returns the amount that should be forwarded by a hop that
+// received the given amount, according to its relay policy. The amount is
+// rounded up, so that it matches the amount computed by senders that add the
+// rounded down proportional fee to the amount they want to be forwarded.
+func forwardingAmount(incoming lnwire.MilliAtom,
+	relay *record.PaymentRelayInfo) lnwire.MilliAtom {
+
+	// amt = ceil((incoming - base) * 1e6 / (1e6 + rate))
+	num := new(big.Int).SetUint64(uint64(incoming - relay.BaseFee))
+	num.Mul(num, big.NewInt(feeRateParts))
+
+	denom := big.NewInt(feeRateParts + int64(relay.FeeRate))
+	num.Add(num, denom)
+	num.Sub(num, big.NewInt(1))
+	num.Div(num, denom)
+
+	return lnwire.MilliAtom(num.Uint64())
+}
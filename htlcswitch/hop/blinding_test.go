@@ -0,0 +1,135 @@
+package hop
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/blindedpath"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlindingKitFwdInfo tests that the hops of a blinded route are able to
+// recover their forwarding instructions from the data encrypted for them.
+func TestBlindingKitFwdInfo(t *testing.T) {
+	t.Parallel()
+
+	relayKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	recipientKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	scid := lnwire.NewShortChanIDFromInt(1234)
+	relay := &record.PaymentRelayInfo{
+		CltvExpiryDelta: 40,
+		FeeRate:         1000,
+		BaseFee:         1000,
+	}
+	relayData, err := record.EncodeBlindedRouteData(&record.BlindedRouteData{
+		ShortChannelID: &scid,
+		RelayInfo:      relay,
+		Constraints: &record.PaymentConstraints{
+			MaxCltvExpiry:     1000,
+			HtlcMinimumMAtoms: 1000,
+		},
+	})
+	require.NoError(t, err)
+
+	var pathID [32]byte
+	pathID[0] = 1
+	recipientData, err := record.EncodeBlindedRouteData(
+		&record.BlindedRouteData{PathID: pathID[:]},
+	)
+	require.NoError(t, err)
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	path, err := blindedpath.BuildBlindedPath(
+		sessionKey, []*blindedpath.HopInfo{
+			{NodePub: relayKey.PubKey(), PlainText: relayData},
+			{NodePub: recipientKey.PubKey(), PlainText: recipientData},
+		},
+	)
+	require.NoError(t, err)
+
+	// The introduction node receives the blinding point in its payload.
+	relayKit := &BlindingKit{
+		NodeKey:        &keychain.PrivKeyECDH{PrivKey: relayKey},
+		IncomingCltv:   500,
+		IncomingAmount: 101100,
+	}
+	relayPayload := &Payload{
+		EncryptedData: path.BlindedHops[0].CipherText,
+		BlindingPoint: path.BlindingPoint,
+	}
+	err = relayKit.DecryptAndValidateFwdInfo(relayPayload, false)
+	require.NoError(t, err)
+
+	fwdInfo := relayPayload.FwdInfo
+	require.Equal(t, scid, fwdInfo.NextHop)
+	require.Equal(t, lnwire.MilliAtom(100000), fwdInfo.AmountToForward)
+	require.Equal(t, uint32(460), fwdInfo.OutgoingCTLV)
+	require.NotNil(t, fwdInfo.NextBlinding)
+
+	// Including the blinding point in both the payload and the
+	// update_add_htlc message is not allowed.
+	relayKit.UpdateAddBlinding = path.BlindingPoint
+	err = relayKit.DecryptAndValidateFwdInfo(&Payload{
+		EncryptedData: path.BlindedHops[0].CipherText,
+		BlindingPoint: path.BlindingPoint,
+	}, false)
+	require.Equal(t, ErrInvalidPayload{
+		Type:      record.BlindingPointOnionType,
+		Violation: IncludedViolation,
+	}, err)
+
+	// HTLCs that violate the constraints of the hop are rejected.
+	relayKit.IncomingCltv = 1001
+	err = relayKit.DecryptAndValidateFwdInfo(&Payload{
+		EncryptedData: path.BlindedHops[0].CipherText,
+	}, false)
+	require.Equal(t, ErrInvalidPayload{
+		Type:      record.EncryptedDataOnionType,
+		Violation: InvalidViolation,
+	}, err)
+
+	// The recipient receives the blinding point derived by the
+	// introduction node and exposes the path id as payment address.
+	recipientKit := &BlindingKit{
+		NodeKey:           &keychain.PrivKeyECDH{PrivKey: recipientKey},
+		UpdateAddBlinding: fwdInfo.NextBlinding,
+		IncomingCltv:      460,
+		IncomingAmount:    100000,
+	}
+	recipientPayload := &Payload{
+		EncryptedData:  path.BlindedHops[1].CipherText,
+		TotalAmtMAtoms: 200000,
+	}
+	err = recipientKit.DecryptAndValidateFwdInfo(recipientPayload, true)
+	require.NoError(t, err)
+
+	mpp := recipientPayload.MultiPath()
+	require.NotNil(t, mpp)
+	require.Equal(t, pathID, mpp.PaymentAddr())
+	require.Equal(t, lnwire.MilliAtom(200000), mpp.TotalMAtoms())
+}
+
+// TestForwardingAmount asserts that the amount forwarded by a blinded hop
+// matches the amount that the sender expects it to forward.
+func TestForwardingAmount(t *testing.T) {
+	t.Parallel()
+
+	relay := &record.PaymentRelayInfo{
+		FeeRate: 1234,
+		BaseFee: 1001,
+	}
+	for _, amt := range []lnwire.MilliAtom{0, 1, 999, 1000, 123456789} {
+		fee := relay.BaseFee +
+			amt*lnwire.MilliAtom(relay.FeeRate)/feeRateParts
+
+		require.Equal(t, amt, forwardingAmount(amt+fee, relay))
+	}
+}
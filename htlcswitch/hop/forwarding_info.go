@@ -1,6 +1,7 @@
 package hop
 
 import (
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrlnd/lnwire"
 )
 
@@ -26,4 +27,8 @@ type ForwardingInfo struct {
 	// OutgoingCTLV is the specified value of the CTLV timelock to be used
 	// in the outgoing HTLC.
 	OutgoingCTLV uint32
+
+	// NextBlinding is the blinding point that should be handed to the
+	// next hop when forwarding an HTLC that is part of a blinded route.
+	NextBlinding *secp256k1.PublicKey
 }
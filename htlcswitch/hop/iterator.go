@@ -7,6 +7,7 @@ import (
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/blindedpath"
 	sphinx "github.com/decred/lightning-onion/v3"
 )
 
@@ -46,16 +47,23 @@ type sphinxHopIterator struct {
 	// includes the information required to properly forward the packet to
 	// the next hop.
 	processedPacket *sphinx.ProcessedPacket
+
+	// blindingKit holds the information needed to process the payload of
+	// a hop within a blinded route. It is nil if the iterator wasn't
+	// created for a hop that may be part of a blinded route.
+	blindingKit *BlindingKit
 }
 
 // makeSphinxHopIterator converts a processed packet returned from a sphinx
 // router and converts it into an hop iterator for usage in the link.
 func makeSphinxHopIterator(ogPacket *sphinx.OnionPacket,
-	packet *sphinx.ProcessedPacket) *sphinxHopIterator {
+	packet *sphinx.ProcessedPacket,
+	blindingKit *BlindingKit) *sphinxHopIterator {
 
 	return &sphinxHopIterator{
 		ogPacket:        ogPacket,
 		processedPacket: packet,
+		blindingKit:     blindingKit,
 	}
 }
 
@@ -78,25 +86,49 @@ func (r *sphinxHopIterator) EncodeNextHop(w io.Writer) error {
 //
 // NOTE: Part of the HopIterator interface.
 func (r *sphinxHopIterator) HopPayload() (*Payload, error) {
+	var (
+		payload *Payload
+		err     error
+	)
 	switch r.processedPacket.Payload.Type {
 
 	// If this is the legacy payload, then we'll extract the information
 	// directly from the pre-populated ForwardingInstructions field.
 	case sphinx.PayloadLegacy:
 		fwdInst := r.processedPacket.ForwardingInstructions
-		return NewLegacyPayload(fwdInst), nil
+		payload = NewLegacyPayload(fwdInst)
 
 	// Otherwise, if this is the TLV payload, then we'll make a new stream
 	// to decode only what we need to make routing decisions.
 	case sphinx.PayloadTLV:
-		return NewPayloadFromReader(bytes.NewReader(
+		payload, err = NewPayloadFromReader(bytes.NewReader(
 			r.processedPacket.Payload.Payload,
 		))
+		if err != nil {
+			return nil, err
+		}
 
 	default:
 		return nil, fmt.Errorf("unknown sphinx payload type: %v",
 			r.processedPacket.Payload.Type)
 	}
+
+	// If we are part of a blinded route, the forwarding instructions are
+	// found in the data encrypted to us by the creator of the route.
+	kit := r.blindingKit
+	if kit == nil {
+		return payload, nil
+	}
+	if payload.EncryptedData == nil && kit.UpdateAddBlinding == nil {
+		return payload, nil
+	}
+
+	isFinalHop := r.processedPacket.Action == sphinx.ExitNode
+	if err := kit.DecryptAndValidateFwdInfo(payload, isFinalHop); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
 }
 
 // ExtractErrorEncrypter decodes and returns the ErrorEncrypter for this hop,
@@ -122,11 +154,20 @@ func (r *sphinxHopIterator) ExtractErrorEncrypter(
 // tests dependent from the sphinx internal parts.
 type OnionProcessor struct {
 	router *sphinx.Router
+
+	// nodeKey is the key of our node, used to process the onions of HTLCs
+	// that are part of a blinded route.
+	nodeKey sphinx.SingleKeyECDH
 }
 
 // NewOnionProcessor creates new instance of decoder.
-func NewOnionProcessor(router *sphinx.Router) *OnionProcessor {
-	return &OnionProcessor{router}
+func NewOnionProcessor(router *sphinx.Router,
+	nodeKey sphinx.SingleKeyECDH) *OnionProcessor {
+
+	return &OnionProcessor{
+		router:  router,
+		nodeKey: nodeKey,
+	}
 }
 
 // Start spins up the onion processor's sphinx router.
@@ -181,20 +222,52 @@ func (p *OnionProcessor) DecodeHopIterator(r io.Reader, rHash []byte,
 		}
 	}
 
-	return makeSphinxHopIterator(onionPkt, sphinxPacket), lnwire.CodeNone
+	return makeSphinxHopIterator(onionPkt, sphinxPacket, nil), lnwire.CodeNone
+}
+
+// ReconstructBlindingInfo holds the information about an HTLC that is needed
+// to reconstruct its hop iterator if the HTLC is part of a blinded route.
+type ReconstructBlindingInfo struct {
+	// IncomingAmt is the amount of the incoming HTLC.
+	IncomingAmt lnwire.MilliAtom
+
+	// IncomingExpiry is the expiry of the incoming HTLC.
+	IncomingExpiry uint32
+
+	// BlindingPoint is the blinding point that was received along with
+	// the HTLC, if it is part of a blinded route and we aren't its
+	// introduction node.
+	BlindingPoint *secp256k1.PublicKey
 }
 
 // ReconstructHopIterator attempts to decode a valid sphinx packet from the passed io.Reader
 // instance using the rHash as the associated data when checking the relevant
-// MACs during the decoding process.
-func (p *OnionProcessor) ReconstructHopIterator(r io.Reader, rHash []byte) (
-	Iterator, error) {
+// MACs during the decoding process. The blinding info is used to process the
+// onion of an HTLC that is part of a blinded route, exactly as it was
+// processed when the HTLC was received.
+func (p *OnionProcessor) ReconstructHopIterator(r io.Reader, rHash []byte,
+	blindingInfo ReconstructBlindingInfo) (Iterator, error) {
 
 	onionPkt := &sphinx.OnionPacket{}
 	if err := onionPkt.Decode(r); err != nil {
 		return nil, err
 	}
 
+	// If the HTLC is part of a blinded route, the onion was encrypted to
+	// our blinded node ID, so we'll blind its ephemeral key in order to
+	// derive the right shared secret.
+	var onionKey *secp256k1.PublicKey
+	if blindingInfo.BlindingPoint != nil {
+		var err error
+		onionKey = onionPkt.EphemeralKey
+		onionPkt.EphemeralKey, err = blindedpath.BlindOnionKey(
+			p.nodeKey, blindingInfo.BlindingPoint, onionKey,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Attempt to process the Sphinx packet. We include the payment hash of
 	// the HTLC as it's authenticated within the Sphinx packet itself as
 	// associated data in order to thwart attempts a replay attacks. In the
@@ -205,7 +278,21 @@ func (p *OnionProcessor) ReconstructHopIterator(r io.Reader, rHash []byte) (
 		return nil, err
 	}
 
-	return makeSphinxHopIterator(onionPkt, sphinxPacket), nil
+	if onionKey != nil {
+		err := p.unblindNextPacket(onionPkt, sphinxPacket, onionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	blindingKit := &BlindingKit{
+		NodeKey:           p.nodeKey,
+		UpdateAddBlinding: blindingInfo.BlindingPoint,
+		IncomingCltv:      blindingInfo.IncomingExpiry,
+		IncomingAmount:    blindingInfo.IncomingAmt,
+	}
+
+	return makeSphinxHopIterator(onionPkt, sphinxPacket, blindingKit), nil
 }
 
 // DecodeHopIteratorRequest encapsulates all date necessary to process an onion
@@ -215,6 +302,14 @@ type DecodeHopIteratorRequest struct {
 	OnionReader  io.Reader
 	RHash        []byte
 	IncomingCltv uint32
+
+	// IncomingAmount is the amount of the incoming HTLC, which hops within
+	// a blinded route use to compute the amount to forward.
+	IncomingAmount lnwire.MilliAtom
+
+	// BlindingPoint is the blinding point that was received along with
+	// the HTLC, if it is part of a blinded route.
+	BlindingPoint *secp256k1.PublicKey
 }
 
 // DecodeHopIteratorResponse encapsulates the outcome of a batched sphinx onion
@@ -246,6 +341,7 @@ func (p *OnionProcessor) DecodeHopIterators(id []byte,
 	var (
 		batchSize = len(reqs)
 		onionPkts = make([]sphinx.OnionPacket, batchSize)
+		onionKeys = make([]*secp256k1.PublicKey, batchSize)
 		resps     = make([]DecodeHopIteratorResponse, batchSize)
 	)
 
@@ -274,6 +370,23 @@ func (p *OnionProcessor) DecodeHopIterators(id []byte,
 			continue
 		}
 
+		// If the HTLC is part of a blinded route, the onion was
+		// encrypted to our blinded node ID, so we'll blind its
+		// ephemeral key in order to derive the right shared secret.
+		if req.BlindingPoint != nil {
+			onionKeys[i] = onionPkt.EphemeralKey
+			onionPkt.EphemeralKey, err = blindedpath.BlindOnionKey(
+				p.nodeKey, req.BlindingPoint,
+				onionPkt.EphemeralKey,
+			)
+			if err != nil {
+				log.Errorf("unable to blind onion key: %v",
+					err)
+				resp.FailCode = lnwire.CodeInvalidOnionKey
+				continue
+			}
+		}
+
 		err = tx.ProcessOnionPacket(
 			uint16(i), onionPkt, req.RHash, req.IncomingCltv,
 		)
@@ -353,14 +466,59 @@ func (p *OnionProcessor) DecodeHopIterators(id []byte,
 			continue
 		}
 
+		// The ephemeral key of the next hop was derived from the
+		// blinded key, so we'll derive it from the original one
+		// instead, as expected by the sender.
+		if onionKeys[i] != nil {
+			err := p.unblindNextPacket(
+				&onionPkts[i], &packets[i], onionKeys[i],
+			)
+			if err != nil {
+				log.Errorf("unable to process blinded onion "+
+					"packet: %v", err)
+				resp.FailCode = lnwire.CodeInvalidOnionKey
+				continue
+			}
+		}
+
 		// Finally, construct a hop iterator from our processed sphinx
 		// packet, simultaneously caching the original onion packet.
-		resp.HopIterator = makeSphinxHopIterator(&onionPkts[i], &packets[i])
+		blindingKit := &BlindingKit{
+			NodeKey:           p.nodeKey,
+			UpdateAddBlinding: reqs[i].BlindingPoint,
+			IncomingCltv:      reqs[i].IncomingCltv,
+			IncomingAmount:    reqs[i].IncomingAmount,
+		}
+		resp.HopIterator = makeSphinxHopIterator(
+			&onionPkts[i], &packets[i], blindingKit,
+		)
 	}
 
 	return resps, nil
 }
 
+// unblindNextPacket replaces the ephemeral key of the onion packet destined
+// to the next hop, which was derived from the blinded key of the processed
+// onion packet, with the key derived from the original ephemeral key.
+func (p *OnionProcessor) unblindNextPacket(onionPkt *sphinx.OnionPacket,
+	packet *sphinx.ProcessedPacket, onionKey *secp256k1.PublicKey) error {
+
+	if packet.Action == sphinx.ExitNode || packet.NextPacket == nil {
+		return nil
+	}
+
+	sharedSecret, err := p.nodeKey.ECDH(onionPkt.EphemeralKey)
+	if err != nil {
+		return err
+	}
+
+	packet.NextPacket.EphemeralKey = blindedpath.NextOnionKey(
+		onionKey, sharedSecret,
+	)
+
+	return nil
+}
+
 // ExtractErrorEncrypter takes an io.Reader which should contain the onion
 // packet as original received by a forwarding node and creates an
 // ErrorEncrypter instance using the derived shared secret. In the case that en
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/record"
 	"github.com/decred/dcrlnd/tlv"
@@ -27,6 +28,10 @@ const (
 	// RequiredViolation indicates that an unknown even type was found in
 	// the payload that we could not process.
 	RequiredViolation
+
+	// InvalidViolation indicates that a type was found in the payload, but
+	// its value could not be used to process the HTLC.
+	InvalidViolation
 )
 
 // String returns a human-readable description of the violation as a verb.
@@ -41,6 +46,9 @@ func (v PayloadViolation) String() string {
 	case RequiredViolation:
 		return "required"
 
+	case InvalidViolation:
+		return "invalid"
+
 	default:
 		return "unknown violation"
 	}
@@ -86,6 +94,27 @@ type Payload struct {
 	// a TLV onion payload.
 	MPP *record.MPP
 
+	// AMP holds the info provided in an option_amp record when parsed from
+	// a TLV onion payload.
+	AMP *record.AMP
+
+	// EncryptedData is the data encrypted to this hop by the creator of a
+	// blinded route. It is only set for hops within a blinded route.
+	EncryptedData []byte
+
+	// BlindingPoint is the blinding point handed to the introduction node
+	// of a blinded route by the sender.
+	BlindingPoint *secp256k1.PublicKey
+
+	// TotalAmtMAtoms is the total amount of a payment to a blinded route.
+	// It is only set for the final hop of a blinded route.
+	TotalAmtMAtoms lnwire.MilliAtom
+
+	// TrampolineOnion is the onion packet handed to a trampoline node by
+	// the sender, which instructs it how to forward the payment towards
+	// its destination. It is only set for the final hop of a route.
+	TrampolineOnion []byte
+
 	// customRecords are user-defined records in the custom type range that
 	// were included in the payload.
 	customRecords record.CustomSet
@@ -111,10 +140,15 @@ func NewLegacyPayload(f *sphinx.HopData) *Payload {
 // should correspond to the bytes encapsulated in a TLV onion payload.
 func NewPayloadFromReader(r io.Reader) (*Payload, error) {
 	var (
-		cid  uint64
-		amt  uint64
-		cltv uint32
-		mpp  = &record.MPP{}
+		cid           uint64
+		amt           uint64
+		cltv          uint32
+		mpp           = &record.MPP{}
+		amp           = &record.AMP{}
+		encryptedData []byte
+		blindingPoint *secp256k1.PublicKey
+		totalAmt      uint64
+		trampoline    []byte
 	)
 
 	tlvStream, err := tlv.NewStream(
@@ -122,6 +156,11 @@ func NewPayloadFromReader(r io.Reader) (*Payload, error) {
 		record.NewLockTimeRecord(&cltv),
 		record.NewNextHopIDRecord(&cid),
 		mpp.Record(),
+		amp.Record(),
+		record.NewBlindingPointRecord(&blindingPoint),
+		record.NewTrampolineOnionRecord(&trampoline),
+		record.NewTotalAmtMAtomsBlindedRecord(&totalAmt),
+		record.NewEncryptedDataRecord(&encryptedData),
 	)
 	if err != nil {
 		return nil, err
@@ -156,6 +195,12 @@ func NewPayloadFromReader(r io.Reader) (*Payload, error) {
 		mpp = nil
 	}
 
+	// If no AMP field was parsed, set the AMP field on the resulting
+	// payload to nil.
+	if _, ok := parsedTypes[record.AMPOnionType]; !ok {
+		amp = nil
+	}
+
 	// Filter out the custom records.
 	customRecords := NewCustomRecords(parsedTypes)
 
@@ -166,8 +211,13 @@ func NewPayloadFromReader(r io.Reader) (*Payload, error) {
 			AmountToForward: lnwire.MilliAtom(amt),
 			OutgoingCTLV:    cltv,
 		},
-		MPP:           mpp,
-		customRecords: customRecords,
+		MPP:             mpp,
+		AMP:             amp,
+		EncryptedData:   encryptedData,
+		BlindingPoint:   blindingPoint,
+		TotalAmtMAtoms:  lnwire.MilliAtom(totalAmt),
+		TrampolineOnion: trampoline,
+		customRecords:   customRecords,
 	}, nil
 }
 
@@ -197,12 +247,19 @@ func NewCustomRecords(parsedTypes tlv.TypeMap) record.CustomSet {
 func ValidateParsedPayloadTypes(parsedTypes tlv.TypeMap,
 	nextHop lnwire.ShortChannelID) error {
 
+	// Hops within a blinded route follow a different set of rules.
+	if isBlindedPayload(parsedTypes) {
+		return validateBlindedPayloadTypes(parsedTypes)
+	}
+
 	isFinalHop := nextHop == Exit
 
 	_, hasAmt := parsedTypes[record.AmtOnionType]
 	_, hasLockTime := parsedTypes[record.LockTimeOnionType]
 	_, hasNextHop := parsedTypes[record.NextHopOnionType]
 	_, hasMPP := parsedTypes[record.MPPOnionType]
+	_, hasAMP := parsedTypes[record.AMPOnionType]
+	_, hasTrampoline := parsedTypes[record.TrampolineOnionType]
 
 	switch {
 
@@ -239,17 +296,139 @@ func ValidateParsedPayloadTypes(parsedTypes tlv.TypeMap,
 			Violation: IncludedViolation,
 			FinalHop:  isFinalHop,
 		}
+
+	// Intermediate nodes should never receive AMP fields.
+	case !isFinalHop && hasAMP:
+		return ErrInvalidPayload{
+			Type:      record.AMPOnionType,
+			Violation: IncludedViolation,
+			FinalHop:  isFinalHop,
+		}
+
+	// Only the final hop of a route can be a trampoline node.
+	case !isFinalHop && hasTrampoline:
+		return ErrInvalidPayload{
+			Type:      record.TrampolineOnionType,
+			Violation: IncludedViolation,
+			FinalHop:  isFinalHop,
+		}
+
+	// An AMP record is only valid alongside an MPP record, which carries
+	// the payment address and total amount of the set.
+	case isFinalHop && hasAMP && !hasMPP:
+		return ErrInvalidPayload{
+			Type:      record.MPPOnionType,
+			Violation: OmittedViolation,
+			FinalHop:  true,
+		}
 	}
 
 	return nil
 }
 
+// isBlindedPayload returns true if any of the records that are specific to
+// hops within a blinded route was parsed.
+func isBlindedPayload(parsedTypes tlv.TypeMap) bool {
+	_, hasEncryptedData := parsedTypes[record.EncryptedDataOnionType]
+	_, hasBlindingPoint := parsedTypes[record.BlindingPointOnionType]
+	_, hasTotalAmt := parsedTypes[record.TotalAmtMAtomsBlindedType]
+
+	return hasEncryptedData || hasBlindingPoint || hasTotalAmt
+}
+
+// validateBlindedPayloadTypes checks the types parsed from the payload of a
+// hop within a blinded route. Intermediate hops of a blinded route learn how to
+// forward the HTLC from their encrypted data, so they must not receive any
+// forwarding parameters in the clear. The final hop is identified by the
+// presence of the amount to forward, and must be given the expiry and the
+// total amount of the payment as well.
+func validateBlindedPayloadTypes(parsedTypes tlv.TypeMap) error {
+	_, hasAmt := parsedTypes[record.AmtOnionType]
+	_, hasLockTime := parsedTypes[record.LockTimeOnionType]
+	_, hasNextHop := parsedTypes[record.NextHopOnionType]
+	_, hasMPP := parsedTypes[record.MPPOnionType]
+	_, hasAMP := parsedTypes[record.AMPOnionType]
+	_, hasEncryptedData := parsedTypes[record.EncryptedDataOnionType]
+	_, hasTotalAmt := parsedTypes[record.TotalAmtMAtomsBlindedType]
+	_, hasTrampoline := parsedTypes[record.TrampolineOnionType]
+
+	isFinalHop := hasAmt
+
+	var (
+		violatingType tlv.Type
+		violation     PayloadViolation
+	)
+	switch {
+
+	// All hops of a blinded route must include their encrypted data.
+	case !hasEncryptedData:
+		violatingType, violation = record.EncryptedDataOnionType,
+			OmittedViolation
+
+	// The next hop is always part of the encrypted data.
+	case hasNextHop:
+		violatingType, violation = record.NextHopOnionType,
+			IncludedViolation
+
+	// Payments to blinded routes don't use MPP records, the total amount
+	// is transmitted in its own record.
+	case hasMPP:
+		violatingType, violation = record.MPPOnionType,
+			IncludedViolation
+
+	// AMP isn't supported for payments to blinded routes.
+	case hasAMP:
+		violatingType, violation = record.AMPOnionType,
+			IncludedViolation
+
+	// Trampoline payments to blinded routes aren't supported.
+	case hasTrampoline:
+		violatingType, violation = record.TrampolineOnionType,
+			IncludedViolation
+
+	// The final hop must be given the expiry of the HTLC.
+	case isFinalHop && !hasLockTime:
+		violatingType, violation = record.LockTimeOnionType,
+			OmittedViolation
+
+	// The final hop must be given the total amount of the payment.
+	case isFinalHop && !hasTotalAmt:
+		violatingType, violation = record.TotalAmtMAtomsBlindedType,
+			OmittedViolation
+
+	// Intermediate hops derive the expiry from their encrypted data.
+	case !isFinalHop && hasLockTime:
+		violatingType, violation = record.LockTimeOnionType,
+			IncludedViolation
+
+	// Only the final hop may be given the total amount.
+	case !isFinalHop && hasTotalAmt:
+		violatingType, violation = record.TotalAmtMAtomsBlindedType,
+			IncludedViolation
+
+	default:
+		return nil
+	}
+
+	return ErrInvalidPayload{
+		Type:      violatingType,
+		Violation: violation,
+		FinalHop:  isFinalHop,
+	}
+}
+
 // MultiPath returns the record corresponding the option_mpp parsed from the
 // onion payload.
 func (h *Payload) MultiPath() *record.MPP {
 	return h.MPP
 }
 
+// AMPRecord returns the record corresponding with option_amp parsed from the
+// onion payload.
+func (h *Payload) AMPRecord() *record.AMP {
+	return h.AMP
+}
+
 // CustomRecords returns the custom tlv type records that were parsed from the
 // payload.
 func (h *Payload) CustomRecords() record.CustomSet {
@@ -0,0 +1,90 @@
+package hop
+
+import (
+	"io"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+// TrampolinePayload is the payload delivered to a trampoline node within the
+// trampoline onion. It instructs the trampoline node which node to forward the
+// payment to, and which amount and expiry the forwarded payment must deliver.
+type TrampolinePayload struct {
+	// AmountToForward is the amount that the outgoing node must receive.
+	AmountToForward lnwire.MilliAtom
+
+	// OutgoingCltv is the expiry that the HTLC received by the outgoing
+	// node must have.
+	OutgoingCltv uint32
+
+	// OutgoingNodeID is the node that the payment must be forwarded to.
+	OutgoingNodeID *secp256k1.PublicKey
+
+	// MPP holds the payment address and total amount of the payment to the
+	// outgoing node, if it is the final recipient.
+	MPP *record.MPP
+}
+
+// NewTrampolinePayloadFromReader parses the payload of a trampoline onion
+// from the passed io.Reader.
+func NewTrampolinePayloadFromReader(r io.Reader) (*TrampolinePayload, error) {
+	var (
+		amt            uint64
+		cltv           uint32
+		mpp            = &record.MPP{}
+		outgoingNodeID *secp256k1.PublicKey
+	)
+
+	tlvStream, err := tlv.NewStream(
+		record.NewAmtToFwdRecord(&amt),
+		record.NewLockTimeRecord(&cltv),
+		mpp.Record(),
+		record.NewOutgoingNodeIDRecord(&outgoingNodeID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := tlvStream.DecodeWithParsedTypes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// A trampoline node can't forward a payment without knowing the
+	// amount, the expiry and the node to forward it to.
+	for _, t := range []tlv.Type{
+		record.AmtOnionType, record.LockTimeOnionType,
+		record.OutgoingNodeIDOnionType,
+	} {
+		if _, ok := parsedTypes[t]; !ok {
+			return nil, ErrInvalidPayload{
+				Type:      t,
+				Violation: OmittedViolation,
+				FinalHop:  true,
+			}
+		}
+	}
+
+	violatingType := getMinRequiredViolation(parsedTypes)
+	if violatingType != nil {
+		return nil, ErrInvalidPayload{
+			Type:      *violatingType,
+			Violation: RequiredViolation,
+			FinalHop:  true,
+		}
+	}
+
+	if _, ok := parsedTypes[record.MPPOnionType]; !ok {
+		mpp = nil
+	}
+
+	return &TrampolinePayload{
+		AmountToForward: lnwire.MilliAtom(amt),
+		OutgoingCltv:    cltv,
+		OutgoingNodeID:  outgoingNodeID,
+		MPP:             mpp,
+	}, nil
+}
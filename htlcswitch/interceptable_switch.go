@@ -3,7 +3,9 @@ package htlcswitch
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/htlcswitch/hop"
 	"github.com/decred/dcrlnd/lntypes"
@@ -17,6 +19,64 @@ var (
 	ErrFwdNotExists = errors.New("forward does not exist")
 )
 
+// InterceptorDisconnectAction is the action taken on the held forwards once
+// the interceptor disconnects.
+type InterceptorDisconnectAction uint8
+
+const (
+	// InterceptorDisconnectResume resumes the held forwards, and forwards
+	// new htlcs without holding them while no interceptor is connected.
+	InterceptorDisconnectResume InterceptorDisconnectAction = iota
+
+	// InterceptorDisconnectFail fails the held forwards, and forwards new
+	// htlcs without holding them while no interceptor is connected.
+	InterceptorDisconnectFail
+
+	// InterceptorDisconnectHold keeps holding the held forwards along with
+	// all new forwards until an interceptor connects, which is then handed
+	// all of them.
+	InterceptorDisconnectHold
+)
+
+// String returns a human readable representation of the action.
+func (a InterceptorDisconnectAction) String() string {
+	switch a {
+	case InterceptorDisconnectResume:
+		return "resume"
+
+	case InterceptorDisconnectFail:
+		return "fail"
+
+	case InterceptorDisconnectHold:
+		return "hold"
+
+	default:
+		return fmt.Sprintf("unknown<%d>", uint8(a))
+	}
+}
+
+// InterceptableSwitchConfig houses the configuration of an
+// InterceptableSwitch.
+type InterceptableSwitchConfig struct {
+	// Switch is the underlying switch that handles the forwards that
+	// aren't intercepted or were resumed.
+	Switch *Switch
+
+	// Notifier is used to receive the new blocks, upon which the held
+	// forwards that are about to expire are failed.
+	Notifier chainntnfs.ChainNotifier
+
+	// DisconnectAction is the action taken on the held forwards once the
+	// interceptor disconnects.
+	DisconnectAction InterceptorDisconnectAction
+
+	// CltvInterceptDelta is the number of blocks before the expiry of the
+	// incoming htlc at which a held forward is failed automatically.
+	// Forwards that are already within this delta are failed instead of
+	// being intercepted.
+	CltvInterceptDelta uint32
+}
+
 // InterceptableSwitch is an implementation of ForwardingSwitch interface.
 // This implementation is used like a proxy that wraps the switch and
 // intercepts forward requests. A reference to the Switch is held in order
@@ -24,9 +84,18 @@ var (
 // Resume - forwards the original request to the switch as is.
 // Settle - routes UpdateFulfillHTLC to the originating link.
 // Fail - routes UpdateFailHTLC to the originating link.
+//
+// The held forwards are kept by the InterceptableSwitch itself, so depending
+// on the configured disconnect action they may outlive the interceptor and be
+// handed to the next one.
 type InterceptableSwitch struct {
+	started sync.Once
+	stopped sync.Once
+
 	sync.RWMutex
 
+	cfg *InterceptableSwitchConfig
+
 	// htlcSwitch is the underline switch
 	htlcSwitch *Switch
 
@@ -34,20 +103,193 @@ type InterceptableSwitch struct {
 	// an incoming htlc. It should return true if it is interested in handling
 	// it.
 	fwdInterceptor ForwardInterceptor
+
+	// holdForwards holds the intercepted forwards that aren't resolved
+	// yet, keyed by the circuit key of their incoming htlc.
+	holdForwards map[channeldb.CircuitKey]*interceptedForward
+
+	blockEpochStream *chainntnfs.BlockEpochEvent
+
+	wg   sync.WaitGroup
+	quit chan struct{}
 }
 
 // NewInterceptableSwitch returns an instance of InterceptableSwitch.
-func NewInterceptableSwitch(s *Switch) *InterceptableSwitch {
-	return &InterceptableSwitch{htlcSwitch: s}
+func NewInterceptableSwitch(
+	cfg *InterceptableSwitchConfig) *InterceptableSwitch {
+
+	return &InterceptableSwitch{
+		cfg:          cfg,
+		htlcSwitch:   cfg.Switch,
+		holdForwards: make(map[channeldb.CircuitKey]*interceptedForward),
+		quit:         make(chan struct{}),
+	}
 }
 
-// SetInterceptor sets the ForwardInterceptor to be used.
+// Start subscribes to the new blocks in order to fail the held forwards that
+// are about to expire.
+func (s *InterceptableSwitch) Start() error {
+	var err error
+	s.started.Do(func() {
+		var blockEpochStream *chainntnfs.BlockEpochEvent
+		blockEpochStream, err = s.cfg.Notifier.RegisterBlockEpochNtfn(
+			nil,
+		)
+		if err != nil {
+			return
+		}
+		s.blockEpochStream = blockEpochStream
+
+		s.wg.Add(1)
+		go s.expiryWatcher()
+	})
+
+	return err
+}
+
+// Stop stops the InterceptableSwitch. The held forwards are kept in the
+// switch's forwarding packages, so they're reforwarded on the next start.
+func (s *InterceptableSwitch) Stop() {
+	s.stopped.Do(func() {
+		close(s.quit)
+		s.wg.Wait()
+
+		if s.blockEpochStream != nil {
+			s.blockEpochStream.Cancel()
+		}
+	})
+}
+
+// expiryWatcher fails the held forwards that are about to expire upon each
+// new block.
+func (s *InterceptableSwitch) expiryWatcher() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case epoch, ok := <-s.blockEpochStream.Epochs:
+			if !ok {
+				return
+			}
+
+			s.failExpired(uint32(epoch.Height))
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// expiresSoon returns true if a forward of an incoming htlc with the given
+// expiry may no longer be held at the given height.
+func (s *InterceptableSwitch) expiresSoon(incomingExpiry,
+	height uint32) bool {
+
+	return incomingExpiry <= height+s.cfg.CltvInterceptDelta
+}
+
+// failExpired fails the held forwards that may no longer be held at the given
+// height.
+func (s *InterceptableSwitch) failExpired(height uint32) {
+	var expired []*interceptedForward
+
+	s.Lock()
+	for key, forward := range s.holdForwards {
+		if !s.expiresSoon(forward.packet.incomingTimeout, height) {
+			continue
+		}
+
+		delete(s.holdForwards, key)
+		expired = append(expired, forward)
+	}
+	s.Unlock()
+
+	for _, forward := range expired {
+		log.Debugf("Failing held forward %v expiring at height %v",
+			forward.packet.inKey(), forward.packet.incomingTimeout)
+
+		if err := forward.fail(); err != nil {
+			log.Errorf("Unable to fail expired held forward %v: %v",
+				forward.packet.inKey(), err)
+		}
+	}
+}
+
+// SetInterceptor sets the ForwardInterceptor to be used. Setting a nil
+// interceptor disconnects the current one, upon which the configured
+// disconnect action is taken on the held forwards. A new interceptor is handed
+// the forwards that are still held.
 func (s *InterceptableSwitch) SetInterceptor(
 	interceptor ForwardInterceptor) {
 
 	s.Lock()
-	defer s.Unlock()
 	s.fwdInterceptor = interceptor
+
+	held := make([]*interceptedForward, 0, len(s.holdForwards))
+	for _, forward := range s.holdForwards {
+		held = append(held, forward)
+	}
+
+	disconnectAction := s.cfg.DisconnectAction
+	if interceptor == nil && disconnectAction != InterceptorDisconnectHold {
+		s.holdForwards = make(
+			map[channeldb.CircuitKey]*interceptedForward,
+		)
+	}
+	s.Unlock()
+
+	if len(held) == 0 {
+		return
+	}
+
+	if interceptor == nil {
+		log.Infof("Interceptor disconnected, taking action %v on %d "+
+			"held forwards", disconnectAction, len(held))
+
+		if disconnectAction == InterceptorDisconnectHold {
+			return
+		}
+
+		for _, forward := range held {
+			if err := s.disconnectForward(forward); err != nil {
+				log.Errorf("Unable to resolve held forward "+
+					"%v: %v", forward.packet.inKey(), err)
+			}
+		}
+
+		return
+	}
+
+	// Hand the forwards that are still held to the new interceptor. This
+	// is done asynchronously, as the interceptor may only be ready to
+	// receive them once this call returns.
+	log.Infof("Interceptor connected, handing over %d held forwards",
+		len(held))
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for _, forward := range held {
+			if !interceptor(forward) {
+				return
+			}
+		}
+	}()
+}
+
+// disconnectForward takes the configured disconnect action on a forward that
+// was removed from the held forwards.
+func (s *InterceptableSwitch) disconnectForward(
+	forward *interceptedForward) error {
+
+	switch s.cfg.DisconnectAction {
+	case InterceptorDisconnectFail:
+		return forward.fail()
+
+	default:
+		return forward.resume()
+	}
 }
 
 // ForwardPackets attempts to forward the batch of htlcs through the
@@ -62,14 +304,17 @@ func (s *InterceptableSwitch) ForwardPackets(linkQuit chan struct{},
 	interceptor = s.fwdInterceptor
 	s.Unlock()
 
-	// Optimize for the case we don't have an interceptor.
-	if interceptor == nil {
+	// Optimize for the case we don't have an interceptor, unless the
+	// forwards must be held until one connects.
+	if interceptor == nil &&
+		s.cfg.DisconnectAction != InterceptorDisconnectHold {
+
 		return s.htlcSwitch.ForwardPackets(linkQuit, packets...)
 	}
 
 	var notIntercepted []*htlcPacket
 	for _, p := range packets {
-		if !s.interceptForward(p, interceptor, linkQuit) {
+		if !s.interceptForward(p, linkQuit) {
 			notIntercepted = append(notIntercepted, p)
 		}
 	}
@@ -81,7 +326,7 @@ func (s *InterceptableSwitch) ForwardPackets(linkQuit chan struct{},
 // are being checked for interception. It can be extended in the future given
 // the right use case.
 func (s *InterceptableSwitch) interceptForward(packet *htlcPacket,
-	interceptor ForwardInterceptor, linkQuit chan struct{}) bool {
+	linkQuit chan struct{}) bool {
 
 	switch htlc := packet.htlc.(type) {
 	case *lnwire.UpdateAddHTLC:
@@ -91,31 +336,113 @@ func (s *InterceptableSwitch) interceptForward(packet *htlcPacket,
 		}
 
 		intercepted := &interceptedForward{
-			linkQuit:   linkQuit,
-			htlc:       htlc,
-			packet:     packet,
-			htlcSwitch: s.htlcSwitch,
+			linkQuit:            linkQuit,
+			htlc:                htlc,
+			packet:              packet,
+			interceptableSwitch: s,
+		}
+
+		// A forward that is about to expire can't be held, so we fail
+		// it right away.
+		height := atomic.LoadUint32(&s.htlcSwitch.bestHeight)
+		if s.expiresSoon(packet.incomingTimeout, height) {
+			log.Debugf("Failing forward %v expiring at height %v "+
+				"instead of intercepting it", packet.inKey(),
+				packet.incomingTimeout)
+
+			if err := intercepted.fail(); err != nil {
+				log.Errorf("Unable to fail forward %v: %v",
+					packet.inKey(), err)
+			}
+
+			return true
+		}
+
+		return s.holdForward(intercepted)
+
+	default:
+		return false
+	}
+}
+
+// holdForward holds the intercepted forward and hands it to the interceptor.
+// It returns false if the forward must be handled by the switch instead.
+func (s *InterceptableSwitch) holdForward(forward *interceptedForward) bool {
+	key := forward.packet.inKey()
+
+	s.Lock()
+
+	// The incoming link may offer a forward again after it restarted. We
+	// keep the forward that is already held, which the interceptor knows
+	// of, but resolve it through the restarted link.
+	if held, ok := s.holdForwards[key]; ok {
+		held.linkQuit = forward.linkQuit
+		held.packet = forward.packet
+		held.htlc = forward.htlc
+		s.Unlock()
+
+		return true
+	}
+
+	s.holdForwards[key] = forward
+	interceptor := s.fwdInterceptor
+	s.Unlock()
+
+	// Without an interceptor, the forward is held until one connects.
+	if interceptor == nil || interceptor(forward) {
+		return true
+	}
+
+	// The interceptor couldn't take the forward as it is disconnecting,
+	// so we take the disconnect action on it, unless the disconnect
+	// already resolved it.
+	switch {
+	case s.cfg.DisconnectAction == InterceptorDisconnectHold:
+		return true
+
+	case !s.release(key):
+		return true
+
+	case s.cfg.DisconnectAction == InterceptorDisconnectFail:
+		if err := forward.fail(); err != nil {
+			log.Errorf("Unable to fail forward %v: %v", key, err)
 		}
+		return true
 
-		// If this htlc was intercepted, don't handle the forward.
-		return interceptor(intercepted)
 	default:
 		return false
 	}
 }
 
+// release removes the forward with the given incoming circuit key from the
+// held forwards, returning false if it isn't held.
+func (s *InterceptableSwitch) release(key channeldb.CircuitKey) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.holdForwards[key]; !ok {
+		return false
+	}
+	delete(s.holdForwards, key)
+
+	return true
+}
+
 // interceptedForward implements the InterceptedForward interface.
 // It is passed from the switch to external interceptors that are interested
 // in holding forwards and resolve them manually.
 type interceptedForward struct {
-	linkQuit   chan struct{}
-	htlc       *lnwire.UpdateAddHTLC
-	packet     *htlcPacket
-	htlcSwitch *Switch
+	linkQuit            chan struct{}
+	htlc                *lnwire.UpdateAddHTLC
+	packet              *htlcPacket
+	interceptableSwitch *InterceptableSwitch
 }
 
 // Packet returns the intercepted htlc packet.
 func (f *interceptedForward) Packet() InterceptedPacket {
+	f.interceptableSwitch.RLock()
+	defer f.interceptableSwitch.RUnlock()
+
 	return InterceptedPacket{
 		IncomingCircuit: channeldb.CircuitKey{
 			ChanID: f.packet.incomingChanID,
@@ -128,23 +455,50 @@ func (f *interceptedForward) Packet() InterceptedPacket {
 		IncomingAmount: f.packet.incomingAmount,
 		IncomingExpiry: f.packet.incomingTimeout,
 		CustomRecords:  f.packet.customRecords,
+		AutoFailHeight: f.packet.incomingTimeout -
+			f.interceptableSwitch.cfg.CltvInterceptDelta,
 	}
 }
 
 // Resume resumes the default behavior as if the packet was not intercepted.
 func (f *interceptedForward) Resume() error {
-	return f.htlcSwitch.ForwardPackets(f.linkQuit, f.packet)
+	return f.ResumeModified(lnwire.ShortChannelID{}, 0)
+}
+
+// ResumeModified resumes the default behavior with the given outgoing channel
+// and amount instead of the requested ones. A zero channel or amount leaves
+// the requested one unchanged.
+func (f *interceptedForward) ResumeModified(
+	outgoingChanID lnwire.ShortChannelID,
+	outgoingAmount lnwire.MilliAtom) error {
+
+	if outgoingAmount > f.packet.incomingAmount {
+		return fmt.Errorf("outgoing amount %v exceeds incoming "+
+			"amount %v", outgoingAmount, f.packet.incomingAmount)
+	}
+
+	if !f.interceptableSwitch.release(f.packet.inKey()) {
+		return ErrFwdNotExists
+	}
+
+	if outgoingChanID != (lnwire.ShortChannelID{}) {
+		f.packet.outgoingChanID = outgoingChanID
+	}
+	if outgoingAmount != 0 {
+		f.packet.amount = outgoingAmount
+		f.htlc.Amount = outgoingAmount
+	}
+
+	return f.resume()
 }
 
 // Fail forward a failed packet to the switch.
 func (f *interceptedForward) Fail() error {
-	reason, err := f.packet.obfuscator.EncryptFirstHop(lnwire.NewTemporaryChannelFailure(nil))
-	if err != nil {
-		return fmt.Errorf("failed to encrypt failure reason %v", err)
+	if !f.interceptableSwitch.release(f.packet.inKey()) {
+		return ErrFwdNotExists
 	}
-	return f.resolve(&lnwire.UpdateFailHTLC{
-		Reason: reason,
-	})
+
+	return f.fail()
 }
 
 // Settle forwards a settled packet to the switch.
@@ -152,11 +506,34 @@ func (f *interceptedForward) Settle(preimage lntypes.Preimage) error {
 	if !preimage.Matches(f.htlc.PaymentHash) {
 		return errors.New("preimage does not match hash")
 	}
+
+	if !f.interceptableSwitch.release(f.packet.inKey()) {
+		return ErrFwdNotExists
+	}
+
 	return f.resolve(&lnwire.UpdateFulfillHTLC{
 		PaymentPreimage: preimage,
 	})
 }
 
+// resume forwards the packet to the switch.
+func (f *interceptedForward) resume() error {
+	return f.interceptableSwitch.htlcSwitch.ForwardPackets(
+		f.linkQuit, f.packet,
+	)
+}
+
+// fail fails the packet back to the incoming link.
+func (f *interceptedForward) fail() error {
+	reason, err := f.packet.obfuscator.EncryptFirstHop(lnwire.NewTemporaryChannelFailure(nil))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt failure reason %v", err)
+	}
+	return f.resolve(&lnwire.UpdateFailHTLC{
+		Reason: reason,
+	})
+}
+
 // resolve is used for both Settle and Fail and forwards the message to the
 // switch.
 func (f *interceptedForward) resolve(message lnwire.Message) error {
@@ -170,5 +547,7 @@ func (f *interceptedForward) resolve(message lnwire.Message) error {
 		htlc:           message,
 		obfuscator:     f.packet.obfuscator,
 	}
-	return f.htlcSwitch.mailOrchestrator.Deliver(pkt.incomingChanID, pkt)
+	return f.interceptableSwitch.htlcSwitch.mailOrchestrator.Deliver(
+		pkt.incomingChanID, pkt,
+	)
 }
@@ -41,6 +41,22 @@ type InvoiceDatabase interface {
 	HodlUnsubscribeAll(subscriber chan<- interface{})
 }
 
+// TrampolineForwarder is an interface which represents the subsystem that
+// forwards payments on behalf of senders that handed us a trampoline onion.
+type TrampolineForwarder interface {
+	// ForwardHtlc starts forwarding the payment described by the
+	// trampoline onion of the passed htlc. The return value describes how
+	// the htlc should be resolved. If the htlc cannot be resolved
+	// immediately, the resolution is sent on the passed in resolutionChan
+	// once the forwarded payment completes.
+	ForwardHtlc(htlc *TrampolineHtlc,
+		resolutionChan chan<- interface{}) (invoices.HtlcResolution,
+		error)
+
+	// UnsubscribeAll unsubscribes from all htlc resolutions.
+	UnsubscribeAll(subscriber chan<- interface{})
+}
+
 // ChannelLink is an interface which represents the subsystem for managing the
 // incoming htlc requests, applying the changes to the channel, and also
 // propagating/forwarding it to htlc switch.
@@ -131,6 +147,10 @@ type ChannelLink interface {
 	// HTLC's which have been set to the over flow queue.
 	Bandwidth() lnwire.MilliAtom
 
+	// MaxOutgoingHtlcs returns the maximum number of htlcs that the remote
+	// peer accepts from us on this link.
+	MaxOutgoingHtlcs() uint16
+
 	// Stats return the statistics of channel link. Number of updates,
 	// total sent/received milli-atoms.
 	Stats() (uint64, lnwire.MilliAtom, lnwire.MilliAtom)
@@ -232,6 +252,10 @@ type InterceptedPacket struct {
 	// CustomRecords are user-defined records in the custom type range that
 	// were included in the payload.
 	CustomRecords record.CustomSet
+
+	// AutoFailHeight is the block height at which the forward is failed
+	// automatically if it is still held.
+	AutoFailHeight uint32
 }
 
 // InterceptedForward is passed to the ForwardInterceptor for every forwarded
@@ -248,6 +272,13 @@ type InterceptedForward interface {
 	// this htlc which usually means forward it.
 	Resume() error
 
+	// ResumeModified notifies the intention to resume an existing hold
+	// forward with the given outgoing channel and amount instead of the
+	// requested ones. A zero channel or amount leaves the requested one
+	// unchanged.
+	ResumeModified(outgoingChanID lnwire.ShortChannelID,
+		outgoingAmount lnwire.MilliAtom) error
+
 	// Settle notifies the intention to settle an existing hold
 	// forward with a given preimage.
 	Settle(lntypes.Preimage) error
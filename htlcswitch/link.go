@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/slog"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/decred/dcrlnd/lnwallet/chainfee"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/queue"
+	"github.com/decred/dcrlnd/record"
 	"github.com/decred/dcrlnd/ticker"
 	"github.com/go-errors/errors"
 )
@@ -61,6 +63,10 @@ const (
 	// a channel's commitment fee to be of its balance. This only applies to
 	// the initiator of the channel.
 	DefaultMaxLinkFeeAllocation float64 = 0.5
+
+	// DefaultMaxDustExposure is the default maximum total value of the
+	// dust htlcs we'll allow on each commitment of a channel.
+	DefaultMaxDustExposure dcrutil.Amount = 500_000
 )
 
 // ForwardingPolicy describes the set of constraints that a given ChannelLink
@@ -168,6 +174,10 @@ type ChannelLinkConfig struct {
 	// in thread-safe manner.
 	Registry InvoiceDatabase
 
+	// Trampoline forwards the payments of htlcs that carry a trampoline
+	// onion. If nil, such htlcs are failed.
+	Trampoline TrampolineForwarder
+
 	// PreimageCache is a global witness beacon that houses any new
 	// preimages discovered by other links. We'll use this to add new
 	// witnesses that we discover which will notify any sub-systems
@@ -270,6 +280,13 @@ type ChannelLinkConfig struct {
 	// initiator of the channel.
 	MaxFeeAllocation float64
 
+	// MaxDustExposure is the maximum total value of the dust htlcs we'll
+	// allow on each commitment of the channel. As dust htlcs are trimmed
+	// from the commitment, their value is lost to fees if it is broadcast.
+	// Htlcs that would exceed this limit are failed. A zero value disables
+	// the limit.
+	MaxDustExposure lnwire.MilliAtom
+
 	// NotifyActiveLink allows the link to tell the ChannelNotifier when a
 	// link is first started.
 	NotifyActiveLink func(wire.OutPoint)
@@ -517,6 +534,9 @@ func (l *channelLink) Stop() {
 	// As the link is stopping, we are no longer interested in htlc
 	// resolutions coming from the invoice registry.
 	l.cfg.Registry.HodlUnsubscribeAll(l.hodlQueue.ChanIn())
+	if l.cfg.Trampoline != nil {
+		l.cfg.Trampoline.UnsubscribeAll(l.hodlQueue.ChanIn())
+	}
 
 	if l.cfg.ChainEvents.Cancel != nil {
 		l.cfg.ChainEvents.Cancel()
@@ -1231,6 +1251,18 @@ func (l *channelLink) processHtlcResolution(resolution invoices.HtlcResolution,
 		)
 		return nil
 
+	// Trampoline htlcs are failed with the failure message determined by
+	// the trampoline forwarder.
+	case *TrampolineFailResolution:
+		l.log.Debugf("received trampoline failure for %v: %v",
+			circuitKey, res.Failure)
+
+		l.sendHTLCError(
+			htlc.pd, NewLinkError(res.Failure), htlc.obfuscator,
+			true,
+		)
+		return nil
+
 	// Fail if we do not get a settle of fail resolution, since we
 	// are only expecting to handle settles and fails.
 	default:
@@ -1244,6 +1276,13 @@ func (l *channelLink) processHtlcResolution(resolution invoices.HtlcResolution,
 func getResolutionFailure(resolution *invoices.HtlcFailResolution,
 	amount lnwire.MilliAtom) *LinkError {
 
+	// If the resolution carries its own failure message, we use it as is.
+	if resolution.Failure != nil {
+		return NewDetailedLinkError(
+			resolution.Failure, resolution.Outcome,
+		)
+	}
+
 	// If the resolution has been resolved as part of a MPP timeout,
 	// we need to fail the htlc with lnwire.FailMppTimeout.
 	if resolution.Outcome == invoices.ResultMppTimeout {
@@ -1289,6 +1328,22 @@ func (l *channelLink) handleDownstreamUpdateAdd(pkt *htlcPacket) error {
 		return nil
 	}
 
+	// Before adding a dust HTLC, make sure it doesn't take the dust
+	// exposure of the channel above our limit, as its value would be lost
+	// to fees should the commitment be broadcast.
+	if l.dustExposureExceeded(htlc.Amount, false, false) {
+		l.log.Warnf("Failing downstream add HTLC: amount %v exceeds "+
+			"max dust exposure %v", htlc.Amount,
+			l.cfg.MaxDustExposure)
+
+		l.mailBox.FailAddWithDetail(pkt, OutgoingFailureDustExposure)
+
+		return NewDetailedLinkError(
+			lnwire.NewTemporaryChannelFailure(nil),
+			OutgoingFailureDustExposure,
+		)
+	}
+
 	// A new payment has been initiated via the downstream channel,
 	// so we add the new HTLC to our local log, then update the
 	// commitment chains.
@@ -1511,6 +1566,35 @@ func (l *channelLink) handleDownstreamPkt(pkt *htlcPacket) {
 	}
 }
 
+// dustExposureExceeded returns true if an HTLC of the given amount is dust on
+// either commitment of the channel and takes the total value of the dust
+// HTLCs on that commitment above the configured maximum. The incoming flag
+// indicates whether the HTLC is offered by the remote party, and pending
+// whether it is already included in the update logs of the channel.
+func (l *channelLink) dustExposureExceeded(amt lnwire.MilliAtom, incoming,
+	pending bool) bool {
+
+	if l.cfg.MaxDustExposure == 0 {
+		return false
+	}
+
+	for _, remote := range []bool{false, true} {
+		if !l.channel.HtlcIsDust(incoming, remote, amt) {
+			continue
+		}
+
+		dustSum := l.channel.GetDustSum(remote)
+		if !pending {
+			dustSum += amt
+		}
+		if dustSum > l.cfg.MaxDustExposure {
+			return true
+		}
+	}
+
+	return false
+}
+
 // tryBatchUpdateCommitTx updates the commitment transaction if the batch is
 // full.
 func (l *channelLink) tryBatchUpdateCommitTx() {
@@ -2126,6 +2210,14 @@ func (l *channelLink) Bandwidth() lnwire.MilliAtom {
 	return l.channel.AvailableBalance()
 }
 
+// MaxOutgoingHtlcs returns the maximum number of htlcs that the remote peer
+// accepts from us on this link.
+//
+// NOTE: Part of the ChannelLink interface.
+func (l *channelLink) MaxOutgoingHtlcs() uint16 {
+	return l.channel.State().RemoteChanCfg.MaxAcceptedHtlcs
+}
+
 // AttachMailBox updates the current mailbox used by this link, and hooks up
 // the mailbox's message and packet outboxes to the link's upstream and
 // downstream chans, respectively.
@@ -2553,9 +2645,11 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 			onionReader := bytes.NewReader(pd.OnionBlob)
 
 			req := hop.DecodeHopIteratorRequest{
-				OnionReader:  onionReader,
-				RHash:        pd.RHash[:],
-				IncomingCltv: pd.Timeout,
+				OnionReader:    onionReader,
+				RHash:          pd.RHash[:],
+				IncomingCltv:   pd.Timeout,
+				IncomingAmount: pd.Amount,
+				BlindingPoint:  pd.BlindingPoint,
 			}
 
 			decodeReqs = append(decodeReqs, req)
@@ -2667,6 +2761,33 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 
 		fwdInfo := pld.ForwardingInfo()
 
+		// If this dust HTLC took the dust exposure of the channel
+		// above our limit, we'll fail it back. This is only checked
+		// the first time the add is processed, as the decision made
+		// for a processed add must be reproduced.
+		if fwdPkg.State == channeldb.FwdStateLockedIn &&
+			l.dustExposureExceeded(pd.Amount, true, true) {
+
+			l.log.Warnf("Failing incoming HTLC(%x): amount %v "+
+				"exceeds max dust exposure %v", pd.RHash[:],
+				pd.Amount, l.cfg.MaxDustExposure)
+
+			failure := l.createFailureWithUpdate(
+				func(upd *lnwire.ChannelUpdate) lnwire.FailureMessage {
+					return lnwire.NewTemporaryChannelFailure(
+						upd,
+					)
+				},
+			)
+
+			l.sendHTLCError(
+				pd, NewDetailedLinkError(
+					failure, OutgoingFailureDustExposure,
+				), obfuscator, fwdInfo.NextHop == hop.Exit,
+			)
+			continue
+		}
+
 		switch fwdInfo.NextHop {
 		case hop.Exit:
 			err := l.processExitHop(
@@ -2706,9 +2827,10 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 				// Otherwise, it was already processed, we can
 				// can collect it and continue.
 				addMsg := &lnwire.UpdateAddHTLC{
-					Expiry:      fwdInfo.OutgoingCTLV,
-					Amount:      fwdInfo.AmountToForward,
-					PaymentHash: pd.RHash,
+					Expiry:        fwdInfo.OutgoingCTLV,
+					Amount:        fwdInfo.AmountToForward,
+					PaymentHash:   pd.RHash,
+					BlindingPoint: fwdInfo.NextBlinding,
 				}
 
 				// Finally, we'll encode the onion packet for
@@ -2748,9 +2870,10 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 			// create the outgoing HTLC using the parameters as
 			// specified in the forwarding info.
 			addMsg := &lnwire.UpdateAddHTLC{
-				Expiry:      fwdInfo.OutgoingCTLV,
-				Amount:      fwdInfo.AmountToForward,
-				PaymentHash: pd.RHash,
+				Expiry:        fwdInfo.OutgoingCTLV,
+				Amount:        fwdInfo.AmountToForward,
+				PaymentHash:   pd.RHash,
+				BlindingPoint: fwdInfo.NextBlinding,
 			}
 
 			// Finally, we'll encode the onion packet for the
@@ -2888,6 +3011,14 @@ func (l *channelLink) processExitHop(pd *lnwallet.PaymentDescriptor,
 		HtlcID: pd.HtlcIndex,
 	}
 
+	// If the sender handed us a trampoline onion, we're asked to forward
+	// the payment towards its destination rather than to settle it.
+	if p, ok := payload.(*hop.Payload); ok && p.TrampolineOnion != nil {
+		return l.processTrampolineHop(
+			pd, obfuscator, circuitKey, p.TrampolineOnion,
+		)
+	}
+
 	event, err := l.cfg.Registry.NotifyExitHopHtlc(
 		invoiceHash, pd.Amount, pd.Timeout, int32(heightNow),
 		circuitKey, l.hodlQueue.ChanIn(), payload,
@@ -2913,6 +3044,51 @@ func (l *channelLink) processExitHop(pd *lnwallet.PaymentDescriptor,
 	return l.processHtlcResolution(event, htlc)
 }
 
+// processTrampolineHop hands an htlc for which this link is the exit hop and
+// that carries a trampoline onion to the trampoline forwarder.
+func (l *channelLink) processTrampolineHop(pd *lnwallet.PaymentDescriptor,
+	obfuscator hop.ErrorEncrypter, circuitKey channeldb.CircuitKey,
+	onion []byte) error {
+
+	htlc := hodlHtlc{
+		pd:         pd,
+		obfuscator: obfuscator,
+	}
+
+	// Fail the htlc if we don't act as trampoline node.
+	if l.cfg.Trampoline == nil {
+		l.log.Debugf("rejecting trampoline htlc %v: trampoline "+
+			"routing disabled", circuitKey)
+
+		failure := lnwire.NewInvalidOnionPayload(
+			uint64(record.TrampolineOnionType), 0,
+		)
+		l.sendHTLCError(pd, NewLinkError(failure), obfuscator, true)
+
+		return nil
+	}
+
+	event, err := l.cfg.Trampoline.ForwardHtlc(&TrampolineHtlc{
+		CircuitKey: circuitKey,
+		Hash:       lntypes.Hash(pd.RHash),
+		Amount:     pd.Amount,
+		Expiry:     pd.Timeout,
+		Onion:      onion,
+	}, l.hodlQueue.ChanIn())
+	if err != nil {
+		return err
+	}
+
+	// If the event is nil, the payment is in flight, so we save payment
+	// descriptor for future reference.
+	if event == nil {
+		l.hodlMap[circuitKey] = htlc
+		return nil
+	}
+
+	return l.processHtlcResolution(event, htlc)
+}
+
 // settleHTLC settles the HTLC on the channel.
 func (l *channelLink) settleHTLC(preimage lntypes.Preimage,
 	pd *lnwallet.PaymentDescriptor) error {
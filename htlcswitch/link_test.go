@@ -6233,3 +6233,169 @@ func assertFailureCode(t *testing.T, err error, code lnwire.FailCode) {
 			code, rtErr.WireMessage().Code())
 	}
 }
+
+// TestChannelLinkDustExposureOutgoing asserts that the link fails outgoing
+// dust HTLCs that would take the dust exposure of the channel above the
+// configured maximum, while still accepting HTLCs that aren't dust.
+func TestChannelLinkDustExposureOutgoing(t *testing.T) {
+	t.Parallel()
+
+	const chanAmt = dcrutil.AtomsPerCoin * 5
+	const chanReserve = dcrutil.AtomsPerCoin * 1
+	aliceLink, bobChannel, _, start, cleanUp, _, err :=
+		newSingleLinkTestHarness(chanAmt, chanReserve)
+	if err != nil {
+		t.Fatalf("unable to create link: %v", err)
+	}
+	defer cleanUp()
+
+	var (
+		coreLink  = aliceLink.(*channelLink)
+		aliceMsgs = coreLink.cfg.Peer.(*mockPeer).sentMsgs
+		dustAmt   = lnwire.NewMAtomsFromAtoms(4000)
+	)
+	coreLink.cfg.MaxDustExposure = lnwire.NewMAtomsFromAtoms(5000)
+
+	if err := start(); err != nil {
+		t.Fatalf("unable to start test harness: %v", err)
+	}
+
+	// Start the switch, so that the failure of the link is delivered as
+	// the result of the payment.
+	if err := coreLink.cfg.Switch.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer coreLink.cfg.Switch.Stop()
+
+	ctx := linkTestContext{
+		t:          t,
+		aliceLink:  aliceLink,
+		aliceMsgs:  aliceMsgs,
+		bobChannel: bobChannel,
+	}
+
+	// The first dust HTLC is within the limit, so Alice should offer it to
+	// Bob.
+	htlc, _ := generateHtlcAndInvoice(t, 0)
+	htlc.Amount = dustAmt
+	ctx.sendHtlcAliceToBob(0, htlc)
+	ctx.receiveHtlcAliceToBob()
+
+	// The second dust HTLC would exceed the limit, so it should be failed
+	// back to the switch instead. Its circuit is attached to the packet
+	// like for a local payment, so that the failure can be delivered.
+	htlc, _ = generateHtlcAndInvoice(t, 1)
+	htlc.Amount = dustAmt
+
+	addPkt := &htlcPacket{
+		incomingHTLCID: 1,
+		htlc:           htlc,
+	}
+	circuit := makePaymentCircuit(&htlc.PaymentHash, addPkt)
+	_, err = coreLink.cfg.Switch.commitCircuits(&circuit)
+	if err != nil {
+		t.Fatalf("unable to commit circuit: %v", err)
+	}
+	addPkt.circuit = &circuit
+
+	if err := aliceLink.HandleSwitchPacket(addPkt); err != nil {
+		t.Fatalf("unable to handle packet: %v", err)
+	}
+	ctx.assertNoMsgFromAlice(100 * time.Millisecond)
+
+	resultChan, err := coreLink.cfg.Switch.GetPaymentResult(
+		1, htlc.PaymentHash, newMockDeobfuscator(),
+	)
+	if err != nil {
+		t.Fatalf("unable to get payment result: %v", err)
+	}
+
+	select {
+	case result, ok := <-resultChan:
+		if !ok {
+			t.Fatalf("unexpected shutdown")
+		}
+		assertFailureCode(
+			t, result.Error, lnwire.CodeTemporaryChannelFailure,
+		)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no result arrived")
+	}
+
+	// An HTLC that isn't dust doesn't add to the dust exposure, so it
+	// should still be offered.
+	htlc, _ = generateHtlcAndInvoice(t, 2)
+	ctx.sendHtlcAliceToBob(2, htlc)
+	ctx.receiveHtlcAliceToBob()
+}
+
+// TestChannelLinkDustExposureIncoming asserts that the link fails incoming
+// dust HTLCs once they take the dust exposure of the channel above the
+// configured maximum.
+func TestChannelLinkDustExposureIncoming(t *testing.T) {
+	t.Parallel()
+
+	const chanAmt = dcrutil.AtomsPerCoin * 5
+	const chanReserve = dcrutil.AtomsPerCoin * 1
+	aliceLink, bobChannel, _, start, cleanUp, _, err :=
+		newSingleLinkTestHarness(chanAmt, chanReserve)
+	if err != nil {
+		t.Fatalf("unable to create link: %v", err)
+	}
+	defer cleanUp()
+
+	var (
+		coreLink  = aliceLink.(*channelLink)
+		aliceMsgs = coreLink.cfg.Peer.(*mockPeer).sentMsgs
+	)
+	coreLink.cfg.MaxDustExposure = lnwire.NewMAtomsFromAtoms(3000)
+
+	if err := start(); err != nil {
+		t.Fatalf("unable to start test harness: %v", err)
+	}
+
+	ctx := linkTestContext{
+		t:          t,
+		aliceLink:  aliceLink,
+		aliceMsgs:  aliceMsgs,
+		bobChannel: bobChannel,
+	}
+
+	// Bob sends a dust HTLC that exceeds the limit on its own. The dust
+	// exposure is checked before the payload is validated against the
+	// invoice, so the amount doesn't need to match.
+	htlc := generateHtlc(t, coreLink, 0)
+	htlc.Amount = lnwire.NewMAtomsFromAtoms(4000)
+
+	ctx.sendHtlcBobToAlice(htlc)
+	ctx.sendCommitSigBobToAlice(0)
+	ctx.receiveRevAndAckAliceToBob()
+	ctx.receiveCommitSigAliceToBob(0)
+	ctx.sendRevAndAckBobToAlice()
+
+	// Once the HTLC is locked in, Alice should fail it back with a
+	// temporary channel failure.
+	var msg lnwire.Message
+	select {
+	case msg = <-aliceMsgs:
+	case <-time.After(15 * time.Second):
+		t.Fatalf("did not receive message")
+	}
+
+	failMsg, ok := msg.(*lnwire.UpdateFailHTLC)
+	if !ok {
+		t.Fatalf("expected UpdateFailHTLC, got %T", msg)
+	}
+
+	failure, err := lnwire.DecodeFailure(
+		bytes.NewReader(failMsg.Reason), 0,
+	)
+	if err != nil {
+		t.Fatalf("unable to decode failure: %v", err)
+	}
+	if failure.Code() != lnwire.CodeTemporaryChannelFailure {
+		t.Fatalf("expected temporary channel failure, got %v",
+			failure.Code())
+	}
+}
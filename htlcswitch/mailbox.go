@@ -52,6 +52,11 @@ type MailBox interface {
 	// OutgoingFailureDownstreamHtlcAdd FailureDetail.
 	FailAdd(pkt *htlcPacket)
 
+	// FailAddWithDetail fails an UpdateAddHTLC that exists within the
+	// mailbox like FailAdd, but the generated LinkError will show the
+	// given FailureDetail.
+	FailAddWithDetail(pkt *htlcPacket, failureDetail OutgoingFailure)
+
 	// MessageOutBox returns a channel that any new messages ready for
 	// delivery will be sent on.
 	MessageOutBox() chan lnwire.Message
@@ -616,6 +621,17 @@ func (m *memoryMailBox) AddPacket(pkt *htlcPacket) error {
 // generated LinkError will show an OutgoingFailureDownstreamHtlcAdd
 // FailureDetail.
 func (m *memoryMailBox) FailAdd(pkt *htlcPacket) {
+	m.FailAddWithDetail(pkt, OutgoingFailureDownstreamHtlcAdd)
+}
+
+// FailAddWithDetail fails an UpdateAddHTLC that exists within the mailbox
+// like FailAdd, but the generated LinkError will show the given
+// FailureDetail.
+//
+// NOTE: This method is part of the MailBox interface.
+func (m *memoryMailBox) FailAddWithDetail(pkt *htlcPacket,
+	failureDetail OutgoingFailure) {
+
 	// First, remove the packet from mailbox. If we didn't find the packet
 	// because it has already been acked, we'll exit early to avoid sending
 	// a duplicate fail message through the switch.
@@ -662,10 +678,8 @@ func (m *memoryMailBox) FailAdd(pkt *htlcPacket) {
 	}
 
 	// Create a link error containing the temporary channel failure and a
-	// detail which indicates the we failed to add the htlc.
-	linkError := NewDetailedLinkError(
-		failure, OutgoingFailureDownstreamHtlcAdd,
-	)
+	// detail which indicates why we failed to add the htlc.
+	linkError := NewDetailedLinkError(failure, failureDetail)
 
 	failPkt := &htlcPacket{
 		incomingChanID: pkt.incomingChanID,
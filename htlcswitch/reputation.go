@@ -0,0 +1,394 @@
+package htlcswitch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/subscribe"
+	"golang.org/x/time/rate"
+)
+
+// reputationDecay is the weight of the most recent resolution in the moving
+// averages of the resolution time and failure ratio of an incoming channel.
+const reputationDecay = 0.05
+
+// ReputationConfig houses the configuration of the ReputationManager.
+type ReputationConfig struct {
+	// ProtectedSlotShare is the share of the htlc slots of each outgoing
+	// channel that is reserved for incoming channels with a good
+	// reputation.
+	ProtectedSlotShare float64
+
+	// ProtectedLiquidityShare is the share of the liquidity of each
+	// outgoing channel that is reserved for incoming channels with a good
+	// reputation.
+	ProtectedLiquidityShare float64
+
+	// MaxResolutionTime is the maximum average time in which the htlcs
+	// forwarded from an incoming channel must be resolved for it to have
+	// a good reputation.
+	MaxResolutionTime time.Duration
+
+	// MaxFailureRatio is the maximum average share of the htlcs forwarded
+	// from an incoming channel that may fail for it to have a good
+	// reputation.
+	MaxFailureRatio float64
+
+	// MinResolved is the minimum number of htlcs forwarded from an
+	// incoming channel that must be resolved before it may have a good
+	// reputation.
+	MinResolved uint64
+
+	// RateLimit is the number of htlcs per second that are forwarded from
+	// an incoming channel without a good reputation.
+	RateLimit rate.Limit
+
+	// RateBurst is the number of htlcs that may be forwarded at once from
+	// an incoming channel without a good reputation.
+	RateBurst int
+
+	// SubscribeHtlcEvents returns a subscription to the htlc events, which
+	// are used to track the resolution of the forwarded htlcs.
+	SubscribeHtlcEvents func() (*subscribe.Client, error)
+
+	// Clock is the time source of the manager.
+	Clock clock.Clock
+}
+
+// channelReputation tracks the reputation of an incoming channel.
+type channelReputation struct {
+	// resolved is the number of resolved htlcs forwarded from the channel.
+	resolved uint64
+
+	// resolutionTime is the moving average of the time it took to resolve
+	// the htlcs forwarded from the channel.
+	resolutionTime time.Duration
+
+	// failureRatio is the moving average of the share of the htlcs
+	// forwarded from the channel that failed.
+	failureRatio float64
+
+	// limiter limits the rate of the htlcs forwarded from the channel
+	// while it doesn't have a good reputation.
+	limiter *rate.Limiter
+}
+
+// reputationHtlc is a htlc that was forwarded and isn't resolved yet.
+type reputationHtlc struct {
+	// outgoingChanID is the channel the htlc was forwarded over.
+	outgoingChanID lnwire.ShortChannelID
+
+	// amt is the amount of the outgoing htlc.
+	amt lnwire.MilliAtom
+
+	// general is true if the htlc uses the slots and liquidity of the
+	// outgoing channel that aren't reserved.
+	general bool
+
+	// forwarded is the time the htlc was forwarded.
+	forwarded time.Time
+}
+
+// generalUsage is the usage of the slots and liquidity of an outgoing channel
+// that aren't reserved for incoming channels with a good reputation.
+type generalUsage struct {
+	htlcs int
+	amt   lnwire.MilliAtom
+}
+
+// ReputationManager protects the outgoing channels of the switch from being
+// jammed. It tracks the reputation of each incoming channel from the time it
+// takes to resolve the htlcs forwarded from it and the share of those htlcs
+// that fail. The htlcs forwarded from incoming channels without a good
+// reputation are rate limited, and may only use the slots and liquidity of
+// an outgoing channel that aren't reserved for channels with a good
+// reputation.
+type ReputationManager struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *ReputationConfig
+
+	// channels holds the reputation of each incoming channel.
+	channels map[lnwire.ShortChannelID]*channelReputation
+
+	// inFlight holds the forwarded htlcs that aren't resolved yet, keyed
+	// by the circuit key of the incoming htlc.
+	inFlight map[channeldb.CircuitKey]*reputationHtlc
+
+	// general holds the usage of the slots and liquidity of each outgoing
+	// channel that aren't reserved.
+	general map[lnwire.ShortChannelID]*generalUsage
+
+	mu sync.Mutex
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// NewReputationManager creates a new reputation manager.
+func NewReputationManager(cfg *ReputationConfig) *ReputationManager {
+	return &ReputationManager{
+		cfg:      cfg,
+		channels: make(map[lnwire.ShortChannelID]*channelReputation),
+		inFlight: make(map[channeldb.CircuitKey]*reputationHtlc),
+		general:  make(map[lnwire.ShortChannelID]*generalUsage),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start subscribes to the htlc events and starts tracking the resolution of
+// the forwarded htlcs.
+func (r *ReputationManager) Start() error {
+	var err error
+	r.started.Do(func() {
+		log.Info("ReputationManager starting")
+
+		var client *subscribe.Client
+		client, err = r.cfg.SubscribeHtlcEvents()
+		if err != nil {
+			return
+		}
+
+		r.wg.Add(1)
+		go r.consume(client)
+	})
+
+	return err
+}
+
+// Stop stops the reputation manager.
+func (r *ReputationManager) Stop() {
+	r.stopped.Do(func() {
+		log.Info("ReputationManager shutting down")
+
+		close(r.quit)
+		r.wg.Wait()
+	})
+}
+
+// consume processes the htlc events until the manager is stopped.
+func (r *ReputationManager) consume(client *subscribe.Client) {
+	defer r.wg.Done()
+	defer client.Cancel()
+
+	for {
+		select {
+		case e := <-client.Updates():
+			r.processEvent(e)
+
+		case <-client.Quit():
+			return
+
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// processEvent updates the forwarded htlcs and the reputation of their
+// incoming channel with the given htlc event.
+func (r *ReputationManager) processEvent(e interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event := e.(type) {
+	// The htlc was added to the outgoing channel, so we start timing its
+	// resolution from here.
+	case *ForwardingEvent:
+		if event.HtlcEventType != HtlcEventTypeForward {
+			return
+		}
+
+		htlc, ok := r.inFlight[event.IncomingCircuit]
+		if ok {
+			htlc.forwarded = event.Timestamp
+		}
+
+	case *SettleEvent:
+		if event.HtlcEventType != HtlcEventTypeForward {
+			return
+		}
+
+		r.resolve(event.IncomingCircuit, event.Timestamp, false)
+
+	case *ForwardingFailEvent:
+		if event.HtlcEventType != HtlcEventTypeForward {
+			return
+		}
+
+		r.resolve(event.IncomingCircuit, event.Timestamp, true)
+
+	// Failures on the incoming link happen before the htlc is forwarded,
+	// so only the failures of forwarded htlcs are tracked.
+	case *LinkFailEvent:
+		if event.HtlcEventType != HtlcEventTypeForward ||
+			event.Incoming {
+
+			return
+		}
+
+		r.resolve(event.IncomingCircuit, event.Timestamp, true)
+	}
+}
+
+// resolve removes the forwarded htlc with the given incoming circuit key and
+// updates the reputation of its incoming channel.
+//
+// NOTE: This method must be called with the mutex held.
+func (r *ReputationManager) resolve(key channeldb.CircuitKey,
+	resolved time.Time, failed bool) {
+
+	htlc, ok := r.inFlight[key]
+	if !ok {
+		return
+	}
+	delete(r.inFlight, key)
+
+	if htlc.general {
+		usage := r.general[htlc.outgoingChanID]
+		usage.htlcs--
+		usage.amt -= htlc.amt
+		if usage.htlcs == 0 {
+			delete(r.general, htlc.outgoingChanID)
+		}
+	}
+
+	resolutionTime := resolved.Sub(htlc.forwarded)
+	if resolutionTime < 0 {
+		resolutionTime = 0
+	}
+
+	var failure float64
+	if failed {
+		failure = 1
+	}
+
+	reputation := r.getReputation(key.ChanID)
+	if reputation.resolved == 0 {
+		reputation.resolutionTime = resolutionTime
+		reputation.failureRatio = failure
+	} else {
+		avgTime := float64(reputation.resolutionTime)
+		reputation.resolutionTime = time.Duration(
+			reputationDecay*float64(resolutionTime) +
+				(1-reputationDecay)*avgTime,
+		)
+		reputation.failureRatio = reputationDecay*failure +
+			(1-reputationDecay)*reputation.failureRatio
+	}
+	reputation.resolved++
+
+	log.Tracef("Reputation of channel %v after resolving htlc %v: "+
+		"resolved=%v, resolution_time=%v, failure_ratio=%.2f",
+		key.ChanID, key.HtlcID, reputation.resolved,
+		reputation.resolutionTime, reputation.failureRatio)
+}
+
+// getReputation returns the reputation of the given incoming channel, creating
+// it if it doesn't exist yet.
+//
+// NOTE: This method must be called with the mutex held.
+func (r *ReputationManager) getReputation(
+	chanID lnwire.ShortChannelID) *channelReputation {
+
+	reputation, ok := r.channels[chanID]
+	if !ok {
+		reputation = &channelReputation{
+			limiter: rate.NewLimiter(
+				r.cfg.RateLimit, r.cfg.RateBurst,
+			),
+		}
+		r.channels[chanID] = reputation
+	}
+
+	return reputation
+}
+
+// goodReputation returns true if the given incoming channel has a good
+// reputation.
+func (r *ReputationManager) goodReputation(
+	reputation *channelReputation) bool {
+
+	return reputation.resolved >= r.cfg.MinResolved &&
+		reputation.resolutionTime <= r.cfg.MaxResolutionTime &&
+		reputation.failureRatio <= r.cfg.MaxFailureRatio
+}
+
+// CheckForward decides whether the htlc with the given incoming circuit key
+// may be forwarded over the given outgoing link. If so, the htlc is tracked
+// until it is resolved. Otherwise, a LinkError describing the decision is
+// returned.
+func (r *ReputationManager) CheckForward(incoming channeldb.CircuitKey,
+	link ChannelLink, amt lnwire.MilliAtom) *LinkError {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The htlc may be offered to the switch again after a restart of its
+	// incoming link, in which case it was already admitted.
+	if _, ok := r.inFlight[incoming]; ok {
+		return nil
+	}
+
+	htlc := &reputationHtlc{
+		outgoingChanID: link.ShortChanID(),
+		amt:            amt,
+		forwarded:      r.cfg.Clock.Now(),
+	}
+
+	reputation := r.getReputation(incoming.ChanID)
+	if r.goodReputation(reputation) {
+		r.inFlight[incoming] = htlc
+		return nil
+	}
+
+	if !reputation.limiter.AllowN(htlc.forwarded, 1) {
+		log.Debugf("Rate limiting htlc %v from channel %v without "+
+			"good reputation", incoming.HtlcID, incoming.ChanID)
+
+		return NewDetailedLinkError(
+			lnwire.NewTemporaryChannelFailure(nil),
+			OutgoingFailureRateLimited,
+		)
+	}
+
+	// Without a good reputation, the htlc may only use the slots and
+	// liquidity of the outgoing channel that aren't reserved.
+	usage, ok := r.general[htlc.outgoingChanID]
+	if !ok {
+		usage = &generalUsage{}
+	}
+
+	maxSlots := int(
+		(1 - r.cfg.ProtectedSlotShare) *
+			float64(link.MaxOutgoingHtlcs()),
+	)
+	maxAmt := lnwire.MilliAtom(
+		(1 - r.cfg.ProtectedLiquidityShare) *
+			float64(link.Bandwidth()+usage.amt),
+	)
+	if usage.htlcs+1 > maxSlots || usage.amt+amt > maxAmt {
+		log.Debugf("Htlc %v from channel %v without good reputation "+
+			"exceeds general resources of channel %v: htlcs=%v, "+
+			"amt=%v", incoming.HtlcID, incoming.ChanID,
+			htlc.outgoingChanID, usage.htlcs, usage.amt)
+
+		return NewDetailedLinkError(
+			lnwire.NewTemporaryChannelFailure(nil),
+			OutgoingFailureInsufficientReputation,
+		)
+	}
+
+	usage.htlcs++
+	usage.amt += amt
+	r.general[htlc.outgoingChanID] = usage
+
+	htlc.general = true
+	r.inFlight[incoming] = htlc
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package htlcswitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testReputationTime = time.Unix(1600000000, 0)
+
+	reputationInChan  = lnwire.NewShortChanIDFromInt(1)
+	reputationOutChan = lnwire.NewShortChanIDFromInt(2)
+)
+
+// reputationTestContext holds a reputation manager along with the outgoing
+// link of the checked htlcs.
+type reputationTestContext struct {
+	t       *testing.T
+	manager *ReputationManager
+	clock   *clock.TestClock
+	link    *mockChannelLink
+	htlcID  uint64
+}
+
+// newReputationTestContext creates a reputation manager with the given
+// configuration, using a test clock.
+func newReputationTestContext(t *testing.T,
+	cfg *ReputationConfig) *reputationTestContext {
+
+	testClock := clock.NewTestClock(testReputationTime)
+	cfg.Clock = testClock
+
+	return &reputationTestContext{
+		t:       t,
+		manager: NewReputationManager(cfg),
+		clock:   testClock,
+		link: newMockChannelLink(
+			nil, lnwire.ChannelID{}, reputationOutChan, nil, true,
+		),
+	}
+}
+
+// checkForward checks the forward of a new htlc with the given amount from
+// the given incoming channel, and asserts the failure detail of the decision.
+func (c *reputationTestContext) checkForward(inChan lnwire.ShortChannelID,
+	amt lnwire.MilliAtom,
+	expected OutgoingFailure) channeldb.CircuitKey {
+
+	c.t.Helper()
+
+	key := channeldb.CircuitKey{
+		ChanID: inChan,
+		HtlcID: c.htlcID,
+	}
+	c.htlcID++
+
+	linkErr := c.manager.CheckForward(key, c.link, amt)
+	if expected == OutgoingFailureNone {
+		require.Nil(c.t, linkErr)
+		return key
+	}
+
+	require.NotNil(c.t, linkErr)
+	require.Equal(c.t, expected, linkErr.FailureDetail)
+	require.IsType(
+		c.t, &lnwire.FailTemporaryChannelFailure{},
+		linkErr.WireMessage(),
+	)
+
+	return key
+}
+
+// resolve resolves the htlc with the given key after the given duration.
+func (c *reputationTestContext) resolve(key channeldb.CircuitKey,
+	after time.Duration, failed bool) {
+
+	htlcKey := HtlcKey{
+		IncomingCircuit: key,
+		OutgoingCircuit: channeldb.CircuitKey{
+			ChanID: reputationOutChan,
+		},
+	}
+	now := c.clock.Now().Add(after)
+
+	if failed {
+		c.manager.processEvent(&ForwardingFailEvent{
+			HtlcKey:       htlcKey,
+			HtlcEventType: HtlcEventTypeForward,
+			Timestamp:     now,
+		})
+		return
+	}
+
+	c.manager.processEvent(&SettleEvent{
+		HtlcKey:       htlcKey,
+		HtlcEventType: HtlcEventTypeForward,
+		Timestamp:     now,
+	})
+}
+
+// TestReputationRateLimit asserts that the htlcs of an incoming channel
+// without a good reputation are rate limited.
+func TestReputationRateLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := newReputationTestContext(t, &ReputationConfig{
+		MaxResolutionTime: time.Minute,
+		MinResolved:       10,
+		RateLimit:         1,
+		RateBurst:         2,
+	})
+
+	ctx.checkForward(reputationInChan, 1000, OutgoingFailureNone)
+	ctx.checkForward(reputationInChan, 1000, OutgoingFailureNone)
+	ctx.checkForward(reputationInChan, 1000, OutgoingFailureRateLimited)
+
+	// The limit applies to each incoming channel on its own.
+	otherChan := lnwire.NewShortChanIDFromInt(3)
+	ctx.checkForward(otherChan, 1000, OutgoingFailureNone)
+
+	// Once time passed, the channel may forward again.
+	ctx.clock.SetTime(testReputationTime.Add(time.Second))
+	ctx.checkForward(reputationInChan, 1000, OutgoingFailureNone)
+	ctx.checkForward(reputationInChan, 1000, OutgoingFailureRateLimited)
+}
+
+// TestReputationGeneralResources asserts that the htlcs of an incoming
+// channel without a good reputation may only use the slots and liquidity of
+// the outgoing channel that aren't reserved, and that they are released once
+// the htlcs are resolved.
+func TestReputationGeneralResources(t *testing.T) {
+	t.Parallel()
+
+	// The mock link accepts 150 htlcs, so three slots aren't reserved.
+	ctx := newReputationTestContext(t, &ReputationConfig{
+		ProtectedSlotShare:      0.98,
+		ProtectedLiquidityShare: 0.5,
+		MaxResolutionTime:       time.Minute,
+		MinResolved:             10,
+		RateLimit:               1,
+		RateBurst:               100,
+	})
+
+	var keys []channeldb.CircuitKey
+	for i := 0; i < 3; i++ {
+		key := ctx.checkForward(
+			reputationInChan, 1000, OutgoingFailureNone,
+		)
+		keys = append(keys, key)
+	}
+	ctx.checkForward(
+		reputationInChan, 1000, OutgoingFailureInsufficientReputation,
+	)
+
+	// Checking a htlc that was already admitted doesn't use another slot.
+	require.Nil(t, ctx.manager.CheckForward(keys[0], ctx.link, 1000))
+
+	// Resolving a htlc releases its slot.
+	ctx.resolve(keys[0], time.Second, false)
+	ctx.checkForward(reputationInChan, 1000, OutgoingFailureNone)
+
+	// Only half of the liquidity of the link may be used.
+	ctx.resolve(keys[1], time.Second, false)
+	ctx.checkForward(
+		reputationInChan, ctx.link.Bandwidth()/2+1,
+		OutgoingFailureInsufficientReputation,
+	)
+	ctx.checkForward(
+		reputationInChan, ctx.link.Bandwidth()/2-2000,
+		OutgoingFailureNone,
+	)
+}
+
+// TestReputationGain asserts that an incoming channel gains a good reputation
+// once enough of its htlcs are resolved quickly and successfully, which lifts
+// its rate limit and grants it access to the reserved resources.
+func TestReputationGain(t *testing.T) {
+	t.Parallel()
+
+	ctx := newReputationTestContext(t, &ReputationConfig{
+		ProtectedSlotShare: 1,
+		MaxResolutionTime:  time.Minute,
+		MaxFailureRatio:    0.5,
+		MinResolved:        2,
+		RateLimit:          1,
+		RateBurst:          2,
+	})
+
+	// Without unreserved slots, a new channel can't forward at all.
+	newChan := lnwire.NewShortChanIDFromInt(5)
+	ctx.checkForward(
+		newChan, 1000, OutgoingFailureInsufficientReputation,
+	)
+
+	// Slow resolutions don't gain a good reputation.
+	slowChan := lnwire.NewShortChanIDFromInt(3)
+	ctx.manager.cfg.ProtectedSlotShare = 0
+	for i := 0; i < 2; i++ {
+		key := ctx.checkForward(slowChan, 1000, OutgoingFailureNone)
+		ctx.resolve(key, time.Hour, false)
+	}
+	for i := 0; i < 2; i++ {
+		key := ctx.checkForward(
+			reputationInChan, 1000, OutgoingFailureNone,
+		)
+		ctx.resolve(key, time.Second, false)
+	}
+
+	// As time doesn't pass, the rate limit of the channels is now
+	// exhausted. We also reserve all slots again.
+	ctx.manager.cfg.ProtectedSlotShare = 1
+	ctx.checkForward(slowChan, 1000, OutgoingFailureRateLimited)
+	for i := 0; i < 5; i++ {
+		ctx.checkForward(reputationInChan, 1000, OutgoingFailureNone)
+	}
+
+	// Failures lose the good reputation again.
+	failedChan := lnwire.NewShortChanIDFromInt(4)
+	ctx.manager.cfg.ProtectedSlotShare = 0
+	for i := 0; i < 2; i++ {
+		key := ctx.checkForward(failedChan, 1000, OutgoingFailureNone)
+		ctx.resolve(key, time.Second, true)
+	}
+	ctx.checkForward(failedChan, 1000, OutgoingFailureRateLimited)
+}
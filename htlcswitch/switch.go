@@ -183,6 +183,11 @@ type Config struct {
 	// HTLCs that are not from the source hop.
 	RejectHTLC bool
 
+	// HtlcReputation protects the outgoing links from being jammed by
+	// incoming channels without a good reputation. If nil, all htlcs that
+	// satisfy the policy of their outgoing link are forwarded.
+	HtlcReputation *ReputationManager
+
 	// Clock is a time source for the switch.
 	Clock clock.Clock
 
@@ -1075,6 +1080,17 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 			return s.failAddPacket(packet, linkErr)
 		}
 
+		// Finally, we'll check whether the incoming channel has the
+		// reputation to use the resources of the destination link.
+		if s.cfg.HtlcReputation != nil {
+			linkErr := s.cfg.HtlcReputation.CheckForward(
+				packet.inKey(), destination, packet.amount,
+			)
+			if linkErr != nil {
+				return s.failAddPacket(packet, linkErr)
+			}
+		}
+
 		// Send the packet to the destination channel link which
 		// manages the channel.
 		packet.outgoingChanID = destination.ShortChanID()
@@ -13,7 +13,9 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/htlcswitch/hop"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwire"
@@ -398,6 +400,108 @@ func TestSwitchForward(t *testing.T) {
 	}
 }
 
+// TestSwitchForwardReputation asserts that the switch fails forwards that are
+// rejected by the reputation manager back to the incoming link, along with the
+// failure detail of the decision.
+func TestSwitchForwardReputation(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create bob server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+
+	// Alice's channel has no reputation yet, so it may only forward a
+	// single htlc.
+	s.cfg.HtlcReputation = NewReputationManager(&ReputationConfig{
+		MaxResolutionTime: time.Minute,
+		MinResolved:       10,
+		RateLimit:         1,
+		RateBurst:         1,
+		Clock:             clock.NewTestClock(time.Unix(0, 0)),
+	})
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	bobChannelLink := newMockChannelLink(
+		s, chanID2, bobChanID, bobPeer, true,
+	)
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add alice link: %v", err)
+	}
+	if err := s.AddLink(bobChannelLink); err != nil {
+		t.Fatalf("unable to add bob link: %v", err)
+	}
+
+	newPacket := func(htlcID uint64) *htlcPacket {
+		return &htlcPacket{
+			incomingChanID: aliceChannelLink.ShortChanID(),
+			incomingHTLCID: htlcID,
+			outgoingChanID: bobChannelLink.ShortChanID(),
+			obfuscator:     NewMockObfuscator(),
+			htlc: &lnwire.UpdateAddHTLC{
+				PaymentHash: [32]byte{byte(htlcID)},
+				Amount:      1,
+			},
+		}
+	}
+
+	// The first htlc is forwarded to Bob.
+	if err := s.ForwardPackets(nil, newPacket(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-bobChannelLink.packets:
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to destination")
+	}
+
+	// The second htlc exceeds the rate limit of Alice's channel, so it is
+	// failed back to Alice.
+	if err := s.ForwardPackets(nil, newPacket(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case pkt := <-aliceChannelLink.packets:
+		linkErr := pkt.linkFailure
+		if linkErr == nil ||
+			linkErr.FailureDetail != OutgoingFailureRateLimited {
+
+			t.Fatalf("expected rate limited failure, got: %v",
+				linkErr)
+		}
+
+	case <-bobChannelLink.packets:
+		t.Fatal("rate limited request was propagated to destination")
+
+	case <-time.After(time.Second):
+		t.Fatal("failure was not propagated to source")
+	}
+}
+
 func TestSwitchForwardFailAfterFullAdd(t *testing.T) {
 	t.Parallel()
 
@@ -3226,10 +3330,11 @@ func TestSwitchHoldForward(t *testing.T) {
 	preimage := [sha256.Size]byte{1}
 	rhash := sha256.Sum256(preimage[:])
 	ogPacket := &htlcPacket{
-		incomingChanID: aliceChannelLink.ShortChanID(),
-		incomingHTLCID: 0,
-		outgoingChanID: bobChannelLink.ShortChanID(),
-		obfuscator:     NewMockObfuscator(),
+		incomingChanID:  aliceChannelLink.ShortChanID(),
+		incomingHTLCID:  0,
+		outgoingChanID:  bobChannelLink.ShortChanID(),
+		incomingTimeout: testStartingHeight + 100,
+		obfuscator:      NewMockObfuscator(),
 		htlc: &lnwire.UpdateAddHTLC{
 			PaymentHash: rhash,
 			Amount:      1,
@@ -3237,7 +3342,11 @@ func TestSwitchHoldForward(t *testing.T) {
 	}
 
 	forwardInterceptor := &mockForwardInterceptor{}
-	switchForwardInterceptor := NewInterceptableSwitch(s)
+	switchForwardInterceptor := NewInterceptableSwitch(
+		&InterceptableSwitchConfig{
+			Switch: s,
+		},
+	)
 	switchForwardInterceptor.SetInterceptor(forwardInterceptor.InterceptForwardHtlc)
 	linkQuit := make(chan struct{})
 
@@ -3298,3 +3407,349 @@ func TestSwitchHoldForward(t *testing.T) {
 	assertOutgoingLinkReceive(t, aliceChannelLink, true)
 	assertNumCircuits(t, s, 0, 0)
 }
+
+// chanInterceptor is a ForwardInterceptor that delivers the intercepted
+// forwards on a channel.
+type chanInterceptor chan InterceptedForward
+
+func (c chanInterceptor) intercept(intercepted InterceptedForward) bool {
+	c <- intercepted
+	return true
+}
+
+// receive returns the next intercepted forward.
+func (c chanInterceptor) receive(t *testing.T) InterceptedForward {
+	t.Helper()
+
+	select {
+	case intercepted := <-c:
+		return intercepted
+
+	case <-time.After(time.Second):
+		t.Fatal("forward was not intercepted")
+		return nil
+	}
+}
+
+// interceptorTestContext holds an interceptable switch forwarding from Alice
+// to Bob.
+type interceptorTestContext struct {
+	t                   *testing.T
+	s                   *Switch
+	interceptableSwitch *InterceptableSwitch
+	aliceChannelLink    *mockChannelLink
+	bobChannelLink      *mockChannelLink
+	linkQuit            chan struct{}
+}
+
+// newInterceptorTestContext creates a started switch with links to Alice and
+// Bob, wrapped in a started interceptable switch with the given configuration.
+func newInterceptorTestContext(t *testing.T,
+	cfg *InterceptableSwitchConfig) *interceptorTestContext {
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create bob server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	bobChannelLink := newMockChannelLink(
+		s, chanID2, bobChanID, bobPeer, true,
+	)
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add alice link: %v", err)
+	}
+	if err := s.AddLink(bobChannelLink); err != nil {
+		t.Fatalf("unable to add bob link: %v", err)
+	}
+
+	cfg.Switch = s
+	if cfg.Notifier == nil {
+		cfg.Notifier = &mockNotifier{}
+	}
+	interceptableSwitch := NewInterceptableSwitch(cfg)
+	if err := interceptableSwitch.Start(); err != nil {
+		t.Fatalf("unable to start interceptable switch: %v", err)
+	}
+
+	return &interceptorTestContext{
+		t:                   t,
+		s:                   s,
+		interceptableSwitch: interceptableSwitch,
+		aliceChannelLink:    aliceChannelLink,
+		bobChannelLink:      bobChannelLink,
+		linkQuit:            make(chan struct{}),
+	}
+}
+
+// stop stops the interceptable switch and the switch.
+func (c *interceptorTestContext) stop() {
+	c.interceptableSwitch.Stop()
+	if err := c.s.Stop(); err != nil {
+		c.t.Fatalf("unable to stop switch: %v", err)
+	}
+}
+
+// forward forwards a htlc with the given incoming htlc id and expiry from
+// Alice to Bob through the interceptable switch.
+func (c *interceptorTestContext) forward(htlcID uint64,
+	incomingTimeout uint32) *htlcPacket {
+
+	c.t.Helper()
+
+	packet := &htlcPacket{
+		incomingChanID:  c.aliceChannelLink.ShortChanID(),
+		incomingHTLCID:  htlcID,
+		outgoingChanID:  c.bobChannelLink.ShortChanID(),
+		incomingAmount:  1000,
+		amount:          1000,
+		incomingTimeout: incomingTimeout,
+		outgoingTimeout: incomingTimeout - testDefaultDelta,
+		obfuscator:      NewMockObfuscator(),
+		htlc: &lnwire.UpdateAddHTLC{
+			PaymentHash: [32]byte{byte(htlcID)},
+			Amount:      1000,
+			Expiry:      incomingTimeout - testDefaultDelta,
+		},
+	}
+
+	err := c.interceptableSwitch.ForwardPackets(c.linkQuit, packet)
+	if err != nil {
+		c.t.Fatalf("can't forward htlc packet: %v", err)
+	}
+
+	return packet
+}
+
+// assertFail asserts that the forward with the given incoming htlc id was
+// failed back to Alice.
+func (c *interceptorTestContext) assertFail(htlcID uint64) {
+	c.t.Helper()
+
+	// The packet is consumed directly, as the failed forwards never
+	// opened a circuit that can be torn down.
+	select {
+	case pkt := <-c.aliceChannelLink.packets:
+		if _, ok := pkt.htlc.(*lnwire.UpdateFailHTLC); !ok {
+			c.t.Fatalf("expected fail, got: %T", pkt.htlc)
+		}
+		if pkt.incomingHTLCID != htlcID {
+			c.t.Fatalf("expected fail of htlc %v, got: %v",
+				htlcID, pkt.incomingHTLCID)
+		}
+
+	case <-time.After(time.Second):
+		c.t.Fatal("forward was not failed")
+	}
+}
+
+// TestInterceptableSwitchDisconnect asserts that the configured action is
+// taken on the held forwards once the interceptor disconnects, and that held
+// forwards are handed to the next interceptor.
+func TestInterceptableSwitchDisconnect(t *testing.T) {
+	t.Parallel()
+
+	const expiry = testStartingHeight + 100
+
+	testCases := []struct {
+		name   string
+		action InterceptorDisconnectAction
+	}{
+		{
+			name:   "resume",
+			action: InterceptorDisconnectResume,
+		},
+		{
+			name:   "fail",
+			action: InterceptorDisconnectFail,
+		},
+		{
+			name:   "hold",
+			action: InterceptorDisconnectHold,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx := newInterceptorTestContext(
+				t, &InterceptableSwitchConfig{
+					DisconnectAction: testCase.action,
+				},
+			)
+			defer ctx.stop()
+
+			interceptor := make(chanInterceptor, 2)
+			ctx.interceptableSwitch.SetInterceptor(
+				interceptor.intercept,
+			)
+
+			ctx.forward(0, expiry)
+			forward := interceptor.receive(t)
+			assertOutgoingLinkReceive(t, ctx.bobChannelLink, false)
+
+			ctx.interceptableSwitch.SetInterceptor(nil)
+
+			switch testCase.action {
+			case InterceptorDisconnectResume:
+				assertOutgoingLinkReceive(
+					t, ctx.bobChannelLink, true,
+				)
+
+			case InterceptorDisconnectFail:
+				ctx.assertFail(0)
+				assertOutgoingLinkReceive(
+					t, ctx.bobChannelLink, false,
+				)
+
+			case InterceptorDisconnectHold:
+				assertOutgoingLinkReceive(
+					t, ctx.bobChannelLink, false,
+				)
+
+				// New forwards are held until the next
+				// interceptor connects, which is handed all
+				// of them.
+				ctx.forward(1, expiry)
+				assertOutgoingLinkReceive(
+					t, ctx.bobChannelLink, false,
+				)
+
+				ctx.interceptableSwitch.SetInterceptor(
+					interceptor.intercept,
+				)
+				for i := 0; i < 2; i++ {
+					err := interceptor.receive(t).Resume()
+					if err != nil {
+						t.Fatalf("unable to resume: %v",
+							err)
+					}
+					assertOutgoingLinkReceive(
+						t, ctx.bobChannelLink, true,
+					)
+				}
+			}
+
+			// The forward can't be resolved twice.
+			if err := forward.Resume(); err != ErrFwdNotExists {
+				t.Fatalf("expected ErrFwdNotExists, got: %v",
+					err)
+			}
+		})
+	}
+}
+
+// TestInterceptableSwitchExpiry asserts that forwards that are about to expire
+// are failed instead of being intercepted, and that held forwards are failed
+// automatically once they're about to expire.
+func TestInterceptableSwitchExpiry(t *testing.T) {
+	t.Parallel()
+
+	const delta = 10
+
+	notifier := &mockNotifier{
+		epochChan: make(chan *chainntnfs.BlockEpoch),
+	}
+	ctx := newInterceptorTestContext(t, &InterceptableSwitchConfig{
+		Notifier:           notifier,
+		CltvInterceptDelta: delta,
+	})
+	defer ctx.stop()
+
+	interceptor := make(chanInterceptor, 1)
+	ctx.interceptableSwitch.SetInterceptor(interceptor.intercept)
+
+	// A forward within the delta is failed right away.
+	ctx.forward(0, testStartingHeight+delta)
+	ctx.assertFail(0)
+	select {
+	case <-interceptor:
+		t.Fatal("expiring forward was intercepted")
+	default:
+	}
+
+	// Otherwise, the forward is held until the delta is reached.
+	ctx.forward(1, testStartingHeight+2*delta)
+	forward := interceptor.receive(t)
+
+	autoFailHeight := forward.Packet().AutoFailHeight
+	if autoFailHeight != testStartingHeight+delta {
+		t.Fatalf("unexpected auto fail height: %v", autoFailHeight)
+	}
+
+	notifier.epochChan <- &chainntnfs.BlockEpoch{
+		Height: int32(autoFailHeight - 1),
+	}
+	assertOutgoingLinkReceive(t, ctx.aliceChannelLink, false)
+
+	notifier.epochChan <- &chainntnfs.BlockEpoch{
+		Height: int32(autoFailHeight),
+	}
+	ctx.assertFail(1)
+	assertOutgoingLinkReceive(t, ctx.bobChannelLink, false)
+
+	if err := forward.Resume(); err != ErrFwdNotExists {
+		t.Fatalf("expected ErrFwdNotExists, got: %v", err)
+	}
+}
+
+// TestInterceptableSwitchResumeModified asserts that a held forward can be
+// resumed over a different outgoing channel and with a lower amount.
+func TestInterceptableSwitchResumeModified(t *testing.T) {
+	t.Parallel()
+
+	ctx := newInterceptorTestContext(t, &InterceptableSwitchConfig{})
+	defer ctx.stop()
+
+	interceptor := make(chanInterceptor, 1)
+	ctx.interceptableSwitch.SetInterceptor(interceptor.intercept)
+
+	// Request a channel that doesn't exist, as is the case for a channel
+	// that is yet to be opened by the interceptor.
+	packet := ctx.forward(0, testStartingHeight+100)
+	packet.outgoingChanID = lnwire.NewShortChanIDFromInt(99)
+	forward := interceptor.receive(t)
+
+	// The outgoing amount may not exceed the incoming amount.
+	err := forward.ResumeModified(lnwire.ShortChannelID{}, 1001)
+	if err == nil {
+		t.Fatal("expected resume with higher amount to fail")
+	}
+
+	err = forward.ResumeModified(ctx.bobChannelLink.ShortChanID(), 900)
+	if err != nil {
+		t.Fatalf("unable to resume: %v", err)
+	}
+
+	select {
+	case pkt := <-ctx.bobChannelLink.packets:
+		htlc := pkt.htlc.(*lnwire.UpdateAddHTLC)
+		if pkt.amount != 900 || htlc.Amount != 900 {
+			t.Fatalf("unexpected outgoing amount: %v",
+				htlc.Amount)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to destination")
+	}
+}
@@ -0,0 +1,342 @@
+package htlcswitch
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/htlcswitch/hop"
+	"github.com/decred/dcrlnd/invoices"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/trampoline"
+	sphinx "github.com/decred/lightning-onion/v3"
+)
+
+// ErrTrampolineIncorrectDetails is returned by the payment function of the
+// trampoline forwarder when the destination rejected the payment details of
+// the forwarded payment.
+var ErrTrampolineIncorrectDetails = errors.New("destination rejected " +
+	"the payment details")
+
+// TrampolineHtlc is an incoming htlc for which we're the exit hop, and that
+// carries a trampoline onion in its payload.
+type TrampolineHtlc struct {
+	// CircuitKey identifies the incoming htlc.
+	CircuitKey channeldb.CircuitKey
+
+	// Hash is the payment hash of the htlc.
+	Hash lntypes.Hash
+
+	// Amount is the amount of the incoming htlc.
+	Amount lnwire.MilliAtom
+
+	// Expiry is the expiry height of the incoming htlc.
+	Expiry uint32
+
+	// Onion is the serialized trampoline onion.
+	Onion []byte
+}
+
+// TrampolinePayment describes the payment that a trampoline node sends to the
+// outgoing node of a trampoline onion.
+type TrampolinePayment struct {
+	// Hash is the payment hash of the payment, which is the same as the
+	// one of the incoming htlc.
+	Hash lntypes.Hash
+
+	// Destination is the node the payment is sent to.
+	Destination *secp256k1.PublicKey
+
+	// Amount is the amount the destination must receive.
+	Amount lnwire.MilliAtom
+
+	// FeeLimit is the maximum routing fee that may be paid.
+	FeeLimit lnwire.MilliAtom
+
+	// FinalCltv is the expiry of the htlc that the destination must
+	// receive.
+	FinalCltv uint32
+
+	// CltvLimit is the maximum expiry of the outgoing htlcs.
+	CltvLimit uint32
+
+	// PaymentAddr is the payment address of the destination, if it was
+	// provided by the sender.
+	PaymentAddr *[32]byte
+}
+
+// TrampolineConfig houses the configuration of a Trampoline forwarder.
+type TrampolineConfig struct {
+	// NodeKey is used to decrypt the trampoline onions handed to us.
+	NodeKey sphinx.SingleKeyECDH
+
+	// BaseFee is the base fee charged for forwarding a trampoline payment.
+	BaseFee lnwire.MilliAtom
+
+	// FeeRate is the proportional fee charged for forwarding a trampoline
+	// payment, in millionths.
+	FeeRate lnwire.MilliAtom
+
+	// CltvDelta is the minimum difference between the expiry of the
+	// incoming htlc and the expiry of the htlc we extend to the first hop
+	// of the outgoing payment.
+	CltvDelta uint32
+
+	// BestHeight returns the current best block height.
+	BestHeight func() uint32
+
+	// SendPayment sends the given payment and blocks until it either
+	// succeeds or fails for good.
+	SendPayment func(*TrampolinePayment) (lntypes.Preimage, error)
+}
+
+// Trampoline forwards payments on behalf of senders that handed us a
+// trampoline onion. Every incoming htlc carrying a trampoline onion results in
+// a payment to the outgoing node of the onion, and is resolved once that
+// payment completes.
+type Trampoline struct {
+	cfg *TrampolineConfig
+
+	// subscribers maps the circuit keys of the incoming htlcs with a
+	// payment in flight to the channel their resolution must be delivered
+	// to. It is nil for htlcs whose link went offline.
+	subscribers map[channeldb.CircuitKey]chan<- interface{}
+	mu          sync.Mutex
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// A compile time check to ensure Trampoline implements the
+// TrampolineForwarder interface.
+var _ TrampolineForwarder = (*Trampoline)(nil)
+
+// NewTrampoline creates a new trampoline forwarder.
+func NewTrampoline(cfg *TrampolineConfig) *Trampoline {
+	return &Trampoline{
+		cfg:         cfg,
+		subscribers: make(map[channeldb.CircuitKey]chan<- interface{}),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Stop signals all payment goroutines to stop delivering resolutions and waits
+// for them to exit.
+func (t *Trampoline) Stop() {
+	close(t.quit)
+	t.wg.Wait()
+}
+
+// ForwardHtlc validates the trampoline onion of the passed htlc and sends the
+// payment it describes. Htlcs with an invalid onion or that don't pay enough
+// fees are failed right away.
+//
+// NOTE: Part of the TrampolineForwarder interface.
+func (t *Trampoline) ForwardHtlc(htlc *TrampolineHtlc,
+	resolutionChan chan<- interface{}) (invoices.HtlcResolution, error) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// If a payment for this htlc is already in flight, which is the case
+	// if the link was restarted, we merely subscribe to its outcome.
+	if _, ok := t.subscribers[htlc.CircuitKey]; ok {
+		t.subscribers[htlc.CircuitKey] = resolutionChan
+		return nil, nil
+	}
+
+	payment, failure := t.decodePayment(htlc)
+	if failure != nil {
+		log.Debugf("Failing trampoline htlc %v: %v", htlc.CircuitKey,
+			failure)
+
+		return NewTrampolineFailResolution(
+			htlc.CircuitKey, failure,
+		), nil
+	}
+
+	log.Debugf("Forwarding trampoline htlc %v to %x", htlc.CircuitKey,
+		payment.Destination.SerializeCompressed())
+
+	t.subscribers[htlc.CircuitKey] = resolutionChan
+
+	t.wg.Add(1)
+	go t.sendPayment(htlc, payment)
+
+	return nil, nil
+}
+
+// UnsubscribeAll unsubscribes the passed channel from the resolutions of all
+// htlcs. The payments are kept in flight, so that they can be picked up again
+// once the link is restarted.
+//
+// NOTE: Part of the TrampolineForwarder interface.
+func (t *Trampoline) UnsubscribeAll(subscriber chan<- interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, c := range t.subscribers {
+		if c == subscriber {
+			t.subscribers[key] = nil
+		}
+	}
+}
+
+// decodePayment decrypts the trampoline onion of the htlc and checks that the
+// htlc pays enough fees and leaves enough time to forward the payment. If it
+// doesn't, the failure to send back to the sender is returned.
+func (t *Trampoline) decodePayment(htlc *TrampolineHtlc) (*TrampolinePayment,
+	lnwire.FailureMessage) {
+
+	invalidOnion := lnwire.NewInvalidOnionPayload(
+		uint64(record.TrampolineOnionType), 0,
+	)
+
+	packet, err := trampoline.ParseOnionPacket(htlc.Onion)
+	if err != nil {
+		return nil, invalidOnion
+	}
+
+	processed, err := trampoline.ProcessOnionPacket(
+		t.cfg.NodeKey, packet, htlc.Hash[:],
+	)
+	if err != nil {
+		return nil, invalidOnion
+	}
+
+	// Forwarding to other trampoline nodes isn't supported, we only
+	// forward payments to their final destination.
+	if processed.NextPacket != nil {
+		return nil, invalidOnion
+	}
+
+	payload, err := hop.NewTrampolinePayloadFromReader(
+		bytes.NewReader(processed.Payload),
+	)
+	if err != nil {
+		var failedType uint64
+		if e, ok := err.(hop.ErrInvalidPayload); ok {
+			failedType = uint64(e.Type)
+		}
+
+		return nil, lnwire.NewInvalidOnionPayload(failedType, 0)
+	}
+
+	// The difference between the incoming amount and the amount to
+	// forward must cover our fee, the rest may be spent on routing fees.
+	fee := t.cfg.BaseFee +
+		payload.AmountToForward*t.cfg.FeeRate/1000000
+	if htlc.Amount < payload.AmountToForward+fee {
+		return nil, &lnwire.FailTrampolineFeeInsufficient{}
+	}
+
+	// The difference between the incoming expiry and the expiry at the
+	// outgoing node must at least cover our required delta, the rest may
+	// be spent on the time locks of the route.
+	if htlc.Expiry < payload.OutgoingCltv+t.cfg.CltvDelta ||
+		payload.OutgoingCltv <= t.cfg.BestHeight() {
+
+		return nil, &lnwire.FailTrampolineExpiryTooSoon{}
+	}
+
+	payment := &TrampolinePayment{
+		Hash:        htlc.Hash,
+		Destination: payload.OutgoingNodeID,
+		Amount:      payload.AmountToForward,
+		FeeLimit:    htlc.Amount - payload.AmountToForward - fee,
+		FinalCltv:   payload.OutgoingCltv,
+		CltvLimit:   htlc.Expiry - t.cfg.CltvDelta,
+	}
+	if payload.MPP != nil {
+		addr := payload.MPP.PaymentAddr()
+		payment.PaymentAddr = &addr
+	}
+
+	return payment, nil
+}
+
+// sendPayment sends the payment for the passed htlc and delivers the
+// resolution of the htlc to its subscriber.
+//
+// NOTE: MUST be run as a goroutine.
+func (t *Trampoline) sendPayment(htlc *TrampolineHtlc,
+	payment *TrampolinePayment) {
+
+	defer t.wg.Done()
+
+	var resolution invoices.HtlcResolution
+	preimage, err := t.cfg.SendPayment(payment)
+	switch {
+	case err == nil:
+		resolution = invoices.NewSettleResolution(
+			preimage, htlc.CircuitKey, int32(t.cfg.BestHeight()),
+			invoices.ResultSettled,
+		)
+
+	// Let the sender know that the destination rejected the payment, so
+	// that it won't retry it through other trampoline nodes.
+	case err == ErrTrampolineIncorrectDetails:
+		resolution = NewTrampolineFailResolution(
+			htlc.CircuitKey, lnwire.NewFailIncorrectDetails(
+				htlc.Amount, t.cfg.BestHeight(),
+			),
+		)
+
+	default:
+		log.Debugf("Trampoline payment for htlc %v failed: %v",
+			htlc.CircuitKey, err)
+
+		resolution = NewTrampolineFailResolution(
+			htlc.CircuitKey, &lnwire.FailTemporaryNodeFailure{},
+		)
+	}
+
+	t.mu.Lock()
+	subscriber := t.subscribers[htlc.CircuitKey]
+	delete(t.subscribers, htlc.CircuitKey)
+	t.mu.Unlock()
+
+	// If the link went offline, the htlc will be forwarded again once it
+	// comes back online, at which point the outcome of the payment is
+	// looked up again.
+	if subscriber == nil {
+		return
+	}
+
+	select {
+	case subscriber <- resolution:
+	case <-t.quit:
+	}
+}
+
+// TrampolineFailResolution is an implementation of the HtlcResolution
+// interface that fails a trampoline htlc with a specific failure message.
+type TrampolineFailResolution struct {
+	circuitKey channeldb.CircuitKey
+
+	// Failure is the failure message the htlc is failed with.
+	Failure lnwire.FailureMessage
+}
+
+// NewTrampolineFailResolution returns a htlc resolution that fails the htlc
+// with the given failure message.
+func NewTrampolineFailResolution(key channeldb.CircuitKey,
+	failure lnwire.FailureMessage) *TrampolineFailResolution {
+
+	return &TrampolineFailResolution{
+		circuitKey: key,
+		Failure:    failure,
+	}
+}
+
+// CircuitKey returns the circuit key for the htlc that we have a resolution
+// for.
+//
+// NOTE: Part of the HtlcResolution interface.
+func (f *TrampolineFailResolution) CircuitKey() channeldb.CircuitKey {
+	return f.circuitKey
+}
@@ -0,0 +1,156 @@
+package htlcswitch
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/invoices"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/trampoline"
+	"github.com/decred/dcrlnd/tlv"
+	"github.com/stretchr/testify/require"
+)
+
+// createTrampolineOnion creates a trampoline onion for the given node that
+// instructs it to forward amt to the destination with the given expiry.
+func createTrampolineOnion(t *testing.T, nodePub, dest *secp256k1.PublicKey,
+	hash lntypes.Hash, amt lnwire.MilliAtom, cltv uint32) []byte {
+
+	amtToFwd := uint64(amt)
+	tlvStream, err := tlv.NewStream(
+		record.NewAmtToFwdRecord(&amtToFwd),
+		record.NewLockTimeRecord(&cltv),
+		record.NewOutgoingNodeIDRecord(&dest),
+	)
+	require.NoError(t, err)
+
+	var payload bytes.Buffer
+	require.NoError(t, tlvStream.Encode(&payload))
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	packet, err := trampoline.NewOnionPacket(
+		sessionKey, []*trampoline.HopInfo{{
+			NodePub: nodePub,
+			Payload: payload.Bytes(),
+		}}, hash[:],
+	)
+	require.NoError(t, err)
+
+	return packet.Bytes()
+}
+
+// TestTrampolineForwardHtlc tests that the trampoline forwarder sends the
+// payment described by valid trampoline onions, and fails the htlcs that don't
+// pay enough fees or leave too little time to forward the payment.
+func TestTrampolineForwardHtlc(t *testing.T) {
+	t.Parallel()
+
+	nodeKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	destKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	const height = 100
+	preimage := lntypes.Preimage{1}
+	hash := preimage.Hash()
+
+	payments := make(chan *TrampolinePayment, 1)
+	forwarder := NewTrampoline(&TrampolineConfig{
+		NodeKey:    &keychain.PrivKeyECDH{PrivKey: nodeKey},
+		BaseFee:    1000,
+		FeeRate:    1000,
+		CltvDelta:  40,
+		BestHeight: func() uint32 { return height },
+		SendPayment: func(p *TrampolinePayment) (lntypes.Preimage,
+			error) {
+
+			payments <- p
+			return preimage, nil
+		},
+	})
+	defer forwarder.Stop()
+
+	onion := createTrampolineOnion(
+		t, nodeKey.PubKey(), destKey.PubKey(), hash, 100000, 200,
+	)
+	htlc := &TrampolineHtlc{
+		CircuitKey: channeldb.CircuitKey{HtlcID: 1},
+		Hash:       hash,
+		Amount:     110000,
+		Expiry:     300,
+		Onion:      onion,
+	}
+
+	// The fee of 1100 is covered, so the payment is sent out and the htlc
+	// is settled once it succeeds.
+	resolutions := make(chan interface{}, 1)
+	resolution, err := forwarder.ForwardHtlc(htlc, resolutions)
+	require.NoError(t, err)
+	require.Nil(t, resolution)
+
+	select {
+	case p := <-payments:
+		require.True(t, p.Destination.IsEqual(destKey.PubKey()))
+		require.Equal(t, lnwire.MilliAtom(100000), p.Amount)
+		require.Equal(t, lnwire.MilliAtom(8900), p.FeeLimit)
+		require.Equal(t, uint32(200), p.FinalCltv)
+		require.Equal(t, uint32(260), p.CltvLimit)
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("payment not sent")
+	}
+
+	select {
+	case item := <-resolutions:
+		settle, ok := item.(*invoices.HtlcSettleResolution)
+		require.True(t, ok)
+		require.Equal(t, preimage, settle.Preimage)
+		require.Equal(t, htlc.CircuitKey, settle.CircuitKey())
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("no resolution received")
+	}
+
+	// Htlcs that don't cover the fee are failed right away.
+	feeHtlc := *htlc
+	feeHtlc.Amount = 101000
+	resolution, err = forwarder.ForwardHtlc(&feeHtlc, resolutions)
+	require.NoError(t, err)
+	require.IsType(t, &TrampolineFailResolution{}, resolution)
+	require.Equal(
+		t, &lnwire.FailTrampolineFeeInsufficient{},
+		resolution.(*TrampolineFailResolution).Failure,
+	)
+
+	// The same goes for htlcs that don't leave enough time to forward
+	// the payment.
+	expiryHtlc := *htlc
+	expiryHtlc.Expiry = 239
+	resolution, err = forwarder.ForwardHtlc(&expiryHtlc, resolutions)
+	require.NoError(t, err)
+	require.Equal(
+		t, &lnwire.FailTrampolineExpiryTooSoon{},
+		resolution.(*TrampolineFailResolution).Failure,
+	)
+
+	// Onions that were created for a different payment hash are
+	// rejected.
+	invalidHtlc := *htlc
+	invalidHtlc.Hash = lntypes.Hash{}
+	resolution, err = forwarder.ForwardHtlc(&invalidHtlc, resolutions)
+	require.NoError(t, err)
+	require.Equal(
+		t, lnwire.NewInvalidOnionPayload(
+			uint64(record.TrampolineOnionType), 0,
+		),
+		resolution.(*TrampolineFailResolution).Failure,
+	)
+}
@@ -0,0 +1,277 @@
+package invoices
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrlnd/chainntnfs"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+)
+
+var (
+	// ErrHtlcNotIntercepted is returned when an intercepted exit hop htlc
+	// is resolved that isn't held anymore.
+	ErrHtlcNotIntercepted = errors.New("htlc not intercepted")
+)
+
+// ExitHopInterceptor is a function that is invoked for every exit hop htlc
+// for which no invoice exists. It returns false if it can't take the htlc,
+// in which case the htlc is failed.
+type ExitHopInterceptor func(InterceptedExitHop) bool
+
+// InterceptedExitHopHtlc contains the relevant information for the exit hop
+// interceptor about an htlc.
+type InterceptedExitHopHtlc struct {
+	// CircuitKey contains the incoming channel and htlc id of the htlc.
+	CircuitKey channeldb.CircuitKey
+
+	// Hash is the payment hash of the htlc.
+	Hash lntypes.Hash
+
+	// Amount is the amount of the htlc.
+	Amount lnwire.MilliAtom
+
+	// Expiry is the absolute block height at which the htlc expires.
+	Expiry uint32
+
+	// AcceptHeight is the block height at which the htlc was accepted.
+	AcceptHeight int32
+
+	// AutoFailHeight is the block height at which the htlc is failed
+	// automatically if it is still held, which is FinalCltvRejectDelta
+	// blocks before its expiry.
+	AutoFailHeight uint32
+
+	// CustomRecords are user-defined records in the custom type range that
+	// were included in the payload.
+	CustomRecords record.CustomSet
+}
+
+// InterceptedExitHop is passed to the ExitHopInterceptor for every exit hop
+// htlc for which no invoice exists. The htlc is held until it is resolved by
+// calling either Settle or Fail, or until it reaches its auto fail height.
+type InterceptedExitHop interface {
+	// Htlc returns the intercepted htlc.
+	Htlc() InterceptedExitHopHtlc
+
+	// Settle settles the intercepted htlc with the given preimage.
+	Settle(preimage lntypes.Preimage) error
+
+	// Fail fails the intercepted htlc with the given failure message.
+	Fail(failure lnwire.FailureMessage) error
+}
+
+// interceptedExitHop implements the InterceptedExitHop interface.
+type interceptedExitHop struct {
+	registry *InvoiceRegistry
+	htlc     InterceptedExitHopHtlc
+}
+
+// Htlc returns the intercepted htlc.
+func (h *interceptedExitHop) Htlc() InterceptedExitHopHtlc {
+	return h.htlc
+}
+
+// Settle settles the intercepted htlc with the given preimage. The preimage
+// is added to the preimage cache first, so that the htlc can still be claimed
+// on chain.
+func (h *interceptedExitHop) Settle(preimage lntypes.Preimage) error {
+	if !preimage.Matches(h.htlc.Hash) {
+		return errors.New("preimage does not match hash")
+	}
+
+	if h.registry.cfg.AddPreimages != nil {
+		err := h.registry.cfg.AddPreimages(preimage)
+		if err != nil {
+			return fmt.Errorf("unable to add preimage: %v", err)
+		}
+	}
+
+	return h.registry.resolveIntercepted(NewSettleResolution(
+		preimage, h.htlc.CircuitKey, h.htlc.AcceptHeight,
+		ResultSettled,
+	))
+}
+
+// Fail fails the intercepted htlc with the given failure message.
+func (h *interceptedExitHop) Fail(failure lnwire.FailureMessage) error {
+	resolution := NewFailResolution(
+		h.htlc.CircuitKey, h.htlc.AcceptHeight, ResultInterceptorFailed,
+	)
+	resolution.Failure = failure
+
+	return h.registry.resolveIntercepted(resolution)
+}
+
+// SetExitHopInterceptor sets the interceptor that is handed the exit hop htlcs
+// for which no invoice exists. Unsetting the interceptor fails all the htlcs
+// it holds.
+func (i *InvoiceRegistry) SetExitHopInterceptor(
+	interceptor ExitHopInterceptor) {
+
+	i.Lock()
+	defer i.Unlock()
+
+	i.exitHopInterceptor = interceptor
+	if interceptor != nil {
+		return
+	}
+
+	log.Debugf("Exit hop interceptor unset, failing %v intercepted htlcs",
+		len(i.interceptedHtlcs))
+
+	for key, intercepted := range i.interceptedHtlcs {
+		delete(i.interceptedHtlcs, key)
+
+		i.notifyHodlSubscribers(NewFailResolution(
+			key, intercepted.htlc.AcceptHeight,
+			ResultInvoiceNotFound,
+		))
+	}
+}
+
+// interceptExitHop hands the exit hop htlc described by the given update
+// context, for which no invoice exists, to the exit hop interceptor. The htlc
+// is held until it is resolved, which is sent on the given hodl channel. It
+// returns false if the htlc isn't intercepted.
+func (i *InvoiceRegistry) interceptExitHop(ctx *invoiceUpdateCtx,
+	hodlChan chan<- interface{}) bool {
+
+	i.Lock()
+
+	interceptor := i.exitHopInterceptor
+	if interceptor == nil {
+		i.Unlock()
+		return false
+	}
+
+	// The htlc can't be held if it is about to expire.
+	if i.interceptExpiresSoon(ctx.expiry, ctx.currentHeight) {
+		i.Unlock()
+		ctx.log("not intercepting htlc that expires too soon")
+
+		return false
+	}
+
+	// The htlc is offered again if the link restarts or the channel is
+	// closed while it is held. The interceptor already has it, so we only
+	// subscribe the caller to its resolution.
+	if _, ok := i.interceptedHtlcs[ctx.circuitKey]; ok {
+		i.hodlSubscribe(hodlChan, ctx.circuitKey)
+		i.Unlock()
+
+		return true
+	}
+
+	intercepted := &interceptedExitHop{
+		registry: i,
+		htlc: InterceptedExitHopHtlc{
+			CircuitKey:    ctx.circuitKey,
+			Hash:          ctx.hash,
+			Amount:        ctx.amtPaid,
+			Expiry:        ctx.expiry,
+			AcceptHeight:  ctx.currentHeight,
+			CustomRecords: ctx.customRecords,
+			AutoFailHeight: ctx.expiry -
+				uint32(i.cfg.FinalCltvRejectDelta),
+		},
+	}
+	i.interceptedHtlcs[ctx.circuitKey] = intercepted
+	i.hodlSubscribe(hodlChan, ctx.circuitKey)
+	i.Unlock()
+
+	ctx.log("intercepted htlc")
+
+	// The interceptor is called without the lock held, as it may block
+	// until it takes the htlc.
+	if interceptor(intercepted) {
+		return true
+	}
+
+	// The interceptor couldn't take the htlc as it is disconnecting, so we
+	// fail it, unless the disconnect already did.
+	err := i.resolveIntercepted(NewFailResolution(
+		ctx.circuitKey, ctx.currentHeight, ResultInvoiceNotFound,
+	))
+	if err != nil && err != ErrHtlcNotIntercepted {
+		log.Errorf("Unable to fail intercepted htlc %v: %v",
+			ctx.circuitKey, err)
+	}
+
+	return true
+}
+
+// interceptExpiresSoon returns true if an intercepted htlc with the given
+// expiry may no longer be held at the given height.
+func (i *InvoiceRegistry) interceptExpiresSoon(expiry uint32,
+	height int32) bool {
+
+	return expiry <= uint32(height+i.cfg.FinalCltvRejectDelta)
+}
+
+// interceptExpiryLoop fails the intercepted htlcs that are about to expire
+// upon each new block, so that the incoming channel isn't force closed if the
+// interceptor doesn't resolve them in time.
+func (i *InvoiceRegistry) interceptExpiryLoop(
+	blockEpochs *chainntnfs.BlockEpochEvent) {
+
+	defer i.wg.Done()
+	defer blockEpochs.Cancel()
+
+	for {
+		select {
+		case epoch, ok := <-blockEpochs.Epochs:
+			if !ok {
+				return
+			}
+
+			i.failExpiredIntercepted(epoch.Height)
+
+		case <-i.quit:
+			return
+		}
+	}
+}
+
+// failExpiredIntercepted fails the intercepted htlcs that may no longer be
+// held at the given height.
+func (i *InvoiceRegistry) failExpiredIntercepted(height int32) {
+	i.Lock()
+	defer i.Unlock()
+
+	for key, intercepted := range i.interceptedHtlcs {
+		if !i.interceptExpiresSoon(intercepted.htlc.Expiry, height) {
+			continue
+		}
+
+		log.Debugf("Failing intercepted htlc %v expiring at height %v",
+			key, intercepted.htlc.Expiry)
+
+		delete(i.interceptedHtlcs, key)
+
+		i.notifyHodlSubscribers(NewFailResolution(
+			key, intercepted.htlc.AcceptHeight, ResultExpiryTooSoon,
+		))
+	}
+}
+
+// resolveIntercepted resolves the intercepted htlc with the given resolution.
+func (i *InvoiceRegistry) resolveIntercepted(resolution HtlcResolution) error {
+	i.Lock()
+	defer i.Unlock()
+
+	key := resolution.CircuitKey()
+	if _, ok := i.interceptedHtlcs[key]; !ok {
+		return ErrHtlcNotIntercepted
+	}
+	delete(i.interceptedHtlcs, key)
+
+	log.Debugf("Resolving intercepted htlc %v with %T", key, resolution)
+
+	i.notifyHodlSubscribers(resolution)
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lntypes"
@@ -33,6 +34,10 @@ const (
 	// DefaultHtlcHoldDuration defines the default for how long mpp htlcs
 	// are held while waiting for the other set members to arrive.
 	DefaultHtlcHoldDuration = 120 * time.Second
+
+	// invoiceGcInterval is the interval at which canceled invoices are
+	// garbage collected, if enabled.
+	invoiceGcInterval = time.Hour
 )
 
 // RegistryConfig contains the configuration parameters for invoice registry.
@@ -60,6 +65,26 @@ type RegistryConfig struct {
 	// KeysendHoldTime indicates for how long we want to accept and hold
 	// spontaneous keysend payments.
 	KeysendHoldTime time.Duration
+
+	// AcceptAMP indicates whether we want to accept spontaneous AMP
+	// payments.
+	AcceptAMP bool
+
+	// GcCanceledInvoicesAfter is the age after which canceled invoices
+	// are deleted from the database. If zero, canceled invoices are never
+	// deleted automatically.
+	GcCanceledInvoicesAfter time.Duration
+
+	// AddPreimages adds the preimages of the exit hop htlcs that are
+	// settled by the exit hop interceptor to the preimage cache, as they
+	// aren't stored with an invoice.
+	AddPreimages func(preimages ...lntypes.Preimage) error
+
+	// Notifier is used to receive the new blocks, upon which the exit hop
+	// htlcs held by the interceptor that are about to expire are failed.
+	// If nil, held htlcs are only failed once they're resolved or the
+	// interceptor is unset.
+	Notifier chainntnfs.ChainNotifier
 }
 
 // htlcReleaseEvent describes an htlc auto-release event. It is used to release
@@ -118,6 +143,14 @@ type InvoiceRegistry struct {
 	// auto-released.
 	htlcAutoReleaseChan chan *htlcReleaseEvent
 
+	// exitHopInterceptor is handed the exit hop htlcs for which no invoice
+	// exists, if set.
+	exitHopInterceptor ExitHopInterceptor
+
+	// interceptedHtlcs holds the exit hop htlcs that were handed to the
+	// exit hop interceptor and aren't resolved yet.
+	interceptedHtlcs map[channeldb.CircuitKey]*interceptedExitHop
+
 	expiryWatcher *InvoiceExpiryWatcher
 
 	wg   sync.WaitGroup
@@ -142,6 +175,7 @@ func NewRegistry(cdb *channeldb.DB, expiryWatcher *InvoiceExpiryWatcher,
 		hodlReverseSubscriptions:  make(map[chan<- interface{}]map[channeldb.CircuitKey]struct{}),
 		cfg:                       cfg,
 		htlcAutoReleaseChan:       make(chan *htlcReleaseEvent),
+		interceptedHtlcs:          make(map[channeldb.CircuitKey]*interceptedExitHop),
 		expiryWatcher:             expiryWatcher,
 		quit:                      make(chan struct{}),
 	}
@@ -178,6 +212,22 @@ func (i *InvoiceRegistry) Start() error {
 	i.wg.Add(1)
 	go i.invoiceEventLoop()
 
+	if i.cfg.GcCanceledInvoicesAfter > 0 {
+		i.wg.Add(1)
+		go i.invoiceGcLoop()
+	}
+
+	if i.cfg.Notifier != nil {
+		blockEpochs, err := i.cfg.Notifier.RegisterBlockEpochNtfn(nil)
+		if err != nil {
+			i.Stop()
+			return err
+		}
+
+		i.wg.Add(1)
+		go i.interceptExpiryLoop(blockEpochs)
+	}
+
 	// Now prefetch all pending invoices to the expiry watcher.
 	err = i.populateExpiryWatcher()
 	if err != nil {
@@ -203,6 +253,17 @@ func (i *InvoiceRegistry) Stop() {
 type invoiceEvent struct {
 	hash    lntypes.Hash
 	invoice *channeldb.Invoice
+
+	// setID is the id of the settled htlc set if the event reports the
+	// settlement of a single set of an AMP invoice. AMP invoices remain
+	// open when a set is settled.
+	setID *channeldb.SetID
+}
+
+// isSettle returns true if the event reports the settlement of an invoice or
+// of a single htlc set of an AMP invoice.
+func (e *invoiceEvent) isSettle() bool {
+	return e.invoice.State == channeldb.ContractSettled || e.setID != nil
 }
 
 // tickAt returns a channel that ticks at the specified time. If the time has
@@ -321,7 +382,22 @@ func (i *InvoiceRegistry) invoiceEventLoop() {
 func (i *InvoiceRegistry) dispatchToSingleClients(event *invoiceEvent) {
 	// Dispatch to single invoice subscribers.
 	for _, client := range i.singleNotificationClients {
-		if client.invoiceRef.PayHash() != event.hash {
+		payHash := client.invoiceRef.PayHash()
+		if payHash == nil {
+			continue
+		}
+
+		// Events for AMP invoices are identified by the set id of the
+		// htlc set that triggered them, which may differ from the
+		// invoice's payment hash. Match those on the invoice's payment
+		// address instead.
+		match := *payHash == event.hash
+		if !match && event.invoice.IsAMP() {
+			payAddr := client.invoiceRef.PayAddr()
+			match = payAddr != nil &&
+				*payAddr == event.invoice.Terms.PaymentAddr
+		}
+		if !match {
 			continue
 		}
 
@@ -342,33 +418,33 @@ func (i *InvoiceRegistry) dispatchToClients(event *invoiceEvent) {
 		// ensure we don't duplicate any events.
 
 		// TODO(joostjager): Refactor switches.
-		state := event.invoice.State
+		var (
+			settle = event.isSettle()
+			add    = !settle &&
+				event.invoice.State == channeldb.ContractOpen
+		)
 		switch {
 		// If we've already sent this settle event to
 		// the client, then we can skip this.
-		case state == channeldb.ContractSettled &&
-			client.settleIndex >= invoice.SettleIndex:
+		case settle && client.settleIndex >= invoice.SettleIndex:
 			continue
 
 		// Similarly, if we've already sent this add to
 		// the client then we can skip this one.
-		case state == channeldb.ContractOpen &&
-			client.addIndex >= invoice.AddIndex:
+		case add && client.addIndex >= invoice.AddIndex:
 			continue
 
 		// These two states should never happen, but we
 		// log them just in case so we can detect this
 		// instance.
-		case state == channeldb.ContractOpen &&
-			client.addIndex+1 != invoice.AddIndex:
+		case add && client.addIndex+1 != invoice.AddIndex:
 			log.Warnf("client=%v for invoice "+
 				"notifications missed an update, "+
 				"add_index=%v, new add event index=%v",
 				clientID, client.addIndex,
 				invoice.AddIndex)
 
-		case state == channeldb.ContractSettled &&
-			client.settleIndex+1 != invoice.SettleIndex:
+		case settle && client.settleIndex+1 != invoice.SettleIndex:
 			log.Warnf("client=%v for invoice "+
 				"notifications missed an update, "+
 				"settle_index=%v, new settle event index=%v",
@@ -379,6 +455,7 @@ func (i *InvoiceRegistry) dispatchToClients(event *invoiceEvent) {
 		select {
 		case client.ntfnQueue.ChanIn() <- &invoiceEvent{
 			invoice: invoice,
+			setID:   event.setID,
 		}:
 		case <-i.quit:
 			return
@@ -388,10 +465,10 @@ func (i *InvoiceRegistry) dispatchToClients(event *invoiceEvent) {
 		// the latest add/settle index it has. We'll use this to ensure
 		// we don't send a notification twice, which can happen if a new
 		// event is added while we're catching up a new client.
-		switch event.invoice.State {
-		case channeldb.ContractSettled:
+		switch {
+		case settle:
 			client.settleIndex = invoice.SettleIndex
-		case channeldb.ContractOpen:
+		case add:
 			client.addIndex = invoice.AddIndex
 		default:
 			log.Errorf("unexpected invoice state: %v",
@@ -435,10 +512,18 @@ func (i *InvoiceRegistry) deliverBacklogEvents(client *InvoiceSubscription) erro
 		// the loop reference causing is to point to the same item.
 		settleEvent := settleEvent
 
-		select {
-		case client.ntfnQueue.ChanIn() <- &invoiceEvent{
+		// AMP invoices remain open when their htlc sets are settled,
+		// so the settled set is looked up to report it as a settle
+		// event.
+		event := &invoiceEvent{
 			invoice: &settleEvent,
-		}:
+		}
+		if settleEvent.IsAMP() {
+			event.setID = settledAMPSet(&settleEvent)
+		}
+
+		select {
+		case client.ntfnQueue.ChanIn() <- event:
 		case <-i.quit:
 			return ErrShuttingDown
 		}
@@ -468,8 +553,22 @@ func (i *InvoiceRegistry) deliverSingleBacklogEvents(
 		return err
 	}
 
+	payHash := client.invoiceRef.PayHash()
+	if payHash == nil {
+		return nil
+	}
+
+	// AMP invoices are settled per htlc set, so record the invoice's
+	// payment address to be able to match the events of each set against
+	// this subscription.
+	if invoice.IsAMP() {
+		client.invoiceRef = channeldb.InvoiceRefByHashAndAddr(
+			*payHash, invoice.Terms.PaymentAddr,
+		)
+	}
+
 	err = client.notify(&invoiceEvent{
-		hash:    client.invoiceRef.PayHash(),
+		hash:    *payHash,
 		invoice: &invoice,
 	})
 	if err != nil {
@@ -527,6 +626,58 @@ func (i *InvoiceRegistry) LookupInvoice(rHash lntypes.Hash) (channeldb.Invoice,
 	return i.cdb.LookupInvoice(ref)
 }
 
+// DeleteInvoice deletes the settled or canceled invoice with the given payment
+// hash from the database.
+func (i *InvoiceRegistry) DeleteInvoice(rHash lntypes.Hash) error {
+	i.Lock()
+	defer i.Unlock()
+
+	ref := channeldb.InvoiceRefByHash(rHash)
+	if err := i.cdb.DeleteInvoice(ref); err != nil {
+		return err
+	}
+
+	log.Debugf("Invoice(%v): deleted", rHash)
+
+	return nil
+}
+
+// deleteCanceledInvoices deletes the canceled invoices that were created
+// longer than the configured age ago.
+func (i *InvoiceRegistry) deleteCanceledInvoices() {
+	i.Lock()
+	defer i.Unlock()
+
+	createdBefore := i.cfg.Clock.Now().Add(-i.cfg.GcCanceledInvoicesAfter)
+	numDeleted, err := i.cdb.DeleteCanceledInvoices(createdBefore)
+	if err != nil {
+		log.Errorf("Unable to delete canceled invoices: %v", err)
+		return
+	}
+
+	if numDeleted > 0 {
+		log.Infof("Deleted %v canceled invoices created before %v",
+			numDeleted, createdBefore)
+	}
+}
+
+// invoiceGcLoop periodically deletes old canceled invoices from the database.
+//
+// NOTE: MUST be run as a goroutine.
+func (i *InvoiceRegistry) invoiceGcLoop() {
+	defer i.wg.Done()
+
+	for {
+		i.deleteCanceledInvoices()
+
+		select {
+		case <-i.cfg.Clock.TickAfter(invoiceGcInterval):
+		case <-i.quit:
+			return
+		}
+	}
+}
+
 // startHtlcTimer starts a new timer via the invoice registry main loop that
 // cancels a single htlc on an invoice when the htlc hold duration has passed.
 func (i *InvoiceRegistry) startHtlcTimer(invoiceRef channeldb.InvoiceRef,
@@ -712,6 +863,72 @@ func (i *InvoiceRegistry) processKeySend(ctx invoiceUpdateCtx) error {
 	return nil
 }
 
+// processAMP just-in-time inserts an invoice if this htlc is a spontaneous AMP
+// htlc. The invoice is indexed by the payment address of the mpp record and
+// uses the set id as its payment hash, since every htlc in the set carries a
+// distinct payment hash.
+func (i *InvoiceRegistry) processAMP(ctx invoiceUpdateCtx) error {
+	// AMP payments MUST also include an MPP record.
+	if ctx.mpp == nil {
+		return errors.New("no MPP specified for AMP")
+	}
+
+	// Create an invoice for the total amount expected, provided in the MPP
+	// record.
+	amt := ctx.mpp.TotalMAtoms()
+
+	// Set the TLV and MPP optional features on the invoice. We'll also make
+	// the AMP features required so that it can't be paid by legacy or MPP
+	// htlcs.
+	rawFeatures := lnwire.NewRawFeatureVector(
+		lnwire.TLVOnionPayloadOptional,
+		lnwire.PaymentAddrOptional,
+		lnwire.MPPOptional,
+		lnwire.AMPRequired,
+	)
+	features := lnwire.NewFeatureVector(rawFeatures, lnwire.Features)
+
+	// Use the minimum block delta that we require for settling htlcs.
+	finalCltvDelta := i.cfg.FinalCltvRejectDelta
+
+	// Pre-check expiry here to prevent inserting an invoice that will not
+	// be settled.
+	if ctx.expiry < uint32(ctx.currentHeight+finalCltvDelta) {
+		return errors.New("final expiry too soon")
+	}
+
+	// We'll use the sender-generated payment address provided in the HTLC
+	// to create our AMP invoice.
+	payAddr := ctx.mpp.PaymentAddr()
+
+	// Create placeholder invoice. AMP invoices don't have a preimage, as
+	// each htlc's preimage is derived once the full set has arrived.
+	invoice := &channeldb.Invoice{
+		CreationDate: i.cfg.Clock.Now(),
+		Terms: channeldb.ContractTerm{
+			FinalCltvDelta: finalCltvDelta,
+			Value:          amt,
+			PaymentAddr:    payAddr,
+			Features:       features,
+		},
+	}
+
+	// Insert invoice into database. Ignore duplicates payment hashes and
+	// payment addrs, this may be a replay or a different HTLC for the AMP
+	// invoice.
+	_, err := i.AddInvoice(invoice, ctx.amp.SetID())
+	switch {
+	case err == channeldb.ErrDuplicateInvoice:
+		return nil
+
+	case err == channeldb.ErrDuplicatePayAddr:
+		return nil
+
+	default:
+		return err
+	}
+}
+
 // NotifyExitHopHtlc attempts to mark an invoice as settled. The return value
 // describes how the htlc should be resolved.
 //
@@ -743,6 +960,7 @@ func (i *InvoiceRegistry) NotifyExitHopHtlc(rHash lntypes.Hash,
 		finalCltvRejectDelta: i.cfg.FinalCltvRejectDelta,
 		customRecords:        payload.CustomRecords(),
 		mpp:                  payload.MultiPath(),
+		amp:                  payload.AMPRecord(),
 	}
 
 	// Process keysend if present. Do this outside of the lock, because
@@ -759,6 +977,20 @@ func (i *InvoiceRegistry) NotifyExitHopHtlc(rHash lntypes.Hash,
 		}
 	}
 
+	// Process AMP if present. Do this outside of the lock, because
+	// AddInvoice obtains its own lock. This is no problem, because the
+	// operation is idempotent.
+	if i.cfg.AcceptAMP && ctx.amp != nil {
+		err := i.processAMP(ctx)
+		if err != nil {
+			ctx.log(fmt.Sprintf("amp error: %v", err))
+
+			return NewFailResolution(
+				circuitKey, currentHeight, ResultAmpError,
+			), nil
+		}
+	}
+
 	// Execute locked notify exit hop logic.
 	i.Lock()
 	resolution, err := i.notifyExitHopHtlcLocked(&ctx, hodlChan)
@@ -767,6 +999,15 @@ func (i *InvoiceRegistry) NotifyExitHopHtlc(rHash lntypes.Hash,
 		return nil, err
 	}
 
+	// Without a matching invoice, the htlc may be intercepted in order to
+	// be resolved externally.
+	if r, ok := resolution.(*HtlcFailResolution); ok &&
+		r.Outcome == ResultInvoiceNotFound &&
+		i.interceptExitHop(&ctx, hodlChan) {
+
+		return nil, nil
+	}
+
 	switch r := resolution.(type) {
 	// The htlc is held. Start a timer outside the lock if the htlc should
 	// be auto-released, because otherwise a deadlock may happen with the
@@ -807,6 +1048,7 @@ func (i *InvoiceRegistry) notifyExitHopHtlcLocked(
 	var (
 		resolution        HtlcResolution
 		updateSubscribers bool
+		settledSet        *channeldb.SetID
 	)
 	invoice, err := i.cdb.UpdateInvoice(
 		ctx.invoiceRef(),
@@ -822,6 +1064,16 @@ func (i *InvoiceRegistry) notifyExitHopHtlcLocked(
 			updateSubscribers = updateDesc != nil &&
 				updateDesc.State != nil
 
+			// Settling a set of an AMP invoice leaves the invoice
+			// open, so we keep track of the set to report its
+			// settlement.
+			if updateSubscribers && inv.IsAMP() &&
+				updateDesc.State.NewState ==
+					channeldb.ContractSettled {
+
+				settledSet = updateDesc.State.SetID
+			}
+
 			// Assign resolution to outer scope variable.
 			resolution = res
 
@@ -877,13 +1129,28 @@ func (i *InvoiceRegistry) notifyExitHopHtlcLocked(
 				continue
 			}
 
+			// Htlcs of previously settled AMP sets have already
+			// been resolved, only settle those of the current set.
+			if ctx.amp != nil && (htlc.AMP == nil ||
+				htlc.AMP.Record.SetID() != ctx.amp.SetID()) {
+
+				continue
+			}
+
+			// AMP htlcs are settled with their own preimage,
+			// derived when the set was reconstructed.
+			preimage := res.Preimage
+			if htlc.AMP != nil && htlc.AMP.Preimage != nil {
+				preimage = *htlc.AMP.Preimage
+			}
+
 			// Notify subscribers that the htlcs should be settled
 			// with our peer. Note that the outcome of the
 			// resolution is set based on the outcome of the single
 			// htlc that we just settled, so may not be accurate
 			// for all htlcs.
 			htlcSettleResolution := NewSettleResolution(
-				res.Preimage, key,
+				preimage, key,
 				int32(htlc.AcceptHeight), res.Outcome,
 			)
 
@@ -930,8 +1197,12 @@ func (i *InvoiceRegistry) notifyExitHopHtlcLocked(
 	// Now that the links have been notified of any state changes to their
 	// HTLCs, we'll go ahead and notify any clients wiaiting on the invoice
 	// state changes.
-	if updateSubscribers {
-		i.notifyClients(ctx.hash, invoice, invoice.State)
+	switch {
+	case settledSet != nil:
+		i.notifyAMPSettle(ctx.invoiceHash(), invoice, *settledSet)
+
+	case updateSubscribers:
+		i.notifyClients(ctx.invoiceHash(), invoice, invoice.State)
 	}
 
 	return resolution, nil
@@ -1095,6 +1366,37 @@ func (i *InvoiceRegistry) notifyClients(hash lntypes.Hash,
 	}
 }
 
+// notifyAMPSettle notifies all currently registered invoice notification
+// clients of the settlement of a single htlc set of an AMP invoice.
+func (i *InvoiceRegistry) notifyAMPSettle(hash lntypes.Hash,
+	invoice *channeldb.Invoice, setID channeldb.SetID) {
+
+	event := &invoiceEvent{
+		invoice: invoice,
+		hash:    hash,
+		setID:   &setID,
+	}
+
+	select {
+	case i.invoiceEvents <- event:
+	case <-i.quit:
+	}
+}
+
+// settledAMPSet returns the id of the htlc set of the AMP invoice that has
+// the same settle index as the invoice, which is the set whose settlement the
+// invoice reports.
+func settledAMPSet(invoice *channeldb.Invoice) *channeldb.SetID {
+	for setID, ampState := range invoice.AMPState {
+		if ampState.SettleIndex == invoice.SettleIndex {
+			setID := setID
+			return &setID
+		}
+	}
+
+	return nil
+}
+
 // invoiceSubscriptionKit defines that are common to both all invoice
 // subscribers and single invoice subscribers.
 type invoiceSubscriptionKit struct {
@@ -1224,11 +1526,11 @@ func (i *InvoiceRegistry) SubscribeNotifications(
 
 				var targetChan chan *channeldb.Invoice
 				state := invoiceEvent.invoice.State
-				switch state {
-				case channeldb.ContractOpen:
-					targetChan = client.NewInvoices
-				case channeldb.ContractSettled:
+				switch {
+				case invoiceEvent.isSettle():
 					targetChan = client.SettledInvoices
+				case state == channeldb.ContractOpen:
+					targetChan = client.NewInvoices
 				default:
 					log.Errorf("unknown invoice "+
 						"state: %v", state)
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/decred/dcrlnd/amp"
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lntypes"
@@ -29,7 +31,7 @@ func TestSettleInvoice(t *testing.T) {
 	}
 	defer subscription.Cancel()
 
-	if subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
+	if *subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
 		t.Fatalf("expected subscription for provided hash")
 	}
 
@@ -244,7 +246,7 @@ func TestCancelInvoice(t *testing.T) {
 	}
 	defer subscription.Cancel()
 
-	if subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
+	if *subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
 		t.Fatalf("expected subscription for provided hash")
 	}
 
@@ -373,7 +375,7 @@ func TestSettleHoldInvoice(t *testing.T) {
 	}
 	defer subscription.Cancel()
 
-	if subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
+	if *subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
 		t.Fatalf("expected subscription for provided hash")
 	}
 
@@ -1086,3 +1088,378 @@ func TestInvoiceExpiryWithRegistry(t *testing.T) {
 		}
 	}
 }
+
+// TestAMPPayment asserts that a spontaneous AMP payment is accepted when AMP
+// is enabled, and that each htlc in the set is settled with the child
+// preimage reconstructed from the shares of the full set.
+func TestAMPPayment(t *testing.T) {
+	defer timeout()()
+
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	ctx.registry.cfg.AcceptAMP = true
+
+	var (
+		setID   = [32]byte{0x01}
+		payAddr = [32]byte{0x02}
+		amt     = lnwire.MilliAtom(2000)
+	)
+
+	// Split a random root seed into two shares, one for each htlc.
+	sharer, err := amp.NewSeedSharer()
+	require.NoError(t, err)
+
+	left, right, err := sharer.Split()
+	require.NoError(t, err)
+
+	children := []*amp.Child{left.Child(0), right.Child(1)}
+
+	payloads := make([]*mockPayload, len(children))
+	for i, child := range children {
+		payloads[i] = &mockPayload{
+			mpp: record.NewMPP(amt, payAddr),
+			amp: record.NewAMP(child.Share, setID, child.Index),
+		}
+	}
+
+	// An AMP htlc without an mpp record is rejected.
+	resolution, err := ctx.registry.NotifyExitHopHtlc(
+		children[0].Hash, amt/2, testHtlcExpiry, testCurrentHeight,
+		getCircuitKey(9), make(chan interface{}, 1),
+		&mockPayload{amp: payloads[0].amp},
+	)
+	require.NoError(t, err)
+	failResolution, ok := resolution.(*HtlcFailResolution)
+	require.True(t, ok, "expected fail resolution")
+	require.Equal(t, ResultAmpError, failResolution.Outcome)
+
+	// Send the first htlc, which should be held until the set is
+	// complete.
+	hodlChan1 := make(chan interface{}, 1)
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		children[0].Hash, amt/2, testHtlcExpiry, testCurrentHeight,
+		getCircuitKey(10), hodlChan1, payloads[0],
+	)
+	require.NoError(t, err)
+	require.Nil(t, resolution, "expected no direct resolution")
+
+	// Send the second htlc, completing the set. This should settle the
+	// htlc directly with its own child preimage.
+	hodlChan2 := make(chan interface{}, 1)
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		children[1].Hash, amt/2, testHtlcExpiry, testCurrentHeight,
+		getCircuitKey(11), hodlChan2, payloads[1],
+	)
+	require.NoError(t, err)
+	settleResolution, ok := resolution.(*HtlcSettleResolution)
+	require.True(t, ok, "expected settle resolution")
+	require.Equal(t, ResultSettled, settleResolution.Outcome)
+	require.Equal(t, children[1].Preimage, settleResolution.Preimage)
+
+	// The first htlc should also be settled with its own preimage.
+	htlcResolution := (<-hodlChan1).(HtlcResolution)
+	settleResolution, ok = htlcResolution.(*HtlcSettleResolution)
+	require.True(t, ok, "expected settle resolution")
+	require.Equal(t, children[0].Preimage, settleResolution.Preimage)
+
+	// Assert that the set was settled for the full amount, while the
+	// invoice itself remains open.
+	inv, err := ctx.registry.LookupInvoice(setID)
+	require.NoError(t, err)
+	require.Equal(t, channeldb.ContractOpen, inv.State)
+	require.Equal(t, amt, inv.AmtPaid)
+	require.True(t, inv.IsAMP())
+	require.Equal(t, amt, inv.AMPState[setID].AmtPaid)
+
+	// Subscribe to the invoice, which is identified by the set id of the
+	// payment that created it.
+	subscription, err := ctx.registry.SubscribeSingleInvoice(setID)
+	require.NoError(t, err)
+	defer subscription.Cancel()
+
+	update := <-subscription.Updates
+	require.Len(t, update.AMPState, 1)
+
+	// A new htlc for the already settled set must be rejected.
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		children[0].Hash, amt/2, testHtlcExpiry, testCurrentHeight,
+		getCircuitKey(12), make(chan interface{}, 1), payloads[0],
+	)
+	require.NoError(t, err)
+	failResolution, ok = resolution.(*HtlcFailResolution)
+	require.True(t, ok, "expected fail resolution")
+	require.Equal(t, ResultAmpError, failResolution.Outcome)
+
+	// Subscribe to all invoice notifications, to be notified of the
+	// settlement of the next set.
+	allSubscriptions, err := ctx.registry.SubscribeNotifications(0, 0)
+	require.NoError(t, err)
+	defer allSubscriptions.Cancel()
+
+	// Pay the invoice again using a new set consisting of a single htlc.
+	// This should settle the new set independently.
+	sharer2, err := amp.NewSeedSharer()
+	require.NoError(t, err)
+
+	setID2 := [32]byte{0x03}
+	child := sharer2.Child(0)
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		child.Hash, amt, testHtlcExpiry, testCurrentHeight,
+		getCircuitKey(13), make(chan interface{}, 1), &mockPayload{
+			mpp: record.NewMPP(amt, payAddr),
+			amp: record.NewAMP(child.Share, setID2, child.Index),
+		},
+	)
+	require.NoError(t, err)
+	settleResolution, ok = resolution.(*HtlcSettleResolution)
+	require.True(t, ok, "expected settle resolution")
+	require.Equal(t, child.Preimage, settleResolution.Preimage)
+
+	// The subscriber should be notified of the settlement of the second
+	// set.
+	update = <-subscription.Updates
+	require.Equal(t, channeldb.ContractOpen, update.State)
+	require.Len(t, update.AMPState, 2)
+	require.Equal(t, 2*amt, update.AmtPaid)
+
+	// Subscribers to all invoices should be notified of the settlement of
+	// the set as well, with the settle index of the set.
+	settled := <-allSubscriptions.SettledInvoices
+	require.Equal(t, channeldb.ContractOpen, settled.State)
+	require.Equal(t, uint64(2), settled.SettleIndex)
+	require.Equal(t, uint64(2), settled.AMPState[setID2].SettleIndex)
+
+	// A client that only saw the settlement of the first set should be
+	// caught up with the settlement of the second one.
+	backlogSubscription, err := ctx.registry.SubscribeNotifications(0, 1)
+	require.NoError(t, err)
+	defer backlogSubscription.Cancel()
+
+	settled = <-backlogSubscription.SettledInvoices
+	require.Equal(t, uint64(2), settled.SettleIndex)
+}
+
+// TestDeleteInvoice tests that only invoices that are no longer pending can be
+// deleted from the registry.
+func TestDeleteInvoice(t *testing.T) {
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	_, err := ctx.registry.AddInvoice(testInvoice, testInvoicePaymentHash)
+	require.NoError(t, err)
+
+	// The invoice is still open, so it can't be deleted yet.
+	err = ctx.registry.DeleteInvoice(testInvoicePaymentHash)
+	require.Equal(t, channeldb.ErrInvoicePending, err)
+
+	// Once canceled, the invoice can be deleted.
+	err = ctx.registry.CancelInvoice(testInvoicePaymentHash)
+	require.NoError(t, err)
+
+	err = ctx.registry.DeleteInvoice(testInvoicePaymentHash)
+	require.NoError(t, err)
+
+	_, err = ctx.registry.LookupInvoice(testInvoicePaymentHash)
+	require.Equal(t, channeldb.ErrInvoiceNotFound, err)
+}
+
+// TestExitHopInterceptor asserts that exit hop htlcs without a matching
+// invoice are held by the exit hop interceptor until it resolves them, and
+// that they are failed once the interceptor is unset.
+func TestExitHopInterceptor(t *testing.T) {
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	// The htlcs are accepted a block before their auto fail height.
+	htlcExpiry := uint32(testCurrentHeight+testFinalCltvRejectDelta) + 1
+
+	var preimages []lntypes.Preimage
+	ctx.registry.cfg.AddPreimages = func(p ...lntypes.Preimage) error {
+		preimages = append(preimages, p...)
+		return nil
+	}
+
+	intercepted := make(chan InterceptedExitHop, 1)
+	ctx.registry.SetExitHopInterceptor(func(h InterceptedExitHop) bool {
+		intercepted <- h
+		return true
+	})
+
+	amt := lnwire.MilliAtom(100000)
+	notify := func(htlcID uint64, expiry uint32,
+		hodlChan chan interface{}) HtlcResolution {
+
+		resolution, err := ctx.registry.NotifyExitHopHtlc(
+			testInvoicePaymentHash, amt, expiry, testCurrentHeight,
+			getCircuitKey(htlcID), hodlChan, testPayload,
+		)
+		require.NoError(t, err)
+
+		return resolution
+	}
+
+	// The htlc without invoice is held and handed to the interceptor.
+	hodlChan := make(chan interface{}, 1)
+	require.Nil(t, notify(0, htlcExpiry, hodlChan))
+
+	htlc := <-intercepted
+	require.Equal(t, InterceptedExitHopHtlc{
+		CircuitKey:     getCircuitKey(0),
+		Hash:           testInvoicePaymentHash,
+		Amount:         amt,
+		Expiry:         htlcExpiry,
+		AcceptHeight:   testCurrentHeight,
+		CustomRecords:  record.CustomSet{},
+		AutoFailHeight: htlcExpiry - uint32(testFinalCltvRejectDelta),
+	}, htlc.Htlc())
+
+	// It can only be settled with its preimage, which is then added to the
+	// preimage cache.
+	require.Error(t, htlc.Settle(lntypes.Preimage{2}))
+	require.NoError(t, htlc.Settle(testInvoicePreimage))
+	require.Equal(t, []lntypes.Preimage{testInvoicePreimage}, preimages)
+
+	settleResolution, ok := (<-hodlChan).(*HtlcSettleResolution)
+	require.True(t, ok)
+	require.Equal(t, testInvoicePreimage, settleResolution.Preimage)
+	require.Equal(t, getCircuitKey(0), settleResolution.CircuitKey())
+
+	require.Equal(
+		t, ErrHtlcNotIntercepted, htlc.Settle(testInvoicePreimage),
+	)
+
+	// A failed htlc carries the failure chosen by the interceptor.
+	require.Nil(t, notify(1, htlcExpiry, hodlChan))
+	htlc = <-intercepted
+
+	failure := &lnwire.FailTemporaryNodeFailure{}
+	require.NoError(t, htlc.Fail(failure))
+
+	failResolution, ok := (<-hodlChan).(*HtlcFailResolution)
+	require.True(t, ok)
+	require.Equal(t, ResultInterceptorFailed, failResolution.Outcome)
+	require.Equal(t, failure, failResolution.Failure)
+
+	// A htlc that has already reached its auto fail height isn't
+	// intercepted.
+	resolution := notify(
+		2, uint32(testCurrentHeight+testFinalCltvRejectDelta),
+		hodlChan,
+	)
+	failResolution, ok = resolution.(*HtlcFailResolution)
+	require.True(t, ok)
+	require.Equal(t, ResultInvoiceNotFound, failResolution.Outcome)
+
+	// A htlc that is offered again while it is held isn't handed to the
+	// interceptor twice, but both callers are notified of its resolution.
+	require.Nil(t, notify(3, htlcExpiry, hodlChan))
+	<-intercepted
+
+	replayChan := make(chan interface{}, 1)
+	require.Nil(t, notify(3, htlcExpiry, replayChan))
+	select {
+	case <-intercepted:
+		t.Fatal("htlc intercepted twice")
+	default:
+	}
+
+	// Unsetting the interceptor fails the htlcs that are still held.
+	ctx.registry.SetExitHopInterceptor(nil)
+	for _, c := range []chan interface{}{hodlChan, replayChan} {
+		failResolution, ok := (<-c).(*HtlcFailResolution)
+		require.True(t, ok)
+		require.Equal(t, ResultInvoiceNotFound, failResolution.Outcome)
+		require.Nil(t, failResolution.Failure)
+	}
+
+	// Without interceptor, htlcs without invoice are failed right away.
+	resolution = notify(4, htlcExpiry, hodlChan)
+	failResolution, ok = resolution.(*HtlcFailResolution)
+	require.True(t, ok)
+	require.Equal(t, ResultInvoiceNotFound, failResolution.Outcome)
+}
+
+// TestExitHopInterceptorExpiry asserts that the htlcs held by the exit hop
+// interceptor are failed once they reach their auto fail height.
+func TestExitHopInterceptorExpiry(t *testing.T) {
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	htlcExpiry := uint32(testCurrentHeight+testFinalCltvRejectDelta) + 1
+
+	intercepted := make(chan InterceptedExitHop, 1)
+	ctx.registry.SetExitHopInterceptor(func(h InterceptedExitHop) bool {
+		intercepted <- h
+		return true
+	})
+
+	// Hold two htlcs, the second one expiring a block later.
+	hodlChans := make([]chan interface{}, 2)
+	for i := range hodlChans {
+		hodlChans[i] = make(chan interface{}, 1)
+
+		resolution, err := ctx.registry.NotifyExitHopHtlc(
+			testInvoicePaymentHash, 100000,
+			htlcExpiry+uint32(i), testCurrentHeight,
+			getCircuitKey(uint64(i)), hodlChans[i], testPayload,
+		)
+		require.NoError(t, err)
+		require.Nil(t, resolution)
+		<-intercepted
+	}
+
+	autoFailHeight := int32(htlcExpiry) - testFinalCltvRejectDelta
+	notifyHeight := func(height int32) {
+		select {
+		case ctx.notifier.epochChan <- &chainntnfs.BlockEpoch{
+			Height: height,
+		}:
+		case <-time.After(5 * time.Second):
+			t.Fatal("block epoch not consumed")
+		}
+	}
+	assertFailed := func(hodlChan chan interface{}) {
+		select {
+		case resolution := <-hodlChan:
+			failResolution, ok := resolution.(*HtlcFailResolution)
+			require.True(t, ok)
+			require.Equal(
+				t, ResultExpiryTooSoon, failResolution.Outcome,
+			)
+
+		case <-time.After(5 * time.Second):
+			t.Fatal("held htlc not failed")
+		}
+	}
+	assertHeld := func(hodlChan chan interface{}) {
+		select {
+		case <-hodlChan:
+			t.Fatal("held htlc resolved")
+		default:
+		}
+	}
+
+	// Both htlcs are still held a block before the auto fail height of
+	// the first one. The epoch is sent twice, so the first one is
+	// processed once the second one is consumed.
+	notifyHeight(autoFailHeight - 1)
+	notifyHeight(autoFailHeight - 1)
+	assertHeld(hodlChans[0])
+	assertHeld(hodlChans[1])
+
+	// Only the first htlc is failed at its auto fail height, and the
+	// second one a block later.
+	notifyHeight(autoFailHeight)
+	assertFailed(hodlChans[0])
+	assertHeld(hodlChans[1])
+
+	notifyHeight(autoFailHeight + 1)
+	assertFailed(hodlChans[1])
+
+	// The interceptor can no longer resolve the failed htlcs.
+	err := ctx.registry.resolveIntercepted(NewFailResolution(
+		getCircuitKey(0), testCurrentHeight, ResultInterceptorFailed,
+	))
+	require.Equal(t, ErrHtlcNotIntercepted, err)
+}
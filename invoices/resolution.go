@@ -5,6 +5,7 @@ import (
 
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
 )
 
 // HtlcResolution describes how an htlc should be resolved.
@@ -25,6 +26,10 @@ type HtlcFailResolution struct {
 
 	// Outcome indicates the outcome of the invoice registry update.
 	Outcome FailResolutionResult
+
+	// Failure is the failure message the htlc is failed with. If nil, the
+	// failure message is derived from the outcome.
+	Failure lnwire.FailureMessage
 }
 
 // NewFailResolution returns a htlc failure resolution.
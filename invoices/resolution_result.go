@@ -105,6 +105,17 @@ const (
 	// ResultMppInProgress is returned when we are busy receiving a mpp
 	// payment.
 	ResultMppInProgress
+
+	// ResultAmpError is returned when we receive invalid AMP parameters.
+	ResultAmpError
+
+	// ResultAmpReconstruction is returned when the derived child
+	// hash/preimage pairs were invalid for at least one HTLC in the set.
+	ResultAmpReconstruction
+
+	// ResultInterceptorFailed is returned when the exit hop interceptor
+	// fails a htlc for which no invoice exists.
+	ResultInterceptorFailed
 )
 
 // String returns a string representation of the result.
@@ -162,6 +173,15 @@ func (f FailResolutionResult) FailureString() string {
 	case ResultMppInProgress:
 		return "mpp reception in progress"
 
+	case ResultAmpError:
+		return "invalid amp parameters"
+
+	case ResultAmpReconstruction:
+		return "amp reconstruction failed"
+
+	case ResultInterceptorFailed:
+		return "failed by exit hop interceptor"
+
 	default:
 		return "unknown failure resolution result"
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/decred/dcrd/chaincfg/v3"
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lntypes"
@@ -24,6 +25,7 @@ import (
 
 type mockPayload struct {
 	mpp           *record.MPP
+	amp           *record.AMP
 	customRecords record.CustomSet
 }
 
@@ -31,6 +33,10 @@ func (p *mockPayload) MultiPath() *record.MPP {
 	return p.mpp
 }
 
+func (p *mockPayload) AMPRecord() *record.AMP {
+	return p.amp
+}
+
 func (p *mockPayload) CustomRecords() record.CustomSet {
 	// This function should always return a map instance, but for mock
 	// configuration we do accept nil.
@@ -134,10 +140,34 @@ func newTestChannelDB(clock clock.Clock) (*channeldb.DB, func(), error) {
 	return cdb, cleanUp, nil
 }
 
+// mockChainNotifier is a chainntnfs.ChainNotifier that only delivers the
+// block epochs sent on its epoch channel.
+type mockChainNotifier struct {
+	chainntnfs.ChainNotifier
+
+	epochChan chan *chainntnfs.BlockEpoch
+}
+
+func newMockChainNotifier() *mockChainNotifier {
+	return &mockChainNotifier{
+		epochChan: make(chan *chainntnfs.BlockEpoch),
+	}
+}
+
+func (m *mockChainNotifier) RegisterBlockEpochNtfn(
+	*chainntnfs.BlockEpoch) (*chainntnfs.BlockEpochEvent, error) {
+
+	return &chainntnfs.BlockEpochEvent{
+		Epochs: m.epochChan,
+		Cancel: func() {},
+	}, nil
+}
+
 type testContext struct {
 	cdb      *channeldb.DB
 	registry *InvoiceRegistry
 	clock    *clock.TestClock
+	notifier *mockChainNotifier
 
 	cleanup func()
 	t       *testing.T
@@ -152,12 +182,14 @@ func newTestContext(t *testing.T) *testContext {
 	}
 
 	expiryWatcher := NewInvoiceExpiryWatcher(clock)
+	notifier := newMockChainNotifier()
 
 	// Instantiate and start the invoice ctx.registry.
 	cfg := RegistryConfig{
 		FinalCltvRejectDelta: testFinalCltvRejectDelta,
 		HtlcHoldDuration:     30 * time.Second,
 		Clock:                clock,
+		Notifier:             notifier,
 	}
 	registry := NewRegistry(cdb, expiryWatcher, &cfg)
 
@@ -171,6 +203,7 @@ func newTestContext(t *testing.T) *testContext {
 		cdb:      cdb,
 		registry: registry,
 		clock:    clock,
+		notifier: notifier,
 		t:        t,
 		cleanup: func() {
 			registry.Stop()
@@ -2,7 +2,9 @@ package invoices
 
 import (
 	"errors"
+	"fmt"
 
+	"github.com/decred/dcrlnd/amp"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwire"
@@ -20,22 +22,43 @@ type invoiceUpdateCtx struct {
 	finalCltvRejectDelta int32
 	customRecords        record.CustomSet
 	mpp                  *record.MPP
+	amp                  *record.AMP
 }
 
 // invoiceRef returns an identifier that can be used to lookup or update the
 // invoice this HTLC is targeting.
 func (i *invoiceUpdateCtx) invoiceRef() channeldb.InvoiceRef {
-	if i.mpp != nil {
+	switch {
+	// AMP HTLCs each carry a distinct payment hash, so the invoice can
+	// only be referenced by its payment address.
+	case i.amp != nil && i.mpp != nil:
+		payAddr := i.mpp.PaymentAddr()
+		return channeldb.InvoiceRefByAddr(payAddr)
+
+	case i.mpp != nil:
 		payAddr := i.mpp.PaymentAddr()
 		return channeldb.InvoiceRefByHashAndAddr(i.hash, payAddr)
+
+	default:
+		return channeldb.InvoiceRefByHash(i.hash)
+	}
+}
+
+// invoiceHash returns the payment hash that the targeted invoice is indexed
+// by. For AMP HTLCs this is the set id of the payment, as the HTLC's own
+// payment hash is only valid for that particular HTLC.
+func (i *invoiceUpdateCtx) invoiceHash() lntypes.Hash {
+	if i.amp != nil {
+		return i.amp.SetID()
 	}
-	return channeldb.InvoiceRefByHash(i.hash)
+	return i.hash
 }
 
 // log logs a message specific to this update context.
 func (i *invoiceUpdateCtx) log(s string) {
-	log.Debugf("Invoice%v: %v, amt=%v, expiry=%v, circuit=%v, mpp=%v",
-		i.invoiceRef, s, i.amtPaid, i.expiry, i.circuitKey, i.mpp)
+	log.Debugf("Invoice%v: %v, amt=%v, expiry=%v, circuit=%v, mpp=%v, "+
+		"amp=%v", i.invoiceRef(), s, i.amtPaid, i.expiry, i.circuitKey,
+		i.mpp, i.amp)
 }
 
 // failRes is a helper function which creates a failure resolution with
@@ -80,9 +103,15 @@ func updateInvoice(ctx *invoiceUpdateCtx, inv *channeldb.Invoice) (
 			return nil, ctx.acceptRes(resultReplayToAccepted), nil
 
 		case channeldb.HtlcStateSettled:
+			// AMP HTLCs are settled with their own preimage rather
+			// than the invoice's preimage.
+			preimage := inv.Terms.PaymentPreimage
+			if htlc.AMP != nil && htlc.AMP.Preimage != nil {
+				preimage = htlc.AMP.Preimage
+			}
+
 			return nil, ctx.settleRes(
-				*inv.Terms.PaymentPreimage,
-				ResultReplayToSettled,
+				*preimage, ResultReplayToSettled,
 			), nil
 
 		default:
@@ -112,6 +141,28 @@ func updateMpp(ctx *invoiceUpdateCtx,
 		CustomRecords: ctx.customRecords,
 	}
 
+	// Only AMP HTLCs may pay to AMP invoices, and AMP HTLCs may only pay
+	// to AMP invoices.
+	if (ctx.amp != nil) != inv.IsAMP() {
+		return nil, ctx.failRes(ResultAmpError), nil
+	}
+
+	// Store the AMP record and the HTLC's payment hash, since each AMP
+	// HTLC carries a distinct hash that must be verified on settlement.
+	if ctx.amp != nil {
+		// AMP invoices can be paid repeatedly, but each set may only
+		// be settled once.
+		setID := channeldb.SetID(ctx.amp.SetID())
+		if _, ok := inv.AMPState[setID]; ok {
+			return nil, ctx.failRes(ResultAmpError), nil
+		}
+
+		acceptDesc.AMP = &channeldb.InvoiceHtlcAMPData{
+			Record: *ctx.amp,
+			Hash:   ctx.hash,
+		}
+	}
+
 	// Only accept payments to open invoices. This behaviour differs from
 	// non-mpp payments that are accepted even after the invoice is settled.
 	// Because non-mpp payments don't have a payment address, this is needed
@@ -146,6 +197,14 @@ func updateMpp(ctx *invoiceUpdateCtx,
 			continue
 		}
 
+		// For AMP payments, only consider htlcs that belong to the
+		// same set as the incoming htlc.
+		if ctx.amp != nil && (htlc.AMP == nil ||
+			htlc.AMP.Record.SetID() != ctx.amp.SetID()) {
+
+			continue
+		}
+
 		if ctx.mpp.TotalMAtoms() != htlc.MppTotalAmt {
 			return nil, ctx.failRes(ResultHtlcSetTotalMismatch), nil
 		}
@@ -185,6 +244,12 @@ func updateMpp(ctx *invoiceUpdateCtx,
 		return &update, ctx.acceptRes(resultPartialAccepted), nil
 	}
 
+	// If this is an AMP payment, the set is now complete and we can
+	// attempt to reconstruct the child preimages of all htlcs in the set.
+	if ctx.amp != nil {
+		return settleAMPSet(ctx, inv, &update)
+	}
+
 	// Check to see if we can settle or this is an hold invoice and
 	// we need to wait for the preimage.
 	if inv.HodlInvoice {
@@ -204,6 +269,74 @@ func updateMpp(ctx *invoiceUpdateCtx,
 	), nil
 }
 
+// settleAMPSet reconstructs the root seed of a complete AMP set and derives
+// the child preimages of each htlc in the set. If all derived child hashes
+// match the payment hashes carried by the htlcs, the set is settled using the
+// per-htlc preimages. The invoice itself remains open, so that it can be paid
+// again using a different set id.
+func settleAMPSet(ctx *invoiceUpdateCtx, inv *channeldb.Invoice,
+	update *channeldb.InvoiceUpdateDesc) (*channeldb.InvoiceUpdateDesc,
+	HtlcResolution, error) {
+
+	// Gather the child descriptors and payment hashes of all accepted
+	// htlcs in the set, including the htlc that was just received.
+	setID := channeldb.SetID(ctx.amp.SetID())
+
+	var (
+		keys   []channeldb.CircuitKey
+		hashes []lntypes.Hash
+		descs  []amp.ChildDesc
+	)
+	addHtlc := func(key channeldb.CircuitKey,
+		ampData *channeldb.InvoiceHtlcAMPData) {
+
+		keys = append(keys, key)
+		hashes = append(hashes, ampData.Hash)
+		descs = append(descs, amp.ChildDesc{
+			Share: ampData.Record.RootShare(),
+			Index: ampData.Record.ChildIndex(),
+		})
+	}
+
+	for key, htlc := range inv.Htlcs {
+		if htlc.State != channeldb.HtlcStateAccepted ||
+			htlc.AMP == nil || htlc.AMP.Record.SetID() != setID {
+
+			continue
+		}
+
+		addHtlc(key, htlc.AMP)
+	}
+	addHtlc(ctx.circuitKey, update.AddHtlcs[ctx.circuitKey].AMP)
+
+	// Reconstruct the root seed from the shares and derive the child
+	// preimages. Each derived child hash must match the payment hash of
+	// the htlc that carried its share.
+	children := amp.ReconstructChildren(descs...)
+	preimages := make(map[channeldb.CircuitKey]lntypes.Preimage)
+	for i, child := range children {
+		if child.Hash != hashes[i] {
+			ctx.log(fmt.Sprintf("amp reconstruction failed for "+
+				"htlc %v: derived hash %v, expected %v",
+				keys[i], child.Hash, hashes[i]))
+
+			return nil, ctx.failRes(ResultAmpReconstruction), nil
+		}
+
+		preimages[keys[i]] = child.Preimage
+	}
+
+	update.State = &channeldb.InvoiceStateUpdateDesc{
+		NewState:      channeldb.ContractSettled,
+		HTLCPreimages: preimages,
+		SetID:         &setID,
+	}
+
+	return update, ctx.settleRes(
+		preimages[ctx.circuitKey], ResultSettled,
+	), nil
+}
+
 // updateLegacy is a callback for DB.UpdateInvoice that contains the invoice
 // settlement logic for legacy payments.
 func updateLegacy(ctx *invoiceUpdateCtx,
@@ -215,6 +348,12 @@ func updateLegacy(ctx *invoiceUpdateCtx,
 		return nil, ctx.failRes(ResultInvoiceAlreadyCanceled), nil
 	}
 
+	// AMP invoices can only be paid by AMP htlcs, which always carry an
+	// mpp record.
+	if inv.IsAMP() {
+		return nil, ctx.failRes(ResultAmpError), nil
+	}
+
 	// If an invoice amount is specified, check that enough is paid. Also
 	// check this for duplicate payments if the invoice is already settled
 	// or accepted. In case this is a zero-valued invoice, it will always be
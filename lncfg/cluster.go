@@ -0,0 +1,90 @@
+package lncfg
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/decred/dcrlnd/cluster"
+)
+
+const (
+	// DefaultEtcdElectionPrefix is used as election prefix if none is
+	// provided through the config.
+	DefaultEtcdElectionPrefix = "/leader/"
+
+	// DefaultLeaderSessionTTL is the default number of seconds after which
+	// the leadership is lost if the leader can't reach etcd.
+	DefaultLeaderSessionTTL = 60
+)
+
+// Cluster holds configuration for clustered LND.
+type Cluster struct {
+	EnableLeaderElection bool `long:"enable-leader-election" description:"Enables leader election if set. Only the elected leader of the nodes sharing the database starts, while the others stand by until the leadership is lost."`
+
+	LeaderElector string `long:"leader-elector" choice:"etcd" description:"Leader elector to use. Valid values: \"etcd\" (default)."`
+
+	EtcdElectionPrefix string `long:"etcd-election-prefix" description:"Election key prefix when using etcd leader elector. Defaults to \"/leader/\"."`
+
+	ID string `long:"id" description:"Identifier for this node inside the cluster (used in leader election). Defaults to the hostname."`
+
+	LeaderSessionTTL int `long:"leader-session-ttl" description:"The number of seconds after which the leadership is lost if the leader can't reach the leader elector, and a standby node takes over."`
+}
+
+// DefaultCluster creates and returns a new default Cluster config.
+func DefaultCluster() *Cluster {
+	hostname, _ := os.Hostname()
+	return &Cluster{
+		LeaderElector:      cluster.EtcdLeaderElector,
+		EtcdElectionPrefix: DefaultEtcdElectionPrefix,
+		ID:                 hostname,
+		LeaderSessionTTL:   DefaultLeaderSessionTTL,
+	}
+}
+
+// MakeLeaderElector is a helper method to construct the concrete leader
+// elector based on the current configuration.
+func (c *Cluster) MakeLeaderElector(electionCtx context.Context,
+	db *DB) (cluster.LeaderElector, error) {
+
+	if c.LeaderElector == cluster.EtcdLeaderElector {
+		return cluster.MakeLeaderElector(
+			electionCtx, c.LeaderElector, c.ID,
+			c.EtcdElectionPrefix, c.LeaderSessionTTL, db.Etcd,
+		)
+	}
+
+	return nil, fmt.Errorf("unsupported leader elector")
+}
+
+// Validate validates the Cluster config.
+func (c *Cluster) Validate() error {
+	if !c.EnableLeaderElection {
+		return nil
+	}
+
+	switch c.LeaderElector {
+	case cluster.EtcdLeaderElector:
+		if c.EtcdElectionPrefix == "" {
+			return fmt.Errorf("etcd-election-prefix must be set")
+		}
+
+	default:
+		return fmt.Errorf("unknown leader elector, valid values are: "+
+			"\"%v\"", cluster.EtcdLeaderElector)
+	}
+
+	if c.ID == "" {
+		return fmt.Errorf("cluster id must be set")
+	}
+
+	if c.LeaderSessionTTL <= 0 {
+		return fmt.Errorf("leader-session-ttl must be positive")
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure Cluster implements the Validator
+// interface.
+var _ Validator = (*Cluster)(nil)
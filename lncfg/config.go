@@ -60,6 +60,14 @@ const (
 	// peer and a block arriving during that round trip to trigger force
 	// closure.
 	DefaultOutgoingCltvRejectDelta = DefaultOutgoingBroadcastDelta + 3
+
+	// DefaultCltvInterceptDelta defines the number of blocks before the
+	// expiry of an incoming htlc at which a forward held by the htlc
+	// interceptor is failed back automatically. We pad the final cltv
+	// reject delta a bit, so the htlc is failed back before a slow round
+	// trip to the interceptor and the incoming peer pushes us inside the
+	// incoming broadcast window.
+	DefaultCltvInterceptDelta = DefaultFinalCltvRejectDelta + 3
 )
 
 // CleanAndExpandPath expands environment variables and leading ~ in the
@@ -8,9 +8,12 @@ import (
 )
 
 const (
-	dbName      = "channel.db"
-	BoltBackend = "bolt"
-	EtcdBackend = "etcd"
+	dbName          = "channel.db"
+	sqliteDBName    = "channel.sqlite"
+	BoltBackend     = "bolt"
+	EtcdBackend     = "etcd"
+	PostgresBackend = "postgres"
+	SqliteBackend   = "sqlite"
 )
 
 // DB holds database configuration for LND.
@@ -19,14 +22,22 @@ type DB struct {
 
 	Etcd *kvdb.EtcdConfig `group:"etcd" namespace:"etcd" description:"Etcd settings."`
 
+	Postgres *kvdb.PostgresConfig `group:"postgres" namespace:"postgres" description:"Postgres settings."`
+
 	Bolt *kvdb.BoltConfig `group:"bolt" namespace:"bolt" description:"Bolt settings."`
+
+	Sqlite *kvdb.SqliteConfig `group:"sqlite" namespace:"sqlite" description:"Sqlite settings."`
+
+	PruneRevocation bool `long:"prune-revocation" description:"Convert the revocation logs of the channels to the compact format on startup, removing the full commitments of revoked states stored by the former format. This may take a while for long-lived channels."`
 }
 
 // NewDB creates and returns a new default DB config.
 func DefaultDB() *DB {
 	return &DB{
 		Backend: BoltBackend,
-		Bolt:    &kvdb.BoltConfig{},
+		Bolt: &kvdb.BoltConfig{
+			AutoCompactMinAge: kvdb.DefaultBoltAutoCompactMinAge,
+		},
 	}
 }
 
@@ -40,9 +51,17 @@ func (db *DB) Validate() error {
 			return fmt.Errorf("etcd host must be set")
 		}
 
+	case PostgresBackend:
+		if db.Postgres.Dsn == "" {
+			return fmt.Errorf("postgres dsn must be set")
+		}
+
+	case SqliteBackend:
+
 	default:
-		return fmt.Errorf("unknown backend, must be either \"%v\" or \"%v\"",
-			BoltBackend, EtcdBackend)
+		return fmt.Errorf("unknown backend, must be either \"%v\", "+
+			"\"%v\", \"%v\" or \"%v\"", BoltBackend, EtcdBackend,
+			PostgresBackend, SqliteBackend)
 	}
 
 	return nil
@@ -64,7 +83,8 @@ type DatabaseBackends struct {
 
 // GetBackends returns a set of kvdb.Backends as set in the DB config.  The
 // local database will ALWAYS be non-nil, while the remote database will only
-// be populated if etcd is specified.
+// be populated if etcd or postgres is specified. The local database is stored
+// in a SQLite file instead of bolt if sqlite is specified.
 func (db *DB) GetBackends(ctx context.Context, dbPath string,
 	networkName string) (*DatabaseBackends, error) {
 
@@ -73,17 +93,40 @@ func (db *DB) GetBackends(ctx context.Context, dbPath string,
 		err               error
 	)
 
-	if db.Backend == EtcdBackend {
+	switch db.Backend {
+	case EtcdBackend:
 		// Prefix will separate key/values in the db.
 		remoteDB, err = kvdb.GetEtcdBackend(ctx, networkName, db.Etcd)
 		if err != nil {
 			return nil, err
 		}
+
+	case PostgresBackend:
+		// Prefix will separate the tables of the networks in the db.
+		remoteDB, err = kvdb.GetPostgresBackend(
+			ctx, networkName, db.Postgres,
+		)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	localDB, err = kvdb.GetBoltBackend(
-		dbPath, dbName, !db.Bolt.SyncFreelist,
-	)
+	if db.Backend == SqliteBackend {
+		localDB, err = kvdb.GetSqliteBackend(
+			ctx, dbPath, sqliteDBName, db.Sqlite,
+		)
+	} else {
+		// The bolt database is compacted before it is opened, if
+		// requested or due according to the config.
+		err = kvdb.CompactBoltBackend(dbPath, dbName, db.Bolt)
+		if err != nil {
+			return nil, err
+		}
+
+		localDB, err = kvdb.GetBoltBackend(
+			dbPath, dbName, !db.Bolt.SyncFreelist,
+		)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -94,5 +137,18 @@ func (db *DB) GetBackends(ctx context.Context, dbPath string,
 	}, nil
 }
 
+// RequestCompaction requests the local database in dbPath to be compacted on
+// the next startup, or withdraws a previous request if cancel is set. Only the
+// bolt database can be compacted, so it fails if the local database is stored
+// in SQLite.
+func (db *DB) RequestCompaction(dbPath string, cancel bool) error {
+	if db.Backend == SqliteBackend {
+		return fmt.Errorf("compaction is only supported for the bolt " +
+			"database, sqlite databases are vacuumed on startup")
+	}
+
+	return kvdb.RequestBoltCompaction(dbPath, dbName, cancel)
+}
+
 // Compile-time constraint to ensure Workers implements the Validator interface.
 var _ Validator = (*DB)(nil)
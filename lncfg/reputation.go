@@ -0,0 +1,114 @@
+package lncfg
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultProtectedShare is the default share of the htlc slots and
+	// liquidity of an outgoing channel that is reserved for incoming
+	// channels with a good reputation.
+	DefaultProtectedShare = 0.5
+
+	// DefaultMaxResolutionTime is the default maximum average time in
+	// which the htlcs forwarded from an incoming channel must be resolved
+	// for it to have a good reputation.
+	DefaultMaxResolutionTime = time.Minute
+
+	// DefaultMaxFailureRatio is the default maximum average share of the
+	// htlcs forwarded from an incoming channel that may fail for it to
+	// have a good reputation.
+	DefaultMaxFailureRatio = 0.9
+
+	// DefaultMinResolved is the default minimum number of resolved htlcs
+	// forwarded from an incoming channel before it may have a good
+	// reputation.
+	DefaultMinResolved = 10
+
+	// DefaultHtlcRateLimit is the default number of htlcs per second that
+	// are forwarded from an incoming channel without a good reputation.
+	DefaultHtlcRateLimit = 1.0
+
+	// DefaultHtlcRateBurst is the default number of htlcs that may be
+	// forwarded at once from an incoming channel without a good
+	// reputation.
+	DefaultHtlcRateBurst = 10
+)
+
+// Reputation holds the configuration of the htlc reputation tracking, which
+// protects the outgoing channels of the node from being jammed.
+type Reputation struct {
+	// Active enables the reputation tracking of incoming channels.
+	Active bool `long:"active" description:"Track the reputation of incoming channels, rate limiting the htlcs forwarded from channels without a good reputation and reserving a share of the htlc slots and liquidity of outgoing channels for channels with a good reputation"`
+
+	// ProtectedSlotShare is the share of the htlc slots of an outgoing
+	// channel that is reserved.
+	ProtectedSlotShare float64 `long:"protectedslotshare" description:"The share of the htlc slots of an outgoing channel that is reserved for incoming channels with a good reputation, between 0 and 1"`
+
+	// ProtectedLiquidityShare is the share of the liquidity of an
+	// outgoing channel that is reserved.
+	ProtectedLiquidityShare float64 `long:"protectedliquidityshare" description:"The share of the liquidity of an outgoing channel that is reserved for incoming channels with a good reputation, between 0 and 1"`
+
+	// MaxResolutionTime is the maximum average resolution time of a
+	// channel with a good reputation.
+	MaxResolutionTime time.Duration `long:"maxresolutiontime" description:"The maximum average time in which the htlcs forwarded from an incoming channel must be resolved for it to have a good reputation"`
+
+	// MaxFailureRatio is the maximum average failure ratio of a channel
+	// with a good reputation.
+	MaxFailureRatio float64 `long:"maxfailureratio" description:"The maximum average share of the htlcs forwarded from an incoming channel that may fail for it to have a good reputation, between 0 and 1"`
+
+	// MinResolved is the minimum number of resolved htlcs of a channel
+	// with a good reputation.
+	MinResolved uint64 `long:"minresolved" description:"The minimum number of htlcs forwarded from an incoming channel that must be resolved before it may have a good reputation"`
+
+	// RateLimit is the rate limit of the channels without a good
+	// reputation.
+	RateLimit float64 `long:"ratelimit" description:"The number of htlcs per second that are forwarded from an incoming channel without a good reputation"`
+
+	// RateBurst is the burst of the channels without a good reputation.
+	RateBurst int `long:"rateburst" description:"The number of htlcs that may be forwarded at once from an incoming channel without a good reputation"`
+}
+
+// DefaultReputation returns the default htlc reputation configuration.
+func DefaultReputation() *Reputation {
+	return &Reputation{
+		ProtectedSlotShare:      DefaultProtectedShare,
+		ProtectedLiquidityShare: DefaultProtectedShare,
+		MaxResolutionTime:       DefaultMaxResolutionTime,
+		MaxFailureRatio:         DefaultMaxFailureRatio,
+		MinResolved:             DefaultMinResolved,
+		RateLimit:               DefaultHtlcRateLimit,
+		RateBurst:               DefaultHtlcRateBurst,
+	}
+}
+
+// Validate checks the values of the htlc reputation configuration.
+func (r *Reputation) Validate() error {
+	if r.ProtectedSlotShare < 0 || r.ProtectedSlotShare > 1 {
+		return fmt.Errorf("protected slot share must be between 0 " +
+			"and 1")
+	}
+	if r.ProtectedLiquidityShare < 0 || r.ProtectedLiquidityShare > 1 {
+		return fmt.Errorf("protected liquidity share must be between " +
+			"0 and 1")
+	}
+	if r.MaxResolutionTime <= 0 {
+		return fmt.Errorf("max resolution time must be positive")
+	}
+	if r.MaxFailureRatio < 0 || r.MaxFailureRatio > 1 {
+		return fmt.Errorf("max failure ratio must be between 0 and 1")
+	}
+	if r.RateLimit <= 0 {
+		return fmt.Errorf("htlc rate limit must be positive")
+	}
+	if r.RateBurst < 0 {
+		return fmt.Errorf("htlc rate burst must not be negative")
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure Reputation implements the Validator
+// interface.
+var _ Validator = (*Reputation)(nil)
@@ -0,0 +1,61 @@
+package lncfg
+
+import "fmt"
+
+const (
+	// DefaultTrampolineBaseFee is the default base fee in milli-atoms
+	// charged for forwarding a trampoline payment.
+	DefaultTrampolineBaseFee = 1000
+
+	// DefaultTrampolineFeeRate is the default proportional fee in
+	// millionths charged for forwarding a trampoline payment.
+	DefaultTrampolineFeeRate = 1000
+
+	// DefaultTrampolineTimeLockDelta is the default minimum number of
+	// blocks between the expiry of an incoming trampoline htlc and the
+	// expiry of the htlcs of the forwarded payment.
+	DefaultTrampolineTimeLockDelta = 80
+
+	// MinTrampolineTimeLockDelta is the smallest time lock delta that is
+	// accepted for trampoline payments.
+	MinTrampolineTimeLockDelta = 18
+)
+
+// Trampoline holds the configuration of trampoline routing, which allows
+// light clients to hand the path finding of their payments to this node.
+type Trampoline struct {
+	// Active enables forwarding payments on behalf of trampoline senders.
+	Active bool `long:"active" description:"Forward payments on behalf of light clients that hand this node a trampoline onion. The trampoline routing feature bit is only advertised if this is set."`
+
+	// BaseFee is the base fee charged for forwarding a trampoline payment.
+	BaseFee int64 `long:"basefee" description:"The base fee in milli-atoms charged for forwarding a trampoline payment, on top of the routing fees of the forwarded payment"`
+
+	// FeeRate is the proportional fee charged for forwarding a trampoline
+	// payment.
+	FeeRate int64 `long:"feerate" description:"The fee rate in millionths charged for forwarding a trampoline payment, on top of the routing fees of the forwarded payment"`
+
+	// TimeLockDelta is the minimum difference between the expiry of an
+	// incoming trampoline htlc and the expiry of the forwarded payment.
+	TimeLockDelta uint32 `long:"timelockdelta" description:"The minimum number of blocks between the expiry of an incoming trampoline htlc and the expiry of the htlcs of the forwarded payment"`
+}
+
+// Validate checks the values of the trampoline configuration.
+func (t *Trampoline) Validate() error {
+	if t.BaseFee < 0 {
+		return fmt.Errorf("trampoline base fee must not be negative")
+	}
+	if t.FeeRate < 0 {
+		return fmt.Errorf("trampoline fee rate must not be negative")
+	}
+	if t.TimeLockDelta < MinTrampolineTimeLockDelta {
+		return fmt.Errorf("trampoline time lock delta %d is less "+
+			"than min: %d", t.TimeLockDelta,
+			MinTrampolineTimeLockDelta)
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure Trampoline implements the Validator
+// interface.
+var _ Validator = (*Trampoline)(nil)
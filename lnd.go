@@ -36,6 +36,8 @@ import (
 	"github.com/decred/dcrlnd/cert"
 	"github.com/decred/dcrlnd/chanacceptor"
 	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/cluster"
+	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/keychain"
 	"github.com/decred/dcrlnd/lncfg"
 	"github.com/decred/dcrlnd/lnrpc"
@@ -272,6 +274,29 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// If leader election is enabled, we'll wait until we're elected as the
+	// leader of the cluster before opening the shared database and
+	// starting any of the subsystems, so only a single node of the cluster
+	// is ever active.
+	if cfg.Cluster.EnableLeaderElection {
+		leaderElector, err := campaignForLeadership(
+			ctx, cfg, shutdownChan,
+		)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			ltndLog.Infof("Attempting to resign from leader role "+
+				"(%v)", cfg.Cluster.ID)
+
+			if err := leaderElector.Resign(); err != nil {
+				ltndLog.Errorf("Leader elector failed to "+
+					"resign: %v", err)
+			}
+		}()
+	}
+
 	localChanDB, remoteChanDB, cleanUp, err := initializeDatabases(ctx, cfg)
 	switch {
 	case err == channeldb.ErrDryRunMigrationOK:
@@ -283,6 +308,12 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 
 	defer cleanUp()
 
+	// If a consistency check of the database was requested, we'll run it
+	// before any subsystem accesses the database, and exit afterwards.
+	if cfg.CheckDB {
+		return checkDatabase(remoteChanDB, cfg.RepairDB)
+	}
+
 	// Only process macaroons if --no-macaroons isn't set.
 	tlsCfg, restCreds, restProxyDest, err := getTLSConfig(cfg)
 	if err != nil {
@@ -727,6 +758,68 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	return nil
 }
 
+// campaignForLeadership blocks until this node is elected as the leader of the
+// cluster, or the daemon is shut down. Once elected, the daemon is shut down if
+// the leadership is lost, so one of the standby nodes can take over.
+func campaignForLeadership(ctx context.Context, cfg *Config,
+	shutdownChan <-chan struct{}) (cluster.LeaderElector, error) {
+
+	// Cancel the campaign if we're shut down before being elected.
+	electionCtx, cancelElection := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-shutdownChan:
+		case <-electionCtx.Done():
+		}
+		cancelElection()
+	}()
+
+	ltndLog.Infof("Using %v leader elector", cfg.Cluster.LeaderElector)
+
+	leaderElector, err := cfg.Cluster.MakeLeaderElector(ctx, cfg.DB)
+	if err != nil {
+		cancelElection()
+		err := fmt.Errorf("unable to create leader elector: %v", err)
+		ltndLog.Error(err)
+		return nil, err
+	}
+
+	ltndLog.Infof("Starting leadership campaign (%v)", cfg.Cluster.ID)
+
+	if err := leaderElector.Campaign(electionCtx); err != nil {
+		cancelElection()
+
+		if resignErr := leaderElector.Resign(); resignErr != nil {
+			ltndLog.Errorf("Leader elector failed to resign: %v",
+				resignErr)
+		}
+
+		err := fmt.Errorf("leadership campaign failed: %v", err)
+		ltndLog.Error(err)
+		return nil, err
+	}
+
+	ltndLog.Infof("Elected as leader (%v)", cfg.Cluster.ID)
+
+	// Shut down once the leadership is lost, as another node may be
+	// elected from then on.
+	go func() {
+		defer cancelElection()
+
+		select {
+		case <-leaderElector.Done():
+			ltndLog.Errorf("Lost leadership (%v), shutting down",
+				cfg.Cluster.ID)
+			signal.RequestShutdown()
+
+		case <-shutdownChan:
+		case <-ctx.Done():
+		}
+	}()
+
+	return leaderElector, nil
+}
+
 // getTLSConfig returns a TLS configuration for the gRPC server and credentials
 // and a proxy destination for the REST reverse proxy.
 func getTLSConfig(cfg *Config) (*tls.Config, *credentials.TransportCredentials,
@@ -1160,6 +1253,7 @@ func initializeDatabases(ctx context.Context,
 			channeldb.OptionSetRejectCacheSize(cfg.Caches.RejectCacheSize),
 			channeldb.OptionSetChannelCacheSize(cfg.Caches.ChannelCacheSize),
 			channeldb.OptionDryRunMigration(cfg.DryRunMigration),
+			channeldb.OptionPruneRevocationLog(cfg.DB.PruneRevocation),
 		)
 		switch {
 		case err == channeldb.ErrDryRunMigrationOK:
@@ -1211,6 +1305,7 @@ func initializeDatabases(ctx context.Context,
 		remoteChanDB, err = channeldb.CreateWithBackend(
 			databaseBackends.RemoteDB,
 			channeldb.OptionDryRunMigration(cfg.DryRunMigration),
+			channeldb.OptionPruneRevocationLog(cfg.DB.PruneRevocation),
 		)
 		switch {
 		case err == channeldb.ErrDryRunMigrationOK:
@@ -1240,3 +1335,47 @@ func initializeDatabases(ctx context.Context,
 
 	return localChanDB, remoteChanDB, cleanUp, nil
 }
+
+// checkDatabase checks the channel database for orphaned or inconsistent
+// records and logs them. If repair is true, the records that can safely be
+// removed are removed.
+func checkDatabase(chanDB *channeldb.DB, repair bool) error {
+	ltndLog.Infof("Checking channel database consistency (repair=%v)",
+		repair)
+
+	inconsistencies, err := chanDB.CheckConsistency(repair)
+	if err != nil {
+		return fmt.Errorf("unable to check channel database: %v", err)
+	}
+
+	circuitInconsistencies, err := htlcswitch.CheckCircuitMap(
+		chanDB, repair,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to check circuit map: %v", err)
+	}
+	inconsistencies = append(inconsistencies, circuitInconsistencies...)
+
+	var numRepairable, numRepaired int
+	for _, inconsistency := range inconsistencies {
+		ltndLog.Warnf("Database inconsistency: %v", inconsistency)
+
+		if inconsistency.Repairable {
+			numRepairable++
+		}
+		if inconsistency.Repaired {
+			numRepaired++
+		}
+	}
+
+	ltndLog.Infof("Channel database check complete: found %d "+
+		"inconsistencies, %d repairable, %d repaired",
+		len(inconsistencies), numRepairable, numRepaired)
+
+	if numRepairable > numRepaired {
+		ltndLog.Infof("Run with --checkdb --repairdb to remove the " +
+			"repairable records")
+	}
+
+	return nil
+}
@@ -95,6 +95,16 @@ type AddInvoiceData struct {
 	// HodlInvoice signals that this invoice shouldn't be settled
 	// immediately upon receiving the payment.
 	HodlInvoice bool
+
+	// Amp signals whether or not to create an AMP invoice. AMP invoices
+	// can be paid multiple times, and are settled using the preimages
+	// derived by the receiver for each htlc, so neither Preimage nor Hash
+	// may be set.
+	Amp bool
+
+	// Blind signals whether or not to hide the identity of our node behind
+	// a blinded path, whose introduction node is one of our channel peers.
+	Blind bool
 }
 
 // AddInvoice attempts to add a new invoice to the invoice database. Any
@@ -108,7 +118,37 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		paymentHash     lntypes.Hash
 	)
 
+	// Blinded invoices must not reveal our channels through route hints,
+	// and the hops of a blinded path can't be given AMP records.
 	switch {
+	case invoice.Blind && invoice.Private:
+		return nil, nil, errors.New("blinded invoices cannot include " +
+			"routing hints")
+
+	case invoice.Blind && invoice.Amp:
+		return nil, nil, errors.New("amp invoices cannot be blinded")
+	}
+
+	switch {
+
+	// AMP invoices don't have a single preimage, and can't be settled
+	// manually.
+	case invoice.Amp && (invoice.Preimage != nil || invoice.Hash != nil):
+		return nil, nil,
+			errors.New("preimage and hash must not be set for amp " +
+				"invoices")
+
+	case invoice.Amp && invoice.HodlInvoice:
+		return nil, nil,
+			errors.New("amp invoices cannot be hodl invoices")
+
+	// AMP htlcs each carry their own payment hash, so the payment hash of
+	// the invoice is only used to identify it. Generate a random one
+	// without a known preimage.
+	case invoice.Amp:
+		if _, err := rand.Read(paymentHash[:]); err != nil {
+			return nil, nil, err
+		}
 
 	// Only either preimage or hash can be set.
 	case invoice.Preimage != nil && invoice.Hash != nil:
@@ -271,6 +311,14 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 
 	// Set our desired invoice features and add them to our list of options.
 	invoiceFeatures := cfg.GenInvoiceFeatures()
+
+	// AMP invoices require the AMP feature, so that they can't be paid by
+	// legacy or regular mpp htlcs.
+	if invoice.Amp {
+		invoiceFeatures = invoiceFeatures.Clone()
+		invoiceFeatures.Unset(lnwire.AMPOptional)
+		invoiceFeatures.Set(lnwire.AMPRequired)
+	}
 	options = append(options, zpay32.Features(invoiceFeatures))
 
 	// Generate and set a random payment address for this invoice. If the
@@ -282,6 +330,27 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 	}
 	options = append(options, zpay32.PaymentAddr(paymentAddr))
 
+	signer := zpay32.MessageSigner{
+		SignCompact: cfg.NodeSigner.SignDigestCompact,
+	}
+
+	// If the invoice should be blinded, we'll add a blinded path to it
+	// whose data identifies the invoice through its payment address. The
+	// invoice is signed with an ephemeral key, as signing it with our
+	// node key would reveal our identity.
+	if invoice.Blind {
+		blindedPath, err := buildBlindedPath(cfg, amtMAtoms, paymentAddr)
+		if err != nil {
+			return nil, nil, err
+		}
+		options = append(options, zpay32.BlindedPath(blindedPath))
+
+		signer, err = ephemeralSigner()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Create and encode the payment request as a bech32 (zpay32) string.
 	creationDate := time.Now()
 	payReq, err := zpay32.NewInvoice(
@@ -291,11 +360,7 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		return nil, nil, err
 	}
 
-	payReqString, err := payReq.Encode(
-		zpay32.MessageSigner{
-			SignCompact: cfg.NodeSigner.SignDigestCompact,
-		},
-	)
+	payReqString, err := payReq.Encode(signer)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -343,6 +408,15 @@ func chanCanBeHopHint(channel *channeldb.OpenChannel,
 		return nil, false
 	}
 
+	return chanRemotePolicy(channel, graph, cfg)
+}
+
+// chanRemotePolicy returns the policy of the remote node of the target channel
+// if the channel is active and the remote node is publicly advertised.
+func chanRemotePolicy(channel *channeldb.OpenChannel,
+	graph *channeldb.ChannelGraph,
+	cfg *AddInvoiceConfig) (*channeldb.ChannelEdgePolicy, bool) {
+
 	// Make sure the channel is active.
 	chanPoint := lnwire.NewChanIDFromOutPoint(
 		&channel.FundingOutpoint,
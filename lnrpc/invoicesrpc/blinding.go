@@ -0,0 +1,169 @@
+package invoicesrpc
+
+import (
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/blindedpath"
+	"github.com/decred/dcrlnd/zpay32"
+)
+
+// errNoBlindedIntroNode is returned when a blinded invoice is requested, but
+// none of our channel peers can be used as the introduction node of the
+// blinded path.
+var errNoBlindedIntroNode = errors.New("no active channel with a public " +
+	"peer is available to create a blinded path")
+
+// selectBlindedIntroChan selects the channel whose peer will act as the
+// introduction node of a blinded path to our node. Channels that are able to
+// carry the full amount to us are preferred.
+func selectBlindedIntroChan(amtMAtoms lnwire.MilliAtom,
+	cfg *AddInvoiceConfig) (*channeldb.OpenChannel,
+	*channeldb.ChannelEdgePolicy, error) {
+
+	openChannels, err := cfg.ChanDB.FetchAllChannels()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	graph := cfg.ChanDB.ChannelGraph()
+
+	var (
+		introChan   *channeldb.OpenChannel
+		introPolicy *channeldb.ChannelEdgePolicy
+	)
+	for _, channel := range openChannels {
+		policy, ok := chanRemotePolicy(channel, graph, cfg)
+		if !ok || policy == nil {
+			continue
+		}
+
+		// If the remote balance is sufficient to carry the payment,
+		// we've found our introduction node.
+		remoteBalance := channel.LocalCommitment.RemoteBalance
+		if remoteBalance >= amtMAtoms {
+			return channel, policy, nil
+		}
+
+		// Otherwise, we'll remember the first eligible channel in case
+		// we don't find a better one.
+		if introChan == nil {
+			introChan, introPolicy = channel, policy
+		}
+	}
+
+	if introChan == nil {
+		return nil, nil, errNoBlindedIntroNode
+	}
+
+	return introChan, introPolicy, nil
+}
+
+// buildBlindedPath creates a blinded path that leads to our node through one
+// of our channel peers. The data encrypted to our node carries the payment
+// address of the invoice, so that payments to the path can be matched with
+// it.
+func buildBlindedPath(cfg *AddInvoiceConfig, amtMAtoms lnwire.MilliAtom,
+	paymentAddr [32]byte) (*zpay32.BlindedPaymentPath, error) {
+
+	introChan, introPolicy, err := selectBlindedIntroChan(amtMAtoms, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The introduction node forwards the payment over its channel with
+	// us, according to its policy.
+	shortChanID := introChan.ShortChanID()
+	relayInfo := &record.PaymentRelayInfo{
+		CltvExpiryDelta: introPolicy.TimeLockDelta,
+		FeeRate:         uint32(introPolicy.FeeProportionalMillionths),
+		BaseFee:         introPolicy.FeeBaseMAtoms,
+	}
+	introData, err := record.EncodeBlindedRouteData(
+		&record.BlindedRouteData{
+			ShortChannelID: &shortChanID,
+			RelayInfo:      relayInfo,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ourData, err := record.EncodeBlindedRouteData(
+		&record.BlindedRouteData{
+			PathID: paymentAddr[:],
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceNode, err := cfg.ChanDB.ChannelGraph().SourceNode()
+	if err != nil {
+		return nil, err
+	}
+	ourPubKey, err := sourceNode.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := blindedpath.BuildBlindedPath(
+		sessionKey, []*blindedpath.HopInfo{
+			{
+				NodePub:   introChan.IdentityPub,
+				PlainText: introData,
+			},
+			{
+				NodePub:   ourPubKey,
+				PlainText: ourData,
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	introHop, ourHop := path.BlindedHops[0], path.BlindedHops[1]
+
+	return &zpay32.BlindedPaymentPath{
+		IntroductionNode: path.IntroductionPoint,
+		BlindingPoint:    path.BlindingPoint,
+		Hops: []zpay32.BlindedPaymentHop{
+			{
+				BlindedNodeID:             introHop.BlindedNodePub,
+				CipherText:                introHop.CipherText,
+				FeeBaseMAtoms:             uint32(relayInfo.BaseFee),
+				FeeProportionalMillionths: relayInfo.FeeRate,
+				CLTVExpiryDelta:           relayInfo.CltvExpiryDelta,
+			},
+			{
+				BlindedNodeID: ourHop.BlindedNodePub,
+				CipherText:    ourHop.CipherText,
+			},
+		},
+	}, nil
+}
+
+// ephemeralSigner returns a signer that signs invoices with a freshly
+// generated key, so that the invoice doesn't reveal our node's identity.
+func ephemeralSigner() (zpay32.MessageSigner, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return zpay32.MessageSigner{}, err
+	}
+
+	return zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return ecdsa.SignCompact(key, hash, true), nil
+		},
+	}, nil
+}
@@ -0,0 +1,268 @@
+// +build !no_invoicesrpc
+
+package invoicesrpc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/invoices"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+var (
+	// ErrInterceptorAlreadyExists is an error returned when a new exit hop
+	// interceptor stream is opened while another one is active.
+	ErrInterceptorAlreadyExists = errors.New("interceptor already exists")
+
+	// ErrHtlcNotExists is an error returned when the client tries to
+	// resolve an intercepted htlc that doesn't exist anymore.
+	ErrHtlcNotExists = errors.New("htlc does not exist")
+
+	// ErrMissingPreimage is an error returned when the client tries to
+	// settle an intercepted htlc without providing a preimage.
+	ErrMissingPreimage = errors.New("missing preimage")
+)
+
+// exitHopInterceptor handles the lifecycle of an exit hop interceptor
+// streaming session. It is created when the stream opens and disconnects
+// when the stream closes.
+type exitHopInterceptor struct {
+	server *Server
+
+	// held holds the intercepted htlcs that were sent to the client and
+	// aren't resolved yet.
+	held map[channeldb.CircuitKey]invoices.InterceptedExitHop
+
+	// stream is the bidirectional RPC stream.
+	stream Invoices_ExitHopInterceptorServer
+
+	// intercepted is where all htlcs intercepted by the invoice registry
+	// are sent to the main loop.
+	intercepted chan invoices.InterceptedExitHop
+
+	// quit is closed when the interceptor is shutting down.
+	quit chan struct{}
+
+	wg sync.WaitGroup
+}
+
+// newExitHopInterceptor creates a new exitHopInterceptor.
+func newExitHopInterceptor(server *Server,
+	stream Invoices_ExitHopInterceptorServer) *exitHopInterceptor {
+
+	return &exitHopInterceptor{
+		server: server,
+		held: make(
+			map[channeldb.CircuitKey]invoices.InterceptedExitHop,
+		),
+		stream:      stream,
+		intercepted: make(chan invoices.InterceptedExitHop),
+		quit:        make(chan struct{}),
+	}
+}
+
+// run registers the interceptor with the invoice registry, sends the
+// intercepted htlcs to the client and resolves them with the responses of the
+// client until the stream closes.
+func (r *exitHopInterceptor) run() error {
+	defer r.onDisconnect()
+
+	// Unsetting the interceptor fails all the htlcs that are still held.
+	registry := r.server.cfg.InvoiceRegistry
+	registry.SetExitHopInterceptor(r.onIntercept)
+	defer registry.SetExitHopInterceptor(nil)
+
+	errChan := make(chan error, 1)
+	responses := make(chan *ExitHopInterceptResponse)
+	r.wg.Add(1)
+	go r.readClientResponses(responses, errChan)
+
+	for {
+		select {
+		case intercepted := <-r.intercepted:
+			if err := r.sendToClient(intercepted); err != nil {
+				return err
+			}
+
+		case resp := <-responses:
+			// A failed resolution doesn't indicate a problem with
+			// the stream, so we only log it.
+			if err := r.resolveFromClient(resp); err != nil {
+				log.Warnf("Client resolution of intercepted "+
+					"htlc failed: %v", err)
+			}
+
+		case err := <-errChan:
+			return err
+
+		case <-r.server.quit:
+			return nil
+		}
+	}
+}
+
+// onIntercept is called by the invoice registry for every intercepted htlc.
+// It returns true if the htlc was delivered to the main loop.
+func (r *exitHopInterceptor) onIntercept(
+	intercepted invoices.InterceptedExitHop) bool {
+
+	select {
+	case r.intercepted <- intercepted:
+		return true
+
+	case <-r.quit:
+		return false
+
+	case <-r.server.quit:
+		return false
+	}
+}
+
+// readClientResponses reads the responses of the client from the stream.
+func (r *exitHopInterceptor) readClientResponses(
+	responses chan<- *ExitHopInterceptResponse, errChan chan<- error) {
+
+	defer r.wg.Done()
+
+	for {
+		resp, err := r.stream.Recv()
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		select {
+		case responses <- resp:
+		case <-r.quit:
+			return
+		case <-r.server.quit:
+			return
+		}
+	}
+}
+
+// sendToClient holds the intercepted htlc and sends it to the client.
+func (r *exitHopInterceptor) sendToClient(
+	intercepted invoices.InterceptedExitHop) error {
+
+	htlc := intercepted.Htlc()
+	r.held[htlc.CircuitKey] = intercepted
+
+	log.Tracef("Sending intercepted htlc %v to client", htlc.CircuitKey)
+
+	return r.stream.Send(&ExitHopInterceptRequest{
+		IncomingCircuitKey: &CircuitKey{
+			ChanId: htlc.CircuitKey.ChanID.ToUint64(),
+			HtlcId: htlc.CircuitKey.HtlcID,
+		},
+		PaymentHash:    htlc.Hash[:],
+		AmtMAtoms:      uint64(htlc.Amount),
+		Expiry:         htlc.Expiry,
+		AcceptHeight:   htlc.AcceptHeight,
+		CustomRecords:  htlc.CustomRecords,
+		AutoFailHeight: htlc.AutoFailHeight,
+	})
+}
+
+// resolveFromClient resolves an intercepted htlc with the response of the
+// client.
+func (r *exitHopInterceptor) resolveFromClient(
+	in *ExitHopInterceptResponse) error {
+
+	if in.IncomingCircuitKey == nil {
+		return errors.New("missing circuit key")
+	}
+
+	key := channeldb.CircuitKey{
+		ChanID: lnwire.NewShortChanIDFromInt(
+			in.IncomingCircuitKey.ChanId,
+		),
+		HtlcID: in.IncomingCircuitKey.HtlcId,
+	}
+	intercepted, ok := r.held[key]
+	if !ok {
+		return ErrHtlcNotExists
+	}
+	delete(r.held, key)
+
+	log.Tracef("Resolving intercepted htlc %v with %v", key, in.Action)
+
+	switch in.Action {
+	case ExitHopInterceptAction_SETTLE:
+		if in.Preimage == nil {
+			return ErrMissingPreimage
+		}
+		preimage, err := lntypes.MakePreimage(in.Preimage)
+		if err != nil {
+			return err
+		}
+		return intercepted.Settle(preimage)
+
+	case ExitHopInterceptAction_FAIL:
+		failure, err := unmarshallFailure(
+			in.FailureCode, intercepted.Htlc(),
+		)
+		if err != nil {
+			return err
+		}
+		return intercepted.Fail(failure)
+
+	default:
+		return fmt.Errorf("unrecognized resolve action %v", in.Action)
+	}
+}
+
+// onDisconnect stops the interceptor. The htlcs that are still held are
+// failed by the invoice registry once the interceptor is unset.
+func (r *exitHopInterceptor) onDisconnect() {
+	close(r.quit)
+
+	log.Infof("Exit hop interceptor disconnected, failing %d held htlcs",
+		len(r.held))
+
+	r.wg.Wait()
+}
+
+// unmarshallFailure returns the failure message for the given failure code
+// that an intercepted htlc is failed with.
+func unmarshallFailure(code lnrpc.Failure_FailureCode,
+	htlc invoices.InterceptedExitHopHtlc) (lnwire.FailureMessage, error) {
+
+	switch code {
+	case lnrpc.Failure_RESERVED,
+		lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS:
+
+		return lnwire.NewFailIncorrectDetails(
+			htlc.Amount, uint32(htlc.AcceptHeight),
+		), nil
+
+	case lnrpc.Failure_FINAL_INCORRECT_CLTV_EXPIRY:
+		return lnwire.NewFinalIncorrectCltvExpiry(htlc.Expiry), nil
+
+	case lnrpc.Failure_FINAL_INCORRECT_HTLC_AMOUNT:
+		return lnwire.NewFinalIncorrectHtlcAmount(htlc.Amount), nil
+
+	case lnrpc.Failure_TEMPORARY_CHANNEL_FAILURE:
+		return lnwire.NewTemporaryChannelFailure(nil), nil
+
+	case lnrpc.Failure_REQUIRED_NODE_FEATURE_MISSING:
+		return &lnwire.FailRequiredNodeFeatureMissing{}, nil
+
+	case lnrpc.Failure_TEMPORARY_NODE_FAILURE:
+		return &lnwire.FailTemporaryNodeFailure{}, nil
+
+	case lnrpc.Failure_PERMANENT_NODE_FAILURE:
+		return &lnwire.FailPermanentNodeFailure{}, nil
+
+	case lnrpc.Failure_MPP_TIMEOUT:
+		return &lnwire.FailMPPTimeout{}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported failure code %v", code)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"google.golang.org/grpc"
 	"gopkg.in/macaroon-bakery.v2/bakery"
@@ -58,6 +59,14 @@ var (
 			Entity: "invoices",
 			Action: "write",
 		}},
+		"/invoicesrpc.Invoices/DeleteInvoice": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
+		"/invoicesrpc.Invoices/ExitHopInterceptor": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
 	}
 
 	// DefaultInvoicesMacFilename is the default name of the invoices
@@ -70,6 +79,8 @@ var (
 // RPC server allows external callers to access the status of the invoices
 // currently active within lnd, as well as configuring it at runtime.
 type Server struct {
+	exitHopInterceptorActive int32 // To be used atomically.
+
 	quit chan struct{}
 
 	cfg *Config
@@ -263,6 +274,25 @@ func (s *Server) CancelInvoice(ctx context.Context,
 	return &CancelInvoiceResp{}, nil
 }
 
+// DeleteInvoice deletes a settled or canceled invoice from the database.
+func (s *Server) DeleteInvoice(ctx context.Context,
+	in *DeleteInvoiceMsg) (*DeleteInvoiceResp, error) {
+
+	paymentHash, err := lntypes.MakeHash(in.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cfg.InvoiceRegistry.DeleteInvoice(paymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Deleted invoice %v", paymentHash)
+
+	return &DeleteInvoiceResp{}, nil
+}
+
 // AddHoldInvoice attempts to add a new hold invoice to the invoice database.
 // Any duplicated invoices are rejected, therefore all invoices *must* have a
 // unique payment hash.
@@ -311,3 +341,17 @@ func (s *Server) AddHoldInvoice(ctx context.Context,
 		PaymentRequest: string(dbInvoice.PaymentRequest),
 	}, nil
 }
+
+// ExitHopInterceptor is a bidirectional stream over which the htlcs paying to
+// this node for which no invoice exists are sent to the client, which settles
+// or fails them. Only one interceptor may be active at a time.
+func (s *Server) ExitHopInterceptor(
+	stream Invoices_ExitHopInterceptorServer) error {
+
+	if !atomic.CompareAndSwapInt32(&s.exitHopInterceptorActive, 0, 1) {
+		return ErrInterceptorAlreadyExists
+	}
+	defer atomic.CompareAndSwapInt32(&s.exitHopInterceptorActive, 1, 0)
+
+	return newExitHopInterceptor(s, stream).run()
+}
@@ -22,13 +22,23 @@ func decodePayReq(invoice *channeldb.Invoice,
 	paymentRequest := string(invoice.PaymentRequest)
 	if paymentRequest == "" {
 		preimage := invoice.Terms.PaymentPreimage
-		if preimage == nil {
+		switch {
+		case preimage != nil:
+			hash := [32]byte(preimage.Hash())
+			return &zpay32.Invoice{
+				PaymentHash: &hash,
+			}, nil
+
+		// Spontaneous AMP invoices don't have a preimage, instead they
+		// are indexed by the set id of their htlcs.
+		case invoice.IsAMP():
+			return &zpay32.Invoice{
+				PaymentHash: ampSetID(invoice),
+			}, nil
+
+		default:
 			return nil, errors.New("cannot reconstruct pay req")
 		}
-		hash := [32]byte(preimage.Hash())
-		return &zpay32.Invoice{
-			PaymentHash: &hash,
-		}, nil
 	}
 
 	var err error
@@ -41,6 +51,29 @@ func decodePayReq(invoice *channeldb.Invoice,
 
 }
 
+// ampSetID returns the set id of the first htlc paying to the given AMP
+// invoice, which is the set that caused the invoice to be inserted. Nil is
+// returned if no htlcs have been received yet.
+func ampSetID(invoice *channeldb.Invoice) *[32]byte {
+	var first *channeldb.InvoiceHTLC
+	for _, htlc := range invoice.Htlcs {
+		if htlc.AMP == nil {
+			continue
+		}
+
+		if first == nil || htlc.AcceptTime.Before(first.AcceptTime) {
+			first = htlc
+		}
+	}
+
+	if first == nil {
+		return nil
+	}
+
+	setID := first.AMP.Record.SetID()
+	return &setID
+}
+
 // CreateRPCInvoice creates an *lnrpc.Invoice from the *channeldb.Invoice.
 func CreateRPCInvoice(invoice *channeldb.Invoice,
 	activeNetParams *chaincfg.Params) (*lnrpc.Invoice, error) {
@@ -115,6 +148,25 @@ func CreateRPCInvoice(invoice *channeldb.Invoice,
 			MppTotalAmtMAtoms: uint64(htlc.MppTotalAmt),
 		}
 
+		// Populate any fields relevant to AMP payments.
+		if htlc.AMP != nil {
+			rootShare := htlc.AMP.Record.RootShare()
+			setID := htlc.AMP.Record.SetID()
+
+			var preimage []byte
+			if htlc.AMP.Preimage != nil {
+				preimage = htlc.AMP.Preimage[:]
+			}
+
+			rpcHtlc.Amp = &lnrpc.AMP{
+				RootShare:  rootShare[:],
+				SetId:      setID[:],
+				ChildIndex: uint32(htlc.AMP.Record.ChildIndex()),
+				Hash:       htlc.AMP.Hash[:],
+				Preimage:   preimage,
+			}
+		}
+
 		// Only report resolved times if htlc is resolved.
 		if htlc.State != channeldb.HtlcStateAccepted {
 			rpcHtlc.ResolveTime = htlc.ResolveTime.Unix()
@@ -123,9 +175,47 @@ func CreateRPCInvoice(invoice *channeldb.Invoice,
 		rpcHtlcs = append(rpcHtlcs, &rpcHtlc)
 	}
 
+	// Spontaneous AMP invoices don't have a payment request either, but
+	// aren't keysend invoices.
+	isKeysend := len(invoice.PaymentRequest) == 0 && !invoice.IsAMP()
+
+	// Report the sub-invoice state of each settled AMP htlc set.
+	var ampInvoiceState map[string]*lnrpc.AMPInvoiceState
+	if invoice.IsAMP() {
+		ampInvoiceState = make(
+			map[string]*lnrpc.AMPInvoiceState, len(invoice.AMPState),
+		)
+		for setID, ampState := range invoice.AMPState {
+			var state lnrpc.InvoiceHTLCState
+			switch ampState.State {
+			case channeldb.HtlcStateAccepted:
+				state = lnrpc.InvoiceHTLCState_ACCEPTED
+			case channeldb.HtlcStateSettled:
+				state = lnrpc.InvoiceHTLCState_SETTLED
+			case channeldb.HtlcStateCanceled:
+				state = lnrpc.InvoiceHTLCState_CANCELED
+			default:
+				return nil, fmt.Errorf("unknown amp state %v",
+					ampState.State)
+			}
+
+			ampInvoiceState[setID.String()] = &lnrpc.AMPInvoiceState{
+				State:         state,
+				SettleTime:    ampState.SettleDate.Unix(),
+				AmtPaidMAtoms: int64(ampState.AmtPaid),
+				SettleIndex:   ampState.SettleIndex,
+			}
+		}
+	}
+
+	var rHash []byte
+	if decoded.PaymentHash != nil {
+		rHash = decoded.PaymentHash[:]
+	}
+
 	rpcInvoice := &lnrpc.Invoice{
 		Memo:            string(invoice.Memo[:]),
-		RHash:           decoded.PaymentHash[:],
+		RHash:           rHash,
 		Value:           int64(atomsAmt),
 		ValueMAtoms:     int64(invoice.Terms.Value),
 		CreationDate:    invoice.CreationDate.Unix(),
@@ -146,7 +236,10 @@ func CreateRPCInvoice(invoice *channeldb.Invoice,
 		State:           state,
 		Htlcs:           rpcHtlcs,
 		Features:        CreateRPCFeatures(invoice.Terms.Features),
-		IsKeysend:       len(invoice.PaymentRequest) == 0,
+		IsKeysend:       isKeysend,
+		IsAmp:           invoice.IsAMP(),
+		AmpInvoiceState: ampInvoiceState,
+		IsBlinded:       len(decoded.BlindedPaths) > 0,
 	}
 
 	if preimage != nil {
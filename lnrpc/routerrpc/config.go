@@ -48,7 +48,11 @@ func DefaultConfig() *Config {
 		PenaltyHalfLife:       routing.DefaultPenaltyHalfLife,
 		AttemptCost: routing.DefaultPaymentAttemptPenalty.
 			ToAtoms(),
-		MaxMcHistory: routing.DefaultMaxMcHistory,
+		MaxMcHistory:             routing.DefaultMaxMcHistory,
+		ProbabilityEstimatorType: routing.AprioriEstimatorName,
+		BimodalScale:             uint64(routing.DefaultBimodalScaleMAtoms),
+		BimodalNodeWeight:        routing.DefaultBimodalNodeWeight,
+		BimodalDecayTime:         routing.DefaultBimodalDecayTime,
 	}
 
 	return &Config{
@@ -65,5 +69,10 @@ func GetRoutingConfig(cfg *Config) *RoutingConfig {
 		AttemptCost:           cfg.AttemptCost,
 		PenaltyHalfLife:       cfg.PenaltyHalfLife,
 		MaxMcHistory:          cfg.MaxMcHistory,
+
+		ProbabilityEstimatorType: cfg.ProbabilityEstimatorType,
+		BimodalScale:             cfg.BimodalScale,
+		BimodalNodeWeight:        cfg.BimodalNodeWeight,
+		BimodalDecayTime:         cfg.BimodalDecayTime,
 	}
 }
@@ -164,6 +164,7 @@ func (r *forwardInterceptor) holdAndForwardToClient(
 		IncomingAmountMAtoms:    uint64(htlc.IncomingAmount),
 		IncomingExpiry:          htlc.IncomingExpiry,
 		CustomRecords:           htlc.CustomRecords,
+		AutoFailHeight:          htlc.AutoFailHeight,
 	}
 
 	return r.stream.Send(interceptionRequest)
@@ -186,7 +187,10 @@ func (r *forwardInterceptor) resolveFromClient(
 
 	switch in.Action {
 	case ResolveHoldForwardAction_RESUME:
-		return interceptedForward.Resume()
+		return interceptedForward.ResumeModified(
+			lnwire.NewShortChanIDFromInt(in.OutgoingRequestedChanId),
+			lnwire.MilliAtom(in.OutgoingAmountMAtoms),
+		)
 	case ResolveHoldForwardAction_FAIL:
 		return interceptedForward.Fail()
 	case ResolveHoldForwardAction_SETTLE:
@@ -203,18 +207,16 @@ func (r *forwardInterceptor) resolveFromClient(
 	}
 }
 
-// onDisconnect removes all previousely held forwards from
-// the store. Before they are removed it ensure to resume as the default
-// behavior.
+// onDisconnect removes all previousely held forwards from the store. The
+// interceptable switch keeps holding them, and takes the configured disconnect
+// action on them once the interceptor is unset.
 func (r *forwardInterceptor) onDisconnect() {
 	// Then close the channel so all go routine will exit.
 	close(r.quit)
 
-	log.Infof("RPC interceptor disconnected, resolving held packets")
-	for key, forward := range r.holdForwards {
-		if err := forward.Resume(); err != nil {
-			log.Errorf("failed to resume hold forward %v", err)
-		}
+	log.Infof("RPC interceptor disconnected, releasing %d held packets",
+		len(r.holdForwards))
+	for key := range r.holdForwards {
 		delete(r.holdForwards, key)
 	}
 	r.wg.Wait()
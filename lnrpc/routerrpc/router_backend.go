@@ -2,6 +2,7 @@ package routerrpc
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -42,6 +43,10 @@ type RouterBackend struct {
 	FetchChannelEndpoints func(chanID uint64) (route.Vertex,
 		route.Vertex, error)
 
+	// FetchPeerChannels returns the short channel ids of the open
+	// channels we have with the given peer.
+	FetchPeerChannels func(peer route.Vertex) ([]uint64, error)
+
 	// FindRoute is a closure that abstracts away how we locate/query for
 	// routes.
 	FindRoute func(source, target route.Vertex,
@@ -52,6 +57,10 @@ type RouterBackend struct {
 
 	MissionControl MissionControl
 
+	// SetPaymentAttemptPenalty applies the given payment attempt penalty
+	// to path finding.
+	SetPaymentAttemptPenalty func(penalty lnwire.MilliAtom)
+
 	// ActiveNetParams are the network parameters of the primary network
 	// that the route is operating on. This is necessary so we can ensure
 	// that we receive payment requests that send to destinations on our
@@ -82,9 +91,10 @@ type RouterBackend struct {
 // MissionControl defines the mission control dependencies of routerrpc.
 type MissionControl interface {
 	// GetProbability is expected to return the success probability of a
-	// payment from fromNode to toNode.
+	// payment from fromNode to toNode. The capacity of the channel may be
+	// zero if it is unknown.
 	GetProbability(fromNode, toNode route.Vertex,
-		amt lnwire.MilliAtom) float64
+		amt lnwire.MilliAtom, capacity dcrutil.Amount) float64
 
 	// ResetHistory resets the history of MissionControl returning it to a
 	// state as if no payment attempts have been made.
@@ -98,6 +108,20 @@ type MissionControl interface {
 	// pair.
 	GetPairHistorySnapshot(fromNode,
 		toNode route.Vertex) routing.TimedPairResult
+
+	// ImportHistory imports the mission control snapshot to our internal
+	// state, only applying results that are more recent than our current
+	// results unless force is set. It returns the number of imported
+	// results.
+	ImportHistory(history *routing.MissionControlSnapshot,
+		force bool) (int, error)
+
+	// GetConfig gets mission control's current config.
+	GetConfig() *routing.MissionControlConfig
+
+	// SetConfig sets mission control's config to the values provided, if
+	// they are valid.
+	SetConfig(cfg *routing.MissionControlConfig) error
 }
 
 // QueryRoutes attempts to query the daemons' Channel Router for a possible
@@ -223,7 +247,7 @@ func (r *RouterBackend) QueryRoutes(ctx context.Context,
 	restrictions := &routing.RestrictParams{
 		FeeLimit: feeLimit,
 		ProbabilitySource: func(fromNode, toNode route.Vertex,
-			amt lnwire.MilliAtom) float64 {
+			amt lnwire.MilliAtom, capacity dcrutil.Amount) float64 {
 
 			if _, ok := ignoredNodes[fromNode]; ok {
 				return 0
@@ -242,7 +266,7 @@ func (r *RouterBackend) QueryRoutes(ctx context.Context,
 			}
 
 			return r.MissionControl.GetProbability(
-				fromNode, toNode, amt,
+				fromNode, toNode, amt, capacity,
 			)
 		},
 		DestCustomRecords: record.CustomSet(in.DestCustomRecords),
@@ -326,8 +350,15 @@ func (r *RouterBackend) getSuccessProbability(rt *route.Route) float64 {
 	for _, hop := range rt.Hops {
 		toNode := hop.PubKeyBytes
 
+		// If the capacity cannot be retrieved, we fall back to an
+		// unknown capacity.
+		capacity, err := r.FetchChannelCapacity(hop.ChannelID)
+		if err != nil {
+			capacity = 0
+		}
+
 		probability := r.MissionControl.GetProbability(
-			fromNode, toNode, amtToFwd,
+			fromNode, toNode, amtToFwd, capacity,
 		)
 
 		successProb *= probability
@@ -395,6 +426,19 @@ func (r *RouterBackend) MarshallRoute(route *route.Route) (*lnrpc.Route, error)
 			}
 		}
 
+		// Extract the AMP fields if present on this hop.
+		var amp *lnrpc.AMPRecord
+		if hop.AMP != nil {
+			rootShare := hop.AMP.RootShare()
+			setID := hop.AMP.SetID()
+
+			amp = &lnrpc.AMPRecord{
+				RootShare:  rootShare[:],
+				SetId:      setID[:],
+				ChildIndex: uint32(hop.AMP.ChildIndex()),
+			}
+		}
+
 		resp.Hops[i] = &lnrpc.Hop{
 			ChanId:             hop.ChannelID,
 			ChanCapacity:       int64(chanCapacity),
@@ -409,6 +453,7 @@ func (r *RouterBackend) MarshallRoute(route *route.Route) (*lnrpc.Route, error)
 			CustomRecords: hop.CustomRecords,
 			TlvPayload:    !hop.LegacyPayload,
 			MppRecord:     mpp,
+			AmpRecord:     amp,
 		}
 		incomingAmt = hop.AmtToForward
 	}
@@ -431,6 +476,11 @@ func UnmarshallHopWithPubkey(rpcHop *lnrpc.Hop, pubkey route.Vertex) (*route.Hop
 		return nil, err
 	}
 
+	amp, err := UnmarshalAMP(rpcHop.AmpRecord)
+	if err != nil {
+		return nil, err
+	}
+
 	return &route.Hop{
 		OutgoingTimeLock: rpcHop.Expiry,
 		AmtToForward:     lnwire.MilliAtom(rpcHop.AmtToForwardMAtoms),
@@ -439,6 +489,7 @@ func UnmarshallHopWithPubkey(rpcHop *lnrpc.Hop, pubkey route.Vertex) (*route.Hop
 		CustomRecords:    customRecords,
 		LegacyPayload:    !rpcHop.TlvPayload,
 		MPP:              mpp,
+		AMP:              amp,
 	}, nil
 }
 
@@ -569,6 +620,19 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 	}
 	payIntent.MaxParts = maxParts
 
+	// Take the split strategy from the request. The default of halving is
+	// left to the payment session.
+	switch rpcPayReq.SplitStrategy {
+	case lnrpc.SplitStrategy_HALVING:
+
+	case lnrpc.SplitStrategy_FEE_AWARE:
+		payIntent.SplitStrategy = &routing.FeeAwareSplitStrategy{}
+
+	default:
+		return nil, fmt.Errorf("unknown split strategy %v",
+			rpcPayReq.SplitStrategy)
+	}
+
 	// Take fee limit from request.
 	payIntent.FeeLimit, err = lnrpc.UnmarshallAmt(
 		rpcPayReq.FeeLimitAtoms, rpcPayReq.FeeLimitMAtoms,
@@ -608,6 +672,13 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 		return nil, err
 	}
 
+	// AMP payments derive the payment hash of every shard from a randomly
+	// generated root seed, so a payment hash may not be specified.
+	if rpcPayReq.Amp && len(rpcPayReq.PaymentHash) > 0 {
+		return nil, errors.New("amp and payment_hash cannot appear " +
+			"together")
+	}
+
 	// If the payment request field isn't blank, then the details of the
 	// invoice are encoded entirely within the encoded payReq.  So we'll
 	// attempt to decode it, populating the payment accordingly.
@@ -673,6 +744,52 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 		payIntent.DestFeatures = payReq.Features
 		payIntent.PaymentAddr = payReq.PaymentAddr
 		payIntent.PaymentRequest = []byte(rpcPayReq.PaymentRequest)
+
+		// If the invoice hides the recipient behind blinded paths, the
+		// payment is routed to the first of them. The recipient is
+		// then identified through the data of the path rather than
+		// through an MPP record.
+		if len(payReq.BlindedPaths) > 0 {
+			if rpcPayReq.Amp {
+				return nil, errors.New("amp is not supported " +
+					"for invoices with blinded paths")
+			}
+
+			blindedPath := payReq.BlindedPaths[0]
+			target, err := routing.BlindedPathTarget(blindedPath)
+			if err != nil {
+				return nil, err
+			}
+
+			payIntent.Target = target
+			payIntent.BlindedPath = blindedPath
+			payIntent.PaymentAddr = nil
+		}
+
+		// AMP invoices must be paid using AMP, which is also possible
+		// for invoices that merely signal support for it. In both
+		// cases, the payment address of the invoice is used for all
+		// shards.
+		isAMPInvoice := payReq.Features != nil &&
+			payReq.Features.HasFeature(lnwire.AMPOptional)
+		switch {
+		case rpcPayReq.Amp && !isAMPInvoice:
+			return nil, errors.New("amp requested for an invoice " +
+				"that does not support amp")
+
+		case rpcPayReq.Amp || (isAMPInvoice &&
+			payReq.Features.IsSet(lnwire.AMPRequired)):
+
+			if payReq.PaymentAddr == nil {
+				return nil, errors.New("amp invoice is " +
+					"missing a payment address")
+			}
+
+			err := populateAMPIntent(payIntent, payReq.PaymentAddr)
+			if err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		// Otherwise, If the payment request field was not specified
 		// (and a custom route wasn't specified), construct the payment
@@ -710,6 +827,25 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 		}
 
 		payIntent.DestFeatures = features
+
+		// If this is an AMP payment, we'll generate the root seed, set
+		// id and payment address used by all shards, and make sure the
+		// destination is assumed to understand AMP.
+		if rpcPayReq.Amp {
+			err := populateAMPIntent(payIntent, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// If a trampoline node is specified, the payment is routed to it and
+	// forwarded to the destination by the trampoline node.
+	if len(rpcPayReq.TrampolineNode) > 0 {
+		err := r.populateTrampolineIntent(payIntent, rpcPayReq)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Check for disallowed payments to self.
@@ -720,6 +856,156 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 	return payIntent, nil
 }
 
+// populateAMPIntent generates random values for the root share and set id of
+// an AMP payment, and sets the destination features required to deliver it.
+// If no payment address is given, as is the case for spontaneous AMP payments,
+// a random one is generated as well.
+func populateAMPIntent(payIntent *routing.LightningPayment,
+	payAddr *[32]byte) error {
+
+	var rootShare, setID [32]byte
+	for _, b := range [][]byte{rootShare[:], setID[:]} {
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+	}
+
+	if payAddr == nil {
+		payAddr = &[32]byte{}
+		if _, err := rand.Read(payAddr[:]); err != nil {
+			return err
+		}
+	}
+
+	payIntent.AMP = &routing.AMPOptions{
+		SetID:     setID,
+		RootShare: rootShare,
+	}
+	payIntent.PaymentAddr = payAddr
+
+	// Unless the caller explicitly specified the destination features, we
+	// assume the destination supports the features required to receive
+	// an AMP payment.
+	if payIntent.DestFeatures == nil {
+		payIntent.DestFeatures = lnwire.NewFeatureVector(
+			lnwire.NewRawFeatureVector(
+				lnwire.TLVOnionPayloadOptional,
+				lnwire.PaymentAddrOptional,
+				lnwire.MPPOptional,
+				lnwire.AMPOptional,
+			),
+			lnwire.Features,
+		)
+	}
+
+	return nil
+}
+
+// populateTrampolineIntent turns the payment intent into a payment to the
+// trampoline node of the request, which carries the original destination,
+// amount and payment address in its trampoline onion. The trampoline node must
+// be a direct peer, as the payment is restricted to our channels with it.
+func (r *RouterBackend) populateTrampolineIntent(
+	payIntent *routing.LightningPayment,
+	rpcPayReq *SendPaymentRequest) error {
+
+	switch {
+	case payIntent.AMP != nil:
+		return errors.New("amp payments can't be sent through a " +
+			"trampoline node")
+
+	case payIntent.BlindedPath != nil:
+		return errors.New("payments to blinded paths can't be sent " +
+			"through a trampoline node")
+
+	case len(payIntent.DestCustomRecords) > 0:
+		return errors.New("custom records can't be sent through a " +
+			"trampoline node")
+
+	case payIntent.LastHop != nil:
+		return errors.New("last hop can't be restricted for payments " +
+			"through a trampoline node")
+
+	case rpcPayReq.TrampolineFeeMAtoms < 0:
+		return errors.New("trampoline fee must not be negative")
+
+	case rpcPayReq.TrampolineCltvDelta < 0:
+		return errors.New("trampoline cltv delta must not be negative")
+	}
+
+	trampolineNode, err := route.NewVertexFromBytes(
+		rpcPayReq.TrampolineNode,
+	)
+	if err != nil {
+		return err
+	}
+
+	// The route to the trampoline node only uses our channels with it, so
+	// that the payment doesn't depend on our knowledge of the graph.
+	peerChans, err := r.FetchPeerChannels(trampolineNode)
+	if err != nil {
+		return err
+	}
+	if len(peerChans) == 0 {
+		return fmt.Errorf("trampoline node %v is not a direct peer",
+			trampolineNode)
+	}
+
+	outgoingChans := peerChans
+	if len(payIntent.OutgoingChannelIDs) > 0 {
+		isPeerChan := make(map[uint64]bool, len(peerChans))
+		for _, chanID := range peerChans {
+			isPeerChan[chanID] = true
+		}
+
+		outgoingChans = nil
+		for _, chanID := range payIntent.OutgoingChannelIDs {
+			if isPeerChan[chanID] {
+				outgoingChans = append(outgoingChans, chanID)
+			}
+		}
+		if len(outgoingChans) == 0 {
+			return fmt.Errorf("no outgoing channel with "+
+				"trampoline node %v", trampolineNode)
+		}
+	}
+	payIntent.OutgoingChannelIDs = outgoingChans
+
+	cltvDelta := uint16(routing.DefaultTrampolineCLTVDelta)
+	if rpcPayReq.TrampolineCltvDelta != 0 {
+		cltvDelta = uint16(rpcPayReq.TrampolineCltvDelta)
+	}
+
+	payIntent.Trampoline = &routing.TrampolineOptions{
+		Destination:    payIntent.Target,
+		Amount:         payIntent.Amount,
+		FinalCLTVDelta: payIntent.FinalCLTVDelta,
+		PaymentAddr:    payIntent.PaymentAddr,
+	}
+
+	// The trampoline node receives the amount of the payment along with
+	// its fee, and must be given enough time to reach the destination.
+	payIntent.Target = trampolineNode
+	payIntent.Amount += lnwire.MilliAtom(rpcPayReq.TrampolineFeeMAtoms)
+	payIntent.FinalCLTVDelta += cltvDelta
+	payIntent.PaymentAddr = nil
+	payIntent.MaxParts = 1
+
+	// The route hints and features of the destination don't apply to the
+	// trampoline node, which must support trampoline routing.
+	payIntent.RouteHints = nil
+	payIntent.DestFeatures = lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(
+			lnwire.TLVOnionPayloadOptional,
+			lnwire.PaymentAddrOptional,
+			lnwire.TrampolineRoutingOptional,
+		),
+		lnwire.Features,
+	)
+
+	return nil
+}
+
 // unmarshallRouteHints unmarshalls a list of route hints.
 func unmarshallRouteHints(rpcRouteHints []*lnrpc.RouteHint) (
 	[][]zpay32.HopHint, error) {
@@ -856,6 +1142,41 @@ func UnmarshalMPP(reqMPP *lnrpc.MPPRecord) (*record.MPP, error) {
 	return record.NewMPP(total, addr), nil
 }
 
+// UnmarshalAMP accepts the AMP fields from a switch payment request and
+// converts them into an AMP record if present.
+func UnmarshalAMP(reqAMP *lnrpc.AMPRecord) (*record.AMP, error) {
+	// If no AMP record was submitted, assume the user wants to send a
+	// regular payment.
+	if reqAMP == nil {
+		return nil, nil
+	}
+
+	reqRootShare := reqAMP.RootShare
+	reqSetID := reqAMP.SetId
+
+	switch {
+	case len(reqRootShare) != 32:
+		return nil, errors.New("AMP root_share must be 32 bytes")
+
+	case len(reqSetID) != 32:
+		return nil, errors.New("AMP set_id must be 32 bytes")
+
+	case reqAMP.ChildIndex > math.MaxUint16:
+		return nil, errors.New("AMP child_index must fit in 16 bits")
+	}
+
+	var (
+		rootShare [32]byte
+		setID     [32]byte
+	)
+	copy(rootShare[:], reqRootShare)
+	copy(setID[:], reqSetID)
+
+	childIndex := uint16(reqAMP.ChildIndex)
+
+	return record.NewAMP(rootShare, setID, childIndex), nil
+}
+
 // MarshalHTLCAttempt constructs an RPC HTLCAttempt from the db representation.
 func (r *RouterBackend) MarshalHTLCAttempt(
 	htlc channeldb.HTLCAttempt) (*lnrpc.HTLCAttempt, error) {
@@ -870,6 +1191,12 @@ func (r *RouterBackend) MarshalHTLCAttempt(
 		Route:         route,
 	}
 
+	if htlc.ShardAllocation != nil {
+		rpcAttempt.ShardAllocation = marshallShardAllocation(
+			htlc.ShardAllocation,
+		)
+	}
+
 	switch {
 	case htlc.Settle != nil:
 		rpcAttempt.Status = lnrpc.HTLCAttempt_SUCCEEDED
@@ -896,6 +1223,29 @@ func (r *RouterBackend) MarshalHTLCAttempt(
 	return rpcAttempt, nil
 }
 
+// marshallShardAllocation marshalls a shard allocation from the database to its
+// rpc representation.
+func marshallShardAllocation(
+	allocation *channeldb.ShardAllocation) *lnrpc.ShardAllocation {
+
+	rpcAllocation := &lnrpc.ShardAllocation{
+		SplitAmtMAtoms: int64(allocation.SplitAmt),
+		NumCandidates:  allocation.NumCandidates,
+		SharePpm:       allocation.SharePPM,
+		ProbabilityPpm: allocation.ProbabilityPPM,
+	}
+
+	switch allocation.Strategy {
+	case channeldb.SplitStrategyFeeAware:
+		rpcAllocation.Strategy = lnrpc.SplitStrategy_FEE_AWARE
+
+	default:
+		rpcAllocation.Strategy = lnrpc.SplitStrategy_HALVING
+	}
+
+	return rpcAllocation
+}
+
 // marshallHtlcFailure marshalls htlc fail info from the database to its rpc
 // representation.
 func marshallHtlcFailure(failure *channeldb.HTLCFailInfo) (*lnrpc.Failure,
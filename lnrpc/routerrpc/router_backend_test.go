@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"reflect"
 	"testing"
 
 	"github.com/decred/dcrd/dcrutil/v4"
@@ -140,18 +141,18 @@ func testQueryRoutes(t *testing.T, useMissionControl bool, useMAtoms bool) {
 		}
 
 		if restrictions.ProbabilitySource(route.Vertex{2},
-			route.Vertex{1}, 0,
+			route.Vertex{1}, 0, 0,
 		) != 0 {
 			t.Fatal("expecting 0% probability for ignored edge")
 		}
 
 		if restrictions.ProbabilitySource(ignoreNodeVertex,
-			route.Vertex{6}, 0,
+			route.Vertex{6}, 0, 0,
 		) != 0 {
 			t.Fatal("expecting 0% probability for ignored node")
 		}
 
-		if restrictions.ProbabilitySource(node1, node2, 0) != 0 {
+		if restrictions.ProbabilitySource(node1, node2, 0, 0) != 0 {
 			t.Fatal("expecting 0% probability for ignored pair")
 		}
 
@@ -176,7 +177,7 @@ func testQueryRoutes(t *testing.T, useMissionControl bool, useMAtoms bool) {
 			expectedProb = testMissionControlProb
 		}
 		if restrictions.ProbabilitySource(route.Vertex{4},
-			route.Vertex{5}, 0,
+			route.Vertex{5}, 0, 0,
 		) != expectedProb {
 			t.Fatal("expecting 100% probability")
 		}
@@ -220,7 +221,7 @@ type mockMissionControl struct {
 }
 
 func (m *mockMissionControl) GetProbability(fromNode, toNode route.Vertex,
-	amt lnwire.MilliAtom) float64 {
+	amt lnwire.MilliAtom, capacity dcrutil.Amount) float64 {
 
 	return testMissionControlProb
 }
@@ -239,6 +240,20 @@ func (m *mockMissionControl) GetPairHistorySnapshot(fromNode,
 	return routing.TimedPairResult{}
 }
 
+func (m *mockMissionControl) GetConfig() *routing.MissionControlConfig {
+	return nil
+}
+
+func (m *mockMissionControl) SetConfig(cfg *routing.MissionControlConfig) error {
+	return nil
+}
+
+func (m *mockMissionControl) ImportHistory(
+	history *routing.MissionControlSnapshot, force bool) (int, error) {
+
+	return len(history.Pairs), nil
+}
+
 type mppOutcome byte
 
 const (
@@ -358,3 +373,103 @@ func testUnmarshalMPP(t *testing.T, test unmarshalMPPTest) {
 		t.Fatalf("test case has non-standard outcome")
 	}
 }
+
+// TestTrampolineIntent asserts that payments through a trampoline node are
+// restricted to our channels with it, and rejected if it isn't a direct peer.
+func TestTrampolineIntent(t *testing.T) {
+	t.Parallel()
+
+	dest, err := hex.DecodeString(destKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trampolineNode, err := hex.DecodeString(hintNodeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trampolineVertex, err := route.NewVertexFromBytes(trampolineNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerChans := map[route.Vertex][]uint64{
+		trampolineVertex: {1, 2},
+	}
+
+	backend := &RouterBackend{
+		MaxPaymentMAtoms: lnwire.MaxMilliAtom,
+		MaxTotalTimelock: 1000,
+		FetchPeerChannels: func(peer route.Vertex) ([]uint64, error) {
+			return peerChans[peer], nil
+		},
+	}
+
+	tests := []struct {
+		name             string
+		trampolineNode   []byte
+		outgoingChanIDs  []uint64
+		expectedOutgoing []uint64
+		expectErr        bool
+	}{
+		{
+			name:             "all peer channels",
+			trampolineNode:   trampolineNode,
+			expectedOutgoing: []uint64{1, 2},
+		},
+		{
+			name:             "restricted peer channels",
+			trampolineNode:   trampolineNode,
+			outgoingChanIDs:  []uint64{2, 3},
+			expectedOutgoing: []uint64{2},
+		},
+		{
+			name:            "no peer channel allowed",
+			trampolineNode:  trampolineNode,
+			outgoingChanIDs: []uint64{3},
+			expectErr:       true,
+		},
+		{
+			name:           "not a direct peer",
+			trampolineNode: dest,
+			expectErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			payIntent, err := backend.extractIntentFromSendRequest(
+				&SendPaymentRequest{
+					Dest:            dest,
+					Amt:             1000,
+					PaymentHash:     make([]byte, 32),
+					TimeoutSeconds:  60,
+					OutgoingChanIds: test.outgoingChanIDs,
+					TrampolineNode:  test.trampolineNode,
+				},
+			)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to extract intent: %v", err)
+			}
+
+			if payIntent.Target != trampolineVertex {
+				t.Fatalf("expected target %v, got %v",
+					trampolineVertex, payIntent.Target)
+			}
+			if !reflect.DeepEqual(
+				test.expectedOutgoing,
+				payIntent.OutgoingChannelIDs,
+			) {
+				t.Fatalf("expected outgoing channels %v, "+
+					"got %v", test.expectedOutgoing,
+					payIntent.OutgoingChannelIDs)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync/atomic"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrlnd/channeldb"
@@ -75,10 +76,30 @@ var (
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/routerrpc.Router/ProbePayment": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/routerrpc.Router/QueryMissionControl": {{
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/routerrpc.Router/XExportMissionControl": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/routerrpc.Router/XImportMissionControl": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/routerrpc.Router/GetMissionControlConfig": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/routerrpc.Router/SetMissionControlConfig": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/routerrpc.Router/QueryProbability": {{
 			Entity: "offchain",
 			Action: "read",
@@ -279,21 +300,23 @@ func (s *Server) SendPaymentV2(req *SendPaymentRequest,
 		if err == channeldb.ErrPaymentInFlight ||
 			err == channeldb.ErrAlreadyPaid {
 
-			log.Debugf("SendPayment async result for hash %x: %v",
-				payment.PaymentHash, err)
+			log.Debugf("SendPayment async result for payment %x: %v",
+				payment.Identifier(), err)
 
 			return status.Error(
 				codes.AlreadyExists, err.Error(),
 			)
 		}
 
-		log.Errorf("SendPayment async error for hash %x: %v",
-			payment.PaymentHash, err)
+		log.Errorf("SendPayment async error for payment %x: %v",
+			payment.Identifier(), err)
 
 		return err
 	}
 
-	return s.trackPayment(payment.PaymentHash, stream, req.NoInflightUpdates)
+	return s.trackPayment(
+		payment.Identifier(), stream, req.NoInflightUpdates,
+	)
 }
 
 // EstimateRouteFee allows callers to obtain a lower bound w.r.t how much it
@@ -339,6 +362,65 @@ func (s *Server) EstimateRouteFee(ctx context.Context,
 	}, nil
 }
 
+// ProbePayment probes the destination of a payment without risking any funds,
+// returning the largest amount that was found to reach it.
+func (s *Server) ProbePayment(ctx context.Context,
+	req *ProbePaymentRequest) (*ProbePaymentResponse, error) {
+
+	// The probe is described the same way as a regular payment, except
+	// for the payment hash which is generated for every probe.
+	payment, err := s.cfg.RouterBackend.extractIntentFromSendRequest(
+		&SendPaymentRequest{
+			Dest:            req.Dest,
+			Amt:             req.Amt,
+			AmtMAtoms:       req.AmtMAtoms,
+			PaymentRequest:  req.PaymentRequest,
+			FinalCltvDelta:  req.FinalCltvDelta,
+			TimeoutSeconds:  req.TimeoutSeconds,
+			FeeLimitAtoms:   req.FeeLimitAtoms,
+			FeeLimitMAtoms:  req.FeeLimitMAtoms,
+			OutgoingChanIds: req.OutgoingChanIds,
+			LastHopPubkey:   req.LastHopPubkey,
+			CltvLimit:       req.CltvLimit,
+			RouteHints:      req.RouteHints,
+			DestFeatures:    req.DestFeatures,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.cfg.Router.ProbePayment(payment, req.MaxProbes)
+	if err != nil {
+		return nil, err
+	}
+
+	failureReason, err := marshallPaymentFailureReason(
+		result.FailureReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ProbePaymentResponse{
+		MaxAmtMAtoms:  int64(result.Amount),
+		FailureReason: failureReason,
+		NumProbes:     result.NumProbes,
+	}
+
+	if result.Route != nil {
+		resp.FeeMAtoms = int64(result.Route.TotalFees())
+		resp.Route, err = s.cfg.RouterBackend.MarshallRoute(
+			result.Route,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
 // SendToRouteV2 sends a payment through a predefined route. The response of this
 // call contains structured error information.
 func (s *Server) SendToRouteV2(ctx context.Context,
@@ -398,6 +480,68 @@ func (s *Server) QueryMissionControl(ctx context.Context,
 
 	snapshot := s.cfg.RouterBackend.MissionControl.GetHistorySnapshot()
 
+	response := QueryMissionControlResponse{
+		Pairs:     toRPCPairHistories(snapshot),
+		Estimator: snapshot.Estimator,
+	}
+
+	return &response, nil
+}
+
+// XExportMissionControl exports the node pair-level mission control history in
+// a format that can be imported with XImportMissionControl.
+func (s *Server) XExportMissionControl(ctx context.Context,
+	req *XExportMissionControlRequest) (*XExportMissionControlResponse,
+	error) {
+
+	snapshot := s.cfg.RouterBackend.MissionControl.GetHistorySnapshot()
+
+	return &XExportMissionControlResponse{
+		Pairs: toRPCPairHistories(snapshot),
+	}, nil
+}
+
+// XImportMissionControl imports the state provided to our internal mission
+// control. Only entries that are fresher than our existing state will be used,
+// unless the force flag is set.
+func (s *Server) XImportMissionControl(ctx context.Context,
+	req *XImportMissionControlRequest) (*XImportMissionControlResponse,
+	error) {
+
+	if len(req.Pairs) == 0 {
+		return nil, errors.New("at least one pair required for import")
+	}
+
+	snapshot := &routing.MissionControlSnapshot{
+		Pairs: make(
+			[]routing.MissionControlPairSnapshot, len(req.Pairs),
+		),
+	}
+
+	for i, pairResult := range req.Pairs {
+		snapshotEntry, err := toPairSnapshot(pairResult)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot.Pairs[i] = *snapshotEntry
+	}
+
+	imported, err := s.cfg.RouterBackend.MissionControl.ImportHistory(
+		snapshot, req.Force,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &XImportMissionControlResponse{
+		NumImported: uint32(imported),
+	}, nil
+}
+
+// toRPCPairHistories marshalls the pairs of a mission control snapshot to
+// their rpc representation.
+func toRPCPairHistories(snapshot *routing.MissionControlSnapshot) []*PairHistory {
 	rpcPairs := make([]*PairHistory, 0, len(snapshot.Pairs))
 	for _, p := range snapshot.Pairs {
 		// Prevent binding to loop variable.
@@ -412,11 +556,143 @@ func (s *Server) QueryMissionControl(ctx context.Context,
 		rpcPairs = append(rpcPairs, &rpcPair)
 	}
 
-	response := QueryMissionControlResponse{
-		Pairs: rpcPairs,
+	return rpcPairs
+}
+
+// toPairSnapshot unmarshalls an rpc pair history to a mission control pair
+// snapshot, validating its values.
+func toPairSnapshot(pairResult *PairHistory) (
+	*routing.MissionControlPairSnapshot, error) {
+
+	from, err := route.NewVertexFromBytes(pairResult.NodeFrom)
+	if err != nil {
+		return nil, err
 	}
 
-	return &response, nil
+	to, err := route.NewVertexFromBytes(pairResult.NodeTo)
+	if err != nil {
+		return nil, err
+	}
+
+	pairPrefix := fmt.Sprintf("pair: %v -> %v:", from, to)
+
+	if from == to {
+		return nil, fmt.Errorf("%v source and destination node must "+
+			"differ", pairPrefix)
+	}
+
+	if pairResult.History == nil {
+		return nil, fmt.Errorf("%v missing history", pairPrefix)
+	}
+
+	failAmt, failTime, err := getPair(
+		lnwire.MilliAtom(pairResult.History.FailAmtMAtoms),
+		dcrutil.Amount(pairResult.History.FailAmtAtoms),
+		pairResult.History.FailTime, true,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%v invalid failure: %v", pairPrefix,
+			err)
+	}
+
+	successAmt, successTime, err := getPair(
+		lnwire.MilliAtom(pairResult.History.SuccessAmtMAtoms),
+		dcrutil.Amount(pairResult.History.SuccessAmtAtoms),
+		pairResult.History.SuccessTime, false,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%v invalid success: %v", pairPrefix,
+			err)
+	}
+
+	if failTime.IsZero() && successTime.IsZero() {
+		return nil, fmt.Errorf("%v must have either a success or "+
+			"failure", pairPrefix)
+	}
+
+	return &routing.MissionControlPairSnapshot{
+		Pair: routing.NewDirectedNodePair(from, to),
+		TimedPairResult: routing.TimedPairResult{
+			FailAmt:     failAmt,
+			FailTime:    failTime,
+			SuccessAmt:  successAmt,
+			SuccessTime: successTime,
+		},
+	}, nil
+}
+
+// getPair validates the values provided for a mission control result and
+// returns the milliatom amount and timestamp for it. A failure may have a zero
+// amount if it is amount-independent, a success always requires an amount.
+func getPair(amtMAtoms lnwire.MilliAtom, amtAtoms dcrutil.Amount,
+	timestamp int64, isFailure bool) (lnwire.MilliAtom, time.Time, error) {
+
+	amt, err := getMAtomsPairValue(amtMAtoms, amtAtoms)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var (
+		timeSet   = timestamp != 0
+		amountSet = amt != 0
+	)
+
+	switch {
+	// If a timestamp and amount is provided, return those values.
+	case timeSet && amountSet:
+		return amt, time.Unix(timestamp, 0), nil
+
+	// Return an error if it does have a timestamp without an amount, and
+	// it's not expected to be a failure.
+	case !isFailure && timeSet && !amountSet:
+		return 0, time.Time{}, errors.New("non-zero timestamp " +
+			"requires non-zero amount for success pairs")
+
+	// Return an error if it does have an amount without a timestamp, it's
+	// considered invalid.
+	case !timeSet && amountSet:
+		return 0, time.Time{}, errors.New("non-zero amount requires " +
+			"non-zero timestamp")
+
+	// If it's a failure with a timestamp but no amount, we'll return the
+	// timestamp with a zero amount.
+	case isFailure && timeSet && !amountSet:
+		return 0, time.Unix(timestamp, 0), nil
+	}
+
+	// If we reach this point, then neither amount nor timestamp were set.
+	return 0, time.Time{}, nil
+}
+
+// getMAtomsPairValue checks the milliatom and atom values set for a pair and
+// ensures that the values provided are either the same, or only a single value
+// is set.
+func getMAtomsPairValue(mAtomsValue lnwire.MilliAtom,
+	atomsValue dcrutil.Amount) (lnwire.MilliAtom, error) {
+
+	// If our milliatom value converted to atoms equals our atoms value, we
+	// just return the milliatom value, since the values are the same.
+	if mAtomsValue.ToAtoms() == atomsValue {
+		return mAtomsValue, nil
+	}
+
+	// If we have no milliatom value, we can just return our atoms value
+	// even if it is zero, because it's impossible that we have mismatched
+	// values.
+	if mAtomsValue == 0 {
+		return lnwire.NewMAtomsFromAtoms(atomsValue), nil
+	}
+
+	// Likewise, we can just use the milliatom value if we have no atoms
+	// value set.
+	if atomsValue == 0 {
+		return mAtomsValue, nil
+	}
+
+	// If our values are non-zero but not equal, we have invalid amounts
+	// set, so we fail.
+	return 0, fmt.Errorf("matoms: %v and atoms: %v values not equal",
+		mAtomsValue, atomsValue)
 }
 
 // toRPCPairData marshalls mission control pair data to the rpc struct.
@@ -439,6 +715,136 @@ func toRPCPairData(data *routing.TimedPairResult) *PairData {
 	return &rpcData
 }
 
+// GetMissionControlConfig returns our current mission control config.
+func (s *Server) GetMissionControlConfig(ctx context.Context,
+	req *GetMissionControlConfigRequest) (*GetMissionControlConfigResponse,
+	error) {
+
+	cfg := s.cfg.RouterBackend.MissionControl.GetConfig()
+
+	rpcCfg := &MissionControlConfig{
+		MaximumPaymentResults: uint32(cfg.MaxMcHistory),
+		MinimumFailureRelaxInterval: uint64(
+			cfg.MinFailureRelaxInterval.Seconds(),
+		),
+		AttemptCost: uint64(cfg.PaymentAttemptPenalty.ToAtoms()),
+	}
+
+	switch estimator := cfg.Estimator.(type) {
+	case *routing.AprioriEstimator:
+		rpcCfg.Model = MissionControlConfig_APRIORI
+		rpcCfg.EstimatorConfig = &MissionControlConfig_Apriori{
+			Apriori: &AprioriParameters{
+				HalfLifeSeconds: uint64(
+					estimator.PenaltyHalfLife.Seconds(),
+				),
+				HopProbability: estimator.AprioriHopProbability,
+				Weight:         estimator.AprioriWeight,
+			},
+		}
+
+	case *routing.BimodalEstimator:
+		rpcCfg.Model = MissionControlConfig_BIMODAL
+		rpcCfg.EstimatorConfig = &MissionControlConfig_Bimodal{
+			Bimodal: &BimodalParameters{
+				NodeWeight:  estimator.BimodalNodeWeight,
+				ScaleMAtoms: uint64(estimator.BimodalScaleMAtoms),
+				DecayTime: uint64(
+					estimator.BimodalDecayTime.Seconds(),
+				),
+			},
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown estimator %v", cfg.Estimator)
+	}
+
+	return &GetMissionControlConfigResponse{
+		Config: rpcCfg,
+	}, nil
+}
+
+// SetMissionControlConfig sets parameters in the mission control config.
+func (s *Server) SetMissionControlConfig(ctx context.Context,
+	req *SetMissionControlConfigRequest) (*SetMissionControlConfigResponse,
+	error) {
+
+	if req.Config == nil {
+		return nil, errors.New("mission control config required")
+	}
+
+	var (
+		estimator routing.Estimator
+		err       error
+	)
+	switch req.Config.Model {
+	case MissionControlConfig_APRIORI:
+		params := req.Config.GetApriori()
+		if params == nil {
+			return nil, errors.New("apriori parameters required " +
+				"for the apriori model")
+		}
+
+		estimator, err = routing.NewAprioriEstimator(
+			routing.AprioriConfig{
+				PenaltyHalfLife: time.Duration(
+					params.HalfLifeSeconds,
+				) * time.Second,
+				AprioriHopProbability: params.HopProbability,
+				AprioriWeight:         params.Weight,
+			},
+		)
+
+	case MissionControlConfig_BIMODAL:
+		params := req.Config.GetBimodal()
+		if params == nil {
+			return nil, errors.New("bimodal parameters required " +
+				"for the bimodal model")
+		}
+
+		estimator, err = routing.NewBimodalEstimator(
+			routing.BimodalConfig{
+				BimodalNodeWeight: params.NodeWeight,
+				BimodalScaleMAtoms: lnwire.MilliAtom(
+					params.ScaleMAtoms,
+				),
+				BimodalDecayTime: time.Duration(
+					params.DecayTime,
+				) * time.Second,
+			},
+		)
+
+	default:
+		return nil, fmt.Errorf("unknown probability model %v",
+			req.Config.Model)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &routing.MissionControlConfig{
+		Estimator:    estimator,
+		MaxMcHistory: int(req.Config.MaximumPaymentResults),
+		MinFailureRelaxInterval: time.Duration(
+			req.Config.MinimumFailureRelaxInterval,
+		) * time.Second,
+		PaymentAttemptPenalty: lnwire.NewMAtomsFromAtoms(
+			dcrutil.Amount(req.Config.AttemptCost),
+		),
+	}
+
+	err = s.cfg.RouterBackend.MissionControl.SetConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The new config was persisted, so that the payment attempt penalty
+	// can be applied to path finding.
+	s.cfg.RouterBackend.SetPaymentAttemptPenalty(cfg.PaymentAttemptPenalty)
+
+	return &SetMissionControlConfigResponse{}, nil
+}
+
 // QueryProbability returns the current success probability estimate for a
 // given node pair and amount.
 func (s *Server) QueryProbability(ctx context.Context,
@@ -456,8 +862,10 @@ func (s *Server) QueryProbability(ctx context.Context,
 
 	amt := lnwire.MilliAtom(req.AmtMAtoms)
 
+	// The capacity of the channel between the pair is not known here, so
+	// the estimator falls back to its capacity-independent behavior.
 	mc := s.cfg.RouterBackend.MissionControl
-	prob := mc.GetProbability(fromNode, toNode, amt)
+	prob := mc.GetProbability(fromNode, toNode, amt, 0)
 	history := mc.GetPairHistorySnapshot(fromNode, toNode)
 
 	return &QueryProbabilityResponse{
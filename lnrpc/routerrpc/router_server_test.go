@@ -0,0 +1,137 @@
+package routerrpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetMAtomsPairValue tests the parsing of the milliatom and atom values
+// of mission control pair data.
+func TestGetMAtomsPairValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		mAtomsValue lnwire.MilliAtom
+		atomsValue  dcrutil.Amount
+		expected    lnwire.MilliAtom
+		expectErr   bool
+	}{
+		{
+			name: "both zero",
+		},
+		{
+			name:        "equal values",
+			mAtomsValue: 2000,
+			atomsValue:  2,
+			expected:    2000,
+		},
+		{
+			name:        "milliatoms only",
+			mAtomsValue: 2500,
+			expected:    2500,
+		},
+		{
+			name:       "atoms only",
+			atomsValue: 3,
+			expected:   3000,
+		},
+		{
+			name:        "mismatched values",
+			mAtomsValue: 2000,
+			atomsValue:  3,
+			expectErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			amt, err := getMAtomsPairValue(
+				test.mAtomsValue, test.atomsValue,
+			)
+			if test.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, amt)
+		})
+	}
+}
+
+// TestToPairSnapshot tests the validation and conversion of imported pair
+// histories.
+func TestToPairSnapshot(t *testing.T) {
+	var (
+		from      = route.Vertex{1}
+		to        = route.Vertex{2}
+		timestamp = time.Unix(1000, 0)
+	)
+
+	newPair := func(history *PairData) *PairHistory {
+		return &PairHistory{
+			NodeFrom: from[:],
+			NodeTo:   to[:],
+			History:  history,
+		}
+	}
+
+	// A pair with both a failure and a success is converted.
+	snapshot, err := toPairSnapshot(newPair(&PairData{
+		FailTime:         timestamp.Unix(),
+		FailAmtMAtoms:    3000,
+		SuccessTime:      timestamp.Unix(),
+		SuccessAmtMAtoms: 1000,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, &routing.MissionControlPairSnapshot{
+		Pair: routing.NewDirectedNodePair(from, to),
+		TimedPairResult: routing.TimedPairResult{
+			FailTime:    timestamp,
+			FailAmt:     3000,
+			SuccessTime: timestamp,
+			SuccessAmt:  1000,
+		},
+	}, snapshot)
+
+	// An amount-independent failure is allowed.
+	snapshot, err = toPairSnapshot(newPair(&PairData{
+		FailTime: timestamp.Unix(),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, timestamp, snapshot.FailTime)
+	require.True(t, snapshot.SuccessTime.IsZero())
+
+	// A success requires an amount.
+	_, err = toPairSnapshot(newPair(&PairData{
+		SuccessTime: timestamp.Unix(),
+	}))
+	require.Error(t, err)
+
+	// An amount requires a timestamp.
+	_, err = toPairSnapshot(newPair(&PairData{
+		FailAmtMAtoms: 1000,
+	}))
+	require.Error(t, err)
+
+	// A pair without any result is rejected.
+	_, err = toPairSnapshot(newPair(&PairData{}))
+	require.Error(t, err)
+
+	// A pair from a node to itself is rejected.
+	_, err = toPairSnapshot(&PairHistory{
+		NodeFrom: from[:],
+		NodeTo:   from[:],
+		History: &PairData{
+			FailTime: timestamp.Unix(),
+		},
+	})
+	require.Error(t, err)
+}
@@ -7,6 +7,11 @@ import (
 )
 
 // RoutingConfig contains the configurable parameters that control routing.
+//
+// Note that once a mission control config was set at runtime through the
+// SetMissionControlConfig RPC, the persisted config takes precedence over the
+// estimator and its parameters, AttemptCost and MaxMcHistory configured here.
+// The overridden values are logged on startup.
 type RoutingConfig struct {
 	// MinRouteProbability is the minimum required route success
 	// probability to attempt the payment.
@@ -29,6 +34,27 @@ type RoutingConfig struct {
 	// channel is back at 50% probability.
 	PenaltyHalfLife time.Duration `long:"penaltyhalflife" description:"Defines the duration after which a penalized node or channel is back at 50% probability"`
 
+	// ProbabilityEstimatorType is the name of the probability estimator
+	// that mission control uses to estimate the success probability of
+	// node pairs.
+	ProbabilityEstimatorType string `long:"estimator" choice:"apriori" choice:"bimodal" description:"Probability estimator used for pathfinding."`
+
+	// BimodalScale describes the scale over which channels statistically
+	// have some liquidity left. A large value (compared to typical channel
+	// capacities) means that channel balances are assumed to be uniformly
+	// distributed, a small value leads to the assumption of very
+	// unbalanced channels.
+	BimodalScale uint64 `long:"bimodalscale" description:"Defines the unbalancedness assumed for the network, the amount defined in matoms. Only used with the bimodal estimator."`
+
+	// BimodalNodeWeight defines how strongly other previous forwardings on
+	// channels of a router should be taken into account when computing a
+	// channel's probability to route. Valid values are in [0, 1].
+	BimodalNodeWeight float64 `long:"bimodalnodeweight" description:"Defines how strongly non-routed channels of forwarders should be taken into account for probability estimation. Valid values are in [0, 1]. Only used with the bimodal estimator."`
+
+	// BimodalDecayTime is the scale for the exponential information decay
+	// over time for previous successes or failures.
+	BimodalDecayTime time.Duration `long:"bimodaldecaytime" description:"Describes the information decay of knowledge about previous successes and failures in channels. Only used with the bimodal estimator."`
+
 	// AttemptCost is the virtual cost in path finding weight units of
 	// executing a payment attempt that fails. It is used to trade off
 	// potentially better routes against their probability of succeeding.
@@ -194,6 +194,15 @@ func rpcFailureResolution(invoiceFailure invoices.FailResolutionResult) (
 	case invoices.ResultMppInProgress:
 		return FailureDetail_MPP_IN_PROGRESS, nil
 
+	case invoices.ResultAmpError:
+		return FailureDetail_INVALID_AMP, nil
+
+	case invoices.ResultAmpReconstruction:
+		return FailureDetail_AMP_RECONSTRUCTION, nil
+
+	case invoices.ResultInterceptorFailed:
+		return FailureDetail_INTERCEPTOR_FAILED, nil
+
 	default:
 		return 0, fmt.Errorf("unknown fail resolution: %v",
 			invoiceFailure.FailureString())
@@ -238,6 +247,15 @@ func rpcOutgoingFailure(failureDetail htlcswitch.OutgoingFailure) (
 	case htlcswitch.OutgoingFailureForwardsDisabled:
 		return FailureDetail_FORWARDS_DISABLED, nil
 
+	case htlcswitch.OutgoingFailureDustExposure:
+		return FailureDetail_DUST_EXPOSURE, nil
+
+	case htlcswitch.OutgoingFailureRateLimited:
+		return FailureDetail_RATE_LIMITED, nil
+
+	case htlcswitch.OutgoingFailureInsufficientReputation:
+		return FailureDetail_INSUFFICIENT_REPUTATION, nil
+
 	default:
 		return 0, fmt.Errorf("unknown outgoing failure "+
 			"detail: %v", failureDetail.FailureString())
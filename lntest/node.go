@@ -168,6 +168,7 @@ type NodeConfig struct {
 	WalletPort  int
 
 	AcceptKeySend bool
+	AcceptAMP     bool
 }
 
 func (cfg NodeConfig) P2PAddr() string {
@@ -254,6 +255,10 @@ func (cfg NodeConfig) genArgs() []string {
 		args = append(args, "--accept-keysend")
 	}
 
+	if cfg.AcceptAMP {
+		args = append(args, "--accept-amp")
+	}
+
 	return args
 }
 
@@ -383,6 +388,10 @@ func newNode(cfg NodeConfig) (*HarnessNode, error) {
 	// enabled.
 	cfg.AcceptKeySend = true
 
+	// Similarly, run all tests with accept AMP, since spontaneous AMP
+	// payments only affect htlcs that carry an AMP record.
+	cfg.AcceptAMP = true
+
 	return &HarnessNode{
 		Cfg:               &cfg,
 		NodeID:            int(nodeNum),
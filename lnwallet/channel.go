@@ -340,6 +340,12 @@ type PaymentDescriptor struct {
 	// NOTE: Populated only on add payment descriptor entry types.
 	OnionBlob []byte
 
+	// BlindingPoint is the ephemeral key used to process the onion blob
+	// of an HTLC that is part of a blinded route.
+	//
+	// NOTE: Populated only on add payment descriptor entry types.
+	BlindingPoint *secp256k1.PublicKey
+
 	// ShaOnionBlob is a sha of the onion blob.
 	//
 	// NOTE: Populated only in payment descriptor with MalformedFail type.
@@ -421,6 +427,7 @@ func PayDescsFromRemoteLogUpdates(chanID lnwire.ShortChannelID, height uint64,
 			}
 			pd.OnionBlob = make([]byte, len(wireMsg.OnionBlob))
 			copy(pd.OnionBlob, wireMsg.OnionBlob[:])
+			pd.BlindingPoint = wireMsg.BlindingPoint
 
 		case *lnwire.UpdateFulfillHTLC:
 			pd = PaymentDescriptor{
@@ -741,6 +748,7 @@ func (c *commitment) toDiskCommit(ourCommit bool) *channeldb.ChannelCommitment {
 			HtlcIndex:     htlc.HtlcIndex,
 			LogIndex:      htlc.LogIndex,
 			Incoming:      false,
+			BlindingPoint: htlc.BlindingPoint,
 		}
 		h.OnionBlob = make([]byte, len(htlc.OnionBlob))
 		copy(h.OnionBlob, htlc.OnionBlob)
@@ -766,6 +774,7 @@ func (c *commitment) toDiskCommit(ourCommit bool) *channeldb.ChannelCommitment {
 			HtlcIndex:     htlc.HtlcIndex,
 			LogIndex:      htlc.LogIndex,
 			Incoming:      true,
+			BlindingPoint: htlc.BlindingPoint,
 		}
 		h.OnionBlob = make([]byte, len(htlc.OnionBlob))
 		copy(h.OnionBlob, htlc.OnionBlob)
@@ -855,6 +864,7 @@ func (lc *LightningChannel) diskHtlcToPayDesc(feeRate chainfee.AtomPerKByte,
 		HtlcIndex:          htlc.HtlcIndex,
 		LogIndex:           htlc.LogIndex,
 		OnionBlob:          htlc.OnionBlob,
+		BlindingPoint:      htlc.BlindingPoint,
 		localOutputIndex:   localOutputIndex,
 		remoteOutputIndex:  remoteOutputIndex,
 		ourPkScript:        ourP2SH,
@@ -1451,6 +1461,7 @@ func (lc *LightningChannel) logUpdateToPayDesc(logUpdate *channeldb.LogUpdate,
 		}
 		pd.OnionBlob = make([]byte, len(wireMsg.OnionBlob))
 		copy(pd.OnionBlob, wireMsg.OnionBlob[:])
+		pd.BlindingPoint = wireMsg.BlindingPoint
 
 		isDustRemote := htlcIsDust(
 			lc.channelState.ChanType, false, false, feeRate,
@@ -1650,6 +1661,7 @@ func (lc *LightningChannel) remoteLogUpdateToPayDesc(logUpdate *channeldb.LogUpd
 		}
 		pd.OnionBlob = make([]byte, len(wireMsg.OnionBlob))
 		copy(pd.OnionBlob, wireMsg.OnionBlob[:])
+		pd.BlindingPoint = wireMsg.BlindingPoint
 
 		// We don't need to generate an htlc script yet. This will be
 		// done once we sign our remote commitment.
@@ -2227,10 +2239,10 @@ type HtlcRetribution struct {
 // transaction. The BreachRetribution is then sent over the ContractBreach
 // channel in order to allow the subscriber of the channel to dispatch justice.
 type BreachRetribution struct {
-	// BreachTransaction is the transaction which breached the channel
-	// contract by spending from the funding multi-sig with a revoked
-	// commitment transaction.
-	BreachTransaction *wire.MsgTx
+	// BreachTxHash is the hash of the transaction which breached the
+	// channel contract by spending from the funding multi-sig with a
+	// revoked commitment transaction.
+	BreachTxHash chainhash.Hash
 
 	// BreachHeight records the block height confirming the breach
 	// transaction, used as a height hint when registering for
@@ -2245,10 +2257,6 @@ type BreachRetribution struct {
 	// RevokedStateNum is the revoked state number which was broadcast.
 	RevokedStateNum uint64
 
-	// PendingHTLCs is a slice of the HTLCs which were pending at this
-	// point within the channel's history transcript.
-	PendingHTLCs []channeldb.HTLC
-
 	// LocalOutputSignDesc is a input.SignDescriptor which is capable of
 	// generating the signature necessary to sweep the output within the
 	// BreachTransaction that pays directly us.
@@ -2299,14 +2307,13 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 	breachHeight uint32) (*BreachRetribution, error) {
 
 	// Query the on-disk revocation log for the snapshot which was recorded
-	// at this particular state num.
-	revokedSnapshot, err := chanState.FindPreviousState(stateNum)
+	// at this particular state num. Depending on the format it was stored
+	// in, we'll either get a compact log entry or the full commitment.
+	revokedLog, revokedCommit, err := chanState.FindPreviousState(stateNum)
 	if err != nil {
 		return nil, err
 	}
 
-	commitHash := revokedSnapshot.CommitTx.TxHash()
-
 	// With the state number broadcast known, we can now derive/restore the
 	// proper revocation preimage necessary to sweep the remote party's
 	// output.
@@ -2328,44 +2335,75 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 	// Next, reconstruct the scripts as they were present at this state
 	// number so we can have the proper witness script to sign and include
 	// within the final witness.
-	theirDelay := uint32(chanState.RemoteChanCfg.CsvDelay)
-	theirPkScript, err := input.CommitScriptToSelf(
-		theirDelay, keyRing.ToLocalKey, keyRing.RevocationKey,
-	)
-	if err != nil {
-		return nil, err
-	}
-	theirWitnessHash, err := input.ScriptHashPkScript(theirPkScript)
+	scripts, err := newRemoteCommitScripts(chanState, keyRing)
 	if err != nil {
 		return nil, err
 	}
 
-	// Since it is the remote breach we are reconstructing, the output going
-	// to us will be a to-remote script with our local params.
-	ourScript, ourDelay, err := CommitScriptToRemote(
-		chanState.ChanType, keyRing.ToRemoteKey,
+	// The compact log entry records the location of the commitment
+	// outputs and the non-dust HTLCs directly, while we'll need to find
+	// them in the commitment transaction of a full commitment.
+	var (
+		commitHash       chainhash.Hash
+		ourAmt, theirAmt dcrutil.Amount
+		ourIndex         uint16
+		theirIndex       uint16
+		htlcEntries      []*channeldb.HTLCEntry
 	)
-	if err != nil {
-		return nil, err
+	if revokedLog != nil {
+		commitHash = revokedLog.CommitTxHash
+		ourAmt = revokedLog.OurBalance.ToAtoms()
+		theirAmt = revokedLog.TheirBalance.ToAtoms()
+		ourIndex = revokedLog.OurOutputIndex
+		theirIndex = revokedLog.TheirOutputIndex
+		htlcEntries = revokedLog.HTLCEntries
+	} else {
+		commitHash = revokedCommit.CommitTx.TxHash()
+		ourAmt = revokedCommit.LocalBalance.ToAtoms()
+		theirAmt = revokedCommit.RemoteBalance.ToAtoms()
+		ourIndex, theirIndex = scripts.findOutputIndexes(
+			revokedCommit.CommitTx,
+		)
+
+		for _, htlc := range revokedCommit.Htlcs {
+			// If the HTLC is dust, then we'll skip it as it
+			// doesn't have an output on the commitment
+			// transaction.
+			if htlcIsDust(
+				chanState.ChanType, htlc.Incoming, false,
+				chainfee.AtomPerKByte(revokedCommit.FeePerKB),
+				htlc.Amt.ToAtoms(),
+				chanState.RemoteChanCfg.DustLimit,
+			) {
+				continue
+			}
+
+			htlcEntries = append(htlcEntries, &channeldb.HTLCEntry{
+				RHash:         htlc.RHash,
+				RefundTimeout: htlc.RefundTimeout,
+				OutputIndex:   uint16(htlc.OutputIndex),
+				Incoming:      htlc.Incoming,
+				Amt:           htlc.Amt.ToAtoms(),
+			})
+		}
 	}
 
 	// In order to fully populate the breach retribution struct, we'll need
-	// to find the exact index of the commitment outputs.
+	// the exact index of the commitment outputs. If an output doesn't
+	// exist, its index is irrelevant, as it's considered dust below.
 	ourOutpoint := wire.OutPoint{
 		Hash: commitHash,
 		Tree: wire.TxTreeRegular,
 	}
+	if ourIndex != channeldb.OutputIndexEmpty {
+		ourOutpoint.Index = uint32(ourIndex)
+	}
 	theirOutpoint := wire.OutPoint{
 		Hash: commitHash,
 		Tree: wire.TxTreeRegular,
 	}
-	for i, txOut := range revokedSnapshot.CommitTx.TxOut {
-		switch {
-		case bytes.Equal(txOut.PkScript, ourScript.PkScript):
-			ourOutpoint.Index = uint32(i)
-		case bytes.Equal(txOut.PkScript, theirWitnessHash):
-			theirOutpoint.Index = uint32(i)
-		}
+	if theirIndex != channeldb.OutputIndexEmpty {
+		theirOutpoint.Index = uint32(theirIndex)
 	}
 
 	// Conditionally instantiate a sign descriptor for each of the
@@ -2376,20 +2414,16 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 		theirSignDesc *input.SignDescriptor
 	)
 
-	// Compute the balances in atoms.
-	ourAmt := revokedSnapshot.LocalBalance.ToAtoms()
-	theirAmt := revokedSnapshot.RemoteBalance.ToAtoms()
-
 	// If our balance exceeds the remote party's dust limit, instantiate
 	// the sign descriptor for our output.
 	if ourAmt >= chanState.RemoteChanCfg.DustLimit {
 		ourSignDesc = &input.SignDescriptor{
 			SingleTweak:   keyRing.LocalCommitKeyTweak,
 			KeyDesc:       chanState.LocalChanCfg.PaymentBasePoint,
-			WitnessScript: ourScript.WitnessScript,
+			WitnessScript: scripts.ourScript.WitnessScript,
 			Output: &wire.TxOut{
 				Version:  scriptVersion,
-				PkScript: ourScript.PkScript,
+				PkScript: scripts.ourScript.PkScript,
 				Value:    int64(ourAmt),
 			},
 			HashType: txscript.SigHashAll,
@@ -2402,10 +2436,10 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 		theirSignDesc = &input.SignDescriptor{
 			KeyDesc:       chanState.LocalChanCfg.RevocationBasePoint,
 			DoubleTweak:   commitmentSecret,
-			WitnessScript: theirPkScript,
+			WitnessScript: scripts.theirScript,
 			Output: &wire.TxOut{
 				Version:  scriptVersion,
-				PkScript: theirWitnessHash,
+				PkScript: scripts.theirPkScript,
 				Value:    int64(theirAmt),
 			},
 			HashType: txscript.SigHashAll,
@@ -2415,24 +2449,15 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 	// With the commitment outputs located, we'll now generate all the
 	// retribution structs for each of the HTLC transactions active on the
 	// remote commitment transaction.
-	htlcRetributions := make([]HtlcRetribution, 0, len(revokedSnapshot.Htlcs))
-	for _, htlc := range revokedSnapshot.Htlcs {
-		// If the HTLC is dust, then we'll skip it as it doesn't have
-		// an output on the commitment transaction.
-		if htlcIsDust(
-			chanState.ChanType, htlc.Incoming, false,
-			chainfee.AtomPerKByte(revokedSnapshot.FeePerKB),
-			htlc.Amt.ToAtoms(), chanState.RemoteChanCfg.DustLimit,
-		) {
-			continue
-		}
-
+	htlcRetributions := make([]HtlcRetribution, 0, len(htlcEntries))
+	for _, htlc := range htlcEntries {
 		// We'll generate the original second level witness script now,
 		// as we'll need it if we're revoking an HTLC output on the
 		// remote commitment transaction, and *they* go to the second
 		// level.
 		secondLevelWitnessScript, err := input.SecondLevelHtlcScript(
-			keyRing.RevocationKey, keyRing.ToLocalKey, theirDelay,
+			keyRing.RevocationKey, keyRing.ToLocalKey,
+			scripts.theirDelay,
 		)
 		if err != nil {
 			return nil, err
@@ -2459,7 +2484,7 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 				Output: &wire.TxOut{
 					Version:  scriptVersion,
 					PkScript: htlcPkScript,
-					Value:    int64(htlc.Amt.ToAtoms()),
+					Value:    int64(htlc.Amt),
 				},
 				HashType: txscript.SigHashAll,
 			},
@@ -2478,21 +2503,119 @@ func NewBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 	// swiftly bring justice to the cheating remote party.
 	return &BreachRetribution{
 		ChainHash:            chanState.ChainHash,
-		BreachTransaction:    revokedSnapshot.CommitTx,
+		BreachTxHash:         commitHash,
 		BreachHeight:         breachHeight,
 		RevokedStateNum:      stateNum,
-		PendingHTLCs:         revokedSnapshot.Htlcs,
 		LocalOutpoint:        ourOutpoint,
 		LocalOutputSignDesc:  ourSignDesc,
-		LocalDelay:           ourDelay,
+		LocalDelay:           scripts.ourDelay,
 		RemoteOutpoint:       theirOutpoint,
 		RemoteOutputSignDesc: theirSignDesc,
-		RemoteDelay:          theirDelay,
+		RemoteDelay:          scripts.theirDelay,
 		HtlcRetributions:     htlcRetributions,
 		KeyRing:              keyRing,
 	}, nil
 }
 
+// remoteCommitScripts holds the scripts of our and their output on a remote
+// commitment transaction.
+type remoteCommitScripts struct {
+	// ourScript is the to-remote script paying to us.
+	ourScript *ScriptInfo
+
+	// ourDelay is the CSV delay of our output.
+	ourDelay uint32
+
+	// theirScript is the to-local witness script paying to them.
+	theirScript []byte
+
+	// theirPkScript is the P2SH output script of theirScript.
+	theirPkScript []byte
+
+	// theirDelay is the CSV delay of their output.
+	theirDelay uint32
+}
+
+// newRemoteCommitScripts derives the scripts of our and their output on the
+// remote commitment with the given key ring.
+func newRemoteCommitScripts(chanState *channeldb.OpenChannel,
+	keyRing *CommitmentKeyRing) (*remoteCommitScripts, error) {
+
+	theirDelay := uint32(chanState.RemoteChanCfg.CsvDelay)
+	theirScript, err := input.CommitScriptToSelf(
+		theirDelay, keyRing.ToLocalKey, keyRing.RevocationKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	theirPkScript, err := input.ScriptHashPkScript(theirScript)
+	if err != nil {
+		return nil, err
+	}
+
+	// Since it is the remote commitment, the output going to us will be a
+	// to-remote script with our local params.
+	ourScript, ourDelay, err := CommitScriptToRemote(
+		chanState.ChanType, keyRing.ToRemoteKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteCommitScripts{
+		ourScript:     ourScript,
+		ourDelay:      ourDelay,
+		theirScript:   theirScript,
+		theirPkScript: theirPkScript,
+		theirDelay:    theirDelay,
+	}, nil
+}
+
+// findOutputIndexes returns the indexes of our and their output on the
+// commitment transaction, or OutputIndexEmpty for an output that doesn't
+// exist.
+func (s *remoteCommitScripts) findOutputIndexes(
+	commitTx *wire.MsgTx) (uint16, uint16) {
+
+	ourIndex := uint16(channeldb.OutputIndexEmpty)
+	theirIndex := uint16(channeldb.OutputIndexEmpty)
+	for i, txOut := range commitTx.TxOut {
+		switch {
+		case bytes.Equal(txOut.PkScript, s.ourScript.PkScript):
+			ourIndex = uint16(i)
+		case bytes.Equal(txOut.PkScript, s.theirPkScript):
+			theirIndex = uint16(i)
+		}
+	}
+
+	return ourIndex, theirIndex
+}
+
+// findOutputIndexesFromRemote returns the indexes of our and their output on
+// the current remote commitment of the channel, which is revoked by the given
+// revocation preimage. OutputIndexEmpty is returned for an output that
+// doesn't exist.
+func findOutputIndexesFromRemote(revocationPreimage *chainhash.Hash,
+	chanState *channeldb.OpenChannel) (uint16, uint16, error) {
+
+	commitmentSecret := secp256k1.PrivKeyFromBytes(revocationPreimage[:])
+	keyRing := DeriveCommitmentKeys(
+		commitmentSecret.PubKey(), false, chanState.ChanType,
+		&chanState.LocalChanCfg, &chanState.RemoteChanCfg,
+	)
+
+	scripts, err := newRemoteCommitScripts(chanState, keyRing)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ourIndex, theirIndex := scripts.findOutputIndexes(
+		chanState.RemoteCommitment.CommitTx,
+	)
+
+	return ourIndex, theirIndex, nil
+}
+
 // htlcIsDust determines if an HTLC output is dust or not depending on two
 // bits: if the HTLC is incoming and if the HTLC will be placed on our
 // commitment transaction, or theirs. These two pieces of information are
@@ -3179,11 +3302,12 @@ func (lc *LightningChannel) createCommitDiff(
 		switch pd.EntryType {
 		case Add:
 			htlc := &lnwire.UpdateAddHTLC{
-				ChanID:      chanID,
-				ID:          pd.HtlcIndex,
-				Amount:      pd.Amount,
-				Expiry:      pd.Timeout,
-				PaymentHash: pd.RHash,
+				ChanID:        chanID,
+				ID:            pd.HtlcIndex,
+				Amount:        pd.Amount,
+				Expiry:        pd.Timeout,
+				PaymentHash:   pd.RHash,
+				BlindingPoint: pd.BlindingPoint,
 			}
 			copy(htlc.OnionBlob[:], pd.OnionBlob)
 			logUpdate.UpdateMsg = htlc
@@ -3316,11 +3440,12 @@ func (lc *LightningChannel) getUnsignedAckedUpdates() []channeldb.LogUpdate {
 		switch pd.EntryType {
 		case Add:
 			htlc := &lnwire.UpdateAddHTLC{
-				ChanID:      chanID,
-				ID:          pd.HtlcIndex,
-				Amount:      pd.Amount,
-				Expiry:      pd.Timeout,
-				PaymentHash: pd.RHash,
+				ChanID:        chanID,
+				ID:            pd.HtlcIndex,
+				Amount:        pd.Amount,
+				Expiry:        pd.Timeout,
+				PaymentHash:   pd.RHash,
+				BlindingPoint: pd.BlindingPoint,
 			}
 			copy(htlc.OnionBlob[:], pd.OnionBlob)
 			logUpdate.UpdateMsg = htlc
@@ -4754,11 +4879,12 @@ func (lc *LightningChannel) ReceiveRevocation(revMsg *lnwire.RevokeAndAck) (
 		switch pd.EntryType {
 		case Add:
 			htlc := &lnwire.UpdateAddHTLC{
-				ChanID:      chanID,
-				ID:          pd.HtlcIndex,
-				Amount:      pd.Amount,
-				Expiry:      pd.Timeout,
-				PaymentHash: pd.RHash,
+				ChanID:        chanID,
+				ID:            pd.HtlcIndex,
+				Amount:        pd.Amount,
+				Expiry:        pd.Timeout,
+				PaymentHash:   pd.RHash,
+				BlindingPoint: pd.BlindingPoint,
 			}
 			copy(htlc.OnionBlob[:], pd.OnionBlob)
 			logUpdate.UpdateMsg = htlc
@@ -4807,12 +4933,23 @@ func (lc *LightningChannel) ReceiveRevocation(revMsg *lnwire.RevokeAndAck) (
 		source, remoteChainTail, addUpdates, settleFailUpdates,
 	)
 
+	// Locate our and their output on the remote commitment that is being
+	// revoked, as the revocation log only records their indexes.
+	ourOutputIndex, theirOutputIndex, err := findOutputIndexesFromRemote(
+		revocationHash, lc.channelState,
+	)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
 	// At this point, the revocation has been accepted, and we've rotated
 	// the current revocation key+hash for the remote party. Therefore we
 	// sync now to ensure the revocation producer state is consistent with
 	// the current commitment height and also to advance the on-disk
 	// commitment chain.
-	err = lc.channelState.AdvanceCommitChainTail(fwdPkg, localPeerUpdates)
+	err = lc.channelState.AdvanceCommitChainTail(
+		fwdPkg, localPeerUpdates, ourOutputIndex, theirOutputIndex,
+	)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
@@ -4924,6 +5061,7 @@ func (lc *LightningChannel) AddHTLC(htlc *lnwire.UpdateAddHTLC,
 		LogIndex:       lc.localUpdateLog.logIndex,
 		HtlcIndex:      lc.localUpdateLog.htlcCounter,
 		OnionBlob:      htlc.OnionBlob[:],
+		BlindingPoint:  htlc.BlindingPoint,
 		OpenCircuitKey: openKey,
 	}
 
@@ -4971,13 +5109,14 @@ func (lc *LightningChannel) ReceiveHTLC(htlc *lnwire.UpdateAddHTLC) (uint64, err
 	}
 
 	pd := &PaymentDescriptor{
-		EntryType: Add,
-		RHash:     PaymentHash(htlc.PaymentHash),
-		Timeout:   htlc.Expiry,
-		Amount:    htlc.Amount,
-		LogIndex:  lc.remoteUpdateLog.logIndex,
-		HtlcIndex: lc.remoteUpdateLog.htlcCounter,
-		OnionBlob: htlc.OnionBlob[:],
+		EntryType:     Add,
+		RHash:         PaymentHash(htlc.PaymentHash),
+		Timeout:       htlc.Expiry,
+		Amount:        htlc.Amount,
+		LogIndex:      lc.remoteUpdateLog.logIndex,
+		HtlcIndex:     lc.remoteUpdateLog.htlcCounter,
+		OnionBlob:     htlc.OnionBlob[:],
+		BlindingPoint: htlc.BlindingPoint,
 	}
 
 	localACKedIndex := lc.remoteCommitChain.tail().ourMessageIndex
@@ -6834,6 +6973,73 @@ func (lc *LightningChannel) CommitFeeRate() chainfee.AtomPerKByte {
 	return chainfee.AtomPerKByte(lc.channelState.LocalCommitment.FeePerKB)
 }
 
+// dustParams returns the dust limit and the fee rate of our commitment, or of
+// the remote commitment if remote is true.
+func (lc *LightningChannel) dustParams(remote bool) (dcrutil.Amount,
+	chainfee.AtomPerKByte) {
+
+	if remote {
+		return lc.channelState.RemoteChanCfg.DustLimit,
+			chainfee.AtomPerKByte(
+				lc.channelState.RemoteCommitment.FeePerKB,
+			)
+	}
+
+	return lc.channelState.LocalChanCfg.DustLimit,
+		chainfee.AtomPerKByte(lc.channelState.LocalCommitment.FeePerKB)
+}
+
+// HtlcIsDust returns true if an HTLC of the given amount would be trimmed from
+// our commitment, or from the remote commitment if remote is true, at its
+// current fee rate. The incoming flag indicates whether the HTLC is offered
+// by the remote party.
+func (lc *LightningChannel) HtlcIsDust(incoming, remote bool,
+	amt lnwire.MilliAtom) bool {
+
+	lc.RLock()
+	defer lc.RUnlock()
+
+	dustLimit, feeRate := lc.dustParams(remote)
+
+	return htlcIsDust(
+		lc.channelState.ChanType, incoming, !remote, feeRate,
+		amt.ToAtoms(), dustLimit,
+	)
+}
+
+// GetDustSum returns the total value of the HTLCs within the update logs that
+// are dust on our commitment, or on the remote commitment if remote is true,
+// at its current fee rate. As dust HTLCs are trimmed from the commitment,
+// this value is lost to fees should the commitment be broadcast.
+func (lc *LightningChannel) GetDustSum(remote bool) lnwire.MilliAtom {
+	lc.RLock()
+	defer lc.RUnlock()
+
+	dustLimit, feeRate := lc.dustParams(remote)
+	chanType := lc.channelState.ChanType
+
+	var dustSum lnwire.MilliAtom
+	sumLog := func(log *updateLog, incoming bool) {
+		for e := log.Front(); e != nil; e = e.Next() {
+			pd := e.Value.(*PaymentDescriptor)
+			if pd.EntryType != Add {
+				continue
+			}
+
+			if htlcIsDust(
+				chanType, incoming, !remote, feeRate,
+				pd.Amount.ToAtoms(), dustLimit,
+			) {
+				dustSum += pd.Amount
+			}
+		}
+	}
+	sumLog(lc.localUpdateLog, false)
+	sumLog(lc.remoteUpdateLog, true)
+
+	return dustSum
+}
+
 // IsPending returns true if the channel's funding transaction has been fully
 // confirmed, and false otherwise.
 func (lc *LightningChannel) IsPending() bool {
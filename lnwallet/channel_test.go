@@ -9454,3 +9454,71 @@ func TestChannelLocalUnsignedUpdatesFailure(t *testing.T) {
 	err = newAliceChannel.ReceiveNewCommitment(bobSig, bobHtlcSigs)
 	require.NoError(t, err)
 }
+
+// TestChannelGetDustSum asserts that the dust sum of each commitment covers
+// the HTLCs of both parties that are dust on that commitment.
+func TestChannelGetDustSum(t *testing.T) {
+	t.Parallel()
+
+	chanType := channeldb.SingleFunderTweaklessBit
+	aliceChannel, bobChannel, cleanUp, err := CreateTestChannels(chanType)
+	require.NoError(t, err)
+	defer cleanUp()
+
+	var (
+		chanState = aliceChannel.State()
+		feeRate   = chainfee.AtomPerKByte(
+			chanState.LocalCommitment.FeePerKB,
+		)
+		timeoutFee = HtlcTimeoutFee(chanType, feeRate)
+	)
+
+	// The first HTLC is dust on both commitments, while the second is
+	// above Alice's dust limit but below Bob's.
+	dustAmt := lnwire.NewMAtomsFromAtoms(1000)
+	midAmt := lnwire.NewMAtomsFromAtoms(
+		chanState.LocalChanCfg.DustLimit + timeoutFee,
+	)
+	require.True(t, aliceChannel.HtlcIsDust(false, false, dustAmt))
+	require.True(t, aliceChannel.HtlcIsDust(false, true, dustAmt))
+	require.False(t, aliceChannel.HtlcIsDust(false, false, midAmt))
+	require.True(t, aliceChannel.HtlcIsDust(false, true, midAmt))
+
+	require.Zero(t, aliceChannel.GetDustSum(false))
+	require.Zero(t, aliceChannel.GetDustSum(true))
+
+	// Alice offers both HTLCs, and Bob offers another dust HTLC.
+	for i, amt := range []lnwire.MilliAtom{dustAmt, midAmt} {
+		htlc, _ := createHTLC(i, amt)
+		_, err := aliceChannel.AddHTLC(htlc, nil)
+		require.NoError(t, err)
+		_, err = bobChannel.ReceiveHTLC(htlc)
+		require.NoError(t, err)
+	}
+
+	htlc, _ := createHTLC(0, dustAmt)
+	_, err = bobChannel.AddHTLC(htlc, nil)
+	require.NoError(t, err)
+	_, err = aliceChannel.ReceiveHTLC(htlc)
+	require.NoError(t, err)
+
+	// The dust HTLCs count towards both commitments, while the second HTLC
+	// only counts towards Bob's commitment. The sums are the same before
+	// and after the HTLCs are locked in.
+	assertDustSums := func() {
+		t.Helper()
+
+		aliceSum := 2 * dustAmt
+		bobSum := 2*dustAmt + midAmt
+		require.Equal(t, aliceSum, aliceChannel.GetDustSum(false))
+		require.Equal(t, bobSum, aliceChannel.GetDustSum(true))
+		require.Equal(t, bobSum, bobChannel.GetDustSum(false))
+		require.Equal(t, aliceSum, bobChannel.GetDustSum(true))
+	}
+	assertDustSums()
+
+	err = ForceStateTransition(aliceChannel, bobChannel)
+	require.NoError(t, err)
+
+	assertDustSums()
+}
@@ -0,0 +1,79 @@
+package lnwire
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// CustomTypeStart is the start of the custom type range for peer messages as
+// defined in BOLT 01.
+const CustomTypeStart MessageType = 32768
+
+// Custom represents an application-defined wire message whose type is within
+// the custom type range. Its payload is opaque to the Lightning protocol.
+type Custom struct {
+	// Type is the message type, which must be within the custom type
+	// range.
+	Type MessageType
+
+	// Data is the opaque payload of the message.
+	Data []byte
+}
+
+// NewCustom returns a new Custom message with the given type and payload. An
+// error is returned if the type isn't within the custom type range.
+func NewCustom(msgType MessageType, data []byte) (*Custom, error) {
+	if msgType < CustomTypeStart {
+		return nil, fmt.Errorf("msg type %d not in custom range "+
+			"starting at %d", msgType, CustomTypeStart)
+	}
+
+	return &Custom{
+		Type: msgType,
+		Data: data,
+	}, nil
+}
+
+// A compile time check to ensure Custom implements the lnwire.Message
+// interface.
+var _ Message = (*Custom)(nil)
+
+// Decode deserializes a serialized Custom message stored in the passed
+// io.Reader observing the specified protocol version. The whole remainder of
+// the reader is read as the payload.
+//
+// This is part of the lnwire.Message interface.
+func (c *Custom) Decode(r io.Reader, pver uint32) error {
+	var err error
+	c.Data, err = ioutil.ReadAll(r)
+
+	return err
+}
+
+// Encode serializes the target Custom message into the passed io.Writer
+// observing the protocol version specified.
+//
+// This is part of the lnwire.Message interface.
+func (c *Custom) Encode(w io.Writer, pver uint32) error {
+	_, err := w.Write(c.Data)
+
+	return err
+}
+
+// MsgType returns the integer uniquely identifying this message type on the
+// wire.
+//
+// This is part of the lnwire.Message interface.
+func (c *Custom) MsgType() MessageType {
+	return c.Type
+}
+
+// MaxPayloadLength returns the maximum allowed payload size for a Custom
+// complete message observing the specified protocol version. The payload may
+// take up the whole message, except for the two bytes of the message type.
+//
+// This is part of the lnwire.Message interface.
+func (c *Custom) MaxPayloadLength(uint32) uint32 {
+	return MaxMessagePayload - 2
+}
@@ -119,6 +119,27 @@ const (
 	// outputs.
 	AnchorsOptional FeatureBit = 21
 
+	// AMPRequired is a required feature bit that signals that the receiver
+	// of a payment requires atomic multi-path payments, where the
+	// preimages of all HTLCs are derived from a sender-generated seed.
+	AMPRequired FeatureBit = 30
+
+	// AMPOptional is an optional feature bit that signals that the receiver
+	// of a payment supports atomic multi-path payments, where the
+	// preimages of all HTLCs are derived from a sender-generated seed.
+	AMPOptional FeatureBit = 31
+
+	// TrampolineRoutingRequired is a required feature bit that signals
+	// that the node requires its peers to support trampoline routing,
+	// where the node finds the route to the destination of a payment on
+	// behalf of the sender.
+	TrampolineRoutingRequired FeatureBit = 56
+
+	// TrampolineRoutingOptional is an optional feature bit that signals
+	// that the node is able to act as a trampoline, finding the route to
+	// the destination of a payment on behalf of the sender.
+	TrampolineRoutingOptional FeatureBit = 57
+
 	// maxAllowedSize is a maximum allowed size of feature vector.
 	//
 	// NOTE: Within the protocol, the maximum allowed message size is 65535
@@ -160,6 +181,10 @@ var Features = map[FeatureBit]string{
 	AnchorsOptional:               "anchor-commitments",
 	WumboChannelsRequired:         "wumbo-channels",
 	WumboChannelsOptional:         "wumbo-channels",
+	AMPRequired:                   "amp",
+	AMPOptional:                   "amp",
+	TrampolineRoutingRequired:     "trampoline-routing",
+	TrampolineRoutingOptional:     "trampoline-routing",
 }
 
 // RawFeatureVector represents a set of feature bits as defined in BOLT-09.  A
@@ -244,7 +244,9 @@ func TestMaxOutPointIndex(t *testing.T) {
 func TestEmptyMessageUnknownType(t *testing.T) {
 	t.Parallel()
 
-	fakeType := MessageType(math.MaxUint16)
+	// Types in the custom range are decoded as custom messages, so the
+	// unknown type is taken from below that range.
+	fakeType := CustomTypeStart - 1
 	if _, err := makeEmptyMessage(fakeType); err == nil {
 		t.Fatalf("should not be able to make an empty message of an " +
 			"unknown type")
@@ -305,6 +307,25 @@ func TestLightningWireProtocol(t *testing.T) {
 	// are too complex for the testing/quick package to automatically
 	// generate.
 	customTypeGen := map[MessageType]func([]reflect.Value, *rand.Rand){
+		CustomTypeStart: func(v []reflect.Value, r *rand.Rand) {
+			numTypes := int32(math.MaxUint16 - CustomTypeStart)
+			msgType := CustomTypeStart + MessageType(
+				r.Int31n(numTypes),
+			)
+			data := make([]byte, r.Int31n(1000))
+			if _, err := r.Read(data); err != nil {
+				t.Fatalf("unable to generate data: %v", err)
+				return
+			}
+
+			req, err := NewCustom(msgType, data)
+			if err != nil {
+				t.Fatalf("unable to create custom msg: %v", err)
+				return
+			}
+
+			v[0] = reflect.ValueOf(*req)
+		},
 		MsgInit: func(v []reflect.Value, r *rand.Rand) {
 			req := NewInitMessage(
 				randRawFeatureVector(r),
@@ -521,6 +542,38 @@ func TestLightningWireProtocol(t *testing.T) {
 
 			v[0] = reflect.ValueOf(req)
 		},
+		MsgUpdateAddHTLC: func(v []reflect.Value, r *rand.Rand) {
+			req := UpdateAddHTLC{
+				ID:     r.Uint64(),
+				Amount: MilliAtom(r.Int63()),
+				Expiry: r.Uint32(),
+			}
+			if _, err := r.Read(req.ChanID[:]); err != nil {
+				t.Fatalf("unable to generate chan id: %v", err)
+				return
+			}
+			if _, err := r.Read(req.PaymentHash[:]); err != nil {
+				t.Fatalf("unable to generate payment hash: %v", err)
+				return
+			}
+			if _, err := r.Read(req.OnionBlob[:]); err != nil {
+				t.Fatalf("unable to generate onion blob: %v", err)
+				return
+			}
+
+			// Only add a blinding point to half of the messages, so
+			// that both encodings are covered.
+			if r.Intn(2) == 0 {
+				var err error
+				req.BlindingPoint, err = randPubKey()
+				if err != nil {
+					t.Fatalf("unable to generate key: %v", err)
+					return
+				}
+			}
+
+			v[0] = reflect.ValueOf(req)
+		},
 		MsgCommitSig: func(v []reflect.Value, r *rand.Rand) {
 			req := NewCommitSig()
 			if _, err := r.Read(req.ChanID[:]); err != nil {
@@ -1023,6 +1076,12 @@ func TestLightningWireProtocol(t *testing.T) {
 				return mainScenario(&m)
 			},
 		},
+		{
+			msgType: CustomTypeStart,
+			scenario: func(m Custom) bool {
+				return mainScenario(&m)
+			},
+		},
 	}
 	for _, test := range tests {
 		var config *quick.Config
@@ -116,6 +116,9 @@ func (t MessageType) String() string {
 	case MsgGossipTimestampRange:
 		return "GossipTimestampRange"
 	default:
+		if t >= CustomTypeStart {
+			return "Custom"
+		}
 		return "<unknown>"
 	}
 }
@@ -217,6 +220,13 @@ func makeEmptyMessage(msgType MessageType) (Message, error) {
 	case MsgGossipTimestampRange:
 		msg = &GossipTimestampRange{}
 	default:
+		// Messages in the custom type range are decoded as opaque
+		// Custom messages.
+		if msgType >= CustomTypeStart {
+			msg = &Custom{Type: msgType}
+			break
+		}
+
 		return nil, &UnknownMessage{msgType}
 	}
 
@@ -1,7 +1,12 @@
 package lnwire
 
 import (
+	"bytes"
 	"io"
+	"io/ioutil"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/tlv"
 )
 
 // OnionPacketSize is the size of the serialized Sphinx onion packet included
@@ -10,6 +15,10 @@ import (
 // of per-hop data, and a 32-byte HMAC over the entire packet.
 const OnionPacketSize = 1366
 
+// blindingPointTLVType is the type of the blinding point within the TLV
+// extension of an UpdateAddHTLC message.
+const blindingPointTLVType tlv.Type = 0
+
 // UpdateAddHTLC is the message sent by Alice to Bob when she wishes to add an
 // HTLC to his remote commitment transaction. In addition to information
 // detailing the value, the ID, expiry, and the onion blob is also included
@@ -52,6 +61,13 @@ type UpdateAddHTLC struct {
 	// should strip off a layer of encryption, exposing the next hop to be
 	// used in the subsequent UpdateAddHTLC message.
 	OnionBlob [OnionPacketSize]byte
+
+	// BlindingPoint is the ephemeral key that the receiving node uses to
+	// process an HTLC that is part of a blinded route. It is only set when
+	// the receiving node is part of a blinded route but isn't its
+	// introduction node. The blinding point is encoded in the TLV
+	// extension of the message.
+	BlindingPoint *secp256k1.PublicKey
 }
 
 // NewUpdateAddHTLC returns a new empty UpdateAddHTLC message.
@@ -68,7 +84,7 @@ var _ Message = (*UpdateAddHTLC)(nil)
 //
 // This is part of the lnwire.Message interface.
 func (c *UpdateAddHTLC) Decode(r io.Reader, pver uint32) error {
-	return ReadElements(r,
+	err := ReadElements(r,
 		&c.ChanID,
 		&c.ID,
 		&c.Amount,
@@ -76,6 +92,39 @@ func (c *UpdateAddHTLC) Decode(r io.Reader, pver uint32) error {
 		&c.Expiry,
 		c.OnionBlob[:],
 	)
+	if err != nil {
+		return err
+	}
+
+	// Any remaining data is the TLV extension of the message.
+	extraData, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(extraData) == 0 {
+		return nil
+	}
+
+	var blindingPoint *secp256k1.PublicKey
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(blindingPointTLVType, &blindingPoint),
+	)
+	if err != nil {
+		return err
+	}
+
+	parsedTypes, err := tlvStream.DecodeWithParsedTypes(
+		bytes.NewReader(extraData),
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := parsedTypes[blindingPointTLVType]; ok {
+		c.BlindingPoint = blindingPoint
+	}
+
+	return nil
 }
 
 // Encode serializes the target UpdateAddHTLC into the passed io.Writer observing
@@ -83,7 +132,7 @@ func (c *UpdateAddHTLC) Decode(r io.Reader, pver uint32) error {
 //
 // This is part of the lnwire.Message interface.
 func (c *UpdateAddHTLC) Encode(w io.Writer, pver uint32) error {
-	return WriteElements(w,
+	err := WriteElements(w,
 		c.ChanID,
 		c.ID,
 		c.Amount,
@@ -91,6 +140,24 @@ func (c *UpdateAddHTLC) Encode(w io.Writer, pver uint32) error {
 		c.Expiry,
 		c.OnionBlob[:],
 	)
+	if err != nil {
+		return err
+	}
+
+	// The TLV extension is only written if there's a blinding point, so
+	// that the encoding of regular HTLCs remains unchanged.
+	if c.BlindingPoint == nil {
+		return nil
+	}
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(blindingPointTLVType, &c.BlindingPoint),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tlvStream.Encode(w)
 }
 
 // MsgType returns the integer uniquely identifying this message type on the
@@ -106,8 +173,9 @@ func (c *UpdateAddHTLC) MsgType() MessageType {
 //
 // This is part of the lnwire.Message interface.
 func (c *UpdateAddHTLC) MaxPayloadLength(uint32) uint32 {
-	// 1450
-	return 32 + 8 + 4 + 8 + 32 + 1366
+	// The message may carry a TLV extension, so we allow it to use the
+	// maximum message payload.
+	return MaxMessagePayload
 }
 
 // TargetChanID returns the channel id of the link for which this message is
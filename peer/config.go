@@ -147,6 +147,11 @@ type Config struct {
 	// invoice-related logic.
 	Invoices *invoices.InvoiceRegistry
 
+	// Trampoline is passed to the ChannelLink on creation and forwards the
+	// payments of htlcs that carry a trampoline onion. If nil, such htlcs
+	// are rejected.
+	Trampoline htlcswitch.TrampolineForwarder
+
 	// ChannelNotifier is used by the link to notify other sub-systems about
 	// channel-related events and by the Brontide to subscribe to
 	// ActiveLinkEvents.
@@ -204,6 +209,10 @@ type Config struct {
 	// to the funding manager or not.
 	IsPendingChannel func([32]byte, *secp256k1.PublicKey) bool
 
+	// HandleCustomMessage is used to hand off a message in the custom type
+	// range, along with the public key of the peer that sent it.
+	HandleCustomMessage func([33]byte, *lnwire.Custom) error
+
 	// Hodl is used when creating ChannelLinks to specify HodlFlags as
 	// breakpoints in dev builds.
 	Hodl *hodl.Config
@@ -222,6 +231,11 @@ type Config struct {
 	// commitment fee. This only applies for the initiator of the channel.
 	MaxChannelFeeAllocation float64
 
+	// MaxDustExposure is used when creating ChannelLinks and is the
+	// maximum total value of the dust htlcs allowed on each commitment of
+	// a channel.
+	MaxDustExposure lnwire.MilliAtom
+
 	// ServerPubKey is the serialized, compressed public key of our lnd node.
 	// It is used to determine which policy (channel edge) to pass to the
 	// ChannelLink.
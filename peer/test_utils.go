@@ -693,7 +693,11 @@ func createTestPeer(notifier chainntnfs.ChainNotifier,
 		Switch:      htlcSwitch,
 
 		ChanActiveTimeout: chanActiveTimeout,
-		InterceptSwitch:   htlcswitch.NewInterceptableSwitch(htlcSwitch),
+		InterceptSwitch: htlcswitch.NewInterceptableSwitch(
+			&htlcswitch.InterceptableSwitchConfig{
+				Switch: htlcSwitch,
+			},
+		),
 
 		ChannelDB:      dbAlice,
 		FeeEstimator:   estimator,
@@ -0,0 +1,272 @@
+package record
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+const (
+	// blindedShortChanIDType is the type of the short channel id of the
+	// next hop within the encrypted data of a blinded hop.
+	blindedShortChanIDType tlv.Type = 2
+
+	// blindedPathIDType is the type of the path id within the encrypted
+	// data of the final hop of a blinded route.
+	blindedPathIDType tlv.Type = 6
+
+	// blindedNextBlindingOverrideType is the type of the blinding point
+	// that overrides the one derived for the next hop.
+	blindedNextBlindingOverrideType tlv.Type = 8
+
+	// blindedPaymentRelayType is the type of the relay parameters of a
+	// blinded hop.
+	blindedPaymentRelayType tlv.Type = 10
+
+	// blindedPaymentConstraintsType is the type of the constraints that a
+	// blinded hop enforces on the HTLCs it receives.
+	blindedPaymentConstraintsType tlv.Type = 12
+)
+
+// PaymentRelayInfo describes the relay policy of a hop in a blinded route.
+type PaymentRelayInfo struct {
+	// CltvExpiryDelta is the expiry delta applied by the hop.
+	CltvExpiryDelta uint16
+
+	// FeeRate is the proportional fee charged by the hop, in parts per
+	// million.
+	FeeRate uint32
+
+	// BaseFee is the base fee charged by the hop.
+	BaseFee lnwire.MilliAtom
+}
+
+// PaymentConstraints describes the restrictions that a hop in a blinded route
+// places on the HTLCs it receives.
+type PaymentConstraints struct {
+	// MaxCltvExpiry is the maximum expiry that an HTLC may have.
+	MaxCltvExpiry uint32
+
+	// HtlcMinimumMAtoms is the minimum amount that an HTLC must carry.
+	HtlcMinimumMAtoms lnwire.MilliAtom
+}
+
+// BlindedRouteData is the data that the creator of a blinded route encrypts
+// for each of its hops.
+type BlindedRouteData struct {
+	// ShortChannelID is the channel to forward the HTLC over. It is only
+	// set for intermediate hops.
+	ShortChannelID *lnwire.ShortChannelID
+
+	// PathID is set by the recipient so that it is able to recognize the
+	// blinded routes that it created. It is only set for the final hop.
+	PathID []byte
+
+	// NextBlindingOverride, if set, replaces the blinding point that
+	// would otherwise be derived for the next hop.
+	NextBlindingOverride *secp256k1.PublicKey
+
+	// RelayInfo holds the relay parameters of an intermediate hop.
+	RelayInfo *PaymentRelayInfo
+
+	// Constraints holds the restrictions enforced by the hop.
+	Constraints *PaymentConstraints
+}
+
+// EncodeBlindedRouteData serializes the data of a blinded hop.
+func EncodeBlindedRouteData(data *BlindedRouteData) ([]byte, error) {
+	var records []tlv.Record
+
+	if data.ShortChannelID != nil {
+		scid := data.ShortChannelID.ToUint64()
+		records = append(records, tlv.MakePrimitiveRecord(
+			blindedShortChanIDType, &scid,
+		))
+	}
+
+	if data.PathID != nil {
+		records = append(records, tlv.MakePrimitiveRecord(
+			blindedPathIDType, &data.PathID,
+		))
+	}
+
+	if data.NextBlindingOverride != nil {
+		records = append(records, tlv.MakePrimitiveRecord(
+			blindedNextBlindingOverrideType,
+			&data.NextBlindingOverride,
+		))
+	}
+
+	if data.RelayInfo != nil {
+		records = append(records, tlv.MakeDynamicRecord(
+			blindedPaymentRelayType, data.RelayInfo,
+			data.RelayInfo.size, encodePaymentRelay,
+			decodePaymentRelay,
+		))
+	}
+
+	if data.Constraints != nil {
+		records = append(records, tlv.MakeDynamicRecord(
+			blindedPaymentConstraintsType, data.Constraints,
+			data.Constraints.size, encodePaymentConstraints,
+			decodePaymentConstraints,
+		))
+	}
+
+	stream, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := stream.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// DecodeBlindedRouteData deserializes the data of a blinded hop.
+func DecodeBlindedRouteData(r io.Reader) (*BlindedRouteData, error) {
+	var (
+		data        BlindedRouteData
+		scid        uint64
+		relayInfo   PaymentRelayInfo
+		constraints PaymentConstraints
+	)
+
+	stream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(blindedShortChanIDType, &scid),
+		tlv.MakePrimitiveRecord(blindedPathIDType, &data.PathID),
+		tlv.MakePrimitiveRecord(
+			blindedNextBlindingOverrideType,
+			&data.NextBlindingOverride,
+		),
+		tlv.MakeDynamicRecord(
+			blindedPaymentRelayType, &relayInfo, relayInfo.size,
+			encodePaymentRelay, decodePaymentRelay,
+		),
+		tlv.MakeDynamicRecord(
+			blindedPaymentConstraintsType, &constraints,
+			constraints.size, encodePaymentConstraints,
+			decodePaymentConstraints,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := parsedTypes[blindedShortChanIDType]; ok {
+		shortChanID := lnwire.NewShortChanIDFromInt(scid)
+		data.ShortChannelID = &shortChanID
+	}
+
+	if _, ok := parsedTypes[blindedPaymentRelayType]; ok {
+		data.RelayInfo = &relayInfo
+	}
+
+	if _, ok := parsedTypes[blindedPaymentConstraintsType]; ok {
+		data.Constraints = &constraints
+	}
+
+	return &data, nil
+}
+
+// size returns the serialized size of the relay info.
+func (p *PaymentRelayInfo) size() uint64 {
+	return 2 + 4 + tlv.SizeTUint32(uint32(p.BaseFee))
+}
+
+// encodePaymentRelay writes the relay info to the provided io.Writer.
+func encodePaymentRelay(w io.Writer, val interface{}, buf *[8]byte) error {
+	if v, ok := val.(*PaymentRelayInfo); ok {
+		if err := tlv.EUint16T(w, v.CltvExpiryDelta, buf); err != nil {
+			return err
+		}
+
+		if err := tlv.EUint32T(w, v.FeeRate, buf); err != nil {
+			return err
+		}
+
+		return tlv.ETUint32T(w, uint32(v.BaseFee), buf)
+	}
+
+	return tlv.NewTypeForEncodingErr(val, "PaymentRelayInfo")
+}
+
+// decodePaymentRelay reads the relay info from the provided io.Reader.
+func decodePaymentRelay(r io.Reader, val interface{}, buf *[8]byte,
+	l uint64) error {
+
+	if v, ok := val.(*PaymentRelayInfo); ok && l >= 6 && l <= 10 {
+		err := tlv.DUint16(r, &v.CltvExpiryDelta, buf, 2)
+		if err != nil {
+			return err
+		}
+
+		if err := tlv.DUint32(r, &v.FeeRate, buf, 4); err != nil {
+			return err
+		}
+
+		var baseFee uint32
+		if err := tlv.DTUint32(r, &baseFee, buf, l-6); err != nil {
+			return err
+		}
+		v.BaseFee = lnwire.MilliAtom(baseFee)
+
+		return nil
+	}
+
+	return tlv.NewTypeForDecodingErr(val, "PaymentRelayInfo", l, 10)
+}
+
+// size returns the serialized size of the payment constraints.
+func (p *PaymentConstraints) size() uint64 {
+	return 4 + tlv.SizeTUint64(uint64(p.HtlcMinimumMAtoms))
+}
+
+// encodePaymentConstraints writes the payment constraints to the provided
+// io.Writer.
+func encodePaymentConstraints(w io.Writer, val interface{},
+	buf *[8]byte) error {
+
+	if v, ok := val.(*PaymentConstraints); ok {
+		if err := tlv.EUint32T(w, v.MaxCltvExpiry, buf); err != nil {
+			return err
+		}
+
+		return tlv.ETUint64T(w, uint64(v.HtlcMinimumMAtoms), buf)
+	}
+
+	return tlv.NewTypeForEncodingErr(val, "PaymentConstraints")
+}
+
+// decodePaymentConstraints reads the payment constraints from the provided
+// io.Reader.
+func decodePaymentConstraints(r io.Reader, val interface{}, buf *[8]byte,
+	l uint64) error {
+
+	if v, ok := val.(*PaymentConstraints); ok && l >= 4 && l <= 12 {
+		if err := tlv.DUint32(r, &v.MaxCltvExpiry, buf, 4); err != nil {
+			return err
+		}
+
+		var htlcMin uint64
+		if err := tlv.DTUint64(r, &htlcMin, buf, l-4); err != nil {
+			return err
+		}
+		v.HtlcMinimumMAtoms = lnwire.MilliAtom(htlcMin)
+
+		return nil
+	}
+
+	return tlv.NewTypeForDecodingErr(val, "PaymentConstraints", l, 12)
+}
@@ -0,0 +1,74 @@
+package record_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlindedRouteDataEncoding tests the encoding and decoding of the data
+// encrypted to the hops of a blinded route.
+func TestBlindedRouteDataEncoding(t *testing.T) {
+	t.Parallel()
+
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	scid := lnwire.NewShortChanIDFromInt(1234)
+
+	tests := []struct {
+		name string
+		data *record.BlindedRouteData
+	}{
+		{
+			name: "empty",
+			data: &record.BlindedRouteData{},
+		},
+		{
+			name: "intermediate hop",
+			data: &record.BlindedRouteData{
+				ShortChannelID:       &scid,
+				NextBlindingOverride: key.PubKey(),
+				RelayInfo: &record.PaymentRelayInfo{
+					CltvExpiryDelta: 40,
+					FeeRate:         1000,
+					BaseFee:         1,
+				},
+				Constraints: &record.PaymentConstraints{
+					MaxCltvExpiry:     500_000,
+					HtlcMinimumMAtoms: 1000,
+				},
+			},
+		},
+		{
+			name: "final hop",
+			data: &record.BlindedRouteData{
+				PathID: bytes.Repeat([]byte{1}, 32),
+				Constraints: &record.PaymentConstraints{
+					MaxCltvExpiry: 500_000,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := record.EncodeBlindedRouteData(test.data)
+			require.NoError(t, err)
+
+			data, err := record.DecodeBlindedRouteData(
+				bytes.NewReader(b),
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.data, data)
+		})
+	}
+}
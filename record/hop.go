@@ -1,6 +1,7 @@
 package record
 
 import (
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrlnd/tlv"
 )
 
@@ -16,6 +17,30 @@ const (
 	// NextHopOnionType is the type used in the onion to reference the ID
 	// of the next hop.
 	NextHopOnionType tlv.Type = 6
+
+	// BlindingPointOnionType is the type used in the onion to reference
+	// the blinding point handed to the introduction node of a blinded
+	// route.
+	BlindingPointOnionType tlv.Type = 12
+
+	// TrampolineOnionType is the type used in the onion to reference the
+	// trampoline onion packet handed to a trampoline node by the sender.
+	TrampolineOnionType tlv.Type = 14
+
+	// OutgoingNodeIDOnionType is the type used in the payload of a
+	// trampoline onion to reference the node that the trampoline node must
+	// forward the payment to.
+	OutgoingNodeIDOnionType tlv.Type = 16
+
+	// TotalAmtMAtomsBlindedType is the type used in the onion to reference
+	// the total amount of a payment to a blinded route.
+	TotalAmtMAtomsBlindedType tlv.Type = 18
+
+	// EncryptedDataOnionType is the type used in the onion to reference
+	// the encrypted data of a hop in a blinded route. Note that the type
+	// differs from the one in the route blinding proposal, as type 10 is
+	// already used for AMP records.
+	EncryptedDataOnionType tlv.Type = 20
 )
 
 // NewAmtToFwdRecord creates a tlv.Record that encodes the amount_to_forward
@@ -45,3 +70,38 @@ func NewLockTimeRecord(lockTime *uint32) tlv.Record {
 func NewNextHopIDRecord(cid *uint64) tlv.Record {
 	return tlv.MakePrimitiveRecord(NextHopOnionType, cid)
 }
+
+// NewEncryptedDataRecord creates a tlv.Record that encodes the
+// encrypted_recipient_data (type 20) for an onion payload.
+func NewEncryptedDataRecord(data *[]byte) tlv.Record {
+	return tlv.MakePrimitiveRecord(EncryptedDataOnionType, data)
+}
+
+// NewBlindingPointRecord creates a tlv.Record that encodes the
+// current_blinding_point (type 12) for an onion payload.
+func NewBlindingPointRecord(point **secp256k1.PublicKey) tlv.Record {
+	return tlv.MakePrimitiveRecord(BlindingPointOnionType, point)
+}
+
+// NewTrampolineOnionRecord creates a tlv.Record that encodes the
+// trampoline_onion_packet (type 14) for an onion payload.
+func NewTrampolineOnionRecord(packet *[]byte) tlv.Record {
+	return tlv.MakePrimitiveRecord(TrampolineOnionType, packet)
+}
+
+// NewOutgoingNodeIDRecord creates a tlv.Record that encodes the
+// outgoing_node_id (type 16) for the payload of a trampoline onion.
+func NewOutgoingNodeIDRecord(nodeID **secp256k1.PublicKey) tlv.Record {
+	return tlv.MakePrimitiveRecord(OutgoingNodeIDOnionType, nodeID)
+}
+
+// NewTotalAmtMAtomsBlindedRecord creates a tlv.Record that encodes the
+// total_amount_msat (type 18) for an onion payload.
+func NewTotalAmtMAtomsBlindedRecord(amt *uint64) tlv.Record {
+	return tlv.MakeDynamicRecord(
+		TotalAmtMAtomsBlindedType, amt, func() uint64 {
+			return tlv.SizeTUint64(*amt)
+		},
+		tlv.ETUint64, tlv.DTUint64,
+	)
+}
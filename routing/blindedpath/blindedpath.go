@@ -0,0 +1,242 @@
+package blindedpath
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	sphinx "github.com/decred/lightning-onion/v3"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var (
+	// ErrNoBlindedHops is returned when attempting to build a blinded path
+	// without any hops.
+	ErrNoBlindedHops = errors.New("blinded path must contain at least " +
+		"one hop")
+
+	// blindedNodeIDKey is the HMAC key used to derive the blinding factor
+	// of the node IDs in a blinded path.
+	blindedNodeIDKey = []byte("blinded_node_id")
+
+	// rhoKey is the HMAC key used to derive the key that encrypts the data
+	// for each hop of a blinded path.
+	rhoKey = []byte("rho")
+)
+
+// HopInfo holds the public key of a node in a route that is to be blinded,
+// along with the plain text data that is to be encrypted for that node.
+type HopInfo struct {
+	// NodePub is the real public key of the node.
+	NodePub *secp256k1.PublicKey
+
+	// PlainText is the data that should be encrypted to the node.
+	PlainText []byte
+}
+
+// BlindedHop is a single hop of a blinded path.
+type BlindedHop struct {
+	// BlindedNodePub is the blinded public key of the node. Senders
+	// encrypt the onion payload of the hop to this key.
+	BlindedNodePub *secp256k1.PublicKey
+
+	// CipherText is the encrypted data that the sender should include in
+	// the onion payload of the hop.
+	CipherText []byte
+}
+
+// BlindedPath is a route whose node identities are hidden from the sender,
+// with the exception of its introduction point.
+type BlindedPath struct {
+	// IntroductionPoint is the real public key of the first node of the
+	// path. The sender routes to this node in the clear.
+	IntroductionPoint *secp256k1.PublicKey
+
+	// BlindingPoint is the ephemeral public key that the introduction point
+	// uses to decrypt its data and to derive the blinding point of the
+	// next hop.
+	BlindingPoint *secp256k1.PublicKey
+
+	// BlindedHops is the list of hops of the path, starting with the
+	// introduction point. Note that the blinded public key of the
+	// introduction point is not used by senders, as the introduction point
+	// is addressed directly.
+	BlindedHops []*BlindedHop
+}
+
+// BuildBlindedPath creates a blinded path to the last node in the set of hops
+// given, encrypting the plain text of each hop to the respective node. The
+// session key is used as the ephemeral blinding key of the introduction point.
+func BuildBlindedPath(sessionKey *secp256k1.PrivateKey,
+	hops []*HopInfo) (*BlindedPath, error) {
+
+	if len(hops) == 0 {
+		return nil, ErrNoBlindedHops
+	}
+
+	path := &BlindedPath{
+		IntroductionPoint: hops[0].NodePub,
+		BlindingPoint:     sessionKey.PubKey(),
+		BlindedHops:       make([]*BlindedHop, len(hops)),
+	}
+
+	ephemeralKey := sessionKey
+	for i, hop := range hops {
+		sessionECDH := &sphinx.PrivKeyECDH{PrivKey: ephemeralKey}
+		sharedSecret, err := sessionECDH.ECDH(hop.NodePub)
+		if err != nil {
+			return nil, err
+		}
+
+		cipherText, err := encryptHopData(sharedSecret, hop.PlainText)
+		if err != nil {
+			return nil, err
+		}
+
+		blindingFactor := hmacSum(blindedNodeIDKey, sharedSecret[:])
+		path.BlindedHops[i] = &BlindedHop{
+			BlindedNodePub: scalarMult(blindingFactor[:], hop.NodePub),
+			CipherText:     cipherText,
+		}
+
+		// The ephemeral key of the next hop is derived from the public
+		// ephemeral key and the shared secret of this hop.
+		nextFactor := nextBlindingFactor(
+			ephemeralKey.PubKey(), sharedSecret,
+		)
+		ephemeralKey = scalarMultPriv(nextFactor[:], ephemeralKey)
+	}
+
+	return path, nil
+}
+
+// DecryptBlindedHopData decrypts the data that was encrypted to the node
+// holding the given key in a blinded path, using the blinding point of the
+// hop.
+func DecryptBlindedHopData(nodeKey sphinx.SingleKeyECDH,
+	blindingPoint *secp256k1.PublicKey, cipherText []byte) ([]byte, error) {
+
+	sharedSecret, err := nodeKey.ECDH(blindingPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	rho := hmacSum(rhoKey, sharedSecret[:])
+	aead, err := chacha20poly1305.New(rho[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [chacha20poly1305.NonceSize]byte
+	return aead.Open(nil, nonce[:], cipherText, nil)
+}
+
+// NextBlindingPoint derives the blinding point that the node holding the
+// given key must hand to the next hop of a blinded path.
+func NextBlindingPoint(nodeKey sphinx.SingleKeyECDH,
+	blindingPoint *secp256k1.PublicKey) (*secp256k1.PublicKey, error) {
+
+	sharedSecret, err := nodeKey.ECDH(blindingPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	factor := nextBlindingFactor(blindingPoint, sharedSecret)
+
+	return scalarMult(factor[:], blindingPoint), nil
+}
+
+// BlindOnionKey blinds the ephemeral key of an onion packet that was created
+// for the blinded node ID of the node holding the given key. As the onion was
+// encrypted to our node ID tweaked by the blinding factor of the hop, the
+// resulting key can be used with our regular node key to derive the shared
+// secret of the onion packet.
+func BlindOnionKey(nodeKey sphinx.SingleKeyECDH, blindingPoint,
+	onionKey *secp256k1.PublicKey) (*secp256k1.PublicKey, error) {
+
+	sharedSecret, err := nodeKey.ECDH(blindingPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	blindingFactor := hmacSum(blindedNodeIDKey, sharedSecret[:])
+
+	return scalarMult(blindingFactor[:], onionKey), nil
+}
+
+// NextOnionKey derives the onion ephemeral key of the next hop from the
+// original onion key and the shared secret of the onion packet, the same way
+// it is done for regular onion packets. It is needed because the onion of a
+// blinded hop is processed with a blinded ephemeral key, which would otherwise
+// result in an invalid ephemeral key for the next hop.
+func NextOnionKey(onionKey *secp256k1.PublicKey,
+	sharedSecret [32]byte) *secp256k1.PublicKey {
+
+	factor := nextBlindingFactor(onionKey, sharedSecret)
+
+	return scalarMult(factor[:], onionKey)
+}
+
+// encryptHopData encrypts the plain text of a hop using the key derived from
+// the shared secret with that hop.
+func encryptHopData(sharedSecret [32]byte, plainText []byte) ([]byte, error) {
+	rho := hmacSum(rhoKey, sharedSecret[:])
+	aead, err := chacha20poly1305.New(rho[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [chacha20poly1305.NonceSize]byte
+	return aead.Seal(nil, nonce[:], plainText, nil), nil
+}
+
+// nextBlindingFactor computes the factor used to derive the ephemeral key of
+// the next hop: sha256(E || ss).
+func nextBlindingFactor(ephemeralKey *secp256k1.PublicKey,
+	sharedSecret [32]byte) [32]byte {
+
+	h := sha256.New()
+	h.Write(ephemeralKey.SerializeCompressed())
+	h.Write(sharedSecret[:])
+
+	var factor [32]byte
+	copy(factor[:], h.Sum(nil))
+
+	return factor
+}
+
+// hmacSum returns the HMAC-SHA256 of the message using the given key.
+func hmacSum(key, msg []byte) [32]byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+
+	var sum [32]byte
+	copy(sum[:], mac.Sum(nil))
+
+	return sum
+}
+
+// scalarMult multiplies the given public key by the scalar s.
+func scalarMult(s []byte, pub *secp256k1.PublicKey) *secp256k1.PublicKey {
+	var scalar secp256k1.ModNScalar
+	scalar.SetByteSlice(s)
+
+	var point, result secp256k1.JacobianPoint
+	pub.AsJacobian(&point)
+	secp256k1.ScalarMultNonConst(&scalar, &point, &result)
+	result.ToAffine()
+
+	return secp256k1.NewPublicKey(&result.X, &result.Y)
+}
+
+// scalarMultPriv multiplies the given private key by the scalar s.
+func scalarMultPriv(s []byte,
+	priv *secp256k1.PrivateKey) *secp256k1.PrivateKey {
+
+	var scalar secp256k1.ModNScalar
+	scalar.SetByteSlice(s)
+	scalar.Mul(&priv.Key)
+
+	return secp256k1.NewPrivateKey(&scalar)
+}
@@ -0,0 +1,87 @@
+package blindedpath
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	sphinx "github.com/decred/lightning-onion/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlindedPath tests that each node of a blinded path is able to decrypt
+// its data and derive the blinding point of the next hop.
+func TestBlindedPath(t *testing.T) {
+	t.Parallel()
+
+	const numHops = 3
+
+	var (
+		nodeKeys = make([]*secp256k1.PrivateKey, numHops)
+		hops     = make([]*HopInfo, numHops)
+	)
+	for i := 0; i < numHops; i++ {
+		key, err := secp256k1.GeneratePrivateKey()
+		require.NoError(t, err)
+
+		nodeKeys[i] = key
+		hops[i] = &HopInfo{
+			NodePub:   key.PubKey(),
+			PlainText: bytes.Repeat([]byte{byte(i)}, 10+i),
+		}
+	}
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	path, err := BuildBlindedPath(sessionKey, hops)
+	require.NoError(t, err)
+	require.Len(t, path.BlindedHops, numHops)
+	require.True(t, path.IntroductionPoint.IsEqual(nodeKeys[0].PubKey()))
+
+	blindingPoint := path.BlindingPoint
+	for i, key := range nodeKeys {
+		nodeKey := &sphinx.PrivKeyECDH{PrivKey: key}
+		hop := path.BlindedHops[i]
+
+		// The blinded node ID must not reveal the real node ID.
+		require.False(t, hop.BlindedNodePub.IsEqual(key.PubKey()))
+
+		plainText, err := DecryptBlindedHopData(
+			nodeKey, blindingPoint, hop.CipherText,
+		)
+		require.NoError(t, err)
+		require.Equal(t, hops[i].PlainText, plainText)
+
+		// Blinding our own public key must result in the blinded node
+		// ID of the hop.
+		blindedPub, err := BlindOnionKey(
+			nodeKey, blindingPoint, key.PubKey(),
+		)
+		require.NoError(t, err)
+		require.True(t, blindedPub.IsEqual(hop.BlindedNodePub))
+
+		// Other nodes are unable to decrypt the data.
+		otherKey := &sphinx.PrivKeyECDH{
+			PrivKey: nodeKeys[(i+1)%numHops],
+		}
+		_, err = DecryptBlindedHopData(
+			otherKey, blindingPoint, hop.CipherText,
+		)
+		require.Error(t, err)
+
+		blindingPoint, err = NextBlindingPoint(nodeKey, blindingPoint)
+		require.NoError(t, err)
+	}
+}
+
+// TestBuildBlindedPathNoHops asserts that a blinded path requires hops.
+func TestBuildBlindedPathNoHops(t *testing.T) {
+	t.Parallel()
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	_, err = BuildBlindedPath(sessionKey, nil)
+	require.Equal(t, ErrNoBlindedHops, err)
+}
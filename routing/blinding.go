@@ -0,0 +1,98 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/zpay32"
+)
+
+// ErrInvalidBlindedPath is returned when a payment is attempted to a blinded
+// path that doesn't contain any hops.
+var ErrInvalidBlindedPath = errors.New("blinded path must contain at " +
+	"least one hop")
+
+// blindedNodeID returns the public key that the sender must encrypt the onion
+// payload of the given hop of a blinded path to. The introduction node is
+// addressed through its real public key.
+func blindedNodeID(path *zpay32.BlindedPaymentPath,
+	idx int) *secp256k1.PublicKey {
+
+	if idx == 0 {
+		return path.IntroductionNode
+	}
+
+	return path.Hops[idx].BlindedNodeID
+}
+
+// BlindedPathTarget returns the vertex that payments to the given blinded path
+// should be routed to.
+func BlindedPathTarget(path *zpay32.BlindedPaymentPath) (route.Vertex, error) {
+	if len(path.Hops) == 0 {
+		return route.Vertex{}, ErrInvalidBlindedPath
+	}
+
+	return route.NewVertex(blindedNodeID(path, len(path.Hops)-1)), nil
+}
+
+// blindedPathToRouteHint converts a blinded path into a route hint that leads
+// from the introduction node to the last blinded hop, so that path finding is
+// able to take the fees and time-lock deltas of the blinded hops into account.
+// As the channels between the blinded hops aren't known to the sender, each
+// hint carries a made up channel ID that is never sent over the wire.
+func blindedPathToRouteHint(
+	path *zpay32.BlindedPaymentPath) []zpay32.HopHint {
+
+	var routeHint []zpay32.HopHint
+	for i, hop := range path.Hops[:len(path.Hops)-1] {
+		routeHint = append(routeHint, zpay32.HopHint{
+			NodeID:                    blindedNodeID(path, i),
+			ChannelID:                 uint64(i + 1),
+			FeeBaseMAtoms:             hop.FeeBaseMAtoms,
+			FeeProportionalMillionths: hop.FeeProportionalMillionths,
+			CLTVExpiryDelta:           hop.CLTVExpiryDelta,
+		})
+	}
+
+	return routeHint
+}
+
+// applyBlindedPath adds the data of the blinded path to the hops of the route
+// that lead through it. The route must end with the hops of the blinded path.
+func applyBlindedPath(rt *route.Route, path *zpay32.BlindedPaymentPath,
+	totalAmt lnwire.MilliAtom) error {
+
+	offset := len(rt.Hops) - len(path.Hops)
+	if offset < 0 {
+		return fmt.Errorf("route with %d hops is shorter than the "+
+			"blinded path with %d hops", len(rt.Hops),
+			len(path.Hops))
+	}
+
+	for i, blindedHop := range path.Hops {
+		hop := rt.Hops[offset+i]
+
+		expectedID := route.NewVertex(blindedNodeID(path, i))
+		if hop.PubKeyBytes != expectedID {
+			return fmt.Errorf("route hop %v doesn't match blinded "+
+				"hop %v", hop.PubKeyBytes, expectedID)
+		}
+
+		hop.EncryptedData = blindedHop.CipherText
+		hop.LegacyPayload = false
+		hop.MPP = nil
+
+		if i == 0 {
+			hop.BlindingPoint = path.BlindingPoint
+		}
+
+		if i == len(path.Hops)-1 {
+			hop.TotalAmtMAtoms = totalAmt
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/zpay32"
+	"github.com/stretchr/testify/require"
+)
+
+// TestApplyBlindedPath asserts that a route found through the route hint of a
+// blinded path is given the data of the blinded path.
+func TestApplyBlindedPath(t *testing.T) {
+	t.Parallel()
+
+	var keys []*secp256k1.PublicKey
+	for i := 0; i < 4; i++ {
+		key, err := secp256k1.GeneratePrivateKey()
+		require.NoError(t, err)
+
+		keys = append(keys, key.PubKey())
+	}
+
+	path := &zpay32.BlindedPaymentPath{
+		IntroductionNode: keys[0],
+		BlindingPoint:    keys[1],
+		Hops: []zpay32.BlindedPaymentHop{
+			{
+				// The blinded node ID of the introduction node
+				// isn't used.
+				BlindedNodeID:             keys[1],
+				CipherText:                []byte{1},
+				FeeBaseMAtoms:             1000,
+				FeeProportionalMillionths: 10,
+				CLTVExpiryDelta:           40,
+			},
+			{
+				BlindedNodeID: keys[2],
+				CipherText:    []byte{2},
+			},
+		},
+	}
+
+	target, err := BlindedPathTarget(path)
+	require.NoError(t, err)
+	require.Equal(t, route.NewVertex(keys[2]), target)
+
+	// The route hint must lead from the introduction node to the last
+	// blinded hop.
+	routeHint := blindedPathToRouteHint(path)
+	require.Len(t, routeHint, 1)
+	require.Equal(t, keys[0], routeHint[0].NodeID)
+	require.EqualValues(t, 1000, routeHint[0].FeeBaseMAtoms)
+	require.EqualValues(t, 10, routeHint[0].FeeProportionalMillionths)
+	require.EqualValues(t, 40, routeHint[0].CLTVExpiryDelta)
+
+	rt := &route.Route{
+		Hops: []*route.Hop{
+			{PubKeyBytes: route.NewVertex(keys[3])},
+			{PubKeyBytes: route.NewVertex(keys[0])},
+			{
+				PubKeyBytes:   target,
+				LegacyPayload: true,
+			},
+		},
+	}
+	err = applyBlindedPath(rt, path, lnwire.MilliAtom(5000))
+	require.NoError(t, err)
+
+	require.Nil(t, rt.Hops[0].EncryptedData)
+	require.Equal(t, []byte{1}, rt.Hops[1].EncryptedData)
+	require.Equal(t, keys[1], rt.Hops[1].BlindingPoint)
+	require.Equal(t, []byte{2}, rt.Hops[2].EncryptedData)
+	require.Nil(t, rt.Hops[2].BlindingPoint)
+	require.False(t, rt.Hops[2].LegacyPayload)
+	require.Equal(t, lnwire.MilliAtom(5000), rt.Hops[2].TotalAmtMAtoms)
+
+	// A route that doesn't lead through the blinded path is rejected.
+	rt.Hops[1].PubKeyBytes = route.NewVertex(keys[3])
+	err = applyBlindedPath(rt, path, lnwire.MilliAtom(5000))
+	require.Error(t, err)
+}
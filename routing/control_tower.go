@@ -52,6 +52,11 @@ type ControlTower interface {
 	// FetchInFlightPayments returns all payments with status InFlight.
 	FetchInFlightPayments() ([]*channeldb.InFlightPayment, error)
 
+	// DeletePayment deletes the payment with the given hash, or only its
+	// failed htlc attempts if failedHtlcsOnly is set. In-flight payments
+	// can't be deleted.
+	DeletePayment(paymentHash lntypes.Hash, failedHtlcsOnly bool) error
+
 	// SubscribePayment subscribes to updates for the payment with the given
 	// hash. A first update with the current state of the payment is always
 	// sent out immediately.
@@ -217,6 +222,18 @@ func (p *controlTower) FetchInFlightPayments() ([]*channeldb.InFlightPayment, er
 	return p.db.FetchInFlightPayments()
 }
 
+// DeletePayment deletes the payment with the given hash, or only its failed
+// htlc attempts if failedHtlcsOnly is set. The payment is locked while it is
+// deleted, so that it can't concurrently be initiated again.
+func (p *controlTower) DeletePayment(paymentHash lntypes.Hash,
+	failedHtlcsOnly bool) error {
+
+	p.paymentsMtx.Lock(paymentHash)
+	defer p.paymentsMtx.Unlock(paymentHash)
+
+	return p.db.DeletePayment(paymentHash, failedHtlcsOnly)
+}
+
 // SubscribePayment subscribes to updates for the payment with the given hash. A
 // first update with the current state of the payment is always sent out
 // immediately.
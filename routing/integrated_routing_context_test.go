@@ -31,6 +31,7 @@ type integratedRoutingContext struct {
 	finalExpiry int32
 
 	mcCfg          MissionControlConfig
+	aprioriCfg     AprioriConfig
 	pathFindingCfg PathFindingConfig
 }
 
@@ -58,10 +59,13 @@ func newIntegratedRoutingContext(t *testing.T) *integratedRoutingContext {
 		finalExpiry: 40,
 
 		mcCfg: MissionControlConfig{
+			SelfNode: source.pubkey,
+		},
+
+		aprioriCfg: AprioriConfig{
 			PenaltyHalfLife:       30 * time.Minute,
 			AprioriHopProbability: 0.6,
 			AprioriWeight:         0.5,
-			SelfNode:              source.pubkey,
 		},
 
 		pathFindingCfg: PathFindingConfig{
@@ -113,6 +117,12 @@ func (c *integratedRoutingContext) testPayment(maxParts uint32) ([]htlcAttempt,
 
 	// Instantiate a new mission control with the current configuration
 	// values.
+	estimator, err := NewAprioriEstimator(c.aprioriCfg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.mcCfg.Estimator = estimator
+
 	mc, err := NewMissionControl(db, &c.mcCfg)
 	if err != nil {
 		c.t.Fatal(err)
@@ -168,7 +178,7 @@ func (c *integratedRoutingContext) testPayment(maxParts uint32) ([]htlcAttempt,
 		}
 
 		// Find a route.
-		route, err := session.RequestRoute(
+		route, _, err := session.RequestRoute(
 			amtRemaining, lnwire.MaxMilliAtom, inFlightHtlcs, 0,
 		)
 		if err != nil {
@@ -1,9 +1,11 @@
 package routing
 
 import (
+	"errors"
 	"sync"
 	"time"
 
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/lnwire"
@@ -57,6 +59,12 @@ const (
 	DefaultMinFailureRelaxInterval = time.Minute
 )
 
+var (
+	// ErrMissingEstimator is returned when mission control is created
+	// without a probability estimator.
+	ErrMissingEstimator = errors.New("missing probability estimator")
+)
+
 // NodeResults contains previous results from a node to its peers.
 type NodeResults map[route.Vertex]TimedPairResult
 
@@ -84,7 +92,7 @@ type MissionControl struct {
 
 	// estimator is the probability estimator that is used with the payment
 	// results that mission control collects.
-	estimator *probabilityEstimator
+	estimator Estimator
 
 	sync.Mutex
 
@@ -97,32 +105,24 @@ type MissionControl struct {
 // MissionControlConfig defines parameters that control mission control
 // behaviour.
 type MissionControlConfig struct {
-	// PenaltyHalfLife defines after how much time a penalized node or
-	// channel is back at 50% probability.
-	PenaltyHalfLife time.Duration
-
-	// AprioriHopProbability is the assumed success probability of a hop in
-	// a route when no other information is available.
-	AprioriHopProbability float64
+	// Estimator gives probability estimates for node pairs.
+	Estimator Estimator
 
 	// MaxMcHistory defines the maximum number of payment results that are
 	// held on disk.
 	MaxMcHistory int
 
-	// AprioriWeight is a value in the range [0, 1] that defines to what
-	// extent historical results should be extrapolated to untried
-	// connections. Setting it to one will completely ignore historical
-	// results and always assume the configured a priori probability for
-	// untried connections. A value of zero will ignore the a priori
-	// probability completely and only base the probability on historical
-	// results, unless there are none available.
-	AprioriWeight float64
-
 	// MinFailureRelaxInterval is the minimum time that must have passed
 	// since the previously recorded failure before the failure amount may
 	// be raised.
 	MinFailureRelaxInterval time.Duration
 
+	// PaymentAttemptPenalty is the virtual cost in path finding weight
+	// units of executing a payment attempt that fails. Mission control
+	// doesn't use it itself, but persists it along with the rest of the
+	// config, so that it can be tuned at runtime.
+	PaymentAttemptPenalty lnwire.MilliAtom
+
 	// SelfNode is our own pubkey.
 	SelfNode route.Vertex
 }
@@ -150,6 +150,9 @@ type TimedPairResult struct {
 // MissionControlSnapshot contains a snapshot of the current state of mission
 // control.
 type MissionControlSnapshot struct {
+	// Estimator is the name of the probability estimator that is in use.
+	Estimator string
+
 	// Pairs is a list of channels for which specific information is
 	// logged.
 	Pairs []MissionControlPairSnapshot
@@ -180,29 +183,41 @@ type paymentResult struct {
 func NewMissionControl(db kvdb.Backend, cfg *MissionControlConfig) (
 	*MissionControl, error) {
 
-	log.Debugf("Instantiating mission control with config: "+
-		"PenaltyHalfLife=%v, AprioriHopProbability=%v, "+
-		"AprioriWeight=%v", cfg.PenaltyHalfLife,
-		cfg.AprioriHopProbability, cfg.AprioriWeight)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 
 	store, err := newMissionControlStore(db, cfg.MaxMcHistory)
 	if err != nil {
 		return nil, err
 	}
 
-	estimator := &probabilityEstimator{
-		aprioriHopProbability:  cfg.AprioriHopProbability,
-		aprioriWeight:          cfg.AprioriWeight,
-		penaltyHalfLife:        cfg.PenaltyHalfLife,
-		prevSuccessProbability: prevSuccessProbability,
+	// A config that was set at runtime takes precedence over the config
+	// that we were started with.
+	persistedCfg, err := store.fetchConfig()
+	if err != nil {
+		return nil, err
+	}
+	if persistedCfg != nil {
+		logConfigOverride(cfg, persistedCfg)
+
+		persistedCfg.SelfNode = cfg.SelfNode
+		cfg = persistedCfg
+		store.maxRecords = cfg.MaxMcHistory
 	}
 
+	log.Debugf("Instantiating mission control with estimator %v: %+v, "+
+		"MaxMcHistory=%v, MinFailureRelaxInterval=%v, "+
+		"PaymentAttemptPenalty=%v", cfg.Estimator, cfg.Estimator,
+		cfg.MaxMcHistory, cfg.MinFailureRelaxInterval,
+		cfg.PaymentAttemptPenalty)
+
 	mc := &MissionControl{
 		state:     newMissionControlState(cfg.MinFailureRelaxInterval),
 		now:       time.Now,
 		cfg:       cfg,
 		store:     store,
-		estimator: estimator,
+		estimator: cfg.Estimator,
 	}
 
 	if err := mc.init(); err != nil {
@@ -233,6 +248,55 @@ func (m *MissionControl) init() error {
 	return nil
 }
 
+// GetConfig returns the config that mission control is currently configured
+// with. The returned config is a copy, so it can be modified by the caller.
+func (m *MissionControl) GetConfig() *MissionControlConfig {
+	m.Lock()
+	defer m.Unlock()
+
+	cfg := *m.cfg
+
+	return &cfg
+}
+
+// SetConfig validates the config provided and updates mission control's config
+// if it is valid. The estimator, the maximum number of stored results and the
+// minimum failure relax interval are applied immediately. The payment attempt
+// penalty needs to be applied to the path finding config by the caller. The
+// new config is persisted and takes precedence over the startup config on the
+// next start.
+func (m *MissionControl) SetConfig(cfg *MissionControlConfig) error {
+	if cfg == nil {
+		return errors.New("nil mission control config")
+	}
+
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	log.Infof("Updating mission control cfg: estimator=%v, %+v, "+
+		"MaxMcHistory=%v, MinFailureRelaxInterval=%v, "+
+		"PaymentAttemptPenalty=%v", cfg.Estimator, cfg.Estimator,
+		cfg.MaxMcHistory, cfg.MinFailureRelaxInterval,
+		cfg.PaymentAttemptPenalty)
+
+	if err := m.store.storeConfig(cfg); err != nil {
+		return err
+	}
+
+	newCfg := *cfg
+	newCfg.SelfNode = m.cfg.SelfNode
+
+	m.cfg = &newCfg
+	m.estimator = cfg.Estimator
+	m.state.minFailureRelaxInterval = cfg.MinFailureRelaxInterval
+
+	return nil
+}
+
 // ResetHistory resets the history of MissionControl returning it to a state as
 // if no payment attempts have been made.
 func (m *MissionControl) ResetHistory() error {
@@ -251,9 +315,10 @@ func (m *MissionControl) ResetHistory() error {
 }
 
 // GetProbability is expected to return the success probability of a payment
-// from fromNode along edge.
+// from fromNode along edge. The capacity of the channel may be zero if it is
+// unknown.
 func (m *MissionControl) GetProbability(fromNode, toNode route.Vertex,
-	amt lnwire.MilliAtom) float64 {
+	amt lnwire.MilliAtom, capacity dcrutil.Amount) float64 {
 
 	m.Lock()
 	defer m.Unlock()
@@ -263,10 +328,12 @@ func (m *MissionControl) GetProbability(fromNode, toNode route.Vertex,
 
 	// Use a distinct probability estimation function for local channels.
 	if fromNode == m.cfg.SelfNode {
-		return m.estimator.getLocalPairProbability(now, results, toNode)
+		return m.estimator.LocalPairProbability(now, results, toNode)
 	}
 
-	return m.estimator.getPairProbability(now, results, toNode, amt)
+	return m.estimator.PairProbability(
+		now, results, toNode, amt, capacity,
+	)
 }
 
 // GetHistorySnapshot takes a snapshot from the current mission control state
@@ -277,7 +344,35 @@ func (m *MissionControl) GetHistorySnapshot() *MissionControlSnapshot {
 
 	log.Debugf("Requesting history snapshot from mission control")
 
-	return m.state.getSnapshot()
+	snapshot := m.state.getSnapshot()
+	snapshot.Estimator = m.estimator.String()
+
+	return snapshot
+}
+
+// ImportHistory imports the set of mission control results provided to our
+// in-memory state. These results are not persisted, so will not survive
+// restarts. If force is set, the imported results overwrite our current
+// results. Otherwise only results that are more recent than our current
+// results for a pair are applied. The number of imported results is returned.
+func (m *MissionControl) ImportHistory(history *MissionControlSnapshot,
+	force bool) (int, error) {
+
+	if history == nil {
+		return 0, errors.New("cannot import nil history")
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	log.Infof("Importing history snapshot with %v pairs to mission "+
+		"control", len(history.Pairs))
+
+	imported := m.state.importSnapshot(history, force)
+
+	log.Infof("Imported %v results to mission control", imported)
+
+	return imported, nil
 }
 
 // GetPairHistorySnapshot returns the stored history for a given node pair.
@@ -423,6 +518,7 @@ func (m *MissionControl) applyPaymentResult(
 
 		m.state.setLastPairResult(
 			pair.From, pair.To, result.timeReply, &pairResult,
+			false,
 		)
 	}
 
@@ -0,0 +1,289 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+const (
+	// mcConfigEstimatorType is the tlv type of the name of the probability
+	// estimator.
+	mcConfigEstimatorType tlv.Type = 0
+
+	// mcConfigMaxHistoryType is the tlv type of the maximum number of
+	// payment results held on disk.
+	mcConfigMaxHistoryType tlv.Type = 1
+
+	// mcConfigMinFailureRelaxType is the tlv type of the minimum failure
+	// relax interval.
+	mcConfigMinFailureRelaxType tlv.Type = 2
+
+	// mcConfigPenaltyHalfLifeType is the tlv type of the penalty half life
+	// of the apriori estimator.
+	mcConfigPenaltyHalfLifeType tlv.Type = 3
+
+	// mcConfigHopProbabilityType is the tlv type of the a priori hop
+	// probability of the apriori estimator.
+	mcConfigHopProbabilityType tlv.Type = 4
+
+	// mcConfigAprioriWeightType is the tlv type of the a priori weight of
+	// the apriori estimator.
+	mcConfigAprioriWeightType tlv.Type = 5
+
+	// mcConfigNodeWeightType is the tlv type of the node weight of the
+	// bimodal estimator.
+	mcConfigNodeWeightType tlv.Type = 6
+
+	// mcConfigScaleType is the tlv type of the scale of the bimodal
+	// estimator.
+	mcConfigScaleType tlv.Type = 7
+
+	// mcConfigDecayTimeType is the tlv type of the decay time of the
+	// bimodal estimator.
+	mcConfigDecayTimeType tlv.Type = 8
+
+	// mcConfigAttemptPenaltyType is the tlv type of the payment attempt
+	// penalty used in path finding.
+	mcConfigAttemptPenaltyType tlv.Type = 9
+)
+
+var (
+	// ErrInvalidMcHistory is returned when we get a negative mission
+	// control history count.
+	ErrInvalidMcHistory = errors.New("mission control history must be " +
+		">= 0")
+
+	// ErrInvalidFailureInterval is returned when we get an invalid failure
+	// interval for mission control.
+	ErrInvalidFailureInterval = errors.New("failure interval must be >= 0")
+)
+
+// validate returns an error if the mission control config provided is invalid.
+func (c *MissionControlConfig) validate() error {
+	if c.Estimator == nil {
+		return ErrMissingEstimator
+	}
+
+	if c.MaxMcHistory < 0 {
+		return ErrInvalidMcHistory
+	}
+
+	if c.MinFailureRelaxInterval < 0 {
+		return ErrInvalidFailureInterval
+	}
+
+	return nil
+}
+
+// serializeMcConfig serializes the tunable parts of a mission control config,
+// including the parameters of its estimator, to the given writer.
+func serializeMcConfig(w io.Writer, cfg *MissionControlConfig) error {
+	var (
+		estimatorName       = []byte(cfg.Estimator.String())
+		maxHistory          = uint64(cfg.MaxMcHistory)
+		minFailureRelax     = uint64(cfg.MinFailureRelaxInterval)
+		penaltyHalfLife     uint64
+		hopProbability      uint64
+		aprioriWeight       uint64
+		nodeWeight          uint64
+		scale               uint64
+		decayTime           uint64
+		attemptPenalty      = uint64(cfg.PaymentAttemptPenalty)
+		estimatorParameters []tlv.Record
+	)
+
+	switch e := cfg.Estimator.(type) {
+	case *AprioriEstimator:
+		penaltyHalfLife = uint64(e.PenaltyHalfLife)
+		hopProbability = math.Float64bits(e.AprioriHopProbability)
+		aprioriWeight = math.Float64bits(e.AprioriWeight)
+
+		estimatorParameters = []tlv.Record{
+			tlv.MakePrimitiveRecord(
+				mcConfigPenaltyHalfLifeType, &penaltyHalfLife,
+			),
+			tlv.MakePrimitiveRecord(
+				mcConfigHopProbabilityType, &hopProbability,
+			),
+			tlv.MakePrimitiveRecord(
+				mcConfigAprioriWeightType, &aprioriWeight,
+			),
+		}
+
+	case *BimodalEstimator:
+		nodeWeight = math.Float64bits(e.BimodalNodeWeight)
+		scale = uint64(e.BimodalScaleMAtoms)
+		decayTime = uint64(e.BimodalDecayTime)
+
+		estimatorParameters = []tlv.Record{
+			tlv.MakePrimitiveRecord(
+				mcConfigNodeWeightType, &nodeWeight,
+			),
+			tlv.MakePrimitiveRecord(mcConfigScaleType, &scale),
+			tlv.MakePrimitiveRecord(
+				mcConfigDecayTimeType, &decayTime,
+			),
+		}
+
+	default:
+		return fmt.Errorf("unable to persist config of estimator %v",
+			cfg.Estimator)
+	}
+
+	records := append([]tlv.Record{
+		tlv.MakePrimitiveRecord(mcConfigEstimatorType, &estimatorName),
+		tlv.MakePrimitiveRecord(mcConfigMaxHistoryType, &maxHistory),
+		tlv.MakePrimitiveRecord(
+			mcConfigMinFailureRelaxType, &minFailureRelax,
+		),
+	}, estimatorParameters...)
+	records = append(records, tlv.MakePrimitiveRecord(
+		mcConfigAttemptPenaltyType, &attemptPenalty,
+	))
+
+	tlvStream, err := tlv.NewStream(records...)
+	if err != nil {
+		return err
+	}
+
+	return tlvStream.Encode(w)
+}
+
+// deserializeMcConfig deserializes a mission control config that was
+// serialized with serializeMcConfig. The estimator is recreated from the
+// persisted parameters. Note that SelfNode is not part of the serialized
+// config and thus is left empty.
+func deserializeMcConfig(r io.Reader) (*MissionControlConfig, error) {
+	var (
+		estimatorName   []byte
+		maxHistory      uint64
+		minFailureRelax uint64
+		penaltyHalfLife uint64
+		hopProbability  uint64
+		aprioriWeight   uint64
+		nodeWeight      uint64
+		scale           uint64
+		decayTime       uint64
+		attemptPenalty  uint64
+	)
+
+	tlvStream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(mcConfigEstimatorType, &estimatorName),
+		tlv.MakePrimitiveRecord(mcConfigMaxHistoryType, &maxHistory),
+		tlv.MakePrimitiveRecord(
+			mcConfigMinFailureRelaxType, &minFailureRelax,
+		),
+		tlv.MakePrimitiveRecord(
+			mcConfigPenaltyHalfLifeType, &penaltyHalfLife,
+		),
+		tlv.MakePrimitiveRecord(
+			mcConfigHopProbabilityType, &hopProbability,
+		),
+		tlv.MakePrimitiveRecord(
+			mcConfigAprioriWeightType, &aprioriWeight,
+		),
+		tlv.MakePrimitiveRecord(mcConfigNodeWeightType, &nodeWeight),
+		tlv.MakePrimitiveRecord(mcConfigScaleType, &scale),
+		tlv.MakePrimitiveRecord(mcConfigDecayTimeType, &decayTime),
+		tlv.MakePrimitiveRecord(
+			mcConfigAttemptPenaltyType, &attemptPenalty,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tlvStream.Decode(r); err != nil {
+		return nil, err
+	}
+
+	var estimator Estimator
+	switch string(estimatorName) {
+	case AprioriEstimatorName:
+		estimator, err = NewAprioriEstimator(AprioriConfig{
+			PenaltyHalfLife: time.Duration(penaltyHalfLife),
+			AprioriHopProbability: math.Float64frombits(
+				hopProbability,
+			),
+			AprioriWeight: math.Float64frombits(aprioriWeight),
+		})
+
+	case BimodalEstimatorName:
+		estimator, err = NewBimodalEstimator(BimodalConfig{
+			BimodalNodeWeight:  math.Float64frombits(nodeWeight),
+			BimodalScaleMAtoms: lnwire.MilliAtom(scale),
+			BimodalDecayTime:   time.Duration(decayTime),
+		})
+
+	default:
+		err = fmt.Errorf("unknown estimator %v", string(estimatorName))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &MissionControlConfig{
+		Estimator:               estimator,
+		MaxMcHistory:            int(maxHistory),
+		MinFailureRelaxInterval: time.Duration(minFailureRelax),
+		PaymentAttemptPenalty:   lnwire.MilliAtom(attemptPenalty),
+	}, nil
+}
+
+// logConfigOverride logs the values of the startup config that are overridden
+// by the persisted config, so that it is clear why changes to the startup
+// options don't take effect.
+func logConfigOverride(startupCfg, persistedCfg *MissionControlConfig) {
+	log.Warnf("Using the mission control config that was set at runtime, " +
+		"it takes precedence over the startup config. Use " +
+		"SetMissionControlConfig to change it.")
+
+	if !reflect.DeepEqual(startupCfg.Estimator, persistedCfg.Estimator) {
+		log.Warnf("Overriding startup estimator %v with %v",
+			estimatorDescription(startupCfg.Estimator),
+			estimatorDescription(persistedCfg.Estimator))
+	}
+
+	if startupCfg.MaxMcHistory != persistedCfg.MaxMcHistory {
+		log.Warnf("Overriding startup MaxMcHistory=%v with %v",
+			startupCfg.MaxMcHistory, persistedCfg.MaxMcHistory)
+	}
+
+	if startupCfg.MinFailureRelaxInterval !=
+		persistedCfg.MinFailureRelaxInterval {
+
+		log.Warnf("Overriding startup MinFailureRelaxInterval=%v "+
+			"with %v", startupCfg.MinFailureRelaxInterval,
+			persistedCfg.MinFailureRelaxInterval)
+	}
+
+	if startupCfg.PaymentAttemptPenalty !=
+		persistedCfg.PaymentAttemptPenalty {
+
+		log.Warnf("Overriding startup PaymentAttemptPenalty=%v with %v",
+			startupCfg.PaymentAttemptPenalty,
+			persistedCfg.PaymentAttemptPenalty)
+	}
+}
+
+// estimatorDescription returns the name of the estimator along with its
+// parameters.
+func estimatorDescription(estimator Estimator) string {
+	switch e := estimator.(type) {
+	case *AprioriEstimator:
+		return fmt.Sprintf("%v (%+v)", e, e.AprioriConfig)
+
+	case *BimodalEstimator:
+		return fmt.Sprintf("%v (%+v)", e, e.BimodalConfig)
+
+	default:
+		return fmt.Sprintf("%v", estimator)
+	}
+}
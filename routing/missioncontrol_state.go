@@ -52,9 +52,10 @@ func (m *missionControlState) resetHistory() {
 	m.lastSecondChance = make(map[DirectedNodePair]time.Time)
 }
 
-// setLastPairResult stores a result for a node pair.
+// setLastPairResult stores a result for a node pair. If force is set, the
+// minimum failure relaxation interval is not enforced.
 func (m *missionControlState) setLastPairResult(fromNode, toNode route.Vertex,
-	timestamp time.Time, result *pairResult) {
+	timestamp time.Time, result *pairResult, force bool) {
 
 	nodePairs, ok := m.lastPairResult[fromNode]
 	if !ok {
@@ -100,7 +101,7 @@ func (m *missionControlState) setLastPairResult(fromNode, toNode route.Vertex,
 		// come in out of order. This check makes it easier for payment
 		// processes to converge to a final state.
 		failInterval := timestamp.Sub(current.FailTime)
-		if failAmt > current.FailAmt &&
+		if !force && failAmt > current.FailAmt &&
 			failInterval < m.minFailureRelaxInterval {
 
 			log.Debugf("Ignoring higher amount failure within min "+
@@ -182,6 +183,67 @@ func (m *missionControlState) requestSecondChance(timestamp time.Time,
 	return false
 }
 
+// importSnapshot takes an existing snapshot and merges it with our current
+// state. Failure results are applied before success results, so that both
+// still clamp the existing amounts in the same way as live results do. If
+// force is false, results are only imported if they are more recent than our
+// current results for the pair. The number of imported results is returned.
+func (m *missionControlState) importSnapshot(snapshot *MissionControlSnapshot,
+	force bool) int {
+
+	var imported int
+
+	for _, pair := range snapshot.Pairs {
+		fromNode := pair.Pair.From
+		toNode := pair.Pair.To
+
+		results, _ := m.getLastPairResult(fromNode)
+		lastResult := results[toNode]
+
+		if !pair.FailTime.IsZero() {
+			failResult := failPairResult(pair.FailAmt)
+			imported += m.importResult(
+				lastResult.FailTime, pair.FailTime, failResult,
+				fromNode, toNode, force,
+			)
+		}
+
+		if !pair.SuccessTime.IsZero() {
+			successResult := successPairResult(pair.SuccessAmt)
+			imported += m.importResult(
+				lastResult.SuccessTime, pair.SuccessTime,
+				successResult, fromNode, toNode, force,
+			)
+		}
+	}
+
+	return imported
+}
+
+// importResult applies a single imported result for a node pair. Unless force
+// is set, the result is only applied if it is more recent than the current
+// result of the same kind. It returns 1 if the result was applied and 0
+// otherwise.
+func (m *missionControlState) importResult(currentTs, importedTs time.Time,
+	importedResult pairResult, fromNode, toNode route.Vertex,
+	force bool) int {
+
+	if !force && !importedTs.After(currentTs) {
+		log.Debugf("Not setting pair result for %v->%v (%v): "+
+			"timestamp %v not newer than last result %v",
+			fromNode, toNode, importedResult, importedTs,
+			currentTs)
+
+		return 0
+	}
+
+	m.setLastPairResult(
+		fromNode, toNode, importedTs, &importedResult, force,
+	)
+
+	return 1
+}
+
 // GetHistorySnapshot takes a snapshot from the current mission control state
 // and actual probability estimates.
 func (m *missionControlState) getSnapshot() *MissionControlSnapshot {
@@ -4,7 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/routing/route"
+	"github.com/stretchr/testify/require"
 )
 
 // TestMissionControlStateFailureResult tests setting failure results on the
@@ -20,7 +22,9 @@ func TestMissionControlStateFailureResult(t *testing.T) {
 	)
 
 	// Report a 1000 sat failure.
-	state.setLastPairResult(from, to, timestamp, &pairResult{amt: 1000})
+	state.setLastPairResult(
+		from, to, timestamp, &pairResult{amt: 1000}, false,
+	)
 	result, _ := state.getLastPairResult(from)
 	if result[to].FailAmt != 1000 {
 		t.Fatalf("unexpected fail amount %v", result[to].FailAmt)
@@ -29,7 +33,9 @@ func TestMissionControlStateFailureResult(t *testing.T) {
 	// Report an 1100 sat failure one hour later. It is expected to
 	// overwrite the previous failure.
 	timestamp = timestamp.Add(time.Hour)
-	state.setLastPairResult(from, to, timestamp, &pairResult{amt: 1100})
+	state.setLastPairResult(
+		from, to, timestamp, &pairResult{amt: 1100}, false,
+	)
 	result, _ = state.getLastPairResult(from)
 	if result[to].FailAmt != 1100 {
 		t.Fatalf("unexpected fail amount %v", result[to].FailAmt)
@@ -39,9 +45,99 @@ func TestMissionControlStateFailureResult(t *testing.T) {
 	// the failure amount is too soon after the previous failure, the result
 	// is not applied.
 	timestamp = timestamp.Add(time.Second)
-	state.setLastPairResult(from, to, timestamp, &pairResult{amt: 1200})
+	state.setLastPairResult(
+		from, to, timestamp, &pairResult{amt: 1200}, false,
+	)
 	result, _ = state.getLastPairResult(from)
 	if result[to].FailAmt != 1100 {
 		t.Fatalf("unexpected fail amount %v", result[to].FailAmt)
 	}
 }
+
+// TestMissionControlStateImport tests importing mission control history
+// snapshots into the state, both merging and force-overwriting.
+func TestMissionControlStateImport(t *testing.T) {
+	var (
+		from      = route.Vertex{1}
+		to        = route.Vertex{2}
+		timestamp = testTime
+	)
+
+	newState := func() *missionControlState {
+		state := newMissionControlState(time.Minute)
+
+		// Start with a success and a failure for the pair.
+		state.setLastPairResult(
+			from, to, timestamp, &pairResult{amt: 3000}, false,
+		)
+		state.setLastPairResult(
+			from, to, timestamp,
+			&pairResult{amt: 1000, success: true}, false,
+		)
+
+		return state
+	}
+
+	snapshot := func(res TimedPairResult) *MissionControlSnapshot {
+		return &MissionControlSnapshot{
+			Pairs: []MissionControlPairSnapshot{{
+				Pair:            NewDirectedNodePair(from, to),
+				TimedPairResult: res,
+			}},
+		}
+	}
+
+	// Importing older results without force doesn't change the state.
+	state := newState()
+	older := snapshot(TimedPairResult{
+		FailTime:    timestamp.Add(-time.Hour),
+		FailAmt:     2000,
+		SuccessTime: timestamp.Add(-time.Hour),
+		SuccessAmt:  1500,
+	})
+	imported := state.importSnapshot(older, false)
+	require.Equal(t, 0, imported)
+
+	results, _ := state.getLastPairResult(from)
+	require.Equal(t, TimedPairResult{
+		FailTime:    timestamp,
+		FailAmt:     3000,
+		SuccessTime: timestamp,
+		SuccessAmt:  1000,
+	}, results[to])
+
+	// Importing the same older results with force overwrites the state.
+	imported = state.importSnapshot(older, true)
+	require.Equal(t, 2, imported)
+
+	results, _ = state.getLastPairResult(from)
+	require.Equal(t, lnwire.MilliAtom(2000), results[to].FailAmt)
+	require.Equal(t, lnwire.MilliAtom(1500), results[to].SuccessAmt)
+	require.Equal(t, timestamp.Add(-time.Hour), results[to].FailTime)
+
+	// Importing newer results is applied without force. A zero success
+	// time means that only the failure is imported.
+	state = newState()
+	newer := snapshot(TimedPairResult{
+		FailTime: timestamp.Add(time.Hour),
+		FailAmt:  500,
+	})
+	imported = state.importSnapshot(newer, false)
+	require.Equal(t, 1, imported)
+
+	results, _ = state.getLastPairResult(from)
+	require.Equal(t, lnwire.MilliAtom(500), results[to].FailAmt)
+	require.Equal(t, timestamp.Add(time.Hour), results[to].FailTime)
+
+	// The failure clamps the previous success amount.
+	require.Equal(t, lnwire.MilliAtom(499), results[to].SuccessAmt)
+
+	// Importing results for an unknown pair creates the pair.
+	state = newMissionControlState(time.Minute)
+	imported = state.importSnapshot(older, false)
+	require.Equal(t, 2, imported)
+
+	results, ok := state.getLastPairResult(from)
+	require.True(t, ok)
+	require.Equal(t, lnwire.MilliAtom(1500), results[to].SuccessAmt)
+}
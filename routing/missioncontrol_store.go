@@ -17,6 +17,14 @@ var (
 	// stored.
 	resultsKey = []byte("missioncontrol-results")
 
+	// configBucketKey is the fixed key of the bucket that holds the
+	// mission control config that was set at runtime.
+	configBucketKey = []byte("missioncontrol-config")
+
+	// configKey is the key under which the mission control config is
+	// stored in the config bucket.
+	configKey = []byte("config")
+
 	// Big endian is the preferred byte order, due to cursor scans over
 	// integer keys iterating in order.
 	byteOrder = binary.BigEndian
@@ -54,6 +62,12 @@ func newMissionControlStore(db kvdb.Backend, maxRecords int) (*missionControlSto
 				err)
 		}
 
+		_, err = tx.CreateTopLevelBucket(configBucketKey)
+		if err != nil {
+			return fmt.Errorf("cannot create config bucket: %v",
+				err)
+		}
+
 		// Count initial number of results and track this number in
 		// memory to avoid calling Stats().KeyN. The reliability of
 		// Stats() is doubtful and seemed to have caused crashes in the
@@ -84,6 +98,52 @@ func (b *missionControlStore) clear() error {
 	})
 }
 
+// storeConfig persists the tunable parts of the mission control config and
+// applies its maximum number of records to the store.
+func (b *missionControlStore) storeConfig(cfg *MissionControlConfig) error {
+	var buf bytes.Buffer
+	if err := serializeMcConfig(&buf, cfg); err != nil {
+		return err
+	}
+
+	return kvdb.Update(b.db, func(tx kvdb.RwTx) error {
+		bucket := tx.ReadWriteBucket(configBucketKey)
+		if err := bucket.Put(configKey, buf.Bytes()); err != nil {
+			return err
+		}
+
+		// Apply the new limit within the transaction, so that it is
+		// serialized with the pruning in AddResult.
+		b.maxRecords = cfg.MaxMcHistory
+
+		return nil
+	})
+}
+
+// fetchConfig returns the mission control config that was persisted with
+// storeConfig. If no config was persisted, nil is returned.
+func (b *missionControlStore) fetchConfig() (*MissionControlConfig, error) {
+	var cfg *MissionControlConfig
+
+	err := kvdb.View(b.db, func(tx kvdb.RTx) error {
+		bucket := tx.ReadBucket(configBucketKey)
+
+		v := bucket.Get(configKey)
+		if v == nil {
+			return nil
+		}
+
+		var err error
+		cfg, err = deserializeMcConfig(bytes.NewReader(v))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
 // fetchAll returns all results currently stored in the database.
 func (b *missionControlStore) fetchAll() ([]*paymentResult, error) {
 	var results []*paymentResult
@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/routing/route"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -37,6 +39,7 @@ var (
 	testPenaltyHalfLife       = 30 * time.Minute
 	testAprioriHopProbability = 0.9
 	testAprioriWeight         = 0.5
+	testCapacity              = dcrutil.Amount(100_000)
 )
 
 type mcTestContext struct {
@@ -75,13 +78,20 @@ func createMcTestContext(t *testing.T) *mcTestContext {
 
 // restartMc creates a new instances of mission control on the same database.
 func (ctx *mcTestContext) restartMc() {
+	estimator, err := NewAprioriEstimator(AprioriConfig{
+		PenaltyHalfLife:       testPenaltyHalfLife,
+		AprioriHopProbability: testAprioriHopProbability,
+		AprioriWeight:         testAprioriWeight,
+	})
+	if err != nil {
+		ctx.t.Fatal(err)
+	}
+
 	mc, err := NewMissionControl(
 		ctx.db,
 		&MissionControlConfig{
-			PenaltyHalfLife:       testPenaltyHalfLife,
-			AprioriHopProbability: testAprioriHopProbability,
-			AprioriWeight:         testAprioriWeight,
-			SelfNode:              mcTestSelf,
+			Estimator: estimator,
+			SelfNode:  mcTestSelf,
 		},
 	)
 	if err != nil {
@@ -102,7 +112,9 @@ func (ctx *mcTestContext) cleanup() {
 func (ctx *mcTestContext) expectP(amt lnwire.MilliAtom, expected float64) {
 	ctx.t.Helper()
 
-	p := ctx.mc.GetProbability(mcTestNode1, mcTestNode2, amt)
+	p := ctx.mc.GetProbability(
+		mcTestNode1, mcTestNode2, amt, testCapacity,
+	)
 	if p != expected {
 		ctx.t.Fatalf("expected probability %v but got %v", expected, p)
 	}
@@ -141,7 +153,9 @@ func TestMissionControl(t *testing.T) {
 
 	// For local channels, we expect a higher probability than our a prior
 	// test probability.
-	selfP := ctx.mc.GetProbability(mcTestSelf, mcTestNode1, 100)
+	selfP := ctx.mc.GetProbability(
+		mcTestSelf, mcTestNode1, 100, testCapacity,
+	)
 	if selfP != prevSuccessProbability {
 		t.Fatalf("expected prev success prob for untried local chans")
 	}
@@ -213,3 +227,53 @@ func TestMissionControlChannelUpdate(t *testing.T) {
 	)
 	ctx.expectP(100, 0)
 }
+
+// TestMissionControlSetConfig tests that the mission control config can be
+// changed at runtime and that the new config survives a restart.
+func TestMissionControlSetConfig(t *testing.T) {
+	ctx := createMcTestContext(t)
+	defer ctx.cleanup()
+
+	require.Equal(
+		t, AprioriEstimatorName, ctx.mc.GetConfig().Estimator.String(),
+	)
+
+	// Invalid configs are rejected.
+	require.Error(t, ctx.mc.SetConfig(&MissionControlConfig{}))
+
+	bimodal, err := NewBimodalEstimator(DefaultBimodalConfig())
+	require.NoError(t, err)
+
+	cfg := &MissionControlConfig{
+		Estimator:               bimodal,
+		MaxMcHistory:            5,
+		MinFailureRelaxInterval: 2 * time.Minute,
+		PaymentAttemptPenalty:   lnwire.NewMAtomsFromAtoms(50),
+	}
+	require.NoError(t, ctx.mc.SetConfig(cfg))
+
+	assertConfig := func() {
+		t.Helper()
+
+		current := ctx.mc.GetConfig()
+		require.Equal(t, mcTestSelf, current.SelfNode)
+		require.Equal(t, 5, current.MaxMcHistory)
+		require.Equal(t, 2*time.Minute, current.MinFailureRelaxInterval)
+		require.Equal(
+			t, lnwire.NewMAtomsFromAtoms(50),
+			current.PaymentAttemptPenalty,
+		)
+		require.Equal(t, bimodal, current.Estimator)
+
+		require.Equal(
+			t, BimodalEstimatorName,
+			ctx.mc.GetHistorySnapshot().Estimator,
+		)
+	}
+	assertConfig()
+
+	// After a restart with the original startup config, the persisted
+	// config is expected to be used.
+	ctx.restartMc()
+	assertConfig()
+}
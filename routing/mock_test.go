@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/lntypes"
@@ -100,6 +101,10 @@ func (m *mockPaymentSessionSource) NewPaymentSessionEmpty() PaymentSession {
 	return &mockPaymentSession{}
 }
 
+func (m *mockPaymentSessionSource) SetPaymentAttemptPenalty(
+	_ lnwire.MilliAtom) {
+}
+
 type mockMissionControl struct {
 }
 
@@ -126,7 +131,7 @@ func (m *mockMissionControl) ReportPaymentSuccess(paymentID uint64,
 }
 
 func (m *mockMissionControl) GetProbability(fromNode, toNode route.Vertex,
-	amt lnwire.MilliAtom) float64 {
+	amt lnwire.MilliAtom, capacity dcrutil.Amount) float64 {
 
 	return 0
 }
@@ -138,16 +143,16 @@ type mockPaymentSession struct {
 var _ PaymentSession = (*mockPaymentSession)(nil)
 
 func (m *mockPaymentSession) RequestRoute(_, _ lnwire.MilliAtom,
-	_, height uint32) (*route.Route, error) {
+	_, height uint32) (*route.Route, *channeldb.ShardAllocation, error) {
 
 	if len(m.routes) == 0 {
-		return nil, errNoPathFound
+		return nil, nil, errNoPathFound
 	}
 
 	r := m.routes[0]
 	m.routes = m.routes[1:]
 
-	return r, nil
+	return r, nil, nil
 }
 
 type mockPayer struct {
@@ -461,6 +466,43 @@ func (m *mockControlTower) FetchInFlightPayments() (
 	return fl, nil
 }
 
+func (m *mockControlTower) DeletePayment(phash lntypes.Hash,
+	failedHtlcsOnly bool) error {
+
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.payments[phash]; !ok {
+		return channeldb.ErrPaymentNotInitiated
+	}
+
+	_, succeeded := m.successful[phash]
+	_, failed := m.failed[phash]
+	if !succeeded && !failed {
+		return channeldb.ErrPaymentInFlight
+	}
+
+	if failedHtlcsOnly {
+		p := m.payments[phash]
+
+		var attempts []channeldb.HTLCAttempt
+		for _, a := range p.attempts {
+			if a.Failure == nil {
+				attempts = append(attempts, a)
+			}
+		}
+		p.attempts = attempts
+
+		return nil
+	}
+
+	delete(m.payments, phash)
+	delete(m.successful, phash)
+	delete(m.failed, phash)
+
+	return nil
+}
+
 func (m *mockControlTower) SubscribePayment(paymentHash lntypes.Hash) (
 	*ControlTowerSubscriber, error) {
 
@@ -7,6 +7,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/feature"
 	"github.com/decred/dcrlnd/lnwire"
@@ -277,7 +278,7 @@ type RestrictParams struct {
 	// ProbabilitySource is a callback that is expected to return the
 	// success probability of traversing the channel from the node.
 	ProbabilitySource func(route.Vertex, route.Vertex,
-		lnwire.MilliAtom) float64
+		lnwire.MilliAtom, dcrutil.Amount) float64
 
 	// FeeLimit is a maximum fee amount allowed to be used on the path from
 	// the source to the target.
@@ -553,7 +554,8 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	// satisfy our specific requirements.
 	processEdge := func(fromVertex route.Vertex,
 		fromFeatures *lnwire.FeatureVector,
-		edge *channeldb.ChannelEdgePolicy, toNodeDist *nodeWithDist) {
+		edge *channeldb.ChannelEdgePolicy, capacity dcrutil.Amount,
+		toNodeDist *nodeWithDist) {
 
 		edgesExpanded++
 
@@ -563,7 +565,7 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 
 		// Request the success probability for this edge.
 		edgeProbability := r.ProbabilitySource(
-			fromVertex, toNodeDist.node, amountToSend,
+			fromVertex, toNodeDist.node, amountToSend, capacity,
 		)
 
 		log.Trace(newLogClosure(func() string {
@@ -807,11 +809,11 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 				continue
 			}
 
-			policy := unifiedPolicy.getPolicy(
+			edge := unifiedPolicy.getEdge(
 				amtToSend, g.bandwidthHints,
 			)
 
-			if policy == nil {
+			if edge == nil {
 				continue
 			}
 
@@ -828,7 +830,10 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 
 			// Check if this candidate node is better than what we
 			// already have.
-			processEdge(fromNode, fromFeatures, policy, partialPath)
+			processEdge(
+				fromNode, fromFeatures, edge.policy,
+				edge.capacity, partialPath,
+			)
 		}
 
 		if nodeHeap.Len() == 0 {
@@ -105,7 +105,9 @@ var (
 
 // noProbabilitySource is used in testing to return the same probability 1 for
 // all edges.
-func noProbabilitySource(route.Vertex, route.Vertex, lnwire.MilliAtom) float64 {
+func noProbabilitySource(route.Vertex, route.Vertex, lnwire.MilliAtom,
+	dcrutil.Amount) float64 {
+
 	return 1
 }
 
@@ -2559,7 +2561,7 @@ func testProbabilityRouting(t *testing.T, p10, p11, p20, minProbability float64,
 
 	// Configure a probability source with the test parameters.
 	ctx.restrictParams.ProbabilitySource = func(fromNode, toNode route.Vertex,
-		amt lnwire.MilliAtom) float64 {
+		amt lnwire.MilliAtom, capacity dcrutil.Amount) float64 {
 
 		if amt == 0 {
 			t.Fatal("expected non-zero amount")
@@ -2639,7 +2641,7 @@ func TestEqualCostRouteSelection(t *testing.T) {
 	target := ctx.testGraphInstance.aliasMap["target"]
 
 	ctx.restrictParams.ProbabilitySource = func(fromNode, toNode route.Vertex,
-		amt lnwire.MilliAtom) float64 {
+		amt lnwire.MilliAtom, capacity dcrutil.Amount) float64 {
 
 		switch {
 		case fromNode == alias["source"] && toNode == alias["a"]:
@@ -11,6 +11,7 @@ import (
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/routing/shards"
 	sphinx "github.com/decred/lightning-onion/v3"
 )
 
@@ -20,8 +21,9 @@ type paymentLifecycle struct {
 	router        *ChannelRouter
 	totalAmount   lnwire.MilliAtom
 	feeLimit      lnwire.MilliAtom
-	paymentHash   lntypes.Hash
+	identifier    lntypes.Hash
 	paySession    PaymentSession
+	shardTracker  shards.ShardTracker
 	timeoutChan   <-chan time.Time
 	currentHeight int32
 }
@@ -80,10 +82,11 @@ func (p *paymentLifecycle) paymentState(payment *channeldb.MPPayment) (
 // resumePayment resumes the paymentLifecycle from the current state.
 func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 	shardHandler := &shardHandler{
-		router:      p.router,
-		paymentHash: p.paymentHash,
-		shardErrors: make(chan error),
-		quit:        make(chan struct{}),
+		router:       p.router,
+		identifier:   p.identifier,
+		shardTracker: p.shardTracker,
+		shardErrors:  make(chan error),
+		quit:         make(chan struct{}),
 	}
 
 	// When the payment lifecycle loop exits, we make sure to signal any
@@ -95,7 +98,7 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 	// up goroutines that'll collect their results and deliver them to the
 	// lifecycle loop below.
 	payment, err := p.router.cfg.Control.FetchPayment(
-		p.paymentHash,
+		p.identifier,
 	)
 	if err != nil {
 		return [32]byte{}, nil, err
@@ -105,7 +108,7 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		a := a
 
 		log.Debugf("Resuming payment shard %v for hash %v",
-			a.AttemptID, p.paymentHash)
+			a.AttemptID, p.identifier)
 
 		shardHandler.collectResultAsync(&a.HTLCAttemptInfo)
 	}
@@ -124,7 +127,7 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		// act on the latest available information, whether we are
 		// resuming an existing payment or just sent a new attempt.
 		payment, err := p.router.cfg.Control.FetchPayment(
-			p.paymentHash,
+			p.identifier,
 		)
 		if err != nil {
 			return [32]byte{}, nil, err
@@ -139,7 +142,7 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 
 		log.Debugf("Payment %v in state terminate=%v, "+
 			"active_shards=%v, rem_value=%v, fee_limit=%v",
-			p.paymentHash, state.terminate, state.numShardsInFlight,
+			p.identifier, state.terminate, state.numShardsInFlight,
 			state.remainingAmt, state.remainingFees)
 
 		switch {
@@ -186,7 +189,7 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 			// return with an error the moment all active shards
 			// have finished.
 			saveErr := p.router.cfg.Control.Fail(
-				p.paymentHash, channeldb.FailureReasonTimeout,
+				p.identifier, channeldb.FailureReasonTimeout,
 			)
 			if saveErr != nil {
 				return [32]byte{}, nil, saveErr
@@ -202,13 +205,13 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		}
 
 		// Create a new payment attempt from the given payment session.
-		rt, err := p.paySession.RequestRoute(
+		rt, allocation, err := p.paySession.RequestRoute(
 			state.remainingAmt, state.remainingFees,
 			uint32(state.numShardsInFlight), uint32(p.currentHeight),
 		)
 		if err != nil {
 			log.Warnf("Failed to find route for payment %v: %v",
-				p.paymentHash, err)
+				p.identifier, err)
 
 			routeErr, ok := err.(noRouteError)
 			if !ok {
@@ -222,10 +225,10 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 				failureCode := routeErr.FailureReason()
 				log.Debugf("Marking payment %v permanently "+
 					"failed with no route: %v",
-					p.paymentHash, failureCode)
+					p.identifier, failureCode)
 
 				saveErr := p.router.cfg.Control.Fail(
-					p.paymentHash, failureCode,
+					p.identifier, failureCode,
 				)
 				if saveErr != nil {
 					return [32]byte{}, nil, saveErr
@@ -242,8 +245,14 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 			continue
 		}
 
+		// If this route will consume the last remaining amount to send
+		// to the receiver, this will be our last shard (for now).
+		lastShard := rt.ReceiverAmt() == state.remainingAmt
+
 		// We found a route to try, launch a new shard.
-		attempt, outcome, err := shardHandler.launchShard(rt)
+		attempt, outcome, err := shardHandler.launchShard(
+			rt, allocation, lastShard,
+		)
 		if err != nil {
 			return [32]byte{}, nil, err
 		}
@@ -253,7 +262,7 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		if outcome.err != nil {
 			log.Warnf("Failed to launch shard %v for "+
 				"payment %v: %v", attempt.AttemptID,
-				p.paymentHash, outcome.err)
+				p.identifier, outcome.err)
 
 			// We must inspect the error to know whether it was
 			// critical or not, to decide whether we should
@@ -279,8 +288,9 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 // shardHandler holds what is necessary to send and collect the result of
 // shards.
 type shardHandler struct {
-	paymentHash lntypes.Hash
-	router      *ChannelRouter
+	identifier   lntypes.Hash
+	router       *ChannelRouter
+	shardTracker shards.ShardTracker
 
 	// shardErrors is a channel where errors collected by calling
 	// collectResultAsync will be delivered. These results are meant to be
@@ -356,14 +366,16 @@ type launchOutcome struct {
 // The launchOutcome is used to indicate whether the attempt was successfully
 // sent. If the launchOutcome wraps a non-nil error, it means that the attempt
 // was not sent onto the network, so no result will be available in the future
-// for it.
-func (p *shardHandler) launchShard(rt *route.Route) (*channeldb.HTLCAttemptInfo,
-	*launchOutcome, error) {
+// for it. The allocation describes how the amount of the shard was chosen, and
+// is nil if the payment wasn't split.
+func (p *shardHandler) launchShard(rt *route.Route,
+	allocation *channeldb.ShardAllocation, lastShard bool) (
+	*channeldb.HTLCAttemptInfo, *launchOutcome, error) {
 
 	// Using the route received from the payment session, create a new
 	// shard to send.
 	firstHop, htlcAdd, attempt, err := p.createNewPaymentAttempt(
-		rt,
+		rt, allocation, lastShard,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -374,7 +386,7 @@ func (p *shardHandler) launchShard(rt *route.Route) (*channeldb.HTLCAttemptInfo,
 	// of the payment that we attempted to send, such that we can query the
 	// Switch for its whereabouts. The route is needed to handle the result
 	// when it eventually comes back.
-	err = p.router.cfg.Control.RegisterAttempt(p.paymentHash, attempt)
+	err = p.router.cfg.Control.RegisterAttempt(p.identifier, attempt)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -425,7 +437,7 @@ func (p *shardHandler) collectResultAsync(attempt *channeldb.HTLCAttemptInfo) {
 
 				log.Errorf("Error collecting result for "+
 					"shard %v for payment %v: %v",
-					attempt.AttemptID, p.paymentHash, err)
+					attempt.AttemptID, p.identifier, err)
 			}
 
 			select {
@@ -464,10 +476,17 @@ func (p *shardHandler) collectResultAsync(attempt *channeldb.HTLCAttemptInfo) {
 func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 	*shardResult, error) {
 
+	// We'll retrieve the hash specific to this shard from the
+	// shardTracker, since it will be needed to regenerate the circuit
+	// below.
+	hash, err := p.shardTracker.GetHash(attempt.AttemptID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Regenerate the circuit for this attempt.
 	_, circuit, err := generateSphinxPacket(
-		&attempt.Route, p.paymentHash[:],
-		attempt.SessionKey,
+		&attempt.Route, hash[:], attempt.SessionKey,
 	)
 	if err != nil {
 		return nil, err
@@ -483,7 +502,7 @@ func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 	// Now ask the switch to return the result of the payment when
 	// available.
 	resultChan, err := p.router.cfg.Payer.GetPaymentResult(
-		attempt.AttemptID, p.paymentHash, errorDecryptor,
+		attempt.AttemptID, p.identifier, errorDecryptor,
 	)
 	switch {
 
@@ -494,7 +513,7 @@ func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 	case err == htlcswitch.ErrPaymentIDNotFound:
 		log.Debugf("Payment ID %v for hash %v not found in "+
 			"the Switch, retrying.", attempt.AttemptID,
-			p.paymentHash)
+			p.identifier)
 
 		attempt, cErr := p.failAttempt(attempt, err)
 		if cErr != nil {
@@ -550,7 +569,7 @@ func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 
 	// We successfully got a payment result back from the switch.
 	log.Debugf("Payment %v succeeded with pid=%v",
-		p.paymentHash, attempt.AttemptID)
+		p.identifier, attempt.AttemptID)
 
 	// Report success to mission control.
 	err = p.router.cfg.MissionControl.ReportPaymentSuccess(
@@ -564,7 +583,7 @@ func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 	// In case of success we atomically store settle result to the DB move
 	// the shard to the settled state.
 	htlcAttempt, err := p.router.cfg.Control.SettleAttempt(
-		p.paymentHash, attempt.AttemptID,
+		p.identifier, attempt.AttemptID,
 		&channeldb.HTLCSettleInfo{
 			Preimage:   result.Preimage,
 			SettleTime: p.router.cfg.Clock.Now(),
@@ -581,7 +600,8 @@ func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 }
 
 // createNewPaymentAttempt creates a new payment attempt from the given route.
-func (p *shardHandler) createNewPaymentAttempt(rt *route.Route) (
+func (p *shardHandler) createNewPaymentAttempt(rt *route.Route,
+	allocation *channeldb.ShardAllocation, lastShard bool) (
 	lnwire.ShortChannelID, *lnwire.UpdateAddHTLC,
 	*channeldb.HTLCAttemptInfo, error) {
 
@@ -591,12 +611,39 @@ func (p *shardHandler) createNewPaymentAttempt(rt *route.Route) (
 		return lnwire.ShortChannelID{}, nil, nil, err
 	}
 
+	// We generate a new, unique payment ID that we will use for
+	// this HTLC.
+	attemptID, err := p.router.cfg.NextPaymentID()
+	if err != nil {
+		return lnwire.ShortChannelID{}, nil, nil, err
+	}
+
+	// Request a new shard from the ShardTracker. If this is an AMP
+	// payment, and this is the last shard, the outstanding shards together
+	// with this one will be enough for the receiver to derive all HTLC
+	// preimages. If this is a non-AMP payment, the ShardTracker will return a
+	// simple shard with the payment's static payment hash.
+	shard, err := p.shardTracker.NewShard(attemptID, lastShard)
+	if err != nil {
+		return lnwire.ShortChannelID{}, nil, nil, err
+	}
+
+	// If this shard carries MPP or AMP options, add them to the last hop
+	// on the route.
+	hop := rt.Hops[len(rt.Hops)-1]
+	if shard.MPP() != nil {
+		hop.MPP = shard.MPP()
+	}
+
+	if shard.AMP() != nil {
+		hop.AMP = shard.AMP()
+	}
+
 	// Generate the raw encoded sphinx packet to be included along
 	// with the htlcAdd message that we send directly to the
 	// switch.
-	onionBlob, _, err := generateSphinxPacket(
-		rt, p.paymentHash[:], sessionKey,
-	)
+	hash := shard.Hash()
+	onionBlob, _, err := generateSphinxPacket(rt, hash[:], sessionKey)
 	if err != nil {
 		return lnwire.ShortChannelID{}, nil, nil, err
 	}
@@ -607,7 +654,7 @@ func (p *shardHandler) createNewPaymentAttempt(rt *route.Route) (
 	htlcAdd := &lnwire.UpdateAddHTLC{
 		Amount:      rt.TotalAmount,
 		Expiry:      rt.TotalTimeLock,
-		PaymentHash: p.paymentHash,
+		PaymentHash: hash,
 	}
 	copy(htlcAdd.OnionBlob[:], onionBlob)
 
@@ -618,20 +665,15 @@ func (p *shardHandler) createNewPaymentAttempt(rt *route.Route) (
 		rt.Hops[0].ChannelID,
 	)
 
-	// We generate a new, unique payment ID that we will use for
-	// this HTLC.
-	attemptID, err := p.router.cfg.NextPaymentID()
-	if err != nil {
-		return lnwire.ShortChannelID{}, nil, nil, err
-	}
-
 	// We now have all the information needed to populate
 	// the current attempt information.
 	attempt := &channeldb.HTLCAttemptInfo{
-		AttemptID:   attemptID,
-		AttemptTime: p.router.cfg.Clock.Now(),
-		SessionKey:  sessionKey,
-		Route:       *rt,
+		AttemptID:       attemptID,
+		AttemptTime:     p.router.cfg.Clock.Now(),
+		SessionKey:      sessionKey,
+		Route:           *rt,
+		Hash:            &hash,
+		ShardAllocation: allocation,
 	}
 
 	return firstHop, htlcAdd, attempt, nil
@@ -643,7 +685,7 @@ func (p *shardHandler) sendPaymentAttempt(
 	htlcAdd *lnwire.UpdateAddHTLC) error {
 
 	log.Tracef("Attempting to send payment %v (pid=%v), "+
-		"using route: %v", p.paymentHash, attempt.AttemptID,
+		"using route: %v", p.identifier, attempt.AttemptID,
 		newLogClosure(func() string {
 			return spew.Sdump(attempt.Route)
 		}),
@@ -659,12 +701,12 @@ func (p *shardHandler) sendPaymentAttempt(
 	if err != nil {
 		log.Errorf("Failed sending attempt %d for payment "+
 			"%v to switch: %v", attempt.AttemptID,
-			p.paymentHash, err)
+			p.identifier, err)
 		return err
 	}
 
 	log.Debugf("Payment %v (pid=%v) successfully sent to switch, route: %v",
-		p.paymentHash, attempt.AttemptID, &attempt.Route)
+		p.identifier, attempt.AttemptID, &attempt.Route)
 
 	return nil
 }
@@ -684,9 +726,9 @@ func (p *shardHandler) handleSendError(attempt *channeldb.HTLCAttemptInfo,
 	}
 
 	log.Debugf("Payment %v failed: final_outcome=%v, raw_err=%v",
-		p.paymentHash, *reason, sendErr)
+		p.identifier, *reason, sendErr)
 
-	err := p.router.cfg.Control.Fail(p.paymentHash, *reason)
+	err := p.router.cfg.Control.Fail(p.identifier, *reason)
 	if err != nil {
 		return err
 	}
@@ -699,7 +741,14 @@ func (p *shardHandler) failAttempt(attempt *channeldb.HTLCAttemptInfo,
 	sendError error) (*channeldb.HTLCAttempt, error) {
 
 	log.Warnf("Attempt %v for payment %v failed: %v", attempt.AttemptID,
-		p.paymentHash, sendError)
+		p.identifier, sendError)
+
+	// Cancel the shard which corresponds to this HTLC attempt, such that
+	// the ShardTracker can return its share to the remaining amount of the
+	// payment.
+	if err := p.shardTracker.CancelShard(attempt.AttemptID); err != nil {
+		return nil, err
+	}
 
 	failInfo := marshallError(
 		sendError,
@@ -707,7 +756,7 @@ func (p *shardHandler) failAttempt(attempt *channeldb.HTLCAttemptInfo,
 	)
 
 	return p.router.cfg.Control.FailAttempt(
-		p.paymentHash, attempt.AttemptID,
+		p.identifier, attempt.AttemptID,
 		failInfo,
 	)
 }
@@ -2,7 +2,9 @@ package routing
 
 import (
 	"fmt"
+	"sort"
 
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrlnd/build"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/lnwire"
@@ -52,6 +54,11 @@ var (
 	// further split the payment if no route is found. It is the minimum
 	// amount that we use as the shard size when splitting.
 	DefaultShardMinAmt = lnwire.NewMAtomsFromAtoms(10000)
+
+	// maxShardCandidates is the maximum number of outgoing channels for
+	// which a candidate route is looked up when a split strategy needs
+	// them.
+	maxShardCandidates = 5
 )
 
 // Error returns the string representation of the noRouteError
@@ -114,12 +121,15 @@ type PaymentSession interface {
 	// fees. It can carry less if the payment is MPP. The activeShards
 	// argument should be set to instruct the payment session about the
 	// number of in flight HTLCS for the payment, such that it can choose
-	// splitting strategy accordingly.
+	// splitting strategy accordingly. If the amount was split, the
+	// allocation decision of the split strategy is returned along with the
+	// route.
 	//
 	// A noRouteError is returned if a non-critical error is encountered
 	// during path finding.
 	RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
-		activeShards, height uint32) (*route.Route, error)
+		activeShards, height uint32) (*route.Route,
+		*channeldb.ShardAllocation, error)
 }
 
 // paymentSession is used during an HTLC routings session to prune the local
@@ -166,7 +176,20 @@ func newPaymentSession(p *LightningPayment,
 	missionControl MissionController, pathFindingConfig PathFindingConfig) (
 	*paymentSession, error) {
 
-	edges, err := RouteHintsToEdges(p.RouteHints, p.Target)
+	// Payments to a blinded path reach the recipient through the hops of
+	// the path, which are added to the route hints.
+	routeHints := p.RouteHints
+	if p.BlindedPath != nil {
+		if len(p.BlindedPath.Hops) == 0 {
+			return nil, ErrInvalidBlindedPath
+		}
+
+		routeHints = append(
+			routeHints, blindedPathToRouteHint(p.BlindedPath),
+		)
+	}
+
+	edges, err := RouteHintsToEdges(routeHints, p.Target)
 	if err != nil {
 		return nil, err
 	}
@@ -196,10 +219,11 @@ func newPaymentSession(p *LightningPayment,
 // NOTE: This function is safe for concurrent access.
 // NOTE: Part of the PaymentSession interface.
 func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
-	activeShards, height uint32) (*route.Route, error) {
+	activeShards, height uint32) (*route.Route,
+	*channeldb.ShardAllocation, error) {
 
 	if p.empty {
-		return nil, errEmptyPaySession
+		return nil, nil, errEmptyPaySession
 	}
 
 	// Add BlockPadding to the finalCltvDelta so that the receiving node
@@ -230,6 +254,15 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 
 	finalHtlcExpiry := int32(height) + int32(finalCltvDelta)
 
+	splitStrategy := p.payment.SplitStrategy
+	if splitStrategy == nil {
+		splitStrategy = &HalvingSplitStrategy{}
+	}
+
+	// allocation is the decision of the split strategy that resulted in
+	// the current amount, if it was split.
+	var allocation *channeldb.ShardAllocation
+
 	for {
 		// We'll also obtain a set of bandwidthHints from the lower
 		// layer for each of our outbound channels. This will allow the
@@ -239,7 +272,7 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 		// attempt, because concurrent payments may change balances.
 		bandwidthHints, err := p.getBandwidthHints()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		p.log.Debugf("pathfinding for amt=%v", maxAmt)
@@ -247,7 +280,7 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 		// Get a routing graph.
 		routingGraph, cleanup, err := p.getRoutingGraph()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		sourceVertex := routingGraph.sourceNode()
@@ -270,12 +303,24 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 		switch {
 		case err == errNoPathFound:
 			// Don't split if this is a legacy payment without mpp
-			// record.
-			if p.payment.PaymentAddr == nil {
+			// record. Payments to blinded paths can always be
+			// split, as the recipient identifies the shards through
+			// the data it encrypted to itself.
+			if p.payment.PaymentAddr == nil &&
+				p.payment.BlindedPath == nil {
+
 				p.log.Debugf("not splitting because payment " +
 					"address is unspecified")
 
-				return nil, errNoPathFound
+				return nil, nil, errNoPathFound
+			}
+
+			// Trampoline nodes forward each htlc on its own, so
+			// trampoline payments are never split.
+			if p.payment.Trampoline != nil {
+				p.log.Debugf("not splitting trampoline payment")
+
+				return nil, nil, errNoPathFound
 			}
 
 			// No splitting if this is the last shard.
@@ -285,24 +330,46 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 					"limit %v has been reached",
 					p.payment.MaxParts)
 
-				return nil, errNoPathFound
+				return nil, nil, errNoPathFound
 			}
 
 			// This is where the magic happens. If we can't find a
-			// route, try it for half the amount.
-			maxAmt /= 2
+			// route, let the split strategy decide on a smaller
+			// amount.
+			split, err := splitStrategy.Split(
+				maxAmt, func() ([]*ShardCandidate, error) {
+					return p.findShardCandidates(
+						maxAmt, restrictions,
+						finalCltvDelta, height,
+					)
+				},
+			)
+			if err != nil {
+				return nil, nil, err
+			}
 
 			// Put a lower bound on the minimum shard size.
-			if maxAmt < p.minShardAmt {
+			if split.Amount < p.minShardAmt {
 				p.log.Debugf("not splitting because minimum "+
 					"shard amount %v has been reached",
 					p.minShardAmt)
 
-				return nil, errNoPathFound
+				return nil, nil, errNoPathFound
 			}
 
-			// Go pathfinding.
-			continue
+			p.log.Debugf("%v split strategy allocated %v of %v",
+				splitStrategy.Type(), split.Amount, maxAmt)
+
+			maxAmt = split.Amount
+			allocation = split.Allocation
+
+			// Go pathfinding if the strategy didn't pick a route
+			// for the shard.
+			if split.Candidate == nil {
+				continue
+			}
+
+			path = split.Candidate.path
 
 		// If there isn't enough local bandwidth, there is no point in
 		// splitting. It won't be possible to create a complete set in
@@ -312,10 +379,10 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 			p.log.Debug("not splitting because local balance " +
 				"is insufficient")
 
-			return nil, err
+			return nil, nil, err
 
 		case err != nil:
-			return nil, err
+			return nil, nil, err
 		}
 
 		// With the next candidate path found, we'll attempt to turn
@@ -331,10 +398,173 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 				paymentAddr: p.payment.PaymentAddr,
 			},
 		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if p.payment.BlindedPath != nil {
+			err := applyBlindedPath(
+				route, p.payment.BlindedPath, p.payment.Amount,
+			)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if p.payment.Trampoline != nil {
+			err := applyTrampoline(
+				route, p.payment.Trampoline,
+				p.payment.PaymentHash, height,
+			)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return route, allocation, err
+	}
+}
+
+// findShardCandidates looks up a route through each of the outgoing channels
+// with the most bandwidth, that carries as much of amt as the channel allows.
+func (p *paymentSession) findShardCandidates(amt lnwire.MilliAtom,
+	restrictions *RestrictParams, finalCltvDelta uint16,
+	height uint32) ([]*ShardCandidate, error) {
+
+	bandwidthHints, err := p.getBandwidthHints()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[uint64]struct{})
+	for _, chanID := range restrictions.OutgoingChannelIDs {
+		allowed[chanID] = struct{}{}
+	}
+
+	var chanIDs []uint64
+	for chanID, bandwidth := range bandwidthHints {
+		if bandwidth < p.minShardAmt {
+			continue
+		}
+
+		if _, ok := allowed[chanID]; len(allowed) > 0 && !ok {
+			continue
+		}
+
+		chanIDs = append(chanIDs, chanID)
+	}
+
+	// Consider the channels with the most bandwidth first.
+	sort.Slice(chanIDs, func(i, j int) bool {
+		return bandwidthHints[chanIDs[i]] > bandwidthHints[chanIDs[j]]
+	})
+	if len(chanIDs) > maxShardCandidates {
+		chanIDs = chanIDs[:maxShardCandidates]
+	}
+
+	routingGraph, cleanup, err := p.getRoutingGraph()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	sourceVertex := routingGraph.sourceNode()
+	finalHtlcExpiry := int32(height) + int32(finalCltvDelta)
+
+	var candidates []*ShardCandidate
+	for _, chanID := range chanIDs {
+		candidateAmt := amt
+		if bandwidthHints[chanID] < candidateAmt {
+			candidateAmt = bandwidthHints[chanID]
+		}
+
+		chanRestrictions := *restrictions
+		chanRestrictions.OutgoingChannelIDs = []uint64{chanID}
+
+		path, err := p.pathFinder(
+			&graphParams{
+				additionalEdges: p.additionalEdges,
+				bandwidthHints:  bandwidthHints,
+				graph:           routingGraph,
+			},
+			&chanRestrictions, &p.pathFindingConfig,
+			sourceVertex, p.payment.Target,
+			candidateAmt, finalHtlcExpiry,
+		)
+		switch {
+		case err == errNoPathFound, err == errInsufficientBalance:
+			continue
+
+		case err != nil:
+			return nil, err
+		}
+
+		rt, err := newRoute(
+			sourceVertex, path, height,
+			finalHopParams{
+				amt:         candidateAmt,
+				totalAmt:    p.payment.Amount,
+				cltvDelta:   finalCltvDelta,
+				records:     p.payment.DestCustomRecords,
+				paymentAddr: p.payment.PaymentAddr,
+			},
+		)
 		if err != nil {
 			return nil, err
 		}
 
-		return route, err
+		// The probability of the route is the product of the
+		// probabilities of its channels for the amount they carry.
+		probability := 1.0
+		fromNode, hopAmt := sourceVertex, rt.TotalAmount
+		for _, hop := range rt.Hops {
+			capacity, err := channelCapacity(
+				routingGraph, fromNode, hop.ChannelID,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			probability *= p.missionControl.GetProbability(
+				fromNode, hop.PubKeyBytes, hopAmt, capacity,
+			)
+			fromNode, hopAmt = hop.PubKeyBytes, hop.AmtToForward
+		}
+
+		candidates = append(candidates, &ShardCandidate{
+			ChannelID:   chanID,
+			Amount:      candidateAmt,
+			Fee:         rt.TotalFees(),
+			Probability: probability,
+			path:        path,
+		})
+	}
+
+	p.log.Debugf("found %v shard candidates for amt=%v", len(candidates),
+		amt)
+
+	return candidates, nil
+}
+
+// channelCapacity returns the capacity of the given channel of a node. Zero is
+// returned for channels that aren't part of the graph, such as the ones of
+// route hints.
+func channelCapacity(g routingGraph, node route.Vertex,
+	chanID uint64) (dcrutil.Amount, error) {
+
+	var capacity dcrutil.Amount
+	err := g.forEachNodeChannel(node, func(info *channeldb.ChannelEdgeInfo,
+		_, _ *channeldb.ChannelEdgePolicy) error {
+
+		if info.ChannelID == chanID {
+			capacity = info.Capacity
+		}
+
+		return nil
+	})
+	if err == channeldb.ErrGraphNodeNotFound {
+		return 0, nil
 	}
+
+	return capacity, err
 }
@@ -1,6 +1,8 @@
 package routing
 
 import (
+	"sync"
+
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/lnwire"
@@ -38,6 +40,10 @@ type SessionSource struct {
 	// PathFindingConfig defines global parameters that control the
 	// trade-off in path finding between fees and probabiity.
 	PathFindingConfig PathFindingConfig
+
+	// pathFindingMtx guards PathFindingConfig, which may be changed at
+	// runtime.
+	pathFindingMtx sync.RWMutex
 }
 
 // getRoutingGraph returns a routing graph and a clean-up function for
@@ -73,9 +79,13 @@ func (m *SessionSource) NewPaymentSession(p *LightningPayment) (
 		return generateBandwidthHints(sourceNode, m.QueryBandwidth)
 	}
 
+	m.pathFindingMtx.RLock()
+	pathFindingConfig := m.PathFindingConfig
+	m.pathFindingMtx.RUnlock()
+
 	session, err := newPaymentSession(
 		p, getBandwidthHints, m.getRoutingGraph,
-		m.MissionControl, m.PathFindingConfig,
+		m.MissionControl, pathFindingConfig,
 	)
 	if err != nil {
 		return nil, err
@@ -93,6 +103,15 @@ func (m *SessionSource) NewPaymentSessionEmpty() PaymentSession {
 	}
 }
 
+// SetPaymentAttemptPenalty sets the payment attempt penalty that is used in
+// path finding by payment sessions that are created from now on.
+func (m *SessionSource) SetPaymentAttemptPenalty(penalty lnwire.MilliAtom) {
+	m.pathFindingMtx.Lock()
+	defer m.pathFindingMtx.Unlock()
+
+	m.PathFindingConfig.PaymentAttemptPenalty = penalty
+}
+
 // RouteHintsToEdges converts a list of invoice route hints to an edge map that
 // can be passed into pathfinding.
 func RouteHintsToEdges(routeHints [][]zpay32.HopHint, target route.Vertex) (
@@ -65,7 +65,7 @@ func TestRequestRoute(t *testing.T) {
 		return path, nil
 	}
 
-	route, err := session.RequestRoute(
+	route, _, err := session.RequestRoute(
 		payment.Amount, payment.FeeLimit, 0, height,
 	)
 	if err != nil {
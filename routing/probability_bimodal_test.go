@@ -0,0 +1,327 @@
+package routing
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	smallAmount  = lnwire.MilliAtom(400_000)
+	largeAmount  = lnwire.MilliAtom(5_000_000)
+	chanCapacity = lnwire.MilliAtom(10_000_000)
+	scale        = lnwire.MilliAtom(400_000)
+)
+
+// newTestBimodalEstimator creates a bimodal estimator with the test scale.
+func newTestBimodalEstimator(t *testing.T) *BimodalEstimator {
+	estimator, err := NewBimodalEstimator(BimodalConfig{
+		BimodalScaleMAtoms: scale,
+		BimodalNodeWeight:  0.2,
+		BimodalDecayTime:   time.Hour,
+	})
+	require.NoError(t, err)
+
+	return estimator
+}
+
+// TestSuccessProbability tests that we get correct probability estimates for
+// the direct channel probability.
+func TestSuccessProbability(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		expectedProbability float64
+		tolerance           float64
+		successAmount       lnwire.MilliAtom
+		failAmount          lnwire.MilliAtom
+		amount              lnwire.MilliAtom
+		capacity            lnwire.MilliAtom
+	}{
+		// We can't send more than the capacity.
+		{
+			name:                "no info, larger than capacity",
+			capacity:            chanCapacity,
+			successAmount:       0,
+			failAmount:          chanCapacity,
+			amount:              chanCapacity + 1,
+			expectedProbability: 0.0,
+		},
+		// With the current model we don't prefer any channels if the
+		// send amount is large compared to the scale but small compared
+		// to the capacity.
+		{
+			name:                "no info, large amount",
+			capacity:            chanCapacity,
+			successAmount:       0,
+			failAmount:          chanCapacity,
+			amount:              largeAmount,
+			expectedProbability: 0.5,
+		},
+		// We always expect to be able to "send" an amount of 0.
+		{
+			name:                "no info, zero amount",
+			capacity:            chanCapacity,
+			successAmount:       0,
+			failAmount:          chanCapacity,
+			amount:              0,
+			expectedProbability: 1.0,
+		},
+		// For a small amount that is much smaller than the scale we
+		// expect the probability to be close to one.
+		{
+			name:                "no info, small amount",
+			capacity:            chanCapacity,
+			successAmount:       0,
+			failAmount:          chanCapacity,
+			amount:              smallAmount,
+			expectedProbability: 0.684,
+			tolerance:           0.001,
+		},
+		// We had an unsettled success, so we should be able to send a
+		// lower amount.
+		{
+			name:                "previous success, lower amount",
+			capacity:            chanCapacity,
+			successAmount:       largeAmount,
+			failAmount:          chanCapacity,
+			amount:              smallAmount,
+			expectedProbability: 1.0,
+		},
+		// We had an unsettled success, so we should be able to send
+		// the same amount.
+		{
+			name:                "previous success, success amount",
+			capacity:            chanCapacity,
+			successAmount:       largeAmount,
+			failAmount:          chanCapacity,
+			amount:              largeAmount,
+			expectedProbability: 1.0,
+		},
+		// We had an unsettled success with a small amount, we know
+		// with increased probability that we can send a comparable
+		// higher amount.
+		{
+			name:                "previous success, larger amount",
+			capacity:            chanCapacity,
+			successAmount:       smallAmount / 2,
+			failAmount:          chanCapacity,
+			amount:              smallAmount,
+			expectedProbability: 0.851,
+			tolerance:           0.001,
+		},
+		// We had a previous failure, which makes it impossible to send
+		// a larger amount.
+		{
+			name:                "previous failure, larger amount",
+			capacity:            chanCapacity,
+			successAmount:       0,
+			failAmount:          largeAmount,
+			amount:              largeAmount + smallAmount,
+			expectedProbability: 0.0,
+		},
+		// A success amount that is larger than the fail amount is not
+		// consistent and leads to a zero probability.
+		{
+			name:                "inconsistent success and fail amounts",
+			capacity:            chanCapacity,
+			successAmount:       largeAmount,
+			failAmount:          smallAmount,
+			amount:              smallAmount,
+			expectedProbability: 0.0,
+		},
+		// A zero capacity is used as a sentinel value for an unknown
+		// capacity, we expect full probability.
+		{
+			name:                "unknown capacity",
+			capacity:            0,
+			successAmount:       0,
+			failAmount:          0,
+			amount:              largeAmount,
+			expectedProbability: 1.0,
+		},
+	}
+
+	estimator := newTestBimodalEstimator(t)
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := estimator.probabilityFormula(
+				test.capacity, test.successAmount,
+				test.failAmount, test.amount,
+			)
+			require.NoError(t, err)
+			require.InDelta(
+				t, test.expectedProbability, p, test.tolerance,
+			)
+		})
+	}
+}
+
+// TestIntegral tests certain limits of the probability distribution integral.
+func TestIntegral(t *testing.T) {
+	t.Parallel()
+
+	estimator := newTestBimodalEstimator(t)
+	c := float64(chanCapacity)
+
+	// The full integral over the distribution is normalized.
+	require.InDelta(t, 1.0, estimator.integral(c, 0, c), 1e-9)
+
+	// Due to the symmetry of the distribution, half of the probability
+	// mass is found in each half of the channel.
+	require.InDelta(t, 0.5, estimator.integral(c, 0, c/2), 1e-9)
+
+	// Nonsensical limits result in a zero integral.
+	require.Zero(t, estimator.integral(c, c/2, 0))
+}
+
+// TestCanSendCannotSend tests the time decay of the sendable and unsendable
+// amounts.
+func TestCanSendCannotSend(t *testing.T) {
+	t.Parallel()
+
+	decay := time.Hour
+	now := testTime
+
+	// Directly after a success, we can send the full amount.
+	require.Equal(t, largeAmount, canSend(largeAmount, now, now, decay))
+
+	// After a long time, we forget about the success.
+	require.Zero(t, canSend(largeAmount, now.Add(100*decay), now, decay))
+
+	// Directly after a failure, we can't send the failed amount.
+	require.Equal(
+		t, largeAmount, cannotSend(largeAmount, chanCapacity, now, now,
+			decay),
+	)
+
+	// After a long time, we assume that only the capacity can't be sent.
+	require.Equal(
+		t, chanCapacity, cannotSend(largeAmount, chanCapacity,
+			now.Add(100*decay), now, decay),
+	)
+
+	// A failure amount larger than the capacity is capped.
+	require.Equal(
+		t, chanCapacity, cannotSend(2*chanCapacity, chanCapacity, now,
+			now, decay),
+	)
+}
+
+// TestBimodalLocalPairProbability tests the probability estimation for local
+// channels.
+func TestBimodalLocalPairProbability(t *testing.T) {
+	t.Parallel()
+
+	estimator := newTestBimodalEstimator(t)
+	toNode := route.Vertex{10}
+
+	// Without any results, we assume local channels to be usable.
+	p := estimator.LocalPairProbability(testTime, NodeResults{}, toNode)
+	require.Equal(t, 1.0, p)
+
+	// Directly after a failure, the probability is zero.
+	results := NodeResults{
+		toNode: TimedPairResult{
+			FailTime: testTime,
+			FailAmt:  smallAmount,
+		},
+	}
+	p = estimator.LocalPairProbability(testTime, results, toNode)
+	require.Zero(t, p)
+
+	// After the decay time, the probability recovers.
+	p = estimator.LocalPairProbability(
+		testTime.Add(time.Hour), results, toNode,
+	)
+	require.InDelta(t, 1-math.Exp(-1), p, 1e-9)
+}
+
+// TestBimodalPairProbability tests that results on other channels of the
+// forwarding node are taken into account when there is no recent direct
+// information.
+func TestBimodalPairProbability(t *testing.T) {
+	t.Parallel()
+
+	estimator := newTestBimodalEstimator(t)
+	capacityAtoms := chanCapacity.ToAtoms()
+
+	var (
+		toNode    = route.Vertex{10}
+		otherNode = route.Vertex{11}
+	)
+
+	noInfo := estimator.PairProbability(
+		testTime, NodeResults{}, toNode, largeAmount, capacityAtoms,
+	)
+	require.InDelta(t, 0.5, noInfo, 1e-9)
+
+	// A recent success on another channel of the node increases the
+	// probability.
+	results := NodeResults{
+		otherNode: TimedPairResult{
+			SuccessTime: testTime,
+			SuccessAmt:  largeAmount,
+		},
+	}
+	p := estimator.PairProbability(
+		testTime, results, toNode, largeAmount, capacityAtoms,
+	)
+	require.Greater(t, p, noInfo)
+
+	// A recent failure on another channel of the node decreases the
+	// probability.
+	results = NodeResults{
+		otherNode: TimedPairResult{
+			FailTime: testTime,
+			FailAmt:  largeAmount,
+		},
+	}
+	p = estimator.PairProbability(
+		testTime, results, toNode, largeAmount, capacityAtoms,
+	)
+	require.Less(t, p, noInfo)
+
+	// Recent direct information takes precedence over the results on
+	// other channels.
+	results[toNode] = TimedPairResult{
+		SuccessTime: testTime,
+		SuccessAmt:  largeAmount,
+	}
+	p = estimator.PairProbability(
+		testTime, results, toNode, largeAmount, capacityAtoms,
+	)
+	require.Equal(t, 1.0, p)
+}
+
+// TestBimodalConfigValidation asserts that invalid estimator parameters are
+// rejected.
+func TestBimodalConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultBimodalConfig()
+	require.NoError(t, cfg.validate())
+
+	invalid := cfg
+	invalid.BimodalScaleMAtoms = 0
+	require.True(t, errors.Is(invalid.validate(), ErrInvalidScale))
+
+	invalid = cfg
+	invalid.BimodalNodeWeight = 1.1
+	require.True(t, errors.Is(invalid.validate(), ErrInvalidNodeWeight))
+
+	invalid = cfg
+	invalid.BimodalDecayTime = 0
+	require.True(t, errors.Is(invalid.validate(), ErrInvalidDecayTime))
+}
@@ -1,54 +1,157 @@
 package routing
 
 import (
+	"errors"
 	"math"
 	"time"
 
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/routing/route"
 )
 
-// probabilityEstimator returns node and pair probabilities based on historical
-// payment results.
-type probabilityEstimator struct {
-	// penaltyHalfLife defines after how much time a penalized node or
+const (
+	// AprioriEstimatorName is the name of the apriori probability
+	// estimator.
+	AprioriEstimatorName = "apriori"
+)
+
+var (
+	// ErrInvalidHalflife is returned when we get an invalid half life.
+	ErrInvalidHalflife = errors.New("penalty half life must be >= 0")
+
+	// ErrInvalidHopProbability is returned when we get an invalid hop
+	// probability.
+	ErrInvalidHopProbability = errors.New("hop probability must be in " +
+		"[0, 1]")
+
+	// ErrInvalidAprioriWeight is returned when we get an apriori weight
+	// that is out of range.
+	ErrInvalidAprioriWeight = errors.New("apriori weight must be in [0, 1]")
+)
+
+// Estimator estimates the success probability of traversing a node pair based
+// on the historical payment results that mission control collected. Mission
+// control dispatches all probability queries to the configured estimator.
+type Estimator interface {
+	// PairProbability estimates the probability of successfully traversing
+	// to toNode based on historical payment outcomes for the from node.
+	// Those outcomes are passed in via the results parameter. The capacity
+	// of the channel may be zero if it is unknown.
+	PairProbability(now time.Time, results NodeResults,
+		toNode route.Vertex, amt lnwire.MilliAtom,
+		capacity dcrutil.Amount) float64
+
+	// LocalPairProbability estimates the probability of successfully
+	// traversing our own local channels to toNode.
+	LocalPairProbability(now time.Time, results NodeResults,
+		toNode route.Vertex) float64
+
+	// String returns the name of the estimator.
+	String() string
+}
+
+// AprioriConfig contains the parameters of the apriori probability estimator.
+type AprioriConfig struct {
+	// PenaltyHalfLife defines after how much time a penalized node or
 	// channel is back at 50% probability.
-	penaltyHalfLife time.Duration
+	PenaltyHalfLife time.Duration
 
-	// aprioriHopProbability is the assumed success probability of a hop in
+	// AprioriHopProbability is the assumed success probability of a hop in
 	// a route when no other information is available.
-	aprioriHopProbability float64
+	AprioriHopProbability float64
 
-	// aprioriWeight is a value in the range [0, 1] that defines to what
+	// AprioriWeight is a value in the range [0, 1] that defines to what
 	// extent historical results should be extrapolated to untried
 	// connections. Setting it to one will completely ignore historical
 	// results and always assume the configured a priori probability for
 	// untried connections. A value of zero will ignore the a priori
 	// probability completely and only base the probability on historical
 	// results, unless there are none available.
-	aprioriWeight float64
+	AprioriWeight float64
+}
+
+// validate checks the configuration of the estimator for allowed values.
+func (c AprioriConfig) validate() error {
+	if c.PenaltyHalfLife < 0 {
+		return ErrInvalidHalflife
+	}
+
+	if c.AprioriHopProbability < 0 || c.AprioriHopProbability > 1 {
+		return ErrInvalidHopProbability
+	}
+
+	if c.AprioriWeight < 0 || c.AprioriWeight > 1 {
+		return ErrInvalidAprioriWeight
+	}
+
+	return nil
+}
+
+// DefaultAprioriConfig returns the default configuration for the apriori
+// estimator.
+func DefaultAprioriConfig() AprioriConfig {
+	return AprioriConfig{
+		PenaltyHalfLife:       DefaultPenaltyHalfLife,
+		AprioriHopProbability: DefaultAprioriHopProbability,
+		AprioriWeight:         DefaultAprioriWeight,
+	}
+}
+
+// AprioriEstimator returns node and pair probabilities based on historical
+// payment results. Untried connections are assumed to succeed with the a
+// priori hop probability, connections that relayed a previous attempt are
+// assumed to succeed with a high probability and failed connections are
+// penalized with a penalty that decays over time.
+type AprioriEstimator struct {
+	// AprioriConfig contains the parameters of the estimator.
+	AprioriConfig
 
 	// prevSuccessProbability is the assumed probability for node pairs that
 	// successfully relayed the previous attempt.
 	prevSuccessProbability float64
 }
 
+// NewAprioriEstimator creates a new apriori estimator from the given
+// configuration.
+func NewAprioriEstimator(cfg AprioriConfig) (*AprioriEstimator, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &AprioriEstimator{
+		AprioriConfig:          cfg,
+		prevSuccessProbability: prevSuccessProbability,
+	}, nil
+}
+
+// A compile-time check to ensure AprioriEstimator implements the Estimator
+// interface.
+var _ Estimator = (*AprioriEstimator)(nil)
+
+// String returns the name of the estimator.
+//
+// NOTE: This is part of the Estimator interface.
+func (p *AprioriEstimator) String() string {
+	return AprioriEstimatorName
+}
+
 // getNodeProbability calculates the probability for connections from a node
 // that have not been tried before. The results parameter is a list of last
 // payment results for that node.
-func (p *probabilityEstimator) getNodeProbability(now time.Time,
+func (p *AprioriEstimator) getNodeProbability(now time.Time,
 	results NodeResults, amt lnwire.MilliAtom) float64 {
 
 	// If the channel history is not to be taken into account, we can return
 	// early here with the configured a priori probability.
-	if p.aprioriWeight == 1 {
-		return p.aprioriHopProbability
+	if p.AprioriWeight == 1 {
+		return p.AprioriHopProbability
 	}
 
 	// If there is no channel history, our best estimate is still the a
 	// priori probability.
 	if len(results) == 0 {
-		return p.aprioriHopProbability
+		return p.AprioriHopProbability
 	}
 
 	// The value of the apriori weight is in the range [0, 1]. Convert it to
@@ -58,7 +161,7 @@ func (p *probabilityEstimator) getNodeProbability(now time.Time,
 	// the weighted average calculation below. When the apriori weight
 	// approaches 1, the apriori factor goes to infinity. It will heavily
 	// outweigh any observations that have been collected.
-	aprioriFactor := 1/(1-p.aprioriWeight) - 1
+	aprioriFactor := 1/(1-p.AprioriWeight) - 1
 
 	// Calculate a weighted average consisting of the apriori probability
 	// and historical observations. This is the part that incentivizes nodes
@@ -76,7 +179,7 @@ func (p *probabilityEstimator) getNodeProbability(now time.Time,
 	// effectively prunes all channels of the node forever. This is the most
 	// aggressive way in which we can penalize nodes and unlikely to yield
 	// good results in a real network.
-	probabilitiesTotal := p.aprioriHopProbability * aprioriFactor
+	probabilitiesTotal := p.AprioriHopProbability * aprioriFactor
 	totalWeight := aprioriFactor
 
 	for _, result := range results {
@@ -104,18 +207,21 @@ func (p *probabilityEstimator) getNodeProbability(now time.Time,
 // getWeight calculates a weight in the range [0, 1] that should be assigned to
 // a payment result. Weight follows an exponential curve that starts at 1 when
 // the result is fresh and asymptotically approaches zero over time. The rate at
-// which this happens is controlled by the penaltyHalfLife parameter.
-func (p *probabilityEstimator) getWeight(age time.Duration) float64 {
-	exp := -age.Hours() / p.penaltyHalfLife.Hours()
+// which this happens is controlled by the PenaltyHalfLife parameter.
+func (p *AprioriEstimator) getWeight(age time.Duration) float64 {
+	exp := -age.Hours() / p.PenaltyHalfLife.Hours()
 	return math.Pow(2, exp)
 }
 
-// getPairProbability estimates the probability of successfully traversing to
+// PairProbability estimates the probability of successfully traversing to
 // toNode based on historical payment outcomes for the from node. Those outcomes
-// are passed in via the results parameter.
-func (p *probabilityEstimator) getPairProbability(
-	now time.Time, results NodeResults,
-	toNode route.Vertex, amt lnwire.MilliAtom) float64 {
+// are passed in via the results parameter. The capacity of the channel is not
+// taken into account by this estimator.
+//
+// NOTE: This is part of the Estimator interface.
+func (p *AprioriEstimator) PairProbability(now time.Time,
+	results NodeResults, toNode route.Vertex, amt lnwire.MilliAtom,
+	capacity dcrutil.Amount) float64 {
 
 	nodeProbability := p.getNodeProbability(now, results, amt)
 
@@ -124,9 +230,11 @@ func (p *probabilityEstimator) getPairProbability(
 	)
 }
 
-// getLocalPairProbability estimates the probability of successfully traversing
+// LocalPairProbability estimates the probability of successfully traversing
 // our own local channels to toNode.
-func (p *probabilityEstimator) getLocalPairProbability(
+//
+// NOTE: This is part of the Estimator interface.
+func (p *AprioriEstimator) LocalPairProbability(
 	now time.Time, results NodeResults, toNode route.Vertex) float64 {
 
 	// For local channels that have never been tried before, we assume them
@@ -142,7 +250,7 @@ func (p *probabilityEstimator) getLocalPairProbability(
 
 // calculateProbability estimates the probability of successfully traversing to
 // toNode based on historical payment outcomes and a fall-back node probability.
-func (p *probabilityEstimator) calculateProbability(
+func (p *AprioriEstimator) calculateProbability(
 	now time.Time, results NodeResults,
 	nodeProbability float64, toNode route.Vertex,
 	amt lnwire.MilliAtom) float64 {
@@ -0,0 +1,193 @@
+package routing
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+const (
+	// DefaultMaxProbes is the default number of probes that are sent out
+	// to find the largest amount that reaches a destination.
+	DefaultMaxProbes = 10
+
+	// probePrecisionDivisor determines the precision of the search for the
+	// largest reachable amount. The search stops once the gap between the
+	// largest amount known to reach the destination and the smallest
+	// amount known not to reach it is below the probed amount divided by
+	// this value.
+	probePrecisionDivisor = 100
+
+	// minProbePrecision is the smallest gap between reachable and
+	// unreachable amounts that is worth probing.
+	minProbePrecision = lnwire.MilliAtom(1000)
+)
+
+// ProbeResult is the outcome of probing a destination.
+type ProbeResult struct {
+	// Amount is the largest amount that was found to reach the
+	// destination. It is zero if no probe reached the destination.
+	Amount lnwire.MilliAtom
+
+	// Route is the route over which Amount reached the destination.
+	Route *route.Route
+
+	// FailureReason is the reason the probe for the full amount of the
+	// payment failed. It is nil if the full amount reached the
+	// destination.
+	FailureReason *channeldb.FailureReason
+
+	// NumProbes is the number of probes that were sent out.
+	NumProbes uint32
+}
+
+// ProbePayment probes the destination of the passed payment without risking
+// any funds. Every probe runs the regular payment lifecycle using a random
+// payment hash that the destination can't know the preimage of, so that the
+// HTLCs are failed back by the destination once they reach it. The outcome of
+// each attempt is reported to mission control. If the full amount of the
+// payment doesn't reach the destination, smaller amounts are probed to find
+// the largest amount that does, sending out at most maxProbes probes in
+// total. The payment hash of the passed payment is ignored.
+func (r *ChannelRouter) ProbePayment(payment *LightningPayment,
+	maxProbes uint32) (*ProbeResult, error) {
+
+	if payment.AMP != nil {
+		return nil, errors.New("amp payments can't be probed")
+	}
+	if payment.Amount == 0 {
+		return nil, errors.New("amount to probe must be positive")
+	}
+	if maxProbes == 0 {
+		maxProbes = DefaultMaxProbes
+	}
+
+	precision := payment.Amount / probePrecisionDivisor
+	if precision < minProbePrecision {
+		precision = minProbePrecision
+	}
+
+	var (
+		result = &ProbeResult{}
+
+		// reachable is the largest amount known to reach the
+		// destination, unreachable the smallest one known not to.
+		reachable   lnwire.MilliAtom
+		unreachable = payment.Amount + 1
+		amt         = payment.Amount
+	)
+	for result.NumProbes < maxProbes {
+		rt, reason, err := r.probeAmount(payment, amt)
+		if err != nil {
+			return nil, err
+		}
+		result.NumProbes++
+
+		if rt != nil {
+			reachable = amt
+			result.Amount = amt
+			result.Route = rt
+		} else {
+			unreachable = amt
+			if result.FailureReason == nil {
+				result.FailureReason = reason
+			}
+		}
+
+		// Stop once we've narrowed down the largest reachable amount
+		// far enough.
+		if unreachable-reachable <= precision {
+			break
+		}
+
+		amt = reachable + (unreachable-reachable)/2
+	}
+
+	log.Debugf("Probed %v with %v probes, largest reachable amount: %v",
+		payment.Target, result.NumProbes, result.Amount)
+
+	return result, nil
+}
+
+// probeAmount sends a single probe for the given amount to the destination of
+// the payment. If the probe reaches the destination, the route it took is
+// returned. Otherwise the reason the probe failed is returned.
+func (r *ChannelRouter) probeAmount(payment *LightningPayment,
+	amt lnwire.MilliAtom) (*route.Route, *channeldb.FailureReason, error) {
+
+	var hash lntypes.Hash
+	if _, err := rand.Read(hash[:]); err != nil {
+		return nil, nil, err
+	}
+
+	// A probe is sent as a single shard, so that the route that reached
+	// the destination carries the full amount. The payment request isn't
+	// stored with the probe, as it isn't an attempt to pay the invoice.
+	probe := *payment
+	probe.Amount = amt
+	probe.PaymentHash = hash
+	probe.MaxParts = 1
+	probe.PaymentRequest = nil
+
+	_, _, err := r.SendPayment(&probe)
+	switch reason := err.(type) {
+	case nil:
+		// The destination can't possibly know the preimage.
+		return nil, nil, errors.New("probe unexpectedly settled")
+
+	case channeldb.FailureReason:
+		// The probe isn't a payment of the user, so its payment record
+		// is deleted once we're done with it.
+		defer r.deleteProbe(hash)
+
+		if reason != channeldb.FailureReasonPaymentDetails {
+			return nil, &reason, nil
+		}
+
+	default:
+		return nil, nil, err
+	}
+
+	// The destination rejected the payment details, so the probe reached
+	// it. Look up the attempt that was failed by the destination.
+	p, err := r.cfg.Control.FetchPayment(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, htlc := range p.HTLCs {
+		if htlc.Failure == nil {
+			continue
+		}
+
+		finalIdx := uint32(len(htlc.Route.Hops))
+		if htlc.Failure.FailureSourceIndex != finalIdx {
+			continue
+		}
+
+		switch htlc.Failure.Message.(type) {
+		case *lnwire.FailIncorrectDetails,
+			*lnwire.FailIncorrectPaymentAmount:
+
+			rt := htlc.Route
+			return &rt, nil, nil
+		}
+	}
+
+	// The payment details were rejected for another reason, for example
+	// because the final cltv delta was too small.
+	reason := channeldb.FailureReasonPaymentDetails
+	return nil, &reason, nil
+}
+
+// deleteProbe deletes the payment record that the probe with the given payment
+// hash left behind.
+func (r *ChannelRouter) deleteProbe(hash lntypes.Hash) {
+	if err := r.cfg.Control.DeletePayment(hash, false); err != nil {
+		log.Errorf("Unable to delete probe %v: %v", hash, err)
+	}
+}
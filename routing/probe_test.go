@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProbePayment tests that probes that reach the destination are reported
+// as successful, and that the router searches for a smaller amount when the
+// full amount doesn't reach the destination.
+func TestProbePayment(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	require.NoError(t, err)
+	defer cleanUp()
+
+	paymentAmt := lnwire.NewMAtomsFromAtoms(1000)
+	payment := &LightningPayment{
+		Target:   ctx.aliases["sophon"],
+		Amount:   paymentAmt,
+		FeeLimit: noFeeLimit,
+	}
+
+	// Let every probe be rejected by the destination, as it doesn't know
+	// the payment hash.
+	payer := ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher)
+	payer.setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			return [32]byte{}, htlcswitch.NewForwardingError(
+				lnwire.NewFailIncorrectDetails(paymentAmt, 0), 2,
+			)
+		},
+	)
+
+	result, err := ctx.router.ProbePayment(payment, 0)
+	require.NoError(t, err)
+	require.Equal(t, paymentAmt, result.Amount)
+	require.Nil(t, result.FailureReason)
+	require.Equal(t, uint32(1), result.NumProbes)
+	require.NotNil(t, result.Route)
+	require.Equal(t, paymentAmt, result.Route.ReceiverAmt())
+	require.Equal(
+		t, ctx.aliases["sophon"],
+		result.Route.Hops[len(result.Route.Hops)-1].PubKeyBytes,
+	)
+
+	// The probes must not leave any payments behind.
+	control := ctx.router.cfg.Control.(*mockControlTower)
+	require.Empty(t, control.payments)
+
+	// Now let every probe fail at the first hop, so that no amount reaches
+	// the destination. The router should give up after the maximum number
+	// of probes.
+	payer.setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			return [32]byte{}, htlcswitch.NewForwardingError(
+				&lnwire.FailUnknownNextPeer{}, 1,
+			)
+		},
+	)
+
+	const maxProbes = 3
+	result, err = ctx.router.ProbePayment(payment, maxProbes)
+	require.NoError(t, err)
+	require.Zero(t, result.Amount)
+	require.Nil(t, result.Route)
+	require.Equal(t, uint32(maxProbes), result.NumProbes)
+	require.NotNil(t, result.FailureReason)
+	require.Equal(t, channeldb.FailureReasonNoRoute, *result.FailureReason)
+	require.Empty(t, control.payments)
+}
@@ -38,6 +38,16 @@ var (
 	// ErrAMPMissingMPP is returned when the caller tries to attach an AMP
 	// record but no MPP record is presented for the final hop.
 	ErrAMPMissingMPP = errors.New("cannot send AMP without MPP record")
+
+	// ErrBlindedHopMPP is returned when the caller tries to attach an MPP
+	// or AMP record to a hop within a blinded route.
+	ErrBlindedHopMPP = errors.New("cannot send MPP or AMP to blinded hop")
+
+	// ErrIntermediateTrampolineHop is returned when the caller tries to
+	// attach a trampoline onion to an intermediate hop, only final hops can
+	// act as trampoline nodes.
+	ErrIntermediateTrampolineHop = errors.New("cannot send trampoline " +
+		"onion to intermediate")
 )
 
 // Vertex is a simple alias for the serialization of a compressed Bitcoin
@@ -119,6 +129,23 @@ type Hop struct {
 	// only be set for the final hop.
 	AMP *record.AMP
 
+	// EncryptedData is the data encrypted to this hop by the creator of a
+	// blinded route. It is only set for hops within a blinded route.
+	EncryptedData []byte
+
+	// BlindingPoint is the blinding point of a blinded route. It is only
+	// set for the introduction node of the blinded route.
+	BlindingPoint *secp256k1.PublicKey
+
+	// TotalAmtMAtoms is the total amount of a payment to a blinded route.
+	// It is only set for the final hop of a blinded route.
+	TotalAmtMAtoms lnwire.MilliAtom
+
+	// TrampolineOnion is the onion packet handed to the final hop when it
+	// acts as a trampoline node, forwarding the payment to the actual
+	// destination.
+	TrampolineOnion []byte
+
 	// CustomRecords if non-nil are a set of additional TLV records that
 	// should be included in the forwarding instructions for this node.
 	CustomRecords record.CustomSet
@@ -161,6 +188,11 @@ func (h *Hop) PackHopPayload(w io.Writer, nextChanID uint64) error {
 			"payloads")
 	}
 
+	// Hops within a blinded route follow their own set of rules.
+	if h.EncryptedData != nil {
+		return h.packBlindedHopPayload(w, nextChanID)
+	}
+
 	// Otherwise, we'll need to make a new stream that includes our
 	// required routing fields, as well as these optional values.
 	var records []tlv.Record
@@ -205,6 +237,17 @@ func (h *Hop) PackHopPayload(w io.Writer, nextChanID uint64) error {
 		}
 	}
 
+	// A trampoline onion can only be delivered to the final hop.
+	if h.TrampolineOnion != nil {
+		if nextChanID != 0 {
+			return ErrIntermediateTrampolineHop
+		}
+
+		records = append(records,
+			record.NewTrampolineOnionRecord(&h.TrampolineOnion),
+		)
+	}
+
 	// Append any custom types destined for this hop.
 	tlvRecords := tlv.MapToRecords(h.CustomRecords)
 	records = append(records, tlvRecords...)
@@ -221,6 +264,50 @@ func (h *Hop) PackHopPayload(w io.Writer, nextChanID uint64) error {
 	return tlvStream.Encode(w)
 }
 
+// packBlindedHopPayload writes the payload of a hop within a blinded route to
+// the passed io.Writer. Intermediate hops find their forwarding instructions in
+// the encrypted data, so only the final hop is given the amount, expiry and
+// total amount of the payment.
+func (h *Hop) packBlindedHopPayload(w io.Writer, nextChanID uint64) error {
+	if h.MPP != nil || h.AMP != nil {
+		return ErrBlindedHopMPP
+	}
+
+	records := []tlv.Record{
+		record.NewEncryptedDataRecord(&h.EncryptedData),
+	}
+
+	if h.BlindingPoint != nil {
+		records = append(records,
+			record.NewBlindingPointRecord(&h.BlindingPoint),
+		)
+	}
+
+	var (
+		amt      = uint64(h.AmtToForward)
+		totalAmt = uint64(h.TotalAmtMAtoms)
+	)
+	if nextChanID == 0 {
+		records = append(records,
+			record.NewAmtToFwdRecord(&amt),
+			record.NewLockTimeRecord(&h.OutgoingTimeLock),
+			record.NewTotalAmtMAtomsBlindedRecord(&totalAmt),
+		)
+
+		// Custom records are only delivered to the recipient.
+		records = append(records, tlv.MapToRecords(h.CustomRecords)...)
+	}
+
+	tlv.SortRecords(records)
+
+	tlvStream, err := tlv.NewStream(records...)
+	if err != nil {
+		return err
+	}
+
+	return tlvStream.Encode(w)
+}
+
 // Size returns the total size this hop's payload would take up in the onion
 // packet.
 func (h *Hop) PayloadSize(nextChanID uint64) uint64 {
@@ -235,20 +322,51 @@ func (h *Hop) PayloadSize(nextChanID uint64) uint64 {
 			tlv.VarIntSize(length) + length
 	}
 
-	// Add amount size.
-	addRecord(record.AmtOnionType, tlv.SizeTUint64(uint64(h.AmtToForward)))
-
-	// Add lock time size.
-	addRecord(
-		record.LockTimeOnionType,
-		tlv.SizeTUint64(uint64(h.OutgoingTimeLock)),
+	// Intermediate hops of a blinded route find their forwarding
+	// instructions in their encrypted data.
+	var (
+		isBlinded             = h.EncryptedData != nil
+		isBlindedIntermediate = isBlinded && nextChanID != 0
 	)
 
+	if !isBlindedIntermediate {
+		// Add amount size.
+		addRecord(
+			record.AmtOnionType,
+			tlv.SizeTUint64(uint64(h.AmtToForward)),
+		)
+
+		// Add lock time size.
+		addRecord(
+			record.LockTimeOnionType,
+			tlv.SizeTUint64(uint64(h.OutgoingTimeLock)),
+		)
+	}
+
 	// Add next hop if present.
-	if nextChanID != 0 {
+	if nextChanID != 0 && !isBlinded {
 		addRecord(record.NextHopOnionType, 8)
 	}
 
+	// Add the blinded route records if present.
+	if isBlinded {
+		addRecord(
+			record.EncryptedDataOnionType,
+			uint64(len(h.EncryptedData)),
+		)
+
+		if h.BlindingPoint != nil {
+			addRecord(record.BlindingPointOnionType, 33)
+		}
+
+		if !isBlindedIntermediate {
+			addRecord(
+				record.TotalAmtMAtomsBlindedType,
+				tlv.SizeTUint64(uint64(h.TotalAmtMAtoms)),
+			)
+		}
+	}
+
 	// Add mpp if present.
 	if h.MPP != nil {
 		addRecord(record.MPPOnionType, h.MPP.PayloadSize())
@@ -259,9 +377,19 @@ func (h *Hop) PayloadSize(nextChanID uint64) uint64 {
 		addRecord(record.AMPOnionType, h.AMP.PayloadSize())
 	}
 
+	// Add trampoline onion if present.
+	if h.TrampolineOnion != nil {
+		addRecord(
+			record.TrampolineOnionType,
+			uint64(len(h.TrampolineOnion)),
+		)
+	}
+
 	// Add custom records.
-	for k, v := range h.CustomRecords {
-		addRecord(tlv.Type(k), uint64(len(v)))
+	if !isBlindedIntermediate {
+		for k, v := range h.CustomRecords {
+			addRecord(tlv.Type(k), uint64(len(v)))
+		}
 	}
 
 	// Add the size required to encode the payload length.
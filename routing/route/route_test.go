@@ -213,3 +213,70 @@ func TestPayloadSize(t *testing.T) {
 		}
 	}
 }
+
+// TestBlindedPayloadSize tests the payload size calculation of hops within a
+// blinded route.
+func TestBlindedPayloadSize(t *testing.T) {
+	hops := []*Hop{
+		{
+			PubKeyBytes:      testPubKeyBytes,
+			AmtToForward:     1200,
+			OutgoingTimeLock: 700000,
+			ChannelID:        63584534844,
+		},
+		{
+			PubKeyBytes:   testPubKeyBytes,
+			AmtToForward:  1100,
+			ChannelID:     3432483437438,
+			EncryptedData: bytes.Repeat([]byte{1}, 60),
+			BlindingPoint: testPubKey,
+		},
+		{
+			PubKeyBytes:   testPubKeyBytes,
+			AmtToForward:  1000,
+			ChannelID:     1,
+			EncryptedData: bytes.Repeat([]byte{2}, 50),
+		},
+		{
+			PubKeyBytes:      testPubKeyBytes,
+			AmtToForward:     1000,
+			OutgoingTimeLock: 600000,
+			ChannelID:        2,
+			EncryptedData:    bytes.Repeat([]byte{3}, 40),
+			TotalAmtMAtoms:   1000,
+			CustomRecords: map[uint64][]byte{
+				100000: {1, 2, 3},
+			},
+		},
+	}
+
+	rt := Route{
+		Hops: hops,
+	}
+	path, err := rt.ToSphinxPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, onionHop := range path[:path.TrueRouteLength()] {
+		hop := hops[i]
+		var nextChan uint64
+		if i < len(hops)-1 {
+			nextChan = hops[i+1].ChannelID
+		}
+
+		expected := uint64(onionHop.HopPayload.NumBytes())
+		actual := hop.PayloadSize(nextChan)
+		if expected != actual {
+			t.Fatalf("unexpected payload size at hop %v: "+
+				"expected %v, got %v",
+				i, expected, actual)
+		}
+	}
+
+	// MPP records can't be sent to hops within a blinded route.
+	hops[3].MPP = record.NewMPP(1000, [32]byte{})
+	if _, err := rt.ToSphinxPath(); err != ErrBlindedHopMPP {
+		t.Fatalf("expected ErrBlindedHopMPP, got: %v", err)
+	}
+}
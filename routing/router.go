@@ -15,6 +15,7 @@ import (
 	sphinx "github.com/decred/lightning-onion/v3"
 	"github.com/go-errors/errors"
 
+	"github.com/decred/dcrlnd/amp"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/clock"
@@ -28,6 +29,7 @@ import (
 	"github.com/decred/dcrlnd/record"
 	"github.com/decred/dcrlnd/routing/chainview"
 	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/routing/shards"
 	"github.com/decred/dcrlnd/ticker"
 	"github.com/decred/dcrlnd/zpay32"
 )
@@ -187,6 +189,10 @@ type PaymentSessionSource interface {
 	// to missioncontrol for resumed payment we don't want to make more
 	// attempts for.
 	NewPaymentSessionEmpty() PaymentSession
+
+	// SetPaymentAttemptPenalty sets the payment attempt penalty that is
+	// used in path finding by new payment sessions.
+	SetPaymentAttemptPenalty(penalty lnwire.MilliAtom)
 }
 
 // MissionController is an interface that exposes failure reporting and
@@ -205,9 +211,10 @@ type MissionController interface {
 	ReportPaymentSuccess(paymentID uint64, rt *route.Route) error
 
 	// GetProbability is expected to return the success probability of a
-	// payment from fromNode along edge.
+	// payment from fromNode along edge. The capacity of the channel may be
+	// zero if it is unknown.
 	GetProbability(fromNode, toNode route.Vertex,
-		amt lnwire.MilliAtom) float64
+		amt lnwire.MilliAtom, capacity dcrutil.Amount) float64
 }
 
 // FeeSchema is the set fee configuration for a Lightning Node on the network.
@@ -401,6 +408,10 @@ type ChannelRouter struct {
 	// announcements over a window of defaultStatInterval.
 	stats *routerStats
 
+	// pathFindingMtx guards the path finding config, which may be changed
+	// at runtime.
+	pathFindingMtx sync.RWMutex
+
 	sync.RWMutex
 
 	quit chan struct{}
@@ -549,6 +560,23 @@ func (r *ChannelRouter) Start() error {
 			// result for the in-flight attempt is received.
 			paySession := r.cfg.SessionSource.NewPaymentSessionEmpty()
 
+			// We create a new shard tracker that will be used to
+			// track the hashes of the in-flight attempts. Older
+			// attempts don't have their HTLC hash set, in which
+			// case we fall back to the overall payment hash.
+			shardHashes := make(map[uint64]lntypes.Hash)
+			for _, a := range payment.Attempts {
+				hash := payment.Info.PaymentHash
+				if a.Hash != nil {
+					hash = *a.Hash
+				}
+
+				shardHashes[a.AttemptID] = hash
+			}
+			shardTracker := shards.NewSimpleShardTracker(
+				payment.Info.PaymentHash, shardHashes,
+			)
+
 			// We pass in a zero timeout value, to indicate we
 			// don't need it to timeout. It will stop immediately
 			// after the existing attempt has finished anyway. We
@@ -557,6 +585,7 @@ func (r *ChannelRouter) Start() error {
 			_, _, err := r.sendPayment(
 				payment.Info.Value, 0,
 				payment.Info.PaymentHash, 0, paySession,
+				shardTracker,
 			)
 			if err != nil {
 				log.Errorf("Resuming payment with hash %v "+
@@ -1416,6 +1445,17 @@ type routingMsg struct {
 	err chan error
 }
 
+// SetPaymentAttemptPenalty sets the payment attempt penalty that is used in
+// path finding for routes that are queried and payments that are started from
+// now on.
+func (r *ChannelRouter) SetPaymentAttemptPenalty(penalty lnwire.MilliAtom) {
+	r.pathFindingMtx.Lock()
+	r.cfg.PathFindingConfig.PaymentAttemptPenalty = penalty
+	r.pathFindingMtx.Unlock()
+
+	r.cfg.SessionSource.SetPaymentAttemptPenalty(penalty)
+}
+
 // FindRoute attempts to query the ChannelRouter for the optimum path to a
 // particular target destination to which it is able to send `amt` after
 // factoring in channel capacities and cumulative fees along the route.
@@ -1458,6 +1498,10 @@ func (r *ChannelRouter) FindRoute(source, target route.Vertex,
 		}
 	}()
 
+	r.pathFindingMtx.RLock()
+	pathFindingConfig := r.cfg.PathFindingConfig
+	r.pathFindingMtx.RUnlock()
+
 	path, err := findPath(
 		&graphParams{
 			additionalEdges: routeHints,
@@ -1465,7 +1509,7 @@ func (r *ChannelRouter) FindRoute(source, target route.Vertex,
 			graph:           routingTx,
 		},
 		restrictions,
-		&r.cfg.PathFindingConfig,
+		&pathFindingConfig,
 		source, target, amt, finalHtlcExpiry,
 	)
 	if err != nil {
@@ -1613,6 +1657,11 @@ type LightningPayment struct {
 	// destination successfully.
 	RouteHints [][]zpay32.HopHint
 
+	// BlindedPath is an optional blinded path to the destination. If set,
+	// the Target must be the last hop of the path, as returned by
+	// BlindedPathTarget, and the payment is routed through the path.
+	BlindedPath *zpay32.BlindedPaymentPath
+
 	// OutgoingChannelIDs is the list of channels that are allowed for the
 	// first hop. If nil, any channel may be used.
 	OutgoingChannelIDs []uint64
@@ -1647,6 +1696,43 @@ type LightningPayment struct {
 	// MaxParts is the maximum number of partial payments that may be used
 	// to complete the full amount.
 	MaxParts uint32
+
+	// AMP is an optional field that is set if and only if this is am AMP
+	// payment.
+	AMP *AMPOptions
+
+	// Trampoline is an optional field that is set if the payment is sent
+	// through a trampoline node. In that case, the Target is the
+	// trampoline node and the Amount includes the fee paid to it.
+	Trampoline *TrampolineOptions
+
+	// SplitStrategy decides how the payment is split if no route is found
+	// for the full amount. If nil, the amount is halved.
+	SplitStrategy SplitStrategy
+}
+
+// AMPOptions houses information that must be known in order to send an AMP
+// payment.
+type AMPOptions struct {
+	// SetID is the identifier of the HTLC set. It is used by the receiver
+	// to group the AMP shards and by the sender as the payment identifier
+	// with the control tower.
+	SetID [32]byte
+
+	// RootShare is the root seed from which the payment hashes and
+	// preimages of all shards are derived.
+	RootShare [32]byte
+}
+
+// Identifier returns a 32-byte slice that uniquely identifies this single
+// payment. For non-AMP payments this will be the payment hash, for AMP
+// payments this will be the used SetID.
+func (l *LightningPayment) Identifier() [32]byte {
+	if l.AMP != nil {
+		return l.AMP.SetID
+	}
+
+	return l.PaymentHash
 }
 
 // SendPayment attempts to send a payment as described within the passed
@@ -1659,7 +1745,7 @@ type LightningPayment struct {
 func (r *ChannelRouter) SendPayment(payment *LightningPayment) ([32]byte,
 	*route.Route, error) {
 
-	paySession, err := r.preparePayment(payment)
+	paySession, shardTracker, err := r.preparePayment(payment)
 	if err != nil {
 		return [32]byte{}, nil, err
 	}
@@ -1670,15 +1756,15 @@ func (r *ChannelRouter) SendPayment(payment *LightningPayment) ([32]byte,
 	// Since this is the first time this payment is being made, we pass nil
 	// for the existing attempt.
 	return r.sendPayment(
-		payment.Amount, payment.FeeLimit, payment.PaymentHash,
-		payment.PayAttemptTimeout, paySession,
+		payment.Amount, payment.FeeLimit, payment.Identifier(),
+		payment.PayAttemptTimeout, paySession, shardTracker,
 	)
 }
 
 // SendPaymentAsync is the non-blocking version of SendPayment. The payment
 // result needs to be retrieved via the control tower.
 func (r *ChannelRouter) SendPaymentAsync(payment *LightningPayment) error {
-	paySession, err := r.preparePayment(payment)
+	paySession, shardTracker, err := r.preparePayment(payment)
 	if err != nil {
 		return err
 	}
@@ -1693,12 +1779,12 @@ func (r *ChannelRouter) SendPaymentAsync(payment *LightningPayment) error {
 			spewPayment(payment))
 
 		_, _, err := r.sendPayment(
-			payment.Amount, payment.FeeLimit, payment.PaymentHash,
-			payment.PayAttemptTimeout, paySession,
+			payment.Amount, payment.FeeLimit, payment.Identifier(),
+			payment.PayAttemptTimeout, paySession, shardTracker,
 		)
 		if err != nil {
-			log.Errorf("Payment with hash %x failed: %v",
-				payment.PaymentHash, err)
+			log.Errorf("Payment %x failed: %v",
+				payment.Identifier(), err)
 		}
 	}()
 
@@ -1729,14 +1815,36 @@ func spewPayment(payment *LightningPayment) logClosure {
 // preparePayment creates the payment session and registers the payment with the
 // control tower.
 func (r *ChannelRouter) preparePayment(payment *LightningPayment) (
-	PaymentSession, error) {
+	PaymentSession, shards.ShardTracker, error) {
+
+	// AMP payments are split among multiple shards that must all carry the
+	// same payment address, so one must be provided.
+	if payment.AMP != nil && payment.PaymentAddr == nil {
+		return nil, nil, errors.New("amp payments require a " +
+			"payment address")
+	}
+
+	// The hops of a blinded path can't be given the AMP record.
+	if payment.AMP != nil && payment.BlindedPath != nil {
+		return nil, nil, errors.New("amp payments to blinded paths " +
+			"are not supported")
+	}
+
+	// The trampoline onion is created for a single destination, which
+	// can't be combined with AMP or blinded paths.
+	if payment.Trampoline != nil &&
+		(payment.AMP != nil || payment.BlindedPath != nil) {
+
+		return nil, nil, errors.New("trampoline payments can't be " +
+			"combined with amp or blinded paths")
+	}
 
 	// Before starting the HTLC routing attempt, we'll create a fresh
 	// payment session which will report our errors back to mission
 	// control.
 	paySession, err := r.cfg.SessionSource.NewPaymentSession(payment)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Record this payment hash with the ControlTower, ensuring it is not
@@ -1744,18 +1852,38 @@ func (r *ChannelRouter) preparePayment(payment *LightningPayment) (
 	//
 	// TODO(roasbeef): store records as part of creation info?
 	info := &channeldb.PaymentCreationInfo{
-		PaymentHash:    payment.PaymentHash,
+		PaymentHash:    payment.Identifier(),
 		Value:          payment.Amount,
 		CreationTime:   r.cfg.Clock.Now(),
 		PaymentRequest: payment.PaymentRequest,
 	}
 
-	err = r.cfg.Control.InitPayment(payment.PaymentHash, info)
+	// Create a new ShardTracker that we'll use during the life cycle of
+	// this payment.
+	var shardTracker shards.ShardTracker
+	switch {
+
+	// If this is an AMP payment, we'll use the AMP shard tracker.
+	case payment.AMP != nil:
+		shardTracker = amp.NewShardTracker(
+			payment.AMP.RootShare, payment.AMP.SetID,
+			*payment.PaymentAddr, payment.Amount,
+		)
+
+	// Otherwise we'll use the simple tracker that will map each attempt to
+	// the same payment hash.
+	default:
+		shardTracker = shards.NewSimpleShardTracker(
+			payment.Identifier(), nil,
+		)
+	}
+
+	err = r.cfg.Control.InitPayment(payment.Identifier(), info)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return paySession, nil
+	return paySession, shardTracker, nil
 }
 
 // SendToRoute attempts to send a payment with the given hash through the
@@ -1805,12 +1933,13 @@ func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, rt *route.Route) (
 
 	// Launch a shard along the given route.
 	sh := &shardHandler{
-		router:      r,
-		paymentHash: hash,
+		router:       r,
+		identifier:   hash,
+		shardTracker: shards.NewSimpleShardTracker(hash, nil),
 	}
 
 	var shardError error
-	attempt, outcome, err := sh.launchShard(rt)
+	attempt, outcome, err := sh.launchShard(rt, nil, false)
 
 	// With SendToRoute, it can happen that the route exceeds protocol
 	// constraints. Mark the payment as failed with an internal error.
@@ -1894,9 +2023,9 @@ func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, rt *route.Route) (
 // router will call this method for every payment still in-flight according to
 // the ControlTower.
 func (r *ChannelRouter) sendPayment(
-	totalAmt, feeLimit lnwire.MilliAtom, paymentHash lntypes.Hash,
-	timeout time.Duration,
-	paySession PaymentSession) ([32]byte, *route.Route, error) {
+	totalAmt, feeLimit lnwire.MilliAtom, identifier lntypes.Hash,
+	timeout time.Duration, paySession PaymentSession,
+	shardTracker shards.ShardTracker) ([32]byte, *route.Route, error) {
 
 	// We'll also fetch the current block height so we can properly
 	// calculate the required HTLC time locks within the route.
@@ -1911,8 +2040,9 @@ func (r *ChannelRouter) sendPayment(
 		router:        r,
 		totalAmount:   totalAmt,
 		feeLimit:      feeLimit,
-		paymentHash:   paymentHash,
+		identifier:    identifier,
 		paySession:    paySession,
+		shardTracker:  shardTracker,
 		currentHeight: currentHeight,
 	}
 
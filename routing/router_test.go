@@ -84,10 +84,17 @@ func createTestCtxFromGraphInstance(startingHeight uint32, graphInstance *testGr
 		PaymentAttemptPenalty: 100,
 	}
 
-	mcConfig := &MissionControlConfig{
+	estimator, err := NewAprioriEstimator(AprioriConfig{
 		PenaltyHalfLife:       time.Hour,
 		AprioriHopProbability: 0.9,
 		AprioriWeight:         0.5,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mcConfig := &MissionControlConfig{
+		Estimator: estimator,
 	}
 
 	mc, err := NewMissionControl(
@@ -3060,3 +3067,32 @@ func TestBuildRoute(t *testing.T) {
 		t.Fatalf("unexpected no channel error node")
 	}
 }
+
+// TestSetPaymentAttemptPenalty tests that a payment attempt penalty that is
+// set at runtime is applied to both the router's and the session source's
+// path finding config.
+func TestSetPaymentAttemptPenalty(t *testing.T) {
+	t.Parallel()
+
+	ctx, cleanUp, err := createTestCtxSingleNode(0)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	penalty := lnwire.NewMAtomsFromAtoms(50)
+	ctx.router.SetPaymentAttemptPenalty(penalty)
+
+	routerPenalty := ctx.router.cfg.PathFindingConfig.PaymentAttemptPenalty
+	if routerPenalty != penalty {
+		t.Fatalf("expected router penalty %v, got %v", penalty,
+			routerPenalty)
+	}
+
+	sessionSource := ctx.router.cfg.SessionSource.(*SessionSource)
+	sessionPenalty := sessionSource.PathFindingConfig.PaymentAttemptPenalty
+	if sessionPenalty != penalty {
+		t.Fatalf("expected session source penalty %v, got %v", penalty,
+			sessionPenalty)
+	}
+}
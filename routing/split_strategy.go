@@ -0,0 +1,159 @@
+package routing
+
+import (
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// ShardCandidate is a route through one of our outgoing channels that is able
+// to carry a shard of a split payment.
+type ShardCandidate struct {
+	// ChannelID is the outgoing channel of the route.
+	ChannelID uint64
+
+	// Amount is the largest amount the route is able to carry, which is
+	// bounded by the bandwidth of the outgoing channel.
+	Amount lnwire.MilliAtom
+
+	// Fee is the total routing fee of the route when carrying Amount.
+	Fee lnwire.MilliAtom
+
+	// Probability is the estimated success probability of the route.
+	Probability float64
+
+	// path is the path of the route.
+	path []*channeldb.ChannelEdgePolicy
+}
+
+// ShardSplit is the outcome of splitting the amount of a payment.
+type ShardSplit struct {
+	// Amount is the amount of the next shard.
+	Amount lnwire.MilliAtom
+
+	// Candidate is the candidate that should carry the shard. If nil,
+	// path finding is done for the shard amount.
+	Candidate *ShardCandidate
+
+	// Allocation records the decision of the strategy.
+	Allocation *channeldb.ShardAllocation
+}
+
+// SplitStrategy decides how the amount of a payment is split when no route
+// is found that is able to carry it in one piece.
+type SplitStrategy interface {
+	// Type returns the identifier of the strategy that is recorded with
+	// the shards it allocates.
+	Type() channeldb.SplitStrategy
+
+	// Split returns the amount of the next shard to try, given the amount
+	// that couldn't be sent in one piece. The findCandidates closure
+	// returns the candidate routes for the amount, and should only be
+	// called by strategies that need them as path finding is expensive.
+	Split(amt lnwire.MilliAtom,
+		findCandidates func() ([]*ShardCandidate, error)) (*ShardSplit,
+		error)
+}
+
+// HalvingSplitStrategy is the default split strategy, which halves the amount
+// until a route is found.
+type HalvingSplitStrategy struct{}
+
+// A compile time check to ensure HalvingSplitStrategy implements the
+// SplitStrategy interface.
+var _ SplitStrategy = (*HalvingSplitStrategy)(nil)
+
+// Type returns the identifier of the strategy.
+//
+// NOTE: Part of the SplitStrategy interface.
+func (s *HalvingSplitStrategy) Type() channeldb.SplitStrategy {
+	return channeldb.SplitStrategyHalving
+}
+
+// Split returns half of the passed amount.
+//
+// NOTE: Part of the SplitStrategy interface.
+func (s *HalvingSplitStrategy) Split(amt lnwire.MilliAtom,
+	_ func() ([]*ShardCandidate, error)) (*ShardSplit, error) {
+
+	return &ShardSplit{
+		Amount: amt / 2,
+		Allocation: &channeldb.ShardAllocation{
+			Strategy: channeldb.SplitStrategyHalving,
+			SplitAmt: amt,
+			SharePPM: 500000,
+		},
+	}, nil
+}
+
+// FeeAwareSplitStrategy weighs the candidate routes through each of our
+// outgoing channels by the amount they are expected to deliver, which is the
+// amount they can carry minus their fee, times their success probability.
+// The next shard is sent through the best candidate, with an amount that is
+// proportional to its share of the total weight. This produces fewer and
+// cheaper shards than halving for large payments.
+type FeeAwareSplitStrategy struct{}
+
+// A compile time check to ensure FeeAwareSplitStrategy implements the
+// SplitStrategy interface.
+var _ SplitStrategy = (*FeeAwareSplitStrategy)(nil)
+
+// Type returns the identifier of the strategy.
+//
+// NOTE: Part of the SplitStrategy interface.
+func (s *FeeAwareSplitStrategy) Type() channeldb.SplitStrategy {
+	return channeldb.SplitStrategyFeeAware
+}
+
+// Split allocates the next shard to the best candidate route. If there are no
+// candidates, the amount is halved.
+//
+// NOTE: Part of the SplitStrategy interface.
+func (s *FeeAwareSplitStrategy) Split(amt lnwire.MilliAtom,
+	findCandidates func() ([]*ShardCandidate, error)) (*ShardSplit, error) {
+
+	candidates, err := findCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		best        *ShardCandidate
+		bestWeight  float64
+		totalWeight float64
+	)
+	for _, c := range candidates {
+		if c.Fee >= c.Amount {
+			continue
+		}
+
+		weight := c.Probability * float64(c.Amount-c.Fee)
+		totalWeight += weight
+
+		if best == nil || weight > bestWeight {
+			best = c
+			bestWeight = weight
+		}
+	}
+
+	if best == nil || totalWeight == 0 {
+		return (&HalvingSplitStrategy{}).Split(amt, findCandidates)
+	}
+
+	share := bestWeight / totalWeight
+	shardAmt := lnwire.MilliAtom(float64(amt) * share)
+	if shardAmt > best.Amount {
+		shardAmt = best.Amount
+	}
+
+	return &ShardSplit{
+		Amount:    shardAmt,
+		Candidate: best,
+		Allocation: &channeldb.ShardAllocation{
+			Strategy:       channeldb.SplitStrategyFeeAware,
+			SplitAmt:       amt,
+			NumCandidates:  uint32(len(candidates)),
+			SharePPM:       uint32(share * 1e6),
+			ProbabilityPPM: uint32(best.Probability * 1e6),
+		},
+	}, nil
+}
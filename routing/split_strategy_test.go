@@ -0,0 +1,69 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFeeAwareSplitStrategy asserts that the fee-aware split strategy
+// allocates shards to the candidate that is expected to deliver the most, and
+// falls back to halving if there are no candidates.
+func TestFeeAwareSplitStrategy(t *testing.T) {
+	t.Parallel()
+
+	const amt = lnwire.MilliAtom(1000000)
+
+	strategy := &FeeAwareSplitStrategy{}
+
+	// The first candidate carries most of the amount but is unlikely to
+	// succeed, the second one is cheap and reliable, and the third one
+	// doesn't leave anything after paying its fee.
+	candidates := []*ShardCandidate{
+		{ChannelID: 1, Amount: 900000, Fee: 1000, Probability: 0.1},
+		{ChannelID: 2, Amount: 600000, Fee: 600, Probability: 0.9},
+		{ChannelID: 3, Amount: 1000, Fee: 1000, Probability: 1},
+	}
+
+	split, err := strategy.Split(amt, func() ([]*ShardCandidate, error) {
+		return candidates, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, candidates[1], split.Candidate)
+
+	// The weight of the second candidate is 0.9*599400 = 539460 out of a
+	// total of 539460 + 0.1*899000 = 629360, so it gets about 86% of the
+	// amount. That is more than it can carry, so its amount is capped.
+	require.Equal(t, lnwire.MilliAtom(600000), split.Amount)
+	require.Equal(
+		t, channeldb.SplitStrategyFeeAware, split.Allocation.Strategy,
+	)
+	require.Equal(t, amt, split.Allocation.SplitAmt)
+	require.Equal(t, uint32(3), split.Allocation.NumCandidates)
+	require.InDelta(t, 857156, split.Allocation.SharePPM, 1)
+	require.Equal(t, uint32(900000), split.Allocation.ProbabilityPPM)
+
+	// Two equal candidates split the amount evenly.
+	candidates = []*ShardCandidate{
+		{ChannelID: 1, Amount: amt, Fee: 100, Probability: 0.5},
+		{ChannelID: 2, Amount: amt, Fee: 100, Probability: 0.5},
+	}
+	split, err = strategy.Split(amt, func() ([]*ShardCandidate, error) {
+		return candidates, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, amt/2, split.Amount)
+
+	// Without candidates, the amount is halved.
+	split, err = strategy.Split(amt, func() ([]*ShardCandidate, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Nil(t, split.Candidate)
+	require.Equal(t, amt/2, split.Amount)
+	require.Equal(
+		t, channeldb.SplitStrategyHalving, split.Allocation.Strategy,
+	)
+}
@@ -0,0 +1,115 @@
+package routing
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/routing/trampoline"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+// DefaultTrampolineCLTVDelta is the default expiry delta handed to a
+// trampoline node, which must cover the time locks of the route from the
+// trampoline node to the destination.
+const DefaultTrampolineCLTVDelta = 288
+
+// TrampolineOptions houses the information needed to send a payment through a
+// trampoline node, which finds a route to the destination on our behalf.
+type TrampolineOptions struct {
+	// Destination is the final recipient of the payment.
+	Destination route.Vertex
+
+	// Amount is the amount the destination must receive.
+	Amount lnwire.MilliAtom
+
+	// FinalCLTVDelta is the expiry delta required by the destination.
+	FinalCLTVDelta uint16
+
+	// PaymentAddr is the payment address of the destination, if known.
+	PaymentAddr *[32]byte
+}
+
+// encodeTrampolinePayload serializes the payload that instructs the trampoline
+// node to forward the payment to the destination.
+func encodeTrampolinePayload(opts *TrampolineOptions,
+	outgoingCltv uint32) ([]byte, error) {
+
+	destination, err := secp256k1.ParsePubKey(opts.Destination[:])
+	if err != nil {
+		return nil, err
+	}
+
+	amt := uint64(opts.Amount)
+	records := []tlv.Record{
+		record.NewAmtToFwdRecord(&amt),
+		record.NewLockTimeRecord(&outgoingCltv),
+		record.NewOutgoingNodeIDRecord(&destination),
+	}
+
+	if opts.PaymentAddr != nil {
+		mpp := record.NewMPP(opts.Amount, *opts.PaymentAddr)
+		records = append(records, mpp.Record())
+	}
+
+	tlv.SortRecords(records)
+
+	tlvStream, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := tlvStream.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// applyTrampoline adds a trampoline onion to the final hop of the route, which
+// must be the trampoline node. The onion instructs the trampoline node to
+// forward the payment to the destination such that it arrives with an expiry
+// that satisfies the final expiry delta of the destination.
+func applyTrampoline(rt *route.Route, opts *TrampolineOptions,
+	paymentHash [32]byte, height uint32) error {
+
+	if len(rt.Hops) == 0 {
+		return errors.New("route to trampoline node has no hops")
+	}
+
+	finalHop := rt.Hops[len(rt.Hops)-1]
+	trampolineNode, err := secp256k1.ParsePubKey(finalHop.PubKeyBytes[:])
+	if err != nil {
+		return err
+	}
+
+	outgoingCltv := height + uint32(opts.FinalCLTVDelta+BlockPadding)
+	payload, err := encodeTrampolinePayload(opts, outgoingCltv)
+	if err != nil {
+		return err
+	}
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+
+	packet, err := trampoline.NewOnionPacket(
+		sessionKey, []*trampoline.HopInfo{{
+			NodePub: trampolineNode,
+			Payload: payload,
+		}}, paymentHash[:],
+	)
+	if err != nil {
+		return err
+	}
+
+	finalHop.TrampolineOnion = packet.Bytes()
+	finalHop.LegacyPayload = false
+
+	return nil
+}
@@ -0,0 +1,457 @@
+package trampoline
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/tlv"
+	sphinx "github.com/decred/lightning-onion/v3"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	// Version is the version of the trampoline onion packets created and
+	// processed by this package.
+	Version byte = 0
+
+	// RoutingInfoSize is the size of the routing info of a trampoline
+	// onion. It is smaller than the routing info of regular onion packets,
+	// so that the trampoline onion fits into the final hop payload of a
+	// regular onion.
+	RoutingInfoSize = 400
+
+	// hmacSize is the size of the HMACs that authenticate the routing info
+	// of each hop.
+	hmacSize = 32
+
+	// PacketSize is the size of a serialized trampoline onion packet: the
+	// version byte, the ephemeral key, the routing info and the HMAC.
+	PacketSize = 1 + secp256k1.PubKeyBytesLenCompressed + RoutingInfoSize +
+		hmacSize
+
+	// numStreamBytes is the number of bytes of the cipher stream used to
+	// encrypt and decrypt the routing info.
+	numStreamBytes = 2 * RoutingInfoSize
+)
+
+var (
+	// ErrNoHops is returned when attempting to create a trampoline onion
+	// without any hops.
+	ErrNoHops = errors.New("trampoline onion must contain at least one " +
+		"hop")
+
+	// ErrInvalidVersion is returned when processing a trampoline onion of
+	// an unknown version.
+	ErrInvalidVersion = errors.New("invalid trampoline onion version")
+
+	// ErrInvalidHMAC is returned when the HMAC of a trampoline onion
+	// doesn't match its contents.
+	ErrInvalidHMAC = errors.New("invalid trampoline onion hmac")
+
+	// ErrPayloadTooLarge is returned when the payloads of the hops don't
+	// fit into the routing info of a trampoline onion.
+	ErrPayloadTooLarge = errors.New("trampoline onion payloads too large")
+
+	// rhoKey is the HMAC key used to derive the key of the cipher stream
+	// that encrypts the routing info.
+	rhoKey = []byte("rho")
+
+	// muKey is the HMAC key used to derive the key that authenticates the
+	// routing info.
+	muKey = []byte("mu")
+
+	// padKey is the HMAC key used to derive the key of the cipher stream
+	// that fills the unused space of the routing info.
+	padKey = []byte("pad")
+)
+
+// HopInfo holds the public key of a trampoline node along with the payload
+// that is to be delivered to it.
+type HopInfo struct {
+	// NodePub is the public key of the trampoline node.
+	NodePub *secp256k1.PublicKey
+
+	// Payload is the TLV payload of the hop.
+	Payload []byte
+}
+
+// OnionPacket is a trampoline onion packet. It has the same structure as a
+// regular onion packet, but a smaller routing info.
+type OnionPacket struct {
+	// Version is the version of the packet.
+	Version byte
+
+	// EphemeralKey is the ephemeral key that the processing node uses to
+	// derive the shared secret of the packet.
+	EphemeralKey *secp256k1.PublicKey
+
+	// RoutingInfo holds the encrypted payloads of all hops.
+	RoutingInfo [RoutingInfoSize]byte
+
+	// HMAC authenticates the routing info of the processing node.
+	HMAC [hmacSize]byte
+}
+
+// Encode writes the serialized packet to the given writer.
+func (p *OnionPacket) Encode(w io.Writer) error {
+	if _, err := w.Write([]byte{p.Version}); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.EphemeralKey.SerializeCompressed()); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.RoutingInfo[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(p.HMAC[:])
+	return err
+}
+
+// Decode reads a serialized packet from the given reader.
+func (p *OnionPacket) Decode(r io.Reader) error {
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return err
+	}
+	p.Version = version[0]
+	if p.Version != Version {
+		return ErrInvalidVersion
+	}
+
+	var pubKey [secp256k1.PubKeyBytesLenCompressed]byte
+	if _, err := io.ReadFull(r, pubKey[:]); err != nil {
+		return err
+	}
+	ephemeralKey, err := secp256k1.ParsePubKey(pubKey[:])
+	if err != nil {
+		return err
+	}
+	p.EphemeralKey = ephemeralKey
+
+	if _, err := io.ReadFull(r, p.RoutingInfo[:]); err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, p.HMAC[:])
+	return err
+}
+
+// Bytes returns the serialized packet.
+func (p *OnionPacket) Bytes() []byte {
+	var b bytes.Buffer
+	_ = p.Encode(&b)
+	return b.Bytes()
+}
+
+// ParseOnionPacket parses a serialized trampoline onion packet.
+func ParseOnionPacket(b []byte) (*OnionPacket, error) {
+	if len(b) != PacketSize {
+		return nil, fmt.Errorf("invalid trampoline onion size: %d",
+			len(b))
+	}
+
+	p := &OnionPacket{}
+	if err := p.Decode(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// NewOnionPacket creates a trampoline onion that delivers the payloads to the
+// given hops in order. The session key is used as the ephemeral key of the
+// first hop. The associated data is authenticated along with the routing info
+// of every hop and should be set to the payment hash.
+func NewOnionPacket(sessionKey *secp256k1.PrivateKey, hops []*HopInfo,
+	assocData []byte) (*OnionPacket, error) {
+
+	if len(hops) == 0 {
+		return nil, ErrNoHops
+	}
+
+	// Frame the payloads of all hops up front, so that we can check that
+	// they fit into the routing info.
+	var totalSize int
+	frameSizes := make([]int, len(hops))
+	for i, hop := range hops {
+		frameSizes[i] = frameSize(hop.Payload)
+		totalSize += frameSizes[i]
+	}
+	if totalSize > RoutingInfoSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	// Derive the ephemeral keys and shared secrets of all hops.
+	var (
+		ephemeralKey  = sessionKey
+		ephemeralKeys = make([]*secp256k1.PublicKey, len(hops))
+		sharedSecrets = make([][32]byte, len(hops))
+	)
+	for i, hop := range hops {
+		sessionECDH := &sphinx.PrivKeyECDH{PrivKey: ephemeralKey}
+		sharedSecret, err := sessionECDH.ECDH(hop.NodePub)
+		if err != nil {
+			return nil, err
+		}
+
+		ephemeralKeys[i] = ephemeralKey.PubKey()
+		sharedSecrets[i] = sharedSecret
+
+		factor := blindingFactor(ephemeralKeys[i], sharedSecret)
+		ephemeralKey = scalarMultPriv(factor[:], ephemeralKey)
+	}
+
+	filler := generateFiller(sharedSecrets, frameSizes)
+
+	// Fill the routing info with pseudo random bytes, so that the unused
+	// space can't be told apart from the payloads.
+	var routingInfo [RoutingInfoSize]byte
+	padStream := cipherStream(
+		hmacSum(padKey, sessionKey.Serialize()), RoutingInfoSize,
+	)
+	copy(routingInfo[:], padStream)
+
+	// Wrap the payloads starting with the last hop, as each hop's HMAC
+	// authenticates the routing info of the next one.
+	var nextHMAC [hmacSize]byte
+	for i := len(hops) - 1; i >= 0; i-- {
+		size := frameSizes[i]
+		copy(routingInfo[size:], routingInfo[:RoutingInfoSize-size])
+
+		var frame bytes.Buffer
+		if err := writeFrame(&frame, hops[i].Payload, nextHMAC); err != nil {
+			return nil, err
+		}
+		copy(routingInfo[:], frame.Bytes())
+
+		rho := hmacSum(rhoKey, sharedSecrets[i][:])
+		xor(routingInfo[:], cipherStream(rho, RoutingInfoSize))
+
+		if i == len(hops)-1 {
+			copy(routingInfo[RoutingInfoSize-len(filler):], filler)
+		}
+
+		mu := hmacSum(muKey, sharedSecrets[i][:])
+		nextHMAC = computeHMAC(mu, routingInfo[:], assocData)
+	}
+
+	return &OnionPacket{
+		Version:      Version,
+		EphemeralKey: ephemeralKeys[0],
+		RoutingInfo:  routingInfo,
+		HMAC:         nextHMAC,
+	}, nil
+}
+
+// ProcessedPacket is the result of processing a trampoline onion.
+type ProcessedPacket struct {
+	// Payload is the payload that was delivered to the processing node.
+	Payload []byte
+
+	// NextPacket is the packet that must be handed to the next trampoline
+	// node. It is nil if the processing node is the last hop of the
+	// trampoline onion.
+	NextPacket *OnionPacket
+}
+
+// ProcessOnionPacket decrypts the payload delivered to the node holding the
+// given key, and derives the packet for the next hop if there is one. The
+// associated data must match the one used to create the packet.
+func ProcessOnionPacket(nodeKey sphinx.SingleKeyECDH, packet *OnionPacket,
+	assocData []byte) (*ProcessedPacket, error) {
+
+	if packet.Version != Version {
+		return nil, ErrInvalidVersion
+	}
+
+	sharedSecret, err := nodeKey.ECDH(packet.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+
+	mu := hmacSum(muKey, sharedSecret[:])
+	expectedHMAC := computeHMAC(mu, packet.RoutingInfo[:], assocData)
+	if !hmac.Equal(expectedHMAC[:], packet.HMAC[:]) {
+		return nil, ErrInvalidHMAC
+	}
+
+	// Extend the routing info with zeroes before decrypting it, so that
+	// the routing info of the next hop is padded to the full size.
+	var extended [numStreamBytes]byte
+	copy(extended[:], packet.RoutingInfo[:])
+	rho := hmacSum(rhoKey, sharedSecret[:])
+	xor(extended[:], cipherStream(rho, numStreamBytes))
+
+	r := bytes.NewReader(extended[:])
+	payload, nextHMAC, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+
+	processed := &ProcessedPacket{
+		Payload: payload,
+	}
+
+	// An empty HMAC signals that we're the last hop.
+	if nextHMAC == [hmacSize]byte{} {
+		return processed, nil
+	}
+
+	size := frameSize(payload)
+	next := &OnionPacket{
+		Version: Version,
+		EphemeralKey: scalarMult(
+			blindingFactor(packet.EphemeralKey, sharedSecret),
+			packet.EphemeralKey,
+		),
+		HMAC: nextHMAC,
+	}
+	copy(next.RoutingInfo[:], extended[size:size+RoutingInfoSize])
+	processed.NextPacket = next
+
+	return processed, nil
+}
+
+// generateFiller computes the bytes that the hops preceding the last one
+// shift into the end of the routing info, so that the HMAC of the last hop
+// can be computed by the sender.
+func generateFiller(sharedSecrets [][32]byte, frameSizes []int) []byte {
+	var filler []byte
+	for i := 0; i < len(sharedSecrets)-1; i++ {
+		start := RoutingInfoSize - len(filler)
+		filler = append(filler, make([]byte, frameSizes[i])...)
+
+		rho := hmacSum(rhoKey, sharedSecrets[i][:])
+		stream := cipherStream(rho, numStreamBytes)
+		xor(filler, stream[start:start+len(filler)])
+	}
+
+	return filler
+}
+
+// frameSize returns the number of bytes the framed payload takes up in the
+// routing info.
+func frameSize(payload []byte) int {
+	return int(tlv.VarIntSize(uint64(len(payload)))) + len(payload) +
+		hmacSize
+}
+
+// writeFrame writes the payload prefixed by its length and followed by the
+// HMAC of the next hop.
+func writeFrame(w io.Writer, payload []byte, nextHMAC [hmacSize]byte) error {
+	var b [8]byte
+	if err := tlv.WriteVarInt(w, uint64(len(payload)), &b); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	_, err := w.Write(nextHMAC[:])
+	return err
+}
+
+// readFrame reads a payload written by writeFrame.
+func readFrame(r io.Reader) ([]byte, [hmacSize]byte, error) {
+	var (
+		b        [8]byte
+		nextHMAC [hmacSize]byte
+	)
+	length, err := tlv.ReadVarInt(r, &b)
+	if err != nil {
+		return nil, nextHMAC, err
+	}
+	if length > RoutingInfoSize-hmacSize {
+		return nil, nextHMAC, ErrPayloadTooLarge
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nextHMAC, err
+	}
+	if _, err := io.ReadFull(r, nextHMAC[:]); err != nil {
+		return nil, nextHMAC, err
+	}
+
+	return payload, nextHMAC, nil
+}
+
+// computeHMAC authenticates the routing info along with the associated data.
+func computeHMAC(key [32]byte, routingInfo, assocData []byte) [hmacSize]byte {
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write(routingInfo)
+	mac.Write(assocData)
+
+	var sum [hmacSize]byte
+	copy(sum[:], mac.Sum(nil))
+
+	return sum
+}
+
+// cipherStream returns numBytes of the chacha20 stream keyed by the given key.
+func cipherStream(key [32]byte, numBytes int) []byte {
+	var nonce [chacha20.NonceSize]byte
+	cipher, _ := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+
+	stream := make([]byte, numBytes)
+	cipher.XORKeyStream(stream, stream)
+
+	return stream
+}
+
+// xor sets dst to the XOR of dst and src.
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// blindingFactor computes the factor used to derive the ephemeral key of the
+// next hop: sha256(E || ss).
+func blindingFactor(ephemeralKey *secp256k1.PublicKey,
+	sharedSecret [32]byte) []byte {
+
+	h := sha256.New()
+	h.Write(ephemeralKey.SerializeCompressed())
+	h.Write(sharedSecret[:])
+
+	return h.Sum(nil)
+}
+
+// hmacSum returns the HMAC-SHA256 of the message using the given key.
+func hmacSum(key, msg []byte) [32]byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+
+	var sum [32]byte
+	copy(sum[:], mac.Sum(nil))
+
+	return sum
+}
+
+// scalarMult multiplies the given public key by the scalar s.
+func scalarMult(s []byte, pub *secp256k1.PublicKey) *secp256k1.PublicKey {
+	var scalar secp256k1.ModNScalar
+	scalar.SetByteSlice(s)
+
+	var point, result secp256k1.JacobianPoint
+	pub.AsJacobian(&point)
+	secp256k1.ScalarMultNonConst(&scalar, &point, &result)
+	result.ToAffine()
+
+	return secp256k1.NewPublicKey(&result.X, &result.Y)
+}
+
+// scalarMultPriv multiplies the given private key by the scalar s.
+func scalarMultPriv(s []byte,
+	priv *secp256k1.PrivateKey) *secp256k1.PrivateKey {
+
+	var scalar secp256k1.ModNScalar
+	scalar.SetByteSlice(s)
+	scalar.Mul(&priv.Key)
+
+	return secp256k1.NewPrivateKey(&scalar)
+}
@@ -0,0 +1,82 @@
+package trampoline
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOnionPacket tests that every hop of a trampoline onion is able to
+// decrypt its payload and to derive the packet of the next hop.
+func TestOnionPacket(t *testing.T) {
+	t.Parallel()
+
+	const numHops = 3
+
+	var (
+		nodeKeys = make([]*secp256k1.PrivateKey, numHops)
+		hops     = make([]*HopInfo, numHops)
+	)
+	for i := range hops {
+		key, err := secp256k1.GeneratePrivateKey()
+		require.NoError(t, err)
+
+		nodeKeys[i] = key
+		hops[i] = &HopInfo{
+			NodePub: key.PubKey(),
+			Payload: bytes.Repeat([]byte{byte(i + 1)}, 20*(i+1)),
+		}
+	}
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	assocData := bytes.Repeat([]byte{0xaa}, 32)
+	packet, err := NewOnionPacket(sessionKey, hops, assocData)
+	require.NoError(t, err)
+
+	// The packet must survive a serialization round trip.
+	serialized := packet.Bytes()
+	require.Len(t, serialized, PacketSize)
+	packet, err = ParseOnionPacket(serialized)
+	require.NoError(t, err)
+
+	for i, hop := range hops {
+		nodeKey := &keychain.PrivKeyECDH{PrivKey: nodeKeys[i]}
+
+		// Processing the packet with the wrong associated data must
+		// fail.
+		_, err := ProcessOnionPacket(nodeKey, packet, nil)
+		require.Equal(t, ErrInvalidHMAC, err)
+
+		processed, err := ProcessOnionPacket(nodeKey, packet, assocData)
+		require.NoError(t, err)
+		require.Equal(t, hop.Payload, processed.Payload)
+
+		if i == numHops-1 {
+			require.Nil(t, processed.NextPacket)
+			break
+		}
+
+		require.NotNil(t, processed.NextPacket)
+		packet = processed.NextPacket
+	}
+}
+
+// TestOnionPacketTooLarge asserts that payloads that don't fit into the
+// routing info are rejected.
+func TestOnionPacketTooLarge(t *testing.T) {
+	t.Parallel()
+
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	_, err = NewOnionPacket(key, []*HopInfo{{
+		NodePub: key.PubKey(),
+		Payload: make([]byte, RoutingInfoSize),
+	}}, nil)
+	require.Equal(t, ErrPayloadTooLarge, err)
+}
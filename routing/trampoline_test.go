@@ -0,0 +1,70 @@
+package routing
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	htlcswitchhop "github.com/decred/dcrlnd/htlcswitch/hop"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/routing/trampoline"
+	"github.com/stretchr/testify/require"
+)
+
+// TestApplyTrampoline asserts that the trampoline node is able to recover the
+// forwarding instructions from the trampoline onion added to a route.
+func TestApplyTrampoline(t *testing.T) {
+	t.Parallel()
+
+	trampolineKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	destKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	rt := &route.Route{
+		Hops: []*route.Hop{{
+			PubKeyBytes:  route.NewVertex(trampolineKey.PubKey()),
+			AmtToForward: 11000,
+		}},
+	}
+
+	addr := [32]byte{1}
+	opts := &TrampolineOptions{
+		Destination:    route.NewVertex(destKey.PubKey()),
+		Amount:         10000,
+		FinalCLTVDelta: 40,
+		PaymentAddr:    &addr,
+	}
+
+	const height = 100
+	paymentHash := [32]byte{2}
+	err = applyTrampoline(rt, opts, paymentHash, height)
+	require.NoError(t, err)
+
+	onion := rt.Hops[0].TrampolineOnion
+	require.Len(t, onion, trampoline.PacketSize)
+
+	packet, err := trampoline.ParseOnionPacket(onion)
+	require.NoError(t, err)
+
+	processed, err := trampoline.ProcessOnionPacket(
+		&keychain.PrivKeyECDH{PrivKey: trampolineKey}, packet,
+		paymentHash[:],
+	)
+	require.NoError(t, err)
+	require.Nil(t, processed.NextPacket)
+
+	payload, err := htlcswitchhop.NewTrampolinePayloadFromReader(
+		bytes.NewReader(processed.Payload),
+	)
+	require.NoError(t, err)
+	require.Equal(t, lnwire.MilliAtom(10000), payload.AmountToForward)
+	require.Equal(
+		t, uint32(height+40+BlockPadding), payload.OutgoingCltv,
+	)
+	require.True(t, payload.OutgoingNodeID.IsEqual(destKey.PubKey()))
+	require.NotNil(t, payload.MPP)
+	require.Equal(t, addr, payload.MPP.PaymentAddr())
+}
@@ -141,20 +141,35 @@ type unifiedPolicy struct {
 func (u *unifiedPolicy) getPolicy(amt lnwire.MilliAtom,
 	bandwidthHints map[uint64]lnwire.MilliAtom) *channeldb.ChannelEdgePolicy {
 
+	edge := u.getEdge(amt, bandwidthHints)
+	if edge == nil {
+		return nil
+	}
+
+	return edge.policy
+}
+
+// getEdge returns the optimal unified edge to use for this connection given a
+// specific amount to send. Next to the policy, the returned edge carries the
+// capacity that is assumed for the connection, which is used for probability
+// estimation. It differentiates between local and network channels.
+func (u *unifiedPolicy) getEdge(amt lnwire.MilliAtom,
+	bandwidthHints map[uint64]lnwire.MilliAtom) *unifiedPolicyEdge {
+
 	if u.localChan {
-		return u.getPolicyLocal(amt, bandwidthHints)
+		return u.getEdgeLocal(amt, bandwidthHints)
 	}
 
-	return u.getPolicyNetwork(amt)
+	return u.getEdgeNetwork(amt)
 }
 
-// getPolicyLocal returns the optimal policy to use for this local connection
-// given a specific amount to send.
-func (u *unifiedPolicy) getPolicyLocal(amt lnwire.MilliAtom,
-	bandwidthHints map[uint64]lnwire.MilliAtom) *channeldb.ChannelEdgePolicy {
+// getEdgeLocal returns the optimal unified edge to use for this local
+// connection given a specific amount to send.
+func (u *unifiedPolicy) getEdgeLocal(amt lnwire.MilliAtom,
+	bandwidthHints map[uint64]lnwire.MilliAtom) *unifiedPolicyEdge {
 
 	var (
-		bestPolicy   *channeldb.ChannelEdgePolicy
+		bestEdge     *unifiedPolicyEdge
 		maxBandwidth lnwire.MilliAtom
 	)
 
@@ -195,23 +210,26 @@ func (u *unifiedPolicy) getPolicyLocal(amt lnwire.MilliAtom,
 		}
 		maxBandwidth = bandwidth
 
-		// Update best policy.
-		bestPolicy = edge.policy
+		// Update best edge.
+		bestEdge = edge
 	}
 
-	return bestPolicy
+	return bestEdge
 }
 
-// getPolicyNetwork returns the optimal policy to use for this connection given
-// a specific amount to send. The goal is to return a policy that maximizes the
-// probability of a successful forward in a non-strict forwarding context.
-func (u *unifiedPolicy) getPolicyNetwork(
-	amt lnwire.MilliAtom) *channeldb.ChannelEdgePolicy {
+// getEdgeNetwork returns the optimal unified edge to use for this connection
+// given a specific amount to send. The goal is to return a policy that
+// maximizes the probability of a successful forward in a non-strict forwarding
+// context. The capacity of the returned edge is the largest capacity of all
+// channels that are candidates for non-strict forwarding.
+func (u *unifiedPolicy) getEdgeNetwork(
+	amt lnwire.MilliAtom) *unifiedPolicyEdge {
 
 	var (
 		bestPolicy  *channeldb.ChannelEdgePolicy
 		maxFee      lnwire.MilliAtom
 		maxTimelock uint16
+		maxCapacity dcrutil.Amount
 	)
 
 	for _, edge := range u.edges {
@@ -233,6 +251,12 @@ func (u *unifiedPolicy) getPolicyNetwork(
 			maxTimelock = edge.policy.TimeLockDelta
 		}
 
+		// Track the maximum capacity of all candidate channels. With
+		// non-strict forwarding, the routing node may use any of them.
+		if edge.capacity > maxCapacity {
+			maxCapacity = edge.capacity
+		}
+
 		// Use the policy that results in the highest fee for this
 		// specific amount.
 		fee := edge.policy.ComputeFee(amt)
@@ -262,7 +286,10 @@ func (u *unifiedPolicy) getPolicyNetwork(
 	modifiedPolicy := *bestPolicy
 	modifiedPolicy.TimeLockDelta = maxTimelock
 
-	return &modifiedPolicy
+	return &unifiedPolicyEdge{
+		policy:   &modifiedPolicy,
+		capacity: maxCapacity,
+	}
 }
 
 // minAmt returns the minimum amount that can be forwarded on this connection.
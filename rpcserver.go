@@ -1,6 +1,7 @@
 package dcrlnd
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/tls"
@@ -8,8 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"net/http"
+	"os"
 	"runtime"
 	"sort"
 	"strconv"
@@ -71,6 +74,8 @@ import (
 	proxy "github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/tv42/zbase32"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gopkg.in/macaroon-bakery.v2/bakery"
 )
 
@@ -396,6 +401,10 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "info",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/CompactDatabase": {{
+			Entity: "info",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/SubscribeChannelGraph": {{
 			Entity: "info",
 			Action: "read",
@@ -404,6 +413,10 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/DeletePayment": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/DeleteAllPayments": {{
 			Entity: "offchain",
 			Action: "write",
@@ -448,6 +461,13 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/BackupDatabase": {{
+			Entity: "offchain",
+			Action: "write",
+		}, {
+			Entity: "onchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/ChannelAcceptor": {{
 			Entity: "onchain",
 			Action: "write",
@@ -475,6 +495,14 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "peers",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/SendCustomMessage": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/SubscribeCustomMessages": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/FundingStateStep": {{
 			Entity: "onchain",
 			Action: "write",
@@ -601,14 +629,40 @@ func newRPCServer(cfg *Config, s *server, macService *macaroons.Service,
 
 			return info.NodeKey1Bytes, info.NodeKey2Bytes, nil
 		},
-		FindRoute:              s.chanRouter.FindRoute,
-		MissionControl:         s.missionControl,
-		ActiveNetParams:        activeNetParams.Params,
-		Tower:                  s.controlTower,
-		MaxTotalTimelock:       cfg.MaxOutgoingCltvExpiry,
-		DefaultFinalCltvDelta:  uint16(cfg.TimeLockDelta),
-		SubscribeHtlcEvents:    s.htlcNotifier.SubscribeHtlcEvents,
-		InterceptableForwarder: s.interceptableSwitch,
+		FetchPeerChannels: func(peer route.Vertex) ([]uint64,
+			error) {
+
+			pubKey, err := secp256k1.ParsePubKey(peer[:])
+			if err != nil {
+				return nil, err
+			}
+
+			channels, err := s.remoteChanDB.FetchOpenChannels(
+				pubKey,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			var chanIDs []uint64
+			for _, channel := range channels {
+				if channel.IsPending {
+					continue
+				}
+				chanID := channel.ShortChanID().ToUint64()
+				chanIDs = append(chanIDs, chanID)
+			}
+			return chanIDs, nil
+		},
+		FindRoute:                s.chanRouter.FindRoute,
+		MissionControl:           s.missionControl,
+		SetPaymentAttemptPenalty: s.chanRouter.SetPaymentAttemptPenalty,
+		ActiveNetParams:          activeNetParams.Params,
+		Tower:                    s.controlTower,
+		MaxTotalTimelock:         cfg.MaxOutgoingCltvExpiry,
+		DefaultFinalCltvDelta:    uint16(cfg.TimeLockDelta),
+		SubscribeHtlcEvents:      s.htlcNotifier.SubscribeHtlcEvents,
+		InterceptableForwarder:   s.interceptableSwitch,
 	}
 
 	genInvoiceFeatures := func() *lnwire.FeatureVector {
@@ -2769,6 +2823,80 @@ func (r *rpcServer) SubscribePeerEvents(req *lnrpc.PeerEventSubscription,
 	}
 }
 
+// SendCustomMessage sends a message in the custom type range to a connected
+// peer.
+func (r *rpcServer) SendCustomMessage(ctx context.Context,
+	req *lnrpc.SendCustomMessageRequest) (*lnrpc.SendCustomMessageResponse,
+	error) {
+
+	if !r.server.Started() {
+		return nil, ErrServerNotActive
+	}
+
+	peer, err := route.NewVertexFromBytes(req.Peer)
+	if err != nil {
+		return nil, err
+	}
+
+	// The type must fit the two bytes of the message type on the wire.
+	if req.Type > math.MaxUint16 {
+		return nil, fmt.Errorf("msg type %v exceeds maximum of %v",
+			req.Type, math.MaxUint16)
+	}
+
+	rpcsLog.Debugf("[sendcustommessage] type=%v, len=%v to peer(%v)",
+		req.Type, len(req.Data), peer)
+
+	err = r.server.SendCustomMessage(
+		peer, lnwire.MessageType(req.Type), req.Data,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.SendCustomMessageResponse{}, nil
+}
+
+// SubscribeCustomMessages returns a uni-directional stream (server -> client)
+// for notifying the client of the custom messages received from our peers.
+func (r *rpcServer) SubscribeCustomMessages(
+	req *lnrpc.SubscribeCustomMessagesRequest,
+	stream lnrpc.Lightning_SubscribeCustomMessagesServer) error {
+
+	client, err := r.server.SubscribeCustomMessages()
+	if err != nil {
+		return err
+	}
+	defer client.Cancel()
+
+	for {
+		select {
+		case e := <-client.Updates():
+			msg, ok := e.(*CustomMessage)
+			if !ok {
+				return fmt.Errorf("unexpected custom message "+
+					"update: %T", e)
+			}
+
+			err := stream.Send(&lnrpc.CustomMessage{
+				Peer: msg.Peer[:],
+				Type: uint32(msg.Msg.Type),
+				Data: msg.Msg.Data,
+			})
+			if err != nil {
+				return err
+			}
+
+		case <-client.Quit():
+			return errors.New("custom message subscription " +
+				"terminated")
+
+		case <-r.quit:
+			return nil
+		}
+	}
+}
+
 // WalletBalance returns total unspent outputs(confirmed and unconfirmed), all
 // confirmed unspent outputs and all unconfirmed unspent outputs under control
 // by the wallet. This method can be modified by having the request specify
@@ -4040,6 +4168,7 @@ type rpcPaymentIntent struct {
 	ignoreMaxOutboundAmt bool
 	destFeatures         *lnwire.FeatureVector
 	paymentAddr          *[32]byte
+	blindedPath          *zpay32.BlindedPaymentPath
 	payReq               []byte
 
 	destCustomRecords record.CustomSet
@@ -4181,6 +4310,20 @@ func (r *rpcServer) extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPayme
 		payIntent.destFeatures = payReq.Features
 		payIntent.paymentAddr = payReq.PaymentAddr
 
+		// Invoices with blinded paths are paid through the first of
+		// them, which also identifies the invoice to the recipient.
+		if len(payReq.BlindedPaths) > 0 {
+			blindedPath := payReq.BlindedPaths[0]
+			target, err := routing.BlindedPathTarget(blindedPath)
+			if err != nil {
+				return payIntent, err
+			}
+
+			payIntent.dest = target
+			payIntent.blindedPath = blindedPath
+			payIntent.paymentAddr = nil
+		}
+
 		if err := validateDest(payIntent.dest); err != nil {
 			return payIntent, err
 		}
@@ -4411,6 +4554,7 @@ func (r *rpcServer) dispatchPaymentIntent(
 			DestCustomRecords:  payIntent.destCustomRecords,
 			DestFeatures:       payIntent.destFeatures,
 			PaymentAddr:        payIntent.paymentAddr,
+			BlindedPath:        payIntent.blindedPath,
 
 			// Don't enable multi-part payments on the main rpc.
 			// Users need to use routerrpc for that.
@@ -4862,6 +5006,8 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		FallbackAddr:    invoice.FallbackAddr,
 		CltvExpiry:      invoice.CltvExpiry,
 		Private:         invoice.Private,
+		Amp:             invoice.IsAmp,
+		Blind:           invoice.IsBlinded,
 	}
 
 	if invoice.RPreimage != nil {
@@ -4949,13 +5095,41 @@ func (r *rpcServer) ListInvoices(ctx context.Context,
 		req.NumMaxInvoices = 100
 	}
 
+	creationStart, creationEnd, err := unmarshallDateRange(
+		"creation_date", req.CreationDateStart, req.CreationDateEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	settleStart, settleEnd, err := unmarshallDateRange(
+		"settle_date", req.SettleDateStart, req.SettleDateEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]channeldb.ContractState, 0, len(req.States))
+	for _, state := range req.States {
+		dbState, err := unmarshallInvoiceState(state)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, dbState)
+	}
+
 	// Next, we'll map the proto request into a format that is understood by
 	// the database.
 	q := channeldb.InvoiceQuery{
-		IndexOffset:    req.IndexOffset,
-		NumMaxInvoices: req.NumMaxInvoices,
-		PendingOnly:    req.PendingOnly,
-		Reversed:       req.Reversed,
+		IndexOffset:       req.IndexOffset,
+		NumMaxInvoices:    req.NumMaxInvoices,
+		PendingOnly:       req.PendingOnly,
+		Reversed:          req.Reversed,
+		CreationDateStart: creationStart,
+		CreationDateEnd:   creationEnd,
+		SettleDateStart:   settleStart,
+		SettleDateEnd:     settleEnd,
+		States:            states,
 	}
 	invoiceSlice, err := r.server.remoteChanDB.QueryInvoices(q)
 	if err != nil {
@@ -4990,6 +5164,57 @@ func (r *rpcServer) ListInvoices(ctx context.Context,
 	return resp, nil
 }
 
+// unmarshallDateRange converts a range of unix timestamps in seconds to a time
+// range. A zero timestamp leaves the range open on that side, and the end of
+// the range includes the whole second it refers to. The name of the range is
+// used in error messages.
+func unmarshallDateRange(name string, start, end int64) (time.Time, time.Time,
+	error) {
+
+	var startTime, endTime time.Time
+	if start < 0 || end < 0 {
+		return startTime, endTime, fmt.Errorf("%v_start and %v_end "+
+			"must not be negative", name, name)
+	}
+
+	if end != 0 && start > end {
+		return startTime, endTime, fmt.Errorf("%v_start must not be "+
+			"after %v_end", name, name)
+	}
+
+	if start != 0 {
+		startTime = time.Unix(start, 0)
+	}
+	if end != 0 {
+		endTime = time.Unix(end+1, 0).Add(-time.Nanosecond)
+	}
+
+	return startTime, endTime, nil
+}
+
+// unmarshallInvoiceState converts an rpc invoice state to its database
+// representation.
+func unmarshallInvoiceState(state lnrpc.Invoice_InvoiceState) (
+	channeldb.ContractState, error) {
+
+	switch state {
+	case lnrpc.Invoice_OPEN:
+		return channeldb.ContractOpen, nil
+
+	case lnrpc.Invoice_SETTLED:
+		return channeldb.ContractSettled, nil
+
+	case lnrpc.Invoice_CANCELED:
+		return channeldb.ContractCanceled, nil
+
+	case lnrpc.Invoice_ACCEPTED:
+		return channeldb.ContractAccepted, nil
+
+	default:
+		return 0, fmt.Errorf("unknown invoice state %v", state)
+	}
+}
+
 // SubscribeInvoices returns a uni-directional stream (server -> client) for
 // notifying the client of newly added/settled invoices.
 func (r *rpcServer) SubscribeInvoices(req *lnrpc.InvoiceSubscription,
@@ -5562,6 +5787,29 @@ func (r *rpcServer) StopDaemon(ctx context.Context,
 	return &lnrpc.StopResponse{}, nil
 }
 
+// CompactDatabase requests the channel database to be compacted the next time
+// the daemon starts, or withdraws a previous request.
+func (r *rpcServer) CompactDatabase(ctx context.Context,
+	req *lnrpc.CompactDatabaseRequest) (*lnrpc.CompactDatabaseResponse,
+	error) {
+
+	err := r.cfg.DB.RequestCompaction(
+		r.cfg.localDatabaseDir(), req.Cancel,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Cancel {
+		rpcsLog.Infof("[compactdb] compaction request withdrawn")
+	} else {
+		rpcsLog.Infof("[compactdb] channel database will be " +
+			"compacted on the next restart")
+	}
+
+	return &lnrpc.CompactDatabaseResponse{}, nil
+}
+
 // SubscribeChannelGraph launches a streaming RPC that allows the caller to
 // receive notifications upon any changes the channel graph topology from the
 // review of the responding node. Events notified include: new nodes coming
@@ -5694,11 +5942,39 @@ func (r *rpcServer) ListPayments(ctx context.Context,
 
 	rpcsLog.Debugf("[ListPayments]")
 
+	creationStart, creationEnd, err := unmarshallDateRange(
+		"creation_date", req.CreationDateStart, req.CreationDateEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	settleStart, settleEnd, err := unmarshallDateRange(
+		"settle_date", req.SettleDateStart, req.SettleDateEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]channeldb.PaymentStatus, 0, len(req.Statuses))
+	for _, status := range req.Statuses {
+		dbStatus, err := unmarshallPaymentStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, dbStatus)
+	}
+
 	query := channeldb.PaymentsQuery{
 		IndexOffset:       req.IndexOffset,
 		MaxPayments:       req.MaxPayments,
 		Reversed:          req.Reversed,
 		IncludeIncomplete: req.IncludeIncomplete,
+		CreationDateStart: creationStart,
+		CreationDateEnd:   creationEnd,
+		SettleDateStart:   settleStart,
+		SettleDateEnd:     settleEnd,
+		Statuses:          statuses,
 	}
 
 	// If the maximum number of payments wasn't specified, then we'll
@@ -5733,13 +6009,78 @@ func (r *rpcServer) ListPayments(ctx context.Context,
 	return paymentsResp, nil
 }
 
-// DeleteAllPayments deletes all outgoing payments from DB.
+// unmarshallPaymentStatus converts an rpc payment status to its database
+// representation.
+func unmarshallPaymentStatus(status lnrpc.Payment_PaymentStatus) (
+	channeldb.PaymentStatus, error) {
+
+	switch status {
+	case lnrpc.Payment_UNKNOWN:
+		return channeldb.StatusUnknown, nil
+
+	case lnrpc.Payment_IN_FLIGHT:
+		return channeldb.StatusInFlight, nil
+
+	case lnrpc.Payment_SUCCEEDED:
+		return channeldb.StatusSucceeded, nil
+
+	case lnrpc.Payment_FAILED:
+		return channeldb.StatusFailed, nil
+
+	default:
+		return 0, fmt.Errorf("unknown payment status %v", status)
+	}
+}
+
+// DeletePayment deletes a payment from the DB given its payment hash. If
+// failedHtlcsOnly is set, only failed HTLC attempts of the payment will be
+// deleted.
+func (r *rpcServer) DeletePayment(ctx context.Context,
+	req *lnrpc.DeletePaymentRequest) (*lnrpc.DeletePaymentResponse, error) {
+
+	hash, err := lntypes.MakeHash(req.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Infof("[DeletePayment] payment_identifier=%v, "+
+		"failed_htlcs_only=%v", hash, req.FailedHtlcsOnly)
+
+	// The payment is deleted through the control tower, so that it can't
+	// be initiated again while it is being deleted.
+	err = r.server.controlTower.DeletePayment(hash, req.FailedHtlcsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.DeletePaymentResponse{}, nil
+}
+
+// DeleteAllPayments deletes all outgoing payments from DB, optionally
+// restricted to failed payments, failed HTLC attempts or a range of creation
+// times. In-flight payments are never deleted.
 func (r *rpcServer) DeleteAllPayments(ctx context.Context,
-	_ *lnrpc.DeleteAllPaymentsRequest) (*lnrpc.DeleteAllPaymentsResponse, error) {
+	req *lnrpc.DeleteAllPaymentsRequest) (
+	*lnrpc.DeleteAllPaymentsResponse, error) {
 
-	rpcsLog.Debugf("[DeleteAllPayments]")
+	rpcsLog.Infof("[DeleteAllPayments] failed_payments_only=%v, "+
+		"failed_htlcs_only=%v, creation_date_start=%v, "+
+		"creation_date_end=%v", req.FailedPaymentsOnly,
+		req.FailedHtlcsOnly, req.CreationDateStart,
+		req.CreationDateEnd)
 
-	if err := r.server.remoteChanDB.DeletePayments(); err != nil {
+	startTime, endTime, err := unmarshallDateRange(
+		"creation_date", req.CreationDateStart, req.CreationDateEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.server.remoteChanDB.DeletePaymentsInRange(
+		startTime, endTime, req.FailedPaymentsOnly,
+		req.FailedHtlcsOnly,
+	)
+	if err != nil {
 		return nil, err
 	}
 
@@ -6128,13 +6469,46 @@ func (r *rpcServer) ForwardingHistory(ctx context.Context,
 		numEvents = 100
 	}
 
+	// Resolve the channel filters of both sides, which include all
+	// current and past channels with the given peers.
+	incomingChanIDs, err := r.forwardingChanFilter(
+		req.IncomingChanIds, req.IncomingPeer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	outgoingChanIDs, err := r.forwardingChanFilter(
+		req.OutgoingChanIds, req.OutgoingPeer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// If one of the filters didn't resolve to any channel, then no event
+	// can match the query.
+	if incomingChanIDs != nil && len(incomingChanIDs) == 0 ||
+		outgoingChanIDs != nil && len(outgoingChanIDs) == 0 {
+
+		resp := &lnrpc.ForwardingHistoryResponse{
+			LastOffsetIndex: req.IndexOffset,
+		}
+		if req.IncludeTotals {
+			resp.Totals = &lnrpc.ForwardingTotals{}
+		}
+
+		return resp, nil
+	}
+
 	// Next, we'll map the proto request into a format that is understood by
 	// the forwarding log.
 	eventQuery := channeldb.ForwardingEventQuery{
-		StartTime:    startTime,
-		EndTime:      endTime,
-		IndexOffset:  req.IndexOffset,
-		NumMaxEvents: numEvents,
+		StartTime:       startTime,
+		EndTime:         endTime,
+		IndexOffset:     req.IndexOffset,
+		NumMaxEvents:    numEvents,
+		IncomingChanIDs: incomingChanIDs,
+		OutgoingChanIDs: outgoingChanIDs,
+		IncludeTotals:   req.IncludeTotals,
 	}
 	timeSlice, err := r.server.remoteChanDB.ForwardingLog().Query(eventQuery)
 	if err != nil {
@@ -6170,9 +6544,65 @@ func (r *rpcServer) ForwardingHistory(ctx context.Context,
 		}
 	}
 
+	if timeSlice.Totals != nil {
+		resp.Totals = &lnrpc.ForwardingTotals{
+			NumEvents:    timeSlice.Totals.NumEvents,
+			AmtInMAtoms:  uint64(timeSlice.Totals.AmtIn),
+			AmtOutMAtoms: uint64(timeSlice.Totals.AmtOut),
+			FeeMAtoms:    uint64(timeSlice.Totals.Fees()),
+		}
+	}
+
 	return resp, nil
 }
 
+// forwardingChanFilter returns the channels that one side of a forwarding
+// history query is restricted to, which are the given channels along with all
+// current and past channels with the given peer. A nil slice is returned if
+// the side isn't restricted, and an empty one if the peer has no channels.
+func (r *rpcServer) forwardingChanFilter(chanIDs []uint64,
+	peer []byte) ([]lnwire.ShortChannelID, error) {
+
+	if len(chanIDs) == 0 && len(peer) == 0 {
+		return nil, nil
+	}
+
+	filter := make([]lnwire.ShortChannelID, 0, len(chanIDs))
+	for _, chanID := range chanIDs {
+		filter = append(filter, lnwire.NewShortChanIDFromInt(chanID))
+	}
+
+	if len(peer) == 0 {
+		return filter, nil
+	}
+
+	peerPubKey, err := secp256k1.ParsePubKey(peer)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse peer pubkey: %v", err)
+	}
+
+	openChannels, err := r.server.remoteChanDB.FetchOpenChannels(peerPubKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range openChannels {
+		filter = append(filter, channel.ShortChanID())
+	}
+
+	closedChannels, err := r.server.remoteChanDB.FetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range closedChannels {
+		if !channel.RemotePub.IsEqual(peerPubKey) {
+			continue
+		}
+		filter = append(filter, channel.ShortChanID)
+	}
+
+	return filter, nil
+}
+
 // ExportChannelBackup attempts to return an encrypted static channel backup
 // for the target channel identified by it channel point. The backup is
 // encrypted with a key generated from the aezeed seed of the user. The
@@ -6514,6 +6944,122 @@ func (r *rpcServer) SubscribeChannelBackups(req *lnrpc.ChannelBackupSubscription
 	}
 }
 
+// dbBackupChunkSize is the maximum size of a chunk of a database backup sent
+// by BackupDatabase.
+const dbBackupChunkSize = 64 * 1024
+
+// dbBackupStreamWriter sends the data written to it as chunks of a database
+// backup stream.
+type dbBackupStreamWriter struct {
+	stream lnrpc.Lightning_BackupDatabaseServer
+}
+
+// Write sends the passed data in chunks of at most dbBackupChunkSize bytes.
+//
+// NOTE: This is part of the io.Writer interface.
+func (w *dbBackupStreamWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > dbBackupChunkSize {
+			chunk = chunk[:dbBackupChunkSize]
+		}
+
+		// The data is copied, as the caller may reuse the passed
+		// slice once Write returns.
+		err := w.stream.Send(&lnrpc.DatabaseBackupChunk{
+			Data: append([]byte(nil), chunk...),
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return n, nil
+}
+
+// BackupDatabase streams a consistent snapshot of the channel database, which
+// is taken within a single read transaction while the node keeps running.
+// Unless requested otherwise, the snapshot is encrypted with a key derived
+// from the node's keychain. The snapshot is spooled to a temporary file first,
+// so the transaction isn't held open while it's streamed to the client.
+func (r *rpcServer) BackupDatabase(req *lnrpc.BackupDatabaseRequest,
+	stream lnrpc.Lightning_BackupDatabaseServer) error {
+
+	// A copy of the etcd backend would be a snapshot of the whole etcd
+	// cluster rather than of the channel database.
+	if r.cfg.DB.Backend == lncfg.EtcdBackend {
+		return status.Errorf(codes.Unimplemented, "database backups "+
+			"aren't supported by the %v backend", lncfg.EtcdBackend)
+	}
+
+	rpcsLog.Infof("[backupdb] creating database snapshot (encrypted=%v)",
+		!req.NoEncrypt)
+
+	spoolFile, err := ioutil.TempFile(
+		r.cfg.localDatabaseDir(), "backupdb",
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create database backup file: %v",
+			err)
+	}
+	defer func() {
+		spoolFile.Close()
+		os.Remove(spoolFile.Name())
+	}()
+
+	// The backend may write the snapshot in small pieces, so we'll buffer
+	// them before writing them to the file.
+	bufWriter := bufio.NewWriter(spoolFile)
+
+	var (
+		w         io.Writer = bufWriter
+		encWriter io.WriteCloser
+	)
+	if !req.NoEncrypt {
+		encWriter, err = chanbackup.NewDBBackupWriter(
+			bufWriter, r.server.cc.keyRing,
+		)
+		if err != nil {
+			return fmt.Errorf("unable to encrypt database backup: "+
+				"%v", err)
+		}
+		w = encWriter
+	}
+
+	if err := r.server.remoteChanDB.Backend.Copy(w); err != nil {
+		return fmt.Errorf("unable to back up database: %v", err)
+	}
+
+	if encWriter != nil {
+		if err := encWriter.Close(); err != nil {
+			return err
+		}
+	}
+	if err := bufWriter.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := spoolFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	rpcsLog.Infof("[backupdb] streaming database snapshot")
+
+	// The file is read in pieces of arbitrary size, so we'll buffer them
+	// to send chunks of a reasonable size.
+	streamWriter := bufio.NewWriterSize(
+		&dbBackupStreamWriter{stream: stream}, dbBackupChunkSize,
+	)
+	if _, err := io.Copy(streamWriter, spoolFile); err != nil {
+		return err
+	}
+
+	return streamWriter.Flush()
+}
+
 // chanAcceptInfo is used in the ChannelAcceptor bidirectional stream and
 // encapsulates the request information sent from the RPCAcceptor to the
 // RPCServer.
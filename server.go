@@ -44,6 +44,7 @@ import (
 	"github.com/decred/dcrlnd/lnpeer"
 	"github.com/decred/dcrlnd/lnrpc"
 	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwallet"
 	"github.com/decred/dcrlnd/lnwallet/chainfee"
 	"github.com/decred/dcrlnd/lnwallet/chanfunding"
@@ -57,6 +58,7 @@ import (
 	"github.com/decred/dcrlnd/routing"
 	"github.com/decred/dcrlnd/routing/localchans"
 	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/subscribe"
 	"github.com/decred/dcrlnd/sweep"
 	"github.com/decred/dcrlnd/ticker"
 	"github.com/decred/dcrlnd/tor"
@@ -66,6 +68,7 @@ import (
 	"github.com/decred/dcrlnd/watchtower/wtpolicy"
 	sphinx "github.com/decred/lightning-onion/v3"
 	"github.com/go-errors/errors"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -218,6 +221,10 @@ type server struct {
 
 	peerNotifier *peernotifier.PeerNotifier
 
+	// customMessageServer notifies its subscribers of the messages in the
+	// custom type range received from our peers.
+	customMessageServer *subscribe.Server
+
 	htlcNotifier *htlcswitch.HtlcNotifier
 
 	witnessBeacon contractcourt.WitnessBeacon
@@ -230,6 +237,15 @@ type server struct {
 
 	controlTower routing.ControlTower
 
+	// trampoline forwards payments on behalf of light clients that hand us
+	// a trampoline onion. It is nil if trampoline routing is disabled.
+	trampoline *htlcswitch.Trampoline
+
+	// htlcReputation protects our outgoing channels from being jammed by
+	// incoming channels without a good reputation. It is nil if the
+	// reputation tracking is disabled.
+	htlcReputation *htlcswitch.ReputationManager
+
 	authGossiper *discovery.AuthenticatedGossiper
 
 	localChanMgr *localchans.Manager
@@ -399,17 +415,21 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		NoStaticRemoteKey: cfg.ProtocolOptions.NoStaticRemoteKey(),
 		NoAnchors:         !cfg.ProtocolOptions.AnchorCommitments(),
 		NoWumbo:           !cfg.ProtocolOptions.Wumbo(),
+		NoTrampoline:      !cfg.Trampoline.Active,
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	registryConfig := invoices.RegistryConfig{
-		FinalCltvRejectDelta: lncfg.DefaultFinalCltvRejectDelta,
-		HtlcHoldDuration:     invoices.DefaultHtlcHoldDuration,
-		Clock:                clock.NewDefaultClock(),
-		AcceptKeySend:        cfg.AcceptKeySend,
-		KeysendHoldTime:      cfg.KeysendHoldTime,
+		FinalCltvRejectDelta:    lncfg.DefaultFinalCltvRejectDelta,
+		HtlcHoldDuration:        invoices.DefaultHtlcHoldDuration,
+		Clock:                   clock.NewDefaultClock(),
+		AcceptKeySend:           cfg.AcceptKeySend,
+		KeysendHoldTime:         cfg.KeysendHoldTime,
+		AcceptAMP:               cfg.AcceptAMP,
+		GcCanceledInvoicesAfter: cfg.GcCanceledInvoicesAfter,
+		Notifier:                cc.chainNotifier,
 	}
 
 	s := &server{
@@ -436,7 +456,7 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 
 		// TODO(roasbeef): derive proper onion key based on rotation
 		// schedule
-		sphinx: hop.NewOnionProcessor(sphinxRouter),
+		sphinx: hop.NewOnionProcessor(sphinxRouter, nodeKeyECDH),
 
 		torController: torController,
 
@@ -463,6 +483,10 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		subscribers: make(map[uint64]*preimageSubscriber),
 	}
 
+	// The preimages of the exit hop htlcs settled by the exit hop
+	// interceptor are only stored in the witness cache.
+	registryConfig.AddPreimages = s.witnessBeacon.AddPreimages
+
 	_, currentHeight, err := s.cc.chainIO.GetBestBlock()
 	if err != nil {
 		return nil, err
@@ -470,6 +494,23 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 
 	s.htlcNotifier = htlcswitch.NewHtlcNotifier(time.Now)
 
+	if cfg.Reputation.Active {
+		rep := cfg.Reputation
+		repCfg := &htlcswitch.ReputationConfig{
+			ProtectedSlotShare:      rep.ProtectedSlotShare,
+			ProtectedLiquidityShare: rep.ProtectedLiquidityShare,
+			MaxResolutionTime:       rep.MaxResolutionTime,
+			MaxFailureRatio:         rep.MaxFailureRatio,
+			MinResolved:             rep.MinResolved,
+			RateLimit:               rate.Limit(rep.RateLimit),
+			RateBurst:               rep.RateBurst,
+			Clock:                   clock.NewDefaultClock(),
+		}
+		repCfg.SubscribeHtlcEvents = s.htlcNotifier.SubscribeHtlcEvents
+
+		s.htlcReputation = htlcswitch.NewReputationManager(repCfg)
+	}
+
 	s.htlcSwitch, err = htlcswitch.New(htlcswitch.Config{
 		DB: remoteChanDB,
 		LocalChannelClose: func(pubKey []byte,
@@ -497,13 +538,34 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		AckEventTicker:         ticker.New(htlcswitch.DefaultAckInterval),
 		AllowCircularRoute:     cfg.AllowCircularRoute,
 		RejectHTLC:             cfg.RejectHTLC,
+		HtlcReputation:         s.htlcReputation,
 		Clock:                  clock.NewDefaultClock(),
 		HTLCExpiry:             htlcswitch.DefaultHTLCExpiry,
 	}, uint32(currentHeight))
 	if err != nil {
 		return nil, err
 	}
-	s.interceptableSwitch = htlcswitch.NewInterceptableSwitch(s.htlcSwitch)
+
+	var disconnectAction htlcswitch.InterceptorDisconnectAction
+	switch cfg.InterceptorDisconnect {
+	case "fail":
+		disconnectAction = htlcswitch.InterceptorDisconnectFail
+
+	case "hold":
+		disconnectAction = htlcswitch.InterceptorDisconnectHold
+
+	default:
+		disconnectAction = htlcswitch.InterceptorDisconnectResume
+	}
+
+	s.interceptableSwitch = htlcswitch.NewInterceptableSwitch(
+		&htlcswitch.InterceptableSwitchConfig{
+			Switch:             s.htlcSwitch,
+			Notifier:           s.cc.chainNotifier,
+			DisconnectAction:   disconnectAction,
+			CltvInterceptDelta: cfg.InterceptorCltvDelta,
+		},
+	)
 
 	chanStatusMgrCfg := &netann.ChanStatusConfig{
 		ChanStatusSampleInterval: cfg.ChanStatusSampleInterval,
@@ -704,31 +766,68 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 	// servers, the mission control instance itself can be moved there too.
 	routingConfig := routerrpc.GetRoutingConfig(cfg.SubRPCServers.RouterRPC)
 
+	// Create the probability estimator that mission control will use to
+	// turn payment results into success probabilities.
+	var estimator routing.Estimator
+	switch routingConfig.ProbabilityEstimatorType {
+	case routing.AprioriEstimatorName:
+		aCfg := routing.AprioriConfig{
+			AprioriHopProbability: routingConfig.AprioriHopProbability,
+			PenaltyHalfLife:       routingConfig.PenaltyHalfLife,
+			AprioriWeight:         routingConfig.AprioriWeight,
+		}
+		estimator, err = routing.NewAprioriEstimator(aCfg)
+		if err != nil {
+			return nil, err
+		}
+
+	case routing.BimodalEstimatorName:
+		bCfg := routing.BimodalConfig{
+			BimodalNodeWeight: routingConfig.BimodalNodeWeight,
+			BimodalScaleMAtoms: lnwire.MilliAtom(
+				routingConfig.BimodalScale,
+			),
+			BimodalDecayTime: routingConfig.BimodalDecayTime,
+		}
+		estimator, err = routing.NewBimodalEstimator(bCfg)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown estimator type %v",
+			routingConfig.ProbabilityEstimatorType)
+	}
+
 	s.missionControl, err = routing.NewMissionControl(
 		remoteChanDB,
 		&routing.MissionControlConfig{
-			AprioriHopProbability:   routingConfig.AprioriHopProbability,
-			PenaltyHalfLife:         routingConfig.PenaltyHalfLife,
+			Estimator:               estimator,
 			MaxMcHistory:            routingConfig.MaxMcHistory,
-			AprioriWeight:           routingConfig.AprioriWeight,
 			SelfNode:                selfNode.PubKeyBytes,
 			MinFailureRelaxInterval: routing.DefaultMinFailureRelaxInterval,
+			PaymentAttemptPenalty: lnwire.NewMAtomsFromAtoms(
+				routingConfig.AttemptCost,
+			),
 		},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("can't create mission control: %v", err)
 	}
 
+	// The payment attempt penalty is persisted by mission control when it
+	// is changed at runtime, so we take it from its config rather than
+	// from the routing config.
+	attemptPenalty := s.missionControl.GetConfig().PaymentAttemptPenalty
+
 	srvrLog.Debugf("Instantiating payment session source with config: "+
 		"PaymentAttemptPenalty=%v, MinRouteProbability=%v",
-		int64(routingConfig.AttemptCost),
+		int64(attemptPenalty.ToAtoms()),
 		routingConfig.MinRouteProbability)
 
 	pathFindingConfig := routing.PathFindingConfig{
-		PaymentAttemptPenalty: lnwire.NewMAtomsFromAtoms(
-			routingConfig.AttemptCost,
-		),
-		MinProbability: routingConfig.MinRouteProbability,
+		PaymentAttemptPenalty: attemptPenalty,
+		MinProbability:        routingConfig.MinRouteProbability,
 	}
 
 	paymentSessionSource := &routing.SessionSource{
@@ -762,6 +861,17 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		return nil, fmt.Errorf("can't create router: %v", err)
 	}
 
+	if cfg.Trampoline.Active {
+		s.trampoline = htlcswitch.NewTrampoline(&htlcswitch.TrampolineConfig{
+			NodeKey:     nodeKeyECDH,
+			BaseFee:     lnwire.MilliAtom(cfg.Trampoline.BaseFee),
+			FeeRate:     lnwire.MilliAtom(cfg.Trampoline.FeeRate),
+			CltvDelta:   cfg.Trampoline.TimeLockDelta,
+			BestHeight:  s.htlcSwitch.BestHeight,
+			SendPayment: s.sendTrampolinePayment,
+		})
+	}
+
 	chanSeries := discovery.NewChanSeries(s.localChanDB.ChannelGraph())
 	gossipMessageStore, err := discovery.NewMessageStore(s.remoteChanDB)
 	if err != nil {
@@ -1187,6 +1297,10 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 	// to peer online and offline events.
 	s.peerNotifier = peernotifier.New()
 
+	// Assemble a subscription server which will provide clients with the
+	// custom messages received from our peers.
+	s.customMessageServer = subscribe.NewServer()
+
 	// Create a channel event store which monitors all open channels.
 	s.chanEventStore = chanfitness.NewChannelEventStore(&chanfitness.Config{
 		SubscribeChannelEvents: s.channelNotifier.SubscribeChannelEvents,
@@ -1385,10 +1499,20 @@ func (s *server) Start() error {
 			startErr = err
 			return
 		}
+		if err := s.customMessageServer.Start(); err != nil {
+			startErr = err
+			return
+		}
 		if err := s.htlcNotifier.Start(); err != nil {
 			startErr = err
 			return
 		}
+		if s.htlcReputation != nil {
+			if err := s.htlcReputation.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
 		if err := s.sphinx.Start(); err != nil {
 			startErr = err
 			return
@@ -1403,6 +1527,10 @@ func (s *server) Start() error {
 			startErr = err
 			return
 		}
+		if err := s.interceptableSwitch.Start(); err != nil {
+			startErr = err
+			return
+		}
 		if err := s.sweeper.Start(); err != nil {
 			startErr = err
 			return
@@ -1544,6 +1672,10 @@ func (s *server) Stop() error {
 		s.chanStatusMgr.Stop()
 		s.cc.chainNotifier.Stop()
 		s.chanRouter.Stop()
+		if s.trampoline != nil {
+			s.trampoline.Stop()
+		}
+		s.interceptableSwitch.Stop()
 		s.htlcSwitch.Stop()
 		s.sphinx.Stop()
 		s.utxoNursery.Stop()
@@ -1553,6 +1685,10 @@ func (s *server) Stop() error {
 		s.sweeper.Stop()
 		s.channelNotifier.Stop()
 		s.peerNotifier.Stop()
+		s.customMessageServer.Stop()
+		if s.htlcReputation != nil {
+			s.htlcReputation.Stop()
+		}
 		s.htlcNotifier.Stop()
 		s.cc.wallet.Shutdown()
 		s.cc.chainView.Stop()
@@ -2591,6 +2727,57 @@ func (s *server) findPeerByPubStr(pubStr string) (*peer.Brontide, error) {
 	return peer, nil
 }
 
+// SendCustomMessage sends a message in the custom type range with the given
+// payload to the connected peer with the given public key.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *server) SendCustomMessage(peerPub [33]byte, msgType lnwire.MessageType,
+	data []byte) error {
+
+	msg, err := lnwire.NewCustom(msgType, data)
+	if err != nil {
+		return err
+	}
+
+	peer, err := s.FindPeerByPubStr(string(peerPub[:]))
+	if err != nil {
+		return err
+	}
+
+	// We wait for the message to be written to the connection, so that
+	// the caller learns of a failure to send it.
+	return peer.SendMessageLazy(true, msg)
+}
+
+// CustomMessage is a message in the custom type range received from one of our
+// peers.
+type CustomMessage struct {
+	// Peer is the public key of the peer that sent the message.
+	Peer [33]byte
+
+	// Msg is the custom message.
+	Msg *lnwire.Custom
+}
+
+// handleCustomMessage notifies the subscribers of a custom message received
+// from the peer with the given public key.
+func (s *server) handleCustomMessage(peer [33]byte, msg *lnwire.Custom) error {
+	srvrLog.Debugf("Received custom message of type %v from peer %x",
+		uint16(msg.Type), peer)
+
+	return s.customMessageServer.SendUpdate(&CustomMessage{
+		Peer: peer,
+		Msg:  msg,
+	})
+}
+
+// SubscribeCustomMessages returns a subscription to the messages in the
+// custom type range received from our peers. The updates are of type
+// *CustomMessage.
+func (s *server) SubscribeCustomMessages() (*subscribe.Client, error) {
+	return s.customMessageServer.Subscribe()
+}
+
 // nextPeerBackoff computes the next backoff duration for a peer's pubkey using
 // exponential backoff. If no previous backoff was known, the default is
 // returned.
@@ -2984,6 +3171,7 @@ func (s *server) peerConnected(conn net.Conn, connReq *connmgr.ConnReq,
 		ProcessFundingLocked:  s.fundingMgr.processFundingLocked,
 		ProcessFundingError:   s.fundingMgr.processFundingError,
 		IsPendingChannel:      s.fundingMgr.IsPendingChannel,
+		HandleCustomMessage:   s.handleCustomMessage,
 
 		Hodl:                    s.cfg.Hodl,
 		UnsafeReplay:            s.cfg.UnsafeReplay,
@@ -2997,6 +3185,15 @@ func (s *server) peerConnected(conn net.Conn, connReq *connmgr.ConnReq,
 	copy(pCfg.PubKeyBytes[:], peerAddr.IdentityKey.SerializeCompressed())
 	copy(pCfg.ServerPubKey[:], s.identityECDH.PubKey().SerializeCompressed())
 
+	// The max dust exposure of the links is configured in atoms.
+	pCfg.MaxDustExposure = lnwire.NewMAtomsFromAtoms(s.cfg.MaxDustExposure)
+
+	// The links reject htlcs that carry a trampoline onion unless they're
+	// given a trampoline forwarder.
+	if s.trampoline != nil {
+		pCfg.Trampoline = s.trampoline
+	}
+
 	p := peer.NewBrontide(pCfg)
 
 	// TODO(roasbeef): update IP address for link-node
@@ -3699,6 +3896,94 @@ func (s *server) applyChannelUpdate(update *lnwire.ChannelUpdate) error {
 	}
 }
 
+// sendTrampolinePayment sends a payment on behalf of a trampoline sender and
+// blocks until its outcome is known. If a payment with the same hash was
+// already initiated, which is the case if the incoming htlc is forwarded again
+// after a restart, the outcome of that payment is awaited instead.
+func (s *server) sendTrampolinePayment(
+	p *htlcswitch.TrampolinePayment) (lntypes.Preimage, error) {
+
+	// The router pads the final expiry delta by a few blocks, which we
+	// subtract here so that the destination receives the expiry requested
+	// by the sender.
+	minExpiry := s.htlcSwitch.BestHeight() + uint32(routing.BlockPadding)
+	if p.FinalCltv <= minExpiry {
+		return lntypes.Preimage{}, fmt.Errorf("final expiry %v too "+
+			"soon", p.FinalCltv)
+	}
+
+	payment := &routing.LightningPayment{
+		Target:            route.NewVertex(p.Destination),
+		Amount:            p.Amount,
+		FeeLimit:          p.FeeLimit,
+		CltvLimit:         p.CltvLimit,
+		PaymentHash:       p.Hash,
+		FinalCLTVDelta:    uint16(p.FinalCltv - minExpiry),
+		PayAttemptTimeout: routing.DefaultPayAttemptTimeout,
+		PaymentAddr:       p.PaymentAddr,
+		MaxParts:          1,
+	}
+
+	preimage, _, err := s.chanRouter.SendPayment(payment)
+	switch {
+	case err == channeldb.ErrPaymentInFlight ||
+		err == channeldb.ErrAlreadyPaid:
+
+		return s.awaitTrampolinePayment(p.Hash)
+
+	case err == channeldb.FailureReasonPaymentDetails:
+		return lntypes.Preimage{}, htlcswitch.ErrTrampolineIncorrectDetails
+
+	case err != nil:
+		return lntypes.Preimage{}, err
+	}
+
+	return preimage, nil
+}
+
+// awaitTrampolinePayment waits for the outcome of a previously initiated
+// trampoline payment.
+func (s *server) awaitTrampolinePayment(
+	hash lntypes.Hash) (lntypes.Preimage, error) {
+
+	sub, err := s.controlTower.SubscribePayment(hash)
+	if err != nil {
+		return lntypes.Preimage{}, err
+	}
+	defer sub.Close()
+
+	var payment *channeldb.MPPayment
+	for {
+		select {
+		case item, ok := <-sub.Updates:
+			if ok {
+				payment = item.(*channeldb.MPPayment)
+				continue
+			}
+
+		case <-s.quit:
+			return lntypes.Preimage{}, ErrServerShuttingDown
+		}
+
+		break
+	}
+
+	settle, reason := payment.TerminalInfo()
+	switch {
+	case settle != nil:
+		return settle.Preimage, nil
+
+	case reason != nil &&
+		*reason == channeldb.FailureReasonPaymentDetails:
+
+		return lntypes.Preimage{}, htlcswitch.ErrTrampolineIncorrectDetails
+
+	default:
+		return lntypes.Preimage{}, fmt.Errorf("payment %v failed: %v",
+			hash, reason)
+	}
+}
+
 // newSweepPkScriptGen creates closure that generates a new public key script
 // which should be used to sweep any funds into the on-chain wallet.
 // Specifically, the script generated is a version 0, pay-to-witness-pubkey-hash
@@ -128,13 +128,12 @@ func genTaskTest(
 	)
 
 	// First, we'll initialize a new breach transaction and the
-	// corresponding breach retribution. The retribution stores a pointer to
-	// the breach transaction, which we will continue to modify.
+	// corresponding breach retribution. The retribution stores the hash of
+	// the breach transaction, which is set once all outputs are added.
 	breachTxn := wire.NewMsgTx()
 	breachTxn.Version = 2
 	breachInfo := &lnwallet.BreachRetribution{
-		RevokedStateNum:   stateNum,
-		BreachTransaction: breachTxn,
+		RevokedStateNum: stateNum,
 		KeyRing: &lnwallet.CommitmentKeyRing{
 			RevocationKey: revPK,
 			ToLocalKey:    toLocalPK,
@@ -188,6 +187,7 @@ func genTaskTest(
 	// its txid and inputs spending from it. We also generate the
 	// input.Inputs that should be derived by the backup task.
 	txid := breachTxn.TxHash()
+	breachInfo.BreachTxHash = txid
 	var index uint32
 	if toLocalAmt > 0 {
 		breachInfo.RemoteOutpoint = wire.OutPoint{
@@ -566,7 +566,7 @@ func testBackupTask(t *testing.T, test backupTaskTest) {
 	}
 
 	// Verify that the breach hint matches the breach txid's prefix.
-	breachTxID := test.breachInfo.BreachTransaction.TxHash()
+	breachTxID := test.breachInfo.BreachTxHash
 	expHint := blob.NewBreachHintFromHash(&breachTxID)
 	if hint != expHint {
 		t.Fatalf("breach hint mismatch, want: %x, got: %v",
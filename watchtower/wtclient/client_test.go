@@ -133,6 +133,7 @@ type mockChannel struct {
 	mu            sync.Mutex
 	commitHeight  uint64
 	retributions  map[uint64]*lnwallet.BreachRetribution
+	commitTxs     map[uint64]*wire.MsgTx
 	localBalance  lnwire.MilliAtom
 	remoteBalance lnwire.MilliAtom
 
@@ -171,6 +172,7 @@ func newMockChannel(t *testing.T, signer *wtmock.MockSigner,
 
 	c := &mockChannel{
 		retributions:   make(map[uint64]*lnwallet.BreachRetribution),
+		commitTxs:      make(map[uint64]*wire.MsgTx),
 		localBalance:   localAmt,
 		remoteBalance:  remoteAmt,
 		revSK:          revSK,
@@ -298,7 +300,7 @@ func (c *mockChannel) createRemoteCommitTx(t *testing.T) {
 	}
 
 	retribution := &lnwallet.BreachRetribution{
-		BreachTransaction:    commitTxn,
+		BreachTxHash:         txid,
 		RevokedStateNum:      c.commitHeight,
 		KeyRing:              commitKeyRing,
 		RemoteDelay:          c.csvDelay,
@@ -309,6 +311,7 @@ func (c *mockChannel) createRemoteCommitTx(t *testing.T) {
 	}
 
 	c.retributions[c.commitHeight] = retribution
+	c.commitTxs[c.commitHeight] = commitTxn
 	c.commitHeight++
 }
 
@@ -362,9 +365,7 @@ func (c *mockChannel) getState(i uint64) (*wire.MsgTx, *lnwallet.BreachRetributi
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	retribution := c.retributions[i]
-
-	return retribution.BreachTransaction, retribution
+	return c.commitTxs[i], c.retributions[i]
 }
 
 type testHarness struct {
@@ -0,0 +1,160 @@
+package zpay32
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+)
+
+var (
+	// ErrNoBlindedPathHops is returned when a blinded payment path doesn't
+	// contain any hops.
+	ErrNoBlindedPathHops = errors.New("blinded payment path has no hops")
+)
+
+// BlindedPaymentHop is a single hop of a blinded payment path.
+type BlindedPaymentHop struct {
+	// BlindedNodeID is the blinded public key of the node. The onion
+	// payload of the hop must be encrypted to this key.
+	BlindedNodeID *secp256k1.PublicKey
+
+	// CipherText is the data encrypted to the node by the creator of the
+	// path, which must be included in the onion payload of the hop.
+	CipherText []byte
+
+	// FeeBaseMAtoms is the base fee charged by the hop to forward a
+	// payment to the next hop of the path.
+	FeeBaseMAtoms uint32
+
+	// FeeProportionalMillionths is the proportional fee charged by the
+	// hop to forward a payment to the next hop of the path.
+	FeeProportionalMillionths uint32
+
+	// CLTVExpiryDelta is the time-lock delta applied by the hop when
+	// forwarding a payment to the next hop of the path.
+	CLTVExpiryDelta uint16
+}
+
+// BlindedPaymentPath is a route to the recipient of an invoice whose nodes,
+// with the exception of the introduction node, are hidden from the payer.
+type BlindedPaymentPath struct {
+	// IntroductionNode is the real public key of the first node of the
+	// path.
+	IntroductionNode *secp256k1.PublicKey
+
+	// BlindingPoint is the blinding point that must be handed to the
+	// introduction node in its onion payload.
+	BlindingPoint *secp256k1.PublicKey
+
+	// Hops is the list of hops of the path, starting with the
+	// introduction node and ending with the recipient.
+	Hops []BlindedPaymentHop
+}
+
+// Encode serializes the blinded payment path into the passed io.Writer.
+func (p *BlindedPaymentPath) Encode(w io.Writer) error {
+	if len(p.Hops) == 0 {
+		return ErrNoBlindedPathHops
+	}
+	if len(p.Hops) > 255 {
+		return fmt.Errorf("too many blinded hops: %d", len(p.Hops))
+	}
+
+	var b bytes.Buffer
+	b.Write(p.IntroductionNode.SerializeCompressed())
+	b.Write(p.BlindingPoint.SerializeCompressed())
+	b.WriteByte(uint8(len(p.Hops)))
+
+	var scratch [4]byte
+	for _, hop := range p.Hops {
+		if len(hop.CipherText) > 0xffff {
+			return fmt.Errorf("blinded hop cipher text too large: "+
+				"%d", len(hop.CipherText))
+		}
+
+		b.Write(hop.BlindedNodeID.SerializeCompressed())
+
+		binary.BigEndian.PutUint32(scratch[:], hop.FeeBaseMAtoms)
+		b.Write(scratch[:])
+
+		binary.BigEndian.PutUint32(
+			scratch[:], hop.FeeProportionalMillionths,
+		)
+		b.Write(scratch[:])
+
+		binary.BigEndian.PutUint16(scratch[:2], hop.CLTVExpiryDelta)
+		b.Write(scratch[:2])
+
+		binary.BigEndian.PutUint16(scratch[:2], uint16(len(hop.CipherText)))
+		b.Write(scratch[:2])
+		b.Write(hop.CipherText)
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Decode deserializes a blinded payment path from the passed io.Reader.
+func (p *BlindedPaymentPath) Decode(r io.Reader) error {
+	var err error
+
+	p.IntroductionNode, err = readPubKey(r)
+	if err != nil {
+		return err
+	}
+
+	p.BlindingPoint, err = readPubKey(r)
+	if err != nil {
+		return err
+	}
+
+	var numHops [1]byte
+	if _, err := io.ReadFull(r, numHops[:]); err != nil {
+		return err
+	}
+	if numHops[0] == 0 {
+		return ErrNoBlindedPathHops
+	}
+
+	p.Hops = make([]BlindedPaymentHop, numHops[0])
+	for i := range p.Hops {
+		hop := &p.Hops[i]
+
+		hop.BlindedNodeID, err = readPubKey(r)
+		if err != nil {
+			return err
+		}
+
+		var relay [12]byte
+		if _, err := io.ReadFull(r, relay[:]); err != nil {
+			return err
+		}
+		hop.FeeBaseMAtoms = binary.BigEndian.Uint32(relay[:4])
+		hop.FeeProportionalMillionths = binary.BigEndian.Uint32(
+			relay[4:8],
+		)
+		hop.CLTVExpiryDelta = binary.BigEndian.Uint16(relay[8:10])
+
+		cipherTextLen := binary.BigEndian.Uint16(relay[10:12])
+		hop.CipherText = make([]byte, cipherTextLen)
+		if _, err := io.ReadFull(r, hop.CipherText); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readPubKey reads a compressed public key from the passed io.Reader.
+func readPubKey(r io.Reader) (*secp256k1.PublicKey, error) {
+	var b [33]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, err
+	}
+
+	return secp256k1.ParsePubKey(b[:])
+}
@@ -268,6 +268,15 @@ func parseTaggedFields(invoice *Invoice, fields []byte, net *chaincfg.Params) er
 			}
 
 			invoice.RouteHints = append(invoice.RouteHints, routeHint)
+		case fieldType5:
+			// Like route hints, multiple blinded paths can be
+			// included in an invoice.
+			path, err := parseBlindedPath(base32Data)
+			if err != nil {
+				return err
+			}
+
+			invoice.BlindedPaths = append(invoice.BlindedPaths, path)
 		case fieldType9:
 			if invoice.Features != nil {
 				// We skip the field if we have already seen a
@@ -450,6 +459,22 @@ func parseRouteHint(data []byte) ([]HopHint, error) {
 	return routeHint, nil
 }
 
+// parseBlindedPath converts the data (encoded in base32) into a blinded payment
+// path.
+func parseBlindedPath(data []byte) (*BlindedPaymentPath, error) {
+	base256Data, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+
+	path := &BlindedPaymentPath{}
+	if err := path.Decode(bytes.NewReader(base256Data)); err != nil {
+		return nil, err
+	}
+
+	return path, nil
+}
+
 // parseFeatures decodes any feature bits directly from the base32
 // representation.
 func parseFeatures(data []byte) (*lnwire.FeatureVector, error) {
@@ -74,6 +74,10 @@ const (
 	// probing the recipient.
 	fieldTypeS = 16
 
+	// fieldType5 contains a blinded payment path to the payee, which
+	// allows the payee to hide its identity from the payer.
+	fieldType5 = 20
+
 	// maxInvoiceLength is the maximum total length an invoice can have.
 	// This is chosen to be the maximum number of bytes that can fit into a
 	// single QR code: https://en.wikipedia.org/wiki/QR_code#Storage
@@ -190,6 +194,13 @@ type Invoice struct {
 	// NOTE: This is optional.
 	RouteHints [][]HopHint
 
+	// BlindedPaths represents one or more blinded payment paths to the
+	// payee. When set, the payee's node ID is not revealed and the payment
+	// must be sent to one of these paths.
+	//
+	// NOTE: This is optional.
+	BlindedPaths []*BlindedPaymentPath
+
 	// Features represents an optional field used to signal optional or
 	// required support for features by the receiver.
 	Features *lnwire.FeatureVector
@@ -266,6 +277,14 @@ func RouteHint(routeHint []HopHint) func(*Invoice) {
 	}
 }
 
+// BlindedPath is a functional option that allows callers of NewInvoice to add
+// a blinded payment path to the payee.
+func BlindedPath(path *BlindedPaymentPath) func(*Invoice) {
+	return func(i *Invoice) {
+		i.BlindedPaths = append(i.BlindedPaths, path)
+	}
+}
+
 // Features is a functional option that allows callers of NewInvoice to set the
 // desired feature bits that are advertised on the invoice. If this option is
 // not used, an empty feature vector will automatically be populated.
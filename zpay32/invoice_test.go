@@ -745,6 +745,71 @@ func TestNewInvoice(t *testing.T) {
 	}
 }
 
+// TestBlindedPathEncodeDecode tests that an invoice carrying blinded payment
+// paths is decoded into the same invoice that was encoded.
+func TestBlindedPathEncodeDecode(t *testing.T) {
+	t.Parallel()
+
+	path := &BlindedPaymentPath{
+		IntroductionNode: testHopHintPubkey1,
+		BlindingPoint:    testHopHintPubkey2,
+		Hops: []BlindedPaymentHop{
+			{
+				BlindedNodeID:             testHopHintPubkey1,
+				CipherText:                []byte{1, 2, 3, 4},
+				FeeBaseMAtoms:             1000,
+				FeeProportionalMillionths: 20,
+				CLTVExpiryDelta:           40,
+			},
+			{
+				BlindedNodeID: testHopHintPubkey2,
+				CipherText:    []byte{5, 6, 7},
+			},
+		},
+	}
+
+	invoice, err := NewInvoice(
+		chaincfg.MainNetParams(), testPaymentHash,
+		time.Unix(1496314658, 0), Amount(testMilliAt25mDCR),
+		Description(testCupOfCoffee), PaymentAddr(testPaymentAddr),
+		Features(emptyFeatures), BlindedPath(path), BlindedPath(path),
+	)
+	if err != nil {
+		t.Fatalf("unable to create invoice: %v", err)
+	}
+
+	encoded, err := invoice.Encode(testMessageSigner)
+	if err != nil {
+		t.Fatalf("unable to encode invoice: %v", err)
+	}
+
+	decoded, err := Decode(encoded, chaincfg.MainNetParams())
+	if err != nil {
+		t.Fatalf("unable to decode invoice: %v", err)
+	}
+
+	if len(decoded.BlindedPaths) != 2 {
+		t.Fatalf("expected 2 blinded paths, got %d",
+			len(decoded.BlindedPaths))
+	}
+
+	for _, decodedPath := range decoded.BlindedPaths {
+		if !reflect.DeepEqual(path, decodedPath) {
+			t.Fatalf("expected blinded path %v, got %v",
+				path, decodedPath)
+		}
+	}
+
+	// A blinded path without any hops can't be encoded.
+	invoice.BlindedPaths = []*BlindedPaymentPath{{
+		IntroductionNode: testHopHintPubkey1,
+		BlindingPoint:    testHopHintPubkey2,
+	}}
+	if _, err := invoice.Encode(testMessageSigner); err != ErrNoBlindedPathHops {
+		t.Fatalf("expected ErrNoBlindedPathHops, got: %v", err)
+	}
+}
+
 // TestMaxInvoiceLength tests that attempting to decode an invoice greater than
 // maxInvoiceLength fails with ErrInvoiceTooLarge.
 func TestMaxInvoiceLength(t *testing.T) {